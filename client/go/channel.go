@@ -0,0 +1,237 @@
+package dsps
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Prefix of HTTP headers to send message attributes.
+const attributeHeaderPrefix = "X-DSPS-Attr-"
+
+// Message is a message of the channel.
+type Message struct {
+	ChannelID string          `json:"channelID"`
+	MessageID string          `json:"messageID"`
+	Content   json.RawMessage `json:"content"`
+	// Metadata separated from Content, nil if not present.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// W3C traceparent of the publisher's trace, empty if not traced.
+	TraceParent string `json:"traceparent,omitempty"`
+}
+
+// BatchPublishResult is result of each message of PublishBatch.
+type BatchPublishResult struct {
+	MessageID string `json:"messageID"`
+	// "published" or "rejected"
+	Status string `json:"status"`
+	// Reason of the rejection, empty if published.
+	Error string `json:"error"`
+}
+
+// SubscriberFilter selects messages to receive, messages must satisfy all conditions.
+type SubscriberFilter struct {
+	// Attribute name -> expected value.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Dot separated JSON path of the content (e.g. "user.id") -> expected value (must be JSON serializable).
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// FetchResult is result of Channel.Fetch.
+type FetchResult struct {
+	Messages     []Message
+	MoreMessages bool
+	// Pass to Channel.Acknowledge to remove received messages from the subscriber, empty if no messages.
+	AckHandle string
+}
+
+// Channel to interact with.
+type Channel struct {
+	client    *Client
+	channelID string
+}
+
+// ID returns ID of the channel.
+func (ch *Channel) ID() string {
+	return ch.channelID
+}
+
+// Publish sends message to this channel.
+// Pass empty messageID to automatically generate ID, content must be JSON serializable.
+// Retrying this method is safe because server deduplicates messages with same messageID.
+func (ch *Channel) Publish(ctx context.Context, messageID string, content interface{}) (Message, error) {
+	return ch.PublishWithAttributes(ctx, messageID, content, nil)
+}
+
+// PublishWithAttributes is same as Publish but also sends attributes (metadata) of the message.
+// Attribute names must be lower case, see server/doc/interface/validation_rule.md of DSPS server.
+func (ch *Channel) PublishWithAttributes(ctx context.Context, messageID string, content interface{}, attributes map[string]string) (Message, error) {
+	if messageID == "" {
+		messageID = generateID("msg-")
+	}
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return Message{}, fmt.Errorf("cannot JSON serialize given message content: %w", err)
+	}
+
+	headers := make(map[string]string, len(attributes))
+	for name, value := range attributes {
+		headers[attributeHeaderPrefix+name] = value
+	}
+
+	err = ch.client.apiCall(ctx, apiRequest{
+		method:  http.MethodPut,
+		path:    fmt.Sprintf("/channel/%s/message/%s", url.PathEscape(ch.channelID), url.PathEscape(messageID)),
+		headers: headers,
+		body:    json.RawMessage(encoded),
+
+		expectedStatusCode: http.StatusOK,
+		retry:              true,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+	return Message{ChannelID: ch.channelID, MessageID: messageID, Content: encoded, Attributes: attributes}, nil
+}
+
+// PublishBatch sends multiple messages to this channel in one request.
+// Messages with empty MessageID get automatically generated ID, ChannelID of messages are ignored.
+// Returns result of each message in the same order, check BatchPublishResult.Status because server could reject some of messages.
+func (ch *Channel) PublishBatch(ctx context.Context, msgs []Message) ([]BatchPublishResult, error) {
+	type item struct {
+		MessageID  string            `json:"messageID"`
+		Content    json.RawMessage   `json:"content"`
+		Attributes map[string]string `json:"attributes,omitempty"`
+	}
+	items := make([]item, len(msgs))
+	for i, msg := range msgs {
+		items[i] = item{MessageID: msg.MessageID, Content: msg.Content, Attributes: msg.Attributes}
+		if items[i].MessageID == "" {
+			items[i].MessageID = generateID("msg-")
+		}
+	}
+
+	var res struct {
+		Messages []BatchPublishResult `json:"messages"`
+	}
+	err := ch.client.apiCall(ctx, apiRequest{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/channel/%s/messages", url.PathEscape(ch.channelID)),
+		body:   items,
+
+		expectedStatusCode: http.StatusOK,
+		responseBody:       &res,
+		retry:              true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Messages, nil
+}
+
+// CreateSubscriber creates polling subscriber, succeeds even if the subscriber already exists.
+func (ch *Channel) CreateSubscriber(ctx context.Context, subscriberID string) error {
+	return ch.CreateSubscriberWithFilter(ctx, subscriberID, nil)
+}
+
+// CreateSubscriberWithFilter creates polling subscriber that receives only messages matched with the filter.
+// If the subscriber already exists, replaces its filter (removes filter if nil).
+func (ch *Channel) CreateSubscriberWithFilter(ctx context.Context, subscriberID string, filter *SubscriberFilter) error {
+	var body interface{}
+	if filter != nil {
+		body = map[string]interface{}{"filter": filter}
+	}
+	return ch.client.apiCall(ctx, apiRequest{
+		method: http.MethodPut,
+		path:   ch.subscriberPath(subscriberID),
+		body:   body,
+
+		expectedStatusCode: http.StatusOK,
+		retry:              true,
+	})
+}
+
+// DeleteSubscriber deletes polling subscriber, succeeds even if the subscriber does not exist.
+func (ch *Channel) DeleteSubscriber(ctx context.Context, subscriberID string) error {
+	return ch.client.apiCall(ctx, apiRequest{
+		method: http.MethodDelete,
+		path:   ch.subscriberPath(subscriberID),
+
+		expectedStatusCode: http.StatusOK,
+		retry:              true,
+	})
+}
+
+// Fetch receives messages of the polling subscriber.
+// If timeout is larger than zero, server waits messages up to the duration (long-polling).
+// Received messages remain in the subscriber until Acknowledge called.
+func (ch *Channel) Fetch(ctx context.Context, subscriberID string, max int, timeout time.Duration) (FetchResult, error) {
+	query := url.Values{}
+	query.Set("max", fmt.Sprintf("%d", max))
+	if timeout > 0 {
+		query.Set("timeout", timeout.String())
+	}
+
+	var res struct {
+		Messages     []Message `json:"messages"`
+		MoreMessages bool      `json:"moreMessages"`
+		AckHandle    string    `json:"ackHandle"`
+	}
+	err := ch.client.apiCall(ctx, apiRequest{
+		method:      http.MethodGet,
+		path:        ch.subscriberPath(subscriberID),
+		queryParams: query,
+
+		expectedStatusCode: http.StatusOK,
+		responseBody:       &res,
+		timeoutOffset:      timeout,
+	})
+	if err != nil {
+		return FetchResult{}, err
+	}
+
+	result := FetchResult{
+		Messages:     make([]Message, len(res.Messages)),
+		MoreMessages: res.MoreMessages,
+		AckHandle:    res.AckHandle,
+	}
+	for i, msg := range res.Messages {
+		msg.ChannelID = ch.channelID
+		result.Messages[i] = msg
+	}
+	return result, nil
+}
+
+// Acknowledge removes received messages from the polling subscriber.
+func (ch *Channel) Acknowledge(ctx context.Context, subscriberID string, ackHandle string) error {
+	query := url.Values{}
+	query.Set("ackHandle", ackHandle)
+	return ch.client.apiCall(ctx, apiRequest{
+		method:      http.MethodDelete,
+		path:        ch.subscriberPath(subscriberID) + "/message",
+		queryParams: query,
+
+		expectedStatusCode: http.StatusNoContent,
+		retry:              true,
+	})
+}
+
+func (ch *Channel) subscriberPath(subscriberID string) string {
+	return fmt.Sprintf("/channel/%s/subscription/polling/%s", url.PathEscape(ch.channelID), url.PathEscape(subscriberID))
+}
+
+func generateID(prefix string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate random ID: %v", err))
+	}
+	return prefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,186 @@
+// Package dsps is a client library of the DSPS (Durable & Simple PubSub) server.
+package dsps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is default timeout of each API call, long-polling extends this value.
+const DefaultTimeout = 15 * time.Second
+
+// Config is configuration of the DSPS client.
+type Config struct {
+	// (Required) URL of the server (e.g. "https://dsps.example.com").
+	// If DSPS server configured with pathPrefix, it should be included also (such as "https://dsps.example.com/path-prefix/").
+	BaseURL string
+
+	// HTTP client to use, http.DefaultClient if nil.
+	// Timeout of the client should be zero (no timeout) or long enough for long-polling, use Timeout instead.
+	HTTPClient *http.Client
+
+	// HTTP headers to send always (APIs may override some headers such as Authorization).
+	Headers map[string]string
+
+	// Timeout of each API call, DefaultTimeout if zero.
+	// Note that long-polling request extends this value.
+	Timeout time.Duration
+
+	// Configuration of API retry (expect for polling), DefaultAPIRetry if nil.
+	APIRetry *RetryConfig
+
+	// JSON web token (JWT - RFC 7519) to send for each request.
+	JWT string
+
+	// Called when any API/communication error occurs, optional.
+	OnAPIFailed func(err error)
+	// Called when user-supplied subscription callback returns error, optional.
+	OnSubscriptionCallbackError func(info SubscriptionCallbackErrorInfo)
+}
+
+// Client is DSPS client, safe for concurrent use.
+type Client struct {
+	baseURL *url.URL
+	h       *http.Client
+	headers map[string]string
+	timeout time.Duration
+	retry   retry
+	jwt     string
+
+	onAPIFailed                 func(err error)
+	onSubscriptionCallbackError func(info SubscriptionCallbackErrorInfo)
+}
+
+// NewClient creates Client instance.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("BaseURL is required")
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BaseURL: %w", err)
+	}
+
+	c := &Client{
+		baseURL: baseURL,
+		h:       cfg.HTTPClient,
+		headers: cfg.Headers,
+		timeout: cfg.Timeout,
+		retry:   retry{config: DefaultAPIRetry},
+		jwt:     cfg.JWT,
+
+		onAPIFailed:                 cfg.OnAPIFailed,
+		onSubscriptionCallbackError: cfg.OnSubscriptionCallbackError,
+	}
+	if c.h == nil {
+		c.h = http.DefaultClient
+	}
+	if c.timeout == 0 {
+		c.timeout = DefaultTimeout
+	}
+	if cfg.APIRetry != nil {
+		if err := cfg.APIRetry.validate(); err != nil {
+			return nil, err
+		}
+		c.retry.config = *cfg.APIRetry
+	}
+	return c, nil
+}
+
+// Channel returns instance to interact with the channel.
+// Note that this method does not check validity & accessibility of the channel.
+func (c *Client) Channel(channelID string) *Channel {
+	return &Channel{client: c, channelID: channelID}
+}
+
+type apiRequest struct {
+	method      string
+	path        string // Path from BaseURL, always starts with "/"
+	queryParams url.Values
+	headers     map[string]string
+	body        interface{} // Encoded as JSON if non-nil
+
+	expectedStatusCode int
+	responseBody       interface{} // Decode response JSON into it if non-nil
+
+	// Extends timeout (e.g. long-polling).
+	timeoutOffset time.Duration
+	// Retry failed request or not, request must be idempotent to retry.
+	retry bool
+}
+
+func (c *Client) apiCall(ctx context.Context, req apiRequest) error {
+	var err error
+	if req.retry {
+		err = c.retry.perform(ctx, func() error { return c.doRequest(ctx, req) })
+	} else {
+		err = c.doRequest(ctx, req)
+	}
+	if err != nil && ctx.Err() == nil && c.onAPIFailed != nil {
+		c.onAPIFailed(err)
+	}
+	return err
+}
+
+func (c *Client) doRequest(ctx context.Context, req apiRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout+req.timeoutOffset)
+	defer cancel()
+
+	u := *c.baseURL
+	u.Path = u.Path + req.path
+	u.RawQuery = req.queryParams.Encode()
+
+	var body io.Reader
+	if req.body != nil {
+		encoded, err := json.Marshal(req.body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body of %s %s: %w", req.method, req.path, err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return fmt.Errorf("failed to make request %s %s: %w", req.method, req.path, err)
+	}
+	for name, value := range c.headers {
+		hreq.Header.Set(name, value)
+	}
+	for name, value := range req.headers {
+		hreq.Header.Set(name, value)
+	}
+	hreq.Header.Set("Accept", "application/json")
+	if req.body != nil {
+		hreq.Header.Set("Content-Type", "application/json")
+	}
+	if c.jwt != "" {
+		hreq.Header.Set("Authorization", "Bearer "+c.jwt)
+	}
+
+	res, err := c.h.Do(hreq)
+	if err != nil {
+		return &RequestError{Method: req.method, Path: req.path, Err: err}
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return &RequestError{Method: req.method, Path: req.path, Err: err}
+	}
+
+	if res.StatusCode != req.expectedStatusCode {
+		return newAPIError(req.method, req.path, res.StatusCode, resBody)
+	}
+	if req.responseBody != nil {
+		if err := json.Unmarshal(resBody, req.responseBody); err != nil {
+			return &RequestError{Method: req.method, Path: req.path, Err: fmt.Errorf("failed to parse response body JSON: %w", err)}
+		}
+	}
+	return nil
+}
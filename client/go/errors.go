@@ -0,0 +1,108 @@
+package dsps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Error codes returned by DSPS server, note that this list possibly change in future version.
+const (
+	ErrorCodeAuthRejected         = "dsps.auth.rejected"
+	ErrorCodeInvalidChannel       = "dsps.storage.invalid-channel"
+	ErrorCodeSubscriptionNotFound = "dsps.storage.subscription-not-found"
+	ErrorCodeMalformedAckHandle   = "dsps.storage.ack-handle-malformed"
+)
+
+// Sentinel errors to compare with errors.Is(), matches with APIError that has same error code.
+var (
+	ErrAuthRejected         = &APIError{Code: ErrorCodeAuthRejected}
+	ErrInvalidChannel       = &APIError{Code: ErrorCodeInvalidChannel}
+	ErrSubscriptionNotFound = &APIError{Code: ErrorCodeSubscriptionNotFound}
+	ErrMalformedAckHandle   = &APIError{Code: ErrorCodeMalformedAckHandle}
+)
+
+// Errors that never resolved by retrying subscription.
+var subscriptionUnrecoverableErrors = []error{ErrAuthRejected, ErrSubscriptionNotFound, ErrInvalidChannel}
+
+// APIError means DSPS server (or reverse proxy) responded unexpected HTTP status.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+
+	// Error code returned by DSPS server (e.g. "dsps.storage.invalid-channel"), empty if not present.
+	Code string
+	// Error message returned by DSPS server, empty if not present.
+	Message string
+}
+
+func newAPIError(method string, path string, statusCode int, body []byte) *APIError {
+	e := &APIError{Method: method, Path: path, StatusCode: statusCode}
+	var decoded struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &decoded) == nil {
+		e.Code = decoded.Code
+		e.Message = decoded.Error
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("DSPS API %s %s returned unexpected status %d", e.Method, e.Path, e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Code != "" {
+		msg += fmt.Sprintf(" (code: %s)", e.Code)
+	}
+	return msg
+}
+
+// Is returns true if target is APIError with same error code, to use errors.Is(err, ErrSubscriptionNotFound).
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// RequestError means failed to communicate with DSPS server (e.g. network error, timeout).
+type RequestError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("DSPS API %s %s failed: %v", e.Method, e.Path, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// IsSubscriptionUnrecoverable returns true if the error never resolved by retrying (e.g. subscriber deleted or expired).
+func IsSubscriptionUnrecoverable(err error) bool {
+	for _, target := range subscriptionUnrecoverableErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if retrying the request might resolve the error.
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// 4xx means client-side problem, retrying never resolves it except for some status codes.
+		switch apiErr.StatusCode {
+		case 408, 429:
+			return true
+		}
+		return apiErr.StatusCode < 400 || 500 <= apiErr.StatusCode
+	}
+	var reqErr *RequestError
+	return errors.As(err, &reqErr)
+}
@@ -0,0 +1,398 @@
+package integrationtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	dsps "github.com/m3dev/dsps/client/go"
+	dspshttp "github.com/m3dev/dsps/server/http"
+	. "github.com/m3dev/dsps/server/http/testing"
+	. "github.com/m3dev/dsps/server/jwt/testing"
+)
+
+func withClient(t *testing.T, configYaml string, cfg dsps.Config, f func(client *dsps.Client, deps *dspshttp.ServerDependencies)) {
+	WithServer(t, configYaml, func(deps *dspshttp.ServerDependencies) {}, func(deps *dspshttp.ServerDependencies, baseURL string) {
+		cfg.BaseURL = baseURL
+		client, err := dsps.NewClient(cfg)
+		if !assert.NoError(t, err) {
+			return
+		}
+		f(client, deps)
+	})
+}
+
+func TestPublishAndFetch(t *testing.T) {
+	ctx := context.Background()
+	withClient(t, `logging: category: "*": FATAL`, dsps.Config{}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		ch := client.Channel("my-channel")
+		assert.Equal(t, "my-channel", ch.ID())
+		assert.NoError(t, ch.CreateSubscriber(ctx, "sbsc-1"))
+
+		msg, err := ch.Publish(ctx, "msg-1", map[string]string{"hi": "hello"})
+		assert.NoError(t, err)
+		assert.Equal(t, dsps.Message{ChannelID: "my-channel", MessageID: "msg-1", Content: json.RawMessage(`{"hi":"hello"}`)}, msg)
+
+		msg, err = ch.Publish(ctx, "", "auto ID")
+		assert.NoError(t, err)
+		assert.Regexp(t, `^msg-[0-9a-f]{32}$`, msg.MessageID)
+
+		results, err := ch.PublishBatch(ctx, []dsps.Message{
+			{MessageID: "msg-3", Content: json.RawMessage(`3`)},
+			{MessageID: "** INVALID **", Content: json.RawMessage(`4`)},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []dsps.BatchPublishResult{
+			{MessageID: "msg-3", Status: "published"},
+			{MessageID: "** INVALID **", Status: "rejected", Error: "MessageID must match with ^[0-9a-z][0-9a-z_-]{0,62}$"},
+		}, results)
+
+		fetched, err := ch.Fetch(ctx, "sbsc-1", 2, 0)
+		assert.NoError(t, err)
+		assert.True(t, fetched.MoreMessages)
+		assert.NotEmpty(t, fetched.AckHandle)
+		if assert.Equal(t, 2, len(fetched.Messages)) {
+			assert.Equal(t, "msg-1", fetched.Messages[0].MessageID)
+			assert.JSONEq(t, `{"hi":"hello"}`, string(fetched.Messages[0].Content))
+			assert.JSONEq(t, `"auto ID"`, string(fetched.Messages[1].Content))
+		}
+		assert.NoError(t, ch.Acknowledge(ctx, "sbsc-1", fetched.AckHandle))
+
+		fetched, err = ch.Fetch(ctx, "sbsc-1", 2, 0)
+		assert.NoError(t, err)
+		assert.False(t, fetched.MoreMessages)
+		if assert.Equal(t, 1, len(fetched.Messages)) {
+			assert.Equal(t, "msg-3", fetched.Messages[0].MessageID)
+		}
+		assert.NoError(t, ch.Acknowledge(ctx, "sbsc-1", fetched.AckHandle))
+
+		// Long-polling
+		fetchStart := time.Now()
+		fetched, err = ch.Fetch(ctx, "sbsc-1", 2, 200*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(fetched.Messages))
+		assert.Empty(t, fetched.AckHandle)
+		assert.GreaterOrEqual(t, int64(time.Since(fetchStart)), int64(200*time.Millisecond))
+
+		assert.NoError(t, ch.DeleteSubscriber(ctx, "sbsc-1"))
+		_, err = ch.Fetch(ctx, "sbsc-1", 2, 0)
+		assert.True(t, errors.Is(err, dsps.ErrSubscriptionNotFound))
+		assert.True(t, dsps.IsSubscriptionUnrecoverable(err))
+	})
+}
+
+func TestPublishWithAttributes(t *testing.T) {
+	ctx := context.Background()
+	withClient(t, `logging: category: "*": FATAL`, dsps.Config{}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		ch := client.Channel("my-channel")
+		assert.NoError(t, ch.CreateSubscriber(ctx, "sbsc-1"))
+
+		msg, err := ch.PublishWithAttributes(ctx, "msg-1", "hi", map[string]string{"event-type": "created"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"event-type": "created"}, msg.Attributes)
+
+		results, err := ch.PublishBatch(ctx, []dsps.Message{
+			{MessageID: "msg-2", Content: json.RawMessage(`2`), Attributes: map[string]string{"event-type": "deleted"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "published", results[0].Status)
+
+		fetched, err := ch.Fetch(ctx, "sbsc-1", 10, 0)
+		assert.NoError(t, err)
+		if assert.Equal(t, 2, len(fetched.Messages)) {
+			assert.Equal(t, "my-channel", fetched.Messages[0].ChannelID)
+			assert.Equal(t, map[string]string{"event-type": "created"}, fetched.Messages[0].Attributes)
+			assert.Equal(t, map[string]string{"event-type": "deleted"}, fetched.Messages[1].Attributes)
+		}
+
+		_, err = ch.PublishWithAttributes(ctx, "msg-3", "hi", map[string]string{"INVALID!": "value"})
+		var apiErr *dsps.APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, 400, apiErr.StatusCode)
+		}
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	withClient(t, `logging: category: "*": FATAL`, dsps.Config{}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		ch := client.Channel("my-channel")
+
+		lock := sync.Mutex{}
+		received := []string{}
+		sbsc, err := ch.Subscribe(ctx, dsps.SubscribeOptions{
+			SubscriberID: "sbsc-1",
+			Callback: func(ctx context.Context, messages []dsps.Message) error {
+				lock.Lock()
+				defer lock.Unlock()
+				for _, msg := range messages {
+					received = append(received, msg.MessageID)
+				}
+				return nil
+			},
+			AbnormalEndCallback: func(err error) {
+				assert.Fail(t, "unexpected abnormal end", err)
+			},
+			BulkSize:           2,
+			LongPollingTimeout: 1 * time.Second,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "my-channel", sbsc.ChannelID())
+		assert.Equal(t, "sbsc-1", sbsc.SubscriberID())
+
+		for _, id := range []string{"msg-1", "msg-2", "msg-3"} {
+			_, err := ch.Publish(ctx, id, id)
+			assert.NoError(t, err)
+		}
+		assert.Eventually(t, func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(received) >= 3
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-1", "msg-2", "msg-3"}, received)
+
+		// Messages should have been acknowledged
+		assert.Eventually(t, func() bool {
+			fetched, err := ch.Fetch(ctx, "sbsc-1", 10, 0)
+			return err == nil && len(fetched.Messages) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		closeStart := time.Now()
+		assert.NoError(t, sbsc.Close(ctx))
+		assert.NoError(t, sbsc.Close(ctx))
+		assert.Less(t, int64(time.Since(closeStart)), int64(500*time.Millisecond), "should cancel long-polling")
+		_, err = ch.Fetch(ctx, "sbsc-1", 2, 0)
+		assert.True(t, errors.Is(err, dsps.ErrSubscriptionNotFound))
+	})
+}
+
+func TestSubscribeWithFilter(t *testing.T) {
+	ctx := context.Background()
+	withClient(t, `logging: category: "*": FATAL`, dsps.Config{}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		ch := client.Channel("my-channel")
+		received := make(chan dsps.Message, 10)
+		s, err := ch.Subscribe(ctx, dsps.SubscribeOptions{
+			Filter: &dsps.SubscriberFilter{
+				Attributes: map[string]string{"event-type": "created"},
+				Content:    map[string]interface{}{"user.id": 1},
+			},
+			Callback: func(ctx context.Context, messages []dsps.Message) error {
+				for _, msg := range messages {
+					received <- msg
+				}
+				return nil
+			},
+			AbnormalEndCallback: func(err error) { assert.NoError(t, err) },
+			LongPollingTimeout:  100 * time.Millisecond,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer func() { assert.NoError(t, s.Close(ctx)) }()
+
+		for _, msg := range []struct {
+			id        string
+			eventType string
+			userID    int
+		}{{"msg-1", "deleted", 1}, {"msg-2", "created", 2}, {"msg-3", "created", 1}} {
+			_, err := ch.PublishWithAttributes(ctx, msg.id, map[string]interface{}{"user": map[string]int{"id": msg.userID}}, map[string]string{"event-type": msg.eventType})
+			assert.NoError(t, err)
+		}
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "msg-3", msg.MessageID)
+		case <-time.After(3 * time.Second):
+			assert.Fail(t, "message not received")
+		}
+	})
+}
+
+func TestSubscribeAbnormalEnd(t *testing.T) {
+	ctx := context.Background()
+	withClient(t, `logging: category: "*": FATAL`, dsps.Config{}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		ch := client.Channel("my-channel")
+		abnormalEnd := make(chan error, 1)
+		sbsc, err := ch.Subscribe(ctx, dsps.SubscribeOptions{
+			Callback: func(ctx context.Context, messages []dsps.Message) error { return nil },
+			AbnormalEndCallback: func(err error) {
+				abnormalEnd <- err
+			},
+			ShortPolling:    true,
+			PollingInterval: 10 * time.Millisecond,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Regexp(t, `^s-[0-9a-f]{32}$`, sbsc.SubscriberID())
+
+		assert.NoError(t, ch.DeleteSubscriber(ctx, sbsc.SubscriberID()))
+		select {
+		case err := <-abnormalEnd:
+			assert.True(t, errors.Is(err, dsps.ErrSubscriptionNotFound))
+		case <-time.After(3 * time.Second):
+			assert.Fail(t, "AbnormalEndCallback not called")
+		}
+	})
+}
+
+func TestSubscriptionCallbackError(t *testing.T) {
+	ctx := context.Background()
+	callbackErrors := make(chan dsps.SubscriptionCallbackErrorInfo, 1)
+	withClient(t, `logging: category: "*": FATAL`, dsps.Config{
+		OnSubscriptionCallbackError: func(info dsps.SubscriptionCallbackErrorInfo) {
+			callbackErrors <- info
+		},
+	}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		ch := client.Channel("my-channel")
+		sbsc, err := ch.Subscribe(ctx, dsps.SubscribeOptions{
+			SubscriberID: "sbsc-1",
+			Callback: func(ctx context.Context, messages []dsps.Message) error {
+				return errors.New("callback error")
+			},
+			AbnormalEndCallback: func(err error) {},
+			LongPollingTimeout:  1 * time.Second,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer func() { assert.NoError(t, sbsc.Close(ctx)) }()
+
+		_, err = ch.Publish(ctx, "msg-1", "hi")
+		assert.NoError(t, err)
+		select {
+		case info := <-callbackErrors:
+			assert.EqualError(t, info.Err, "callback error")
+			assert.Equal(t, "my-channel", info.ChannelID)
+			assert.Equal(t, "sbsc-1", info.SubscriberID)
+			assert.Equal(t, 1, len(info.Messages))
+		case <-time.After(3 * time.Second):
+			assert.Fail(t, "OnSubscriptionCallbackError not called")
+		}
+	})
+}
+
+func TestJWT(t *testing.T) {
+	ctx := context.Background()
+	config := `
+logging: category: "*": FATAL
+channels:
+	-
+		regex: '.+'
+		jwt:
+			iss: [ "https://issuer.example.com/issuer-url" ]
+			keys:
+				RS256: [ "../../../server/jwt/testdata/RS256-2048bit-public.pem" ]
+`
+	apiErrors := make(chan error, 8)
+	withClient(t, config, dsps.Config{
+		JWT:         "invalid-jwt",
+		OnAPIFailed: func(err error) { apiErrors <- err },
+		APIRetry:    &dsps.RetryConfig{Count: 0, IntervalMultiplier: 1},
+	}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		_, err := client.Channel("my-channel").Publish(ctx, "msg-1", "hi")
+		assert.True(t, errors.Is(err, dsps.ErrAuthRejected))
+		assert.True(t, dsps.IsSubscriptionUnrecoverable(err))
+		assert.Regexp(t, `DSPS API PUT /channel/my-channel/message/msg-1 returned unexpected status 403: .+ \(code: dsps.auth.rejected\)`, err.Error())
+
+		var apiErr *dsps.APIError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, 403, apiErr.StatusCode)
+		assert.Equal(t, err, <-apiErrors)
+	})
+
+	validJwt := GenerateJwt(t, JwtProps{
+		Alg:     "RS256",
+		Keyname: "RS256-2048bit",
+		JwtDir:  "../../../server/jwt",
+		Iss:     "https://issuer.example.com/issuer-url",
+	})
+	withClient(t, config, dsps.Config{JWT: validJwt}, func(client *dsps.Client, deps *dspshttp.ServerDependencies) {
+		_, err := client.Channel("my-channel").Publish(ctx, "msg-1", "hi")
+		assert.NoError(t, err)
+	})
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	lock := sync.Mutex{}
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		attempts++
+		assert.Equal(t, "Bearer my-jwt", r.Header.Get("Authorization"))
+		assert.Equal(t, "my-value", r.Header.Get("X-My-Header"))
+		if r.URL.Path == "/channel/ch-1/message/not-retried" {
+			w.WriteHeader(400)
+			return
+		}
+		if attempts <= 2 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := dsps.NewClient(dsps.Config{
+		BaseURL: server.URL,
+		Headers: map[string]string{"X-My-Header": "my-value"},
+		JWT:     "my-jwt",
+		APIRetry: &dsps.RetryConfig{
+			Count:              2,
+			Interval:           1 * time.Millisecond,
+			IntervalMultiplier: 1.0,
+		},
+	})
+	assert.NoError(t, err)
+	_, err = client.Channel("ch-1").Publish(ctx, "msg-1", "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// Should not retry 4xx
+	attempts = 0
+	_, err = client.Channel("ch-1").Publish(ctx, "not-retried", "hi")
+	var apiErr *dsps.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 400, apiErr.StatusCode)
+	assert.Equal(t, "", apiErr.Code)
+	assert.Equal(t, 1, attempts)
+
+	// Gives up after retries
+	attempts = -10
+	_, err = client.Channel("ch-1").Publish(ctx, "msg-1", "hi")
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 503, apiErr.StatusCode)
+	assert.Equal(t, -7, attempts)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := dsps.NewClient(dsps.Config{})
+	assert.EqualError(t, err, "BaseURL is required")
+
+	_, err = dsps.NewClient(dsps.Config{BaseURL: "http://localhost:3000", APIRetry: &dsps.RetryConfig{Count: -1, IntervalMultiplier: 1}})
+	assert.EqualError(t, err, "APIRetry.Count must not be negative: -1")
+
+	_, err = dsps.NewClient(dsps.Config{BaseURL: "http://localhost:3000", APIRetry: &dsps.RetryConfig{Count: 1, IntervalMultiplier: 0.5}})
+	assert.Regexp(t, `APIRetry.IntervalMultiplier must be equal to or larger than 1.0`, err.Error())
+
+	client, err := dsps.NewClient(dsps.Config{BaseURL: "http://localhost:3000"})
+	assert.NoError(t, err)
+	_, err = client.Channel("ch-1").Subscribe(context.Background(), dsps.SubscribeOptions{})
+	assert.EqualError(t, err, "SubscribeOptions.Callback is required")
+	_, err = client.Channel("ch-1").Subscribe(context.Background(), dsps.SubscribeOptions{
+		Callback: func(ctx context.Context, messages []dsps.Message) error { return nil },
+	})
+	assert.EqualError(t, err, "SubscribeOptions.AbnormalEndCallback is required")
+}
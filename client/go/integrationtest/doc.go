@@ -0,0 +1,4 @@
+// Package integrationtest tests the DSPS Go client against an in-process DSPS server.
+//
+// This is a separate module so that the client module does not depend on the server module.
+package integrationtest
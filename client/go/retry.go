@@ -0,0 +1,84 @@
+package dsps
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryConfig is configuration of API retry.
+type RetryConfig struct {
+	// Max count of retries, 0 to disable retry.
+	Count int
+	// Base interval of retries.
+	Interval time.Duration
+	// Exponential backoff factor, multiply to the previous interval.
+	IntervalMultiplier float64
+	// Max range of the retry interval randomization, plus or minus to the resulted interval.
+	IntervalJitter time.Duration
+}
+
+// DefaultAPIRetry is default value of Config.APIRetry.
+//
+// Retry intervals before each retry:
+//
+//	1st retry: 1.0 * 1.5^1 ± 0.5 = 1.0  to 2.0  sec
+//	2nd retry: 1.0 * 1.5^2 ± 0.5 = 1.75 to 2.75 sec
+//	3rd retry: 1.0 * 1.5^3 ± 0.5 = 2.88 to 3.88 sec
+var DefaultAPIRetry = RetryConfig{
+	Count:              3,
+	Interval:           1 * time.Second,
+	IntervalMultiplier: 1.5,
+	IntervalJitter:     500 * time.Millisecond,
+}
+
+func (cfg RetryConfig) validate() error {
+	if cfg.Count < 0 {
+		return fmt.Errorf("APIRetry.Count must not be negative: %d", cfg.Count)
+	}
+	if cfg.Interval < 0 {
+		return fmt.Errorf("APIRetry.Interval must not be negative: %v", cfg.Interval)
+	}
+	if cfg.IntervalMultiplier < 1.0 {
+		return fmt.Errorf("APIRetry.IntervalMultiplier must be equal to or larger than 1.0: %f", cfg.IntervalMultiplier)
+	}
+	if cfg.IntervalJitter < 0 {
+		return fmt.Errorf("APIRetry.IntervalJitter must not be negative: %v", cfg.IntervalJitter)
+	}
+	return nil
+}
+
+type retry struct {
+	config RetryConfig
+}
+
+// Calls task until it succeeds or fails with non-retryable error.
+func (r retry) perform(ctx context.Context, task func() error) error {
+	for nextAttempt := 1; ; nextAttempt++ {
+		err := task()
+		if err == nil {
+			return nil
+		}
+		if nextAttempt > r.config.Count || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(r.interval(nextAttempt)):
+		}
+	}
+}
+
+func (r retry) interval(nextAttempt int) time.Duration {
+	base := float64(r.config.Interval) * math.Pow(r.config.IntervalMultiplier, float64(nextAttempt))
+	return withJitter(time.Duration(base), r.config.IntervalJitter)
+}
+
+// Returns d ± jitter, never returns negative value.
+func withJitter(d time.Duration, jitter time.Duration) time.Duration {
+	return time.Duration(math.Max(0, float64(d)+(rand.Float64()*2-1)*float64(jitter))) //nolint:gosec
+}
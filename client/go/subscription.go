@@ -0,0 +1,290 @@
+package dsps
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default values of SubscribeOptions.
+const (
+	DefaultPollingBulkSize            = 32
+	DefaultLongPollingTimeout         = 30 * time.Second
+	DefaultLongPollingInterval        = 50 * time.Millisecond
+	DefaultLongPollingIntervalJitter  = 100 * time.Millisecond
+	DefaultShortPollingInterval       = 5 * time.Second
+	DefaultShortPollingIntervalJitter = 500 * time.Millisecond
+	DefaultPollingPagingInterval      = 50 * time.Millisecond
+	DefaultPollingErrorInterval       = 5 * time.Second
+	DefaultPollingErrorIntervalJitter = 2500 * time.Millisecond
+	dedupWindowSizeMultiplier         = 3 // Must be equal to or larger than 1
+)
+
+// SubscribeOptions is parameters of Channel.Subscribe.
+type SubscribeOptions struct {
+	// If empty, automatically generate ID.
+	SubscriberID string
+
+	// (Required) Called when messages received.
+	// Messages are acknowledged (removed from the subscriber) after this callback returns, even if it returns error.
+	// To catch errors, use Config.OnSubscriptionCallbackError.
+	Callback func(ctx context.Context, messages []Message) error
+
+	// (Required) Called when subscription abnormally aborted (e.g. subscriber expired, JWT rejected).
+	// Subscription loop has been stopped when called, you should handle this error (e.g. re-subscribe).
+	AbnormalEndCallback func(err error)
+
+	// Receive only messages matched with the filter, nil to receive all messages.
+	Filter *SubscriberFilter
+
+	// Size of bulk message fetch (no guarantee, could receive more or less for each callback), default is DefaultPollingBulkSize.
+	BulkSize int
+
+	// Set true to use short-polling rather than long-polling.
+	ShortPolling bool
+	// Timeout of long-polling, default is DefaultLongPollingTimeout.
+	LongPollingTimeout time.Duration
+
+	// Interval of polling, default is DefaultLongPollingInterval or DefaultShortPollingInterval.
+	// For long-polling, recommend to use smaller value for best response time.
+	PollingInterval time.Duration
+	// Random offset to add or minus from PollingInterval, default is DefaultLongPollingIntervalJitter or DefaultShortPollingIntervalJitter.
+	PollingIntervalJitter time.Duration
+	// Interval just after received one or more messages and server has more messages, default is DefaultPollingPagingInterval.
+	PollingPagingInterval time.Duration
+	// Interval after polling API failure, default is DefaultPollingErrorInterval.
+	PollingErrorInterval time.Duration
+	// Random value to add/minus from PollingErrorInterval, default is DefaultPollingErrorIntervalJitter.
+	PollingErrorIntervalJitter time.Duration
+}
+
+// SubscriptionCallbackErrorInfo describes error returned by SubscribeOptions.Callback.
+type SubscriptionCallbackErrorInfo struct {
+	Err error
+
+	Messages     []Message
+	ChannelID    string
+	SubscriberID string
+}
+
+// Subscription is a running subscription loop.
+type Subscription struct {
+	channel *Channel
+	opts    SubscribeOptions
+	dedup   *dedup
+
+	cancel    context.CancelFunc
+	loopEnded chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Subscribe creates polling subscriber and starts subscription loop in background.
+// To stop the loop and delete the subscriber, call Subscription.Close.
+func (ch *Channel) Subscribe(ctx context.Context, opts SubscribeOptions) (*Subscription, error) {
+	if err := opts.fillDefaults(); err != nil {
+		return nil, err
+	}
+	if err := ch.CreateSubscriberWithFilter(ctx, opts.SubscriberID, opts.Filter); err != nil {
+		return nil, err
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	s := &Subscription{
+		channel: ch,
+		opts:    opts,
+		dedup:   &dedup{windowSize: opts.BulkSize * dedupWindowSizeMultiplier},
+
+		cancel:    cancel,
+		loopEnded: make(chan struct{}),
+	}
+	go s.loop(loopCtx)
+	return s, nil
+}
+
+func (opts *SubscribeOptions) fillDefaults() error {
+	if opts.Callback == nil {
+		return fmt.Errorf("SubscribeOptions.Callback is required")
+	}
+	if opts.AbnormalEndCallback == nil {
+		return fmt.Errorf("SubscribeOptions.AbnormalEndCallback is required")
+	}
+	if opts.SubscriberID == "" {
+		opts.SubscriberID = generateID("s-")
+	}
+	if opts.BulkSize == 0 {
+		opts.BulkSize = DefaultPollingBulkSize
+	}
+	if opts.BulkSize < 0 {
+		return fmt.Errorf("SubscribeOptions.BulkSize must be larger than zero but %d given", opts.BulkSize)
+	}
+	if opts.ShortPolling {
+		opts.LongPollingTimeout = 0
+		if opts.PollingInterval == 0 {
+			opts.PollingInterval = DefaultShortPollingInterval
+		}
+		if opts.PollingIntervalJitter == 0 {
+			opts.PollingIntervalJitter = DefaultShortPollingIntervalJitter
+		}
+	} else {
+		if opts.LongPollingTimeout == 0 {
+			opts.LongPollingTimeout = DefaultLongPollingTimeout
+		}
+		if opts.PollingInterval == 0 {
+			opts.PollingInterval = DefaultLongPollingInterval
+		}
+		if opts.PollingIntervalJitter == 0 {
+			opts.PollingIntervalJitter = DefaultLongPollingIntervalJitter
+		}
+	}
+	if opts.PollingPagingInterval == 0 {
+		opts.PollingPagingInterval = DefaultPollingPagingInterval
+	}
+	if opts.PollingErrorInterval == 0 {
+		opts.PollingErrorInterval = DefaultPollingErrorInterval
+	}
+	if opts.PollingErrorIntervalJitter == 0 {
+		opts.PollingErrorIntervalJitter = DefaultPollingErrorIntervalJitter
+	}
+	return nil
+}
+
+// ChannelID returns ID of the subscribing channel.
+func (s *Subscription) ChannelID() string {
+	return s.channel.channelID
+}
+
+// SubscriberID returns ID of the subscriber.
+func (s *Subscription) SubscriberID() string {
+	return s.opts.SubscriberID
+}
+
+// Close stops subscription loop and deletes the subscriber.
+// This method blocks until running callback returns.
+func (s *Subscription) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		select {
+		case <-s.loopEnded:
+		case <-ctx.Done():
+			s.closeErr = ctx.Err()
+			return
+		}
+		s.closeErr = s.channel.DeleteSubscriber(ctx, s.opts.SubscriberID)
+	})
+	return s.closeErr
+}
+
+type pollingIntervalMode int
+
+const (
+	pollingNoMessages pollingIntervalMode = iota
+	pollingNoMoreMessages
+	pollingPaginated
+	pollingError
+)
+
+func (s *Subscription) loop(ctx context.Context) {
+	defer close(s.loopEnded)
+	for ctx.Err() == nil {
+		mode, err := s.poll(ctx)
+		if ctx.Err() != nil {
+			return // Closed
+		}
+		if IsSubscriptionUnrecoverable(err) {
+			s.opts.AbnormalEndCallback(err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.interval(mode)):
+		}
+	}
+}
+
+func (s *Subscription) poll(ctx context.Context) (pollingIntervalMode, error) {
+	fetchStartAt := time.Now()
+	result, err := s.channel.Fetch(ctx, s.opts.SubscriberID, s.opts.BulkSize, s.opts.LongPollingTimeout)
+	if err != nil {
+		return pollingError, err
+	}
+
+	var mode pollingIntervalMode
+	switch {
+	case len(result.Messages) == 0:
+		mode = pollingNoMessages
+		if fetchDuration := time.Since(fetchStartAt); !s.opts.ShortPolling && fetchDuration < s.opts.LongPollingTimeout {
+			// Server returned empty messages without waiting timeout (why?), sleep to prevent massive API call.
+			select {
+			case <-ctx.Done():
+			case <-time.After(s.opts.LongPollingTimeout - fetchDuration):
+			}
+		}
+		return mode, nil
+	case result.MoreMessages:
+		mode = pollingPaginated
+	default:
+		mode = pollingNoMoreMessages
+	}
+
+	// After acknowledge failure, server returns same messages again.
+	if msgs := s.dedup.filter(result.Messages); len(msgs) > 0 {
+		if err := s.opts.Callback(ctx, msgs); err != nil && s.channel.client.onSubscriptionCallbackError != nil {
+			s.channel.client.onSubscriptionCallbackError(SubscriptionCallbackErrorInfo{
+				Err:          err,
+				Messages:     msgs,
+				ChannelID:    s.channel.channelID,
+				SubscriberID: s.opts.SubscriberID,
+			})
+		}
+	}
+
+	// Should acknowledge even if closed, otherwise receive same messages next time.
+	if err := s.channel.Acknowledge(context.Background(), s.opts.SubscriberID, result.AckHandle); err != nil {
+		// Retry fetch + ack in next loop, use longer interval.
+		if IsSubscriptionUnrecoverable(err) {
+			return pollingError, err
+		}
+		return pollingError, nil
+	}
+	return mode, nil
+}
+
+func (s *Subscription) interval(mode pollingIntervalMode) time.Duration {
+	switch mode {
+	case pollingError:
+		return withJitter(s.opts.PollingErrorInterval, s.opts.PollingErrorIntervalJitter)
+	case pollingPaginated:
+		return s.opts.PollingPagingInterval
+	default:
+		return withJitter(s.opts.PollingInterval, s.opts.PollingIntervalJitter)
+	}
+}
+
+type dedup struct {
+	windowSize int
+	ids        []string
+}
+
+func (d *dedup) filter(msgs []Message) []Message {
+	result := make([]Message, 0, len(msgs))
+	seen := make(map[string]bool, len(d.ids))
+	for _, id := range d.ids {
+		seen[id] = true
+	}
+	for _, msg := range msgs {
+		if seen[msg.MessageID] {
+			continue
+		}
+		seen[msg.MessageID] = true
+		d.ids = append(d.ids, msg.MessageID)
+		result = append(result, msg)
+	}
+	if len(d.ids) > d.windowSize {
+		d.ids = d.ids[len(d.ids)-d.windowSize:]
+	}
+	return result
+}
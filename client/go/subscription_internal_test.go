@@ -0,0 +1,36 @@
+package dsps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedup(t *testing.T) {
+	msgs := func(ids ...string) []Message {
+		result := make([]Message, len(ids))
+		for i, id := range ids {
+			result[i] = Message{MessageID: id}
+		}
+		return result
+	}
+	d := &dedup{windowSize: 3}
+	assert.Equal(t, msgs("a", "b"), d.filter(msgs("a", "b", "a")))
+	assert.Equal(t, msgs("c"), d.filter(msgs("a", "b", "c")))
+	assert.Equal(t, msgs("d"), d.filter(msgs("c", "d")))
+	assert.Equal(t, msgs("a"), d.filter(msgs("a")), "should forget old IDs")
+}
+
+func TestRetryInterval(t *testing.T) {
+	r := retry{config: RetryConfig{Interval: 1 * time.Second, IntervalMultiplier: 1.5, IntervalJitter: 0}}
+	assert.Equal(t, 1500*time.Millisecond, r.interval(1))
+	assert.Equal(t, 2250*time.Millisecond, r.interval(2))
+
+	r.config.IntervalJitter = 100 * time.Millisecond
+	for i := 0; i < 10; i++ {
+		assert.InDelta(t, float64(1500*time.Millisecond), float64(r.interval(1)), float64(100*time.Millisecond))
+	}
+	assert.Equal(t, time.Duration(0), withJitter(0, 0))
+	assert.GreaterOrEqual(t, int64(withJitter(0, 1*time.Second)), int64(0))
+}
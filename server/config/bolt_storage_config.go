@@ -0,0 +1,35 @@
+package config
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// BoltStorageConfig is definition of "storage.bolt" configuration
+type BoltStorageConfig struct {
+	Path string `json:"path"`
+
+	DisablePubSub bool `json:"disablePubSub"`
+	DisableJwt    bool `json:"disableJwt"`
+
+	GCInterval  *domain.Duration `json:"gcInterval"`
+	OpenTimeout *domain.Duration `json:"openTimeout"`
+}
+
+func postprocessBoltSubStorageConfig(config *BoltStorageConfig) error {
+	if config.Path == "" {
+		return xerrors.New("Bolt storage configration requires 'path' of the database file")
+	}
+
+	if config.GCInterval == nil {
+		config.GCInterval = makeDurationPtr("5m")
+	}
+	if config.GCInterval.Duration <= 0 {
+		return xerrors.New("Bolt storage configration 'gcInterval' must be positive duration")
+	}
+	if config.OpenTimeout == nil {
+		config.OpenTimeout = makeDurationPtr("5s")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package config_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/config"
+	. "github.com/m3dev/dsps/server/testing"
+)
+
+func TestBoltDefaultConfig(t *testing.T) {
+	configYaml := strings.ReplaceAll(`
+storages:
+	myBolt:
+		bolt:
+			path: '/var/lib/dsps/dsps.db'
+`, "\t", "  ")
+	config, err := ParseConfig(context.Background(), Overrides{}, configYaml)
+	assert.NoError(t, err)
+
+	cfg := *config.Storages["myBolt"].Bolt
+	assert.Equal(t, "/var/lib/dsps/dsps.db", cfg.Path)
+	assert.False(t, cfg.DisablePubSub)
+	assert.False(t, cfg.DisableJwt)
+	assert.Equal(t, MakeDuration("5m"), *cfg.GCInterval)
+	assert.Equal(t, MakeDuration("5s"), *cfg.OpenTimeout)
+}
+
+func TestBoltConfig(t *testing.T) {
+	configYaml := strings.ReplaceAll(`
+storages:
+	myBolt:
+		bolt:
+			path: '/var/lib/dsps/dsps.db'
+			disablePubSub: true
+			disableJwt: true
+			gcInterval: 1m
+			openTimeout: 10s
+`, "\t", "  ")
+	config, err := ParseConfig(context.Background(), Overrides{}, configYaml)
+	assert.NoError(t, err)
+
+	cfg := *config.Storages["myBolt"].Bolt
+	assert.True(t, cfg.DisablePubSub)
+	assert.True(t, cfg.DisableJwt)
+	assert.Equal(t, MakeDuration("1m"), *cfg.GCInterval)
+	assert.Equal(t, MakeDuration("10s"), *cfg.OpenTimeout)
+}
+
+func TestBoltConfigError(t *testing.T) {
+	_, err := ParseConfig(context.Background(), Overrides{}, `storages: { myBolt: { bolt: {} } }`)
+	assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myBolt].bolt: Bolt storage configration requires 'path' of the database file")
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `storages: { myBolt: { bolt: { path: 'dsps.db', gcInterval: 0s } } }`)
+	assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myBolt].bolt: Bolt storage configration 'gcInterval' must be positive duration")
+}
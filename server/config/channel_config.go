@@ -13,7 +13,8 @@ var channlesConfigDefault = ChannelsConfig{
 	},
 }
 var channelConfigDefaults = ChannelConfig{
-	Expire: makeDurationPtr("30m"),
+	Expire:          makeDurationPtr("30m"),
+	BacklogOverflow: domain.BacklogOverflowReject,
 }
 
 // ChannelsConfig is list of configured channels
@@ -24,8 +25,13 @@ type ChannelConfig struct {
 	Regex  *domain.Regex    `json:"regex"`
 	Expire *domain.Duration `json:"expire"`
 
-	Webhooks []OutgoingWebhookConfig `json:"webhooks"`
-	Jwt      *JwtValidationConfig    `json:"jwt"`
+	MaxMessageBytes int                    `json:"maxMessageBytes"`
+	MaxBacklog      int                    `json:"maxBacklog"`
+	BacklogOverflow domain.BacklogOverflow `json:"backlogOverflow"`
+
+	Webhooks  []OutgoingWebhookConfig `json:"webhooks"`
+	Jwt       *JwtValidationConfig    `json:"jwt"`
+	RateLimit *RateLimitConfig        `json:"rateLimit"`
 }
 
 // PostprocessChannelsConfig fixes/validates config
@@ -50,6 +56,20 @@ func postprocessChanelConfig(ch *ChannelConfig) error {
 		return err
 	}
 
+	if ch.MaxMessageBytes < 0 {
+		return fmt.Errorf("maxMessageBytes must not be negative")
+	}
+	if ch.MaxBacklog < 0 {
+		return fmt.Errorf("maxBacklog must not be negative")
+	}
+	switch ch.BacklogOverflow {
+	case "":
+		ch.BacklogOverflow = channelConfigDefaults.BacklogOverflow
+	case domain.BacklogOverflowReject, domain.BacklogOverflowEvict:
+	default:
+		return fmt.Errorf(`backlogOverflow must be "%s" or "%s" but got "%s"`, domain.BacklogOverflowReject, domain.BacklogOverflowEvict, ch.BacklogOverflow)
+	}
+
 	for i := range ch.Webhooks {
 		webhook := &ch.Webhooks[i]
 		if err := postprocessWebhookConfig(webhook); err != nil {
@@ -61,5 +81,10 @@ func postprocessChanelConfig(ch *ChannelConfig) error {
 			return fmt.Errorf("error on JWT config: %w", err)
 		}
 	}
+	if ch.RateLimit != nil {
+		if err := postprocessRateLimitConfig(ch.RateLimit); err != nil {
+			return fmt.Errorf("error on rateLimit config: %w", err)
+		}
+	}
 	return nil
 }
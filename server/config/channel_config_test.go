@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	. "github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
 	. "github.com/m3dev/dsps/server/testing"
 )
 
@@ -27,6 +28,10 @@ channels:
 	assert.Equal(t, "chat-room-(?P<id>\\d+)", cfg.Regex.String())
 	assert.Equal(t, MakeDurationPtr("30m"), cfg.Expire)
 
+	assert.Equal(t, 0, cfg.MaxMessageBytes)
+	assert.Equal(t, 0, cfg.MaxBacklog)
+	assert.Equal(t, domain.BacklogOverflowReject, cfg.BacklogOverflow)
+
 	assert.Equal(t, 0, len(cfg.Webhooks))
 	assert.Nil(t, cfg.Jwt)
 }
@@ -38,6 +43,9 @@ channels:
 	regex: 'chat-room-(?P<id>\d+)'
 	# Must be larger than final retry attempt time
 	expire: 15m
+	maxMessageBytes: 4096
+	maxBacklog: 1000
+	backlogOverflow: evict
 `, "\t", "  ")
 	config, err := ParseConfig(context.Background(), Overrides{}, configYaml)
 	if err != nil {
@@ -48,4 +56,20 @@ channels:
 	cfg := config.Channels[0]
 	assert.Equal(t, "chat-room-(?P<id>\\d+)", cfg.Regex.String())
 	assert.Equal(t, MakeDurationPtr("15m"), cfg.Expire)
+	assert.Equal(t, 4096, cfg.MaxMessageBytes)
+	assert.Equal(t, 1000, cfg.MaxBacklog)
+	assert.Equal(t, domain.BacklogOverflowEvict, cfg.BacklogOverflow)
+}
+
+func TestChannelConfigError(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`maxMessageBytes: -1`:      `maxMessageBytes must not be negative`,
+		`maxBacklog: -1`:           `maxBacklog must not be negative`,
+		`backlogOverflow: "block"`: `backlogOverflow must be "reject" or "evict" but got "block"`,
+	} {
+		_, err := ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', `+yaml+` } ]`)
+		if assert.Error(t, err, yaml) {
+			assert.Equal(t, `Channel configration problem: error on channels[0]: `+expected, err.Error())
+		}
+	}
 }
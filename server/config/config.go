@@ -31,14 +31,15 @@ type Overrides struct {
 
 // ServerConfig represents parsed/post-processed configuration.
 type ServerConfig struct {
-	BuildInfo  *BuildInfo        `json:"__buildInfo"`
-	Storages   StoragesConfig    `json:"storages"`
-	HTTPServer *HTTPServerConfig `json:"http"`
-	Logging    *LoggingConfig    `json:"logging"`
-	Telemetry  *TelemetryConfig  `json:"telemetry"`
-	Sentry     *SentryConfig     `json:"sentry"`
-	Channels   ChannelsConfig    `json:"channels"`
-	Admin      *AdminConfig      `json:"admin"`
+	BuildInfo          *BuildInfo                `json:"__buildInfo"`
+	Storages           StoragesConfig            `json:"storages"`
+	StorageMultiplexer *StorageMultiplexerConfig `json:"storageMultiplexer"`
+	HTTPServer         *HTTPServerConfig         `json:"http"`
+	Logging            *LoggingConfig            `json:"logging"`
+	Telemetry          *TelemetryConfig          `json:"telemetry"`
+	Sentry             *SentryConfig             `json:"sentry"`
+	Channels           ChannelsConfig            `json:"channels"`
+	Admin              *AdminConfig              `json:"admin"`
 }
 
 // BuildInfo represents compile time metadata.
@@ -70,13 +71,14 @@ func LoadConfigFile(ctx context.Context, configFile string, configOverrides Over
 // ParseConfig constructs post-processed configuration object.
 func ParseConfig(ctx context.Context, overrides Overrides, yaml string) (ServerConfig, error) {
 	config := ServerConfig{
-		BuildInfo:  parseBuildInfo(overrides),
-		Storages:   DefaultStoragesConfig(),
-		Logging:    loggingConfigDefault(),
-		Telemetry:  tracingConfigDefault(),
-		Sentry:     DefaultSentryConfig(),
-		HTTPServer: httpServerConfigDefault(),
-		Admin:      adminConfigDefault(),
+		BuildInfo:          parseBuildInfo(overrides),
+		Storages:           DefaultStoragesConfig(),
+		StorageMultiplexer: storageMultiplexerConfigDefault(),
+		Logging:            loggingConfigDefault(),
+		Telemetry:          tracingConfigDefault(),
+		Sentry:             DefaultSentryConfig(),
+		HTTPServer:         httpServerConfigDefault(),
+		Admin:              adminConfigDefault(),
 	}
 
 	if strings.Contains(yaml, "\t") {
@@ -95,6 +97,9 @@ func ParseConfig(ctx context.Context, overrides Overrides, yaml string) (ServerC
 	if err := PostprocessStorageConfig(&config.Storages); err != nil {
 		return config, fmt.Errorf("Storage configration problem: %w", err)
 	}
+	if err := PostprocessStorageMultiplexerConfig(config.StorageMultiplexer, config.Storages); err != nil {
+		return config, fmt.Errorf("Storage multiplexer configration problem: %w", err)
+	}
 	if err := PostprocessHTTPServerConfig(config.HTTPServer, overrides); err != nil {
 		return config, fmt.Errorf("HTTP server configration problem: %w", err)
 	}
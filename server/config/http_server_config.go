@@ -22,6 +22,30 @@ type HTTPServerConfig struct {
 	WriteTimeout            domain.Duration `json:"writeTimeout"`
 	LongPollingMaxTimeout   domain.Duration `json:"longPollingMaxTimeout"`
 	GracefulShutdownTimeout domain.Duration `json:"gracefulShutdownTimeout"`
+
+	TLS HTTPServerTLSConfig `json:"tls"`
+	H2C bool                `json:"h2c"`
+}
+
+// HTTPServerTLSConfig represents TLS settings of HTTP webserver
+type HTTPServerTLSConfig struct {
+	CertFile       string          `json:"certFile"`
+	KeyFile        string          `json:"keyFile"`
+	ReloadInterval domain.Duration `json:"reloadInterval"`
+
+	ClientCAFile string `json:"clientCaFile"`
+	ClientAuth   string `json:"clientAuth"`
+}
+
+// Client certificate verification modes
+const (
+	HTTPServerClientAuthRequire  = "require"
+	HTTPServerClientAuthOptional = "optional"
+)
+
+// IsEnabled returns true if the server should serve HTTPS
+func (config HTTPServerTLSConfig) IsEnabled() bool {
+	return config.CertFile != ""
 }
 
 func httpServerConfigDefault() *HTTPServerConfig {
@@ -90,5 +114,41 @@ func PostprocessHTTPServerConfig(config *HTTPServerConfig, overrides Overrides)
 
 	// Remove "/" prefix and suffix
 	config.PathPrefix = strings.TrimPrefix(strings.TrimSuffix(config.PathPrefix, "/"), "/")
+
+	if err := postprocessHTTPServerTLSConfig(&config.TLS); err != nil {
+		return err
+	}
+	if config.H2C && config.TLS.IsEnabled() {
+		return fmt.Errorf("h2c (HTTP/2 over cleartext) cannot be used with TLS, HTTP/2 is automatically enabled on TLS")
+	}
+	return nil
+}
+
+func postprocessHTTPServerTLSConfig(config *HTTPServerTLSConfig) error {
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return fmt.Errorf("tls.certFile and tls.keyFile must be specified together")
+	}
+	if !config.IsEnabled() {
+		if config.ClientCAFile != "" || config.ClientAuth != "" {
+			return fmt.Errorf("tls.clientCaFile and tls.clientAuth require tls.certFile and tls.keyFile")
+		}
+		return nil
+	}
+
+	if config.ReloadInterval.Duration == 0 {
+		config.ReloadInterval = makeDuration("1m")
+	}
+	if config.ClientCAFile == "" {
+		if config.ClientAuth != "" {
+			return fmt.Errorf("tls.clientAuth requires tls.clientCaFile")
+		}
+		return nil
+	}
+	if config.ClientAuth == "" {
+		config.ClientAuth = HTTPServerClientAuthRequire
+	}
+	if config.ClientAuth != HTTPServerClientAuthRequire && config.ClientAuth != HTTPServerClientAuthOptional {
+		return fmt.Errorf(`tls.clientAuth must be "%s" or "%s" but got "%s"`, HTTPServerClientAuthRequire, HTTPServerClientAuthOptional, config.ClientAuth)
+	}
 	return nil
 }
@@ -64,6 +64,47 @@ http:
 	assert.Equal(t, 4*time.Second, cfg.GracefulShutdownTimeout.Duration)
 }
 
+func TestHttpServerTLSConfig(t *testing.T) {
+	config, err := ParseConfig(context.Background(), Overrides{}, `http: { tls: { certFile: "/etc/dsps/server.pem", keyFile: "/etc/dsps/server-key.pem" } }`)
+	assert.NoError(t, err)
+	assert.True(t, config.HTTPServer.TLS.IsEnabled())
+	assert.Equal(t, "/etc/dsps/server.pem", config.HTTPServer.TLS.CertFile)
+	assert.Equal(t, "/etc/dsps/server-key.pem", config.HTTPServer.TLS.KeyFile)
+	assert.Equal(t, 1*time.Minute, config.HTTPServer.TLS.ReloadInterval.Duration)
+	assert.Equal(t, "", config.HTTPServer.TLS.ClientAuth)
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `http: { tls: { certFile: "/etc/dsps/server.pem", keyFile: "/etc/dsps/server-key.pem", reloadInterval: 10s, clientCaFile: "/etc/dsps/client-ca.pem" } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, config.HTTPServer.TLS.ReloadInterval.Duration)
+	assert.Equal(t, "/etc/dsps/client-ca.pem", config.HTTPServer.TLS.ClientCAFile)
+	assert.Equal(t, HTTPServerClientAuthRequire, config.HTTPServer.TLS.ClientAuth)
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `http: { tls: { certFile: "/etc/dsps/server.pem", keyFile: "/etc/dsps/server-key.pem", clientCaFile: "/etc/dsps/client-ca.pem", clientAuth: optional } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, HTTPServerClientAuthOptional, config.HTTPServer.TLS.ClientAuth)
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `http: { h2c: true }`)
+	assert.NoError(t, err)
+	assert.False(t, config.HTTPServer.TLS.IsEnabled())
+	assert.True(t, config.HTTPServer.H2C)
+}
+
+func TestHttpServerTLSConfigError(t *testing.T) {
+	for yaml, expected := range map[string]string{
+		`http: { tls: { certFile: "/etc/dsps/server.pem" } }`:                                                           `tls.certFile and tls.keyFile must be specified together`,
+		`http: { tls: { keyFile: "/etc/dsps/server-key.pem" } }`:                                                        `tls.certFile and tls.keyFile must be specified together`,
+		`http: { tls: { clientCaFile: "/etc/dsps/client-ca.pem" } }`:                                                    `tls.clientCaFile and tls.clientAuth require tls.certFile and tls.keyFile`,
+		`http: { tls: { certFile: "/etc/dsps/server.pem", keyFile: "/etc/dsps/server-key.pem", clientAuth: require } }`: `tls.clientAuth requires tls.clientCaFile`,
+		`http: { tls: { certFile: "a.pem", keyFile: "b.pem", clientCaFile: "c.pem", clientAuth: always } }`:             `tls.clientAuth must be "require" or "optional" but got "always"`,
+		`http: { h2c: true, tls: { certFile: "/etc/dsps/server.pem", keyFile: "/etc/dsps/server-key.pem" } }`:           `h2c \(HTTP/2 over cleartext\) cannot be used with TLS`,
+	} {
+		_, err := ParseConfig(context.Background(), Overrides{}, yaml)
+		if assert.Error(t, err, yaml) {
+			assert.Regexp(t, `^HTTP server configration problem: `+expected, err.Error())
+		}
+	}
+}
+
 func TestHttpServerConfigOverride(t *testing.T) {
 	cfg := HTTPServerConfig{}
 	assert.NoError(t, PostprocessHTTPServerConfig(&cfg, Overrides{Port: 9876}))
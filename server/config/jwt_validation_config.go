@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/m3dev/dsps/server/domain"
 	jwtpkg "github.com/m3dev/dsps/server/jwt"
@@ -12,12 +13,21 @@ type JwtValidationConfig struct {
 	Iss  []domain.JwtIss            `json:"iss"`
 	Aud  []domain.JwtAud            `json:"aud"`
 	Keys map[domain.JwtAlg][]string `json:"keys"`
+	Jwks *JwksConfig                `json:"jwks"`
 
 	Claims map[string]domain.TemplateStrings `json:"claims"`
 
 	ClockSkewLeeway *domain.Duration `json:"clockSkewLeeway"`
 }
 
+// JwksConfig is configuration to fetch JWT verification keys from JWK Set URL
+type JwksConfig struct {
+	URL             string           `json:"url"`
+	Algs            []domain.JwtAlg  `json:"algs"`
+	RefreshInterval *domain.Duration `json:"refreshInterval"`
+	Timeout         *domain.Duration `json:"timeout"`
+}
+
 func postprocessJwtConfig(jwt *JwtValidationConfig) error {
 	if jwt.Claims == nil {
 		jwt.Claims = make(map[string]domain.TemplateStrings)
@@ -30,8 +40,11 @@ func postprocessJwtConfig(jwt *JwtValidationConfig) error {
 		return fmt.Errorf(`must supply one or more "iss" (issuer claim) list`)
 	}
 
-	if len(jwt.Keys) == 0 {
-		return fmt.Errorf(`must supply one or more "keys" (signing algorithm and keys) setting`)
+	if len(jwt.Keys) == 0 && jwt.Jwks == nil {
+		return fmt.Errorf(`must supply one or more "keys" (signing algorithm and keys) setting or "jwks" setting`)
+	}
+	if jwt.Keys == nil {
+		jwt.Keys = make(map[domain.JwtAlg][]string)
 	}
 	for alg, keyFiles := range jwt.Keys {
 		if err := jwtpkg.ValidateAlg(alg); err != nil {
@@ -48,5 +61,38 @@ func postprocessJwtConfig(jwt *JwtValidationConfig) error {
 			}
 		}
 	}
+	if jwt.Jwks != nil {
+		if err := postprocessJwksConfig(jwt.Jwks); err != nil {
+			return fmt.Errorf(`invalid "jwks" setting: %w`, err)
+		}
+	}
+	return nil
+}
+
+func postprocessJwksConfig(jwks *JwksConfig) error {
+	if len(jwks.Algs) == 0 {
+		jwks.Algs = []domain.JwtAlg{"RS256"}
+	}
+	if jwks.RefreshInterval == nil {
+		jwks.RefreshInterval = makeDurationPtr("15m")
+	}
+	if jwks.Timeout == nil {
+		jwks.Timeout = makeDurationPtr("10s")
+	}
+
+	if u, err := url.Parse(jwks.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf(`"url" must be a valid http(s) URL but given "%s"`, jwks.URL)
+	}
+	for _, alg := range jwks.Algs {
+		if !jwtpkg.IsRSA(alg) && !jwtpkg.IsECDSA(alg) {
+			return fmt.Errorf(`"algs" must contain only RSA or ECDSA signing algorithms but given "%s"`, alg)
+		}
+	}
+	if jwks.RefreshInterval.Duration <= 0 {
+		return fmt.Errorf(`"refreshInterval" must be positive duration`)
+	}
+	if jwks.Timeout.Duration <= 0 {
+		return fmt.Errorf(`"timeout" must be positive duration`)
+	}
 	return nil
 }
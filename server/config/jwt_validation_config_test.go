@@ -80,7 +80,7 @@ func TestJwtConfigError(t *testing.T) {
 	assert.Regexp(t, `must supply one or more "iss" \(issuer claim\) list`, err.Error())
 
 	_, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ] } } ]`)
-	assert.Regexp(t, `must supply one or more "keys" \(signing algorithm and keys\) setting`, err.Error())
+	assert.Regexp(t, `must supply one or more "keys" \(signing algorithm and keys\) setting or "jwks" setting`, err.Error())
 
 	_, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], keys: { INVALID: [ "../jwt/testdata/RS256-2048bit-public.pem" ] } } } ]`)
 	assert.Regexp(t, `invalid signing algorithm name given "INVALID"`, err.Error())
@@ -91,3 +91,33 @@ func TestJwtConfigError(t *testing.T) {
 	_, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], keys: { RS256: [ "/file/not/found" ] } } } ]`)
 	assert.Regexp(t, `failed to read JWT key file "/file/not/found"`, err.Error())
 }
+
+func TestJwksConfig(t *testing.T) {
+	config, err := ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], jwks: { url: "https://example.com/.well-known/jwks.json" } } } ]`)
+	assert.NoError(t, err)
+	jwks := config.Channels[0].Jwt.Jwks
+	assert.Equal(t, "https://example.com/.well-known/jwks.json", jwks.URL)
+	assert.Equal(t, []domain.JwtAlg{"RS256"}, jwks.Algs)
+	assert.Equal(t, MakeDurationPtr("15m"), jwks.RefreshInterval)
+	assert.Equal(t, MakeDurationPtr("10s"), jwks.Timeout)
+	assert.Equal(t, 0, len(config.Channels[0].Jwt.Keys))
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], jwks: { url: "http://localhost:8080/jwks", algs: [ "ES256", "PS256" ], refreshInterval: "1m", timeout: "3s" } } } ]`)
+	assert.NoError(t, err)
+	jwks = config.Channels[0].Jwt.Jwks
+	assert.Equal(t, []domain.JwtAlg{"ES256", "PS256"}, jwks.Algs)
+	assert.Equal(t, MakeDurationPtr("1m"), jwks.RefreshInterval)
+	assert.Equal(t, MakeDurationPtr("3s"), jwks.Timeout)
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], jwks: { url: "ftp://example.com/jwks" } } } ]`)
+	assert.Regexp(t, `invalid "jwks" setting: "url" must be a valid http\(s\) URL but given "ftp://example.com/jwks"`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], jwks: { url: "https://example.com/jwks", algs: [ "HS256" ] } } } ]`)
+	assert.Regexp(t, `"algs" must contain only RSA or ECDSA signing algorithms but given "HS256"`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], jwks: { url: "https://example.com/jwks", refreshInterval: "0s" } } } ]`)
+	assert.Regexp(t, `"refreshInterval" must be positive duration`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: '.+', jwt: { iss: [ "issuer1" ], jwks: { url: "https://example.com/jwks", timeout: "0s" } } } ]`)
+	assert.Regexp(t, `"timeout" must be positive duration`, err.Error())
+}
@@ -17,6 +17,8 @@ type OutgoingWebhookConfig struct {
 	Headers    map[string]domain.TemplateString `json:"headers"`
 
 	MaxRedirects *int `json:"maxRedirects"`
+
+	DeadLetterChannel *domain.TemplateString `json:"deadLetterChannel"`
 }
 
 var validWebhookMethods = map[string]interface{}{
@@ -39,6 +39,7 @@ channels:
 	assert.Equal(t, MakeDurationPtr("1.5s"), webhook.Retry.IntervalJitter)
 	assert.Equal(t, 0, len(webhook.Headers))
 	assert.Equal(t, 10, *cfg.Webhooks[0].MaxRedirects)
+	assert.Nil(t, webhook.DeadLetterChannel)
 }
 
 func TestWebhookFullConfig(t *testing.T) {
@@ -60,6 +61,7 @@ channels:
 				User-Agent: my DSPS server
 				X-Chat-Room-ID: '{{.channel.id}}'
 			maxRedirects: 123
+			deadLetterChannel: 'failed-webhooks-{{.channel.id}}'
 `, "\t", "  ")
 	config, err := ParseConfig(context.Background(), Overrides{}, configYaml)
 	if err != nil {
@@ -81,6 +83,7 @@ channels:
 	assert.Equal(t, "my DSPS server", webhook.Headers["User-Agent"].String())
 	assert.Equal(t, "{{.channel.id}}", webhook.Headers["X-Chat-Room-ID"].String())
 	assert.Equal(t, 123, *cfg.Webhooks[0].MaxRedirects)
+	assert.Equal(t, "failed-webhooks-{{.channel.id}}", webhook.DeadLetterChannel.String())
 }
 
 func TestInvalidWebhookConfig(t *testing.T) {
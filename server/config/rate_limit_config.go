@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// RateLimitConfig represents "channels[].rateLimit" configuration
+type RateLimitConfig struct {
+	Publish []RateLimitRuleConfig `json:"publish"`
+	Fetch   []RateLimitRuleConfig `json:"fetch"`
+}
+
+// RateLimitRuleConfig limits number of requests in each interval
+type RateLimitRuleConfig struct {
+	Key      domain.RateLimitKey `json:"key"`
+	Limit    int                 `json:"limit"`
+	Interval *domain.Duration    `json:"interval"`
+}
+
+var rateLimitRuleConfigDefaults = RateLimitRuleConfig{
+	Key:      domain.RateLimitKeyChannel,
+	Interval: makeDurationPtr("1s"),
+}
+
+func postprocessRateLimitConfig(config *RateLimitConfig) error {
+	for i := range config.Publish {
+		if err := postprocessRateLimitRuleConfig(&config.Publish[i]); err != nil {
+			return fmt.Errorf("error on publish[%d]: %w", i, err)
+		}
+	}
+	for i := range config.Fetch {
+		if err := postprocessRateLimitRuleConfig(&config.Fetch[i]); err != nil {
+			return fmt.Errorf("error on fetch[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func postprocessRateLimitRuleConfig(rule *RateLimitRuleConfig) error {
+	if rule.Key == "" {
+		rule.Key = rateLimitRuleConfigDefaults.Key
+	}
+	switch rule.Key {
+	case domain.RateLimitKeyChannel, domain.RateLimitKeySub, domain.RateLimitKeyIP:
+	default:
+		return fmt.Errorf(`key must be one of "%s", "%s" or "%s" but got "%s"`, domain.RateLimitKeyChannel, domain.RateLimitKeySub, domain.RateLimitKeyIP, rule.Key)
+	}
+	if err := intMustBeLargerThanZero("limit", rule.Limit); err != nil {
+		return err
+	}
+	if rule.Interval == nil {
+		rule.Interval = rateLimitRuleConfigDefaults.Interval
+	}
+	if err := durationMustBeLargerThanZero("interval", *rule.Interval); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/testing"
+)
+
+func TestRateLimitConfig(t *testing.T) {
+	configYaml := strings.ReplaceAll(`
+channels:
+-
+	regex: 'chat-room-(?P<id>\d+)'
+	rateLimit:
+		publish:
+			- limit: 10
+			- key: ip
+				limit: 100
+				interval: 1m
+		fetch:
+			- key: sub
+				limit: 5
+				interval: 10s
+`, "\t", "  ")
+	config, err := ParseConfig(context.Background(), Overrides{}, configYaml)
+	assert.NoError(t, err)
+
+	cfg := config.Channels[0].RateLimit
+	assert.Equal(t, []RateLimitRuleConfig{
+		{Key: domain.RateLimitKeyChannel, Limit: 10, Interval: MakeDurationPtr("1s")},
+		{Key: domain.RateLimitKeyIP, Limit: 100, Interval: MakeDurationPtr("1m")},
+	}, cfg.Publish)
+	assert.Equal(t, []RateLimitRuleConfig{
+		{Key: domain.RateLimitKeySub, Limit: 5, Interval: MakeDurationPtr("10s")},
+	}, cfg.Fetch)
+
+	// Not configured by default
+	config, err = ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: ".+" } ]`)
+	assert.NoError(t, err)
+	assert.Nil(t, config.Channels[0].RateLimit)
+}
+
+func TestRateLimitConfigError(t *testing.T) {
+	for rateLimit, expected := range map[string]string{
+		`{ publish: [ { key: "user", limit: 1 } ] }`:   `error on publish\[0\]: key must be one of "channel", "sub" or "ip" but got "user"`,
+		`{ publish: [ { key: "ip" } ] }`:               `error on publish\[0\]: limit must not be negative nor zero`,
+		`{ fetch: [ { limit: 1 }, { limit: -1 } ] }`:   `error on fetch\[1\]: limit must not be negative nor zero`,
+		`{ fetch: [ { limit: 1, interval: "-1s" } ] }`: `error on fetch\[0\]: interval must not be negative nor zero`,
+	} {
+		_, err := ParseConfig(context.Background(), Overrides{}, `channels: [ { regex: ".+", rateLimit: `+rateLimit+` } ]`)
+		if assert.Error(t, err, rateLimit) {
+			assert.Regexp(t, `^Channel configration problem: error on channels\[0\]: error on rateLimit config: `+expected, err.Error())
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"runtime"
 
 	"golang.org/x/xerrors"
@@ -10,16 +11,21 @@ import (
 
 // RedisStorageConfig is definition of "storage.redis" configuration
 type RedisStorageConfig struct {
-	SingleNode *string   `json:"singleNode"`
-	Cluster    *[]string `json:"cluster"`
+	SingleNode *string              `json:"singleNode"`
+	Cluster    *[]string            `json:"cluster"`
+	Sentinel   *RedisSentinelConfig `json:"sentinel"`
 
 	DisablePubSub bool `json:"disablePubSub"`
 	DisableJwt    bool `json:"disableJwt"`
 
+	Engine string `json:"engine"`
+
 	Username string `json:"username"`
 	Password string `json:"password"`
 	DBNumber int    `json:"db" validate:"min=0"`
 
+	TLS RedisTLSConfig `json:"tls"`
+
 	ScriptReloadInterval *domain.Duration `json:"scriptReloadInterval"`
 
 	Timeout struct {
@@ -41,6 +47,36 @@ type RedisStorageConfig struct {
 	} `json:"connection"`
 }
 
+// RedisSentinelConfig is definition of "storage.redis.sentinel" configuration
+type RedisSentinelConfig struct {
+	MasterName string   `json:"masterName"`
+	Addrs      []string `json:"addrs"`
+	Password   string   `json:"password"`
+}
+
+// RedisTLSConfig is definition of "storage.redis.tls" configuration
+type RedisTLSConfig struct {
+	Enable             bool   `json:"enable"`
+	CAFile             string `json:"caFile"`
+	CertFile           string `json:"certFile"`
+	KeyFile            string `json:"keyFile"`
+	ServerName         string `json:"serverName"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+}
+
+// Redis PubSub storage engines
+const (
+	// Store each message as a key, notify subscribers with Redis Pub/Sub
+	RedisEngineKeys = "keys"
+	// Store messages of each channel in a Redis Stream
+	RedisEngineStreams = "streams"
+)
+
+// IsStreamsEngine returns true only for Redis Streams based engine
+func (config RedisStorageConfig) IsStreamsEngine() bool {
+	return config.Engine == RedisEngineStreams
+}
+
 // IsSingleNode returns true only for single-node Redis
 func (config RedisStorageConfig) IsSingleNode() bool {
 	return config.SingleNode != nil && len(*config.SingleNode) > 0
@@ -51,12 +87,45 @@ func (config RedisStorageConfig) IsCluster() bool {
 	return config.Cluster != nil && len(*config.Cluster) > 0
 }
 
+// IsSentinel returns true only for Redis managed by Redis Sentinel
+func (config RedisStorageConfig) IsSentinel() bool {
+	return config.Sentinel != nil
+}
+
 func postprocessRedisSubStorageConfig(config *RedisStorageConfig) error {
-	if config.IsSingleNode() && config.IsCluster() {
-		return xerrors.New("Redis configration can have ONLY ONE of 'singleNode' and 'cluster' item, cannot specify both")
+	endpoints := 0
+	for _, specified := range []bool{config.IsSingleNode(), config.IsCluster(), config.IsSentinel()} {
+		if specified {
+			endpoints++
+		}
+	}
+	if endpoints > 1 {
+		return xerrors.New("Redis configration can have ONLY ONE of 'singleNode', 'cluster' and 'sentinel' item, cannot specify two or more")
+	}
+	if endpoints == 0 {
+		return xerrors.New("Redis configration must have one of 'singleNode', 'cluster' and 'sentinel' item")
+	}
+	if config.IsSentinel() {
+		if config.Sentinel.MasterName == "" {
+			return xerrors.New("Redis sentinel configration requires 'masterName'")
+		}
+		if len(config.Sentinel.Addrs) == 0 {
+			return xerrors.New("Redis sentinel configration requires at least one sentinel address in 'addrs'")
+		}
+	}
+
+	if (config.TLS.CertFile == "") != (config.TLS.KeyFile == "") {
+		return xerrors.New("Redis TLS configration requires both of 'certFile' and 'keyFile' for client certificate")
+	}
+	if !config.TLS.Enable && (config.TLS.CAFile != "" || config.TLS.CertFile != "" || config.TLS.ServerName != "" || config.TLS.InsecureSkipVerify) {
+		return xerrors.New("Redis TLS configration has options but 'enable' is not true")
+	}
+
+	if config.Engine == "" {
+		config.Engine = RedisEngineKeys
 	}
-	if !(config.IsSingleNode() || config.IsCluster()) {
-		return xerrors.New("Redis configration must have one of 'singleNode' and 'cluster' item")
+	if config.Engine != RedisEngineKeys && config.Engine != RedisEngineStreams {
+		return fmt.Errorf(`Redis configration has unknown engine "%s", must be "%s" or "%s"`, config.Engine, RedisEngineKeys, RedisEngineStreams)
 	}
 
 	if config.ScriptReloadInterval == nil {
@@ -20,7 +20,7 @@ storages:
 			username: "user"
 `, "\t", "  ")
 	_, err := ParseConfig(context.Background(), Overrides{}, configYaml)
-	assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myRedis].redis: Redis configration must have one of 'singleNode' and 'cluster' item")
+	assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myRedis].redis: Redis configration must have one of 'singleNode', 'cluster' and 'sentinel' item")
 }
 
 func TestRedisAmbiguousAddrs(t *testing.T) {
@@ -34,7 +34,131 @@ storages:
 				- 'another-node-of-cluster-1:6379'
 `, "\t", "  ")
 	_, err := ParseConfig(context.Background(), Overrides{}, configYaml)
-	assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myRedis].redis: Redis configration can have ONLY ONE of 'singleNode' and 'cluster' item, cannot specify both")
+	assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myRedis].redis: Redis configration can have ONLY ONE of 'singleNode', 'cluster' and 'sentinel' item, cannot specify two or more")
+}
+
+func TestRedisSentinelConfig(t *testing.T) {
+	configYaml := strings.ReplaceAll(`
+storages:
+	myRedis:
+		redis:
+			sentinel:
+				masterName: 'mymaster'
+				addrs:
+					- 'sentinel-1:26379'
+					- 'sentinel-2:26379'
+				password: 'sentinel-secret'
+			password: 'redis-secret'
+`, "\t", "  ")
+	config, err := ParseConfig(context.Background(), Overrides{}, configYaml)
+	assert.NoError(t, err)
+
+	cfg := *config.Storages["myRedis"].Redis
+	assert.False(t, cfg.IsSingleNode())
+	assert.False(t, cfg.IsCluster())
+	assert.True(t, cfg.IsSentinel())
+	assert.Equal(t, "mymaster", cfg.Sentinel.MasterName)
+	assert.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, cfg.Sentinel.Addrs)
+	assert.Equal(t, "sentinel-secret", cfg.Sentinel.Password)
+	assert.Equal(t, "redis-secret", cfg.Password)
+}
+
+func TestRedisSentinelConfigError(t *testing.T) {
+	for _, tc := range []struct {
+		yaml string
+		err  string
+	}{
+		{
+			yaml: `
+			singleNode: 'localhost:6379'
+			sentinel:
+				masterName: 'mymaster'
+				addrs: ['sentinel-1:26379']`,
+			err: "Redis configration can have ONLY ONE of 'singleNode', 'cluster' and 'sentinel' item, cannot specify two or more",
+		},
+		{
+			yaml: `
+			sentinel:
+				addrs: ['sentinel-1:26379']`,
+			err: "Redis sentinel configration requires 'masterName'",
+		},
+		{
+			yaml: `
+			sentinel:
+				masterName: 'mymaster'`,
+			err: "Redis sentinel configration requires at least one sentinel address in 'addrs'",
+		},
+	} {
+		configYaml := strings.ReplaceAll(`
+storages:
+	myRedis:
+		redis:`+tc.yaml+"\n", "\t", "  ")
+		_, err := ParseConfig(context.Background(), Overrides{}, configYaml)
+		assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myRedis].redis: "+tc.err)
+	}
+}
+
+func TestRedisTLSConfig(t *testing.T) {
+	configYaml := strings.ReplaceAll(`
+storages:
+	myRedis:
+		redis:
+			singleNode: 'localhost:6379'
+			tls:
+				enable: true
+				caFile: '/etc/ssl/redis-ca.pem'
+				certFile: '/etc/ssl/redis-client.pem'
+				keyFile: '/etc/ssl/redis-client.key'
+				serverName: 'redis.example.com'
+				insecureSkipVerify: true
+`, "\t", "  ")
+	config, err := ParseConfig(context.Background(), Overrides{}, configYaml)
+	assert.NoError(t, err)
+	assert.Equal(t, RedisTLSConfig{
+		Enable:             true,
+		CAFile:             "/etc/ssl/redis-ca.pem",
+		CertFile:           "/etc/ssl/redis-client.pem",
+		KeyFile:            "/etc/ssl/redis-client.key",
+		ServerName:         "redis.example.com",
+		InsecureSkipVerify: true,
+	}, config.Storages["myRedis"].Redis.TLS)
+
+	// Disabled by default
+	config, err = ParseConfig(context.Background(), Overrides{}, `storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, RedisTLSConfig{}, config.Storages["myRedis"].Redis.TLS)
+}
+
+func TestRedisTLSConfigError(t *testing.T) {
+	for _, tc := range []struct {
+		tls string
+		err string
+	}{
+		{
+			tls: `{ enable: true, certFile: "/etc/ssl/redis-client.pem" }`,
+			err: "Redis TLS configration requires both of 'certFile' and 'keyFile' for client certificate",
+		},
+		{
+			tls: `{ enable: true, keyFile: "/etc/ssl/redis-client.key" }`,
+			err: "Redis TLS configration requires both of 'certFile' and 'keyFile' for client certificate",
+		},
+		{
+			tls: `{ caFile: "/etc/ssl/redis-ca.pem" }`,
+			err: "Redis TLS configration has options but 'enable' is not true",
+		},
+		{
+			tls: `{ insecureSkipVerify: true }`,
+			err: "Redis TLS configration has options but 'enable' is not true",
+		},
+	} {
+		_, err := ParseConfig(context.Background(), Overrides{}, `storages: { myRedis: { redis: { singleNode: "localhost:6379", tls: `+tc.tls+` } } }`)
+		assert.EqualError(t, err, "Storage configration problem: There is a configuration error on storage[myRedis].redis: "+tc.err)
+	}
+}
+
+func TestRedisEngineConfigError(t *testing.T) {
+	_, err := ParseConfig(context.Background(), Overrides{}, `storages: { myRedis: { redis: { singleNode: "localhost:6379", engine: "list" } } }`)
+	assert.EqualError(t, err, `Storage configration problem: There is a configuration error on storage[myRedis].redis: Redis configration has unknown engine "list", must be "keys" or "streams"`)
 }
 
 func TestRedisInvalidConfig(t *testing.T) {
@@ -68,6 +192,8 @@ storages:
 	}
 	cfg := *config.Storages["myRedis"].Redis
 
+	assert.Equal(t, RedisEngineKeys, cfg.Engine)
+	assert.False(t, cfg.IsStreamsEngine())
 	assert.Equal(t, MakeDurationPtr("5m"), cfg.ScriptReloadInterval)
 
 	assert.Equal(t, MakeDurationPtr("5s"), cfg.Timeout.Connect)
@@ -89,6 +215,7 @@ storages:
 	myRedis:
 		redis:
 			singleNode: 'localhost:6379'
+			engine: streams
 			timeout:
 				connect: 1s500ms
 				read: 3s
@@ -114,6 +241,8 @@ storages:
 	}
 	cfg := *config.Storages["myRedis"].Redis
 
+	assert.Equal(t, RedisEngineStreams, cfg.Engine)
+	assert.True(t, cfg.IsStreamsEngine())
 	assert.Equal(t, MakeDurationPtr("1s500ms"), cfg.Timeout.Connect)
 	assert.Equal(t, MakeDurationPtr("3s"), cfg.Timeout.Read)
 	assert.Equal(t, MakeDurationPtr("7s"), cfg.Timeout.Write)
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CheckReloadable returns error if the new configuration changes items that cannot be applied without restarting the server.
+func (config *ServerConfig) CheckReloadable(newConfig *ServerConfig) error {
+	for _, section := range []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"storages", config.Storages, newConfig.Storages},
+		{"storageMultiplexer", config.StorageMultiplexer, newConfig.StorageMultiplexer},
+		{"http", config.HTTPServer, newConfig.HTTPServer},
+		{"telemetry", config.Telemetry, newConfig.Telemetry},
+		{"sentry", config.Sentry, newConfig.Sentry},
+		{"logging.attributes", nonNilMap(config.Logging.Attributes), nonNilMap(newConfig.Logging.Attributes)},
+	} {
+		oldJSON, err := json.Marshal(section.old)
+		if err != nil {
+			return fmt.Errorf(`failed to compare "%s" configuration: %w`, section.name, err)
+		}
+		newJSON, err := json.Marshal(section.new)
+		if err != nil {
+			return fmt.Errorf(`failed to compare "%s" configuration: %w`, section.name, err)
+		}
+		if !bytes.Equal(oldJSON, newJSON) {
+			return fmt.Errorf(`"%s" configuration has been changed but it cannot be reloaded, restart the server to apply it`, section.name)
+		}
+	}
+	return nil
+}
+
+// nonNilMap makes omitted (nil) map comparable with empty map
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/config"
+)
+
+func TestCheckReloadable(t *testing.T) {
+	parse := func(yaml string) *ServerConfig {
+		config, err := ParseConfig(context.Background(), Overrides{}, yaml)
+		assert.NoError(t, err)
+		return &config
+	}
+	base := parse(`
+storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }
+channels: [ { regex: 'test.+', expire: 5m } ]
+admin: { auth: { bearer: [ 'token1' ] } }
+logging: { category: { "*": INFO } }
+`)
+
+	// Reloadable changes
+	assert.NoError(t, base.CheckReloadable(parse(`
+storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }
+channels: [ { regex: 'test.+', expire: 10m }, { regex: 'chat.+' } ]
+admin: { auth: { bearer: [ 'token2' ] } }
+logging: { category: { "*": DEBUG } }
+`)))
+	// Omitted attributes equals to empty attributes
+	assert.NoError(t, base.CheckReloadable(parse(`
+storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }
+logging: { attributes: {} }
+`)))
+
+	for _, testcase := range []struct {
+		yaml    string
+		section string
+	}{
+		{`storages: { myRedis: { redis: { singleNode: "localhost:6380" } } }`, "storages"},
+		{`storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }
+storageMultiplexer: { mode: shard }`, "storageMultiplexer"},
+		{`storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }
+http: { port: 8080 }`, "http"},
+		{`storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }
+telemetry: { ot: { tracing: { enable: true } } }`, "telemetry"},
+		{`storages: { myRedis: { redis: { singleNode: "localhost:6379" } } }
+logging: { attributes: { foo: bar } }`, "logging.attributes"},
+	} {
+		err := base.CheckReloadable(parse(testcase.yaml))
+		if assert.Error(t, err) {
+			assert.Equal(t, `"`+testcase.section+`" configuration has been changed but it cannot be reloaded, restart the server to apply it`, err.Error())
+		}
+	}
+}
@@ -13,6 +13,7 @@ type StoragesConfig map[domain.StorageID]*StorageConfig
 type StorageConfig struct {
 	Onmemory *OnmemoryStorageConfig `json:"onmemory"`
 	Redis    *RedisStorageConfig    `json:"redis"`
+	Bolt     *BoltStorageConfig     `json:"bolt"`
 }
 
 // DefaultStoragesConfig returns default configuration of storage backends
@@ -38,6 +39,12 @@ func PostprocessStorageConfig(config *StoragesConfig) error {
 				return fmt.Errorf("There is a configuration error on storage[%s].redis: %w", id, err)
 			}
 		}
+		if s.Bolt != nil {
+			types++
+			if err := postprocessBoltSubStorageConfig(s.Bolt); err != nil {
+				return fmt.Errorf("There is a configuration error on storage[%s].bolt: %w", id, err)
+			}
+		}
 		switch types {
 		case 0:
 			return fmt.Errorf("there is a configuration error on storage[%s]: no storage type under the item", id)
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// StorageMultiplexerConfig is definition of "storageMultiplexer" configuration, controls how to use multiple storages
+type StorageMultiplexerConfig struct {
+	Mode string `json:"mode"`
+
+	// Storages to route channels in sharding mode, nil means all storages
+	Shards []domain.StorageID `json:"shards"`
+	// Shards before changes in sharding mode (newest first), channels moved by the changes are also read from their previous owners
+	PreviousShards [][]domain.StorageID `json:"previousShards"`
+}
+
+// Storage multiplexer modes
+const (
+	// Write to all storages for redundancy
+	StorageMultiplexerModeReplicate = "replicate"
+	// Route each channel to one of the storages for horizontal scaling
+	StorageMultiplexerModeShard = "shard"
+)
+
+// IsSharding returns true only for sharding mode
+func (config StorageMultiplexerConfig) IsSharding() bool {
+	return config.Mode == StorageMultiplexerModeShard
+}
+
+func storageMultiplexerConfigDefault() *StorageMultiplexerConfig {
+	return &StorageMultiplexerConfig{
+		Mode: StorageMultiplexerModeReplicate,
+	}
+}
+
+// PostprocessStorageMultiplexerConfig fixup given configurations
+func PostprocessStorageMultiplexerConfig(config *StorageMultiplexerConfig, storages StoragesConfig) error {
+	if config.Mode == "" {
+		config.Mode = StorageMultiplexerModeReplicate
+	}
+	if config.Mode != StorageMultiplexerModeReplicate && config.Mode != StorageMultiplexerModeShard {
+		return fmt.Errorf(`mode must be "%s" or "%s" but got "%s"`, StorageMultiplexerModeReplicate, StorageMultiplexerModeShard, config.Mode)
+	}
+	if !config.IsSharding() {
+		if config.Shards != nil || config.PreviousShards != nil {
+			return fmt.Errorf(`shards and previousShards are available only in "%s" mode`, StorageMultiplexerModeShard)
+		}
+		return nil
+	}
+	if config.Shards != nil {
+		if err := validateShards(config.Shards, storages); err != nil {
+			return fmt.Errorf("shards: %w", err)
+		}
+	}
+	for i, shards := range config.PreviousShards {
+		if err := validateShards(shards, storages); err != nil {
+			return fmt.Errorf("previousShards[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateShards(shards []domain.StorageID, storages StoragesConfig) error {
+	if len(shards) == 0 {
+		return fmt.Errorf("must not be empty")
+	}
+	for _, id := range shards {
+		if _, ok := storages[id]; !ok {
+			return fmt.Errorf(`storage "%s" is not defined in storages`, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package config_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func TestStorageMultiplexerDefaultConfig(t *testing.T) {
+	config, err := ParseConfig(context.Background(), Overrides{}, ``)
+	assert.NoError(t, err)
+	assert.Equal(t, StorageMultiplexerModeReplicate, config.StorageMultiplexer.Mode)
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `storageMultiplexer: {}`)
+	assert.NoError(t, err)
+	assert.Equal(t, StorageMultiplexerModeReplicate, config.StorageMultiplexer.Mode)
+}
+
+func TestStorageMultiplexerConfig(t *testing.T) {
+	config, err := ParseConfig(context.Background(), Overrides{}, `storageMultiplexer: { mode: shard }`)
+	assert.NoError(t, err)
+	assert.Equal(t, StorageMultiplexerModeShard, config.StorageMultiplexer.Mode)
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `storageMultiplexer: { mode: INVALID }`)
+	assert.EqualError(t, err, `Storage multiplexer configration problem: mode must be "replicate" or "shard" but got "INVALID"`)
+}
+
+func TestStorageMultiplexerShardsConfig(t *testing.T) {
+	config, err := ParseConfig(context.Background(), Overrides{}, strings.Join([]string{
+		`storages: { s1: { onmemory: {} }, s2: { onmemory: {} }, s3: { onmemory: {} } }`,
+		`storageMultiplexer: { mode: shard, shards: [ s2, s3 ], previousShards: [ [ s1, s2, s3 ], [ s1, s2 ] ] }`,
+	}, "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.StorageID{"s2", "s3"}, config.StorageMultiplexer.Shards)
+	assert.Equal(t, [][]domain.StorageID{{"s1", "s2", "s3"}, {"s1", "s2"}}, config.StorageMultiplexer.PreviousShards)
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `storageMultiplexer: { shards: [ default ] }`)
+	assert.EqualError(t, err, `Storage multiplexer configration problem: shards and previousShards are available only in "shard" mode`)
+	_, err = ParseConfig(context.Background(), Overrides{}, `storageMultiplexer: { mode: shard, shards: [] }`)
+	assert.EqualError(t, err, `Storage multiplexer configration problem: shards: must not be empty`)
+	_, err = ParseConfig(context.Background(), Overrides{}, `storageMultiplexer: { mode: shard, previousShards: [ [ default, INVALID ] ] }`)
+	assert.EqualError(t, err, `Storage multiplexer configration problem: previousShards[0]: storage "INVALID" is not defined in storages`)
+}
@@ -2,13 +2,15 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/m3dev/dsps/server/domain"
 )
 
 // TelemetryConfig is to setup tracing/metrics.
 type TelemetryConfig struct {
-	OT *OpenTelemetryConfig `json:"ot"`
+	OT         *OpenTelemetryConfig `json:"ot"`
+	Prometheus *PrometheusConfig    `json:"prometheus"`
 }
 
 // OpenTelemetryConfig is to setup OpenTelemetry
@@ -39,6 +41,7 @@ type OpenTelemetryTracingBatchConfig struct {
 type OpenTelemetryExportersConfig struct {
 	Stdout OpenTelemetryExporterStdoutConfig `json:"stdout"`
 	GCP    OpenTelemetryExporterGCPConfig    `json:"gcp"`
+	OTLP   OpenTelemetryExporterOTLPConfig   `json:"otlp"`
 }
 
 // OpenTelemetryExporterStdoutConfig configure stdout exporter
@@ -53,16 +56,61 @@ type OpenTelemetryExporterGCPConfig struct {
 	ProjectID   string `json:"projectID"`
 }
 
+// OpenTelemetryExporterOTLPConfig configure OTLP exporter
+type OpenTelemetryExporterOTLPConfig struct {
+	Enable   bool   `json:"enable"`
+	Protocol string `json:"protocol"`
+	Endpoint string `json:"endpoint"`
+	URLPath  string `json:"urlPath"`
+
+	Headers     map[string]string                  `json:"headers"`
+	Insecure    bool                               `json:"insecure"`
+	TLS         OpenTelemetryExporterOTLPTLSConfig `json:"tls"`
+	Compression string                             `json:"compression"`
+	Timeout     *domain.Duration                   `json:"timeout"`
+}
+
+// OpenTelemetryExporterOTLPTLSConfig configure TLS of OTLP exporter
+type OpenTelemetryExporterOTLPTLSConfig struct {
+	CAFile   string `json:"caFile"`
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+// OTLP exporter protocols
+const (
+	OTLPProtocolGRPC         = "grpc"
+	OTLPProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// PrometheusConfig is to setup Prometheus metrics endpoint
+type PrometheusConfig struct {
+	Enable bool   `json:"enable"`
+	Path   string `json:"path"`
+}
+
 func tracingConfigDefault() *TelemetryConfig {
 	return &TelemetryConfig{
 		OT: &OpenTelemetryConfig{
 			Tracing: &OpenTelemetryTracingConfig{Enable: false},
 		},
+		Prometheus: &PrometheusConfig{Enable: false},
 	}
 }
 
 // PostprocessTelemetryConfig fixes/validates config
 func PostprocessTelemetryConfig(config *TelemetryConfig) error {
+	// YAML decoder allocates new object for each block, thus blocks omitted in the configuration file could be nil.
+	defaults := tracingConfigDefault()
+	if config.OT == nil {
+		config.OT = defaults.OT
+	}
+	if config.OT.Tracing == nil {
+		config.OT.Tracing = defaults.OT.Tracing
+	}
+	if config.Prometheus == nil {
+		config.Prometheus = defaults.Prometheus
+	}
 	if err := postprocessOTTracingConfig(config.OT.Tracing); err != nil {
 		return fmt.Errorf(`OT tracing configuration error: %w`, err)
 	}
@@ -70,7 +118,70 @@ func PostprocessTelemetryConfig(config *TelemetryConfig) error {
 	if len(config.OT.Exporters.Stdout.Quantiles) == 0 {
 		config.OT.Exporters.Stdout.Quantiles = []float64{0.5, 0.9, 0.99}
 	}
+	if err := postprocessOTLPExporterConfig(&config.OT.Exporters.OTLP); err != nil {
+		return fmt.Errorf(`OTLP exporter configuration error: %w`, err)
+	}
+
+	if err := postprocessPrometheusConfig(config.Prometheus); err != nil {
+		return fmt.Errorf(`Prometheus configuration error: %w`, err)
+	}
+
+	return nil
+}
+
+func postprocessPrometheusConfig(config *PrometheusConfig) error {
+	if config.Path == "" {
+		config.Path = "/metrics"
+	}
+	if !strings.HasPrefix(config.Path, "/") {
+		return fmt.Errorf(`path must start with "/" but got "%s"`, config.Path)
+	}
+	return nil
+}
 
+func postprocessOTLPExporterConfig(config *OpenTelemetryExporterOTLPConfig) error {
+	switch config.Protocol {
+	case "", OTLPProtocolGRPC:
+		config.Protocol = OTLPProtocolGRPC
+		if config.Endpoint == "" {
+			config.Endpoint = "localhost:4317"
+		}
+	case OTLPProtocolHTTPProtobuf:
+		if config.Endpoint == "" {
+			config.Endpoint = "localhost:4318"
+		}
+	default:
+		return fmt.Errorf(`protocol must be "%s" or "%s" but got "%s"`, OTLPProtocolGRPC, OTLPProtocolHTTPProtobuf, config.Protocol)
+	}
+	if strings.Contains(config.Endpoint, "://") {
+		return fmt.Errorf(`endpoint must be "host:port" without scheme but got "%s"`, config.Endpoint)
+	}
+
+	if config.URLPath == "" {
+		config.URLPath = "/v1/traces"
+	}
+	if !strings.HasPrefix(config.URLPath, "/") {
+		return fmt.Errorf(`urlPath must start with "/" but got "%s"`, config.URLPath)
+	}
+
+	switch config.Compression {
+	case "":
+		config.Compression = "none"
+	case "none", "gzip":
+	default:
+		return fmt.Errorf(`compression must be "none" or "gzip" but got "%s"`, config.Compression)
+	}
+
+	if config.Timeout == nil {
+		config.Timeout = makeDurationPtr("10s")
+	}
+
+	if (config.TLS.CertFile == "") != (config.TLS.KeyFile == "") {
+		return fmt.Errorf(`tls.certFile and tls.keyFile must be specified together`)
+	}
+	if config.Insecure && (config.TLS.CAFile != "" || config.TLS.CertFile != "") {
+		return fmt.Errorf(`tls options cannot be used with insecure`)
+	}
 	return nil
 }
 
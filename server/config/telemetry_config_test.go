@@ -74,3 +74,91 @@ func TestTracingConfigError(t *testing.T) {
 	_, err := ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { tracing: { enable: true, sampling: -2.0 } } }`)
 	assert.Regexp(t, `sampling ratio must be within \[0.0, 1.0\]`, err.Error())
 }
+
+func TestPrometheusConfig(t *testing.T) {
+	config, err := ParseConfig(context.Background(), Overrides{}, ``)
+	assert.NoError(t, err)
+	assert.False(t, config.Telemetry.Prometheus.Enable)
+	assert.Equal(t, "/metrics", config.Telemetry.Prometheus.Path)
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { prometheus: { enable: true, path: /internal/metrics } }`)
+	assert.NoError(t, err)
+	assert.True(t, config.Telemetry.Prometheus.Enable)
+	assert.Equal(t, "/internal/metrics", config.Telemetry.Prometheus.Path)
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { tracing: { enable: true } } }`)
+	assert.NoError(t, err)
+	assert.False(t, config.Telemetry.Prometheus.Enable)
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { prometheus: { enable: true, path: metrics } }`)
+	assert.Regexp(t, `path must start with "/"`, err.Error())
+}
+
+func TestOTLPExporterConfig(t *testing.T) {
+	config, err := ParseConfig(context.Background(), Overrides{}, ``)
+	assert.NoError(t, err)
+	otlp := config.Telemetry.OT.Exporters.OTLP
+	assert.False(t, otlp.Enable)
+	assert.Equal(t, OTLPProtocolGRPC, otlp.Protocol)
+	assert.Equal(t, "localhost:4317", otlp.Endpoint)
+	assert.Equal(t, "/v1/traces", otlp.URLPath)
+	assert.Equal(t, "none", otlp.Compression)
+	assert.Equal(t, 10*time.Second, otlp.Timeout.Duration)
+
+	configYaml := strings.ReplaceAll(`
+telemetry:
+	ot:
+		exporters:
+			otlp:
+				enable: true
+				protocol: http/protobuf
+				endpoint: collector.example.com:443
+				urlPath: /custom/traces
+				headers:
+					X-API-Key: secret
+				tls:
+					caFile: /etc/ssl/ca.pem
+					certFile: /etc/ssl/client.pem
+					keyFile: /etc/ssl/client-key.pem
+				compression: gzip
+				timeout: 3s
+`, "\t", "  ")
+	config, err = ParseConfig(context.Background(), Overrides{}, configYaml)
+	assert.NoError(t, err)
+	otlp = config.Telemetry.OT.Exporters.OTLP
+	assert.True(t, otlp.Enable)
+	assert.Equal(t, OTLPProtocolHTTPProtobuf, otlp.Protocol)
+	assert.Equal(t, "collector.example.com:443", otlp.Endpoint)
+	assert.Equal(t, "/custom/traces", otlp.URLPath)
+	assert.Equal(t, map[string]string{"X-API-Key": "secret"}, otlp.Headers)
+	assert.False(t, otlp.Insecure)
+	assert.Equal(t, "/etc/ssl/ca.pem", otlp.TLS.CAFile)
+	assert.Equal(t, "/etc/ssl/client.pem", otlp.TLS.CertFile)
+	assert.Equal(t, "/etc/ssl/client-key.pem", otlp.TLS.KeyFile)
+	assert.Equal(t, "gzip", otlp.Compression)
+	assert.Equal(t, 3*time.Second, otlp.Timeout.Duration)
+
+	config, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { exporters: { otlp: { protocol: http/protobuf } } } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:4318", config.Telemetry.OT.Exporters.OTLP.Endpoint)
+}
+
+func TestOTLPExporterConfigError(t *testing.T) {
+	_, err := ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { exporters: { otlp: { protocol: http/json } } } }`)
+	assert.Regexp(t, `OTLP exporter configuration error: protocol must be "grpc" or "http/protobuf" but got "http/json"`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { exporters: { otlp: { endpoint: "http://localhost:4318" } } } }`)
+	assert.Regexp(t, `endpoint must be "host:port" without scheme`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { exporters: { otlp: { urlPath: v1/traces } } } }`)
+	assert.Regexp(t, `urlPath must start with "/"`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { exporters: { otlp: { compression: zstd } } } }`)
+	assert.Regexp(t, `compression must be "none" or "gzip" but got "zstd"`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { exporters: { otlp: { tls: { certFile: /etc/ssl/client.pem } } } } }`)
+	assert.Regexp(t, `tls.certFile and tls.keyFile must be specified together`, err.Error())
+
+	_, err = ParseConfig(context.Background(), Overrides{}, `telemetry: { ot: { exporters: { otlp: { insecure: true, tls: { caFile: /etc/ssl/ca.pem } } } } }`)
+	assert.Regexp(t, `tls options cannot be used with insecure`, err.Error())
+}
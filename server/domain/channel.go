@@ -28,8 +28,33 @@ type Channel interface {
 
 	// Note that this method does not check revocation list.
 	ValidateJwt(ctx context.Context, jwt string) error
+	// Returns true if ValidateJwt verifies JWT, false if the channel accepts any (or no) bearer token.
+	HasJwtValidation() bool
 
-	SendOutgoingWebhook(ctx context.Context, msg Message) error
+	// Returns outgoing-webhooks of this channel, note that each webhook has persistent String() representation.
+	OutgoingWebhooks() []OutgoingWebhook
+
+	// Returns max byte length of message content, 0 means unlimited.
+	MaxMessageBytes() int
+	// Returns max count of messages retained in this channel.
+	BacklogLimit() BacklogLimit
+
+	// Returns rate limit rules of the operation on this channel.
+	RateLimits(op RateLimitOperation) []RateLimitRule
+
+	// Returns regex(es) of the channel configurations applied to this channel.
+	// Low-cardinality identifier of the channel, e.g. for metrics labels.
+	RegexLabel() string
+}
+
+// OutgoingWebhook sends message to a webhook endpoint
+type OutgoingWebhook interface {
+	Send(ctx context.Context, msg Message) error
+
+	// Returns channel to publish messages that could not be delivered, nil if not configured.
+	DeadLetterChannel() *ChannelID
+
+	String() string
 }
 
 // see: doc/interface/validation_rule.md
@@ -44,3 +69,21 @@ func ParseChannelID(str string) (ChannelID, error) {
 	}
 	return ChannelID(str), nil
 }
+
+// BacklogOverflow is a policy to publish message to the channel that reached to BacklogLimit
+type BacklogOverflow string
+
+// Policies of BacklogOverflow
+const (
+	// Reject new message
+	BacklogOverflowReject BacklogOverflow = "reject"
+	// Discard oldest message to store new message
+	BacklogOverflowEvict BacklogOverflow = "evict"
+)
+
+// BacklogLimit limits count of messages retained in a channel
+type BacklogLimit struct {
+	// 0 means unlimited
+	Max      int
+	Overflow BacklogOverflow
+}
@@ -3,10 +3,10 @@ package channel
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/m3dev/dsps/server/domain"
 	jwtv "github.com/m3dev/dsps/server/jwt/validator"
-	"github.com/m3dev/dsps/server/webhook/outgoing"
 	"golang.org/x/xerrors"
 )
 
@@ -14,9 +14,12 @@ type channelImpl struct {
 	id    domain.ChannelID
 	atoms []*channelAtom
 
-	expire          domain.Duration
-	jwtValidators   []jwtv.Validator
-	outgoingWebhook outgoing.Client
+	expire           domain.Duration
+	maxMessageBytes  int
+	backlogLimit     domain.BacklogLimit
+	jwtValidators    []jwtv.Validator
+	outgoingWebhooks []domain.OutgoingWebhook
+	rateLimits       map[domain.RateLimitOperation][]domain.RateLimitRule
 }
 
 func (c *channelImpl) Expire() domain.Duration {
@@ -25,8 +28,11 @@ func (c *channelImpl) Expire() domain.Duration {
 
 func newChannelImpl(id domain.ChannelID, atoms []*channelAtom) (*channelImpl, error) {
 	expire := domain.Duration{Duration: 0}
+	maxMessageBytes := 0
+	backlogLimit := domain.BacklogLimit{Max: 0, Overflow: domain.BacklogOverflowReject}
 	jwtValidators := make([]jwtv.Validator, 0, len(atoms))
-	outgoingWebhooks := make([]outgoing.Client, 0, len(atoms)*2)
+	outgoingWebhooks := make([]domain.OutgoingWebhook, 0, len(atoms)*2)
+	rateLimits := make(map[domain.RateLimitOperation][]domain.RateLimitRule)
 	for _, atom := range atoms {
 		tplEnv := atom.TemplateEnvironmentOf(id)
 		if tplEnv == nil {
@@ -36,6 +42,13 @@ func newChannelImpl(id domain.ChannelID, atoms []*channelAtom) (*channelImpl, er
 		if expire.Duration < atom.Expire().Duration {
 			expire = atom.Expire()
 		}
+		// Smallest limit wins, 0 means unlimited
+		if n := atom.MaxMessageBytes(); n > 0 && (maxMessageBytes == 0 || n < maxMessageBytes) {
+			maxMessageBytes = n
+		}
+		if bl := atom.BacklogLimit(); bl.Max > 0 && (backlogLimit.Max == 0 || bl.Max < backlogLimit.Max) {
+			backlogLimit = bl
+		}
 
 		if atom.JwtValidatorTemplate != nil {
 			jv, err := atom.JwtValidatorTemplate.NewValidator(tplEnv)
@@ -52,14 +65,21 @@ func newChannelImpl(id domain.ChannelID, atoms []*channelAtom) (*channelImpl, er
 			}
 			outgoingWebhooks = append(outgoingWebhooks, client)
 		}
+
+		for op, rules := range atom.RateLimitRules {
+			rateLimits[op] = append(rateLimits[op], rules...)
+		}
 	}
 	return &channelImpl{
 		id:    id,
 		atoms: atoms,
 
-		expire:          expire,
-		jwtValidators:   jwtValidators,
-		outgoingWebhook: outgoing.NewMultiplexClient(outgoingWebhooks),
+		expire:           expire,
+		maxMessageBytes:  maxMessageBytes,
+		backlogLimit:     backlogLimit,
+		jwtValidators:    jwtValidators,
+		outgoingWebhooks: outgoingWebhooks,
+		rateLimits:       rateLimits,
 	}, nil
 }
 
@@ -72,6 +92,30 @@ func (c *channelImpl) ValidateJwt(ctx context.Context, jwt string) error {
 	return nil
 }
 
-func (c *channelImpl) SendOutgoingWebhook(ctx context.Context, msg domain.Message) error {
-	return c.outgoingWebhook.Send(ctx, msg)
+func (c *channelImpl) HasJwtValidation() bool {
+	return len(c.jwtValidators) > 0
+}
+
+func (c *channelImpl) OutgoingWebhooks() []domain.OutgoingWebhook {
+	return c.outgoingWebhooks
+}
+
+func (c *channelImpl) MaxMessageBytes() int {
+	return c.maxMessageBytes
+}
+
+func (c *channelImpl) BacklogLimit() domain.BacklogLimit {
+	return c.backlogLimit
+}
+
+func (c *channelImpl) RateLimits(op domain.RateLimitOperation) []domain.RateLimitRule {
+	return c.rateLimits[op]
+}
+
+func (c *channelImpl) RegexLabel() string {
+	regexes := make([]string, len(c.atoms))
+	for i, atom := range c.atoms {
+		regexes[i] = atom.String()
+	}
+	return strings.Join(regexes, ",")
 }
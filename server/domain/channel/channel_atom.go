@@ -3,12 +3,15 @@ package channel
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 
 	"golang.org/x/xerrors"
 
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/domain"
 	jwtv "github.com/m3dev/dsps/server/jwt/validator"
+	"github.com/m3dev/dsps/server/logger"
+	dspssync "github.com/m3dev/dsps/server/sync"
 	"github.com/m3dev/dsps/server/webhook/outgoing"
 )
 
@@ -19,6 +22,7 @@ type channelAtom struct {
 
 	JwtValidatorTemplate     jwtv.Template
 	OutgoingWebHookTemplates []outgoing.ClientTemplate
+	RateLimitRules           map[domain.RateLimitOperation][]domain.RateLimitRule
 }
 
 func newChannelAtom(ctx context.Context, config *config.ChannelConfig, deps ProviderDeps, validate bool) (*channelAtom, error) {
@@ -27,7 +31,8 @@ func newChannelAtom(ctx context.Context, config *config.ChannelConfig, deps Prov
 	}
 
 	atom := &channelAtom{
-		config: config,
+		config:         config,
+		RateLimitRules: newRateLimitRules(config),
 	}
 	if validate {
 		if err := atom.validate(); err != nil {
@@ -36,7 +41,10 @@ func newChannelAtom(ctx context.Context, config *config.ChannelConfig, deps Prov
 	}
 
 	if config.Jwt != nil {
-		jvt, err := jwtv.NewTemplate(ctx, config.Jwt, deps.Clock)
+		jvt, err := jwtv.NewTemplate(ctx, config.Jwt, deps.Clock, dspssync.DaemonSystemDeps{
+			Telemetry: deps.Telemetry,
+			Sentry:    deps.Sentry,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -55,10 +63,39 @@ func newChannelAtom(ctx context.Context, config *config.ChannelConfig, deps Prov
 	return atom, nil
 }
 
+func newRateLimitRules(cfg *config.ChannelConfig) map[domain.RateLimitOperation][]domain.RateLimitRule {
+	result := make(map[domain.RateLimitOperation][]domain.RateLimitRule)
+	if cfg.RateLimit == nil {
+		return result
+	}
+	for op, rules := range map[domain.RateLimitOperation][]config.RateLimitRuleConfig{
+		domain.RateLimitPublish: cfg.RateLimit.Publish,
+		domain.RateLimitFetch:   cfg.RateLimit.Fetch,
+	} {
+		for i, rule := range rules {
+			// ID must be same among server processes to share counters, and must change if the rule changed to reset counters.
+			h := fnv.New64a()
+			fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%s", cfg.Regex.String(), op, i, rule.Key, rule.Interval.String())
+			result[op] = append(result[op], domain.RateLimitRule{
+				ID:       fmt.Sprintf("%x", h.Sum64()),
+				Key:      rule.Key,
+				Limit:    rule.Limit,
+				Interval: *rule.Interval,
+			})
+		}
+	}
+	return result
+}
+
 func (c *channelAtom) Shutdown(ctx context.Context) {
 	for _, webhook := range c.OutgoingWebHookTemplates {
 		webhook.Close()
 	}
+	if c.JwtValidatorTemplate != nil {
+		if err := c.JwtValidatorTemplate.Shutdown(ctx); err != nil {
+			logger.Of(ctx).WarnError(logger.CatAuth, "Failed to shutdown JWT validator", err)
+		}
+	}
 }
 
 func (c *channelAtom) String() string {
@@ -139,3 +176,14 @@ func (c *channelAtom) IsMatch(id domain.ChannelID) bool {
 func (c *channelAtom) Expire() domain.Duration {
 	return *c.config.Expire
 }
+
+func (c *channelAtom) MaxMessageBytes() int {
+	return c.config.MaxMessageBytes
+}
+
+func (c *channelAtom) BacklogLimit() domain.BacklogLimit {
+	return domain.BacklogLimit{
+		Max:      c.config.MaxBacklog,
+		Overflow: c.config.BacklogOverflow,
+	}
+}
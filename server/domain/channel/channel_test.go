@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/m3dev/dsps/server/domain"
 	"github.com/m3dev/dsps/server/domain/channel"
 	. "github.com/m3dev/dsps/server/jwt/testing"
 )
@@ -74,3 +75,87 @@ func TestJwtValidation(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), `"iss" claim of the presented JWT ("https://example.com/issuer2") does not match with any of expected values`)
 }
+
+func TestHasJwtValidation(t *testing.T) {
+	assert.False(t, channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: '.+', expire: '35m' }`,
+	}).HasJwtValidation())
+	assert.True(t, channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: '.+', expire: '35m' }`,
+		`{ regex: '.+', expire: '35m', jwt: { iss: [ "https://example.com/issuer" ], keys: { ES512: [ "../../jwt/testdata/ES512-test1-public.pem" ] } } }`,
+	}).HasJwtValidation())
+}
+
+func TestOutgoingWebhooks(t *testing.T) {
+	assert.Equal(t, 0, len(channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: '.+', expire: '35m' }`,
+	}).OutgoingWebhooks()))
+
+	webhooks := channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: '(?P<id>.+)', expire: '35m', webhooks: [ { url: 'http://localhost:3001/{{.channel.id}}' } ] }`,
+		`{ regex: '(?P<id>.+)', expire: '35m', webhooks: [ { url: 'http://localhost:3002/{{.channel.id}}' } ] }`,
+	}).OutgoingWebhooks()
+	if assert.Equal(t, 2, len(webhooks)) {
+		assert.Equal(t, "PUT http://localhost:3001/test", webhooks[0].String())
+		assert.Equal(t, "PUT http://localhost:3002/test", webhooks[1].String())
+	}
+}
+
+func TestRegexLabel(t *testing.T) {
+	assert.Equal(t, "test.+", channel.NewChannelByAtomYamls(t, "test1", []string{
+		`{ regex: 'test.+', expire: '35m' }`,
+	}).RegexLabel())
+	assert.Equal(t, "test.+,.+", channel.NewChannelByAtomYamls(t, "test1", []string{
+		`{ regex: 'test.+', expire: '35m' }`,
+		`{ regex: '.+', expire: '35m' }`,
+	}).RegexLabel())
+}
+
+func TestRateLimits(t *testing.T) {
+	assert.Empty(t, channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: '.+', expire: '35m' }`,
+	}).RateLimits(domain.RateLimitPublish))
+
+	c := channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: 'test', expire: '35m', rateLimit: { publish: [ { limit: 10 } ], fetch: [ { key: sub, limit: 5, interval: 10s } ] } }`,
+		`{ regex: '.+', expire: '35m', rateLimit: { publish: [ { key: ip, limit: 100, interval: 1m } ] } }`,
+	})
+	publish := c.RateLimits(domain.RateLimitPublish)
+	if assert.Equal(t, 2, len(publish)) {
+		assert.Equal(t, domain.RateLimitKeyChannel, publish[0].Key)
+		assert.Equal(t, 10, publish[0].Limit)
+		assert.Equal(t, time.Second, publish[0].Interval.Duration)
+		assert.Equal(t, domain.RateLimitKeyIP, publish[1].Key)
+		assert.Equal(t, 100, publish[1].Limit)
+		assert.Equal(t, time.Minute, publish[1].Interval.Duration)
+		assert.NotEqual(t, publish[0].ID, publish[1].ID)
+	}
+	fetch := c.RateLimits(domain.RateLimitFetch)
+	if assert.Equal(t, 1, len(fetch)) {
+		assert.Equal(t, domain.RateLimitKeySub, fetch[0].Key)
+		assert.NotEqual(t, publish[0].ID, fetch[0].ID)
+	}
+
+	// Rule ID must be stable to share counters among server processes
+	c2 := channel.NewChannelByAtomYamls(t, "test2", []string{
+		`{ regex: '.+', expire: '35m', rateLimit: { publish: [ { key: ip, limit: 200, interval: 1m } ] } }`,
+	})
+	assert.Equal(t, publish[1].ID, c2.RateLimits(domain.RateLimitPublish)[0].ID)
+}
+
+func TestMessageLimits(t *testing.T) {
+	c := channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: '.+', expire: '35m' }`,
+	})
+	assert.Equal(t, 0, c.MaxMessageBytes())
+	assert.Equal(t, domain.BacklogLimit{Max: 0, Overflow: domain.BacklogOverflowReject}, c.BacklogLimit())
+
+	// Smallest limit wins
+	c = channel.NewChannelByAtomYamls(t, "test", []string{
+		`{ regex: '.+', expire: '35m', maxMessageBytes: 1024, maxBacklog: 100 }`,
+		`{ regex: 'test', expire: '35m', maxMessageBytes: 256, maxBacklog: 10, backlogOverflow: evict }`,
+		`{ regex: 'te.+', expire: '35m', maxMessageBytes: 0, maxBacklog: 0 }`,
+	})
+	assert.Equal(t, 256, c.MaxMessageBytes())
+	assert.Equal(t, domain.BacklogLimit{Max: 10, Overflow: domain.BacklogOverflowEvict}, c.BacklogLimit())
+}
@@ -0,0 +1,76 @@
+package channel
+
+import (
+	"context"
+	"sync"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// ReloadableProvider is a ChannelProvider that can replace its channel configurations at runtime
+type ReloadableProvider interface {
+	domain.ChannelProvider
+
+	// Reload replaces all channel configurations (and cache of channels) atomically.
+	// If the configuration is invalid, returns error and keeps current configurations.
+	Reload(ctx context.Context, config *config.ServerConfig) error
+}
+
+// NewReloadableChannelProvider initializes ReloadableProvider
+func NewReloadableChannelProvider(ctx context.Context, config *config.ServerConfig, deps ProviderDeps) (ReloadableProvider, error) {
+	current, err := NewChannelProvider(ctx, config, deps)
+	if err != nil {
+		return nil, err
+	}
+	return &reloadableChannelProvider{
+		deps:    deps,
+		current: current,
+	}, nil
+}
+
+type reloadableChannelProvider struct {
+	deps ProviderDeps
+
+	lock    sync.RWMutex
+	current domain.ChannelProvider
+}
+
+func (rcp *reloadableChannelProvider) Reload(ctx context.Context, config *config.ServerConfig) error {
+	next, err := NewChannelProvider(ctx, config, rcp.deps)
+	if err != nil {
+		return err
+	}
+
+	rcp.lock.Lock()
+	prev := rcp.current
+	rcp.current = next
+	rcp.lock.Unlock()
+
+	// Channels taken from the previous provider are still usable after shutdown:
+	// outgoing webhook clients only close idle connections, in-flight calls complete normally.
+	prev.Shutdown(ctx)
+	return nil
+}
+
+func (rcp *reloadableChannelProvider) provider() domain.ChannelProvider {
+	rcp.lock.RLock()
+	defer rcp.lock.RUnlock()
+	return rcp.current
+}
+
+func (rcp *reloadableChannelProvider) Get(id domain.ChannelID) (domain.Channel, error) {
+	return rcp.provider().Get(id)
+}
+
+func (rcp *reloadableChannelProvider) GetFileDescriptorPressure() int {
+	return rcp.provider().GetFileDescriptorPressure()
+}
+
+func (rcp *reloadableChannelProvider) JWTClockSkewLeewayMax() domain.Duration {
+	return rcp.provider().JWTClockSkewLeewayMax()
+}
+
+func (rcp *reloadableChannelProvider) Shutdown(ctx context.Context) {
+	rcp.provider().Shutdown(ctx)
+}
@@ -0,0 +1,64 @@
+package channel
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/sentry"
+	"github.com/m3dev/dsps/server/telemetry"
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+func TestReloadableProvider(t *testing.T) {
+	ctx := context.Background()
+	cfg, err := config.ParseConfig(ctx, config.Overrides{}, `channels: [ { regex: "test.+", expire: "1s" } ]`)
+	assert.NoError(t, err)
+	cp, err := NewReloadableChannelProvider(ctx, &cfg, ProviderDeps{
+		Clock:     dspstesting.NewStubClock(t),
+		Telemetry: telemetry.NewEmptyTelemetry(t),
+		Sentry:    sentry.NewEmptySentry(),
+	})
+	assert.NoError(t, err)
+	defer cp.Shutdown(ctx)
+
+	test1, err := cp.Get("test1")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, test1.Expire().Duration)
+	_, err = cp.Get("chat1")
+	dspstesting.IsError(t, domain.ErrInvalidChannel, err)
+	assert.Equal(t, domain.Duration{}, cp.JWTClockSkewLeewayMax())
+
+	newCfg, err := config.ParseConfig(ctx, config.Overrides{}, strings.ReplaceAll(`channels: [
+		{ regex: "test.+", expire: "5m" },
+		{ regex: "chat.+", expire: "1s", jwt: { iss: [ "https://issuer.example.com/issuer-url" ], keys: { none: [] }, clockSkewLeeway: 15m } }
+	]`, "\t", "  "))
+	assert.NoError(t, err)
+	assert.NoError(t, cp.Reload(ctx, &newCfg))
+
+	// Cache also replaced
+	test1Reloaded, err := cp.Get("test1")
+	assert.NoError(t, err)
+	assert.NotSame(t, test1, test1Reloaded)
+	assert.Equal(t, 5*time.Minute, test1Reloaded.Expire().Duration)
+	chat1, err := cp.Get("chat1")
+	assert.NoError(t, err)
+	assert.NotNil(t, chat1)
+	assert.Equal(t, domain.Duration{Duration: 15 * time.Minute}, cp.JWTClockSkewLeewayMax())
+
+	// Invalid configuration keeps current one
+	invalidCfg := newCfg
+	invalidCfg.Channels = config.ChannelsConfig{newCfg.Channels[0]}
+	invalidCfg.Channels[0].Jwt = &config.JwtValidationConfig{
+		Iss:  []domain.JwtIss{"https://issuer.example.com/issuer-url"},
+		Keys: map[domain.JwtAlg][]string{"RS256": {"/file/not/found"}},
+	}
+	assert.Error(t, cp.Reload(ctx, &invalidCfg))
+	_, err = cp.Get("chat1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+)
+
+// ChannelExport is a snapshot of the channel data in the storage, to copy the channel into another storage.
+// Unlike ChannelInspection, it does not contain storage specific clocks so that any type of storage can import it.
+type ChannelExport struct {
+	ChannelID ChannelID
+	// Retained messages in published order (excludes expired and evicted messages)
+	Messages    []Message
+	Subscribers []SubscriberExport
+}
+
+// SubscriberExport is a snapshot of the subscriber in the storage, see ChannelExport.
+type SubscriberExport struct {
+	SubscriberID SubscriberID
+	// nil if no filter
+	Filter *SubscriberFilter
+	// nil if not a queue subscriber, leases are not exported (leased messages are redelivered after import)
+	VisibilityTimeout *Duration
+	// Count of leading ChannelExport.Messages already acknowledged by (or published before creation of) the subscriber.
+	// Messages after them are the backlog of the subscriber (filter still applies).
+	Consumed int
+}
+
+// Backlog returns messages not acknowledged by the subscriber yet, before filtering.
+func (sbsc SubscriberExport) Backlog(ch ChannelExport) []Message {
+	if sbsc.Consumed >= len(ch.Messages) {
+		return []Message{}
+	}
+	return ch.Messages[sbsc.Consumed:]
+}
+
+// ExportChannelsOneByOne implements PubSubStorage.ExportChannels with InspectChannels and ExportChannel of the storage.
+// Suitable for storages that can find data of a channel cheaply.
+func ExportChannelsOneByOne(ctx context.Context, s PubSubStorage, fn func(ChannelExport) error) error {
+	channels, err := s.InspectChannels(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		export, err := s.ExportChannel(ctx, ch.ChannelID)
+		if err != nil {
+			return err
+		}
+		if len(export.Messages) == 0 && len(export.Subscribers) == 0 {
+			continue // Expired after InspectChannels
+		}
+		if err := fn(export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokedJwt is an entry of revoked JWTs in the storage
+type RevokedJwt struct {
+	Jti JwtJti
+	Exp JwtExp
+}
@@ -0,0 +1,29 @@
+package domain
+
+// ChannelInspection is a snapshot of the channel state in the storage, for administration purpose.
+type ChannelInspection struct {
+	ChannelID ChannelID
+	// Storage specific clock of the latest message in the channel
+	Clock int64
+	// Remaining time until the storage discards the channel, nil if unknown
+	TTL *Duration
+
+	Subscribers []SubscriberInspection
+}
+
+// SubscriberInspection is a snapshot of the subscriber state in the storage, for administration purpose.
+type SubscriberInspection struct {
+	SubscriberID SubscriberID
+	// Storage specific clock of the latest acknowledged message, comparable with ChannelInspection.Clock
+	Clock int64
+	// Count of messages not acknowledged by the subscriber yet (could include expired or filtered messages)
+	Backlog int64
+	// Remaining time until the storage discards the subscriber, nil if unknown
+	TTL *Duration
+	// nil if no filter
+	Filter *SubscriberFilter
+	// nil if not a queue subscriber
+	VisibilityTimeout *Duration
+	// Count of messages leased to consumers of the queue subscriber, excluding expired leases
+	Leased int64
+}
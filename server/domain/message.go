@@ -19,11 +19,49 @@ type MessageLocator struct {
 type Message struct {
 	MessageLocator
 	Content json.RawMessage
+	// Attributes is optional metadata separated from Content, nil if not present
+	Attributes MessageAttributes
+	// TraceParent is W3C traceparent of the publisher's trace, empty if not traced
+	TraceParent string
 }
 
+// ErrMessageTooLarge : Content of the message exceeds size limit of the channel
+var ErrMessageTooLarge = NewErrorWithCode("dsps.message.too-large")
+
+// MessageAttributes is key-value metadata of the message
+type MessageAttributes map[string]string
+
+// Limits of message attributes, see: doc/interface/validation_rule.md
+const (
+	MaxMessageAttributes         = 32
+	MaxMessageAttributeValueSize = 1024
+)
+
 // see: doc/interface/validation_rule.md
 var messageIDRegexp = regexp.MustCompile("^[0-9a-z][0-9a-z_-]{0,62}$")
 
+// see: doc/interface/validation_rule.md
+var messageAttributeNameRegexp = regexp.MustCompile("^[0-9a-z][0-9a-z_.-]{0,62}$")
+
+// ParseMessageAttributes validates attributes, returns nil if empty
+func ParseMessageAttributes(attrs map[string]string) (MessageAttributes, error) {
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	if len(attrs) > MaxMessageAttributes {
+		return nil, fmt.Errorf("Too many message attributes (max: %d)", MaxMessageAttributes)
+	}
+	for name, value := range attrs {
+		if !messageAttributeNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("Message attribute name must match with %s", messageAttributeNameRegexp.String())
+		}
+		if len(value) > MaxMessageAttributeValueSize {
+			return nil, fmt.Errorf("Message attribute \"%s\" is too long (max: %d bytes)", name, MaxMessageAttributeValueSize)
+		}
+	}
+	return MessageAttributes(attrs), nil
+}
+
 // ParseMessageID try to parse ID
 func ParseMessageID(str string) (MessageID, error) {
 	if !messageIDRegexp.MatchString(str) {
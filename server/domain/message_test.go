@@ -2,6 +2,8 @@ package domain_test
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	. "github.com/m3dev/dsps/server/domain"
@@ -22,6 +24,29 @@ func TestParseMessageID(t *testing.T) {
 	assert.Errorf(t, err, errorMsg)
 }
 
+func TestParseMessageAttributes(t *testing.T) {
+	attrs, err := ParseMessageAttributes(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, attrs)
+
+	attrs, err = ParseMessageAttributes(map[string]string{"event-type": "created", "x.trace_id": ""})
+	assert.NoError(t, err)
+	assert.Equal(t, MessageAttributes{"event-type": "created", "x.trace_id": ""}, attrs)
+
+	_, err = ParseMessageAttributes(map[string]string{"Event-Type": "created"})
+	assert.EqualError(t, err, `Message attribute name must match with ^[0-9a-z][0-9a-z_.-]{0,62}$`)
+
+	_, err = ParseMessageAttributes(map[string]string{"key": strings.Repeat("a", MaxMessageAttributeValueSize+1)})
+	assert.EqualError(t, err, `Message attribute "key" is too long (max: 1024 bytes)`)
+
+	tooMany := map[string]string{}
+	for i := 0; i <= MaxMessageAttributes; i++ {
+		tooMany[fmt.Sprintf("key-%d", i)] = "value"
+	}
+	_, err = ParseMessageAttributes(tooMany)
+	assert.EqualError(t, err, `Too many message attributes (max: 32)`)
+}
+
 func TestBelongsToSameChannel(t *testing.T) {
 	assert.True(t, BelongsToSameChannel([]Message{}))
 	assert.True(t, BelongsToSameChannel([]Message{
@@ -0,0 +1,32 @@
+package domain
+
+// RateLimitOperation is a kind of requests that rate limit applies to
+type RateLimitOperation string
+
+// Operations that rate limit applies to
+const (
+	RateLimitPublish RateLimitOperation = "publish"
+	RateLimitFetch   RateLimitOperation = "fetch"
+)
+
+// RateLimitKey represents how to group requests to count
+type RateLimitKey string
+
+// Keys of rate limit counters
+const (
+	// Count requests for each channel
+	RateLimitKeyChannel RateLimitKey = "channel"
+	// Count requests for each "sub" claim of JWT
+	RateLimitKeySub RateLimitKey = "sub"
+	// Count requests for each client IP address
+	RateLimitKeyIP RateLimitKey = "ip"
+)
+
+// RateLimitRule limits number of requests in a fixed time window
+type RateLimitRule struct {
+	// Persistent identifier of the rule, used to make counter keys
+	ID       string
+	Key      RateLimitKey
+	Limit    int
+	Interval Duration
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "fmt"
+
+// SubscriberRewindTarget specifies the position to move the subscriber cursor back to.
+// Exactly one of the fields should be set.
+type SubscriberRewindTarget struct {
+	// Redeliver messages published after the storage specific clock (see SubscriberInspection.Clock)
+	Clock *int64
+	// Redeliver the message and messages published after it
+	MessageID *MessageID
+	// Redeliver all messages retained in the storage
+	Earliest bool
+}
+
+func (t SubscriberRewindTarget) String() string {
+	switch {
+	case t.Clock != nil:
+		return fmt.Sprintf("clock %d", *t.Clock)
+	case t.MessageID != nil:
+		return fmt.Sprintf("message %s", *t.MessageID)
+	case t.Earliest:
+		return "earliest"
+	default:
+		return "(empty)"
+	}
+}
@@ -17,11 +17,17 @@ var (
 	ErrMalformedAckHandle = NewErrorWithCode("dsps.storage.ack-handle-malformed")
 	// ErrMalformedMessageJSON : Given message content is not valid JSON
 	ErrMalformedMessageJSON = NewErrorWithCode("dsps.storage.message-json-malformed")
+	// ErrRewindTargetNotFound : Message or clock to rewind subscriber to is not (or no longer) retained in the storage
+	ErrRewindTargetNotFound = NewErrorWithCode("dsps.storage.rewind-target-not-found")
+	// ErrBacklogFull : Channel already retains max count of messages and configured to reject new messages
+	ErrBacklogFull = NewErrorWithCode("dsps.storage.backlog-full")
+	// ErrQueueSubscriberUnsupported : Storage does not support queue subscriber (competing consumers)
+	ErrQueueSubscriberUnsupported = NewErrorWithCode("dsps.storage.queue-subscriber-unsupported")
 )
 
 // IsStorageNonFatalError returns true if given error does not indicate storage system error
 func IsStorageNonFatalError(err error) bool {
-	return errors.Is(err, ErrInvalidChannel) || errors.Is(err, ErrSubscriptionNotFound) || errors.Is(err, ErrMalformedAckHandle)
+	return errors.Is(err, ErrInvalidChannel) || errors.Is(err, ErrSubscriptionNotFound) || errors.Is(err, ErrMalformedAckHandle) || errors.Is(err, ErrRewindTargetNotFound) || errors.Is(err, ErrBacklogFull) || errors.Is(err, ErrQueueSubscriberUnsupported)
 }
 
 //go:generate mockgen -source=${GOFILE} -package=mock -destination=./mock/${GOFILE}
@@ -40,6 +46,8 @@ type Storage interface {
 	AsPubSubStorage() PubSubStorage
 	// Retruns nil if neither supported nor supported.
 	AsJwtStorage() JwtStorage
+	// Returns nil if not supported. Counters must be shared among server processes.
+	AsRateLimitStorage() RateLimitStorage
 
 	// Estimated maximum pressure of syscall.RLIMIT_NOFILE
 	GetFileDescriptorPressure() int
@@ -47,7 +55,16 @@ type Storage interface {
 
 // PubSubStorage interface is an abstraction layer of PubSub storage implementations
 type PubSubStorage interface {
-	NewSubscriber(ctx context.Context, sl SubscriberLocator) error
+	// Creates subscriber if not exists, filter could be nil to receive all messages.
+	// If the subscriber already exists, replaces its filter with given one.
+	// If the subscriber is a queue subscriber, turns it into normal subscriber (messages leased to consumers are redelivered).
+	NewSubscriber(ctx context.Context, sl SubscriberLocator, filter *SubscriberFilter) error
+	// Creates queue subscriber if not exists, consumers polling the queue subscriber compete for its messages.
+	// FetchMessages leases returned messages to the caller for visibilityTimeout, other callers do not receive them during the lease.
+	// AcknowledgeMessages completes only messages of the lease, unacknowledged messages become visible again after the lease expires.
+	// If the subscriber already exists, replaces its filter and visibilityTimeout keeping its cursor and leases.
+	// Returns ErrQueueSubscriberUnsupported if the storage does not support it.
+	NewQueueSubscriber(ctx context.Context, sl SubscriberLocator, filter *SubscriberFilter, visibilityTimeout Duration) error
 	RemoveSubscriber(ctx context.Context, sl SubscriberLocator) error
 
 	// All messages must belong to same channel.
@@ -58,10 +75,32 @@ type PubSubStorage interface {
 	AcknowledgeMessages(ctx context.Context, handle AckHandle) error
 	// If the message had been acknowledged or sent before subscriber creation, returns true. Otherwise false (can includes unsure messages).
 	IsOldMessages(ctx context.Context, sl SubscriberLocator, msgs []MessageLocator) (map[MessageLocator]bool, error)
+
+	// Returns state of the channel and its subscribers, or all channels if channelID is empty.
+	// Returned slice is sorted by ChannelID, subscribers are sorted by SubscriberID.
+	InspectChannels(ctx context.Context, channelID ChannelID) ([]ChannelInspection, error)
+	// Moves cursor of the subscriber back so that the subscriber receives messages again (filter of the subscriber still applies).
+	// Returns ErrRewindTargetNotFound if the target is not retained in the storage.
+	RewindSubscriber(ctx context.Context, sl SubscriberLocator, target SubscriberRewindTarget) error
+	// Returns data of the channel to copy it into another storage, returned value has no messages nor subscribers if the channel does not exist.
+	// Use NewSubscriber, PublishMessages and RewindSubscriber of the other storage to import it.
+	ExportChannel(ctx context.Context, channelID ChannelID) (ChannelExport, error)
+	// Calls fn with data of each channel (same as ExportChannel) in ChannelID order, channels without messages nor subscribers are skipped.
+	// Stops and returns the error if fn returns error.
+	// Use this rather than ExportChannel for each channel of InspectChannels, because storage could export all channels more efficiently.
+	ExportChannels(ctx context.Context, fn func(ChannelExport) error) error
 }
 
 // JwtStorage interface is an abstraction layer of JWT storage implementations
 type JwtStorage interface {
 	RevokeJwt(ctx context.Context, exp JwtExp, jti JwtJti) error
 	IsRevokedJwt(ctx context.Context, jti JwtJti) (bool, error)
+	// Returns all revoked JWTs not expired yet, to copy them into another storage with RevokeJwt.
+	ExportRevokedJwts(ctx context.Context) ([]RevokedJwt, error)
+}
+
+// RateLimitStorage interface is an abstraction layer of rate limit counter storage implementations
+type RateLimitStorage interface {
+	// Increments counter of the fixed time window, returns incremented count and remaining duration of the window.
+	IncrementRateLimitCounter(ctx context.Context, key string, window Duration) (count int64, resetIn Duration, err error)
 }
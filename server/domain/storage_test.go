@@ -9,7 +9,7 @@ import (
 )
 
 func TestIsStorageNonFatalError(t *testing.T) {
-	for _, err := range []error{ErrInvalidChannel, ErrSubscriptionNotFound, ErrMalformedAckHandle} {
+	for _, err := range []error{ErrInvalidChannel, ErrSubscriptionNotFound, ErrMalformedAckHandle, ErrRewindTargetNotFound, ErrBacklogFull, ErrQueueSubscriberUnsupported} {
 		assert.True(t, IsStorageNonFatalError(err))
 	}
 	assert.False(t, IsStorageNonFatalError(errors.New(`test error`)))
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SubscriberFilter selects messages to deliver to the subscriber, all conditions must be satisfied.
+// nil filter matches with any message.
+type SubscriberFilter struct {
+	// Equality match on message attributes (attribute name -> expected value)
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Equality match on message content (dot separated JSON path -> expected JSON value)
+	Content map[string]json.RawMessage `json:"content,omitempty"`
+
+	content map[string]interface{} // Decoded Content
+}
+
+// Limits of subscriber filter, see: doc/interface/validation_rule.md
+const (
+	MaxSubscriberFilterConditions = 16
+)
+
+// ParseSubscriberFilter parses JSON representation of the filter, returns nil if no conditions given
+func ParseSubscriberFilter(raw []byte) (*SubscriberFilter, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	filter := &SubscriberFilter{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(filter); err != nil {
+		return nil, fmt.Errorf("Subscriber filter is not valid: %w", err)
+	}
+	if len(filter.Attributes)+len(filter.Content) == 0 {
+		return nil, nil
+	}
+	if len(filter.Attributes)+len(filter.Content) > MaxSubscriberFilterConditions {
+		return nil, fmt.Errorf("Too many subscriber filter conditions (max: %d)", MaxSubscriberFilterConditions)
+	}
+	if _, err := ParseMessageAttributes(filter.Attributes); err != nil {
+		return nil, err
+	}
+
+	filter.content = make(map[string]interface{}, len(filter.Content))
+	for path, expected := range filter.Content {
+		if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+			return nil, fmt.Errorf("Subscriber filter has invalid content path \"%s\"", path)
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(expected, &decoded); err != nil {
+			return nil, fmt.Errorf("Subscriber filter has invalid JSON value of content path \"%s\": %w", path, err)
+		}
+		filter.content[path] = decoded
+	}
+	return filter, nil
+}
+
+// String returns JSON representation of the filter
+func (f *SubscriberFilter) String() string {
+	if f == nil {
+		return "null"
+	}
+	encoded, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Sprintf("%v", err) // Should not happen because filter has been validated
+	}
+	return string(encoded)
+}
+
+// Match returns true if the message satisfies all conditions of the filter
+func (f *SubscriberFilter) Match(msg Message) bool {
+	if f == nil {
+		return true
+	}
+	for name, expected := range f.Attributes {
+		if value, ok := msg.Attributes[name]; !ok || value != expected {
+			return false
+		}
+	}
+	if len(f.content) == 0 {
+		return true
+	}
+
+	var content interface{}
+	if err := json.Unmarshal(msg.Content, &content); err != nil {
+		return false
+	}
+	for path, expected := range f.content {
+		value, ok := lookupJSONPath(content, strings.Split(path, "."))
+		if !ok || !reflect.DeepEqual(value, expected) {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupJSONPath(value interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = obj[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
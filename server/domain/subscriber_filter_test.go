@@ -0,0 +1,79 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/domain"
+)
+
+func TestParseSubscriberFilter(t *testing.T) {
+	for _, raw := range []string{``, ` `, `null`, `{}`, `{"attributes":{},"content":{}}`} {
+		filter, err := ParseSubscriberFilter([]byte(raw))
+		assert.NoError(t, err)
+		assert.Nil(t, filter)
+	}
+
+	filter, err := ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"},"content":{"user.id":123}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"event-type": "created"}, filter.Attributes)
+	assert.JSONEq(t, `{"attributes":{"event-type":"created"},"content":{"user.id":123}}`, filter.String())
+
+	// Round trip
+	filter, err = ParseSubscriberFilter([]byte(filter.String()))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"attributes":{"event-type":"created"},"content":{"user.id":123}}`, filter.String())
+
+	var nilFilter *SubscriberFilter
+	assert.Equal(t, "null", nilFilter.String())
+
+	_, err = ParseSubscriberFilter([]byte(`INVALID`))
+	assert.Contains(t, err.Error(), "Subscriber filter is not valid")
+	_, err = ParseSubscriberFilter([]byte(`{"unknown":{}}`))
+	assert.Contains(t, err.Error(), "Subscriber filter is not valid")
+	_, err = ParseSubscriberFilter([]byte(`{"attributes":{"INVALID":"x"}}`))
+	assert.EqualError(t, err, `Message attribute name must match with ^[0-9a-z][0-9a-z_.-]{0,62}$`)
+	for _, path := range []string{``, `.a`, `a.`, `a..b`} {
+		_, err = ParseSubscriberFilter([]byte(fmt.Sprintf(`{"content":{"%s":1}}`, path)))
+		assert.EqualError(t, err, fmt.Sprintf(`Subscriber filter has invalid content path "%s"`, path))
+	}
+
+	conditions := make([]string, MaxSubscriberFilterConditions+1)
+	for i := range conditions {
+		conditions[i] = fmt.Sprintf(`"key-%d":"value"`, i)
+	}
+	_, err = ParseSubscriberFilter([]byte(`{"attributes":{` + strings.Join(conditions, ",") + `}}`))
+	assert.EqualError(t, err, `Too many subscriber filter conditions (max: 16)`)
+}
+
+func TestSubscriberFilterMatch(t *testing.T) {
+	msg := func(content string, attrs MessageAttributes) Message {
+		return Message{
+			MessageLocator: MessageLocator{ChannelID: "ch-1", MessageID: "msg-1"},
+			Content:        json.RawMessage(content),
+			Attributes:     attrs,
+		}
+	}
+
+	var nilFilter *SubscriberFilter
+	assert.True(t, nilFilter.Match(msg(`{}`, nil)))
+
+	filter, err := ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+	assert.True(t, filter.Match(msg(`{}`, MessageAttributes{"event-type": "created", "other": "x"})))
+	assert.False(t, filter.Match(msg(`{}`, MessageAttributes{"event-type": "deleted"})))
+	assert.False(t, filter.Match(msg(`{}`, nil)))
+
+	filter, err = ParseSubscriberFilter([]byte(`{"content":{"kind":"order","user.id":123,"user.tags":["a","b"]}}`))
+	assert.NoError(t, err)
+	assert.True(t, filter.Match(msg(`{"kind":"order","user":{"id":123,"tags":["a","b"],"name":"foo"}}`, nil)))
+	assert.False(t, filter.Match(msg(`{"kind":"order","user":{"id":124,"tags":["a","b"]}}`, nil)))
+	assert.False(t, filter.Match(msg(`{"kind":"order","user":{"id":123}}`, nil)))
+	assert.False(t, filter.Match(msg(`{"kind":"order","user":"123"}`, nil)))
+	assert.False(t, filter.Match(msg(`"order"`, nil)))
+	assert.False(t, filter.Match(msg(`INVALID JSON`, nil)))
+}
@@ -13,24 +13,37 @@ import (
 // InitEndpoints registers endpoints of the DSPS server
 func InitEndpoints(mainCtx context.Context, rt *router.Router, deps *ServerDependencies) {
 	endpoints.InitProbeEndpoints(rt, deps)
+	endpoints.InitMetricsEndpoints(rt, deps)
+
+	// Shared by polling, SSE and WebSocket to count fetch requests with the same counters.
+	fetchLimiter := middleware.NewRateLimiter(deps, domain.RateLimitFetch)
+	endpoints.InitSubscriptionWebSocketEndpoints(rt, deps, fetchLimiter)
 
 	adminRouter := rt.NewGroup("/admin", middleware.NewAdminAuth(mainCtx, deps))
 	endpoints.InitAdminJwtEndpoints(adminRouter, deps)
 	endpoints.InitAdminLoggingEndpoints(adminRouter, deps)
+	endpoints.InitAdminChannelsEndpoints(adminRouter, deps)
 
+	channelOf := func(c context.Context, args router.MiddlewareArgs) (domain.ChannelID, domain.Channel, error) {
+		id, err := domain.ParseChannelID(args.PS.ByName("channelID"))
+		if err != nil {
+			return "", nil, err
+		}
+		channel, err := deps.ChannelProvider.Get(id)
+		return id, channel, err
+	}
 	channelRouter := rt.NewGroup(
 		"/channel/:channelID",
 		router.AsMiddlewareFunc(func(ctx context.Context, args router.MiddlewareArgs, next func(context.Context, router.MiddlewareArgs)) {
 			next(logger.WithAttributes(ctx).WithStr("channelID", args.PS.ByName("channelID")).Build(), args)
 		}),
 		middleware.NewNormalAuth(mainCtx, deps, func(c context.Context, args router.MiddlewareArgs) (domain.Channel, error) {
-			id, err := domain.ParseChannelID(args.PS.ByName("channelID"))
-			if err != nil {
-				return nil, err
-			}
-			return deps.ChannelProvider.Get(id)
+			_, channel, err := channelOf(c, args)
+			return channel, err
 		}),
 	)
-	endpoints.InitPublishEndpoints(channelRouter, deps)
-	endpoints.InitSubscriptionPollingEndpoints(channelRouter, deps)
+	endpoints.InitPublishEndpoints(channelRouter.NewGroup("", middleware.NewRateLimit(mainCtx, middleware.NewRateLimiter(deps, domain.RateLimitPublish), channelOf)), deps)
+	fetchRouter := channelRouter.NewGroup("", middleware.NewRateLimit(mainCtx, fetchLimiter, channelOf))
+	endpoints.InitSubscriptionPollingEndpoints(fetchRouter, deps)
+	endpoints.InitSubscriptionSSEEndpoints(fetchRouter, deps)
 }
@@ -0,0 +1,175 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/http/router"
+	"github.com/m3dev/dsps/server/http/utils"
+)
+
+// AdminChannelsEndpointDependency is to inject required objects to the endpoint
+type AdminChannelsEndpointDependency interface {
+	GetStorage() domain.Storage
+}
+
+// InitAdminChannelsEndpoints registers endpoints
+func InitAdminChannelsEndpoints(adminRouter *router.Router, deps AdminChannelsEndpointDependency) {
+	pubsub := deps.GetStorage().AsPubSubStorage()
+	adminRouter.GET("/channels", func(ctx context.Context, args router.HandlerArgs) {
+		if pubsub == nil {
+			utils.SendPubSubUnsupportedError(ctx, args.W)
+			return
+		}
+
+		inspections, err := pubsub.InspectChannels(ctx, "")
+		if err != nil {
+			utils.SendInternalServerError(ctx, args.W, err)
+			return
+		}
+
+		channels := make([]interface{}, len(inspections))
+		for i, inspection := range inspections {
+			channels[i] = channelInspectionJSON(inspection)
+		}
+		utils.SendJSON(ctx, args.W, http.StatusOK, map[string]interface{}{
+			"channels": channels,
+		})
+	})
+
+	adminRouter.GET("/channel/:channelID", func(ctx context.Context, args router.HandlerArgs) {
+		if pubsub == nil {
+			utils.SendPubSubUnsupportedError(ctx, args.W)
+			return
+		}
+
+		channelID, err := domain.ParseChannelID(args.PS.ByName("channelID"))
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "channelID", err)
+			return
+		}
+
+		inspections, err := pubsub.InspectChannels(ctx, channelID)
+		if err != nil {
+			utils.SendInternalServerError(ctx, args.W, err)
+			return
+		}
+		if len(inspections) == 0 {
+			utils.SendError(ctx, args.W, http.StatusNotFound, "Channel not found", xerrors.Errorf("channel %s not found in the storage", channelID))
+			return
+		}
+		utils.SendJSON(ctx, args.W, http.StatusOK, channelInspectionJSON(inspections[0]))
+	})
+
+	adminRouter.PUT("/channel/:channelID/subscriber/:subscriberID/rewind", func(ctx context.Context, args router.HandlerArgs) {
+		if pubsub == nil {
+			utils.SendPubSubUnsupportedError(ctx, args.W)
+			return
+		}
+
+		channelID, err := domain.ParseChannelID(args.PS.ByName("channelID"))
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "channelID", err)
+			return
+		}
+		subscriberID, err := domain.ParseSubscriberID(args.PS.ByName("subscriberID"))
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "subscriberID", err)
+			return
+		}
+		sl := domain.SubscriberLocator{ChannelID: channelID, SubscriberID: subscriberID}
+
+		target := domain.SubscriberRewindTarget{}
+		specified := 0
+		if str := args.R.GetQueryParam("clock"); str != "" {
+			clock, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				utils.SendInvalidParameter(ctx, args.W, "clock", err)
+				return
+			}
+			target.Clock = &clock
+			specified++
+		}
+		if str := args.R.GetQueryParam("messageID"); str != "" {
+			messageID, err := domain.ParseMessageID(str)
+			if err != nil {
+				utils.SendInvalidParameter(ctx, args.W, "messageID", err)
+				return
+			}
+			target.MessageID = &messageID
+			specified++
+		}
+		if str := args.R.GetQueryParam("earliest"); str != "" {
+			if str != "true" {
+				utils.SendInvalidParameter(ctx, args.W, "earliest", xerrors.Errorf("earliest must be \"true\" if specified: %s", str))
+				return
+			}
+			target.Earliest = true
+			specified++
+		}
+		if specified != 1 {
+			utils.SendError(ctx, args.W, http.StatusBadRequest, `Specify exactly one of "clock", "messageID" or "earliest" parameter`, nil)
+			return
+		}
+
+		if err := pubsub.RewindSubscriber(ctx, sl, target); err != nil {
+			if errors.Is(err, domain.ErrInvalidChannel) {
+				utils.SendError(ctx, args.W, http.StatusForbidden, err.Error(), err)
+			} else if errors.Is(err, domain.ErrSubscriptionNotFound) {
+				utils.SendError(ctx, args.W, http.StatusNotFound, "Subscriber not found", err)
+			} else if errors.Is(err, domain.ErrRewindTargetNotFound) {
+				utils.SendError(ctx, args.W, http.StatusNotFound, "Rewind target not found in the storage", err)
+			} else {
+				utils.SendInternalServerError(ctx, args.W, err)
+			}
+			return
+		}
+
+		// Returns state of the subscriber after rewind
+		inspections, err := pubsub.InspectChannels(ctx, channelID)
+		if err != nil {
+			utils.SendInternalServerError(ctx, args.W, err)
+			return
+		}
+		for _, inspection := range inspections {
+			for _, sbsc := range inspection.Subscribers {
+				if sbsc.SubscriberID == subscriberID {
+					utils.SendJSON(ctx, args.W, http.StatusOK, subscriberInspectionJSON(sbsc))
+					return
+				}
+			}
+		}
+		utils.SendError(ctx, args.W, http.StatusNotFound, "Subscriber not found", xerrors.Errorf("subscriber %v disappeared after rewind", sl))
+	})
+}
+
+func channelInspectionJSON(inspection domain.ChannelInspection) map[string]interface{} {
+	subscribers := make([]interface{}, len(inspection.Subscribers))
+	for i, sbsc := range inspection.Subscribers {
+		subscribers[i] = subscriberInspectionJSON(sbsc)
+	}
+	return map[string]interface{}{
+		"channelID":   inspection.ChannelID,
+		"clock":       inspection.Clock,
+		"ttl":         inspection.TTL,
+		"subscribers": subscribers,
+	}
+}
+
+func subscriberInspectionJSON(sbsc domain.SubscriberInspection) map[string]interface{} {
+	return map[string]interface{}{
+		"subscriberID": sbsc.SubscriberID,
+		"clock":        sbsc.Clock,
+		"backlog":      sbsc.Backlog,
+		"ttl":          sbsc.TTL,
+		"filter":       sbsc.Filter,
+
+		"visibilityTimeout": sbsc.VisibilityTimeout,
+		"leased":            sbsc.Leased,
+	}
+}
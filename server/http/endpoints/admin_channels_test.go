@@ -0,0 +1,214 @@
+package endpoints_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/domain/mock"
+	. "github.com/m3dev/dsps/server/http"
+	. "github.com/m3dev/dsps/server/http/testing"
+)
+
+func TestAdminChannelsWithoutPubSubSupport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
+
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channels", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 501, nil, `No PubSub compatible storage available`)
+
+		res = DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channel/my-channel", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 501, nil, `No PubSub compatible storage available`)
+	})
+}
+
+func TestAdminChannelsSuccess(t *testing.T) {
+	ctx := context.Background()
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		pubsub := deps.Storage.AsPubSubStorage()
+		filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+		assert.NoError(t, err)
+		assert.NoError(t, pubsub.NewSubscriber(ctx, domain.SubscriberLocator{ChannelID: "ch-1", SubscriberID: "sbsc-1"}, nil))
+		assert.NoError(t, pubsub.NewSubscriber(ctx, domain.SubscriberLocator{ChannelID: "ch-1", SubscriberID: "sbsc-2"}, filter))
+		assert.NoError(t, pubsub.NewQueueSubscriber(ctx, domain.SubscriberLocator{ChannelID: "ch-2", SubscriberID: "sbsc-1"}, nil, domain.Duration{Duration: 30 * time.Second}))
+		assert.NoError(t, pubsub.PublishMessages(ctx, []domain.Message{
+			{MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-1"}, Content: json.RawMessage(`{}`), Attributes: domain.MessageAttributes{"event-type": "created"}},
+			{MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-2"}, Content: json.RawMessage(`{}`)},
+		}))
+
+		res := DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channels", AdminAuthHeaders(t, deps), ``)
+		assert.Equal(t, 200, res.StatusCode)
+		var list struct {
+			Channels []struct {
+				ChannelID   string `json:"channelID"`
+				Subscribers []struct {
+					SubscriberID string `json:"subscriberID"`
+				} `json:"subscribers"`
+			} `json:"channels"`
+		}
+		BodyJSONOfRes(t, res, &list)
+		if assert.Len(t, list.Channels, 2) {
+			assert.Equal(t, "ch-1", list.Channels[0].ChannelID)
+			assert.Len(t, list.Channels[0].Subscribers, 2)
+			assert.Equal(t, "ch-2", list.Channels[1].ChannelID)
+			assert.Len(t, list.Channels[1].Subscribers, 1)
+		}
+
+		res = DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channel/ch-1", AdminAuthHeaders(t, deps), ``)
+		body := BodyJSONMapOfRes(t, res)
+		assert.Equal(t, "ch-1", body["channelID"])
+		assert.Equal(t, float64(2), body["clock"])
+		assert.Nil(t, body["ttl"]) // onmemory storage never discards channel
+		subscribers := body["subscribers"].([]interface{})
+		if assert.Len(t, subscribers, 2) {
+			sbsc1 := subscribers[0].(map[string]interface{})
+			assert.Equal(t, "sbsc-1", sbsc1["subscriberID"])
+			assert.Equal(t, float64(0), sbsc1["clock"])
+			assert.Equal(t, float64(2), sbsc1["backlog"])
+			assert.NotNil(t, sbsc1["ttl"])
+			assert.Nil(t, sbsc1["filter"])
+			sbsc2 := subscribers[1].(map[string]interface{})
+			assert.Equal(t, "sbsc-2", sbsc2["subscriberID"])
+			assert.Equal(t, float64(2), sbsc2["backlog"]) // Includes messages not matched with the filter until fetch
+			assert.Equal(t, map[string]interface{}{"attributes": map[string]interface{}{"event-type": "created"}}, sbsc2["filter"])
+			assert.Nil(t, sbsc2["visibilityTimeout"])
+			assert.Equal(t, float64(0), sbsc2["leased"])
+		}
+
+		res = DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channel/ch-2", AdminAuthHeaders(t, deps), ``)
+		subscribers = BodyJSONMapOfRes(t, res)["subscribers"].([]interface{})
+		if assert.Len(t, subscribers, 1) {
+			sbsc := subscribers[0].(map[string]interface{})
+			assert.Equal(t, "30s", sbsc["visibilityTimeout"])
+			assert.Equal(t, float64(0), sbsc["leased"])
+		}
+	})
+}
+
+func TestAdminChannelsFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage, pubsub, _ := NewMockStorages(ctrl)
+
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channels", map[string]string{}, ``)
+		assert.Equal(t, 403, res.StatusCode)
+
+		res = DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channel/INVALID!", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "channelID" parameter`)
+
+		pubsub.EXPECT().InspectChannels(gomock.Any(), domain.ChannelID("not-found")).Return([]domain.ChannelInspection{}, nil)
+		res = DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channel/not-found", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 404, nil, `Channel not found`)
+
+		pubsub.EXPECT().InspectChannels(gomock.Any(), domain.ChannelID("")).Return(nil, errors.New("mock error"))
+		res = DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channels", AdminAuthHeaders(t, deps), ``)
+		AssertInternalServerErrorResponse(t, res)
+
+		pubsub.EXPECT().InspectChannels(gomock.Any(), domain.ChannelID("ch-1")).Return(nil, errors.New("mock error"))
+		res = DoHTTPRequestWithHeaders(t, "GET", baseURL+"/admin/channel/ch-1", AdminAuthHeaders(t, deps), ``)
+		AssertInternalServerErrorResponse(t, res)
+	})
+}
+
+func TestAdminRewindSubscriberSuccess(t *testing.T) {
+	ctx := context.Background()
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		pubsub := deps.Storage.AsPubSubStorage()
+		sl := domain.SubscriberLocator{ChannelID: "ch-1", SubscriberID: "sbsc-1"}
+		assert.NoError(t, pubsub.NewSubscriber(ctx, sl, nil))
+		assert.NoError(t, pubsub.PublishMessages(ctx, []domain.Message{
+			{MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-1"}, Content: json.RawMessage(`{}`)},
+			{MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-2"}, Content: json.RawMessage(`{}`)},
+			{MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-3"}, Content: json.RawMessage(`{}`)},
+		}))
+		received, _, ackHandle, err := pubsub.FetchMessages(ctx, sl, 10, domain.Duration{})
+		assert.NoError(t, err)
+		assert.Len(t, received, 3)
+		assert.NoError(t, pubsub.AcknowledgeMessages(ctx, ackHandle))
+
+		res := DoHTTPRequestWithHeaders(t, "PUT", baseURL+"/admin/channel/ch-1/subscriber/sbsc-1/rewind?messageID=msg-2", AdminAuthHeaders(t, deps), ``)
+		body := BodyJSONMapOfRes(t, res)
+		assert.Equal(t, "sbsc-1", body["subscriberID"])
+		assert.Equal(t, float64(1), body["clock"])
+		assert.Equal(t, float64(2), body["backlog"])
+
+		res = DoHTTPRequestWithHeaders(t, "PUT", baseURL+"/admin/channel/ch-1/subscriber/sbsc-1/rewind?earliest=true", AdminAuthHeaders(t, deps), ``)
+		body = BodyJSONMapOfRes(t, res)
+		assert.Equal(t, float64(0), body["clock"])
+		assert.Equal(t, float64(3), body["backlog"])
+
+		res = DoHTTPRequestWithHeaders(t, "PUT", baseURL+"/admin/channel/ch-1/subscriber/sbsc-1/rewind?clock=3", AdminAuthHeaders(t, deps), ``)
+		body = BodyJSONMapOfRes(t, res)
+		assert.Equal(t, float64(3), body["clock"])
+		assert.Equal(t, float64(0), body["backlog"])
+
+		res = DoHTTPRequestWithHeaders(t, "PUT", baseURL+"/admin/channel/ch-1/subscriber/sbsc-1/rewind?messageID=msg-unknown", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 404, domain.ErrRewindTargetNotFound, `Rewind target not found in the storage`)
+
+		res = DoHTTPRequestWithHeaders(t, "PUT", baseURL+"/admin/channel/ch-1/subscriber/sbsc-unknown/rewind?earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 404, domain.ErrSubscriptionNotFound, `Subscriber not found`)
+	})
+}
+
+func TestAdminRewindSubscriberFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage, pubsub, _ := NewMockStorages(ctrl)
+
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		url := baseURL + "/admin/channel/ch-1/subscriber/sbsc-1/rewind"
+		res := DoHTTPRequestWithHeaders(t, "PUT", url+"?earliest=true", map[string]string{}, ``)
+		assert.Equal(t, 403, res.StatusCode)
+
+		res = DoHTTPRequestWithHeaders(t, "PUT", baseURL+"/admin/channel/INVALID!/subscriber/sbsc-1/rewind?earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "channelID" parameter`)
+		res = DoHTTPRequestWithHeaders(t, "PUT", baseURL+"/admin/channel/ch-1/subscriber/INVALID!/rewind?earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "subscriberID" parameter`)
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?clock=abc", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "clock" parameter`)
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?messageID=INVALID!", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "messageID" parameter`)
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?earliest=false", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "earliest" parameter`)
+		res = DoHTTPRequestWithHeaders(t, "PUT", url, AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Specify exactly one of "clock", "messageID" or "earliest" parameter`)
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?clock=1&earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 400, nil, `Specify exactly one of "clock", "messageID" or "earliest" parameter`)
+
+		pubsub.EXPECT().RewindSubscriber(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("mock error"))
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertInternalServerErrorResponse(t, res)
+
+		pubsub.EXPECT().RewindSubscriber(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.ErrInvalidChannel)
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 403, domain.ErrInvalidChannel, ``)
+
+		pubsub.EXPECT().RewindSubscriber(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		pubsub.EXPECT().InspectChannels(gomock.Any(), domain.ChannelID("ch-1")).Return(nil, errors.New("mock error"))
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertInternalServerErrorResponse(t, res)
+
+		pubsub.EXPECT().RewindSubscriber(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		pubsub.EXPECT().InspectChannels(gomock.Any(), domain.ChannelID("ch-1")).Return([]domain.ChannelInspection{}, nil)
+		res = DoHTTPRequestWithHeaders(t, "PUT", url+"?earliest=true", AdminAuthHeaders(t, deps), ``)
+		AssertErrorResponse(t, res, 404, nil, `Subscriber not found`)
+	})
+}
@@ -22,6 +22,7 @@ func TestJwtRevokeWithoutPubSubSupport(t *testing.T) {
 	storage := NewMockStorage(ctrl)
 	storage.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
 	storage.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
 
 	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
 		deps.Storage = storage
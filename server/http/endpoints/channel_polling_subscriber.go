@@ -1,12 +1,16 @@
 package endpoints
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"golang.org/x/xerrors"
+
 	"github.com/m3dev/dsps/server/domain"
 	"github.com/m3dev/dsps/server/http/lifecycle"
 	"github.com/m3dev/dsps/server/http/router"
@@ -56,25 +60,91 @@ func subscriberPutEndpoint(deps PollingEndpointDependency) router.Handler {
 			return
 		}
 
-		err = pubsub.NewSubscriber(ctx, domain.SubscriberLocator{
+		body, err := parseSubscriberPutBody(args.R)
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "filter", err)
+			return
+		}
+		filter, err := domain.ParseSubscriberFilter(body.Filter)
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "filter", err)
+			return
+		}
+		visibilityTimeout, err := parseVisibilityTimeout(body.VisibilityTimeout)
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "visibilityTimeout", err)
+			return
+		}
+
+		sl := domain.SubscriberLocator{
 			ChannelID:    channelID,
 			SubscriberID: subscriberID,
-		})
+		}
+		if visibilityTimeout != nil {
+			err = pubsub.NewQueueSubscriber(ctx, sl, filter, *visibilityTimeout)
+		} else {
+			err = pubsub.NewSubscriber(ctx, sl, filter)
+		}
 		if err != nil {
 			if errors.Is(err, domain.ErrInvalidChannel) {
 				// Could not create/access to the channel because not permitted by configuration
 				utils.SendError(ctx, args.W, http.StatusForbidden, err.Error(), err)
+			} else if errors.Is(err, domain.ErrQueueSubscriberUnsupported) {
+				utils.SendError(ctx, args.W, http.StatusNotImplemented, "Storage does not support queue subscriber", err)
 			} else {
 				utils.SendInternalServerError(ctx, args.W, err)
 			}
 			return
 		}
 
-		utils.SendJSON(ctx, args.W, http.StatusOK, map[string]interface{}{
+		result := map[string]interface{}{
 			"channelID":    channelID,
 			"subscriberID": subscriberID,
-		})
+		}
+		if filter != nil {
+			result["filter"] = filter
+		}
+		if visibilityTimeout != nil {
+			result["visibilityTimeout"] = visibilityTimeout
+		}
+		utils.SendJSON(ctx, args.W, http.StatusOK, result)
+	}
+}
+
+type subscriberPutBody struct {
+	Filter            json.RawMessage `json:"filter"`
+	VisibilityTimeout json.RawMessage `json:"visibilityTimeout"`
+}
+
+// Request body is optional, returns empty fields if no body given.
+func parseSubscriberPutBody(r router.Request) (subscriberPutBody, error) {
+	var decoded subscriberPutBody
+	body, err := r.ReadBody()
+	if err != nil {
+		return decoded, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return decoded, nil
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return decoded, xerrors.Errorf("Request body is not valid JSON: %w", err)
 	}
+	return decoded, nil
+}
+
+// Returns nil if not given (not a queue subscriber).
+func parseVisibilityTimeout(raw json.RawMessage) (*domain.Duration, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var vt domain.Duration
+	if err := json.Unmarshal(raw, &vt); err != nil {
+		return nil, err
+	}
+	if vt.Milliseconds() <= 0 {
+		return nil, xerrors.Errorf("visibilityTimeout must be 1ms or longer: %s", vt)
+	}
+	return &vt, nil
 }
 
 func subscriberDeleteEndpoint(deps PollingEndpointDependency) router.Handler {
@@ -188,10 +258,17 @@ func subscriberGetEndpoint(deps PollingEndpointDependency) router.Handler {
 
 			resultMsgs := make([]interface{}, 0, len(msgs))
 			for _, msg := range msgs {
-				resultMsgs = append(resultMsgs, map[string]interface{}{
+				resultMsg := map[string]interface{}{
 					"messageID": msg.MessageID,
 					"content":   msg.Content,
-				})
+				}
+				if len(msg.Attributes) > 0 {
+					resultMsg["attributes"] = msg.Attributes
+				}
+				if msg.TraceParent != "" {
+					resultMsg["traceparent"] = msg.TraceParent
+				}
+				resultMsgs = append(resultMsgs, resultMsg)
 			}
 			result := map[string]interface{}{
 				"channelID":    channelID,
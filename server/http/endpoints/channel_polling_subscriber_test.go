@@ -23,6 +23,7 @@ func TestPollingEndpointsWithoutPubSubSupport(t *testing.T) {
 	storage := NewMockStorage(ctrl)
 	storage.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
 	storage.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
 
 	sl := domain.SubscriberLocator{
 		ChannelID:    "my-channel",
@@ -69,6 +70,95 @@ func TestPollingSubscriberPutSuccess(t *testing.T) {
 	})
 }
 
+func TestPollingSubscriberPutWithFilter(t *testing.T) {
+	sl := domain.SubscriberLocator{
+		ChannelID:    "my-channel",
+		SubscriberID: "sbsc-1",
+	}
+	url := func(baseURL string) string {
+		return fmt.Sprintf("%s/channel/%s/subscription/polling/%s", baseURL, sl.ChannelID, sl.SubscriberID)
+	}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "PUT", url(baseURL), `{"filter":{"attributes":{"event-type":"created"},"content":{"kind":"order"}}}`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID":    string(sl.ChannelID),
+			"subscriberID": string(sl.SubscriberID),
+			"filter": map[string]interface{}{
+				"attributes": map[string]interface{}{"event-type": "created"},
+				"content":    map[string]interface{}{"kind": "order"},
+			},
+		})
+
+		for i, msg := range []struct {
+			eventType string
+			content   string
+		}{
+			{"created", `{"kind":"user"}`},
+			{"deleted", `{"kind":"order"}`},
+			{"created", `{"kind":"order"}`},
+		} {
+			res = DoHTTPRequestWithHeaders(t, "PUT", fmt.Sprintf("%s/channel/%s/message/msg-%d", baseURL, sl.ChannelID, i), map[string]string{
+				"X-DSPS-Attr-Event-Type": msg.eventType,
+			}, msg.content)
+			assert.Equal(t, 200, res.StatusCode)
+		}
+
+		res = DoHTTPRequest(t, "GET", url(baseURL), ``)
+		body := BodyJSONMapOfRes(t, res)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"messageID":  "msg-2",
+				"content":    map[string]interface{}{"kind": "order"},
+				"attributes": map[string]interface{}{"event-type": "created"},
+			},
+		}, body["messages"])
+
+		res = DoHTTPRequest(t, "PUT", url(baseURL), `INVALID`)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "filter" parameter`)
+		res = DoHTTPRequest(t, "PUT", url(baseURL), `{"filter":{"content":{"a..b":1}}}`)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "filter" parameter`)
+	})
+}
+
+func TestPollingSubscriberPutQueue(t *testing.T) {
+	sl := domain.SubscriberLocator{
+		ChannelID:    "my-channel",
+		SubscriberID: "sbsc-1",
+	}
+	url := func(baseURL string) string {
+		return fmt.Sprintf("%s/channel/%s/subscription/polling/%s", baseURL, sl.ChannelID, sl.SubscriberID)
+	}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "PUT", url(baseURL), `{"visibilityTimeout":"30s"}`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID":         string(sl.ChannelID),
+			"subscriberID":      string(sl.SubscriberID),
+			"visibilityTimeout": "30s",
+		})
+
+		for i := 1; i <= 2; i++ {
+			res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/msg-%d", baseURL, sl.ChannelID, i), `{}`)
+			assert.Equal(t, 200, res.StatusCode)
+		}
+
+		// Consumers compete for messages
+		for _, expected := range []string{"msg-1", "msg-2"} {
+			res = DoHTTPRequest(t, "GET", url(baseURL)+"?max=1", ``)
+			body := BodyJSONMapOfRes(t, res)
+			if assert.Len(t, body["messages"], 1) {
+				assert.Equal(t, expected, body["messages"].([]interface{})[0].(map[string]interface{})["messageID"])
+			}
+		}
+		res = DoHTTPRequest(t, "GET", url(baseURL), ``)
+		assert.Empty(t, BodyJSONMapOfRes(t, res)["messages"])
+
+		res = DoHTTPRequest(t, "PUT", url(baseURL), `{"visibilityTimeout":"INVALID"}`)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "visibilityTimeout" parameter`)
+		res = DoHTTPRequest(t, "PUT", url(baseURL), `{"visibilityTimeout":"0s"}`)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "visibilityTimeout" parameter`)
+	})
+}
+
 func TestPollingSubscriberPutFailure(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -87,13 +177,17 @@ func TestPollingSubscriberPutFailure(t *testing.T) {
 		res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/subscription/polling/%s", baseURL, sl.ChannelID, "*** INVALID ***"), ``)
 		AssertErrorResponse(t, res, 400, nil, `Invalid "subscriberID" parameter`)
 
-		pubsub.EXPECT().NewSubscriber(gomock.Any(), sl).Return(domain.ErrInvalidChannel)
+		pubsub.EXPECT().NewSubscriber(gomock.Any(), sl, nil).Return(domain.ErrInvalidChannel)
 		res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/subscription/polling/%s", baseURL, sl.ChannelID, sl.SubscriberID), ``)
 		AssertErrorResponse(t, res, 403, domain.ErrInvalidChannel, "")
 
-		pubsub.EXPECT().NewSubscriber(gomock.Any(), sl).Return(errors.New("mock error"))
+		pubsub.EXPECT().NewSubscriber(gomock.Any(), sl, nil).Return(errors.New("mock error"))
 		res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/subscription/polling/%s", baseURL, sl.ChannelID, sl.SubscriberID), ``)
 		AssertInternalServerErrorResponse(t, res)
+
+		pubsub.EXPECT().NewQueueSubscriber(gomock.Any(), sl, nil, domain.Duration{Duration: 30 * time.Second}).Return(domain.ErrQueueSubscriberUnsupported)
+		res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/subscription/polling/%s", baseURL, sl.ChannelID, sl.SubscriberID), `{"visibilityTimeout":"30s"}`)
+		AssertErrorResponse(t, res, 501, domain.ErrQueueSubscriberUnsupported, "Storage does not support queue subscriber")
 	})
 }
 
@@ -113,7 +207,7 @@ func TestPollingSubscriberDeleteSuccess(t *testing.T) {
 			"subscriberID": string(sl.SubscriberID),
 		})
 
-		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(context.Background(), sl))
+		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(context.Background(), sl, nil))
 
 		res = DoHTTPRequest(t, "DELETE", fmt.Sprintf("%s/channel/%s/subscription/polling/%s", baseURL, sl.ChannelID, sl.SubscriberID), ``)
 		AssertResponseJSON(t, res, 200, map[string]interface{}{
@@ -178,7 +272,7 @@ func TestPollingSubscriberGetSuccess(t *testing.T) {
 		}
 	}
 	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
-		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(ctx, sl))
+		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
 
 		// No message
 		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/polling/%s?timeout=0ms", baseURL, sl.ChannelID, sl.SubscriberID), ``)
@@ -341,7 +435,7 @@ func TestPollingSubscriberMessageDeleteSuccess(t *testing.T) {
 	}
 	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
 		pubsub := deps.Storage.AsPubSubStorage()
-		assert.NoError(t, pubsub.NewSubscriber(ctx, sl))
+		assert.NoError(t, pubsub.NewSubscriber(ctx, sl, nil))
 		assert.NoError(t, pubsub.PublishMessages(ctx, msgs))
 		fetched, _, ackHandle, err := pubsub.FetchMessages(ctx, sl, len(msgs)/2, domain.Duration{Duration: 0})
 		assert.Equal(t, msgs[:len(msgs)/2], fetched)
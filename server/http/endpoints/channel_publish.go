@@ -6,24 +6,39 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"golang.org/x/xerrors"
 
 	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/http/middleware"
 	"github.com/m3dev/dsps/server/http/router"
 	"github.com/m3dev/dsps/server/http/utils"
-	"github.com/m3dev/dsps/server/logger"
+	"github.com/m3dev/dsps/server/telemetry"
+	"github.com/m3dev/dsps/server/webhook/delivery"
 )
 
 // PublishEndpointDependency is to inject required objects to the endpoint
 type PublishEndpointDependency interface {
-	GetStorage() domain.Storage
 	GetChannelProvider() domain.ChannelProvider
+	GetStorage() domain.Storage
+	GetWebhookQueue() delivery.Queue
+	GetTelemetry() *telemetry.Telemetry
 }
 
+// Max count of messages in a batch publish request.
+var maxBatchPublishMessages = 1000
+
+// Prefix of HTTP headers to specify message attributes.
+const messageAttributeHeaderPrefix = "X-Dsps-Attr-"
+
 // InitPublishEndpoints registers endpoints
 func InitPublishEndpoints(channelRouter *router.Router, deps PublishEndpointDependency) {
 	pubsub := deps.GetStorage().AsPubSubStorage()
+	channelProvider := deps.GetChannelProvider()
+	webhookQueue := deps.GetWebhookQueue()
+	telemetry := deps.GetTelemetry()
 
 	channelRouter.PUT("/message/:messageID", func(ctx context.Context, args router.HandlerArgs) {
 		if pubsub == nil {
@@ -51,38 +66,218 @@ func InitPublishEndpoints(channelRouter *router.Router, deps PublishEndpointDepe
 			utils.SendError(ctx, args.W, http.StatusBadRequest, "Request body is not JSON", err)
 			return
 		}
+		channel, ok := getChannel(ctx, args.W, channelProvider, channelID)
+		if !ok {
+			return
+		}
+		if err := checkMessageSize(content, channel.MaxMessageBytes()); err != nil {
+			utils.SendError(ctx, args.W, http.StatusRequestEntityTooLarge, "Message content too large", err)
+			return
+		}
 
-		message := domain.Message{
+		attributes, err := parseMessageAttributeHeaders(args.R.Header)
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "attributes", err)
+			return
+		}
+
+		messages := []domain.Message{{
 			MessageLocator: domain.MessageLocator{
 				ChannelID: channelID,
 				MessageID: messageID,
 			},
-			Content: content,
+			Content:    content,
+			Attributes: attributes,
+		}}
+
+		if !preparePublish(ctx, args.W, webhookQueue, telemetry, channelID, messages) {
+			return
+		}
+		if err := pubsub.PublishMessages(ctx, messages); err != nil {
+			sendPublishError(ctx, args.W, channel, err)
+			return
 		}
 
-		err = pubsub.PublishMessages(ctx, []domain.Message{message})
+		utils.SendJSON(ctx, args.W, http.StatusOK, map[string]interface{}{
+			"channelID": channelID,
+			"messageID": messageID,
+		})
+	})
+
+	channelRouter.POST("/messages", func(ctx context.Context, args router.HandlerArgs) {
+		if pubsub == nil {
+			utils.SendPubSubUnsupportedError(ctx, args.W)
+			return
+		}
+
+		channelID, err := domain.ParseChannelID(args.PS.ByName("channelID"))
 		if err != nil {
-			if errors.Is(err, domain.ErrInvalidChannel) {
-				// Could not create/access to the channel because not permitted by configuration
-				utils.SendError(ctx, args.W, http.StatusForbidden, err.Error(), err)
-			} else {
-				utils.SendInternalServerError(ctx, args.W, err)
-			}
+			utils.SendInvalidParameter(ctx, args.W, "channelID", err)
 			return
 		}
 
-		ch, err := deps.GetChannelProvider().Get(channelID)
+		var items []struct {
+			MessageID  string            `json:"messageID"`
+			Content    json.RawMessage   `json:"content"`
+			Attributes map[string]string `json:"attributes"`
+		}
+		body, err := args.R.ReadBody()
+		if err == nil {
+			err = json.Unmarshal(body, &items)
+		}
 		if err != nil {
-			utils.SendInternalServerError(ctx, args.W, err)
+			utils.SendError(ctx, args.W, http.StatusBadRequest, "Request body is not JSON array", err)
 			return
 		}
-		if err := ch.SendOutgoingWebhook(ctx, message); err != nil {
-			logger.Of(ctx).WarnError(logger.CatOutgoingWebhook, fmt.Sprintf(`failed to send outgoing-webhook (channel: %s, msgID: %s): %%w`, channelID, messageID), err)
+		if len(items) > maxBatchPublishMessages {
+			utils.SendError(ctx, args.W, http.StatusBadRequest, fmt.Sprintf("Too many messages in a request (max: %d)", maxBatchPublishMessages), xerrors.Errorf("%d messages given", len(items)))
+			return
+		}
+
+		channel, ok := getChannel(ctx, args.W, channelProvider, channelID)
+		if !ok {
+			return
+		}
+
+		results := make([]map[string]interface{}, len(items))
+		messages := make([]domain.Message, 0, len(items))
+		messageResults := make([]map[string]interface{}, 0, len(items)) // results[] of each messages[]
+		for i, item := range items {
+			results[i] = map[string]interface{}{"messageID": item.MessageID}
+			messageID, err := domain.ParseMessageID(item.MessageID)
+			if err == nil && len(item.Content) == 0 {
+				err = xerrors.New("content is required")
+			}
+			if err == nil {
+				err = checkMessageSize(item.Content, channel.MaxMessageBytes())
+			}
+			var attributes domain.MessageAttributes
+			if err == nil {
+				attributes, err = domain.ParseMessageAttributes(item.Attributes)
+			}
+			if err != nil {
+				setRejectedResult(results[i], err)
+				continue
+			}
+			results[i]["status"] = "published"
+			messageResults = append(messageResults, results[i])
+			messages = append(messages, domain.Message{
+				MessageLocator: domain.MessageLocator{
+					ChannelID: channelID,
+					MessageID: messageID,
+				},
+				Content:    item.Content,
+				Attributes: attributes,
+			})
+		}
+
+		if len(messages) > 0 {
+			if !preparePublish(ctx, args.W, webhookQueue, telemetry, channelID, messages) {
+				return
+			}
+			err := pubsub.PublishMessages(ctx, messages)
+			if errors.Is(err, domain.ErrBacklogFull) {
+				// Messages before the rejected one have been published, find it by publishing one by one (published ones are deduplicated).
+				// The rejected message and following ones are rejected to keep order of messages.
+				for i := range messages {
+					if err = pubsub.PublishMessages(ctx, messages[i:i+1]); errors.Is(err, domain.ErrBacklogFull) {
+						for _, result := range messageResults[i:] {
+							setRejectedResult(result, xerrors.Errorf("Channel backlog is full: %w", domain.ErrBacklogFull))
+						}
+						err = nil
+						break
+					} else if err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				sendPublishError(ctx, args.W, channel, err)
+				return
+			}
 		}
 
 		utils.SendJSON(ctx, args.W, http.StatusOK, map[string]interface{}{
 			"channelID": channelID,
-			"messageID": messageID,
+			"messages":  results,
 		})
 	})
 }
+
+// Returns false if failed, error response has been sent in that case.
+func getChannel(ctx context.Context, w router.ResponseWriter, channelProvider domain.ChannelProvider, channelID domain.ChannelID) (domain.Channel, bool) {
+	channel, err := channelProvider.Get(channelID)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidChannel) {
+			utils.SendError(ctx, w, http.StatusForbidden, err.Error(), err)
+		} else {
+			utils.SendInternalServerError(ctx, w, err)
+		}
+		return nil, false
+	}
+	return channel, true
+}
+
+func setRejectedResult(result map[string]interface{}, err error) {
+	result["status"] = "rejected"
+	result["error"] = err.Error()
+	if errWithCode := domain.NewErrorWithCode(""); errors.As(err, &errWithCode) {
+		result["code"] = errWithCode.Code()
+	}
+}
+
+func checkMessageSize(content []byte, maxMessageBytes int) error {
+	if maxMessageBytes > 0 && len(content) > maxMessageBytes {
+		return xerrors.Errorf("content is %d bytes, exceeds %d bytes limit of the channel: %w", len(content), maxMessageBytes, domain.ErrMessageTooLarge)
+	}
+	return nil
+}
+
+func parseMessageAttributeHeaders(header http.Header) (domain.MessageAttributes, error) {
+	attrs := map[string]string{}
+	for key, values := range header {
+		// Keys are canonicalized by net/http (e.g. "X-Dsps-Attr-Event-Type").
+		if len(key) <= len(messageAttributeHeaderPrefix) || !strings.EqualFold(key[:len(messageAttributeHeaderPrefix)], messageAttributeHeaderPrefix) {
+			continue
+		}
+		name := strings.ToLower(key[len(messageAttributeHeaderPrefix):])
+		if len(values) != 1 {
+			return nil, xerrors.Errorf("Message attribute \"%s\" must be specified only once", name)
+		}
+		attrs[name] = values[0]
+	}
+	return domain.ParseMessageAttributes(attrs)
+}
+
+// Returns false if failed, error response has been sent in that case.
+func preparePublish(ctx context.Context, w router.ResponseWriter, webhookQueue delivery.Queue, telemetry *telemetry.Telemetry, channelID domain.ChannelID, messages []domain.Message) bool {
+	// Outgoing-webhook receives messages through internal subscribers, thus must prepare them before publish.
+	if err := webhookQueue.Prepare(ctx, channelID); err != nil {
+		if errors.Is(err, domain.ErrInvalidChannel) {
+			utils.SendError(ctx, w, http.StatusForbidden, err.Error(), err)
+		} else {
+			utils.SendInternalServerError(ctx, w, err)
+		}
+		return false
+	}
+
+	// Keep trace context of the publisher, so that subscribers can continue the trace.
+	traceParent := telemetry.TraceParent(ctx)
+	for i := range messages {
+		messages[i].TraceParent = traceParent
+	}
+	return true
+}
+
+func sendPublishError(ctx context.Context, w router.ResponseWriter, channel domain.Channel, err error) {
+	if errors.Is(err, domain.ErrInvalidChannel) {
+		// Could not create/access to the channel because not permitted by configuration
+		utils.SendError(ctx, w, http.StatusForbidden, err.Error(), err)
+	} else if errors.Is(err, domain.ErrBacklogFull) {
+		// Retained messages free the room by expiration at the latest
+		w.Header().Set("Retry-After", strconv.FormatInt(middleware.RetryAfterSeconds(channel.Expire()), 10))
+		utils.SendError(ctx, w, http.StatusTooManyRequests, "Channel backlog is full", err)
+	} else {
+		utils.SendInternalServerError(ctx, w, err)
+	}
+}
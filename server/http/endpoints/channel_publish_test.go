@@ -2,9 +2,14 @@ package endpoints_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -21,6 +26,7 @@ func TestPublishEndpointsWithoutPubSubSupport(t *testing.T) {
 	storage := NewMockStorage(ctrl)
 	storage.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
 	storage.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
 
 	chID := "my-channel"
 	msgID := "msg-1"
@@ -43,7 +49,7 @@ func TestChannelPublishSuccess(t *testing.T) {
 	content := `{"hi":"hello!"}`
 	sl := domain.SubscriberLocator{ChannelID: domain.ChannelID(chID), SubscriberID: "sbsc-1"}
 	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
-		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(ctx, sl))
+		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
 
 		res := DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, msgID), content)
 		AssertResponseJSON(t, res, 200, map[string]interface{}{
@@ -65,6 +71,61 @@ func TestChannelPublishSuccess(t *testing.T) {
 	})
 }
 
+func TestChannelPublishAttributes(t *testing.T) {
+	chID := "my-channel"
+	sbscURL := func(baseURL string) string {
+		return fmt.Sprintf("%s/channel/%s/subscription/polling/sbsc-1", baseURL, chID)
+	}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		AssertResponseJSON(t, DoHTTPRequest(t, "PUT", sbscURL(baseURL), ``), 200, map[string]interface{}{
+			"channelID":    chID,
+			"subscriberID": "sbsc-1",
+		})
+
+		res := DoHTTPRequestWithHeaders(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, "msg-1"), map[string]string{
+			"X-DSPS-Attr-Event-Type": "created",
+			"x-dsps-attr-trace.id":   "abc",
+			"X-Other-Header":         "ignored",
+		}, `{"hi":"hello!"}`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messageID": "msg-1",
+		})
+
+		res = DoHTTPRequestWithHeaders(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, "msg-2"), map[string]string{
+			"X-DSPS-Attr-Invalid+Name": "value",
+		}, `{"hi":"hello!"}`)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "attributes" parameter`)
+
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), `[
+			{"messageID":"msg-3","content":{},"attributes":{"event-type":"deleted"}},
+			{"messageID":"msg-4","content":{},"attributes":{"INVALID":"value"}}
+		]`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messages": []interface{}{
+				map[string]interface{}{"messageID": "msg-3", "status": "published"},
+				map[string]interface{}{"messageID": "msg-4", "status": "rejected", "error": `Message attribute name must match with ^[0-9a-z][0-9a-z_.-]{0,62}$`},
+			},
+		})
+
+		res = DoHTTPRequest(t, "GET", sbscURL(baseURL), ``)
+		body := BodyJSONMapOfRes(t, res)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"messageID":  "msg-1",
+				"content":    map[string]interface{}{"hi": "hello!"},
+				"attributes": map[string]interface{}{"event-type": "created", "trace.id": "abc"},
+			},
+			map[string]interface{}{
+				"messageID":  "msg-3",
+				"content":    map[string]interface{}{},
+				"attributes": map[string]interface{}{"event-type": "deleted"},
+			},
+		}, body["messages"])
+	})
+}
+
 func TestChannelPublishFailure(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -97,3 +158,295 @@ func TestChannelPublishFailure(t *testing.T) {
 		AssertInternalServerErrorResponse(t, res)
 	})
 }
+
+func TestChannelPublishLimits(t *testing.T) {
+	ctx := context.Background()
+	config := `
+logging: category: "*": FATAL
+channels:
+  - regex: 'limited-.+'
+    maxMessageBytes: 16
+    maxBacklog: 2
+`
+	WithServer(t, config, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		chID := "limited-1"
+		sl := domain.SubscriberLocator{ChannelID: domain.ChannelID(chID), SubscriberID: "sbsc-1"}
+		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
+
+		res := DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, "msg-0"), `{"hi":"hello, world!"}`)
+		AssertErrorResponse(t, res, 413, domain.ErrMessageTooLarge, `Message content too large`)
+
+		res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, "msg-1"), `{"hi":"hello!"}`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{"messageID": "msg-1"})
+
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), `[
+			{"messageID":"msg-2","content":{"hi":"hello, world!"}},
+			{"messageID":"msg-3","content":{}}
+		]`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messages": []interface{}{
+				map[string]interface{}{"messageID": "msg-2", "status": "rejected", "code": "dsps.message.too-large", "error": "content is 22 bytes, exceeds 16 bytes limit of the channel: dsps.message.too-large"},
+				map[string]interface{}{"messageID": "msg-3", "status": "published"},
+			},
+		})
+
+		// Backlog is full (default overflow policy is reject)
+		res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, "msg-4"), `{}`)
+		AssertErrorResponse(t, res, 429, domain.ErrBacklogFull, `Channel backlog is full`)
+		assert.Equal(t, "1800", res.Header.Get("Retry-After"))
+
+		// Batch publish reports the rejected message and following ones
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), `[
+			{"messageID":"msg-3","content":{}},
+			{"messageID":"msg-5","content":{}},
+			{"messageID":"msg-6","content":{"hi":"hello, world!"}},
+			{"messageID":"msg-7","content":{}}
+		]`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messages": []interface{}{
+				map[string]interface{}{"messageID": "msg-3", "status": "published"},
+				map[string]interface{}{"messageID": "msg-5", "status": "rejected", "code": "dsps.storage.backlog-full", "error": "Channel backlog is full: dsps.storage.backlog-full"},
+				map[string]interface{}{"messageID": "msg-6", "status": "rejected", "code": "dsps.message.too-large", "error": "content is 22 bytes, exceeds 16 bytes limit of the channel: dsps.message.too-large"},
+				map[string]interface{}{"messageID": "msg-7", "status": "rejected", "code": "dsps.storage.backlog-full", "error": "Channel backlog is full: dsps.storage.backlog-full"},
+			},
+		})
+
+		fetched, _, _, err := deps.Storage.AsPubSubStorage().FetchMessages(ctx, sl, 10, domain.Duration{Duration: 1})
+		assert.NoError(t, err)
+		if assert.Equal(t, 2, len(fetched)) {
+			assert.Equal(t, "msg-1", string(fetched[0].MessageID))
+			assert.Equal(t, "msg-3", string(fetched[1].MessageID))
+		}
+	})
+}
+
+func TestChannelPublishOutgoingWebhook(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var decoded map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &decoded))
+		select {
+		case received <- decoded:
+		default:
+			assert.Fail(t, "outgoing-webhook delivered more than once")
+		}
+	}))
+	defer webhookServer.Close()
+
+	chID := "my-channel"
+	msgID := "msg-1"
+	WithServer(t, fmt.Sprintf(`
+logging: category: "*": FATAL
+channels:
+	-
+		regex: '.+'
+		webhooks:
+			- url: '%s/webhook'
+`, webhookServer.URL), func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequestWithHeaders(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, msgID), map[string]string{
+			"X-DSPS-Attr-Event-Type": "created",
+		}, `{"hi":"hello!"}`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messageID": msgID,
+		})
+
+		select {
+		case body := <-received:
+			assert.Equal(t, map[string]interface{}{
+				"type":       "dsps.channel.outgoing-webhook",
+				"channelID":  chID,
+				"messageID":  msgID,
+				"content":    map[string]interface{}{"hi": "hello!"},
+				"attributes": map[string]interface{}{"event-type": "created"},
+			}, body)
+		case <-time.After(3 * time.Second):
+			assert.Fail(t, "outgoing-webhook not delivered")
+		}
+	})
+}
+
+func TestChannelPublishTraceContext(t *testing.T) {
+	traceParent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	received := make(chan string, 1)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("traceparent")
+	}))
+	defer webhookServer.Close()
+
+	chID := "my-channel"
+	sbscURL := func(baseURL string) string {
+		return fmt.Sprintf("%s/channel/%s/subscription/polling/sbsc-1", baseURL, chID)
+	}
+	WithServer(t, fmt.Sprintf(`
+logging: category: "*": FATAL
+channels:
+	-
+		regex: '.+'
+		webhooks:
+			- url: '%s/webhook'
+`, webhookServer.URL), func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		AssertResponseJSON(t, DoHTTPRequest(t, "PUT", sbscURL(baseURL), ``), 200, map[string]interface{}{
+			"channelID":    chID,
+			"subscriberID": "sbsc-1",
+		})
+
+		res := DoHTTPRequestWithHeaders(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, "msg-1"), map[string]string{
+			"traceparent": traceParent,
+		}, `{}`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messageID": "msg-1",
+		})
+		res = DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, chID, "msg-2"), `{}`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messageID": "msg-2",
+		})
+
+		// Tracing is disabled in this test, thus trace context is passed through as is.
+		res = DoHTTPRequest(t, "GET", sbscURL(baseURL), ``)
+		body := BodyJSONMapOfRes(t, res)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"messageID":   "msg-1",
+				"content":     map[string]interface{}{},
+				"traceparent": traceParent,
+			},
+			map[string]interface{}{
+				"messageID": "msg-2",
+				"content":   map[string]interface{}{},
+			},
+		}, body["messages"])
+
+		for _, expected := range []string{traceParent, ""} {
+			select {
+			case header := <-received:
+				assert.Equal(t, expected, header)
+			case <-time.After(3 * time.Second):
+				assert.Fail(t, "outgoing-webhook not delivered")
+			}
+		}
+	})
+}
+
+func TestChannelPublishOutgoingWebhookPrepareFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage, pubsub, _ := NewMockStorages(ctrl)
+
+	WithServer(t, `
+logging: category: "*": FATAL
+channels:
+	-
+		regex: '.+'
+		webhooks:
+			- url: 'http://localhost:3001/webhook'
+`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		// Should not publish message if failed to create internal subscriber for outgoing-webhook
+		pubsub.EXPECT().NewQueueSubscriber(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("mock error"))
+		res := DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/%s", baseURL, "my-channel", "msg-1"), `{}`)
+		AssertInternalServerErrorResponse(t, res)
+	})
+}
+
+func TestChannelBatchPublishSuccess(t *testing.T) {
+	ctx := context.Background()
+	chID := "my-channel"
+	sl := domain.SubscriberLocator{ChannelID: domain.ChannelID(chID), SubscriberID: "sbsc-1"}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		assert.NoError(t, deps.Storage.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
+
+		body := `[
+			{ "messageID": "msg-1", "content": { "hi": "hello!" } },
+			{ "messageID": "** INVALID MESSAGE ID **", "content": {} },
+			{ "messageID": "msg-2" },
+			{ "messageID": "msg-3", "content": "text" }
+		]`
+		res := DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), body)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messages": []interface{}{
+				map[string]interface{}{"messageID": "msg-1", "status": "published"},
+				map[string]interface{}{"messageID": "** INVALID MESSAGE ID **", "status": "rejected", "error": "MessageID must match with ^[0-9a-z][0-9a-z_-]{0,62}$"},
+				map[string]interface{}{"messageID": "msg-2", "status": "rejected", "error": "content is required"},
+				map[string]interface{}{"messageID": "msg-3", "status": "published"},
+			},
+		})
+
+		fetched, _, _, err := deps.Storage.AsPubSubStorage().FetchMessages(ctx, sl, 10, domain.Duration{Duration: 1})
+		assert.NoError(t, err)
+		if assert.Equal(t, 2, len(fetched)) {
+			assert.Equal(t, "msg-1", string(fetched[0].MessageID))
+			assert.JSONEq(t, `{"hi":"hello!"}`, string(fetched[0].Content))
+			assert.Equal(t, "msg-3", string(fetched[1].MessageID))
+			assert.JSONEq(t, `"text"`, string(fetched[1].Content))
+		}
+
+		// Should be idempotent
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), `[{ "messageID": "msg-1", "content": { "hi": "hello!" } }]`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messages": []interface{}{
+				map[string]interface{}{"messageID": "msg-1", "status": "published"},
+			},
+		})
+		fetched, _, _, err = deps.Storage.AsPubSubStorage().FetchMessages(ctx, sl, 10, domain.Duration{Duration: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(fetched))
+
+		// Empty request
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), `[]`)
+		AssertResponseJSON(t, res, 200, map[string]interface{}{
+			"channelID": chID,
+			"messages":  []interface{}{},
+		})
+	})
+}
+
+func TestChannelBatchPublishFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage, pubsub, _ := NewMockStorages(ctrl)
+
+	chID := "my-channel"
+	body := `[{ "messageID": "msg-1", "content": {} }, { "messageID": "msg-2", "content": {} }]`
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, "** INVALID CHANNEL ID **"), body)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "channelID" parameter`)
+
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), `{ "messageID": "msg-1", "content": {} }`)
+		AssertErrorResponse(t, res, 400, nil, `Request body is not JSON array`)
+
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), `[`)
+		AssertErrorResponse(t, res, 400, nil, `Request body is not JSON array`)
+
+		tooMany := make([]map[string]interface{}, 1001)
+		for i := range tooMany {
+			tooMany[i] = map[string]interface{}{"messageID": fmt.Sprintf("msg-%d", i), "content": i}
+		}
+		tooManyJSON, err := json.Marshal(tooMany)
+		assert.NoError(t, err)
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), string(tooManyJSON))
+		AssertErrorResponse(t, res, 400, nil, `Too many messages in a request \(max: 1000\)`)
+
+		pubsub.EXPECT().PublishMessages(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msgs []domain.Message) error {
+			assert.Equal(t, 2, len(msgs))
+			return domain.ErrInvalidChannel
+		})
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), body)
+		AssertErrorResponse(t, res, 403, domain.ErrInvalidChannel, "")
+
+		pubsub.EXPECT().PublishMessages(gomock.Any(), gomock.Any()).Return(errors.New("mock error"))
+		res = DoHTTPRequest(t, "POST", fmt.Sprintf("%s/channel/%s/messages", baseURL, chID), body)
+		AssertInternalServerErrorResponse(t, res)
+	})
+}
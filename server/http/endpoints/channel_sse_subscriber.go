@@ -0,0 +1,230 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/http/lifecycle"
+	"github.com/m3dev/dsps/server/http/router"
+	"github.com/m3dev/dsps/server/http/utils"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+// Max duration of each FetchMessages call, server sends keep-alive comment when no message arrived within it.
+var sseKeepAliveInterval = 15 * time.Second
+
+// Interval to re-check the subscriber when all of fetched messages had been already sent to the client (awaiting acknowledgement).
+var sseRedeliveryCheckInterval = 1 * time.Second
+
+// SSEEndpointDependency is to inject required objects to the endpoint
+type SSEEndpointDependency interface {
+	GetServerClose() lifecycle.ServerClose
+	GetStorage() domain.Storage
+
+	GetLongPollingMaxTimeout() domain.Duration
+}
+
+// InitSubscriptionSSEEndpoints registers endpoints
+func InitSubscriptionSSEEndpoints(channelRouter *router.Router, deps SSEEndpointDependency) {
+	group := channelRouter.NewGroup(
+		"/subscription/sse/:subscriberID",
+		router.AsMiddlewareFunc(func(ctx context.Context, args router.MiddlewareArgs, next func(context.Context, router.MiddlewareArgs)) {
+			next(logger.WithAttributes(ctx).WithStr("subscriberID", args.PS.ByName("subscriberID")).Build(), args)
+		}),
+	)
+	group.GET("", subscriberSSEEndpoint(deps))
+}
+
+func subscriberSSEEndpoint(deps SSEEndpointDependency) router.Handler {
+	pubsub := deps.GetStorage().AsPubSubStorage()
+	serverClose := deps.GetServerClose()
+	longPollingMaxTimeout := deps.GetLongPollingMaxTimeout().Duration
+	return func(ctx context.Context, args router.HandlerArgs) {
+		if pubsub == nil {
+			utils.SendPubSubUnsupportedError(ctx, args.W)
+			return
+		}
+
+		channelID, err := domain.ParseChannelID(args.PS.ByName("channelID"))
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "channelID", err)
+			return
+		}
+
+		subscriberID, err := domain.ParseSubscriberID(args.PS.ByName("subscriberID"))
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "subscriberID", err)
+			return
+		}
+
+		timeout, err := time.ParseDuration(args.R.GetQueryParamOrDefault("timeout", longPollingMaxTimeout.String()))
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "timeout", err)
+			return
+		}
+		if timeout > longPollingMaxTimeout {
+			logger.Of(ctx).Infof(logger.CatHTTP, "Client requested SSE timeout %v is too long, rounded to longPollingMaxTimeout (%v)", timeout, longPollingMaxTimeout)
+			timeout = longPollingMaxTimeout
+		}
+
+		max, err := strconv.ParseInt(args.R.GetQueryParamOrDefault("max", "64"), 10, 0)
+		if err != nil {
+			utils.SendInvalidParameter(ctx, args.W, "max", err)
+			return
+		}
+
+		sl := domain.SubscriberLocator{
+			ChannelID:    channelID,
+			SubscriberID: subscriberID,
+		}
+		serverClose.WithCancel(ctx, func(ctxWithCancel context.Context) {
+			// Fetch once before sending response header to report errors with ordinary status code.
+			msgs, _, ackHandle, err := pubsub.FetchMessages(ctxWithCancel, sl, int(max), domain.Duration{Duration: 0})
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					// Send empty stream rather than error status, otherwise client does not reconnect.
+					logger.Of(ctx).Infof(logger.CatHTTP, "SSE canceled due to context cancel, returned empty stream to client.")
+					msgs = []domain.Message{}
+					ackHandle = domain.AckHandle{}
+					// Continue to normal flow
+				} else {
+					if errors.Is(err, domain.ErrInvalidChannel) {
+						utils.SendError(ctx, args.W, http.StatusForbidden, err.Error(), err)
+					} else if errors.Is(err, domain.ErrSubscriptionNotFound) {
+						// Channel / subscriber might be expired or intentionally deleted.
+						utils.SendError(ctx, args.W, http.StatusNotFound, err.Error(), err)
+					} else {
+						utils.SendInternalServerError(ctx, args.W, err)
+					}
+					return
+				}
+			}
+
+			args.W.Header().Set("Content-Type", "text/event-stream")
+			args.W.Header().Set("Cache-Control", "no-cache")
+			args.W.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (e.g. nginx)
+			args.W.WriteHeader(http.StatusOK)
+
+			streamCtx, cancel := context.WithTimeout(ctxWithCancel, timeout)
+			defer cancel()
+			stream := sseStream{w: args.W, channelID: channelID, sent: map[domain.MessageID]bool{}}
+			stream.sendMessages(ctx, msgs, ackHandle)
+
+			for {
+				wait := sseKeepAliveInterval
+				if deadline, ok := streamCtx.Deadline(); ok && time.Until(deadline) < wait {
+					wait = time.Until(deadline)
+				}
+				if wait <= 0 || streamCtx.Err() != nil {
+					return
+				}
+
+				msgs, _, ackHandle, err := pubsub.FetchMessages(streamCtx, sl, int(max), domain.Duration{Duration: wait})
+				if err != nil {
+					if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+						return // End of stream, client will reconnect.
+					}
+					stream.sendError(ctx, err)
+					return
+				}
+				if len(msgs) == 0 {
+					stream.sendKeepAlive(ctx)
+					continue
+				}
+				if stream.sendMessages(ctx, msgs, ackHandle) == 0 {
+					// All messages have been sent but not acknowledged yet, await client's acknowledgement.
+					select {
+					case <-streamCtx.Done():
+						return
+					case <-time.After(sseRedeliveryCheckInterval):
+					}
+				}
+			}
+		})
+	}
+}
+
+type sseStream struct {
+	w         router.ResponseWriter
+	channelID domain.ChannelID
+
+	// Messages that already sent to the client but not acknowledged yet.
+	sent map[domain.MessageID]bool
+}
+
+// Returns count of newly sent messages.
+func (stream *sseStream) sendMessages(ctx context.Context, msgs []domain.Message, ackHandle domain.AckHandle) int {
+	sent := make(map[domain.MessageID]bool, len(msgs))
+	newMsgs := 0
+	for _, msg := range msgs {
+		sent[msg.MessageID] = true
+		if stream.sent[msg.MessageID] {
+			continue
+		}
+		newMsgs++
+		data := map[string]interface{}{
+			"channelID": stream.channelID,
+			"messageID": msg.MessageID,
+			"content":   msg.Content,
+			"ackHandle": ackHandle.Handle,
+		}
+		if len(msg.Attributes) > 0 {
+			data["attributes"] = msg.Attributes
+		}
+		if msg.TraceParent != "" {
+			data["traceparent"] = msg.TraceParent
+		}
+		stream.write(ctx, string(msg.MessageID), "message", data)
+	}
+	// Forget acknowledged messages.
+	stream.sent = sent
+	if newMsgs > 0 {
+		stream.w.Flush()
+	}
+	return newMsgs
+}
+
+func (stream *sseStream) sendError(ctx context.Context, err error) {
+	body := map[string]interface{}{"error": "Internal Server Error"}
+	if errWithCode := domain.NewErrorWithCode(""); errors.As(err, &errWithCode) {
+		body["error"] = err.Error()
+		body["code"] = errWithCode.Code()
+		logger.Of(ctx).InfoError(logger.CatHTTP, "Sending error event to SSE client", err)
+	} else {
+		logger.Of(ctx).Error("error occurred during SSE streaming", err)
+	}
+	stream.write(ctx, "", "error", body)
+	stream.w.Flush()
+}
+
+func (stream *sseStream) sendKeepAlive(ctx context.Context) {
+	if _, err := stream.w.Write([]byte(":\n\n")); err != nil {
+		logger.Of(ctx).Debugf(logger.CatHTTP, "failed to write SSE keep-alive: %v", err)
+	}
+	stream.w.Flush()
+}
+
+func (stream *sseStream) write(ctx context.Context, id string, event string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		panic(xerrors.Errorf("failed to encode SSE data: %w", err))
+	}
+
+	var frame string
+	if id != "" {
+		frame = fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", id, event, encoded)
+	} else {
+		frame = fmt.Sprintf("event: %s\ndata: %s\n\n", event, encoded)
+	}
+	if _, err := stream.w.Write([]byte(frame)); err != nil {
+		logger.Of(ctx).Debugf(logger.CatHTTP, "failed to write SSE event: %v", err)
+	}
+}
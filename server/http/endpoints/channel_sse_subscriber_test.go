@@ -0,0 +1,224 @@
+package endpoints_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/domain/mock"
+	. "github.com/m3dev/dsps/server/http"
+	. "github.com/m3dev/dsps/server/http/testing"
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  map[string]interface{}
+}
+
+func readSSEEvents(t *testing.T, res *http.Response) []sseEvent {
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	raw, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+
+	events := []sseEvent{}
+	for _, frame := range strings.Split(string(raw), "\n\n") {
+		ev := sseEvent{}
+		for _, line := range strings.Split(frame, "\n") {
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				ev.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.Data))
+			}
+		}
+		if ev.Event != "" {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func TestSSEEndpointsWithoutPubSubSupport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
+
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s", baseURL, "my-channel", "sbsc-1"), ``)
+		AssertErrorResponse(t, res, 501, nil, `No PubSub compatible storage available`)
+	})
+}
+
+func TestSSESubscriberSuccess(t *testing.T) {
+	ctx := context.Background()
+	sl := domain.SubscriberLocator{
+		ChannelID:    "my-channel",
+		SubscriberID: "sbsc-1",
+	}
+	msgs := make([]domain.Message, 3)
+	for i := range msgs {
+		msgs[i] = domain.Message{
+			MessageLocator: domain.MessageLocator{
+				ChannelID: sl.ChannelID,
+				MessageID: domain.MessageID(fmt.Sprintf("msg-%d", i)),
+			},
+			Content: json.RawMessage(fmt.Sprintf(`{"hi": "hello %d"}`, i)),
+		}
+	}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		pubsub := deps.Storage.AsPubSubStorage()
+		assert.NoError(t, pubsub.NewSubscriber(ctx, sl, nil))
+		assert.NoError(t, pubsub.PublishMessages(ctx, msgs[0:2]))
+
+		// Publish a message while streaming
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			assert.NoError(t, pubsub.PublishMessages(ctx, msgs[2:3]))
+		}()
+		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?timeout=1500ms", baseURL, sl.ChannelID, sl.SubscriberID), ``)
+		events := readSSEEvents(t, res)
+		if !assert.Equal(t, 3, len(events)) {
+			return
+		}
+		for i, ev := range events {
+			assert.Equal(t, "message", ev.Event)
+			assert.Equal(t, string(msgs[i].MessageID), ev.ID)
+			assert.Equal(t, string(sl.ChannelID), ev.Data["channelID"])
+			assert.Equal(t, string(msgs[i].MessageID), ev.Data["messageID"])
+			assert.Equal(t, map[string]interface{}{"hi": fmt.Sprintf("hello %d", i)}, ev.Data["content"])
+			assert.NotEmpty(t, ev.Data["ackHandle"])
+		}
+		assert.Equal(t, events[0].Data["ackHandle"], events[1].Data["ackHandle"]) // Same batch
+
+		// Acknowledge with the handle of the last event
+		res = DoHTTPRequest(t, "DELETE", fmt.Sprintf("%s/channel/%s/subscription/polling/%s/message?ackHandle=%s", baseURL, sl.ChannelID, sl.SubscriberID, events[2].Data["ackHandle"]), ``)
+		assert.Equal(t, 204, res.StatusCode)
+
+		// No more messages
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?timeout=500ms", baseURL, sl.ChannelID, sl.SubscriberID), ``)
+		assert.Equal(t, 0, len(readSSEEvents(t, res)))
+	})
+}
+
+func TestSSESubscriberUnacknowledgedMessages(t *testing.T) {
+	ctx := context.Background()
+	sl := domain.SubscriberLocator{
+		ChannelID:    "my-channel",
+		SubscriberID: "sbsc-1",
+	}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		pubsub := deps.Storage.AsPubSubStorage()
+		assert.NoError(t, pubsub.NewSubscriber(ctx, sl, nil))
+		assert.NoError(t, pubsub.PublishMessages(ctx, []domain.Message{{
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-1"},
+			Content:        json.RawMessage(`{}`),
+		}}))
+
+		// Should not send same message twice in a stream
+		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?timeout=1500ms", baseURL, sl.ChannelID, sl.SubscriberID), ``)
+		events := readSSEEvents(t, res)
+		if assert.Equal(t, 1, len(events)) {
+			assert.Equal(t, "msg-1", events[0].ID)
+		}
+
+		// Resend unacknowledged message in the next stream
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?timeout=100ms", baseURL, sl.ChannelID, sl.SubscriberID), ``)
+		events = readSSEEvents(t, res)
+		if assert.Equal(t, 1, len(events)) {
+			assert.Equal(t, "msg-1", events[0].ID)
+		}
+	})
+}
+
+func TestSSESubscriberFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage, pubsub, _ := NewMockStorages(ctrl)
+
+	sl := domain.SubscriberLocator{
+		ChannelID:    "my-channel",
+		SubscriberID: "sbsc-1",
+	}
+	max := 16
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s", baseURL, "*** INVALID ***", sl.SubscriberID), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "channelID" parameter`)
+
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s", baseURL, sl.ChannelID, "*** INVALID ***"), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "subscriberID" parameter`)
+
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?timeout=INVALID", baseURL, sl.ChannelID, sl.SubscriberID), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "timeout" parameter`)
+
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?max=INVALID", baseURL, sl.ChannelID, sl.SubscriberID), ``)
+		AssertErrorResponse(t, res, 400, nil, `Invalid "max" parameter`)
+
+		pubsub.EXPECT().FetchMessages(gomock.Any(), sl, max, domain.Duration{}).Return([]domain.Message{}, false, domain.AckHandle{}, domain.ErrInvalidChannel)
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?max=%d", baseURL, sl.ChannelID, sl.SubscriberID, max), ``)
+		AssertErrorResponse(t, res, 403, domain.ErrInvalidChannel, "")
+
+		pubsub.EXPECT().FetchMessages(gomock.Any(), sl, max, domain.Duration{}).Return([]domain.Message{}, false, domain.AckHandle{}, domain.ErrSubscriptionNotFound)
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?max=%d", baseURL, sl.ChannelID, sl.SubscriberID, max), ``)
+		AssertErrorResponse(t, res, 404, domain.ErrSubscriptionNotFound, "")
+
+		pubsub.EXPECT().FetchMessages(gomock.Any(), sl, max, domain.Duration{}).Return([]domain.Message{}, false, domain.AckHandle{}, errors.New("mock error"))
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?max=%d", baseURL, sl.ChannelID, sl.SubscriberID, max), ``)
+		AssertInternalServerErrorResponse(t, res)
+
+		// Error after streaming started
+		pubsub.EXPECT().FetchMessages(gomock.Any(), sl, max, domain.Duration{}).Return([]domain.Message{}, false, domain.AckHandle{}, nil)
+		pubsub.EXPECT().FetchMessages(gomock.Any(), sl, max, gomock.Any()).Return([]domain.Message{}, false, domain.AckHandle{}, domain.ErrSubscriptionNotFound)
+		res = DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?max=%d", baseURL, sl.ChannelID, sl.SubscriberID, max), ``)
+		events := readSSEEvents(t, res)
+		if assert.Equal(t, 1, len(events)) {
+			assert.Equal(t, "error", events[0].Event)
+			assert.Equal(t, domain.ErrSubscriptionNotFound.Code(), events[0].Data["code"])
+		}
+	})
+}
+
+func TestSSESubscriberServerClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage, pubsub, _ := NewMockStorages(ctrl)
+
+	sl := domain.SubscriberLocator{
+		ChannelID:    "my-channel",
+		SubscriberID: "sbsc-1",
+	}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		deps.ServerClose.Close()
+
+		pubsub.EXPECT().FetchMessages(gomock.Any(), sl, 64, domain.Duration{}).DoAndReturn(func(ctx context.Context, _ domain.SubscriberLocator, max int, timeout domain.Duration) ([]domain.Message, bool, domain.AckHandle, error) {
+			assert.Error(t, ctx.Err(), "context should be closed")
+			return []domain.Message{}, false, domain.AckHandle{}, ctx.Err()
+		})
+		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/%s/subscription/sse/%s?timeout=10s", baseURL, sl.ChannelID, sl.SubscriberID), ``)
+		assert.Equal(t, 0, len(readSSEEvents(t, res)))
+	})
+}
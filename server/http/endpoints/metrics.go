@@ -0,0 +1,25 @@
+package endpoints
+
+import (
+	"context"
+
+	"github.com/m3dev/dsps/server/http/router"
+	"github.com/m3dev/dsps/server/telemetry"
+)
+
+// MetricsEndpointDependency is to inject required objects to the endpoint
+type MetricsEndpointDependency interface {
+	GetTelemetry() *telemetry.Telemetry
+	GetMetricsPath() string
+}
+
+// InitMetricsEndpoints registers endpoints, do nothing if Prometheus metrics disabled
+func InitMetricsEndpoints(rt *router.Router, deps MetricsEndpointDependency) {
+	handler := deps.GetTelemetry().MetricsHandler()
+	if handler == nil {
+		return
+	}
+	rt.GET(deps.GetMetricsPath(), func(ctx context.Context, args router.HandlerArgs) {
+		handler.ServeHTTP(args.W, args.R.Request)
+	})
+}
@@ -0,0 +1,35 @@
+package endpoints_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/http"
+	. "github.com/m3dev/dsps/server/http/testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	WithServer(t, `{ logging: { category: { "*": FATAL } }, telemetry: { prometheus: { enable: true } } }`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "PUT", baseURL+"/channel/my-channel/message/msg-1", `{}`)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 200, res.StatusCode)
+
+		res = DoHTTPRequest(t, "GET", baseURL+"/metrics", "")
+		body, err := ioutil.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Contains(t, string(body), `dsps_published_messages_total{channel=".+"} 1`)
+		assert.Contains(t, string(body), `go_goroutines`)
+	})
+}
+
+func TestMetricsEndpointDisabled(t *testing.T) {
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "GET", baseURL+"/metrics", "")
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 404, res.StatusCode)
+	})
+}
@@ -0,0 +1,505 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/http/lifecycle"
+	"github.com/m3dev/dsps/server/http/middleware"
+	"github.com/m3dev/dsps/server/http/router"
+	"github.com/m3dev/dsps/server/http/utils"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+var (
+	// ErrWebSocketNotSubscribed : Client sent fetch/ack frame for the subscriber that is not subscribed on the connection
+	ErrWebSocketNotSubscribed = domain.NewErrorWithCode("dsps.websocket.not-subscribed")
+	// ErrWebSocketMalformedFrame : Client sent a frame that could not be decoded
+	ErrWebSocketMalformedFrame = domain.NewErrorWithCode("dsps.websocket.malformed-frame")
+)
+
+// Interval of ping control frame to keep connection alive.
+var wsPingInterval = 30 * time.Second
+
+// Server closes connection if client does not respond to ping within this duration.
+var wsPongTimeout = 60 * time.Second
+
+// Timeout to write each frame.
+var wsWriteTimeout = 10 * time.Second
+
+// Interval to re-check the subscriber when all of fetched messages had been already pushed to the client (awaiting acknowledgement).
+var wsRedeliveryCheckInterval = 1 * time.Second
+
+// Max size of the frame sent from client.
+const wsReadLimit = 64 * 1024
+
+// Default count of messages to fetch at once.
+const wsDefaultFetchMax = 64
+
+// WebSocketEndpointDependency is to inject required objects to the endpoint
+type WebSocketEndpointDependency interface {
+	GetServerClose() lifecycle.ServerClose
+	GetStorage() domain.Storage
+	GetChannelProvider() domain.ChannelProvider
+
+	GetLongPollingMaxTimeout() domain.Duration
+}
+
+// InitSubscriptionWebSocketEndpoints registers endpoints.
+// Subscribe and fetch frames are counted with fetchLimiter.
+func InitSubscriptionWebSocketEndpoints(rt *router.Router, deps WebSocketEndpointDependency, fetchLimiter *middleware.RateLimiter) {
+	rt.GET("/subscription/websocket", subscriberWebSocketEndpoint(deps, fetchLimiter))
+}
+
+var wsUpgrader = websocket.Upgrader{
+	// Authentication relies on bearer token rather than cookie, so that cross-origin connection is harmless.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func subscriberWebSocketEndpoint(deps WebSocketEndpointDependency, fetchLimiter *middleware.RateLimiter) router.Handler {
+	pubsub := deps.GetStorage().AsPubSubStorage()
+	jwtStorage := deps.GetStorage().AsJwtStorage()
+	channelProvider := deps.GetChannelProvider()
+	serverClose := deps.GetServerClose()
+	longPollingMaxTimeout := deps.GetLongPollingMaxTimeout()
+	return func(ctx context.Context, args router.HandlerArgs) {
+		if pubsub == nil {
+			utils.SendPubSubUnsupportedError(ctx, args.W)
+			return
+		}
+
+		// Token in Authorization header is used for subscribe frames without token.
+		defaultToken := utils.GetBearerToken(ctx, router.MiddlewareArgs{HandlerArgs: args})
+		clientIP := fetchLimiter.ClientIP(args.R)
+
+		conn, err := wsUpgrader.Upgrade(args.W, args.R.Request, nil)
+		if err != nil {
+			// Upgrader already sent error response.
+			logger.Of(ctx).Infof(logger.CatHTTP, "Failed to upgrade to WebSocket: %v", err)
+			return
+		}
+
+		serverClose.WithCancel(ctx, func(ctxWithCancel context.Context) {
+			session := &wsSession{
+				conn:                  conn,
+				pubsub:                pubsub,
+				jwtStorage:            jwtStorage,
+				channelProvider:       channelProvider,
+				longPollingMaxTimeout: longPollingMaxTimeout,
+				defaultToken:          defaultToken,
+				fetchLimiter:          fetchLimiter,
+				clientIP:              clientIP,
+				subscriptions:         map[domain.SubscriberLocator]*wsSubscription{},
+			}
+			session.run(ctx, ctxWithCancel)
+		})
+	}
+}
+
+// Frame sent from client.
+type wsRequestFrame struct {
+	Type string `json:"type"`
+	// Client defined ID to correlate request and response, echoed back in the response frame.
+	ID string `json:"id"`
+
+	ChannelID    string `json:"channelID"`
+	SubscriberID string `json:"subscriberID"`
+
+	Token     string          `json:"token"`     // subscribe
+	Filter    json.RawMessage `json:"filter"`    // subscribe
+	Max       int             `json:"max"`       // fetch
+	AckHandle string          `json:"ackHandle"` // ack
+}
+
+type wsSession struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+
+	pubsub                domain.PubSubStorage
+	jwtStorage            domain.JwtStorage
+	channelProvider       domain.ChannelProvider
+	longPollingMaxTimeout domain.Duration
+	defaultToken          string
+	fetchLimiter          *middleware.RateLimiter
+	clientIP              string
+
+	subscriptionsLock sync.Mutex
+	subscriptions     map[domain.SubscriberLocator]*wsSubscription
+	pushers           sync.WaitGroup
+}
+
+type wsSubscription struct {
+	cancel context.CancelFunc
+
+	// Channel and validated token of the subscribe frame, used for rate limit of fetch frames.
+	channel domain.Channel
+	token   string
+}
+
+// ctx is the request context for logging, ctxWithCancel is canceled on server close.
+func (s *wsSession) run(ctx context.Context, ctxWithCancel context.Context) {
+	sessionCtx, cancel := context.WithCancel(ctxWithCancel)
+	defer func() {
+		cancel()
+		s.pushers.Wait()
+	}()
+
+	go s.keepAlive(ctx, sessionCtx)
+
+	s.conn.SetReadLimit(wsReadLimit)
+	_ = s.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && sessionCtx.Err() == nil {
+				logger.Of(ctx).Infof(logger.CatHTTP, "WebSocket connection closed: %v", err)
+			}
+			return
+		}
+
+		var frame wsRequestFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			s.sendError(ctx, wsRequestFrame{}, "Malformed frame", domain.WrapErrorWithCode(ErrWebSocketMalformedFrame.Code(), err))
+			continue
+		}
+		switch frame.Type {
+		case "subscribe":
+			s.handleSubscribe(ctx, sessionCtx, frame)
+		case "unsubscribe":
+			s.handleUnsubscribe(ctx, frame)
+		case "fetch":
+			s.handleFetch(ctx, sessionCtx, frame)
+		case "ack":
+			s.handleAck(ctx, sessionCtx, frame)
+		default:
+			s.sendError(ctx, frame, `Unknown frame type`, ErrWebSocketMalformedFrame)
+		}
+	}
+}
+
+// Sends ping periodically, closes the connection on session end (e.g. server close).
+func (s *wsSession) keepAlive(ctx context.Context, sessionCtx context.Context) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sessionCtx.Done():
+			s.writeLock.Lock()
+			_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(wsWriteTimeout))
+			s.writeLock.Unlock()
+			if err := s.conn.Close(); err != nil {
+				logger.Of(ctx).Debugf(logger.CatHTTP, "failed to close WebSocket connection: %v", err)
+			}
+			return
+		case <-ticker.C:
+			s.writeLock.Lock()
+			err := s.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(wsWriteTimeout))
+			s.writeLock.Unlock()
+			if err != nil {
+				logger.Of(ctx).Debugf(logger.CatHTTP, "failed to send WebSocket ping: %v", err)
+			}
+		}
+	}
+}
+
+func (s *wsSession) parseLocator(ctx context.Context, frame wsRequestFrame) (domain.SubscriberLocator, bool) {
+	channelID, err := domain.ParseChannelID(frame.ChannelID)
+	if err != nil {
+		s.sendError(ctx, frame, `Invalid "channelID" parameter`, err)
+		return domain.SubscriberLocator{}, false
+	}
+	subscriberID, err := domain.ParseSubscriberID(frame.SubscriberID)
+	if err != nil {
+		s.sendError(ctx, frame, `Invalid "subscriberID" parameter`, err)
+		return domain.SubscriberLocator{}, false
+	}
+	return domain.SubscriberLocator{ChannelID: channelID, SubscriberID: subscriberID}, true
+}
+
+func (s *wsSession) getSubscription(sl domain.SubscriberLocator) *wsSubscription {
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+	return s.subscriptions[sl]
+}
+
+// Sends error frame and returns true if the frame exceeds fetch rate limit.
+func (s *wsSession) rateLimited(ctx context.Context, frame wsRequestFrame, channelID domain.ChannelID, channel domain.Channel, token string) bool {
+	limited, resetIn := s.fetchLimiter.Count(ctx, channelID, channel, token, s.clientIP)
+	if !limited {
+		return false
+	}
+	res := s.errorFrame(ctx, frame, "Too Many Requests", middleware.ErrRateLimited)
+	res["retryAfter"] = middleware.RetryAfterSeconds(resetIn)
+	s.send(ctx, res)
+	return true
+}
+
+func (s *wsSession) handleSubscribe(ctx context.Context, sessionCtx context.Context, frame wsRequestFrame) {
+	sl, ok := s.parseLocator(ctx, frame)
+	if !ok {
+		return
+	}
+
+	channel, err := s.channelProvider.Get(sl.ChannelID)
+	if err != nil {
+		s.sendError(ctx, frame, `Invalid "channelID" parameter`, err)
+		return
+	}
+	token := frame.Token
+	if token == "" {
+		token = s.defaultToken
+	}
+	if err := middleware.ValidateChannelJwt(ctx, s.jwtStorage, channel, token); err != nil {
+		logger.Of(ctx).Infof(logger.CatAuth, `JWT verification failure on WebSocket subscribe: %v`, err)
+		s.sendError(ctx, frame, "Unauthorized", middleware.ErrAuthRejection)
+		return
+	}
+	if s.rateLimited(ctx, frame, sl.ChannelID, channel, token) {
+		return
+	}
+
+	filter, err := domain.ParseSubscriberFilter(frame.Filter)
+	if err != nil {
+		s.sendError(ctx, frame, `Invalid "filter" parameter`, err)
+		return
+	}
+	if err := s.pubsub.NewSubscriber(sessionCtx, sl, filter); err != nil {
+		s.sendStorageError(ctx, frame, err)
+		return
+	}
+
+	s.subscriptionsLock.Lock()
+	if sbsc, exists := s.subscriptions[sl]; exists {
+		sbsc.channel, sbsc.token = channel, token
+	} else {
+		pusherCtx, cancel := context.WithCancel(sessionCtx)
+		s.subscriptions[sl] = &wsSubscription{cancel: cancel, channel: channel, token: token}
+		s.pushers.Add(1)
+		go func() {
+			defer s.pushers.Done()
+			s.push(ctx, pusherCtx, sl)
+		}()
+	}
+	s.subscriptionsLock.Unlock()
+
+	res := map[string]interface{}{
+		"type":         "subscribed",
+		"id":           frame.ID,
+		"channelID":    sl.ChannelID,
+		"subscriberID": sl.SubscriberID,
+	}
+	if filter != nil {
+		res["filter"] = filter
+	}
+	s.send(ctx, res)
+}
+
+func (s *wsSession) handleUnsubscribe(ctx context.Context, frame wsRequestFrame) {
+	sl, ok := s.parseLocator(ctx, frame)
+	if !ok {
+		return
+	}
+
+	s.subscriptionsLock.Lock()
+	if sbsc, exists := s.subscriptions[sl]; exists {
+		sbsc.cancel()
+		delete(s.subscriptions, sl)
+	}
+	s.subscriptionsLock.Unlock()
+
+	s.send(ctx, map[string]interface{}{
+		"type":         "unsubscribed",
+		"id":           frame.ID,
+		"channelID":    sl.ChannelID,
+		"subscriberID": sl.SubscriberID,
+	})
+}
+
+func (s *wsSession) handleFetch(ctx context.Context, sessionCtx context.Context, frame wsRequestFrame) {
+	sl, ok := s.parseLocator(ctx, frame)
+	if !ok {
+		return
+	}
+	sbsc := s.getSubscription(sl)
+	if sbsc == nil {
+		s.sendError(ctx, frame, "Not subscribed on this connection", ErrWebSocketNotSubscribed)
+		return
+	}
+	if s.rateLimited(ctx, frame, sl.ChannelID, sbsc.channel, sbsc.token) {
+		return
+	}
+
+	max := frame.Max
+	if max <= 0 {
+		max = wsDefaultFetchMax
+	}
+	msgs, moreMsg, ackHandle, err := s.pubsub.FetchMessages(sessionCtx, sl, max, domain.Duration{Duration: 0})
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			s.sendStorageError(ctx, frame, err)
+		}
+		return
+	}
+	s.sendMessages(ctx, frame.ID, sl, msgs, moreMsg, ackHandle)
+}
+
+func (s *wsSession) handleAck(ctx context.Context, sessionCtx context.Context, frame wsRequestFrame) {
+	sl, ok := s.parseLocator(ctx, frame)
+	if !ok {
+		return
+	}
+	if s.getSubscription(sl) == nil {
+		s.sendError(ctx, frame, "Not subscribed on this connection", ErrWebSocketNotSubscribed)
+		return
+	}
+	if frame.AckHandle == "" {
+		s.sendError(ctx, frame, `Missing "ackHandle" parameter`, nil)
+		return
+	}
+
+	if err := s.pubsub.AcknowledgeMessages(sessionCtx, domain.AckHandle{SubscriberLocator: sl, Handle: frame.AckHandle}); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			s.sendStorageError(ctx, frame, err)
+		}
+		return
+	}
+	s.send(ctx, map[string]interface{}{
+		"type":         "acked",
+		"id":           frame.ID,
+		"channelID":    sl.ChannelID,
+		"subscriberID": sl.SubscriberID,
+	})
+}
+
+// Pushes messages of the subscriber until unsubscribe or session end.
+func (s *wsSession) push(ctx context.Context, pusherCtx context.Context, sl domain.SubscriberLocator) {
+	ctx = logger.WithAttributes(ctx).WithStr("channelID", string(sl.ChannelID)).WithStr("subscriberID", string(sl.SubscriberID)).Build()
+
+	// Messages that already pushed to the client but not acknowledged yet.
+	sent := map[domain.MessageID]bool{}
+	for pusherCtx.Err() == nil {
+		msgs, moreMsg, ackHandle, err := s.pubsub.FetchMessages(pusherCtx, sl, wsDefaultFetchMax, s.longPollingMaxTimeout)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			s.sendStorageError(ctx, wsRequestFrame{ChannelID: string(sl.ChannelID), SubscriberID: string(sl.SubscriberID)}, err)
+
+			s.subscriptionsLock.Lock()
+			if sbsc, exists := s.subscriptions[sl]; exists {
+				sbsc.cancel()
+				delete(s.subscriptions, sl)
+			}
+			s.subscriptionsLock.Unlock()
+			return
+		}
+
+		fetched := make(map[domain.MessageID]bool, len(msgs))
+		newMsgs := make([]domain.Message, 0, len(msgs))
+		for _, msg := range msgs {
+			fetched[msg.MessageID] = true
+			if !sent[msg.MessageID] {
+				newMsgs = append(newMsgs, msg)
+			}
+		}
+		sent = fetched // Forget acknowledged messages.
+
+		if len(newMsgs) > 0 {
+			s.sendMessages(ctx, "", sl, newMsgs, moreMsg, ackHandle)
+		} else if len(msgs) > 0 {
+			// All messages have been pushed but not acknowledged yet, await client's acknowledgement.
+			select {
+			case <-pusherCtx.Done():
+				return
+			case <-time.After(wsRedeliveryCheckInterval):
+			}
+		}
+	}
+}
+
+func (s *wsSession) sendMessages(ctx context.Context, id string, sl domain.SubscriberLocator, msgs []domain.Message, moreMsg bool, ackHandle domain.AckHandle) {
+	resultMsgs := make([]interface{}, 0, len(msgs))
+	for _, msg := range msgs {
+		resultMsg := map[string]interface{}{
+			"messageID": msg.MessageID,
+			"content":   msg.Content,
+		}
+		if len(msg.Attributes) > 0 {
+			resultMsg["attributes"] = msg.Attributes
+		}
+		if msg.TraceParent != "" {
+			resultMsg["traceparent"] = msg.TraceParent
+		}
+		resultMsgs = append(resultMsgs, resultMsg)
+	}
+	result := map[string]interface{}{
+		"type":         "messages",
+		"channelID":    sl.ChannelID,
+		"subscriberID": sl.SubscriberID,
+		"messages":     resultMsgs,
+		"moreMessages": moreMsg,
+	}
+	if id != "" {
+		result["id"] = id
+	}
+	if len(msgs) > 0 {
+		result["ackHandle"] = ackHandle.Handle
+	}
+	s.send(ctx, result)
+}
+
+func (s *wsSession) sendStorageError(ctx context.Context, frame wsRequestFrame, err error) {
+	if domain.IsStorageNonFatalError(err) {
+		s.sendError(ctx, frame, err.Error(), err)
+		return
+	}
+	logger.Of(ctx).Error("internal server error on WebSocket endpoint", err)
+	s.sendError(ctx, frame, "Internal Server Error", nil)
+}
+
+func (s *wsSession) sendError(ctx context.Context, frame wsRequestFrame, message string, err error) {
+	s.send(ctx, s.errorFrame(ctx, frame, message, err))
+}
+
+func (s *wsSession) errorFrame(ctx context.Context, frame wsRequestFrame, message string, err error) map[string]interface{} {
+	res := map[string]interface{}{
+		"type":  "error",
+		"error": message,
+	}
+	if frame.ID != "" {
+		res["id"] = frame.ID
+	}
+	if frame.ChannelID != "" {
+		res["channelID"] = frame.ChannelID
+	}
+	if frame.SubscriberID != "" {
+		res["subscriberID"] = frame.SubscriberID
+	}
+	if errWithCode := domain.NewErrorWithCode(""); errors.As(err, &errWithCode) {
+		res["code"] = errWithCode.Code()
+	}
+	if err != nil {
+		logger.Of(ctx).InfoError(logger.CatHTTP, "Sending error frame to WebSocket client: "+message, err)
+	}
+	return res
+}
+
+func (s *wsSession) send(ctx context.Context, data interface{}) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := s.conn.WriteJSON(data); err != nil {
+		logger.Of(ctx).Debugf(logger.CatHTTP, "failed to write WebSocket frame: %v", err)
+	}
+}
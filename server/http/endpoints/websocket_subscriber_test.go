@@ -0,0 +1,337 @@
+package endpoints_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/domain/mock"
+	. "github.com/m3dev/dsps/server/http"
+	. "github.com/m3dev/dsps/server/http/endpoints"
+	"github.com/m3dev/dsps/server/http/middleware"
+	. "github.com/m3dev/dsps/server/http/testing"
+	. "github.com/m3dev/dsps/server/jwt/testing"
+)
+
+func dialWebSocket(t *testing.T, baseURL string, headers http.Header) *websocket.Conn {
+	url := strings.Replace(baseURL, "http://", "ws://", 1) + "/subscription/websocket"
+	conn, res, err := websocket.DefaultDialer.Dial(url, headers)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, res.Body.Close())
+	return conn
+}
+
+func wsSend(t *testing.T, conn *websocket.Conn, frame map[string]interface{}) {
+	assert.NoError(t, conn.WriteJSON(frame))
+}
+
+func wsReceive(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	var frame map[string]interface{}
+	if !assert.NoError(t, conn.ReadJSON(&frame)) {
+		t.FailNow()
+	}
+	return frame
+}
+
+func TestWebSocketEndpointsWithoutPubSubSupport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage := NewMockStorage(ctrl)
+	storage.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
+
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/subscription/websocket", baseURL), ``)
+		AssertErrorResponse(t, res, 501, nil, `No PubSub compatible storage available`)
+	})
+}
+
+func TestWebSocketSubscriberSuccess(t *testing.T) {
+	ctx := context.Background()
+	sl1 := domain.SubscriberLocator{ChannelID: "my-channel-1", SubscriberID: "sbsc-1"}
+	sl2 := domain.SubscriberLocator{ChannelID: "my-channel-2", SubscriberID: "sbsc-2"}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		pubsub := deps.Storage.AsPubSubStorage()
+		conn := dialWebSocket(t, baseURL, nil)
+		defer conn.Close()
+
+		// Subscribe multiple subscribers on one connection
+		for i, sl := range []domain.SubscriberLocator{sl1, sl2} {
+			wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": fmt.Sprintf("req-%d", i), "channelID": sl.ChannelID, "subscriberID": sl.SubscriberID})
+			assert.Equal(t, map[string]interface{}{
+				"type":         "subscribed",
+				"id":           fmt.Sprintf("req-%d", i),
+				"channelID":    string(sl.ChannelID),
+				"subscriberID": string(sl.SubscriberID),
+			}, wsReceive(t, conn))
+		}
+
+		// Server pushes messages
+		assert.NoError(t, pubsub.PublishMessages(ctx, []domain.Message{{
+			MessageLocator: domain.MessageLocator{ChannelID: sl2.ChannelID, MessageID: "msg-1"},
+			Content:        json.RawMessage(`{"hi":"hello"}`),
+		}}))
+		pushed := wsReceive(t, conn)
+		assert.Equal(t, "messages", pushed["type"])
+		assert.Equal(t, string(sl2.ChannelID), pushed["channelID"])
+		assert.Equal(t, string(sl2.SubscriberID), pushed["subscriberID"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"messageID": "msg-1", "content": map[string]interface{}{"hi": "hello"}}}, pushed["messages"])
+		assert.Equal(t, false, pushed["moreMessages"])
+		assert.NotEmpty(t, pushed["ackHandle"])
+
+		// Fetch returns unacknowledged messages
+		wsSend(t, conn, map[string]interface{}{"type": "fetch", "id": "req-fetch", "channelID": sl2.ChannelID, "subscriberID": sl2.SubscriberID, "max": 16})
+		fetched := wsReceive(t, conn)
+		assert.Equal(t, "messages", fetched["type"])
+		assert.Equal(t, "req-fetch", fetched["id"])
+		assert.Equal(t, pushed["messages"], fetched["messages"])
+
+		// Ack in-band
+		wsSend(t, conn, map[string]interface{}{"type": "ack", "id": "req-ack", "channelID": sl2.ChannelID, "subscriberID": sl2.SubscriberID, "ackHandle": fetched["ackHandle"]})
+		assert.Equal(t, map[string]interface{}{
+			"type":         "acked",
+			"id":           "req-ack",
+			"channelID":    string(sl2.ChannelID),
+			"subscriberID": string(sl2.SubscriberID),
+		}, wsReceive(t, conn))
+
+		wsSend(t, conn, map[string]interface{}{"type": "fetch", "id": "req-fetch-2", "channelID": sl2.ChannelID, "subscriberID": sl2.SubscriberID})
+		fetched = wsReceive(t, conn)
+		assert.Equal(t, []interface{}{}, fetched["messages"])
+		assert.Nil(t, fetched["ackHandle"])
+
+		// Unsubscribe stops push and in-band operations
+		wsSend(t, conn, map[string]interface{}{"type": "unsubscribe", "id": "req-unsubscribe", "channelID": sl1.ChannelID, "subscriberID": sl1.SubscriberID})
+		assert.Equal(t, "unsubscribed", wsReceive(t, conn)["type"])
+		wsSend(t, conn, map[string]interface{}{"type": "fetch", "id": "req-fetch-3", "channelID": sl1.ChannelID, "subscriberID": sl1.SubscriberID})
+		frame := wsReceive(t, conn)
+		assert.Equal(t, "error", frame["type"])
+		assert.Equal(t, ErrWebSocketNotSubscribed.Code(), frame["code"])
+	})
+}
+
+func TestWebSocketSubscriberFilter(t *testing.T) {
+	ctx := context.Background()
+	sl := domain.SubscriberLocator{ChannelID: "my-channel", SubscriberID: "sbsc-1"}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		pubsub := deps.Storage.AsPubSubStorage()
+		conn := dialWebSocket(t, baseURL, nil)
+		defer conn.Close()
+
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-1", "channelID": sl.ChannelID, "subscriberID": sl.SubscriberID, "filter": map[string]interface{}{
+			"attributes": map[string]interface{}{"event-type": "created"},
+		}})
+		assert.Equal(t, map[string]interface{}{
+			"type":         "subscribed",
+			"id":           "req-1",
+			"channelID":    string(sl.ChannelID),
+			"subscriberID": string(sl.SubscriberID),
+			"filter":       map[string]interface{}{"attributes": map[string]interface{}{"event-type": "created"}},
+		}, wsReceive(t, conn))
+
+		// Server pushes only messages matched with the filter
+		assert.NoError(t, pubsub.PublishMessages(ctx, []domain.Message{{
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-1"},
+			Content:        json.RawMessage(`{}`),
+			Attributes:     domain.MessageAttributes{"event-type": "deleted"},
+		}, {
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-2"},
+			Content:        json.RawMessage(`{}`),
+			Attributes:     domain.MessageAttributes{"event-type": "created"},
+		}}))
+		pushed := wsReceive(t, conn)
+		assert.Equal(t, "messages", pushed["type"])
+		if messages, ok := pushed["messages"].([]interface{}); assert.True(t, ok) && assert.Len(t, messages, 1) {
+			assert.Equal(t, "msg-2", messages[0].(map[string]interface{})["messageID"])
+		}
+	})
+}
+
+func TestWebSocketSubscriberInvalidFrames(t *testing.T) {
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		conn := dialWebSocket(t, baseURL, nil)
+		defer conn.Close()
+
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{ INVALID JSON`)))
+		frame := wsReceive(t, conn)
+		assert.Equal(t, "error", frame["type"])
+		assert.Equal(t, ErrWebSocketMalformedFrame.Code(), frame["code"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "unknown", "id": "req-1"})
+		frame = wsReceive(t, conn)
+		assert.Equal(t, "error", frame["type"])
+		assert.Equal(t, "req-1", frame["id"])
+		assert.Equal(t, ErrWebSocketMalformedFrame.Code(), frame["code"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-2", "channelID": "*** INVALID ***", "subscriberID": "sbsc-1"})
+		frame = wsReceive(t, conn)
+		assert.Equal(t, `Invalid "channelID" parameter`, frame["error"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-3", "channelID": "my-channel", "subscriberID": "*** INVALID ***"})
+		frame = wsReceive(t, conn)
+		assert.Equal(t, `Invalid "subscriberID" parameter`, frame["error"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-3", "channelID": "my-channel", "subscriberID": "sbsc-1", "filter": map[string]interface{}{"content": map[string]interface{}{"a..b": 1}}})
+		frame = wsReceive(t, conn)
+		assert.Equal(t, `Invalid "filter" parameter`, frame["error"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "ack", "id": "req-4", "channelID": "my-channel", "subscriberID": "sbsc-1", "ackHandle": "xxx"})
+		frame = wsReceive(t, conn)
+		assert.Equal(t, ErrWebSocketNotSubscribed.Code(), frame["code"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-5", "channelID": "my-channel", "subscriberID": "sbsc-1"})
+		assert.Equal(t, "subscribed", wsReceive(t, conn)["type"])
+		wsSend(t, conn, map[string]interface{}{"type": "ack", "id": "req-6", "channelID": "my-channel", "subscriberID": "sbsc-1"})
+		frame = wsReceive(t, conn)
+		assert.Equal(t, `Missing "ackHandle" parameter`, frame["error"])
+		wsSend(t, conn, map[string]interface{}{"type": "ack", "id": "req-7", "channelID": "my-channel", "subscriberID": "sbsc-1", "ackHandle": "xxx"})
+		frame = wsReceive(t, conn)
+		assert.Equal(t, domain.ErrMalformedAckHandle.Code(), frame["code"])
+	})
+}
+
+func TestWebSocketSubscriberAuth(t *testing.T) {
+	WithServer(t, `
+logging: category: "*": FATAL
+channels:
+	-
+		regex: 'auth-test-channel'
+		jwt:
+			iss: [ "https://issuer.example.com/issuer-url" ]
+			keys:
+				RS256: [ "../../jwt/testdata/RS256-2048bit-public.pem" ]
+	`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		jwt := GenerateJwt(t, JwtProps{
+			Alg:     "RS256",
+			Keyname: "RS256-2048bit",
+			JwtDir:  "../../jwt",
+			Iss:     "https://issuer.example.com/issuer-url",
+		})
+
+		conn := dialWebSocket(t, baseURL, nil)
+		defer conn.Close()
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-1", "channelID": "auth-test-channel", "subscriberID": "sbsc-1"})
+		frame := wsReceive(t, conn)
+		assert.Equal(t, "error", frame["type"])
+		assert.Equal(t, middleware.ErrAuthRejection.Code(), frame["code"])
+
+		// Token in the frame
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-2", "channelID": "auth-test-channel", "subscriberID": "sbsc-1", "token": jwt})
+		assert.Equal(t, "subscribed", wsReceive(t, conn)["type"])
+
+		// Token in the Authorization header
+		conn2 := dialWebSocket(t, baseURL, http.Header{"Authorization": []string{"Bearer " + jwt}})
+		defer conn2.Close()
+		wsSend(t, conn2, map[string]interface{}{"type": "subscribe", "id": "req-3", "channelID": "auth-test-channel", "subscriberID": "sbsc-1"})
+		assert.Equal(t, "subscribed", wsReceive(t, conn2)["type"])
+
+		// Channel not permitted
+		wsSend(t, conn2, map[string]interface{}{"type": "subscribe", "id": "req-4", "channelID": "not-permitted", "subscriberID": "sbsc-1"})
+		frame = wsReceive(t, conn2)
+		assert.Equal(t, `Invalid "channelID" parameter`, frame["error"])
+		assert.Equal(t, domain.ErrInvalidChannel.Code(), frame["code"])
+	})
+}
+
+func TestWebSocketSubscriberFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage, pubsub, _ := NewMockStorages(ctrl)
+
+	sl := domain.SubscriberLocator{ChannelID: "my-channel", SubscriberID: "sbsc-1"}
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		conn := dialWebSocket(t, baseURL, nil)
+		defer conn.Close()
+
+		pubsub.EXPECT().NewSubscriber(gomock.Any(), sl, nil).Return(errors.New("mock error"))
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-1", "channelID": sl.ChannelID, "subscriberID": sl.SubscriberID})
+		assert.Equal(t, map[string]interface{}{
+			"type":         "error",
+			"id":           "req-1",
+			"channelID":    string(sl.ChannelID),
+			"subscriberID": string(sl.SubscriberID),
+			"error":        "Internal Server Error",
+		}, wsReceive(t, conn))
+
+		// Push failure ends the subscription
+		pubsub.EXPECT().NewSubscriber(gomock.Any(), sl, nil).Return(nil)
+		pubsub.EXPECT().FetchMessages(gomock.Any(), sl, gomock.Any(), gomock.Any()).Return([]domain.Message{}, false, domain.AckHandle{}, domain.ErrSubscriptionNotFound)
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-2", "channelID": sl.ChannelID, "subscriberID": sl.SubscriberID})
+		assert.Equal(t, "subscribed", wsReceive(t, conn)["type"])
+		frame := wsReceive(t, conn)
+		assert.Equal(t, "error", frame["type"])
+		assert.Equal(t, domain.ErrSubscriptionNotFound.Code(), frame["code"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "fetch", "id": "req-3", "channelID": sl.ChannelID, "subscriberID": sl.SubscriberID})
+		assert.Equal(t, ErrWebSocketNotSubscribed.Code(), wsReceive(t, conn)["code"])
+	})
+}
+
+func TestWebSocketSubscriberServerClose(t *testing.T) {
+	WithServer(t, `logging: category: "*": FATAL`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		conn := dialWebSocket(t, baseURL, nil)
+		defer conn.Close()
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-1", "channelID": "my-channel", "subscriberID": "sbsc-1"})
+		assert.Equal(t, "subscribed", wsReceive(t, conn)["type"])
+
+		deps.ServerClose.Close()
+		assert.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "unexpected error: %v", err)
+	})
+}
+
+func TestWebSocketSubscriberRateLimit(t *testing.T) {
+	WithServer(t, `
+logging: category: "*": FATAL
+channels:
+	-
+		regex: 'limited-.+'
+		rateLimit:
+			fetch: [ { key: channel, limit: 2, interval: 1m } ]
+	`, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		conn := dialWebSocket(t, baseURL, nil)
+		defer conn.Close()
+
+		// Polling GET and WebSocket share fetch counters
+		res := DoHTTPRequest(t, "GET", fmt.Sprintf("%s/channel/limited-1/subscription/polling/sbsc-0", baseURL), ``)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 404, res.StatusCode)
+
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-subscribe", "channelID": "limited-1", "subscriberID": "sbsc-1"})
+		assert.Equal(t, "subscribed", wsReceive(t, conn)["type"])
+
+		wsSend(t, conn, map[string]interface{}{"type": "fetch", "id": "req-fetch", "channelID": "limited-1", "subscriberID": "sbsc-1"})
+		assert.Equal(t, map[string]interface{}{
+			"type":         "error",
+			"id":           "req-fetch",
+			"channelID":    "limited-1",
+			"subscriberID": "sbsc-1",
+			"error":        "Too Many Requests",
+			"code":         middleware.ErrRateLimited.Code(),
+			"retryAfter":   float64(60),
+		}, wsReceive(t, conn))
+
+		// Other channel has its own counter
+		wsSend(t, conn, map[string]interface{}{"type": "subscribe", "id": "req-subscribe-2", "channelID": "limited-2", "subscriberID": "sbsc-1"})
+		assert.Equal(t, "subscribed", wsReceive(t, conn)["type"])
+	})
+}
@@ -31,23 +31,7 @@ func NewNormalAuth(mainCtx context.Context, deps NormalAuthDependency, channelOf
 			return
 		}
 
-		bearerToken := utils.GetBearerToken(ctx, args)
-		authErr := channel.ValidateJwt(ctx, bearerToken)
-		if authErr == nil && jwtStorage != nil {
-			// If bearerToken is not JWT, channel.ValidateJwt() rejects it if JWT validation configured.
-			// If JWT validation not configured, it is okay to pass non-JWT or empty bearerToken.
-			jti, jwtParseError := jwt.ExtractJti(bearerToken)
-			if jti != nil {
-				sentry.AddTag(ctx, "jti", string(*jti))
-			}
-			if authErr == nil && jwtParseError == nil && jti != nil {
-				var revoked bool
-				revoked, authErr = jwtStorage.IsRevokedJwt(ctx, *jti)
-				if authErr == nil && revoked {
-					authErr = errors.New(`presented JWT has been revoked`)
-				}
-			}
-		}
+		authErr := ValidateChannelJwt(ctx, jwtStorage, channel, utils.GetBearerToken(ctx, args))
 		if authErr != nil {
 			logger.Of(ctx).Infof(logger.CatAuth, `JWT verification failure: %v`, authErr)
 			sentry.AddBreadcrumb(ctx, &sentrygo.Breadcrumb{
@@ -70,3 +54,24 @@ func NewNormalAuth(mainCtx context.Context, deps NormalAuthDependency, channelOf
 		next(ctx, args)
 	})
 }
+
+// ValidateChannelJwt verifies given bearer token for the channel, also checks revocation if JwtStorage available.
+func ValidateChannelJwt(ctx context.Context, jwtStorage domain.JwtStorage, channel domain.Channel, bearerToken string) error {
+	authErr := channel.ValidateJwt(ctx, bearerToken)
+	if authErr == nil && jwtStorage != nil {
+		// If bearerToken is not JWT, channel.ValidateJwt() rejects it if JWT validation configured.
+		// If JWT validation not configured, it is okay to pass non-JWT or empty bearerToken.
+		jti, jwtParseError := jwt.ExtractJti(bearerToken)
+		if jti != nil {
+			sentry.AddTag(ctx, "jti", string(*jti))
+		}
+		if jwtParseError == nil && jti != nil {
+			var revoked bool
+			revoked, authErr = jwtStorage.IsRevokedJwt(ctx, *jti)
+			if authErr == nil && revoked {
+				authErr = errors.New(`presented JWT has been revoked`)
+			}
+		}
+	}
+	return authErr
+}
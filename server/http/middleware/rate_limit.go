@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	sentrygo "github.com/getsentry/sentry-go"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/http/router"
+	"github.com/m3dev/dsps/server/http/utils"
+	"github.com/m3dev/dsps/server/jwt"
+	"github.com/m3dev/dsps/server/logger"
+	"github.com/m3dev/dsps/server/sentry"
+)
+
+// RateLimitDependency is to inject required objects to the middleware
+type RateLimitDependency interface {
+	RealIPDependency
+	GetStorage() domain.Storage
+}
+
+// RateLimiter counts requests of an operation against rate limit rules of channels.
+// Endpoints counting the same operation should share one RateLimiter, so that they share local counters.
+type RateLimiter struct {
+	deps    RateLimitDependency
+	op      domain.RateLimitOperation
+	counter domain.RateLimitStorage
+}
+
+// NewRateLimiter creates RateLimiter of the operation
+func NewRateLimiter(deps RateLimitDependency, op domain.RateLimitOperation) *RateLimiter {
+	counter := deps.GetStorage().AsRateLimitStorage()
+	if counter == nil {
+		// Without shared storage, limits are enforced for each server process.
+		counter = newLocalRateLimitCounter()
+	}
+	return &RateLimiter{deps: deps, op: op, counter: counter}
+}
+
+// Count counts a request to the channel, returns true with remaining duration of the window if the request exceeds any rule.
+// bearerToken must have been validated with the channel, because "sub" key trusts JWT without validation.
+func (l *RateLimiter) Count(ctx context.Context, channelID domain.ChannelID, channel domain.Channel, bearerToken string, clientIP string) (bool, domain.Duration) {
+	for _, rule := range channel.RateLimits(l.op) {
+		key := rateLimitCounterKey(channelID, channel, bearerToken, clientIP, rule)
+		count, resetIn, err := l.counter.IncrementRateLimitCounter(ctx, key, rule.Interval)
+		if err != nil {
+			// Storage failure should not stop the service.
+			logger.Of(ctx).WarnError(logger.CatHTTP, fmt.Sprintf(`failed to count request for rate limit "%s", skip the rule`, key), err)
+			continue
+		}
+		if count > int64(rule.Limit) {
+			logger.Of(ctx).Infof(logger.CatHTTP, `Rate limit exceeded: %s (%d > %d in %s)`, key, count, rule.Limit, rule.Interval)
+			sentry.AddBreadcrumb(ctx, &sentrygo.Breadcrumb{
+				Level:    sentrygo.LevelWarning,
+				Category: "rate-limit",
+				Message:  fmt.Sprintf(`Rate limit exceeded: %s`, key),
+			})
+			return true, resetIn
+		}
+	}
+	return false, domain.Duration{}
+}
+
+// ClientIP returns client IP address used for "ip" key.
+func (l *RateLimiter) ClientIP(r router.Request) string {
+	return GetRealIP(l.deps, r)
+}
+
+// NewRateLimit creates middleware to enforce rate limit rules of the channel.
+// Should be placed after authentication middleware, because "sub" key trusts JWT without validation.
+//
+// RateLimitFetch applies only to GET requests (polling and SSE), RateLimitPublish applies to any requests.
+func NewRateLimit(mainCtx context.Context, limiter *RateLimiter, channelOf func(context.Context, router.MiddlewareArgs) (domain.ChannelID, domain.Channel, error)) router.MiddlewareFunc {
+	return func(method, path string) router.Middleware {
+		if limiter.op == domain.RateLimitFetch && method != http.MethodGet {
+			return func(ctx context.Context, args router.MiddlewareArgs, next func(context.Context, router.MiddlewareArgs)) {
+				next(ctx, args)
+			}
+		}
+		return func(ctx context.Context, args router.MiddlewareArgs, next func(context.Context, router.MiddlewareArgs)) {
+			channelID, channel, err := channelOf(ctx, args)
+			if err != nil {
+				utils.SendInvalidParameter(ctx, args.W, "channelID", err)
+				return
+			}
+
+			if limited, resetIn := limiter.Count(ctx, channelID, channel, utils.GetBearerToken(ctx, args), limiter.ClientIP(args.R)); limited {
+				args.W.Header().Set("Retry-After", strconv.FormatInt(RetryAfterSeconds(resetIn), 10))
+				utils.SendError(ctx, args.W, http.StatusTooManyRequests, "Too Many Requests", ErrRateLimited)
+				return
+			}
+
+			next(ctx, args)
+		}
+	}
+}
+
+// rateLimitCounterKey returns "{rule ID}.{kind}.{value}".
+// Counters of "sub" and "ip" are shared among all channels matched to the rule.
+func rateLimitCounterKey(channelID domain.ChannelID, channel domain.Channel, bearerToken string, clientIP string, rule domain.RateLimitRule) string {
+	switch rule.Key {
+	case domain.RateLimitKeySub:
+		// Fallback to IP address if JWT not presented or it does not have "sub".
+		// Also if the channel does not validate JWT, otherwise client can forge "sub" to get fresh counter.
+		if channel.HasJwtValidation() {
+			if sub, err := jwt.ExtractSub(bearerToken); err == nil && sub != "" {
+				return fmt.Sprintf("%s.sub.%s", rule.ID, sub)
+			}
+		}
+		return fmt.Sprintf("%s.ip.%s", rule.ID, clientIP)
+	case domain.RateLimitKeyIP:
+		return fmt.Sprintf("%s.ip.%s", rule.ID, clientIP)
+	default:
+		return fmt.Sprintf("%s.channel.%s", rule.ID, channelID)
+	}
+}
+
+// RetryAfterSeconds returns value of Retry-After (seconds, at least 1) for the remaining duration of the window.
+func RetryAfterSeconds(resetIn domain.Duration) int64 {
+	sec := int64(math.Ceil(resetIn.Seconds()))
+	if sec < 1 {
+		return 1
+	}
+	return sec
+}
+
+// localRateLimitCounter is in-memory fixed window counter, used if Storage does not support RateLimitStorage.
+type localRateLimitCounter struct {
+	lock      sync.Mutex
+	windows   map[string]*localRateLimitWindow
+	lastSweep time.Time
+}
+
+type localRateLimitWindow struct {
+	count   int64
+	resetAt time.Time
+}
+
+// Interval to remove expired windows not to leak memory
+const localRateLimitSweepInterval = time.Minute
+
+func newLocalRateLimitCounter() *localRateLimitCounter {
+	return &localRateLimitCounter{
+		windows:   map[string]*localRateLimitWindow{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *localRateLimitCounter) IncrementRateLimitCounter(ctx context.Context, key string, window domain.Duration) (int64, domain.Duration, error) {
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if now.Sub(c.lastSweep) > localRateLimitSweepInterval {
+		for k, w := range c.windows {
+			if !now.Before(w.resetAt) {
+				delete(c.windows, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	w, ok := c.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &localRateLimitWindow{resetAt: now.Add(window.Duration)}
+		c.windows[key] = w
+	}
+	w.count++
+	return w.count, domain.Duration{Duration: w.resetAt.Sub(now)}, nil
+}
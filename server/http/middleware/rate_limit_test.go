@@ -0,0 +1,191 @@
+package middleware_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/domain/mock"
+	. "github.com/m3dev/dsps/server/http"
+	. "github.com/m3dev/dsps/server/http/middleware"
+	. "github.com/m3dev/dsps/server/http/testing"
+	. "github.com/m3dev/dsps/server/jwt/testing"
+)
+
+const configWithRateLimit = `
+logging: category: "*": ERROR
+channels:
+	-
+		regex: 'limit-by-channel-.+'
+		rateLimit:
+			publish: [ { key: channel, limit: 2, interval: 1m } ]
+			fetch: [ { key: channel, limit: 1, interval: 1m } ]
+	-
+		regex: 'limit-by-ip-.+'
+		rateLimit:
+			publish: [ { key: ip, limit: 2, interval: 1m } ]
+	-
+		regex: 'limit-by-sub-.+'
+		rateLimit:
+			publish: [ { key: sub, limit: 1, interval: 1m } ]
+	-
+		regex: 'limit-by-sub-jwt-.+'
+		jwt:
+			iss: [ "https://issuer.example.com/issuer-url" ]
+			keys:
+				RS256: [ "../../jwt/testdata/RS256-2048bit-public.pem" ]
+	-
+		regex: 'limit-short-.+'
+		rateLimit:
+			publish: [ { key: channel, limit: 1, interval: 300ms } ]
+`
+
+func TestRateLimitByChannel(t *testing.T) {
+	WithServer(t, configWithRateLimit, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		publish := func(channelID string) *http.Response {
+			return DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/msg-%d", baseURL, channelID, time.Now().UnixNano()), `{}`)
+		}
+		for i := 0; i < 2; i++ {
+			res := publish("limit-by-channel-1")
+			assert.NoError(t, res.Body.Close())
+			assert.Equal(t, 200, res.StatusCode)
+		}
+		res := publish("limit-by-channel-1")
+		assert.Equal(t, "60", res.Header.Get("Retry-After"))
+		AssertErrorResponse(t, res, 429, ErrRateLimited, `Too Many Requests`)
+
+		// Other channel has its own counter
+		res = publish("limit-by-channel-2")
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 200, res.StatusCode)
+
+		// Fetch limit applies only to GET
+		subscriberURL := fmt.Sprintf("%s/channel/%s/subscription/polling/sbsc-1", baseURL, "limit-by-channel-1")
+		for i := 0; i < 2; i++ {
+			res = DoHTTPRequest(t, "PUT", subscriberURL, ``)
+			assert.NoError(t, res.Body.Close())
+			assert.Equal(t, 200, res.StatusCode)
+		}
+		res = DoHTTPRequest(t, "GET", subscriberURL, ``)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 200, res.StatusCode)
+		res = DoHTTPRequest(t, "GET", subscriberURL, ``)
+		AssertErrorResponse(t, res, 429, ErrRateLimited, `Too Many Requests`)
+	})
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	WithServer(t, configWithRateLimit, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		// Counted across channels matched to the same rule
+		for _, channelID := range []string{"limit-by-ip-1", "limit-by-ip-2"} {
+			res := DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/msg-1", baseURL, channelID), `{}`)
+			assert.NoError(t, res.Body.Close())
+			assert.Equal(t, 200, res.StatusCode)
+		}
+		res := DoHTTPRequest(t, "PUT", fmt.Sprintf("%s/channel/%s/message/msg-1", baseURL, "limit-by-ip-3"), `{}`)
+		AssertErrorResponse(t, res, 429, ErrRateLimited, `Too Many Requests`)
+	})
+}
+
+func TestRateLimitBySub(t *testing.T) {
+	WithServer(t, configWithRateLimit, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		publish := func(channelID string, sub string) *http.Response {
+			headers := map[string]string{}
+			if sub != "" {
+				headers["Authorization"] = "Bearer " + GenerateJwt(t, JwtProps{
+					Alg:     "RS256",
+					Keyname: "RS256-2048bit",
+					JwtDir:  jwtDir,
+					Iss:     "https://issuer.example.com/issuer-url",
+					Claims:  map[string]interface{}{"sub": sub},
+				})
+			}
+			return DoHTTPRequestWithHeaders(t, "PUT", fmt.Sprintf("%s/channel/%s/message/msg-%d", baseURL, channelID, time.Now().UnixNano()), headers, `{}`)
+		}
+		for _, sub := range []string{"user-1", "user-2"} {
+			res := publish("limit-by-sub-jwt-1", sub)
+			assert.NoError(t, res.Body.Close())
+			assert.Equal(t, 200, res.StatusCode, sub)
+		}
+		for _, sub := range []string{"user-1", "user-2"} {
+			res := publish("limit-by-sub-jwt-1", sub)
+			AssertErrorResponse(t, res, 429, ErrRateLimited, `Too Many Requests`)
+		}
+	})
+}
+
+func TestRateLimitBySubWithoutJwtValidation(t *testing.T) {
+	WithServer(t, configWithRateLimit, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		publish := func(sub string) *http.Response {
+			headers := map[string]string{}
+			if sub != "" {
+				headers["Authorization"] = "Bearer " + GenerateJwt(t, JwtProps{
+					Alg:     "RS256",
+					Keyname: "RS256-2048bit",
+					JwtDir:  jwtDir,
+					Claims:  map[string]interface{}{"sub": sub},
+				})
+			}
+			return DoHTTPRequestWithHeaders(t, "PUT", fmt.Sprintf("%s/channel/%s/message/msg-%d", baseURL, "limit-by-sub-1", time.Now().UnixNano()), headers, `{}`)
+		}
+		res := publish("user-1")
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 200, res.StatusCode)
+
+		// Unverified "sub" is not trusted, counted by IP address
+		for _, sub := range []string{"user-2", ""} {
+			res := publish(sub)
+			AssertErrorResponse(t, res, 429, ErrRateLimited, `Too Many Requests`)
+		}
+	})
+}
+
+func TestRateLimitWindowReset(t *testing.T) {
+	WithServer(t, configWithRateLimit, func(deps *ServerDependencies) {}, func(deps *ServerDependencies, baseURL string) {
+		putURL := fmt.Sprintf("%s/channel/%s/message/msg-1", baseURL, "limit-short-1")
+		res := DoHTTPRequest(t, "PUT", putURL, `{}`)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 200, res.StatusCode)
+
+		res = DoHTTPRequest(t, "PUT", putURL, `{}`)
+		assert.Equal(t, "1", res.Header.Get("Retry-After")) // Rounded up
+		AssertErrorResponse(t, res, 429, ErrRateLimited, `Too Many Requests`)
+
+		time.Sleep(400 * time.Millisecond)
+		res = DoHTTPRequest(t, "PUT", putURL, `{}`)
+		assert.NoError(t, res.Body.Close())
+		assert.Equal(t, 200, res.StatusCode)
+	})
+}
+
+func TestRateLimitWithStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	storage := mock.NewMockStorage(ctrl)
+	rateLimit := mock.NewMockRateLimitStorage(ctrl)
+	storage.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(rateLimit).AnyTimes()
+
+	WithServer(t, configWithRateLimit, func(deps *ServerDependencies) {
+		deps.Storage = storage
+	}, func(deps *ServerDependencies, baseURL string) {
+		putURL := fmt.Sprintf("%s/channel/%s/message/msg-1", baseURL, "limit-by-channel-1")
+
+		// Storage failure should not reject request
+		rateLimit.EXPECT().IncrementRateLimitCounter(gomock.Any(), gomock.Any(), domain.Duration{Duration: time.Minute}).Return(int64(0), domain.Duration{}, errors.New("mocked storage error"))
+		res := DoHTTPRequest(t, "PUT", putURL, `{}`)
+		AssertErrorResponse(t, res, 501, nil, `No PubSub compatible storage available`)
+
+		rateLimit.EXPECT().IncrementRateLimitCounter(gomock.Any(), gomock.Any(), domain.Duration{Duration: time.Minute}).Return(int64(3), domain.Duration{Duration: 1500 * time.Millisecond}, nil)
+		res = DoHTTPRequest(t, "PUT", putURL, `{}`)
+		assert.Equal(t, "2", res.Header.Get("Retry-After"))
+		AssertErrorResponse(t, res, 429, ErrRateLimited, `Too Many Requests`)
+	})
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+// ReloadableChannelProvider is a ChannelProvider which supports configuration reload
+type ReloadableChannelProvider interface {
+	Reload(ctx context.Context, config *config.ServerConfig) error
+}
+
+// ReloadConfig applies new configuration to the running server.
+// Applies channels, admin and logging thresholds configuration, rejects changes that cannot be applied without restart.
+// If returns error, nothing changed.
+func (deps *ServerDependencies) ReloadConfig(ctx context.Context, newConfig *config.ServerConfig) error {
+	if err := deps.currentConfig().CheckReloadable(newConfig); err != nil {
+		return err
+	}
+	if _, err := logger.NewFilter(newConfig.Logging.Category); err != nil {
+		return fmt.Errorf(`invalid "logging" configuration: %w`, err)
+	}
+	cp, ok := deps.ChannelProvider.(ReloadableChannelProvider)
+	if !ok {
+		return fmt.Errorf(`ChannelProvider %T does not support configuration reload`, deps.ChannelProvider)
+	}
+	if err := cp.Reload(ctx, newConfig); err != nil {
+		return fmt.Errorf(`failed to reload channels configuration: %w`, err)
+	}
+
+	if err := deps.LogFilter.ReplaceThresholds(newConfig.Logging.Category); err != nil {
+		return err // Should not happen because validated above
+	}
+	deps.configLock.Lock()
+	defer deps.configLock.Unlock()
+	deps.Config = newConfig
+	return nil
+}
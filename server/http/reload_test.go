@@ -0,0 +1,74 @@
+package http_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/domain/channel"
+	. "github.com/m3dev/dsps/server/http"
+	. "github.com/m3dev/dsps/server/http/testing"
+	"github.com/m3dev/dsps/server/logger"
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+func TestReloadConfig(t *testing.T) {
+	ctx := context.Background()
+	WithServerDeps(t, `channels: [ { regex: "test.+" } ]`, func(deps *ServerDependencies) {
+		cp, err := channel.NewReloadableChannelProvider(ctx, deps.Config, channel.ProviderDeps{
+			Clock:     domain.RealSystemClock,
+			Telemetry: deps.Telemetry,
+			Sentry:    deps.Sentry,
+		})
+		assert.NoError(t, err)
+		defer cp.Shutdown(ctx)
+		deps.ChannelProvider = cp
+
+		newCfg := parseConfig(t, `
+			channels: [ { regex: "chat.+" } ]
+			admin: { auth: { bearer: [ "new-token" ] } }
+			logging: { category: { "*": ERROR } }
+		`)
+		assert.NoError(t, deps.ReloadConfig(ctx, &newCfg))
+		_, err = deps.ChannelProvider.Get("chat1")
+		assert.NoError(t, err)
+		_, err = deps.ChannelProvider.Get("test1")
+		dspstesting.IsError(t, domain.ErrInvalidChannel, err)
+		assert.Equal(t, []string{"new-token"}, deps.GetAdminAuthConfig().BearerTokens)
+		assert.False(t, deps.LogFilter.Filter(logger.INFO, logger.CatServer))
+
+		// Changes cannot be reloaded
+		unreloadable := parseConfig(t, `
+			channels: [ { regex: "chat.+" } ]
+			http: { port: 9999 }
+		`)
+		assert.Regexp(t, `"http" configuration has been changed`, deps.ReloadConfig(ctx, &unreloadable).Error())
+
+		// Invalid channels configuration
+		invalid := newCfg
+		invalid.Channels = config.ChannelsConfig{newCfg.Channels[0]}
+		invalid.Channels[0].Jwt = &config.JwtValidationConfig{
+			Iss:  []domain.JwtIss{"https://issuer.example.com/issuer-url"},
+			Keys: map[domain.JwtAlg][]string{"RS256": {"/file/not/found"}},
+		}
+		assert.Regexp(t, `failed to reload channels configuration`, deps.ReloadConfig(ctx, &invalid).Error())
+		assert.Same(t, &newCfg, deps.Config)
+	})
+}
+
+func TestReloadConfigWithoutReloadableChannelProvider(t *testing.T) {
+	WithServerDeps(t, `channels: [ { regex: "test.+" } ]`, func(deps *ServerDependencies) {
+		newCfg := parseConfig(t, `channels: [ { regex: "chat.+" } ]`)
+		assert.Regexp(t, `does not support configuration reload`, deps.ReloadConfig(context.Background(), &newCfg).Error())
+	})
+}
+
+func parseConfig(t *testing.T, yaml string) config.ServerConfig {
+	cfg, err := config.ParseConfig(context.Background(), config.Overrides{}, strings.ReplaceAll(yaml, "\t", "  "))
+	assert.NoError(t, err)
+	return cfg
+}
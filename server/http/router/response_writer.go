@@ -1,12 +1,19 @@
 package router
 
-import "net/http"
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
 
 // ResponseWriter extends net/http ResponseWriter
 type ResponseWriter interface {
 	Header() http.Header
 	Write([]byte) (int, error)
 	WriteHeader(statusCode int)
+	Flush()
+	Hijack() (net.Conn, *bufio.ReadWriter, error)
 
 	Written() ResponseWritten
 }
@@ -49,6 +56,26 @@ func (w *responseWriter) WriteHeader(statusCode int) {
 	w.written.StatusCode = statusCode
 }
 
+// Flush sends buffered data to the client, no-op if underlying writer does not support it.
+func (w *responseWriter) Flush() {
+	if f, ok := w.inner.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the underlying connection (e.g. for WebSocket), fails if underlying writer does not support it.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.inner.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying ResponseWriter does not support Hijack")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.written.StatusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
 func (w *responseWriter) Written() ResponseWritten {
 	return w.written
 }
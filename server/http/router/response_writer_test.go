@@ -20,3 +20,19 @@ func TestResponseWriter(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, ResponseWritten{StatusCode: 400, BodyBytes: 5}, w.Written())
 }
+
+func TestResponseWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewResponseWriter(rec)
+	_, err := w.Write([]byte{1, 2, 3})
+	assert.NoError(t, err)
+	w.Flush()
+	assert.True(t, rec.Flushed)
+}
+
+func TestResponseWriterHijackUnsupported(t *testing.T) {
+	w := NewResponseWriter(httptest.NewRecorder())
+	_, _, err := w.Hijack()
+	assert.Error(t, err)
+	assert.Equal(t, ResponseWritten{StatusCode: 200, BodyBytes: 0}, w.Written())
+}
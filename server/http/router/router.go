@@ -36,12 +36,16 @@ func NewRouter(cp ContextProvider, r *httprouter.Router, pathPrefix string, midd
 
 // NewGroup creates new child node of the router tree
 func (rt *Router) NewGroup(pathPrefix string, middlewareFuncs ...MiddlewareFunc) *Router {
+	// Copy not to share underlying array among sibling groups
+	funcs := make([]MiddlewareFunc, 0, len(rt.middlewareFuncs)+len(middlewareFuncs))
+	funcs = append(funcs, rt.middlewareFuncs...)
+	funcs = append(funcs, middlewareFuncs...)
 	return &Router{
 		r:  rt.r,
 		cp: rt.cp,
 
 		pathPrefix:      concatPath(rt.pathPrefix, pathPrefix),
-		middlewareFuncs: append(rt.middlewareFuncs, middlewareFuncs...),
+		middlewareFuncs: funcs,
 	}
 }
 
@@ -1,12 +1,15 @@
 package http
 
 import (
+	"sync"
+
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/domain"
 	"github.com/m3dev/dsps/server/http/lifecycle"
 	"github.com/m3dev/dsps/server/logger"
 	"github.com/m3dev/dsps/server/sentry"
 	"github.com/m3dev/dsps/server/telemetry"
+	"github.com/m3dev/dsps/server/webhook/delivery"
 )
 
 // ServerDependencies struct holds all resource references to build web server
@@ -14,11 +17,20 @@ type ServerDependencies struct {
 	Config          *config.ServerConfig
 	ChannelProvider domain.ChannelProvider
 	Storage         domain.Storage
+	WebhookQueue    delivery.Queue
 
 	Telemetry   *telemetry.Telemetry
 	Sentry      sentry.Sentry
 	LogFilter   *logger.Filter
 	ServerClose lifecycle.ServerClose
+
+	configLock sync.RWMutex // Guards Config against ReloadConfig
+}
+
+func (deps *ServerDependencies) currentConfig() *config.ServerConfig {
+	deps.configLock.RLock()
+	defer deps.configLock.RUnlock()
+	return deps.Config
 }
 
 // GetChannelProvider returns ChannelProvider object
@@ -31,34 +43,39 @@ func (deps *ServerDependencies) GetStorage() domain.Storage {
 	return deps.Storage
 }
 
+// GetWebhookQueue returns outgoing-webhook delivery queue
+func (deps *ServerDependencies) GetWebhookQueue() delivery.Queue {
+	return deps.WebhookQueue
+}
+
 // GetDefaultHeaders returns default response headers config
 func (deps *ServerDependencies) GetDefaultHeaders() map[string]string {
-	return deps.Config.HTTPServer.DefaultHeaders
+	return deps.currentConfig().HTTPServer.DefaultHeaders
 }
 
 // GetLongPollingMaxTimeout returns configuration value
 func (deps *ServerDependencies) GetLongPollingMaxTimeout() domain.Duration {
-	return deps.Config.HTTPServer.LongPollingMaxTimeout
+	return deps.currentConfig().HTTPServer.LongPollingMaxTimeout
 }
 
 // DiscloseAuthRejectionDetail returns configuration value
 func (deps *ServerDependencies) DiscloseAuthRejectionDetail() bool {
-	return deps.Config.HTTPServer.DiscloseAuthRejectionDetail
+	return deps.currentConfig().HTTPServer.DiscloseAuthRejectionDetail
 }
 
 // GetIPHeaderName returns configuration value
 func (deps *ServerDependencies) GetIPHeaderName() string {
-	return deps.Config.HTTPServer.RealIPHeader
+	return deps.currentConfig().HTTPServer.RealIPHeader
 }
 
 // GetTrustedProxyRanges returns configuration value
 func (deps *ServerDependencies) GetTrustedProxyRanges() []domain.CIDR {
-	return deps.Config.HTTPServer.TrustedProxyRanges
+	return deps.currentConfig().HTTPServer.TrustedProxyRanges
 }
 
 // GetAdminAuthConfig returns configuration value
 func (deps *ServerDependencies) GetAdminAuthConfig() *config.AdminAuthConfig {
-	return &deps.Config.Admin.Auth
+	return &deps.currentConfig().Admin.Auth
 }
 
 // GetTelemetry returns telemetry facility
@@ -66,6 +83,11 @@ func (deps *ServerDependencies) GetTelemetry() *telemetry.Telemetry {
 	return deps.Telemetry
 }
 
+// GetMetricsPath returns path of the Prometheus metrics endpoint
+func (deps *ServerDependencies) GetMetricsPath() string {
+	return deps.currentConfig().Telemetry.Prometheus.Path
+}
+
 // GetSentry returns sentry facility
 func (deps *ServerDependencies) GetSentry() sentry.Sentry {
 	return deps.Sentry
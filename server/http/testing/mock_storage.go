@@ -14,5 +14,6 @@ func NewMockStorages(ctrl *gomock.Controller) (storage *mock.MockStorage, pubsub
 
 	storage.EXPECT().AsPubSubStorage().Return(pubsub).AnyTimes()
 	storage.EXPECT().AsJwtStorage().Return(jwts).AnyTimes()
+	storage.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
 	return
 }
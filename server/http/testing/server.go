@@ -18,6 +18,7 @@ import (
 	"github.com/m3dev/dsps/server/storage"
 	"github.com/m3dev/dsps/server/storage/deps"
 	"github.com/m3dev/dsps/server/telemetry"
+	"github.com/m3dev/dsps/server/webhook/delivery"
 )
 
 // WithServerDeps runs given test function with ServerDependencies
@@ -42,7 +43,7 @@ func WithServerDeps(t *testing.T, configYaml string, f func(*http.ServerDependen
 		Sentry:    sentry,
 	})
 	assert.NoError(t, err)
-	storage, err := storage.NewStorage(ctx, &cfg.Storages, clock, channelProvider, deps.StorageDeps{
+	storage, err := storage.NewStorage(ctx, &cfg.Storages, cfg.StorageMultiplexer, clock, channelProvider, deps.StorageDeps{
 		Telemetry: telemetry,
 		Sentry:    sentry,
 	})
@@ -67,6 +68,15 @@ func WithServer(t *testing.T, configYaml string, setup func(deps *http.ServerDep
 	WithServerDeps(t, configYaml, func(deps *http.ServerDependencies) {
 		setup(deps)
 
+		// Setup could replace storage or channel provider, thus make queue after that.
+		if deps.WebhookQueue == nil {
+			deps.WebhookQueue = delivery.NewQueue(deps.Storage, deps.ChannelProvider, domain.RealSystemClock, delivery.Deps{
+				Telemetry: deps.Telemetry,
+				Sentry:    deps.Sentry,
+			})
+			defer func() { assert.NoError(t, deps.WebhookQueue.Shutdown(context.Background())) }()
+		}
+
 		server := http.CreateServer(context.Background(), deps)
 		ts := httptest.NewServer(server)
 		defer ts.Close()
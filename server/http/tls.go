@@ -0,0 +1,108 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/logger"
+	dspssync "github.com/m3dev/dsps/server/sync"
+	"github.com/m3dev/dsps/server/tlsconfig"
+)
+
+// certificateReloader holds server certificate and reloads it from files periodically,
+// so that renewed certificate is applied without restarting the server.
+type certificateReloader struct {
+	cfg *config.HTTPServerTLSConfig
+
+	daemonSystem *dspssync.DaemonSystem
+
+	lock    sync.RWMutex
+	cert    *tls.Certificate
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newCertificateReloader(ctx context.Context, cfg *config.HTTPServerTLSConfig, deps dspssync.DaemonSystemDeps) (*certificateReloader, error) {
+	r := &certificateReloader{
+		cfg: cfg,
+		daemonSystem: dspssync.NewDaemonSystem("dsps.http.tls", deps, func(ctx context.Context, name string, err error) {
+			logger.Of(ctx).WarnError(logger.CatServer, fmt.Sprintf(`failed to reload TLS certificate in background routine "%s", keep using current certificate`, name), err)
+		}),
+	}
+	// Load synchronously because the server cannot start without certificate.
+	if err := r.reload(ctx); err != nil {
+		return nil, err
+	}
+	r.daemonSystem.Start("certificate-reload", func(ctx context.Context) (dspssync.DaemonNextRun, error) {
+		return dspssync.DaemonNextRun{Interval: cfg.ReloadInterval.Duration}, r.reload(ctx)
+	})
+	return r, nil
+}
+
+func (r *certificateReloader) reload(ctx context.Context) error {
+	certPEM, err := ioutil.ReadFile(r.cfg.CertFile) //nolint:gosec // Only loads file specified by server configuration file
+	if err != nil {
+		return fmt.Errorf("failed to read TLS certificate file: %w", err)
+	}
+	keyPEM, err := ioutil.ReadFile(r.cfg.KeyFile) //nolint:gosec // Only loads file specified by server configuration file
+	if err != nil {
+		return fmt.Errorf("failed to read TLS private key file: %w", err)
+	}
+
+	r.lock.RLock()
+	unchanged := bytes.Equal(certPEM, r.certPEM) && bytes.Equal(keyPEM, r.keyPEM)
+	r.lock.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate (%s, %s): %w", r.cfg.CertFile, r.cfg.KeyFile, err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cert = &cert
+	r.certPEM = certPEM
+	r.keyPEM = keyPEM
+	logger.Of(ctx).Infof(logger.CatServer, "Loaded TLS certificate from %s", r.cfg.CertFile)
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certificateReloader) shutdown(ctx context.Context) error {
+	return r.daemonSystem.Shutdown(ctx)
+}
+
+func newServerTLSConfig(cfg *config.HTTPServerTLSConfig, certificates *certificateReloader) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificates.GetCertificate,
+	}
+	if cfg.ClientCAFile != "" {
+		pool, err := tlsconfig.LoadCertPool(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS client CA: %w", err)
+		}
+		tlsConfig.ClientCAs = pool
+		switch cfg.ClientAuth {
+		case config.HTTPServerClientAuthOptional:
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConfig, nil
+}
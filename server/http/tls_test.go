@@ -0,0 +1,224 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/sentry"
+	dspssync "github.com/m3dev/dsps/server/sync"
+	"github.com/m3dev/dsps/server/telemetry"
+)
+
+func TestHTTPSServer(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "test CA", nil)
+	writeTestCertificate(t, dir, "server", newTestCertificate(t, "server 1", ca))
+
+	_, addr := startTestServer(t, fmt.Sprintf(`http: { tls: { certFile: "%s", keyFile: "%s", reloadInterval: 10ms } }`, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")))
+	client := newTestHTTPSClient(ca, nil)
+	serverName := func() string {
+		client.CloseIdleConnections() // Make new TLS handshake
+		res, err := client.Get("https://" + addr + "/")
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer res.Body.Close()
+		assert.Equal(t, "HTTP/2.0", res.Proto)
+		return res.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "server 1", serverName())
+
+	// Renew certificate
+	writeTestCertificate(t, dir, "server", newTestCertificate(t, "server 2", ca))
+	assert.Eventually(t, func() bool { return serverName() == "server 2" }, 3*time.Second, 10*time.Millisecond)
+
+	// Broken certificate file does not replace current one
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "server.pem"), []byte("broken"), 0600))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "server 2", serverName())
+}
+
+func TestHTTPSServerClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "test CA", nil)
+	writeTestCertificate(t, dir, "server", newTestCertificate(t, "server", ca))
+	writeTestCertificate(t, dir, "ca", ca)
+	clientCert := newTestCertificate(t, "client", ca)
+
+	for _, tc := range []struct {
+		clientAuth      string
+		acceptAnonymous bool
+	}{
+		{clientAuth: config.HTTPServerClientAuthRequire, acceptAnonymous: false},
+		{clientAuth: config.HTTPServerClientAuthOptional, acceptAnonymous: true},
+	} {
+		_, addr := startTestServer(t, fmt.Sprintf(`http: { tls: { certFile: "%s", keyFile: "%s", clientCaFile: "%s", clientAuth: "%s" } }`, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"), filepath.Join(dir, "ca.pem"), tc.clientAuth))
+
+		res, err := newTestHTTPSClient(ca, clientCert).Get("https://" + addr + "/")
+		if assert.NoError(t, err, tc.clientAuth) {
+			res.Body.Close()
+		}
+
+		// Client does not send certificate not issued by the server's client CA, so that both are anonymous access.
+		for _, anonymous := range []*tls.Certificate{nil, newTestCertificate(t, "unknown client", newTestCertificate(t, "unknown CA", nil))} {
+			res, err = newTestHTTPSClient(ca, anonymous).Get("https://" + addr + "/")
+			if tc.acceptAnonymous {
+				if assert.NoError(t, err, tc.clientAuth) {
+					res.Body.Close()
+				}
+			} else {
+				assert.Error(t, err, tc.clientAuth)
+			}
+		}
+	}
+}
+
+func TestHTTPSServerSetupError(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "test CA", nil)
+	writeTestCertificate(t, dir, "server", newTestCertificate(t, "server", ca))
+
+	for yaml, expected := range map[string]string{
+		`http: { tls: { certFile: "/no/such/cert.pem", keyFile: "/no/such/key.pem" } }`:                                                                                                                   `failed to read TLS certificate file`,
+		fmt.Sprintf(`http: { tls: { certFile: "%s", keyFile: "%s" } }`, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.pem")):                                                               `failed to load TLS certificate`,
+		fmt.Sprintf(`http: { tls: { certFile: "%s", keyFile: "%s", clientCaFile: "/no/such/ca.pem" } }`, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem")):                          `invalid TLS client CA: failed to read CA file`,
+		fmt.Sprintf(`http: { tls: { certFile: "%s", keyFile: "%s", clientCaFile: "%s" } }`, filepath.Join(dir, "server.pem"), filepath.Join(dir, "server-key.pem"), filepath.Join(dir, "server-key.pem")): `invalid TLS client CA: no valid certificate found in CA file`,
+	} {
+		cfg, err := config.ParseConfig(context.Background(), config.Overrides{}, yaml)
+		assert.NoError(t, err)
+		_, _, err = newHTTPServer(context.Background(), cfg.HTTPServer, http.NotFoundHandler(), newTestDaemonDeps(t))
+		if assert.Error(t, err, yaml) {
+			assert.Regexp(t, expected, err.Error())
+		}
+	}
+}
+
+func TestH2CServer(t *testing.T) {
+	_, addr := startTestServer(t, `http: { h2c: true }`)
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr) // Cleartext
+			},
+		},
+	}
+	res, err := client.Get("http://" + addr + "/")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, "HTTP/2.0", res.Proto)
+	}
+
+	// HTTP/1.1 is still available
+	res, err = http.Get("http://" + addr + "/")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, "HTTP/1.1", res.Proto)
+	}
+}
+
+func startTestServer(t *testing.T, configYaml string) (*http.Server, string) {
+	t.Helper()
+	cfg, err := config.ParseConfig(context.Background(), config.Overrides{}, configYaml)
+	assert.NoError(t, err)
+
+	srv, closeServer, err := newHTTPServer(context.Background(), cfg.HTTPServer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), newTestDaemonDeps(t))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		if srv.TLSConfig != nil {
+			_ = srv.ServeTLS(listener, "", "")
+		} else {
+			_ = srv.Serve(listener)
+		}
+	}()
+	t.Cleanup(func() {
+		assert.NoError(t, srv.Close())
+		closeServer(context.Background())
+	})
+	return srv, listener.Addr().String()
+}
+
+func newTestDaemonDeps(t *testing.T) dspssync.DaemonSystemDeps {
+	return dspssync.DaemonSystemDeps{
+		Telemetry: telemetry.NewEmptyTelemetry(t),
+		Sentry:    sentry.NewEmptySentry(),
+	}
+}
+
+func newTestHTTPSClient(ca *tls.Certificate, clientCert *tls.Certificate) *http.Client {
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()} //nolint:gosec // Test client
+	tlsConfig.RootCAs.AddCert(ca.Leaf)
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			ForceAttemptHTTP2: true,
+		},
+	}
+}
+
+// newTestCertificate creates certificate signed by the parent, or self-signed CA certificate if parent is nil.
+func newTestCertificate(t *testing.T, commonName string, parent *tls.Certificate) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(1 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signerCert, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeTestCertificate writes "{name}.pem" and "{name}-key.pem"
+func writeTestCertificate(t *testing.T, dir string, name string, cert *tls.Certificate) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+}
@@ -10,18 +10,24 @@ import (
 	"syscall"
 
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/m3dev/dsps/server/config"
 	httplifecycle "github.com/m3dev/dsps/server/http/lifecycle"
 	"github.com/m3dev/dsps/server/http/middleware"
 	"github.com/m3dev/dsps/server/http/router"
 	"github.com/m3dev/dsps/server/logger"
+	dspssync "github.com/m3dev/dsps/server/sync"
 )
 
 // StartServer starts HTTP web server
 func StartServer(mainContext context.Context, deps *ServerDependencies) {
 	engine := CreateServer(mainContext, deps)
-	runServer(mainContext, deps.Config, engine, deps.GetServerClose())
+	runServer(mainContext, deps.Config, engine, deps.GetServerClose(), dspssync.DaemonSystemDeps{
+		Telemetry: deps.Telemetry,
+		Sentry:    deps.Sentry,
+	})
 }
 
 // CreateServer creates server (http.Handler) instance.
@@ -48,19 +54,59 @@ func CreateServer(mainContext context.Context, deps *ServerDependencies) http.Ha
 	return r
 }
 
-func runServer(mainContext context.Context, config *config.ServerConfig, engine http.Handler, serverClose httplifecycle.ServerClose) {
-	addr := config.HTTPServer.Listen
-
+// newHTTPServer creates http.Server, returned function releases resources of the server (e.g. TLS certificate reloader).
+func newHTTPServer(mainContext context.Context, config *config.HTTPServerConfig, engine http.Handler, daemonDeps dspssync.DaemonSystemDeps) (*http.Server, func(context.Context), error) {
 	srv := &http.Server{
-		Addr:           addr,
+		Addr:           config.Listen,
 		Handler:        engine,
-		IdleTimeout:    config.HTTPServer.IdleTimeout.Duration,
-		ReadTimeout:    config.HTTPServer.ReadTimeout.Duration,
-		WriteTimeout:   config.HTTPServer.LongPollingMaxTimeout.Duration + config.HTTPServer.WriteTimeout.Duration,
+		IdleTimeout:    config.IdleTimeout.Duration,
+		ReadTimeout:    config.ReadTimeout.Duration,
+		WriteTimeout:   config.LongPollingMaxTimeout.Duration + config.WriteTimeout.Duration,
 		MaxHeaderBytes: 1 << 20,
 	}
+	if config.H2C {
+		// HTTP/2 over cleartext, e.g. behind a proxy that terminates TLS.
+		srv.Handler = h2c.NewHandler(engine, &http2.Server{IdleTimeout: config.IdleTimeout.Duration})
+	}
+	if !config.TLS.IsEnabled() {
+		return srv, func(context.Context) {}, nil
+	}
+
+	certificates, err := newCertificateReloader(mainContext, &config.TLS, daemonDeps)
+	if err != nil {
+		return nil, nil, err
+	}
+	if srv.TLSConfig, err = newServerTLSConfig(&config.TLS, certificates); err != nil {
+		if err := certificates.shutdown(mainContext); err != nil {
+			logger.Of(mainContext).WarnError(logger.CatServer, "Failed to stop TLS certificate reloader", err)
+		}
+		return nil, nil, err
+	}
+	// http.Server enables HTTP/2 automatically on TLS.
+	return srv, func(ctx context.Context) {
+		if err := certificates.shutdown(ctx); err != nil {
+			logger.Of(ctx).WarnError(logger.CatServer, "Failed to stop TLS certificate reloader", err)
+		}
+	}, nil
+}
+
+func runServer(mainContext context.Context, config *config.ServerConfig, engine http.Handler, serverClose httplifecycle.ServerClose, daemonDeps dspssync.DaemonSystemDeps) {
+	addr := config.HTTPServer.Listen
+
+	srv, closeServer, err := newHTTPServer(mainContext, config.HTTPServer, engine, daemonDeps)
+	if err != nil {
+		logger.Of(mainContext).FatalExitProcess("HTTP server TLS setup failed", err)
+		return
+	}
+	defer closeServer(mainContext)
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "") // Certificate is supplied by TLSConfig.GetCertificate
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil {
 			if err != http.ErrServerClosed {
 				logger.Of(mainContext).FatalExitProcess(fmt.Sprintf("HTTP server listen failed on %s", addr), err)
 			} else {
@@ -0,0 +1,146 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// Jwk is a public key in JWK Set (RFC 7517)
+type Jwk struct {
+	// "kid" of the key, could be empty
+	Kid string
+	// "alg" of the key, empty if not specified
+	Alg domain.JwtAlg
+	// *rsa.PublicKey or *ecdsa.PublicKey
+	Key interface{}
+}
+
+// IsUsableFor returns true if the key can verify signature of given alg
+func (k Jwk) IsUsableFor(alg domain.JwtAlg) bool {
+	if k.Alg != "" && k.Alg != alg {
+		return false
+	}
+	switch k.Key.(type) {
+	case *rsa.PublicKey:
+		return IsRSA(alg)
+	case *ecdsa.PublicKey:
+		return IsECDSA(alg)
+	default:
+		return false
+	}
+}
+
+type rawJwks struct {
+	Keys []rawJwk `json:"keys"`
+}
+
+type rawJwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJwks parses JWK Set JSON.
+// Keys not for signature verification or of unsupported key type (e.g. "oct") are ignored.
+func ParseJwks(raw []byte) ([]Jwk, error) {
+	var jwks rawJwks
+	if err := json.Unmarshal(raw, &jwks); err != nil {
+		return nil, fmt.Errorf(`failed to parse JWK Set JSON: %w`, err)
+	}
+
+	result := make([]Jwk, 0, len(jwks.Keys))
+	for i, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key interface{}
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaPublicKey()
+		case "EC":
+			key, err = jwk.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse keys[%d] (kid: "%s") of JWK Set: %w`, i, jwk.Kid, err)
+		}
+		result = append(result, Jwk{
+			Kid: jwk.Kid,
+			Alg: domain.JwtAlg(jwk.Alg),
+			Key: key,
+		})
+	}
+	return result, nil
+}
+
+func (jwk rawJwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeJwkBigInt("n", jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeJwkBigInt("e", jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > int64(^uint32(0)>>1) {
+		return nil, fmt.Errorf(`RSA public exponent "e" is too large`)
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (jwk rawJwk) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf(`unsupported EC curve "%s"`, jwk.Crv)
+	}
+	x, err := decodeJwkBigInt("x", jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJwkBigInt("y", jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf(`EC public key is not on the curve "%s"`, jwk.Crv)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeJwkBigInt(name string, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf(`missing "%s" parameter`, name)
+	}
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid base64url value of "%s" parameter: %w`, name, err)
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
@@ -0,0 +1,56 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/jwt"
+	. "github.com/m3dev/dsps/server/jwt/testing"
+)
+
+func TestParseJwks(t *testing.T) {
+	keys, err := ParseJwks(GenerateJwks(t, ".",
+		JwkProps{Keyname: "RS256-2048bit", Alg: "RS256", AlgInJwk: true, Kid: "rsa-key"},
+		JwkProps{Keyname: "ES512-test1", Alg: "ES512", Kid: "ec-key"},
+	))
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, "rsa-key", keys[0].Kid)
+		assert.Equal(t, domain.JwtAlg("RS256"), keys[0].Alg)
+		expectedRSA, err := LoadVerificationKey("RS256", "./testdata/RS256-2048bit-public.pem")
+		assert.NoError(t, err)
+		assert.True(t, expectedRSA.(*rsa.PublicKey).Equal(keys[0].Key))
+		assert.True(t, keys[0].IsUsableFor("RS256"))
+		assert.False(t, keys[0].IsUsableFor("PS256")) // "alg" specified in JWK
+		assert.False(t, keys[0].IsUsableFor("ES512"))
+
+		assert.Equal(t, "ec-key", keys[1].Kid)
+		assert.Equal(t, domain.JwtAlg(""), keys[1].Alg)
+		expectedEC, err := LoadVerificationKey("ES512", "./testdata/ES512-test1-public.pem")
+		assert.NoError(t, err)
+		assert.True(t, expectedEC.(*ecdsa.PublicKey).Equal(keys[1].Key))
+		assert.True(t, keys[1].IsUsableFor("ES512"))
+		assert.False(t, keys[1].IsUsableFor("RS256"))
+	}
+
+	// Ignored keys
+	keys, err = ParseJwks([]byte(`{"keys":[{"kty":"oct","k":"AAAA"},{"kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"}]}`))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 0)
+
+	// Errors
+	_, err = ParseJwks([]byte(`INVALID`))
+	assert.Contains(t, err.Error(), "failed to parse JWK Set JSON")
+	_, err = ParseJwks([]byte(`{"keys":[{"kty":"RSA","kid":"k1","e":"AQAB"}]}`))
+	assert.EqualError(t, err, `failed to parse keys[0] (kid: "k1") of JWK Set: missing "n" parameter`)
+	_, err = ParseJwks([]byte(`{"keys":[{"kty":"RSA","n":"!!!","e":"AQAB"}]}`))
+	assert.Contains(t, err.Error(), `invalid base64url value of "n" parameter`)
+	_, err = ParseJwks([]byte(`{"keys":[{"kty":"EC","crv":"P-192","x":"AQAB","y":"AQAB"}]}`))
+	assert.Contains(t, err.Error(), `unsupported EC curve "P-192"`)
+	_, err = ParseJwks([]byte(`{"keys":[{"kty":"EC","crv":"P-256","x":"AQAB","y":"AQAB"}]}`))
+	assert.Contains(t, err.Error(), `EC public key is not on the curve "P-256"`)
+}
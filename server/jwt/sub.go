@@ -0,0 +1,15 @@
+package jwt
+
+import (
+	jwtgo "github.com/dgrijalva/jwt-go/v4"
+)
+
+// ExtractSub read "sub" claim of JWT, returns empty string if not present. Does not perform any JWT validation.
+func ExtractSub(jwtStr string) (string, error) {
+	parser := jwtgo.NewParser()
+	claims := jwtgo.StandardClaims{}
+	if _, _, err := parser.ParseUnverified(jwtStr, &claims); err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
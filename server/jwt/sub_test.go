@@ -0,0 +1,32 @@
+package jwt_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/jwt"
+	. "github.com/m3dev/dsps/server/jwt/testing"
+)
+
+func TestExtractSub(t *testing.T) {
+	sub, err := ExtractSub(GenerateJwt(t, JwtProps{
+		Alg:     "ES512",
+		Keyname: "ES512-test1",
+		JwtDir:  ".",
+		Claims:  map[string]interface{}{"sub": "user-1234"},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1234", sub)
+
+	sub, err = ExtractSub(GenerateJwt(t, JwtProps{
+		Alg:     "ES512",
+		Keyname: "ES512-test1",
+		JwtDir:  ".",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "", sub)
+
+	_, err = ExtractSub(`this-is-not-JWT`)
+	assert.Error(t, err)
+}
@@ -19,6 +19,8 @@ type JwtProps struct {
 	JwtDir string
 
 	Alg domain.JwtAlg
+	// "kid" header, omitted if empty
+	Kid string
 	// Issuer
 	Iss domain.JwtIss
 	// ID
@@ -68,6 +70,9 @@ func GenerateJwt(t *testing.T, props JwtProps) string {
 	}
 
 	token := jwtgo.NewWithClaims(jwtgo.GetSigningMethod(string(props.Alg)), claims)
+	if props.Kid != "" {
+		token.Header["kid"] = props.Kid
+	}
 	if props.Alg == "none" {
 		jwt, err := token.SigningString()
 		assert.NoError(t, err)
@@ -0,0 +1,65 @@
+package testing
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/jwt"
+)
+
+// JwkProps is only for GenerateJwks
+type JwkProps struct {
+	// Filename prefix under ../testdata (e.g. "ES512-test1")
+	Keyname string
+	// Alg to load the key, also set to "alg" of JWK if AlgInJwk is true
+	Alg      domain.JwtAlg
+	AlgInJwk bool
+	Kid      string
+}
+
+// GenerateJwks generates JWK Set JSON of public keys only for testing purpose.
+func GenerateJwks(t *testing.T, jwtDir string, keys ...JwkProps) []byte {
+	if jwtDir == "" {
+		jwtDir = ".."
+	}
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+	jwks := make([]map[string]interface{}, 0, len(keys))
+	for _, props := range keys {
+		key, err := jwt.LoadVerificationKey(props.Alg, jwtDir+"/testdata/"+props.Keyname+"-public.pem")
+		assert.NoError(t, err)
+
+		jwk := map[string]interface{}{"use": "sig"}
+		if props.Kid != "" {
+			jwk["kid"] = props.Kid
+		}
+		if props.AlgInJwk {
+			jwk["alg"] = string(props.Alg)
+		}
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = encode(key.N)
+			jwk["e"] = encode(big.NewInt(int64(key.E)))
+		case *ecdsa.PublicKey:
+			jwk["kty"] = "EC"
+			jwk["crv"] = key.Curve.Params().Name
+			jwk["x"] = encode(key.X)
+			jwk["y"] = encode(key.Y)
+		default:
+			assert.Failf(t, "unsupported key type", "%T", key)
+		}
+		jwks = append(jwks, jwk)
+	}
+
+	result, err := json.Marshal(map[string]interface{}{"keys": jwks})
+	assert.NoError(t, err)
+	return result
+}
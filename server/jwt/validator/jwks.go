@@ -0,0 +1,136 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/jwt"
+	"github.com/m3dev/dsps/server/logger"
+	dspssync "github.com/m3dev/dsps/server/sync"
+)
+
+const (
+	jwksMaxBodyBytes     = 1024 * 1024
+	jwksRetryIntervalMax = 10 * time.Second
+)
+
+// jwksCache holds keys fetched from JWK Set URL and refreshes them periodically.
+type jwksCache struct {
+	cfg    *config.JwksConfig
+	algs   map[domain.JwtAlg]bool
+	client *http.Client
+
+	daemonSystem *dspssync.DaemonSystem
+
+	lock        sync.RWMutex
+	keys        []jwt.Jwk
+	lastAttempt time.Time
+}
+
+func newJwksCache(ctx context.Context, cfg *config.JwksConfig, deps dspssync.DaemonSystemDeps) *jwksCache {
+	c := &jwksCache{
+		cfg:    cfg,
+		algs:   make(map[domain.JwtAlg]bool, len(cfg.Algs)),
+		client: &http.Client{Timeout: cfg.Timeout.Duration},
+		daemonSystem: dspssync.NewDaemonSystem("dsps.jwt.jwks", deps, func(ctx context.Context, name string, err error) {
+			logger.Of(ctx).WarnError(logger.CatAuth, fmt.Sprintf(`failed to refresh JWK Set in background routine "%s"`, name), err)
+		}),
+	}
+	for _, alg := range cfg.Algs {
+		c.algs[alg] = true
+	}
+
+	// Fetch synchronously so that the server can validate JWT right after startup.
+	if err := c.refresh(ctx); err != nil {
+		logger.Of(ctx).WarnError(logger.CatAuth, fmt.Sprintf(`failed to fetch JWK Set from %s, retrying in background`, cfg.URL), err)
+	}
+	c.daemonSystem.Start("jwks-refresh", func(ctx context.Context) (dspssync.DaemonNextRun, error) {
+		c.lock.RLock()
+		elapsed := time.Since(c.lastAttempt)
+		fetched := c.keys != nil
+		c.lock.RUnlock()
+
+		interval := cfg.RefreshInterval.Duration
+		if !fetched && interval > jwksRetryIntervalMax {
+			interval = jwksRetryIntervalMax // Retry sooner if no keys available
+		}
+		if elapsed < interval {
+			return dspssync.DaemonNextRun{Interval: interval - elapsed}, nil
+		}
+		if err := c.refresh(ctx); err != nil {
+			return dspssync.DaemonNextRun{Interval: jwksRetryIntervalMax}, err
+		}
+		return dspssync.DaemonNextRun{Interval: cfg.RefreshInterval.Duration}, nil
+	})
+	return c
+}
+
+func (c *jwksCache) refresh(ctx context.Context) error {
+	c.lock.Lock()
+	c.lastAttempt = time.Now()
+	c.lock.Unlock()
+
+	keys, err := c.fetch(ctx)
+	if err != nil {
+		return err // Keep using previously fetched keys
+	}
+	logger.Of(ctx).Debugf(logger.CatAuth, "fetched %d keys from JWK Set %s", len(keys), c.cfg.URL)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.keys = keys
+	return nil
+}
+
+func (c *jwksCache) fetch(ctx context.Context) ([]jwt.Jwk, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create JWK Set request: %w`, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to fetch JWK Set from %s: %w`, c.cfg.URL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(`failed to fetch JWK Set from %s: HTTP status %d`, c.cfg.URL, res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, jwksMaxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read JWK Set from %s: %w`, c.cfg.URL, err)
+	}
+	return jwt.ParseJwks(body)
+}
+
+// Returns true if JWT signed with the alg should be verified with JWK Set.
+func (c *jwksCache) accepts(alg domain.JwtAlg) bool {
+	return c.algs[alg]
+}
+
+// Returns keys usable for the alg, only keys with same "kid" if kid is not empty.
+func (c *jwksCache) keysFor(alg domain.JwtAlg, kid string) []interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	result := make([]interface{}, 0, len(c.keys))
+	for _, key := range c.keys {
+		if kid != "" && key.Kid != kid {
+			continue
+		}
+		if key.IsUsableFor(alg) {
+			result = append(result, key.Key)
+		}
+	}
+	return result
+}
+
+func (c *jwksCache) shutdown(ctx context.Context) error {
+	return c.daemonSystem.Shutdown(ctx)
+}
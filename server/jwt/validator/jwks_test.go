@@ -0,0 +1,130 @@
+package validator_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/jwt/testing"
+	. "github.com/m3dev/dsps/server/jwt/validator"
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+type jwksServer struct {
+	*httptest.Server
+
+	lock   sync.Mutex
+	status int
+	body   []byte
+}
+
+func newJwksServer(t *testing.T, body []byte) *jwksServer {
+	s := &jwksServer{status: http.StatusOK, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		w.WriteHeader(s.status)
+		_, _ = w.Write(s.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(status int, body []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.status = status
+	s.body = body
+}
+
+func newJwksTemplate(t *testing.T, url string, refreshInterval string, keys map[domain.JwtAlg][]string) Template {
+	tpl, err := NewTemplate(context.Background(), &config.JwtValidationConfig{
+		Iss:  []domain.JwtIss{"https://example.com/issuer"},
+		Keys: keys,
+		Jwks: &config.JwksConfig{
+			URL:             url,
+			Algs:            []domain.JwtAlg{"RS256", "ES512"},
+			RefreshInterval: dspstesting.MakeDurationPtr(refreshInterval),
+			Timeout:         dspstesting.MakeDurationPtr("1s"),
+		},
+		ClockSkewLeeway: &domain.Duration{},
+	}, domain.RealSystemClock, newDaemonSystemDeps(t))
+	assert.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, tpl.Shutdown(context.Background())) })
+	return tpl
+}
+
+func TestJwksValidation(t *testing.T) {
+	ctx := context.Background()
+	server := newJwksServer(t, GenerateJwks(t, "..",
+		JwkProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "rsa-key"},
+		JwkProps{Keyname: "ES512-test1", Alg: "ES512", AlgInJwk: true, Kid: "ec-key"},
+	))
+	v, err := newJwksTemplate(t, server.URL, "15m", map[domain.JwtAlg][]string{}).NewValidator(struct{}{})
+	assert.NoError(t, err)
+
+	// Select by kid
+	assert.NoError(t, v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "rsa-key", Iss: "https://example.com/issuer"})))
+	assert.NoError(t, v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "ES512-test1", Alg: "ES512", Kid: "ec-key", Iss: "https://example.com/issuer"})))
+	// Without kid, try all keys
+	assert.NoError(t, v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-2048bit", Alg: "RS256", Iss: "https://example.com/issuer"})))
+
+	// Unknown kid
+	err = v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "unknown-key", Iss: "https://example.com/issuer"}))
+	assert.Contains(t, err.Error(), `no RS256 signing key found in JWK Set for the presented JWT (kid: "unknown-key")`)
+	// kid of another key type
+	err = v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "ec-key", Iss: "https://example.com/issuer"}))
+	assert.Contains(t, err.Error(), `no RS256 signing key found in JWK Set for the presented JWT (kid: "ec-key")`)
+	// Signed by key not in the set
+	err = v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-4096bit", Alg: "RS256", Kid: "rsa-key", Iss: "https://example.com/issuer"}))
+	assert.Contains(t, err.Error(), `JWT validation failed`)
+	// alg not allowed
+	err = v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-2048bit", Alg: "PS256", Kid: "rsa-key", Iss: "https://example.com/issuer"}))
+	assert.Contains(t, err.Error(), `JWT validation failed`)
+}
+
+func TestJwksWithStaticKeys(t *testing.T) {
+	ctx := context.Background()
+	server := newJwksServer(t, GenerateJwks(t, "..", JwkProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "rsa-key"}))
+	v, err := newJwksTemplate(t, server.URL, "15m", map[domain.JwtAlg][]string{
+		"RS256": {"../testdata/RS256-4096bit-public.pem"},
+	}).NewValidator(struct{}{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "rsa-key", Iss: "https://example.com/issuer"})))
+	assert.NoError(t, v.Validate(ctx, GenerateJwt(t, JwtProps{Keyname: "RS256-4096bit", Alg: "RS256", Iss: "https://example.com/issuer"})))
+}
+
+func TestJwksRefresh(t *testing.T) {
+	ctx := context.Background()
+	server := newJwksServer(t, nil)
+	server.set(http.StatusInternalServerError, []byte(`error`))
+	v, err := newJwksTemplate(t, server.URL, "50ms", map[domain.JwtAlg][]string{}).NewValidator(struct{}{})
+	assert.NoError(t, err)
+
+	jwt1 := GenerateJwt(t, JwtProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "key1", Iss: "https://example.com/issuer"})
+	jwt2 := GenerateJwt(t, JwtProps{Keyname: "RS256-4096bit", Alg: "RS256", Kid: "key2", Iss: "https://example.com/issuer"})
+	assert.Error(t, v.Validate(ctx, jwt1)) // Initial fetch failed
+
+	// Retry after failure
+	server.set(http.StatusOK, GenerateJwks(t, "..", JwkProps{Keyname: "RS256-2048bit", Alg: "RS256", Kid: "key1"}))
+	assert.Eventually(t, func() bool { return v.Validate(ctx, jwt1) == nil }, 3*time.Second, 10*time.Millisecond)
+	assert.Error(t, v.Validate(ctx, jwt2))
+
+	// Key rotation
+	server.set(http.StatusOK, GenerateJwks(t, "..", JwkProps{Keyname: "RS256-4096bit", Alg: "RS256", Kid: "key2"}))
+	assert.Eventually(t, func() bool { return v.Validate(ctx, jwt2) == nil }, 3*time.Second, 10*time.Millisecond)
+	assert.Error(t, v.Validate(ctx, jwt1))
+
+	// Keep previous keys if refresh failed
+	server.set(http.StatusOK, []byte(`INVALID`))
+	time.Sleep(200 * time.Millisecond)
+	assert.NoError(t, v.Validate(ctx, jwt2))
+}
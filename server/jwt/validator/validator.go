@@ -11,12 +11,15 @@ import (
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/domain"
 	"github.com/m3dev/dsps/server/jwt"
+	dspssync "github.com/m3dev/dsps/server/sync"
 )
 
 // Template is a template of Validator
 type Template interface {
 	NewValidator(tplEnv domain.TemplateStringEnv) (Validator, error)
 	JWTClockSkewLeewayMax() domain.Duration
+	// Stops background routines of the template (e.g. JWK Set refresh)
+	Shutdown(ctx context.Context) error
 }
 
 type validatorTemplate struct {
@@ -25,6 +28,7 @@ type validatorTemplate struct {
 
 	validAlgs []string
 	keysMap   map[domain.JwtAlg][]interface{}
+	jwks      *jwksCache // nil if JWK Set not configured
 	parser    *jwtgo.Parser
 }
 
@@ -40,7 +44,8 @@ type validator struct {
 }
 
 // NewTemplate creates Template instance.
-func NewTemplate(ctx context.Context, cfg *config.JwtValidationConfig, clock domain.SystemClock) (Template, error) {
+// If JWK Set is configured, fetches keys and starts background routine to refresh them with given deps.
+func NewTemplate(ctx context.Context, cfg *config.JwtValidationConfig, clock domain.SystemClock, deps dspssync.DaemonSystemDeps) (Template, error) {
 	validAlgs := make([]string, 0, len(cfg.Keys))
 	keysMap := make(map[domain.JwtAlg][]interface{}, len(cfg.Keys))
 	for alg, keyFilesOrg := range cfg.Keys {
@@ -62,12 +67,23 @@ func NewTemplate(ctx context.Context, cfg *config.JwtValidationConfig, clock dom
 		keysMap[alg] = keys
 	}
 
+	var jwks *jwksCache
+	if cfg.Jwks != nil {
+		for _, alg := range cfg.Jwks.Algs {
+			if _, found := keysMap[alg]; !found {
+				validAlgs = append(validAlgs, string(alg))
+			}
+		}
+		jwks = newJwksCache(ctx, cfg.Jwks, deps)
+	}
+
 	return &validatorTemplate{
 		cfg:   cfg,
 		clock: clock,
 
 		validAlgs: validAlgs,
 		keysMap:   keysMap,
+		jwks:      jwks,
 		parser: jwtgo.NewParser(
 			jwtgo.WithValidMethods(validAlgs),
 			jwtgo.WithLeeway(cfg.ClockSkewLeeway.Duration),
@@ -92,6 +108,13 @@ func (v *validatorTemplate) JWTClockSkewLeewayMax() domain.Duration {
 	return *v.cfg.ClockSkewLeeway
 }
 
+func (v *validatorTemplate) Shutdown(ctx context.Context) error {
+	if v.jwks == nil {
+		return nil
+	}
+	return v.jwks.shutdown(ctx)
+}
+
 func (v *validator) Validate(ctx context.Context, jwt string) error {
 	if jwt == "" {
 		return fmt.Errorf("no JWT presented")
@@ -203,6 +226,13 @@ func (v *validator) validateCustomClaims(ctx context.Context, claims jwtgo.MapCl
 func (v *validator) findKeyCandidate(t *jwtgo.Token, jwt string) (interface{}, error) {
 	alg := domain.JwtAlg(t.Method.Alg())
 	keys := v.keysMap[alg]
+	if v.jwks != nil && v.jwks.accepts(alg) {
+		kid, _ := t.Header["kid"].(string)
+		keys = append(append(make([]interface{}, 0, len(keys)), keys...), v.jwks.keysFor(alg, kid)...)
+		if len(keys) == 0 {
+			return nil, fmt.Errorf(`no %s signing key found in JWK Set for the presented JWT (kid: "%s")`, alg, kid)
+		}
+	}
 	switch len(keys) {
 	case 0:
 		return nil, fmt.Errorf(`signing algorithm "%s" of the presented JWT is not in configured allow list %v`, alg, v.validAlgs)
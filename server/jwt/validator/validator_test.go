@@ -12,6 +12,9 @@ import (
 	"github.com/m3dev/dsps/server/domain"
 	. "github.com/m3dev/dsps/server/jwt/testing"
 	. "github.com/m3dev/dsps/server/jwt/validator"
+	"github.com/m3dev/dsps/server/sentry"
+	dspssync "github.com/m3dev/dsps/server/sync"
+	"github.com/m3dev/dsps/server/telemetry"
 )
 
 var pregeneratedPublicKeys = map[domain.JwtAlg][]string{
@@ -77,7 +80,7 @@ func TestMinimalValidation(t *testing.T) {
 		Keys: map[domain.JwtAlg][]string{"none": {}},
 		// No "aud" validation etc.
 		ClockSkewLeeway: &domain.Duration{},
-	}, domain.RealSystemClock)
+	}, domain.RealSystemClock, newDaemonSystemDeps(t))
 	assert.NoError(t, err)
 	v, err := tpl.NewValidator(struct{}{})
 	assert.NoError(t, err)
@@ -230,7 +233,7 @@ func TestClockLeeway(t *testing.T) {
 		Iss:             []domain.JwtIss{"https://example.com/issuer"},
 		Keys:            pregeneratedPublicKeys,
 		ClockSkewLeeway: &domain.Duration{Duration: 300 * time.Second},
-	}, domain.RealSystemClock)
+	}, domain.RealSystemClock, newDaemonSystemDeps(t))
 	assert.NoError(t, err)
 	v, err := tpl.NewValidator(struct{}{})
 	assert.NoError(t, err)
@@ -305,7 +308,7 @@ func TestCustomClaims(t *testing.T) {
 			"https://example.com/payed-user": domain.NewTemplateStrings(trueTpl),
 		},
 		ClockSkewLeeway: &domain.Duration{},
-	}, domain.RealSystemClock)
+	}, domain.RealSystemClock, newDaemonSystemDeps(t))
 	assert.NoError(t, err)
 	v, err := tpl.NewValidator(map[string]map[string]string{
 		"channel": {
@@ -368,9 +371,16 @@ func createDefaultValidator(t *testing.T) Validator {
 		Aud:             []domain.JwtAud{"https://example.com/audience", "https://example.com/audience2"},
 		Keys:            pregeneratedPublicKeys,
 		ClockSkewLeeway: &domain.Duration{},
-	}, domain.RealSystemClock)
+	}, domain.RealSystemClock, newDaemonSystemDeps(t))
 	assert.NoError(t, err)
 	v, err := tpl.NewValidator(struct{}{})
 	assert.NoError(t, err)
 	return v
 }
+
+func newDaemonSystemDeps(t *testing.T) dspssync.DaemonSystemDeps {
+	return dspssync.DaemonSystemDeps{
+		Telemetry: telemetry.NewEmptyTelemetry(t),
+		Sentry:    sentry.NewEmptySentry(),
+	}
+}
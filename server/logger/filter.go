@@ -2,26 +2,46 @@ package logger
 
 import (
 	"sync"
+	"sync/atomic"
 )
 
 // Filter controls log verbosity
 type Filter struct {
-	// Do not modify after creation otherwise breaks goroutine-safe
-	thresholds       sync.Map
+	// Holds *filterThresholds, replaced as a whole (copy-on-write) to keep goroutine-safe
+	current atomic.Value
+	// Serializes writers so that concurrent SetThreshold calls do not lose updates
+	writeLock sync.Mutex
+}
+
+// filterThresholds is immutable once stored into Filter
+type filterThresholds struct {
+	thresholds       map[Category]Level
 	defaultThreshold Level
 }
 
 func newDefaultFilter() *Filter {
-	return &Filter{
-		thresholds:       sync.Map{},
+	filter := &Filter{}
+	filter.current.Store(&filterThresholds{
+		thresholds:       map[Category]Level{},
 		defaultThreshold: INFO,
-	}
+	})
+	return filter
 }
 
 // NewFilter creates Filter instance with given thresholds configuration.
 func NewFilter(thresholds map[string]string) (*Filter, error) {
-	result := Filter{
-		thresholds:       sync.Map{},
+	parsed, err := parseThresholds(thresholds)
+	if err != nil {
+		return nil, err
+	}
+	filter := &Filter{}
+	filter.current.Store(parsed)
+	return filter, nil
+}
+
+func parseThresholds(thresholds map[string]string) (*filterThresholds, error) {
+	result := &filterThresholds{
+		thresholds:       make(map[Category]Level, len(thresholds)),
 		defaultThreshold: INFO,
 	}
 	for key, value := range thresholds {
@@ -29,25 +49,61 @@ func NewFilter(thresholds map[string]string) (*Filter, error) {
 		if err != nil {
 			return nil, err
 		}
-		result.SetThreshold(ParseCategory(key), level)
+		result.set(ParseCategory(key), level)
+	}
+	return result, nil
+}
+
+func (t *filterThresholds) set(cat Category, level Level) {
+	if cat == "*" {
+		t.defaultThreshold = level
+	} else {
+		t.thresholds[cat] = level
 	}
-	return &result, nil
+}
+
+func (filter *Filter) load() *filterThresholds {
+	return filter.current.Load().(*filterThresholds)
 }
 
 // Filter determines whether to output (true) the log or not (false).
 func (filter *Filter) Filter(level Level, cat Category) bool {
-	threshold, ok := filter.thresholds.Load(cat)
+	current := filter.load()
+	threshold, ok := current.thresholds[cat]
 	if !ok {
-		threshold = filter.defaultThreshold
+		threshold = current.defaultThreshold
 	}
-	return level >= threshold.(Level)
+	return level >= threshold
 }
 
 // SetThreshold changes threshold immediately
 func (filter *Filter) SetThreshold(cat Category, level Level) {
-	if cat == "*" {
-		filter.defaultThreshold = level
-	} else {
-		filter.thresholds.Store(cat, level)
+	filter.writeLock.Lock()
+	defer filter.writeLock.Unlock()
+
+	current := filter.load()
+	replacement := &filterThresholds{
+		thresholds:       make(map[Category]Level, len(current.thresholds)+1),
+		defaultThreshold: current.defaultThreshold,
+	}
+	for c, l := range current.thresholds {
+		replacement.thresholds[c] = l
+	}
+	replacement.set(cat, level)
+	filter.current.Store(replacement)
+}
+
+// ReplaceThresholds replaces all thresholds with given configuration (e.g. on configuration reload).
+// Categories not in the configuration fall back to the default threshold.
+// If the configuration is invalid, returns error without changing any thresholds.
+// Concurrent Filter calls see either old or new thresholds as a whole, never a partial set.
+func (filter *Filter) ReplaceThresholds(thresholds map[string]string) error {
+	replacement, err := parseThresholds(thresholds)
+	if err != nil {
+		return err
 	}
+	filter.writeLock.Lock()
+	defer filter.writeLock.Unlock()
+	filter.current.Store(replacement)
+	return nil
 }
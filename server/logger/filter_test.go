@@ -1,6 +1,7 @@
 package logger_test
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,3 +37,52 @@ func TestInvalidFilterDefinition(t *testing.T) {
 	_, err := NewFilter(map[string]string{"auth": "INVALID_LEVEL"})
 	assert.EqualError(t, err, `invalid log level string given: "INVALID_LEVEL"`)
 }
+
+func TestReplaceThresholds(t *testing.T) {
+	filter, err := NewFilter(map[string]string{
+		"*":    "WARN",
+		"auth": "INFO",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, filter.ReplaceThresholds(map[string]string{
+		"http": "DEBUG",
+	}))
+	assert.True(t, filter.Filter(INFO, "any")) // Default threshold is INFO
+	assert.False(t, filter.Filter(DEBUG, "any"))
+	assert.False(t, filter.Filter(DEBUG, "auth")) // Removed from configuration
+	assert.True(t, filter.Filter(DEBUG, "http"))
+
+	// Invalid configuration does not change anything
+	assert.EqualError(t, filter.ReplaceThresholds(map[string]string{"*": "ERROR", "auth": "INVALID_LEVEL"}), `invalid log level string given: "INVALID_LEVEL"`)
+	assert.True(t, filter.Filter(INFO, "any"))
+	assert.True(t, filter.Filter(DEBUG, "http"))
+}
+
+func TestReplaceThresholdsConcurrently(t *testing.T) {
+	filter, err := NewFilter(map[string]string{"*": "WARN", "auth": "DEBUG"})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Both configurations allow DEBUG log of auth, never see partial state
+			assert.True(t, filter.Filter(DEBUG, "auth"))
+			assert.False(t, filter.Filter(INFO, "any"))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, filter.ReplaceThresholds(map[string]string{"*": "ERROR", "auth": "DEBUG"}))
+		filter.SetThreshold("http", INFO)
+	}
+	close(stop)
+	wg.Wait()
+}
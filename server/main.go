@@ -18,6 +18,7 @@ import (
 	"github.com/m3dev/dsps/server/storage/deps"
 	"github.com/m3dev/dsps/server/telemetry"
 	"github.com/m3dev/dsps/server/unix"
+	"github.com/m3dev/dsps/server/webhook/delivery"
 )
 
 // Git commit hash or tag
@@ -41,6 +42,9 @@ func main() {
 }
 
 func mainImpl(ctx context.Context, args []string, clock domain.SystemClock) error {
+	if len(args) > 0 && args[0] == migrateStorageCommand {
+		return migrateStorageMain(ctx, args[1:], clock)
+	}
 	defer func() { logger.Of(ctx).Debugf(logger.CatServer, "Sever closed.") }()
 
 	var (
@@ -63,7 +67,10 @@ func mainImpl(ctx context.Context, args []string, clock domain.SystemClock) erro
 		Debug:        *debug,
 	}
 
-	config, err := config.LoadConfigFile(ctx, configFile, configOverrides)
+	loadConfig := func() (config.ServerConfig, error) {
+		return config.LoadConfigFile(ctx, configFile, configOverrides)
+	}
+	config, err := loadConfig()
 	if err != nil {
 		return err
 	}
@@ -90,7 +97,7 @@ func mainImpl(ctx context.Context, args []string, clock domain.SystemClock) erro
 	}
 	defer telemetry.Shutdown(ctx)
 
-	channelProvider, err := channel.NewChannelProvider(ctx, &config, channel.ProviderDeps{
+	channelProvider, err := channel.NewReloadableChannelProvider(ctx, &config, channel.ProviderDeps{
 		Clock:     clock,
 		Telemetry: telemetry,
 		Sentry:    sentry,
@@ -100,7 +107,7 @@ func mainImpl(ctx context.Context, args []string, clock domain.SystemClock) erro
 	}
 	defer channelProvider.Shutdown(ctx)
 
-	storage, err := storage.NewStorage(ctx, &config.Storages, clock, channelProvider, deps.StorageDeps{
+	storage, err := storage.NewStorage(ctx, &config.Storages, config.StorageMultiplexer, clock, channelProvider, deps.StorageDeps{
 		Telemetry: telemetry,
 		Sentry:    sentry,
 	})
@@ -113,19 +120,35 @@ func mainImpl(ctx context.Context, args []string, clock domain.SystemClock) erro
 		}
 	}()
 
+	webhookQueue := delivery.NewQueue(storage, channelProvider, clock, delivery.Deps{
+		Telemetry: telemetry,
+		Sentry:    sentry,
+	})
+	defer func() {
+		if err := webhookQueue.Shutdown(ctx); err != nil {
+			logger.Of(ctx).WarnError(logger.CatOutgoingWebhook, "Failed to shutdown outgoing-webhook delivery: %w", err)
+		}
+	}()
+	webhookQueue.StartResuming()
+
 	unix.NotifyUlimit(ctx, unix.UlimitRequirement{
 		NoFiles: channelProvider.GetFileDescriptorPressure() + storage.GetFileDescriptorPressure(),
 	})
 
-	http.StartServer(ctx, &http.ServerDependencies{
+	serverDeps := &http.ServerDependencies{
 		Config:          &config,
 		ChannelProvider: channelProvider,
 		Storage:         storage,
+		WebhookQueue:    webhookQueue,
 
 		Telemetry:   telemetry,
 		Sentry:      sentry,
 		LogFilter:   logFilter,
 		ServerClose: httplifecycle.NewServerClose(),
-	})
+	}
+	stopReload := watchReloadSignal(ctx, configFile, loadConfig, serverDeps)
+	defer stopReload()
+
+	http.StartServer(ctx, serverDeps)
 	return nil
 }
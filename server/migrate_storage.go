@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/domain/channel"
+	"github.com/m3dev/dsps/server/logger"
+	"github.com/m3dev/dsps/server/sentry"
+	"github.com/m3dev/dsps/server/storage"
+	"github.com/m3dev/dsps/server/storage/deps"
+	"github.com/m3dev/dsps/server/storage/migration"
+	"github.com/m3dev/dsps/server/telemetry"
+)
+
+const migrateStorageCommand = "migrate-storage"
+
+// migrateStorageMain copies data between storages in the configuration file: dsps migrate-storage -from <ID> -to <ID> <config file>
+func migrateStorageMain(ctx context.Context, args []string, clock domain.SystemClock) error {
+	flags := flag.NewFlagSet(migrateStorageCommand, flag.ContinueOnError)
+	var (
+		from  = flags.String("from", "", "Storage ID to copy data from (required)")
+		to    = flags.String("to", "", "Storage ID to copy data to (required)")
+		debug = flags.Bool("debug", false, "Enable debug logs")
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s -from <storage ID> -to <storage ID> <config file>\n", os.Args[0], migrateStorageCommand)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" || *from == *to {
+		flags.Usage()
+		return fmt.Errorf("%s requires two different storage IDs with -from and -to", migrateStorageCommand)
+	}
+
+	config, err := config.LoadConfigFile(ctx, flags.Arg(0), config.Overrides{
+		BuildVersion: buildVersion,
+		BuildDist:    buildDist,
+		BuildAt:      buildAt,
+		Debug:        *debug,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := logger.InitLogger(config.Logging); err != nil {
+		return err
+	}
+	if src := config.Storages[domain.StorageID(*from)]; src != nil && src.Onmemory != nil {
+		// onmemory storage of this process is always empty, data of running servers cannot be read from here
+		return fmt.Errorf("%s cannot copy data from onmemory storage \"%s\", because it only holds data in the memory of each server process", migrateStorageCommand, *from)
+	}
+
+	sentry, err := sentry.NewSentry(config.Sentry)
+	if err != nil {
+		return err
+	}
+	defer sentry.Shutdown(ctx)
+
+	telemetry, err := telemetry.InitTelemetry(config.Telemetry)
+	if err != nil {
+		return err
+	}
+	defer telemetry.Shutdown(ctx)
+
+	channelProvider, err := channel.NewChannelProvider(ctx, &config, channel.ProviderDeps{
+		Clock:     clock,
+		Telemetry: telemetry,
+		Sentry:    sentry,
+	})
+	if err != nil {
+		return err
+	}
+	defer channelProvider.Shutdown(ctx)
+
+	storageDeps := deps.StorageDeps{
+		Telemetry: telemetry,
+		Sentry:    sentry,
+	}
+	src, err := storage.NewSingleStorage(ctx, domain.StorageID(*from), &config.Storages, clock, channelProvider, storageDeps)
+	if err != nil {
+		return err
+	}
+	defer shutdownStorage(ctx, src)
+	dst, err := storage.NewSingleStorage(ctx, domain.StorageID(*to), &config.Storages, clock, channelProvider, storageDeps)
+	if err != nil {
+		return err
+	}
+	defer shutdownStorage(ctx, dst)
+
+	logger.Of(ctx).Infof(logger.CatStorage, "Migrating storage \"%s\" to \"%s\"...", *from, *to)
+	result, err := migration.MigrateStorage(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	logger.Of(ctx).Infof(logger.CatStorage, "Migrated %d channels (%d subscribers, %d messages) and %d revoked JWTs, skipped %d channels not permitted in the configuration", result.Channels, result.Subscribers, result.Messages, result.RevokedJwts, result.SkippedChannels)
+	return nil
+}
+
+func shutdownStorage(ctx context.Context, s domain.Storage) {
+	if err := s.Shutdown(ctx); err != nil {
+		logger.Of(ctx).WarnError(logger.CatStorage, "Failed to shutdown storage: %w", err)
+	}
+}
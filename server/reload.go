@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/http"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+// watchReloadSignal reloads configuration file on SIGHUP, returns function to stop watching.
+func watchReloadSignal(ctx context.Context, configFile string, loadConfig func() (config.ServerConfig, error), deps *http.ServerDependencies) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if configFile == "-" || configFile == "" {
+				logger.Of(ctx).Warnf(logger.CatServer, "Received SIGHUP but configuration cannot be reloaded because it is not loaded from a file")
+				continue
+			}
+
+			logger.Of(ctx).Infof(logger.CatServer, "Received SIGHUP, reloading configuration file %s", configFile)
+			newConfig, err := loadConfig()
+			if err == nil {
+				err = deps.ReloadConfig(ctx, &newConfig)
+			}
+			if err != nil {
+				logger.Of(ctx).Error("Failed to reload configuration, keep running with current configuration", err)
+				continue
+			}
+			logger.Of(ctx).Infof(logger.CatServer, "Configuration reloaded from %s", configFile)
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
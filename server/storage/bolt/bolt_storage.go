@@ -0,0 +1,105 @@
+package bolt
+
+import (
+	"context"
+	"fmt"
+
+	bbolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+	"github.com/m3dev/dsps/server/storage/deps"
+	"github.com/m3dev/dsps/server/sync"
+)
+
+// NewBoltStorage creates Storage instance
+func NewBoltStorage(ctx context.Context, config *config.BoltStorageConfig, systemClock domain.SystemClock, channelProvider domain.ChannelProvider, deps deps.StorageDeps) (domain.Storage, error) {
+	// Note: bbolt locks the file exclusively, other processes cannot open the same database file.
+	db, err := bbolt.Open(config.Path, 0600, &bbolt.Options{Timeout: config.OpenTimeout.Duration})
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to open bolt database file %s: %w", config.Path, err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{bucketChannels, bucketRevokedJwts} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close() //nolint:errcheck,gosec
+		return nil, xerrors.Errorf("Failed to initialize bolt database file %s: %w", config.Path, err)
+	}
+
+	s := &boltStorage{
+		db:   db,
+		path: config.Path,
+
+		systemClock:     systemClock,
+		channelProvider: channelProvider,
+
+		pubsubEnabled: !config.DisablePubSub,
+		jwtEnabled:    !config.DisableJwt,
+
+		notifier: newPublishNotifier(),
+		daemonSystem: sync.NewDaemonSystem("dsps.storage.bolt", sync.DaemonSystemDeps{
+			Telemetry: deps.Telemetry,
+			Sentry:    deps.Sentry,
+		}, func(ctx context.Context, name string, err error) {
+			logger.Of(ctx).Error(fmt.Sprintf(`error in background routine "%s"`, name), err)
+		}),
+	}
+
+	s.startGC(config.GCInterval.Duration)
+
+	return s, nil
+}
+
+type boltStorage struct {
+	db   *bbolt.DB
+	path string
+
+	pubsubEnabled bool
+	jwtEnabled    bool
+
+	systemClock     domain.SystemClock
+	channelProvider domain.ChannelProvider
+
+	notifier     *publishNotifier
+	daemonSystem *sync.DaemonSystem
+}
+
+func (s *boltStorage) String() string {
+	return "bolt"
+}
+
+func (s *boltStorage) Shutdown(ctx context.Context) error {
+	if err := s.daemonSystem.Shutdown(ctx); err != nil {
+		logger.Of(ctx).WarnError(logger.CatStorage, "Failed to stop background routines", err)
+	}
+
+	logger.Of(ctx).Debugf(logger.CatStorage, "Closing bolt database file %s...", s.path)
+	return s.db.Close()
+}
+
+func (s *boltStorage) AsPubSubStorage() domain.PubSubStorage {
+	if !s.pubsubEnabled {
+		return nil
+	}
+	return s
+}
+func (s *boltStorage) AsJwtStorage() domain.JwtStorage {
+	if !s.jwtEnabled {
+		return nil
+	}
+	return s
+}
+func (s *boltStorage) AsRateLimitStorage() domain.RateLimitStorage {
+	return nil // Database file cannot be shared with other server instances, no benefit over the local counter of the rate limit middleware
+}
+
+func (s *boltStorage) GetFileDescriptorPressure() int {
+	return 1 // Database file
+}
@@ -0,0 +1,104 @@
+package bolt_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/storage/bolt"
+	. "github.com/m3dev/dsps/server/storage/deps/testing"
+	. "github.com/m3dev/dsps/server/storage/testing"
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+func newConfig(path string) *config.BoltStorageConfig {
+	gcInterval := dspstesting.MakeDuration("5m")
+	openTimeout := dspstesting.MakeDuration("100ms")
+	return &config.BoltStorageConfig{
+		Path:        path,
+		GCInterval:  &gcInterval,
+		OpenTimeout: &openTimeout,
+	}
+}
+
+var storageCtor func(t *testing.T) StorageCtor = func(t *testing.T) StorageCtor {
+	return func(ctx context.Context, systemClock domain.SystemClock, channelProvider domain.ChannelProvider) (domain.Storage, error) {
+		return NewBoltStorage(context.Background(), newConfig(filepath.Join(t.TempDir(), "dsps.db")), systemClock, channelProvider, EmptyDeps(t))
+	}
+}
+
+func TestCoreFunction(t *testing.T) {
+	CoreFunctionTest(t, storageCtor(t))
+}
+
+func TestPubSub(t *testing.T) {
+	PubSubTest(t, storageCtor(t))
+}
+
+func TestJwt(t *testing.T) {
+	JwtTest(t, storageCtor(t))
+}
+
+func TestFeatureFlags(t *testing.T) {
+	cfg := newConfig(filepath.Join(t.TempDir(), "dsps.db"))
+	cfg.DisablePubSub = true
+	cfg.DisableJwt = true
+	s, err := NewBoltStorage(context.Background(), cfg, domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, s.Shutdown(context.Background())) }()
+	assert.Nil(t, s.AsPubSubStorage())
+	assert.Nil(t, s.AsJwtStorage())
+	assert.Nil(t, s.AsRateLimitStorage())
+}
+
+func TestPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dsps.db")
+	sl := domain.SubscriberLocator{ChannelID: "ch-1", SubscriberID: "sbsc-1"}
+	msg := domain.Message{
+		MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-1"},
+		Content:        json.RawMessage(`{"hi":"hello"}`),
+		Attributes:     domain.MessageAttributes{"event-type": "created"},
+	}
+	jwtExp, err := domain.ParseJwtExp("4102444800") // 2100-01-01
+	assert.NoError(t, err)
+
+	s, err := NewBoltStorage(ctx, newConfig(path), domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, s.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
+	assert.NoError(t, s.AsPubSubStorage().PublishMessages(ctx, []domain.Message{msg}))
+	assert.NoError(t, s.AsJwtStorage().RevokeJwt(ctx, jwtExp, "jti-1"))
+
+	// Database file is locked by the storage
+	_, err = NewBoltStorage(ctx, newConfig(path), domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	assert.Regexp(t, `Failed to open bolt database file`, err.Error())
+	assert.NoError(t, s.Shutdown(ctx))
+
+	// Reopen
+	s, err = NewBoltStorage(ctx, newConfig(path), domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	received, _, ackHandle, err := s.AsPubSubStorage().FetchMessages(ctx, sl, 10, dspstesting.MakeDuration("0s"))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Message{msg}, received)
+	assert.NoError(t, s.AsPubSubStorage().AcknowledgeMessages(ctx, ackHandle))
+	revoked, err := s.AsJwtStorage().IsRevokedJwt(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestGetFileDescriptorPressure(t *testing.T) {
+	s, err := NewBoltStorage(context.Background(), newConfig(filepath.Join(t.TempDir(), "dsps.db")), domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, s.Shutdown(context.Background())) }()
+	assert.Equal(t, 1, s.GetFileDescriptorPressure())
+}
@@ -0,0 +1,103 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// Root buckets
+var (
+	bucketChannels    = []byte("channels")     // ChannelID -> channel bucket
+	bucketRevokedJwts = []byte("revoked-jwts") // JwtJti -> exp (unix epoch seconds)
+)
+
+// Keys and sub-buckets of each channel bucket
+var (
+	keyChannelClock   = []byte("clock")       // Clock of the latest message
+	bucketMessages    = []byte("messages")    // clock -> boltMessage
+	bucketMessageIDs  = []byte("ids")         // MessageID -> boltMessageIndex, remains after eviction for deduplication
+	bucketSubscribers = []byte("subscribers") // SubscriberID -> boltSubscriber
+)
+
+// expiringValue is common part of values removed by GC
+type expiringValue struct {
+	ExpireAt time.Time `json:"exp"`
+}
+
+type boltMessage struct {
+	ID       domain.MessageID  `json:"id"`
+	Content  json.RawMessage   `json:"content"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Trace    string            `json:"trace,omitempty"`
+	ExpireAt time.Time         `json:"exp"`
+}
+
+func (msg boltMessage) toDomain(ch domain.ChannelID) domain.Message {
+	return domain.Message{
+		MessageLocator: domain.MessageLocator{
+			ChannelID: ch,
+			MessageID: msg.ID,
+		},
+		Content:     msg.Content,
+		Attributes:  msg.Attrs,
+		TraceParent: msg.Trace,
+	}
+}
+
+type boltMessageIndex struct {
+	Clock    uint64    `json:"clock"`
+	ExpireAt time.Time `json:"exp"`
+}
+
+type boltSubscriber struct {
+	Clock    uint64          `json:"clock"` // Clock of the latest acknowledged message
+	ExpireAt time.Time       `json:"exp"`
+	Filter   json.RawMessage `json:"filter,omitempty"`
+}
+
+type boltRevokedJwt struct {
+	ExpireAt time.Time `json:"exp"`
+}
+
+func (sbsc boltSubscriber) filter() (*domain.SubscriberFilter, error) {
+	return domain.ParseSubscriberFilter(sbsc.Filter)
+}
+
+func encodeClock(clock uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, clock) // Big endian to keep keys sorted by clock
+	return b
+}
+
+func decodeClock(b []byte) uint64 {
+	if len(b) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func putJSON(bucket *bbolt.Bucket, key []byte, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return xerrors.Errorf("Failed to encode bolt storage value of %q: %w", key, err)
+	}
+	return bucket.Put(key, encoded)
+}
+
+// getJSON returns false if the key does not exist
+func getJSON(bucket *bbolt.Bucket, key []byte, value interface{}) (bool, error) {
+	raw := bucket.Get(key)
+	if raw == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, value); err != nil {
+		return false, xerrors.Errorf("Failed to parse bolt storage value of %q (%s): %w", key, string(raw), err)
+	}
+	return true, nil
+}
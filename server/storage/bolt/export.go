@@ -0,0 +1,87 @@
+package bolt
+
+import (
+	"context"
+	"sort"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (s *boltStorage) ExportChannels(ctx context.Context, fn func(domain.ChannelExport) error) error {
+	return domain.ExportChannelsOneByOne(ctx, s, fn)
+}
+
+func (s *boltStorage) ExportChannel(ctx context.Context, channelID domain.ChannelID) (domain.ChannelExport, error) {
+	now := s.systemClock.Now().Time
+	result := domain.ChannelExport{
+		ChannelID:   channelID,
+		Messages:    []domain.Message{},
+		Subscribers: []domain.SubscriberExport{},
+	}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketChannels).Bucket([]byte(channelID))
+		if bucket == nil {
+			return nil
+		}
+		// Do not use getChannel, channels removed from the configuration are also exportable
+		ch := &boltChannel{bucket: bucket}
+
+		clocks := []uint64{}
+		if err := ch.forEachMessage(0, now, func(clock uint64, msg *boltMessage) (bool, error) {
+			clocks = append(clocks, clock)
+			result.Messages = append(result.Messages, msg.toDomain(channelID))
+			return true, nil
+		}); err != nil {
+			return err
+		}
+
+		// Keys of the bucket are sorted in byte order
+		return bucket.Bucket(bucketSubscribers).ForEach(func(k, v []byte) error {
+			sbsc, err := ch.getSubscriber(domain.SubscriberID(k))
+			if err != nil {
+				return err
+			}
+			if !sbsc.ExpireAt.After(now) {
+				return nil // Expired, GC will remove it
+			}
+			filter, err := sbsc.filter()
+			if err != nil {
+				return err
+			}
+			result.Subscribers = append(result.Subscribers, domain.SubscriberExport{
+				SubscriberID: domain.SubscriberID(k),
+				Filter:       filter,
+				Consumed:     sort.Search(len(clocks), func(i int) bool { return clocks[i] > sbsc.Clock }),
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return domain.ChannelExport{}, err
+	}
+	return result, nil
+}
+
+func (s *boltStorage) ExportRevokedJwts(ctx context.Context) ([]domain.RevokedJwt, error) {
+	now := s.systemClock.Now().Time
+	result := []domain.RevokedJwt{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketRevokedJwts)
+		return bucket.ForEach(func(k, v []byte) error {
+			value := boltRevokedJwt{}
+			if _, err := getJSON(bucket, k, &value); err != nil {
+				return err
+			}
+			if !now.After(value.ExpireAt) {
+				result = append(result, domain.RevokedJwt{Jti: domain.JwtJti(k), Exp: domain.JwtExp(value.ExpireAt)})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
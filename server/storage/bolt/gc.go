@@ -0,0 +1,94 @@
+package bolt
+
+import (
+	"context"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/m3dev/dsps/server/sync"
+)
+
+func (s *boltStorage) startGC(interval time.Duration) {
+	s.daemonSystem.Start("gc", func(ctx context.Context) (sync.DaemonNextRun, error) {
+		err := s.GC(ctx)
+		return sync.DaemonNextRun{Interval: interval}, err
+	})
+}
+
+// GC removes expired subscribers, messages and JWT revocations.
+// To prevent long blocking of writers, runs one transaction for each channel.
+func (s *boltStorage) GC(ctx context.Context) error {
+	now := s.systemClock.Now().Time
+
+	channelIDs := [][]byte{}
+	if err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketChannels).ForEach(func(k, v []byte) error {
+			channelIDs = append(channelIDs, append([]byte{}, k...)) // Key is valid only within the transaction
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	for _, id := range channelIDs {
+		if err := ctx.Err(); err != nil {
+			return err // Context canceled
+		}
+		if err := s.db.Update(func(tx *bbolt.Tx) error {
+			root := tx.Bucket(bucketChannels)
+			bucket := root.Bucket(id)
+			if bucket == nil {
+				return nil
+			}
+
+			empty := true
+			for _, name := range [][]byte{bucketSubscribers, bucketMessages, bucketMessageIDs} {
+				remains, err := deleteExpiredValues(bucket.Bucket(name), now)
+				if err != nil {
+					return err
+				}
+				empty = empty && remains == 0
+			}
+			if empty {
+				// Drop channel clock as well, no subscriber can detect reset of the clock.
+				return root.DeleteBucket(id)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Delete expired JWT revocation memory
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		_, err := deleteExpiredValues(tx.Bucket(bucketRevokedJwts), now)
+		return err
+	})
+}
+
+// deleteExpiredValues returns count of remaining values.
+func deleteExpiredValues(bucket *bbolt.Bucket, now time.Time) (int, error) {
+	expired := [][]byte{}
+	remains := 0
+	if err := bucket.ForEach(func(k, v []byte) error {
+		value := expiringValue{}
+		if _, err := getJSON(bucket, k, &value); err != nil {
+			return err
+		}
+		if value.ExpireAt.After(now) {
+			remains++
+		} else {
+			expired = append(expired, append([]byte{}, k...)) // Cannot delete during iteration
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	for _, k := range expired {
+		if err := bucket.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return remains, nil
+}
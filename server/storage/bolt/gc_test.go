@@ -0,0 +1,72 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/storage/deps/testing"
+	. "github.com/m3dev/dsps/server/storage/testing"
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+func TestGC(t *testing.T) {
+	ctx := context.Background()
+	clock := dspstesting.NewStubClock(t)
+	gcInterval := dspstesting.MakeDuration("5m")
+	openTimeout := dspstesting.MakeDuration("100ms")
+	s, err := NewBoltStorage(ctx, &config.BoltStorageConfig{
+		Path:        filepath.Join(t.TempDir(), "dsps.db"),
+		GCInterval:  &gcInterval,
+		OpenTimeout: &openTimeout,
+	}, clock, StubChannelProvider, EmptyDeps(t))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.(*boltStorage)
+
+	jwtExp := domain.JwtExp(clock.Now().Add(StubChannelExpire.Duration))
+	assert.NoError(t, storage.RevokeJwt(ctx, jwtExp, "jti-1"))
+	sl1 := domain.SubscriberLocator{ChannelID: "ch-1", SubscriberID: "sbsc-1"}
+	sl2 := domain.SubscriberLocator{ChannelID: "ch-2", SubscriberID: "sbsc-2"}
+	assert.NoError(t, storage.NewSubscriber(ctx, sl1, nil))
+	assert.NoError(t, storage.NewSubscriber(ctx, sl2, nil))
+	assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{{
+		MessageLocator: domain.MessageLocator{ChannelID: sl1.ChannelID, MessageID: "msg-1"},
+		Content:        json.RawMessage(`{}`),
+	}}))
+
+	// Keep sbsc-2 alive
+	clock.Add(StubChannelExpire.Duration / 2)
+	_, _, _, err = storage.FetchMessages(ctx, sl2, 1, dspstesting.MakeDuration("0s"))
+	assert.NoError(t, err)
+
+	clock.Add(StubChannelExpire.Duration/2 + time.Second)
+	assert.NoError(t, storage.GC(ctx))
+
+	// Channel without subscribers nor messages is removed
+	assert.NoError(t, storage.db.View(func(tx *bbolt.Tx) error {
+		assert.Nil(t, tx.Bucket(bucketChannels).Bucket([]byte(sl1.ChannelID)))
+		assert.NotNil(t, tx.Bucket(bucketChannels).Bucket([]byte(sl2.ChannelID)))
+		assert.Nil(t, tx.Bucket(bucketRevokedJwts).Get([]byte("jti-1")))
+		return nil
+	}))
+	_, _, _, err = storage.FetchMessages(ctx, sl1, 1, dspstesting.MakeDuration("0s"))
+	assert.True(t, errors.Is(err, domain.ErrSubscriptionNotFound))
+	_, _, _, err = storage.FetchMessages(ctx, sl2, 1, dspstesting.MakeDuration("0s"))
+	assert.NoError(t, err)
+
+	// Cancelled
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.True(t, errors.Is(storage.GC(canceledCtx), context.Canceled))
+}
@@ -0,0 +1,66 @@
+package bolt
+
+import (
+	"context"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (s *boltStorage) InspectChannels(ctx context.Context, channelID domain.ChannelID) ([]domain.ChannelInspection, error) {
+	now := s.systemClock.Now().Time
+	result := []domain.ChannelInspection{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		// Keys of the bucket are sorted in byte order
+		return tx.Bucket(bucketChannels).ForEach(func(k, v []byte) error {
+			id := domain.ChannelID(k)
+			if channelID != "" && channelID != id {
+				return nil
+			}
+
+			bucket := tx.Bucket(bucketChannels).Bucket(k)
+			inspection := domain.ChannelInspection{
+				ChannelID: id,
+				Clock:     int64(decodeClock(bucket.Get(keyChannelClock))),
+				TTL:       nil, // GC discards channel only after all subscribers and messages expired
+
+				Subscribers: []domain.SubscriberInspection{},
+			}
+			messages := bucket.Bucket(bucketMessages)
+			if err := bucket.Bucket(bucketSubscribers).ForEach(func(k, v []byte) error {
+				sbsc := &boltSubscriber{}
+				if _, err := getJSON(bucket.Bucket(bucketSubscribers), k, sbsc); err != nil {
+					return err
+				}
+				filter, err := sbsc.filter()
+				if err != nil {
+					return err
+				}
+
+				backlog := int64(0)
+				c := messages.Cursor()
+				for k, _ := c.Seek(encodeClock(sbsc.Clock + 1)); k != nil; k, _ = c.Next() {
+					backlog++
+				}
+				ttl := domain.Duration{Duration: sbsc.ExpireAt.Sub(now)}
+				inspection.Subscribers = append(inspection.Subscribers, domain.SubscriberInspection{
+					SubscriberID: domain.SubscriberID(k),
+					Clock:        int64(sbsc.Clock),
+					Backlog:      backlog, // Includes expired and filtered messages
+					TTL:          &ttl,
+					Filter:       filter,
+				})
+				return nil
+			}); err != nil {
+				return err
+			}
+			result = append(result, inspection)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
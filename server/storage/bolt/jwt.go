@@ -0,0 +1,29 @@
+package bolt
+
+import (
+	"context"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (s *boltStorage) RevokeJwt(ctx context.Context, exp domain.JwtExp, jti domain.JwtJti) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return putJSON(tx.Bucket(bucketRevokedJwts), []byte(jti), boltRevokedJwt{ExpireAt: exp.Time()})
+	})
+}
+
+func (s *boltStorage) IsRevokedJwt(ctx context.Context, jti domain.JwtJti) (bool, error) {
+	revoked := false
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := boltRevokedJwt{}
+		found, err := getJSON(tx.Bucket(bucketRevokedJwts), []byte(jti), &value)
+		revoked = found && !s.systemClock.Now().After(value.ExpireAt)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
@@ -0,0 +1,57 @@
+package bolt
+
+import (
+	"sync"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// publishNotifier wakes up long polling on publish.
+// Because other processes cannot open the database file, notification within this process is enough.
+type publishNotifier struct {
+	lock    sync.Mutex
+	waiters map[domain.ChannelID]*publishWaiter
+}
+
+type publishWaiter struct {
+	published chan struct{}
+	count     int
+}
+
+func newPublishNotifier() *publishNotifier {
+	return &publishNotifier{
+		waiters: map[domain.ChannelID]*publishWaiter{},
+	}
+}
+
+// wait returns chan closed on next publish to the channel, caller must call returned release function after use.
+func (n *publishNotifier) wait(id domain.ChannelID) (<-chan struct{}, func()) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	w := n.waiters[id]
+	if w == nil {
+		w = &publishWaiter{published: make(chan struct{})}
+		n.waiters[id] = w
+	}
+	w.count++
+	return w.published, func() {
+		n.lock.Lock()
+		defer n.lock.Unlock()
+
+		w.count--
+		if w.count == 0 && n.waiters[id] == w {
+			delete(n.waiters, id)
+		}
+	}
+}
+
+func (n *publishNotifier) notify(id domain.ChannelID) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if w := n.waiters[id]; w != nil {
+		close(w.published)
+		delete(n.waiters, id)
+	}
+}
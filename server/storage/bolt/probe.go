@@ -0,0 +1,19 @@
+package bolt
+
+import (
+	"context"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+func (s *boltStorage) Liveness(ctx context.Context) (interface{}, error) {
+	return "ok", nil
+}
+
+func (s *boltStorage) Readiness(ctx context.Context) (interface{}, error) {
+	// Fails if the database file has been closed
+	if err := s.db.View(func(tx *bbolt.Tx) error { return nil }); err != nil {
+		return nil, err
+	}
+	return "ok", nil
+}
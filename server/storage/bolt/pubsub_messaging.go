@@ -0,0 +1,233 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (s *boltStorage) PublishMessages(ctx context.Context, msgs []domain.Message) error {
+	if !domain.BelongsToSameChannel(msgs) {
+		return xerrors.New("Messages belongs to various channels")
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	now := s.systemClock.Now().Time
+	published := 0
+	var publishErr error
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		ch, err := s.getChannel(tx, msgs[0].ChannelID, true)
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if publishErr = s.publishMessage(ch, msg, now); publishErr != nil {
+				break // Commit messages before the failure as well as other storages
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if published > 0 {
+		s.notifier.notify(msgs[0].ChannelID)
+	}
+	return publishErr
+}
+
+func (s *boltStorage) publishMessage(ch *boltChannel, msg domain.Message, now time.Time) error {
+	ids := ch.bucket.Bucket(bucketMessageIDs)
+	index := boltMessageIndex{}
+	if found, err := getJSON(ids, []byte(msg.MessageID), &index); err != nil {
+		return err
+	} else if found && index.ExpireAt.After(now) {
+		return nil // Duplicated message
+	}
+
+	expireAt := now.Add(ch.Expire().Duration)
+	encoded, err := json.Marshal(boltMessage{
+		ID:       msg.MessageID,
+		Content:  msg.Content,
+		Attrs:    msg.Attributes,
+		Trace:    msg.TraceParent,
+		ExpireAt: expireAt,
+	})
+	if err != nil {
+		return xerrors.Errorf(`%w: %v`, domain.ErrMalformedMessageJSON, err)
+	}
+	if err := s.makeRoomForMessage(ch, now); err != nil {
+		return err
+	}
+
+	clock := ch.clock() + 1 // Must start with 1
+	if err := ch.bucket.Bucket(bucketMessages).Put(encodeClock(clock), encoded); err != nil {
+		return err
+	}
+	if err := putJSON(ids, []byte(msg.MessageID), boltMessageIndex{Clock: clock, ExpireAt: expireAt}); err != nil {
+		return err
+	}
+	return ch.bucket.Put(keyChannelClock, encodeClock(clock))
+}
+
+// makeRoomForMessage enforces BacklogLimit of the channel before storing new message.
+func (s *boltStorage) makeRoomForMessage(ch *boltChannel, now time.Time) error {
+	limit := ch.BacklogLimit()
+	if limit.Max <= 0 {
+		return nil
+	}
+
+	retained := make([]uint64, 0, limit.Max)
+	if err := ch.forEachMessage(0, now, func(clock uint64, msg *boltMessage) (bool, error) {
+		retained = append(retained, clock)
+		return true, nil
+	}); err != nil {
+		return err
+	}
+	if len(retained) < limit.Max {
+		return nil
+	}
+	if limit.Overflow != domain.BacklogOverflowEvict {
+		return xerrors.Errorf("channel retains %d messages (max: %d): %w", len(retained), limit.Max, domain.ErrBacklogFull)
+	}
+
+	// Evict oldest messages, could be more than one if the limit has been reduced by configuration reload.
+	// Index of the message remains for deduplication.
+	messages := ch.bucket.Bucket(bucketMessages)
+	for _, clock := range retained[:len(retained)-limit.Max+1] {
+		if err := messages.Delete(encodeClock(clock)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *boltStorage) FetchMessages(ctx context.Context, sl domain.SubscriberLocator, max int, waituntil domain.Duration) ([]domain.Message, bool, domain.AckHandle, error) {
+	timeoutTimer := time.NewTimer(waituntil.Duration)
+	defer timeoutTimer.Stop()
+
+	for {
+		// Start waiting before fetch, not to miss messages published during fetch.
+		published, release := s.notifier.wait(sl.ChannelID)
+		messages, moreMessages, lastClock, err := s.fetchMessages(sl, max)
+		if err != nil || len(messages) > 0 {
+			release()
+			if err != nil {
+				return []domain.Message{}, false, domain.AckHandle{}, err
+			}
+			return messages, moreMessages, encodeAckHandle(sl, ackHandleData{LastClock: lastClock}), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			release()
+			return []domain.Message{}, false, domain.AckHandle{}, ctx.Err()
+		case <-timeoutTimer.C:
+			release()
+			return []domain.Message{}, false, domain.AckHandle{}, nil
+		case <-published:
+			release()
+		}
+	}
+}
+
+func (s *boltStorage) fetchMessages(sl domain.SubscriberLocator, max int) (messages []domain.Message, moreMessages bool, lastClock uint64, err error) {
+	now := s.systemClock.Now().Time
+	messages = []domain.Message{}
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		ch, err := s.getChannel(tx, sl.ChannelID, false)
+		if err != nil {
+			return err
+		}
+		sbsc, err := findSubscriber(ch, sl.SubscriberID)
+		if err != nil {
+			return err
+		}
+		filter, err := sbsc.filter()
+		if err != nil {
+			return err
+		}
+
+		if err := ch.forEachMessage(sbsc.Clock, now, func(clock uint64, msg *boltMessage) (bool, error) {
+			wrapped := msg.toDomain(sl.ChannelID)
+			if !filter.Match(wrapped) {
+				lastClock = clock // Acknowledging messages also skips filtered messages.
+				return true, nil
+			}
+			if len(messages) >= max {
+				moreMessages = true
+				return false, nil
+			}
+			messages = append(messages, wrapped)
+			lastClock = clock
+			return true, nil
+		}); err != nil {
+			return err
+		}
+		if len(messages) == 0 && lastClock > sbsc.Clock {
+			sbsc.Clock = lastClock // All messages are filtered out, skip them to not scan them again.
+		}
+		return ch.putSubscriber(sl.SubscriberID, sbsc, now)
+	})
+	return
+}
+
+func (s *boltStorage) AcknowledgeMessages(ctx context.Context, handle domain.AckHandle) error {
+	now := s.systemClock.Now().Time
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		ch, err := s.getChannel(tx, handle.ChannelID, false)
+		if err != nil {
+			return err
+		}
+		sbsc, err := findSubscriber(ch, handle.SubscriberID)
+		if err != nil {
+			return err
+		}
+
+		data, err := decodeAckHandle(handle)
+		if err != nil {
+			return err
+		}
+		if data.LastClock > sbsc.Clock && data.LastClock <= ch.clock() {
+			sbsc.Clock = data.LastClock
+		} // else: AckHandle is stale, may be already consumed
+		return ch.putSubscriber(handle.SubscriberID, sbsc, now)
+	})
+}
+
+func (s *boltStorage) IsOldMessages(ctx context.Context, sl domain.SubscriberLocator, msgs []domain.MessageLocator) (map[domain.MessageLocator]bool, error) {
+	result := map[domain.MessageLocator]bool{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		ch, err := s.getChannel(tx, sl.ChannelID, false)
+		if err != nil {
+			return err
+		}
+		sbsc, err := findSubscriber(ch, sl.SubscriberID)
+		if err != nil {
+			return err
+		}
+
+		ids := ch.bucket.Bucket(bucketMessageIDs)
+		for _, msg := range msgs {
+			index := boltMessageIndex{}
+			found, err := getJSON(ids, []byte(msg.MessageID), &index)
+			if err != nil {
+				return err
+			}
+			result[msg] = msg.ChannelID == sl.ChannelID && found && index.Clock <= sbsc.Clock
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,197 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// boltChannel is a view of the channel bucket within a transaction
+type boltChannel struct {
+	domain.Channel
+	bucket *bbolt.Bucket
+}
+
+func (s *boltStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter) error {
+	now := s.systemClock.Now().Time
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		ch, err := s.getChannel(tx, sl.ChannelID, true)
+		if err != nil {
+			return err
+		}
+
+		sbsc, err := ch.getSubscriber(sl.SubscriberID)
+		if err != nil {
+			return err
+		}
+		if sbsc == nil {
+			sbsc = &boltSubscriber{Clock: ch.clock()}
+		}
+		// If already exists, only replace filter
+		sbsc.Filter = nil
+		if filter != nil {
+			sbsc.Filter = json.RawMessage(filter.String())
+		}
+		return ch.putSubscriber(sl.SubscriberID, sbsc, now)
+	})
+}
+
+// NewQueueSubscriber is not supported because bolt storage keeps only a cursor per subscriber.
+func (s *boltStorage) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	return xerrors.Errorf("bolt storage: %w", domain.ErrQueueSubscriberUnsupported)
+}
+
+func (s *boltStorage) RemoveSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		ch, err := s.getChannel(tx, sl.ChannelID, false)
+		if errors.Is(err, domain.ErrInvalidChannel) {
+			// Because channel does not exist, subscriber also does not exist.
+			// This method returns nil (success) if subscriber does not exist.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ch == nil {
+			return nil
+		}
+		return ch.bucket.Bucket(bucketSubscribers).Delete([]byte(sl.SubscriberID))
+	})
+}
+
+func (s *boltStorage) RewindSubscriber(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) error {
+	now := s.systemClock.Now().Time
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		ch, err := s.getChannel(tx, sl.ChannelID, false)
+		if err != nil {
+			return err
+		}
+		sbsc, err := findSubscriber(ch, sl.SubscriberID)
+		if err != nil {
+			return err
+		}
+
+		var cursor uint64
+		switch {
+		case target.Clock != nil:
+			cursor = uint64(*target.Clock)
+			if *target.Clock < 0 || (cursor != ch.clock() && !ch.isRetained(cursor+1, now)) {
+				return xerrors.Errorf("%w: clock %d", domain.ErrRewindTargetNotFound, *target.Clock)
+			}
+		case target.MessageID != nil:
+			index := boltMessageIndex{}
+			found, err := getJSON(ch.bucket.Bucket(bucketMessageIDs), []byte(*target.MessageID), &index)
+			if err != nil {
+				return err
+			}
+			if !found || !ch.isRetained(index.Clock, now) { // Evicted or expired
+				return xerrors.Errorf("%w: message %s", domain.ErrRewindTargetNotFound, *target.MessageID)
+			}
+			cursor = index.Clock - 1
+		case target.Earliest:
+			cursor = ch.clock()
+			if err := ch.forEachMessage(0, now, func(clock uint64, msg *boltMessage) (bool, error) {
+				cursor = clock - 1
+				return false, nil
+			}); err != nil {
+				return err
+			}
+		default:
+			return xerrors.New("Rewind target not specified")
+		}
+
+		sbsc.Clock = cursor
+		return ch.putSubscriber(sl.SubscriberID, sbsc, now)
+	})
+}
+
+// getChannel returns nil if the channel has not been stored and create is false.
+func (s *boltStorage) getChannel(tx *bbolt.Tx, id domain.ChannelID, create bool) (*boltChannel, error) {
+	rawCh, err := s.channelProvider.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	root := tx.Bucket(bucketChannels)
+	bucket := root.Bucket([]byte(id))
+	if bucket == nil {
+		if !create {
+			return nil, nil
+		}
+		if bucket, err = root.CreateBucket([]byte(id)); err != nil {
+			return nil, xerrors.Errorf("Failed to create bolt bucket of the channel %s: %w", id, err)
+		}
+		for _, name := range [][]byte{bucketMessages, bucketMessageIDs, bucketSubscribers} {
+			if _, err := bucket.CreateBucket(name); err != nil {
+				return nil, xerrors.Errorf("Failed to create bolt bucket of the channel %s: %w", id, err)
+			}
+		}
+	}
+	return &boltChannel{Channel: rawCh, bucket: bucket}, nil
+}
+
+// findSubscriber returns ErrSubscriptionNotFound if the channel or the subscriber not found.
+func findSubscriber(ch *boltChannel, id domain.SubscriberID) (*boltSubscriber, error) {
+	if ch == nil {
+		return nil, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+	}
+	sbsc, err := ch.getSubscriber(id)
+	if err != nil {
+		return nil, err
+	}
+	if sbsc == nil {
+		return nil, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+	}
+	return sbsc, nil
+}
+
+func (ch *boltChannel) clock() uint64 {
+	return decodeClock(ch.bucket.Get(keyChannelClock))
+}
+
+// getSubscriber returns nil if not found
+func (ch *boltChannel) getSubscriber(id domain.SubscriberID) (*boltSubscriber, error) {
+	sbsc := &boltSubscriber{}
+	found, err := getJSON(ch.bucket.Bucket(bucketSubscribers), []byte(id), sbsc)
+	if err != nil || !found {
+		return nil, err
+	}
+	return sbsc, nil
+}
+
+// putSubscriber also extends expiration of the subscriber.
+func (ch *boltChannel) putSubscriber(id domain.SubscriberID, sbsc *boltSubscriber, now time.Time) error {
+	sbsc.ExpireAt = now.Add(ch.Expire().Duration)
+	return putJSON(ch.bucket.Bucket(bucketSubscribers), []byte(id), sbsc)
+}
+
+// isRetained returns false if the message has been evicted or expired.
+func (ch *boltChannel) isRetained(clock uint64, now time.Time) bool {
+	msg := expiringValue{}
+	found, err := getJSON(ch.bucket.Bucket(bucketMessages), encodeClock(clock), &msg)
+	return err == nil && found && msg.ExpireAt.After(now)
+}
+
+// forEachMessage calls f for each retained message published after the given clock in order, until f returns false.
+func (ch *boltChannel) forEachMessage(after uint64, now time.Time, f func(clock uint64, msg *boltMessage) (bool, error)) error {
+	c := ch.bucket.Bucket(bucketMessages).Cursor()
+	for k, v := c.Seek(encodeClock(after + 1)); k != nil; k, v = c.Next() {
+		msg := &boltMessage{}
+		if err := json.Unmarshal(v, msg); err != nil {
+			return xerrors.Errorf("Failed to parse bolt storage message '%s': %w", string(v), err)
+		}
+		if !msg.ExpireAt.After(now) {
+			continue // Expired, GC will remove it
+		}
+		if next, err := f(decodeClock(k), msg); err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
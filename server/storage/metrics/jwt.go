@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (ms *metricsStorage) RevokeJwt(ctx context.Context, exp domain.JwtExp, jti domain.JwtJti) error {
+	return ms.jwt.RevokeJwt(ctx, exp, jti)
+}
+
+func (ms *metricsStorage) IsRevokedJwt(ctx context.Context, jti domain.JwtJti) (bool, error) {
+	return ms.jwt.IsRevokedJwt(ctx, jti)
+}
+
+func (ms *metricsStorage) ExportRevokedJwts(ctx context.Context) ([]domain.RevokedJwt, error) {
+	return ms.jwt.ExportRevokedJwts(ctx)
+}
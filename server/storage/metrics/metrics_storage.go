@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/storage/deps"
+	"github.com/m3dev/dsps/server/telemetry"
+)
+
+type metricsStorage struct {
+	t               *telemetry.Telemetry
+	clock           domain.SystemClock
+	channelProvider domain.ChannelProvider
+
+	s      domain.Storage
+	pubsub domain.PubSubStorage
+	jwt    domain.JwtStorage
+
+	rateLimit domain.RateLimitStorage
+}
+
+// NewMetricsStorage wraps given Storage to record metrics.
+// Should wrap only the root storage, otherwise operations are counted for each storage.
+func NewMetricsStorage(s domain.Storage, clock domain.SystemClock, channelProvider domain.ChannelProvider, deps deps.StorageDeps) domain.Storage {
+	return &metricsStorage{
+		t:               deps.Telemetry,
+		clock:           clock,
+		channelProvider: channelProvider,
+
+		s:      s,
+		pubsub: s.AsPubSubStorage(),
+		jwt:    s.AsJwtStorage(),
+
+		rateLimit: s.AsRateLimitStorage(),
+	}
+}
+
+func (ms *metricsStorage) AsPubSubStorage() domain.PubSubStorage {
+	if ms.pubsub == nil {
+		return nil
+	}
+	return ms
+}
+
+func (ms *metricsStorage) AsJwtStorage() domain.JwtStorage {
+	if ms.jwt == nil {
+		return nil
+	}
+	return ms
+}
+
+func (ms *metricsStorage) AsRateLimitStorage() domain.RateLimitStorage {
+	if ms.rateLimit == nil {
+		return nil
+	}
+	return ms
+}
+
+func (ms *metricsStorage) String() string {
+	return ms.s.String()
+}
+
+func (ms *metricsStorage) GetFileDescriptorPressure() int {
+	return ms.s.GetFileDescriptorPressure()
+}
+
+func (ms *metricsStorage) Shutdown(ctx context.Context) error {
+	return ms.s.Shutdown(ctx)
+}
+
+func (ms *metricsStorage) Liveness(ctx context.Context) (interface{}, error) {
+	return ms.s.Liveness(ctx)
+}
+
+func (ms *metricsStorage) Readiness(ctx context.Context) (interface{}, error) {
+	return ms.s.Readiness(ctx)
+}
+
+// channelLabel returns low-cardinality label of the channel.
+func (ms *metricsStorage) channelLabel(id domain.ChannelID) string {
+	channel, err := ms.channelProvider.Get(id)
+	if err != nil {
+		return ""
+	}
+	return channel.RegexLabel()
+}
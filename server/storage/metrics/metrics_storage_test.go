@@ -0,0 +1,83 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/storage/deps/testing"
+	. "github.com/m3dev/dsps/server/storage/metrics"
+	"github.com/m3dev/dsps/server/storage/onmemory"
+	. "github.com/m3dev/dsps/server/storage/testing"
+	"github.com/m3dev/dsps/server/telemetry"
+)
+
+var onmemoryMetricsCtor = func(t *testing.T) func(telemetry *telemetry.Telemetry, onmemConfig config.OnmemoryStorageConfig) StorageCtor {
+	return func(telemetry *telemetry.Telemetry, onmemConfig config.OnmemoryStorageConfig) StorageCtor {
+		deps := EmptyDeps(t)
+		deps.Telemetry = telemetry
+		return func(ctx context.Context, systemClock domain.SystemClock, channelProvider domain.ChannelProvider) (domain.Storage, error) {
+			storage, err := onmemory.NewOnmemoryStorage(context.Background(), &onmemConfig, systemClock, channelProvider, deps)
+			if err != nil {
+				return nil, err
+			}
+			return NewMetricsStorage(storage, systemClock, channelProvider, deps), nil
+		}
+	}
+}
+
+func TestPubSubMetrics(t *testing.T) {
+	ctx := context.Background()
+	sl := domain.SubscriberLocator{ChannelID: "test-channel", SubscriberID: "sbsc-1"}
+	result := telemetry.WithStubMetrics(t, func(telemetry *telemetry.Telemetry) {
+		s, err := onmemoryMetricsCtor(t)(telemetry, config.OnmemoryStorageConfig{})(ctx, domain.RealSystemClock, StubChannelProvider)
+		assert.NoError(t, err)
+		defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+		pubsub := s.AsPubSubStorage()
+
+		assert.NoError(t, pubsub.NewSubscriber(ctx, sl, nil))
+		assert.NoError(t, pubsub.PublishMessages(ctx, []domain.Message{
+			{MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-1"}, Content: []byte(`{}`)},
+			{MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-2"}, Content: []byte(`{}`)},
+		}))
+		_, _, ackHandle, err := pubsub.FetchMessages(ctx, sl, 100, domain.Duration{})
+		assert.NoError(t, err)
+		assert.NoError(t, pubsub.AcknowledgeMessages(ctx, ackHandle))
+		_, _, _, err = pubsub.FetchMessages(ctx, sl, 100, domain.Duration{Duration: 1})
+		assert.NoError(t, err)
+
+		// Failed operations are not counted
+		assert.Error(t, pubsub.PublishMessages(ctx, []domain.Message{
+			{MessageLocator: domain.MessageLocator{ChannelID: DisabledChannelID, MessageID: "msg-1"}, Content: []byte(`{}`)},
+		}))
+	})
+	label := map[string]string{"channel": ".+"}
+	assert.Equal(t, 2.0, result.Value("dsps_published_messages_total", label))
+	assert.Equal(t, 1.0, result.Value("dsps_fetch_duration_seconds", label))
+	assert.Equal(t, 1.0, result.Value("dsps_long_polling_wait_seconds", label))
+	assert.Equal(t, 1.0, result.Value("dsps_acknowledgements_total", label))
+}
+
+func TestCoreFunction(t *testing.T) {
+	CoreFunctionTest(t, onmemoryMetricsCtor(t)(telemetry.NewEmptyTelemetry(t), config.OnmemoryStorageConfig{
+		DisableJwt:    true,
+		DisablePubSub: true,
+	}))
+}
+
+func TestPubSub(t *testing.T) {
+	telemetry.WithStubMetrics(t, func(telemetry *telemetry.Telemetry) {
+		PubSubTest(t, onmemoryMetricsCtor(t)(telemetry, config.OnmemoryStorageConfig{
+			DisableJwt: true,
+		}))
+	})
+}
+
+func TestJwt(t *testing.T) {
+	JwtTest(t, onmemoryMetricsCtor(t)(telemetry.NewEmptyTelemetry(t), config.OnmemoryStorageConfig{
+		DisablePubSub: true,
+	}))
+}
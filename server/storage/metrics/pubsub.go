@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (ms *metricsStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter) error {
+	return ms.pubsub.NewSubscriber(ctx, sl, filter)
+}
+
+func (ms *metricsStorage) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	return ms.pubsub.NewQueueSubscriber(ctx, sl, filter, visibilityTimeout)
+}
+
+func (ms *metricsStorage) RemoveSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
+	return ms.pubsub.RemoveSubscriber(ctx, sl)
+}
+
+func (ms *metricsStorage) PublishMessages(ctx context.Context, msgs []domain.Message) error {
+	if err := ms.pubsub.PublishMessages(ctx, msgs); err != nil {
+		return err
+	}
+
+	counts := make(map[domain.ChannelID]int, 1)
+	for _, msg := range msgs {
+		counts[msg.ChannelID]++
+	}
+	for channelID, count := range counts {
+		ms.t.CountPublishedMessages(ms.channelLabel(channelID), count)
+	}
+	return nil
+}
+
+func (ms *metricsStorage) FetchMessages(ctx context.Context, sl domain.SubscriberLocator, max int, waituntil domain.Duration) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, err error) {
+	startAt := ms.clock.Now()
+	messages, moreMessages, ackHandle, err = ms.pubsub.FetchMessages(ctx, sl, max, waituntil)
+	if err == nil {
+		ms.t.ObserveFetch(ms.channelLabel(sl.ChannelID), waituntil.Duration, ms.clock.Now().Sub(startAt.Time))
+	}
+	return
+}
+
+func (ms *metricsStorage) AcknowledgeMessages(ctx context.Context, handle domain.AckHandle) error {
+	if err := ms.pubsub.AcknowledgeMessages(ctx, handle); err != nil {
+		return err
+	}
+	ms.t.CountAcknowledgement(ms.channelLabel(handle.ChannelID))
+	return nil
+}
+
+func (ms *metricsStorage) IsOldMessages(ctx context.Context, sl domain.SubscriberLocator, msgs []domain.MessageLocator) (map[domain.MessageLocator]bool, error) {
+	return ms.pubsub.IsOldMessages(ctx, sl, msgs)
+}
+
+func (ms *metricsStorage) InspectChannels(ctx context.Context, channelID domain.ChannelID) ([]domain.ChannelInspection, error) {
+	return ms.pubsub.InspectChannels(ctx, channelID)
+}
+
+func (ms *metricsStorage) RewindSubscriber(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) error {
+	return ms.pubsub.RewindSubscriber(ctx, sl, target)
+}
+
+func (ms *metricsStorage) ExportChannel(ctx context.Context, channelID domain.ChannelID) (domain.ChannelExport, error) {
+	return ms.pubsub.ExportChannel(ctx, channelID)
+}
+
+func (ms *metricsStorage) ExportChannels(ctx context.Context, fn func(domain.ChannelExport) error) error {
+	return ms.pubsub.ExportChannels(ctx, fn)
+}
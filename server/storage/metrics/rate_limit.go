@@ -0,0 +1,11 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (ms *metricsStorage) IncrementRateLimitCounter(ctx context.Context, key string, window domain.Duration) (int64, domain.Duration, error) {
+	return ms.rateLimit.IncrementRateLimitCounter(ctx, key, window)
+}
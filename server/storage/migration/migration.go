@@ -0,0 +1,108 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+// Max count of messages in a PublishMessages call on import
+const importBatchSize = 100
+
+// Result is a summary of the migration
+type Result struct {
+	Channels        int
+	SkippedChannels int // Channels no longer permitted in the configuration
+	Subscribers     int
+	Messages        int
+	RevokedJwts     int
+}
+
+// MigrateStorage copies revoked JWTs and all channels (subscribers with their cursors and retained messages) from src to dst.
+// Running it again is safe because messages are deduplicated by message ID, but backlogs of the subscribers are redelivered.
+func MigrateStorage(ctx context.Context, src domain.Storage, dst domain.Storage) (Result, error) {
+	result := Result{}
+
+	if srcJwt := src.AsJwtStorage(); srcJwt != nil {
+		dstJwt := dst.AsJwtStorage()
+		if dstJwt == nil {
+			return result, errors.New("Destination storage does not support JWT (disableJwt)")
+		}
+		revoked, err := srcJwt.ExportRevokedJwts(ctx)
+		if err != nil {
+			return result, fmt.Errorf("Failed to export revoked JWTs: %w", err)
+		}
+		for _, r := range revoked {
+			if err := dstJwt.RevokeJwt(ctx, r.Exp, r.Jti); err != nil {
+				return result, fmt.Errorf("Failed to import revoked JWT (jti: %s): %w", r.Jti, err)
+			}
+			result.RevokedJwts++
+		}
+	}
+
+	if srcPubSub := src.AsPubSubStorage(); srcPubSub != nil {
+		dstPubSub := dst.AsPubSubStorage()
+		if dstPubSub == nil {
+			return result, errors.New("Destination storage does not support PubSub (disablePubSub)")
+		}
+		err := srcPubSub.ExportChannels(ctx, func(export domain.ChannelExport) error {
+			err := ImportChannel(ctx, dstPubSub, export)
+			if errors.Is(err, domain.ErrInvalidChannel) {
+				logger.Of(ctx).Warnf(logger.CatStorage, "Skipped channel %s because it is not permitted in the configuration", export.ChannelID)
+				result.SkippedChannels++
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("Failed to import channel %s: %w", export.ChannelID, err)
+			}
+			logger.Of(ctx).Debugf(logger.CatStorage, "Migrated channel %s (%d subscribers, %d messages)", export.ChannelID, len(export.Subscribers), len(export.Messages))
+			result.Channels++
+			result.Subscribers += len(export.Subscribers)
+			result.Messages += len(export.Messages)
+			return nil
+		})
+		if err != nil {
+			return result, fmt.Errorf("Failed to migrate channels: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// ImportChannel writes exported channel into the storage.
+// Subscribers are created after messages then rewound, so that each subscriber receives only its backlog.
+// Leases of queue subscribers are not imported, leased messages are redelivered from the destination.
+func ImportChannel(ctx context.Context, dst domain.PubSubStorage, export domain.ChannelExport) error {
+	for i := 0; i < len(export.Messages); i += importBatchSize {
+		end := i + importBatchSize
+		if end > len(export.Messages) {
+			end = len(export.Messages)
+		}
+		if err := dst.PublishMessages(ctx, export.Messages[i:end]); err != nil {
+			return err
+		}
+	}
+
+	for _, sbsc := range export.Subscribers {
+		sl := domain.SubscriberLocator{ChannelID: export.ChannelID, SubscriberID: sbsc.SubscriberID}
+		var err error
+		if sbsc.VisibilityTimeout != nil {
+			err = dst.NewQueueSubscriber(ctx, sl, sbsc.Filter, *sbsc.VisibilityTimeout)
+		} else {
+			err = dst.NewSubscriber(ctx, sl, sbsc.Filter)
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to create subscriber %s: %w", sbsc.SubscriberID, err)
+		}
+		backlog := sbsc.Backlog(export)
+		if len(backlog) == 0 {
+			continue
+		}
+		if err := dst.RewindSubscriber(ctx, sl, domain.SubscriberRewindTarget{MessageID: &backlog[0].MessageID}); err != nil {
+			return fmt.Errorf("Failed to restore cursor of subscriber %s: %w", sbsc.SubscriberID, err)
+		}
+	}
+	return nil
+}
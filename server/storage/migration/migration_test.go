@@ -0,0 +1,156 @@
+package migration_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/storage/bolt"
+	. "github.com/m3dev/dsps/server/storage/deps/testing"
+	. "github.com/m3dev/dsps/server/storage/migration"
+	"github.com/m3dev/dsps/server/storage/onmemory"
+	. "github.com/m3dev/dsps/server/storage/testing"
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+var removedChannelID domain.ChannelID = "ch-removed"
+
+// Configuration of the destination server no longer permits removedChannelID
+var destinationChannelProvider domain.ChannelProvider = dspstesting.ChannelProviderFunc(func(id domain.ChannelID) (domain.Channel, error) {
+	if id == removedChannelID {
+		return nil, domain.ErrInvalidChannel
+	}
+	return StubChannelProvider.Get(id)
+})
+
+func newMessage(ch domain.ChannelID, id domain.MessageID, eventType string) domain.Message {
+	return domain.Message{
+		MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: id},
+		Content:        json.RawMessage(`{}`),
+		Attributes:     domain.MessageAttributes{"event-type": eventType},
+	}
+}
+
+func TestMigrateStorage(t *testing.T) {
+	ctx := context.Background()
+	src, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, src.Shutdown(ctx)) }()
+	gcInterval := dspstesting.MakeDuration("5m")
+	openTimeout := dspstesting.MakeDuration("100ms")
+	dst, err := bolt.NewBoltStorage(ctx, &config.BoltStorageConfig{
+		Path:        filepath.Join(t.TempDir(), "dsps.db"),
+		GCInterval:  &gcInterval,
+		OpenTimeout: &openTimeout,
+	}, domain.RealSystemClock, destinationChannelProvider, EmptyDeps(t))
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, dst.Shutdown(ctx)) }()
+
+	// Prepare source
+	ch := domain.ChannelID("ch-1")
+	sl1 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+	sl2 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-2"}
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+	assert.NoError(t, src.AsPubSubStorage().NewSubscriber(ctx, sl1, nil))
+	assert.NoError(t, src.AsPubSubStorage().NewSubscriber(ctx, sl2, filter))
+	msgs := []domain.Message{
+		newMessage(ch, "msg-1", "created"),
+		newMessage(ch, "msg-2", "deleted"),
+		newMessage(ch, "msg-3", "created"),
+	}
+	assert.NoError(t, src.AsPubSubStorage().PublishMessages(ctx, msgs))
+	fetched, _, ackHandle, err := src.AsPubSubStorage().FetchMessages(ctx, sl1, 1, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, msgs[:1], fetched)
+	assert.NoError(t, src.AsPubSubStorage().AcknowledgeMessages(ctx, ackHandle))
+
+	removedSl := domain.SubscriberLocator{ChannelID: removedChannelID, SubscriberID: "sbsc-1"}
+	assert.NoError(t, src.AsPubSubStorage().NewSubscriber(ctx, removedSl, nil))
+
+	exp := domain.JwtExp(time.Now().Add(time.Hour))
+	assert.NoError(t, src.AsJwtStorage().RevokeJwt(ctx, exp, "jti-1"))
+
+	result, err := MigrateStorage(ctx, src, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{
+		Channels:        1,
+		SkippedChannels: 1,
+		Subscribers:     2,
+		Messages:        3,
+		RevokedJwts:     1,
+	}, result)
+
+	// Subscribers receive only their backlog from the destination
+	fetched, _, _, err = dst.AsPubSubStorage().FetchMessages(ctx, sl1, 10, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, msgs[1:], fetched)
+	fetched, _, _, err = dst.AsPubSubStorage().FetchMessages(ctx, sl2, 10, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Message{msgs[0], msgs[2]}, fetched)
+
+	revoked, err := dst.AsJwtStorage().IsRevokedJwt(ctx, "jti-1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Migrate again, messages are not duplicated
+	_, err = MigrateStorage(ctx, src, dst)
+	assert.NoError(t, err)
+	export, err := dst.AsPubSubStorage().ExportChannel(ctx, ch)
+	assert.NoError(t, err)
+	assert.Equal(t, msgs, export.Messages)
+}
+
+func TestMigrateStorageUnsupportedDestination(t *testing.T) {
+	ctx := context.Background()
+	src, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	assert.NoError(t, err)
+	dst, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{DisablePubSub: true}, domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+	assert.NoError(t, err)
+
+	_, err = MigrateStorage(ctx, src, dst)
+	assert.EqualError(t, err, "Destination storage does not support PubSub (disablePubSub)")
+}
+
+func TestImportQueueSubscriber(t *testing.T) {
+	ctx := context.Background()
+	newStorage := func() domain.PubSubStorage {
+		s, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, domain.RealSystemClock, StubChannelProvider, EmptyDeps(t))
+		assert.NoError(t, err)
+		t.Cleanup(func() { assert.NoError(t, s.Shutdown(ctx)) })
+		return s.AsPubSubStorage()
+	}
+	src, dst := newStorage(), newStorage()
+
+	ch := domain.ChannelID("ch-1")
+	sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+	vt := dspstesting.MakeDuration("30s")
+	assert.NoError(t, src.NewQueueSubscriber(ctx, sl, nil, vt))
+	msgs := []domain.Message{
+		newMessage(ch, "msg-1", "created"),
+		newMessage(ch, "msg-2", "created"),
+	}
+	assert.NoError(t, src.PublishMessages(ctx, msgs))
+	fetched, _, _, err := src.FetchMessages(ctx, sl, 1, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, msgs[:1], fetched) // Leased, not acknowledged
+
+	export, err := src.ExportChannel(ctx, ch)
+	assert.NoError(t, err)
+	assert.NoError(t, ImportChannel(ctx, dst, export))
+
+	inspections, err := dst.InspectChannels(ctx, ch)
+	if assert.NoError(t, err) && assert.Len(t, inspections, 1) && assert.Len(t, inspections[0].Subscribers, 1) {
+		assert.Equal(t, &vt, inspections[0].Subscribers[0].VisibilityTimeout)
+	}
+	// Lease is not imported
+	fetched, _, _, err = dst.FetchMessages(ctx, sl, 10, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, msgs, fetched)
+}
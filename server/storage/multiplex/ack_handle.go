@@ -8,7 +8,9 @@ import (
 	"github.com/m3dev/dsps/server/domain"
 )
 
-// EncodeMultiplexAckHandle encapsle AckHandle of multiplex storage
+// EncodeMultiplexAckHandle encapsle AckHandle of multiplex storage.
+// Handle contains AckHandles of storages that returned messages, keyed by StorageID,
+// thus it also works in sharding mode that fetches messages from only owner (and previous owner) of the channel.
 func encodeMultiplexAckHandle(handles map[domain.StorageID]domain.AckHandle) (domain.AckHandle, error) {
 	var sl domain.SubscriberLocator
 	raw := map[domain.StorageID]string{}
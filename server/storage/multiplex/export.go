@@ -0,0 +1,77 @@
+package multiplex
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// ExportChannel returns export of one of the storages, because clock based cursors cannot be merged across storages.
+// Prefers the owner of the channel in sharding mode, otherwise prefers the storage in StorageID order.
+func (s *storageMultiplexer) ExportChannel(ctx context.Context, channelID domain.ChannelID) (domain.ChannelExport, error) {
+	results, err := s.parallelOnChannel(ctx, channelID, true, "ExportChannel", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+		if child := child.AsPubSubStorage(); child != nil {
+			return child.ExportChannel(ctx, channelID)
+		}
+		return nil, errMultiplexSkipped
+	})
+	if err != nil {
+		return domain.ChannelExport{}, err
+	}
+
+	storageIDs := make([]domain.StorageID, 0, len(results))
+	for id := range results {
+		storageIDs = append(storageIDs, id)
+	}
+	sort.Slice(storageIDs, func(i, j int) bool {
+		if owner := s.isChannelOwner(storageIDs[i], channelID); owner != s.isChannelOwner(storageIDs[j], channelID) {
+			return owner
+		}
+		return storageIDs[i] < storageIDs[j]
+	})
+	for _, id := range storageIDs {
+		if export := results[id].(domain.ChannelExport); len(export.Messages) > 0 || len(export.Subscribers) > 0 {
+			return export, nil
+		}
+	}
+	return domain.ChannelExport{
+		ChannelID:   channelID,
+		Messages:    []domain.Message{},
+		Subscribers: []domain.SubscriberExport{},
+	}, nil
+}
+
+// ExportChannels exports channels one by one with InspectChannels and ExportChannel, to choose preferred storage for each channel.
+func (s *storageMultiplexer) ExportChannels(ctx context.Context, fn func(domain.ChannelExport) error) error {
+	return domain.ExportChannelsOneByOne(ctx, s, fn)
+}
+
+// ExportRevokedJwts merges results of the storages.
+func (s *storageMultiplexer) ExportRevokedJwts(ctx context.Context) ([]domain.RevokedJwt, error) {
+	results, err := s.parallelAtLeastOneSuccess(ctx, "ExportRevokedJwts", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+		if child := child.AsJwtStorage(); child != nil {
+			return child.ExportRevokedJwts(ctx)
+		}
+		return nil, errMultiplexSkipped
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := map[domain.JwtJti]domain.JwtExp{}
+	for _, result := range results {
+		for _, revoked := range result.([]domain.RevokedJwt) {
+			if exp, ok := merged[revoked.Jti]; !ok || time.Time(exp).Before(time.Time(revoked.Exp)) {
+				merged[revoked.Jti] = revoked.Exp
+			}
+		}
+	}
+	list := make([]domain.RevokedJwt, 0, len(merged))
+	for jti, exp := range merged {
+		list = append(list, domain.RevokedJwt{Jti: jti, Exp: exp})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Jti < list[j].Jti })
+	return list, nil
+}
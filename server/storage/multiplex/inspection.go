@@ -0,0 +1,78 @@
+package multiplex
+
+import (
+	"context"
+	"sort"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// InspectChannels merges results of the storages.
+// Note that clocks are storage specific, thus merged result contains clocks of any one of storages (the owner of the channel in sharding mode).
+// Backlog and TTL of the merged result is the largest one of the storages.
+func (s *storageMultiplexer) InspectChannels(ctx context.Context, channelID domain.ChannelID) ([]domain.ChannelInspection, error) {
+	results, err := s.parallelAtLeastOneSuccess(ctx, "InspectChannels", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+		if child := child.AsPubSubStorage(); child != nil {
+			return child.InspectChannels(ctx, channelID)
+		}
+		return nil, errMultiplexSkipped
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	storageIDs := make([]domain.StorageID, 0, len(results))
+	for id := range results {
+		storageIDs = append(storageIDs, id)
+	}
+	sort.Slice(storageIDs, func(i, j int) bool { return storageIDs[i] < storageIDs[j] })
+
+	channels := map[domain.ChannelID]*domain.ChannelInspection{}
+	subscribers := map[domain.ChannelID]map[domain.SubscriberID]*domain.SubscriberInspection{}
+	for _, storageID := range storageIDs {
+		for _, ch := range results[storageID].([]domain.ChannelInspection) {
+			merged := channels[ch.ChannelID]
+			if merged == nil {
+				merged = &domain.ChannelInspection{ChannelID: ch.ChannelID, Clock: ch.Clock, TTL: ch.TTL}
+				channels[ch.ChannelID] = merged
+				subscribers[ch.ChannelID] = map[domain.SubscriberID]*domain.SubscriberInspection{}
+			} else {
+				merged.TTL = maxTTL(merged.TTL, ch.TTL)
+				if s.isChannelOwner(storageID, ch.ChannelID) {
+					merged.Clock = ch.Clock
+				}
+			}
+
+			for _, sbsc := range ch.Subscribers {
+				sbsc := sbsc
+				if existing := subscribers[ch.ChannelID][sbsc.SubscriberID]; existing != nil {
+					if existing.Backlog < sbsc.Backlog {
+						existing.Backlog = sbsc.Backlog
+					}
+					existing.TTL = maxTTL(existing.TTL, sbsc.TTL)
+					continue
+				}
+				subscribers[ch.ChannelID][sbsc.SubscriberID] = &sbsc
+			}
+		}
+	}
+
+	result := make([]domain.ChannelInspection, 0, len(channels))
+	for id, ch := range channels {
+		ch.Subscribers = make([]domain.SubscriberInspection, 0, len(subscribers[id]))
+		for _, sbsc := range subscribers[id] {
+			ch.Subscribers = append(ch.Subscribers, *sbsc)
+		}
+		sort.Slice(ch.Subscribers, func(i, j int) bool { return ch.Subscribers[i].SubscriberID < ch.Subscribers[j].SubscriberID })
+		result = append(result, *ch)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ChannelID < result[j].ChannelID })
+	return result, nil
+}
+
+func maxTTL(a *domain.Duration, b *domain.Duration) *domain.Duration {
+	if a == nil || (b != nil && a.Duration < b.Duration) {
+		return b
+	}
+	return a
+}
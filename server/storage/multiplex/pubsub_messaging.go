@@ -14,7 +14,11 @@ import (
 const parallelFetchEarlyReturnWindow = 300 * time.Millisecond
 
 func (s *storageMultiplexer) PublishMessages(ctx context.Context, msgs []domain.Message) error {
-	_, err := s.parallelAtLeastOneSuccess(ctx, "PublishMessages", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+	var channelID domain.ChannelID
+	if len(msgs) > 0 {
+		channelID = msgs[0].ChannelID
+	}
+	_, err := s.parallelOnChannel(ctx, channelID, false, "PublishMessages", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
 		if child := child.AsPubSubStorage(); child != nil {
 			return nil, child.PublishMessages(ctx, msgs)
 		}
@@ -32,7 +36,7 @@ func (s *storageMultiplexer) FetchMessages(ctx context.Context, sl domain.Subscr
 	parallelCtx, parallelCtxCancel := context.WithCancel(ctx)
 	defer parallelCtxCancel()
 	subscriptionMissingCh := make(chan domain.StorageID, len(s.children))
-	results, err := s.parallelAtLeastOneSuccess(parallelCtx, "FetchMessages", func(ctx context.Context, storageID domain.StorageID, child domain.Storage) (interface{}, error) {
+	results, err := s.parallelOnChannel(parallelCtx, sl.ChannelID, true, "FetchMessages", func(ctx context.Context, storageID domain.StorageID, child domain.Storage) (interface{}, error) {
 		if child := child.AsPubSubStorage(); child != nil {
 			msgs, moreMsgs, ackHandle, err := child.FetchMessages(ctx, sl, max, waituntil)
 			if err != nil {
@@ -54,13 +58,29 @@ func (s *storageMultiplexer) FetchMessages(ctx context.Context, sl domain.Subscr
 		return nil, false, domain.AckHandle{}, err
 	}
 
+	owners := s.pubsubTargets(sl.ChannelID, false)
+	sources := make([]domain.StorageID, 0, len(results))
+	for id := range results {
+		sources = append(sources, id)
+	}
 	for id := range subscriptionMissingCh {
+		if _, ok := owners[id]; !ok {
+			continue // Previous owner of the channel in sharding mode, no need to receive future messages.
+		}
 		// Subscriber missing on this storage.
 		// This situation could occur if the storage had been temporary unavailable when subscriber created.
-		// So that automatically create subscriber to receive future messages.
+		// So that automatically create subscriber to receive future messages, with settings of the subscriber on storages that fetch succeeded.
 		logger.Of(ctx).Debugf(logger.CatStorage, `Auto-creating (recovering) subscriber %v on storage '%s' because fetch succeeded in the multiplexer but this storage reported the subscriber does not exist.`, sl, id)
-		if err := s.children[id].AsPubSubStorage().NewSubscriber(ctx, sl); err != nil {
+		if err := s.recoverSubscriber(ctx, sl, id, sources); err != nil {
 			logger.Of(ctx).WarnError(logger.CatStorage, fmt.Sprintf("Failed to auto-create (recover) subscriber %v on storage '%s': %%w", sl, id), err)
+			continue
+		}
+		if s.shard != nil {
+			// In sharding mode, the owner could be a storage added after subscriber creation.
+			// Messages published after rebalancing exist only on the owner, so that rewind to receive them.
+			if err := s.children[id].AsPubSubStorage().RewindSubscriber(ctx, sl, domain.SubscriberRewindTarget{Earliest: true}); err != nil {
+				logger.Of(ctx).WarnError(logger.CatStorage, fmt.Sprintf("Failed to rewind auto-created subscriber %v on storage '%s': %%w", sl, id), err)
+			}
 		}
 	}
 
@@ -115,12 +135,17 @@ func (s *storageMultiplexer) AcknowledgeMessages(ctx context.Context, handle dom
 	if err != nil {
 		return err
 	}
-	_, err = s.parallelAtLeastOneSuccess(ctx, "AcknowledgeMessages", func(ctx context.Context, id domain.StorageID, child domain.Storage) (interface{}, error) {
+	// Route to storages that returned messages rather than current owners, because sharding could be changed after fetch.
+	// Storages added after creation of the handle are not included.
+	targets := map[domain.StorageID]domain.Storage{}
+	for id := range h {
+		if child, ok := s.children[id]; ok {
+			targets[id] = child
+		}
+	}
+	_, err = parallelAtLeastOneSuccessOn(ctx, targets, "AcknowledgeMessages", func(ctx context.Context, id domain.StorageID, child domain.Storage) (interface{}, error) {
 		if child := child.AsPubSubStorage(); child != nil {
-			if handle, ok := h[id]; ok {
-				return nil, child.AcknowledgeMessages(ctx, handle)
-			}
-			return nil, errMultiplexSkipped // This storage added after creation of the handle
+			return nil, child.AcknowledgeMessages(ctx, h[id])
 		}
 		return nil, errMultiplexSkipped
 	})
@@ -134,9 +159,10 @@ func (s *storageMultiplexer) IsOldMessages(ctx context.Context, sl domain.Subscr
 		return map[domain.MessageLocator]bool{}, nil
 	}
 
-	ch := make(chan map[domain.MessageLocator]bool, len(s.children))
+	targets := s.pubsubTargets(sl.ChannelID, true)
+	ch := make(chan map[domain.MessageLocator]bool, len(targets))
 	wg := sync.WaitGroup{}
-	for id, child := range s.children {
+	for id, child := range targets {
 		id := id
 		if child := child.AsPubSubStorage(); child != nil {
 			wg.Add(1)
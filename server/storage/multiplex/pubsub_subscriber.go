@@ -2,14 +2,37 @@ package multiplex
 
 import (
 	"context"
+	"fmt"
+	"sort"
 
 	"github.com/m3dev/dsps/server/domain"
 )
 
-func (s *storageMultiplexer) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
-	_, err := s.parallelAtLeastOneSuccess(ctx, "NewSubscriber", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+func (s *storageMultiplexer) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter) error {
+	_, err := s.parallelOnChannel(ctx, sl.ChannelID, false, "NewSubscriber", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
 		if child := child.AsPubSubStorage(); child != nil {
-			return nil, child.NewSubscriber(ctx, sl)
+			return nil, child.NewSubscriber(ctx, sl, filter)
+		}
+		return nil, errMultiplexSkipped
+	})
+	return err
+}
+
+// NewQueueSubscriber requires exactly one storage to own the channel (sharding mode or single PubSub storage),
+// because leases taken independently on each storage cannot prevent duplicated deliveries to consumers.
+func (s *storageMultiplexer) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	owners := 0
+	for _, child := range s.pubsubTargets(sl.ChannelID, false) {
+		if child.AsPubSubStorage() != nil {
+			owners++
+		}
+	}
+	if owners > 1 {
+		return fmt.Errorf("Multiplexer in replicate mode (%d storages own the channel): %w", owners, domain.ErrQueueSubscriberUnsupported)
+	}
+	_, err := s.parallelOnChannel(ctx, sl.ChannelID, false, "NewQueueSubscriber", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+		if child := child.AsPubSubStorage(); child != nil {
+			return nil, child.NewQueueSubscriber(ctx, sl, filter, visibilityTimeout)
 		}
 		return nil, errMultiplexSkipped
 	})
@@ -17,7 +40,7 @@ func (s *storageMultiplexer) NewSubscriber(ctx context.Context, sl domain.Subscr
 }
 
 func (s *storageMultiplexer) RemoveSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
-	_, err := s.parallelAtLeastOneSuccess(ctx, "RemoveSubscriber", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+	_, err := s.parallelOnChannel(ctx, sl.ChannelID, true, "RemoveSubscriber", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
 		if child := child.AsPubSubStorage(); child != nil {
 			return nil, child.RemoveSubscriber(ctx, sl)
 		}
@@ -25,3 +48,43 @@ func (s *storageMultiplexer) RemoveSubscriber(ctx context.Context, sl domain.Sub
 	})
 	return err
 }
+
+// Note that clock is storage specific, thus rewinding by clock is meaningful only if all storages have same clock.
+func (s *storageMultiplexer) RewindSubscriber(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) error {
+	_, err := s.parallelOnChannel(ctx, sl.ChannelID, true, "RewindSubscriber", func(ctx context.Context, _ domain.StorageID, child domain.Storage) (interface{}, error) {
+		if child := child.AsPubSubStorage(); child != nil {
+			return nil, child.RewindSubscriber(ctx, sl, target)
+		}
+		return nil, errMultiplexSkipped
+	})
+	return err
+}
+
+// recoverSubscriber creates the subscriber missing on the storage with the same settings (queue mode, filter and visibility timeout)
+// as the one found on other storages, so that recovered subscriber behaves same as the original one.
+func (s *storageMultiplexer) recoverSubscriber(ctx context.Context, sl domain.SubscriberLocator, id domain.StorageID, sources []domain.StorageID) error {
+	sort.Slice(sources, func(i, j int) bool { return sources[i] < sources[j] })
+	var lastErr error
+	for _, sourceID := range sources {
+		inspections, err := s.children[sourceID].AsPubSubStorage().InspectChannels(ctx, sl.ChannelID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, ch := range inspections {
+			for _, sbsc := range ch.Subscribers {
+				if ch.ChannelID != sl.ChannelID || sbsc.SubscriberID != sl.SubscriberID {
+					continue
+				}
+				if sbsc.VisibilityTimeout != nil {
+					return s.children[id].AsPubSubStorage().NewQueueSubscriber(ctx, sl, sbsc.Filter, *sbsc.VisibilityTimeout)
+				}
+				return s.children[id].AsPubSubStorage().NewSubscriber(ctx, sl, sbsc.Filter)
+			}
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("Unable to find settings of the subscriber on other storages: %w", lastErr)
+	}
+	return fmt.Errorf("Unable to find settings of the subscriber on other storages: %w", domain.ErrSubscriptionNotFound)
+}
@@ -0,0 +1,74 @@
+package multiplex
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// Virtual nodes per storage, to distribute channels evenly
+const shardRingReplicas = 160
+
+// shardRing is consistent hashing ring to route channels to storages.
+// Adding a storage moves only channels routed to the new storage, removing a storage moves only channels routed to the removed storage.
+type shardRing struct {
+	points  []uint64
+	storage map[uint64]domain.StorageID
+}
+
+func newShardRing(ids []domain.StorageID) *shardRing {
+	r := &shardRing{
+		points:  make([]uint64, 0, len(ids)*shardRingReplicas),
+		storage: make(map[uint64]domain.StorageID, len(ids)*shardRingReplicas),
+	}
+	sorted := append([]domain.StorageID{}, ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] }) // Resolve hash collision deterministically
+	for _, id := range sorted {
+		for i := 0; i < shardRingReplicas; i++ {
+			point := shardRingHash(fmt.Sprintf("%s#%d", id, i))
+			if _, exists := r.storage[point]; exists {
+				continue
+			}
+			r.storage[point] = id
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+func shardRingHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key)) //nolint:errcheck,gosec // Never fails
+	// FNV alone does not spread similar short keys (e.g. "chat-1", "chat-2") well, apply finalizer of MurmurHash3
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// lookup returns up to n distinct storages in order of preference for the channel, first one is the owner.
+func (r *shardRing) lookup(channelID domain.ChannelID, n int) []domain.StorageID {
+	result := make([]domain.StorageID, 0, n)
+	if len(r.points) == 0 {
+		return result
+	}
+	hash := shardRingHash(string(channelID))
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	for i := 0; i < len(r.points) && len(result) < n; i++ {
+		id := r.storage[r.points[(start+i)%len(r.points)]]
+		found := false
+		for _, existing := range result {
+			found = found || existing == id
+		}
+		if !found {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package multiplex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func TestShardRingDistribution(t *testing.T) {
+	ring := newShardRing([]domain.StorageID{"s1", "s2", "s3"})
+	counts := map[domain.StorageID]int{}
+	for i := 0; i < 3000; i++ {
+		owners := ring.lookup(domain.ChannelID(fmt.Sprintf("ch-%d", i)), 2)
+		if assert.Len(t, owners, 2) {
+			assert.NotEqual(t, owners[0], owners[1])
+			counts[owners[0]]++
+		}
+	}
+	for _, id := range []domain.StorageID{"s1", "s2", "s3"} {
+		assert.InDelta(t, 1000, counts[id], 250, id)
+	}
+
+	// Deterministic
+	assert.Equal(t, ring.lookup("ch-1", 3), newShardRing([]domain.StorageID{"s3", "s1", "s2"}).lookup("ch-1", 3))
+
+	assert.Equal(t, []domain.StorageID{"s1"}, newShardRing([]domain.StorageID{"s1"}).lookup("ch-1", 2))
+	assert.Equal(t, []domain.StorageID{}, newShardRing([]domain.StorageID{}).lookup("ch-1", 2))
+}
+
+func TestShardRingRebalance(t *testing.T) {
+	before := newShardRing([]domain.StorageID{"s1", "s2"})
+	after := newShardRing([]domain.StorageID{"s1", "s2", "s3"})
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		ch := domain.ChannelID(fmt.Sprintf("ch-%d", i))
+		oldOwner := before.lookup(ch, 1)[0]
+		newOwners := after.lookup(ch, 2)
+		if newOwners[0] == oldOwner {
+			continue
+		}
+		// Channels move only to the added storage, previous owner is the next one.
+		moved++
+		assert.Equal(t, domain.StorageID("s3"), newOwners[0])
+		assert.Equal(t, oldOwner, newOwners[1])
+	}
+	assert.InDelta(t, 1000, moved, 250)
+}
+
+func TestShardingReadTargets(t *testing.T) {
+	// s3 added, then s1 removed
+	s := &storageMultiplexer{
+		children: map[domain.StorageID]domain.Storage{"s1": nil, "s2": nil, "s3": nil},
+		shard:    newShardRing([]domain.StorageID{"s2", "s3"}),
+		previousShards: []*shardRing{
+			newShardRing([]domain.StorageID{"s1", "s2", "s3"}),
+			newShardRing([]domain.StorageID{"s1", "s2"}),
+		},
+	}
+	stayed := 0
+	for i := 0; i < 3000; i++ {
+		ch := domain.ChannelID(fmt.Sprintf("ch-%d", i))
+		expected := map[domain.StorageID]bool{}
+		for _, ring := range append([]*shardRing{s.shard}, s.previousShards...) {
+			expected[ring.lookup(ch, 1)[0]] = true
+		}
+		owner := s.shard.lookup(ch, 1)[0]
+		assert.Len(t, s.pubsubTargets(ch, false), 1)
+		assert.Contains(t, s.pubsubTargets(ch, false), owner)
+
+		// Read from previous owners only if the channel has moved
+		targets := s.pubsubTargets(ch, true)
+		assert.Len(t, targets, len(expected))
+		for id := range expected {
+			assert.Contains(t, targets, id)
+		}
+		if len(targets) == 1 {
+			stayed++
+		}
+	}
+	assert.InDelta(t, 1000, stayed, 250) // Channels owned by s2 on all rings
+}
@@ -2,7 +2,9 @@ package multiplex
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"golang.org/x/sync/errgroup"
@@ -10,36 +12,151 @@ import (
 	"github.com/m3dev/dsps/server/domain"
 )
 
-// NewStorageMultiplexer creates Storage instance that wraps multiple Storage instances
+// NewStorageMultiplexer creates Storage instance that wraps multiple Storage instances, writes to all of them for redundancy
 func NewStorageMultiplexer(children map[domain.StorageID]domain.Storage) (domain.Storage, error) {
+	return newStorageMultiplexer(children, false, nil, nil)
+}
+
+// NewShardingStorageMultiplexer creates Storage instance that wraps multiple Storage instances, routes each channel to one of them.
+// shards is the list of storages to route channels, nil means all storages.
+// previousShards is the list of shards before changes (e.g. storage addition or removal), the multiplexer also reads channels moved by the changes from their previous owners.
+// Note that JWT revocations are not sharded, written to all storages.
+func NewShardingStorageMultiplexer(children map[domain.StorageID]domain.Storage, shards []domain.StorageID, previousShards [][]domain.StorageID) (domain.Storage, error) {
+	return newStorageMultiplexer(children, true, shards, previousShards)
+}
+
+func newStorageMultiplexer(children map[domain.StorageID]domain.Storage, sharding bool, shards []domain.StorageID, previousShards [][]domain.StorageID) (domain.Storage, error) {
 	if len(children) == 0 {
 		return nil, fmt.Errorf("List of storages must not be empty")
 	}
 
-	pubsubSupported := false
+	pubsubStorageIDs := []domain.StorageID{}
 	jwtSupported := false
-	for _, c := range children {
+	for id, c := range children {
 		if pubsub := c.AsPubSubStorage(); pubsub != nil {
-			pubsubSupported = true
+			pubsubStorageIDs = append(pubsubStorageIDs, id)
 		}
 		if jwt := c.AsJwtStorage(); jwt != nil {
 			jwtSupported = true
 		}
 	}
 
+	var shard *shardRing
+	var previousShardRings []*shardRing
+	if sharding {
+		if shards == nil {
+			shards = pubsubStorageIDs
+		}
+		ring, err := newShardRingOf(children, shards)
+		if err != nil {
+			return nil, err
+		}
+		shard = ring
+		for _, ids := range previousShards {
+			ring, err := newShardRingOf(children, ids)
+			if err != nil {
+				return nil, err
+			}
+			previousShardRings = append(previousShardRings, ring)
+		}
+	}
 	return &storageMultiplexer{
-		children: children,
+		children:       children,
+		shard:          shard,
+		previousShards: previousShardRings,
 
-		pubsubSupported: pubsubSupported,
+		pubsubSupported: len(pubsubStorageIDs) > 0,
 		jwtSupported:    jwtSupported,
+
+		rateLimit: selectRateLimitStorage(children),
 	}, nil
 }
 
 type storageMultiplexer struct {
-	children map[domain.StorageID]domain.Storage
+	children       map[domain.StorageID]domain.Storage
+	shard          *shardRing   // nil unless sharding mode
+	previousShards []*shardRing // Rings before changes in sharding mode
 
 	pubsubSupported bool
 	jwtSupported    bool
+
+	rateLimit domain.RateLimitStorage
+}
+
+// pubsubTargets returns storages to run PubSub operation of the channel.
+// In sharding mode, returns the owner of the channel.
+// For read operations, also returns owners of the channel on previous rings if the channel has moved,
+// so that subscribers continue to receive messages stored before storages have been added or removed.
+func (s *storageMultiplexer) pubsubTargets(channelID domain.ChannelID, read bool) map[domain.StorageID]domain.Storage {
+	if s.shard == nil {
+		return s.children
+	}
+	targets := map[domain.StorageID]domain.Storage{}
+	for _, id := range s.shard.lookup(channelID, 1) {
+		targets[id] = s.children[id]
+	}
+	if read {
+		for _, ring := range s.previousShards {
+			for _, id := range ring.lookup(channelID, 1) {
+				targets[id] = s.children[id]
+			}
+		}
+	}
+	return targets
+}
+
+// newShardRingOf creates shardRing of given storages, storages that do not support PubSub are excluded.
+func newShardRingOf(children map[domain.StorageID]domain.Storage, ids []domain.StorageID) (*shardRing, error) {
+	pubsubStorageIDs := make([]domain.StorageID, 0, len(ids))
+	for _, id := range ids {
+		child, ok := children[id]
+		if !ok {
+			return nil, fmt.Errorf("Storage \"%s\" of shards not found", id)
+		}
+		if child.AsPubSubStorage() != nil {
+			pubsubStorageIDs = append(pubsubStorageIDs, id)
+		}
+	}
+	return newShardRing(pubsubStorageIDs), nil
+}
+
+// isChannelOwner returns true if the storage is the owner of the channel in sharding mode, always false in replicate mode.
+func (s *storageMultiplexer) isChannelOwner(id domain.StorageID, channelID domain.ChannelID) bool {
+	if s.shard == nil {
+		return false
+	}
+	owners := s.shard.lookup(channelID, 1)
+	return len(owners) > 0 && owners[0] == id
+}
+
+// parallelOnChannel runs PubSub operation of the channel on pubsubTargets, see parallelAtLeastOneSuccess.
+// Previous owner of the channel in sharding mode does not know subscribers created after rebalancing,
+// thus ErrSubscriptionNotFound of the previous owner is ignored so that the error of the owner takes precedence.
+func (s *storageMultiplexer) parallelOnChannel(ctx context.Context, channelID domain.ChannelID, read bool, operationName string, f func(ctx context.Context, id domain.StorageID, s domain.Storage) (interface{}, error)) (map[domain.StorageID]interface{}, error) {
+	owners := s.pubsubTargets(channelID, false)
+	return parallelAtLeastOneSuccessOn(ctx, s.pubsubTargets(channelID, read), operationName, func(ctx context.Context, id domain.StorageID, child domain.Storage) (interface{}, error) {
+		result, err := f(ctx, id, child)
+		if _, owner := owners[id]; !owner && errors.Is(err, domain.ErrSubscriptionNotFound) {
+			return nil, errMultiplexSkipped
+		}
+		return result, err
+	})
+}
+
+// selectRateLimitStorage returns only one child (first one in StorageID order) to store rate limit counters,
+// because counting in multiple storages multiplies request count.
+func selectRateLimitStorage(children map[domain.StorageID]domain.Storage) domain.RateLimitStorage {
+	ids := make([]string, 0, len(children))
+	for id := range children {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if rateLimit := children[domain.StorageID(id)].AsRateLimitStorage(); rateLimit != nil {
+			return rateLimit
+		}
+	}
+	return nil
 }
 
 func (s *storageMultiplexer) AsPubSubStorage() domain.PubSubStorage {
@@ -55,6 +172,10 @@ func (s *storageMultiplexer) AsJwtStorage() domain.JwtStorage {
 	return s
 }
 
+func (s *storageMultiplexer) AsRateLimitStorage() domain.RateLimitStorage {
+	return s.rateLimit
+}
+
 func (s *storageMultiplexer) String() string {
 	return storageMapToString(s.children)
 }
@@ -14,6 +14,16 @@ import (
 )
 
 var onmemoryMultiplexCtor = func(t *testing.T, onmemConfigs ...config.OnmemoryStorageConfig) StorageCtor {
+	return onmemoryMultiplexCtorOf(t, NewStorageMultiplexer, onmemConfigs...)
+}
+
+var onmemoryShardingCtor = func(t *testing.T, onmemConfigs ...config.OnmemoryStorageConfig) StorageCtor {
+	return onmemoryMultiplexCtorOf(t, func(children map[domain.StorageID]domain.Storage) (domain.Storage, error) {
+		return NewShardingStorageMultiplexer(children, nil, nil)
+	}, onmemConfigs...)
+}
+
+func onmemoryMultiplexCtorOf(t *testing.T, newMultiplexer func(map[domain.StorageID]domain.Storage) (domain.Storage, error), onmemConfigs ...config.OnmemoryStorageConfig) StorageCtor {
 	return func(ctx context.Context, systemClock domain.SystemClock, channelProvider domain.ChannelProvider) (domain.Storage, error) {
 		storages := map[domain.StorageID]domain.Storage{}
 		for i := range onmemConfigs {
@@ -23,7 +33,7 @@ var onmemoryMultiplexCtor = func(t *testing.T, onmemConfigs ...config.OnmemorySt
 			}
 			storages[domain.StorageID(fmt.Sprintf("storage%d", i+1))] = storage
 		}
-		return NewStorageMultiplexer(storages)
+		return newMultiplexer(storages)
 	}
 }
 
@@ -72,3 +82,49 @@ func TestJwt(t *testing.T) {
 		},
 	))
 }
+
+func TestShardingCoreFunction(t *testing.T) {
+	CoreFunctionTest(t, onmemoryShardingCtor(
+		t,
+		config.OnmemoryStorageConfig{
+			DisablePubSub: true,
+			DisableJwt:    true,
+		},
+		config.OnmemoryStorageConfig{
+			DisablePubSub: true,
+			DisableJwt:    true,
+		},
+	))
+}
+
+func TestShardingPubSub(t *testing.T) {
+	PubSubTest(t, onmemoryShardingCtor(
+		t,
+		config.OnmemoryStorageConfig{
+			DisableJwt: true,
+		},
+		config.OnmemoryStorageConfig{
+			DisablePubSub: true, // Storage without feature support
+			DisableJwt:    true,
+		},
+		config.OnmemoryStorageConfig{
+			DisableJwt: true,
+		},
+		config.OnmemoryStorageConfig{
+			DisableJwt: true,
+		},
+	))
+}
+
+func TestShardingJwt(t *testing.T) {
+	JwtTest(t, onmemoryShardingCtor(
+		t,
+		config.OnmemoryStorageConfig{
+			DisablePubSub: true,
+		},
+		config.OnmemoryStorageConfig{
+			DisablePubSub: true,
+			DisableJwt:    true, // Storage without feature support
+		},
+	))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -35,7 +36,7 @@ func TestLongPollingEarlyReturn(t *testing.T) {
 	// Start subscription
 	ch := domain.ChannelID("ch-1")
 	sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
-	assert.NoError(t, s.AsPubSubStorage().NewSubscriber(ctx, sl))
+	assert.NoError(t, s.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
 
 	// Publish only to s1.
 	msgs := []domain.Message{
@@ -77,7 +78,7 @@ func TestAckHandleDurability(t *testing.T) {
 	assert.NoError(t, err)
 	ch := domain.ChannelID("ch-1")
 	sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
-	assert.NoError(t, sBefore.AsPubSubStorage().NewSubscriber(ctx, sl))
+	assert.NoError(t, sBefore.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
 	msgs := []domain.Message{
 		{
 			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: "msg-1"},
@@ -124,7 +125,7 @@ func TestSubscriptionDurability(t *testing.T) {
 	// Start subscription only on s1
 	ch := domain.ChannelID("ch-1")
 	sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
-	assert.NoError(t, s1.AsPubSubStorage().NewSubscriber(ctx, sl))
+	assert.NoError(t, s1.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
 
 	// Publish messages (both s1 and s2)
 	msgs := []domain.Message{
@@ -192,7 +193,9 @@ func TestOldMessageDurability(t *testing.T) {
 	s1 := NewMockStorage(ctrl)
 	s2 := NewMockStorage(ctrl)
 	s1.EXPECT().AsJwtStorage().AnyTimes().Return(nil)
+	s1.EXPECT().AsRateLimitStorage().AnyTimes().Return(nil)
 	s2.EXPECT().AsJwtStorage().AnyTimes().Return(nil)
+	s2.EXPECT().AsRateLimitStorage().AnyTimes().Return(nil)
 	pubsub := NewMockPubSubStorage(ctrl)
 	s1.EXPECT().AsPubSubStorage().AnyTimes().Return(pubsub)
 	s2.EXPECT().AsPubSubStorage().AnyTimes().Return(pubsub)
@@ -253,10 +256,12 @@ func TestGetFileDescriptorPressure(t *testing.T) {
 	mock1.EXPECT().GetFileDescriptorPressure().Return(21)
 	mock1.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
 	mock1.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	mock1.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
 	mock2 := NewMockStorage(ctrl)
 	mock2.EXPECT().GetFileDescriptorPressure().Return(300)
 	mock2.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
 	mock2.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+	mock2.EXPECT().AsRateLimitStorage().Return(nil).AnyTimes()
 
 	s, err := NewStorageMultiplexer(map[domain.StorageID]domain.Storage{
 		"mock1": mock1,
@@ -265,3 +270,294 @@ func TestGetFileDescriptorPressure(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 321, s.GetFileDescriptorPressure())
 }
+
+func TestRateLimitStorageSelection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rateLimit2 := NewMockRateLimitStorage(ctrl)
+	rateLimit3 := NewMockRateLimitStorage(ctrl)
+	mocks := map[domain.StorageID]domain.RateLimitStorage{"mock1": nil, "mock2": rateLimit2, "mock3": rateLimit3}
+	children := map[domain.StorageID]domain.Storage{}
+	for id, rateLimit := range mocks {
+		mock := NewMockStorage(ctrl)
+		mock.EXPECT().AsPubSubStorage().Return(nil).AnyTimes()
+		mock.EXPECT().AsJwtStorage().Return(nil).AnyTimes()
+		mock.EXPECT().AsRateLimitStorage().Return(rateLimit).AnyTimes()
+		children[id] = mock
+	}
+
+	// Must use only one storage (first one in ID order that supports rate limit) not to count twice
+	s, err := NewStorageMultiplexer(children)
+	assert.NoError(t, err)
+	assert.Same(t, rateLimit2, s.AsRateLimitStorage())
+
+	delete(children, "mock2")
+	delete(children, "mock3")
+	s, err = NewStorageMultiplexer(children)
+	assert.NoError(t, err)
+	assert.Nil(t, s.AsRateLimitStorage())
+}
+
+func TestShardingRebalance(t *testing.T) {
+	ctx := context.Background()
+	clock := domain.RealSystemClock
+	cp := StubChannelProvider
+
+	s1, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s2, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s3, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+
+	message := func(ch domain.ChannelID, id domain.MessageID) domain.Message {
+		return domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: id},
+			Content:        json.RawMessage(`{}`),
+		}
+	}
+	channels := make([]domain.ChannelID, 0, 20)
+	for i := 0; i < 20; i++ {
+		channels = append(channels, domain.ChannelID(fmt.Sprintf("ch-%d", i)))
+	}
+
+	// Subscribe and publish with s1 + s2
+	sBefore, err := NewShardingStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2}, nil, nil)
+	assert.NoError(t, err)
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		assert.NoError(t, sBefore.AsPubSubStorage().NewSubscriber(ctx, sl, nil))
+		assert.NoError(t, sBefore.AsPubSubStorage().PublishMessages(ctx, []domain.Message{message(ch, "msg-1")}))
+	}
+
+	// Add s3, some channels move to s3
+	sAfter, err := NewShardingStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2, "s3": s3}, nil, [][]domain.StorageID{{"s1", "s2"}})
+	assert.NoError(t, err)
+	for _, ch := range channels {
+		assert.NoError(t, sAfter.AsPubSubStorage().PublishMessages(ctx, []domain.Message{message(ch, "msg-2")}))
+	}
+	moved, err := s3.AsPubSubStorage().InspectChannels(ctx, "")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, moved)
+
+	// Subscribers receive messages published both before and after rebalancing
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		received := []domain.Message{}
+		for i := 0; i < 3; i++ {
+			fetched, _, ackHandle, err := sAfter.AsPubSubStorage().FetchMessages(ctx, sl, 10, MakeDuration("10ms"))
+			assert.NoError(t, err)
+			received = append(received, fetched...)
+			if len(fetched) > 0 {
+				assert.NoError(t, sAfter.AsPubSubStorage().AcknowledgeMessages(ctx, ackHandle))
+			}
+		}
+		MessagesEqual(t, []domain.Message{message(ch, "msg-1"), message(ch, "msg-2")}, received)
+	}
+
+	// Remove subscribers from both owner and previous owner
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		assert.NoError(t, sAfter.AsPubSubStorage().RemoveSubscriber(ctx, sl))
+		_, _, _, err := sAfter.AsPubSubStorage().FetchMessages(ctx, sl, 10, MakeDuration("10ms"))
+		IsError(t, domain.ErrSubscriptionNotFound, err)
+	}
+}
+
+func TestShardingMultipleRebalances(t *testing.T) {
+	ctx := context.Background()
+	clock := domain.RealSystemClock
+	cp := StubChannelProvider
+
+	s1, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s2, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s3, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	children := map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2, "s3": s3}
+
+	message := func(ch domain.ChannelID, id domain.MessageID) domain.Message {
+		return domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: id},
+			Content:        json.RawMessage(`{}`),
+		}
+	}
+	channels := make([]domain.ChannelID, 0, 20)
+	for i := 0; i < 20; i++ {
+		channels = append(channels, domain.ChannelID(fmt.Sprintf("ch-%d", i)))
+	}
+
+	// s1 + s2, then add s3, then remove s1
+	phases := []struct {
+		shards         []domain.StorageID
+		previousShards [][]domain.StorageID
+	}{
+		{[]domain.StorageID{"s1", "s2"}, nil},
+		{[]domain.StorageID{"s1", "s2", "s3"}, [][]domain.StorageID{{"s1", "s2"}}},
+		{[]domain.StorageID{"s2", "s3"}, [][]domain.StorageID{{"s1", "s2", "s3"}, {"s1", "s2"}}},
+	}
+	var s domain.Storage
+	for i, phase := range phases {
+		s, err = NewShardingStorageMultiplexer(children, phase.shards, phase.previousShards)
+		assert.NoError(t, err)
+		for _, ch := range channels {
+			if i == 0 {
+				assert.NoError(t, s.AsPubSubStorage().NewSubscriber(ctx, domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}, nil))
+			}
+			assert.NoError(t, s.AsPubSubStorage().PublishMessages(ctx, []domain.Message{message(ch, domain.MessageID(fmt.Sprintf("msg-%d", i)))}))
+		}
+	}
+
+	// Subscribers receive messages published in all phases
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		received := []domain.Message{}
+		for i := 0; i < 4; i++ {
+			fetched, _, ackHandle, err := s.AsPubSubStorage().FetchMessages(ctx, sl, 10, MakeDuration("10ms"))
+			assert.NoError(t, err)
+			received = append(received, fetched...)
+			if len(fetched) > 0 {
+				assert.NoError(t, s.AsPubSubStorage().AcknowledgeMessages(ctx, ackHandle))
+			}
+		}
+		MessagesEqual(t, []domain.Message{message(ch, "msg-0"), message(ch, "msg-1"), message(ch, "msg-2")}, received)
+	}
+
+	_, err = NewShardingStorageMultiplexer(children, []domain.StorageID{"s1", "s4"}, nil)
+	assert.EqualError(t, err, `Storage "s4" of shards not found`)
+}
+
+func TestShardingRebalanceFilter(t *testing.T) {
+	ctx := context.Background()
+	clock := domain.RealSystemClock
+	cp := StubChannelProvider
+
+	s1, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s2, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s3, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+
+	message := func(ch domain.ChannelID, id domain.MessageID, eventType string) domain.Message {
+		return domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: id},
+			Content:        json.RawMessage(`{}`),
+			Attributes:     domain.MessageAttributes{"event-type": eventType},
+		}
+	}
+	channels := make([]domain.ChannelID, 0, 20)
+	for i := 0; i < 20; i++ {
+		channels = append(channels, domain.ChannelID(fmt.Sprintf("ch-%d", i)))
+	}
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+
+	sBefore, err := NewShardingStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2}, nil, nil)
+	assert.NoError(t, err)
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		assert.NoError(t, sBefore.AsPubSubStorage().NewSubscriber(ctx, sl, filter))
+	}
+
+	// Add s3, some channels move to s3
+	sAfter, err := NewShardingStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2, "s3": s3}, nil, [][]domain.StorageID{{"s1", "s2"}})
+	assert.NoError(t, err)
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		_, _, _, err := sAfter.AsPubSubStorage().FetchMessages(ctx, sl, 10, MakeDuration("10ms"))
+		assert.NoError(t, err)
+		assert.NoError(t, sAfter.AsPubSubStorage().PublishMessages(ctx, []domain.Message{message(ch, "msg-1", "deleted"), message(ch, "msg-2", "created")}))
+	}
+	inspections, err := s3.AsPubSubStorage().InspectChannels(ctx, "")
+	assert.NoError(t, err)
+	recovered := 0
+	for _, ch := range inspections {
+		recovered += len(ch.Subscribers)
+	}
+	assert.NotZero(t, recovered)
+
+	// Subscribers recovered on the new owner keep the filter
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		fetched, _, _, err := sAfter.AsPubSubStorage().FetchMessages(ctx, sl, 10, MakeDuration("10ms"))
+		assert.NoError(t, err)
+		MessagesEqual(t, []domain.Message{message(ch, "msg-2", "created")}, fetched)
+	}
+}
+
+func TestQueueSubscriberReplicateMode(t *testing.T) {
+	ctx := context.Background()
+	clock := domain.RealSystemClock
+	cp := StubChannelProvider
+
+	s1, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s2, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	sl := domain.SubscriberLocator{ChannelID: "ch-1", SubscriberID: "sbsc-1"}
+
+	// Leases of each storage cannot be merged
+	s, err := NewStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2})
+	assert.NoError(t, err)
+	IsError(t, domain.ErrQueueSubscriberUnsupported, s.AsPubSubStorage().NewQueueSubscriber(ctx, sl, nil, MakeDuration("1s")))
+
+	// Only one storage owns the channel
+	s, err = NewShardingStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2}, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.AsPubSubStorage().NewQueueSubscriber(ctx, sl, nil, MakeDuration("1s")))
+}
+
+func TestShardingRebalanceQueueSubscriber(t *testing.T) {
+	ctx := context.Background()
+	clock := domain.RealSystemClock
+	cp := StubChannelProvider
+
+	s1, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s2, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+	s3, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, clock, cp, EmptyDeps(t))
+	assert.NoError(t, err)
+
+	channels := make([]domain.ChannelID, 0, 20)
+	for i := 0; i < 20; i++ {
+		channels = append(channels, domain.ChannelID(fmt.Sprintf("ch-%d", i)))
+	}
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+	vt := MakeDuration("1m")
+
+	sBefore, err := NewShardingStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2}, nil, nil)
+	assert.NoError(t, err)
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		assert.NoError(t, sBefore.AsPubSubStorage().NewQueueSubscriber(ctx, sl, filter, vt))
+	}
+
+	// Add s3, some channels move to s3
+	sAfter, err := NewShardingStorageMultiplexer(map[domain.StorageID]domain.Storage{"s1": s1, "s2": s2, "s3": s3}, nil, [][]domain.StorageID{{"s1", "s2"}})
+	assert.NoError(t, err)
+	for _, ch := range channels {
+		sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-1"}
+		_, _, _, err := sAfter.AsPubSubStorage().FetchMessages(ctx, sl, 10, MakeDuration("10ms"))
+		assert.NoError(t, err)
+	}
+
+	// Subscribers recovered on s3 keep queue mode and filter of the previous owner
+	inspections, err := s3.AsPubSubStorage().InspectChannels(ctx, "")
+	assert.NoError(t, err)
+	recovered := 0
+	for _, ch := range inspections {
+		for _, sbsc := range ch.Subscribers {
+			recovered++
+			assert.Equal(t, &vt, sbsc.VisibilityTimeout)
+			if assert.NotNil(t, sbsc.Filter) {
+				assert.Equal(t, filter.String(), sbsc.Filter.String())
+			}
+		}
+	}
+	assert.NotZero(t, recovered)
+}
@@ -28,10 +28,15 @@ var errMultiplexSkipped = errors.New("storage.multiplex.skipped")
 
 // Concurrently call given func for all children (even if one or more failed), then returns nil (success) if one or more succeeded
 func (s *storageMultiplexer) parallelAtLeastOneSuccess(ctx context.Context, operationName string, f func(ctx context.Context, id domain.StorageID, s domain.Storage) (interface{}, error)) (map[domain.StorageID]interface{}, error) {
+	return parallelAtLeastOneSuccessOn(ctx, s.children, operationName, f)
+}
+
+// Same as parallelAtLeastOneSuccess but only for given children
+func parallelAtLeastOneSuccessOn(ctx context.Context, children map[domain.StorageID]domain.Storage, operationName string, f func(ctx context.Context, id domain.StorageID, s domain.Storage) (interface{}, error)) (map[domain.StorageID]interface{}, error) {
 	wg := sync.WaitGroup{}
-	successCh := make(chan childResult, len(s.children))
-	errCh := make(chan childResult, len(s.children))
-	for id, child := range s.children {
+	successCh := make(chan childResult, len(children))
+	errCh := make(chan childResult, len(children))
+	for id, child := range children {
 		wg.Add(1)
 		id := id
 		child := child
@@ -36,7 +36,9 @@ func decodeAckHandle(h domain.AckHandle) (ackHandleData, error) {
 // AckHandleData represents decoded (raw) ReceiptHandle
 type ackHandleData struct {
 	LastMessageID domain.MessageID `json:"mid"`
-	Checksum      string           `json:"xs"`
+	// Empty if not leased by queue subscriber
+	LeaseID  string `json:"lid,omitempty"`
+	Checksum string `json:"xs"`
 }
 
 func (data ackHandleData) ComputeChecksum(sl domain.SubscriberLocator) string {
@@ -48,6 +50,10 @@ func (data ackHandleData) ComputeChecksum(sl domain.SubscriberLocator) string {
 	hashBuffer.WriteString(string(sl.SubscriberID))
 	hashBuffer.WriteByte(0x00)
 	hashBuffer.WriteString(string(data.LastMessageID))
+	if data.LeaseID != "" {
+		hashBuffer.WriteByte(0x00)
+		hashBuffer.WriteString(data.LeaseID)
+	}
 
 	base64Buffer := bytes.Buffer{}
 	binary.Write(&base64Buffer, binary.BigEndian, crc32.ChecksumIEEE(hashBuffer.Bytes())) //nolint:errcheck,gosec
@@ -16,13 +16,15 @@ func TestAckHandleChecksum(t *testing.T) {
 	}
 	for _, data := range []ackHandleData{
 		{LastMessageID: "msg-1"},
+		{LastMessageID: "msg-1", LeaseID: "lease-1"},
 	} {
 		data.Checksum = "INVALID"
 		data.ComputeChecksum(sl)
 		assert.Equal(t, "INVALID", data.Checksum, "ComputeChechsum() should not modify struct")
 
 		assert.Equal(t, data.ComputeChecksum(sl), data.ComputeChecksum(sl))
-		assert.NotEqual(t, data.ComputeChecksum(sl), ackHandleData{LastMessageID: data.LastMessageID + "-diff"}.ComputeChecksum(sl))
+		assert.NotEqual(t, data.ComputeChecksum(sl), ackHandleData{LastMessageID: data.LastMessageID + "-diff", LeaseID: data.LeaseID}.ComputeChecksum(sl))
+		assert.NotEqual(t, data.ComputeChecksum(sl), ackHandleData{LastMessageID: data.LastMessageID, LeaseID: data.LeaseID + "-diff"}.ComputeChecksum(sl))
 		assert.NotEqual(t, data.ComputeChecksum(sl), data.ComputeChecksum(domain.SubscriberLocator{
 			ChannelID:    sl.ChannelID,
 			SubscriberID: sl.SubscriberID + "-different",
@@ -0,0 +1,83 @@
+package onmemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (s *onmemoryStorage) ExportChannels(ctx context.Context, fn func(domain.ChannelExport) error) error {
+	return domain.ExportChannelsOneByOne(ctx, s, fn)
+}
+
+func (s *onmemoryStorage) ExportChannel(ctx context.Context, channelID domain.ChannelID) (domain.ChannelExport, error) {
+	result := domain.ChannelExport{
+		ChannelID:   channelID,
+		Messages:    []domain.Message{},
+		Subscribers: []domain.SubscriberExport{},
+	}
+
+	unlock, err := s.lock.Lock(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+
+	ch := s.channels[channelID]
+	if ch == nil {
+		return result, nil
+	}
+
+	now := s.systemClock.Now()
+	retained := make([]*onmemoryMessage, 0, len(ch.log))
+	for _, msg := range ch.log {
+		if !msg.evicted && msg.ExpireAt.After(now.Time) {
+			retained = append(retained, msg)
+		}
+	}
+	sort.Slice(retained, func(i, j int) bool { return retained[i].channelClock < retained[j].channelClock })
+	for _, msg := range retained {
+		result.Messages = append(result.Messages, msg.Message)
+	}
+
+	expireBefore := now.Add(-ch.Expire().Duration)
+	for sid, sbsc := range ch.subscribers {
+		if sbsc.lastActivity.Before(expireBefore) {
+			continue // Expired, GC will remove it
+		}
+		consumed := sort.Search(len(retained), func(i int) bool { return retained[i].channelClock > sbsc.channelClock })
+		export := domain.SubscriberExport{
+			SubscriberID: sid,
+			Filter:       sbsc.filter,
+			Consumed:     consumed,
+		}
+		if sbsc.queue != nil {
+			vt := sbsc.queue.visibilityTimeout
+			export.VisibilityTimeout = &vt
+		}
+		result.Subscribers = append(result.Subscribers, export)
+	}
+	sort.Slice(result.Subscribers, func(i, j int) bool {
+		return result.Subscribers[i].SubscriberID < result.Subscribers[j].SubscriberID
+	})
+	return result, nil
+}
+
+func (s *onmemoryStorage) ExportRevokedJwts(ctx context.Context) ([]domain.RevokedJwt, error) {
+	unlock, err := s.lock.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	now := s.systemClock.Now()
+	result := make([]domain.RevokedJwt, 0, len(s.revokedJwts))
+	for jti, exp := range s.revokedJwts {
+		if !now.After(exp.Time()) {
+			result = append(result, domain.RevokedJwt{Jti: jti, Exp: exp})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Jti < result[j].Jti })
+	return result, nil
+}
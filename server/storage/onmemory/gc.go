@@ -52,6 +52,19 @@ func (s *onmemoryStorage) GC(ctx context.Context) error {
 				}
 			}
 			sbsc.messages = aliveMsgs
+
+			// Remove leases of messages no longer in the queue (evicted or expired).
+			if sbsc.queue != nil {
+				queued := make(map[uint64]bool, len(sbsc.messages))
+				for _, msg := range sbsc.messages {
+					queued[msg.channelClock] = true
+				}
+				for clock := range sbsc.queue.leases {
+					if !queued[clock] {
+						delete(sbsc.queue.leases, clock)
+					}
+				}
+			}
 		}
 
 		// Remove expired message log.
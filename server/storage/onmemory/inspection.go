@@ -0,0 +1,60 @@
+package onmemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (s *onmemoryStorage) InspectChannels(ctx context.Context, channelID domain.ChannelID) ([]domain.ChannelInspection, error) {
+	unlock, err := s.lock.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := make([]domain.ChannelInspection, 0, len(s.channels))
+	for id, ch := range s.channels {
+		if channelID != "" && channelID != id {
+			continue
+		}
+
+		inspection := domain.ChannelInspection{
+			ChannelID: id,
+			Clock:     int64(ch.channelClock),
+			TTL:       nil, // This storage never discards channel
+
+			Subscribers: make([]domain.SubscriberInspection, 0, len(ch.subscribers)),
+		}
+		for sid, sbsc := range ch.subscribers {
+			now := s.systemClock.Now()
+			ttl := domain.Duration{Duration: sbsc.lastActivity.Add(ch.Expire().Duration).Sub(now.Time)}
+			si := domain.SubscriberInspection{
+				SubscriberID: sid,
+				Clock:        int64(sbsc.channelClock),
+				Backlog:      int64(len(sbsc.messages)), // Includes messages not matched with the filter until fetch
+				TTL:          &ttl,
+				Filter:       sbsc.filter,
+			}
+			if sbsc.queue != nil {
+				vt := sbsc.queue.visibilityTimeout
+				si.VisibilityTimeout = &vt
+				for _, msg := range sbsc.messages {
+					if sbsc.queue.isLeased(msg, now) {
+						si.Leased++
+					}
+				}
+			}
+			inspection.Subscribers = append(inspection.Subscribers, si)
+		}
+		sort.Slice(inspection.Subscribers, func(i, j int) bool {
+			return inspection.Subscribers[i].SubscriberID < inspection.Subscribers[j].SubscriberID
+		})
+		result = append(result, inspection)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ChannelID < result[j].ChannelID
+	})
+	return result, nil
+}
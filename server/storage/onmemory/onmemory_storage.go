@@ -96,6 +96,9 @@ func (s *onmemoryStorage) AsJwtStorage() domain.JwtStorage {
 	}
 	return s
 }
+func (s *onmemoryStorage) AsRateLimitStorage() domain.RateLimitStorage {
+	return nil // Counters in this process are not shared with other server instances, no benefit over the local counter of the rate limit middleware
+}
 
 func (s *onmemoryStorage) GetFileDescriptorPressure() int {
 	return 0
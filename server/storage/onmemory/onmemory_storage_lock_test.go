@@ -38,7 +38,7 @@ func TestPubSubLockFail(t *testing.T) {
 	}
 
 	testLockFail(t, "10ms", func(ctx context.Context, storage *onmemoryStorage) error {
-		return storage.NewSubscriber(ctx, sl)
+		return storage.NewSubscriber(ctx, sl, nil)
 	})
 	testLockFail(t, "10ms", func(ctx context.Context, storage *onmemoryStorage) error {
 		return storage.RemoveSubscriber(ctx, sl)
@@ -49,7 +49,7 @@ func TestPubSubLockFail(t *testing.T) {
 	func() { // Test FetchMessages, lock failure before polling
 		s := makeRawStorage(t)
 		defer func() { assert.NoError(t, s.Shutdown(context.Background())) }()
-		assert.NoError(t, s.NewSubscriber(context.Background(), sl))
+		assert.NoError(t, s.NewSubscriber(context.Background(), sl, nil))
 
 		unlock, err := s.lock.Lock(context.Background()) // Make deadlock
 		assert.NoError(t, err)
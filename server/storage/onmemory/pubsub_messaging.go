@@ -3,9 +3,12 @@ package onmemory
 import (
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"golang.org/x/xerrors"
 
 	"github.com/m3dev/dsps/server/domain"
@@ -15,6 +18,8 @@ type onmemoryMessage struct {
 	domain.Message
 	channelClock uint64
 	ExpireAt     domain.Time
+	// Discarded due to backlog limit, log entry remains only for deduplication
+	evicted bool
 }
 
 func (msg *onmemoryMessage) Validate() error {
@@ -53,6 +58,10 @@ func (s *onmemoryStorage) PublishMessages(ctx context.Context, msgs []domain.Mes
 		if err := wrapped.Validate(); err != nil {
 			return err
 		}
+		if err := s.makeRoomForMessage(ch); err != nil {
+			ch.channelClock = ch.channelClock - 1 // Rollback
+			return err
+		}
 		ch.log[msg.MessageLocator] = &wrapped
 
 		for _, sbsc := range ch.subscribers {
@@ -63,6 +72,47 @@ func (s *onmemoryStorage) PublishMessages(ctx context.Context, msgs []domain.Mes
 	return nil
 }
 
+// makeRoomForMessage enforces BacklogLimit of the channel before storing new message.
+// Note: caller must hold lock of the storage.
+func (s *onmemoryStorage) makeRoomForMessage(ch *onmemoryChannel) error {
+	limit := ch.BacklogLimit()
+	if limit.Max <= 0 {
+		return nil
+	}
+
+	now := s.systemClock.Now()
+	retained := make([]*onmemoryMessage, 0, len(ch.log))
+	for _, msg := range ch.log {
+		if !msg.evicted && msg.ExpireAt.After(now.Time) {
+			retained = append(retained, msg)
+		}
+	}
+	if len(retained) < limit.Max {
+		return nil
+	}
+	if limit.Overflow != domain.BacklogOverflowEvict {
+		return xerrors.Errorf("channel retains %d messages (max: %d): %w", len(retained), limit.Max, domain.ErrBacklogFull)
+	}
+
+	// Evict oldest messages, could be more than one if the limit has been reduced by configuration reload.
+	sort.Slice(retained, func(i, j int) bool { return retained[i].channelClock < retained[j].channelClock })
+	evicted := map[domain.MessageLocator]bool{}
+	for _, msg := range retained[:len(retained)-limit.Max+1] {
+		msg.evicted = true
+		evicted[msg.MessageLocator] = true
+	}
+	for _, sbsc := range ch.subscribers {
+		msgs := make([]*onmemoryMessage, 0, len(sbsc.messages))
+		for _, msg := range sbsc.messages {
+			if !evicted[msg.MessageLocator] {
+				msgs = append(msgs, msg)
+			}
+		}
+		sbsc.messages = msgs
+	}
+	return nil
+}
+
 func (s *onmemoryStorage) FetchMessages(ctx context.Context, sl domain.SubscriberLocator, max int, waituntil domain.Duration) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, err error) {
 	sbsc, err := s.findSubscriberForFetchMessages(ctx, sl)
 	if err != nil {
@@ -73,8 +123,16 @@ func (s *onmemoryStorage) FetchMessages(ctx context.Context, sl domain.Subscribe
 	received := make(chan domain.Message, max)
 	completed := make(chan error, 2)
 
+	// Used only if the subscriber is a queue subscriber
+	leaseID, err := newLeaseID()
+	if err != nil {
+		return []domain.Message{}, false, domain.AckHandle{}, err
+	}
+
 	var full int32
+	var leased int32
 	atomic.StoreInt32(&full, 0)
+	atomic.StoreInt32(&leased, 0)
 	go func() {
 		defer close(received)
 		defer func() { completed <- nil }()
@@ -93,12 +151,35 @@ func (s *onmemoryStorage) FetchMessages(ctx context.Context, sl domain.Subscribe
 				}
 				defer unlock()
 
-				sbsc.lastActivity = s.systemClock.Now()
-				// Fetch messages as possible
+				now := s.systemClock.Now()
+				sbsc.lastActivity = now
+				// Fetch messages as possible, messages not matched with the filter are discarded as if acknowledged
+				retained := make([]*onmemoryMessage, 0, len(sbsc.messages))
+				defer func() { sbsc.messages = retained }()
 				for _, msg := range sbsc.messages {
+					if !sbsc.filter.Match(msg.Message) {
+						if sbsc.queue != nil {
+							delete(sbsc.queue.leases, msg.channelClock)
+						}
+						if len(retained) == 0 && sbsc.channelClock < msg.channelClock {
+							sbsc.channelClock = msg.channelClock // Cursor points the last message before the first retained message
+						}
+						continue
+					}
+					retained = append(retained, msg)
+					if sbsc.queue != nil && sbsc.queue.isLeased(msg, now) {
+						continue // Other consumer is processing it
+					}
 					select {
 					case received <- msg.Message: // Receive message
 						found = true
+						if sbsc.queue != nil {
+							sbsc.queue.leases[msg.channelClock] = onmemoryLease{
+								id:       leaseID,
+								expireAt: domain.Time{Time: now.Add(sbsc.queue.visibilityTimeout.Duration)},
+							}
+							atomic.StoreInt32(&leased, 1)
+						}
 					default: // Queue is full (reached to max)
 						atomic.StoreInt32(&full, 1)
 					}
@@ -140,9 +221,13 @@ func (s *onmemoryStorage) FetchMessages(ctx context.Context, sl domain.Subscribe
 	moreMessages = (atomic.LoadInt32(&full) == int32(1))
 
 	if len(messages) > 0 {
-		ackHandle = encodeAckHandle(sl, ackHandleData{
+		data := ackHandleData{
 			LastMessageID: messages[len(messages)-1].MessageID,
-		})
+		}
+		if atomic.LoadInt32(&leased) == 1 {
+			data.LeaseID = leaseID
+		}
+		ackHandle = encodeAckHandle(sl, data)
 	} else {
 		ackHandle = domain.AckHandle{}
 	}
@@ -172,6 +257,14 @@ func (s *onmemoryStorage) AcknowledgeMessages(ctx context.Context, handle domain
 		return err
 	}
 
+	if sbsc.queue != nil || rhd.LeaseID != "" {
+		if sbsc.queue == nil || rhd.LeaseID == "" {
+			return nil // AckHandle is stale, subscriber type has been changed
+		}
+		sbsc.ackLease(rhd.LeaseID)
+		return nil
+	}
+
 	var readUntil = -1
 	for i, msg := range sbsc.messages {
 		if rhd.LastMessageID == msg.MessageID {
@@ -187,6 +280,40 @@ func (s *onmemoryStorage) AcknowledgeMessages(ctx context.Context, handle domain
 	return nil
 }
 
+// ackLease removes messages of the lease from the queue subscriber, other messages remain in the queue.
+// If the lease has been expired and the messages have been leased to other consumer, this method does nothing for them.
+func (sbsc *onmemorySubscriber) ackLease(leaseID string) {
+	var acked uint64
+	remaining := make([]*onmemoryMessage, 0, len(sbsc.messages))
+	for _, msg := range sbsc.messages {
+		if lease, ok := sbsc.queue.leases[msg.channelClock]; ok && lease.id == leaseID {
+			delete(sbsc.queue.leases, msg.channelClock)
+			if acked < msg.channelClock {
+				acked = msg.channelClock
+			}
+			continue
+		}
+		remaining = append(remaining, msg)
+	}
+	sbsc.messages = remaining
+
+	// Cursor points the last message before the first unacknowledged message
+	if len(remaining) > 0 {
+		acked = remaining[0].channelClock - 1
+	}
+	if sbsc.channelClock < acked {
+		sbsc.channelClock = acked
+	}
+}
+
+func newLeaseID() (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", xerrors.Errorf("Failed to generate lease ID: %w", err)
+	}
+	return strings.ReplaceAll(id.String(), "-", ""), nil
+}
+
 func (s *onmemoryStorage) IsOldMessages(ctx context.Context, sl domain.SubscriberLocator, msgs []domain.MessageLocator) (map[domain.MessageLocator]bool, error) {
 	unlock, err := s.lock.Lock(ctx)
 	if err != nil {
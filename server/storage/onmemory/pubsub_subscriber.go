@@ -3,6 +3,7 @@ package onmemory
 import (
 	"context"
 	"errors"
+	"sort"
 
 	"golang.org/x/xerrors"
 
@@ -21,9 +22,24 @@ type onmemorySubscriber struct {
 	lastActivity domain.Time
 	channelClock uint64
 	messages     []*onmemoryMessage
+	filter       *domain.SubscriberFilter
+	// nil if not a queue subscriber
+	queue *onmemoryQueue
 }
 
-func (s *onmemoryStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
+// onmemoryQueue holds state of the queue subscriber, consumers of the subscriber lease messages
+type onmemoryQueue struct {
+	visibilityTimeout domain.Duration
+	// Lease of the message in the subscriber queue (key: channelClock of the message)
+	leases map[uint64]onmemoryLease
+}
+
+type onmemoryLease struct {
+	id       string
+	expireAt domain.Time
+}
+
+func (s *onmemoryStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter) error {
 	unlock, err := s.lock.Lock(ctx)
 	if err != nil {
 		return err
@@ -35,19 +51,51 @@ func (s *onmemoryStorage) NewSubscriber(ctx context.Context, sl domain.Subscribe
 		return err
 	}
 
-	if ch.subscribers[sl.SubscriberID] != nil {
-		return nil // Already exists (success)
+	if sbsc := ch.subscribers[sl.SubscriberID]; sbsc != nil {
+		sbsc.filter = filter // Already exists (success), only replace filter
+		sbsc.queue = nil     // Turn into normal subscriber, leased messages will be redelivered
+		return nil
 	}
 
 	instance := onmemorySubscriber{
 		channelClock: ch.channelClock,
 		lastActivity: s.systemClock.Now(),
 		messages:     []*onmemoryMessage{},
+		filter:       filter,
 	}
 	ch.subscribers[sl.SubscriberID] = &instance
 	return nil
 }
 
+func (s *onmemoryStorage) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	unlock, err := s.lock.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ch, err := s.getChannel(sl.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	sbsc := ch.subscribers[sl.SubscriberID]
+	if sbsc == nil {
+		sbsc = &onmemorySubscriber{
+			channelClock: ch.channelClock,
+			lastActivity: s.systemClock.Now(),
+			messages:     []*onmemoryMessage{},
+		}
+		ch.subscribers[sl.SubscriberID] = sbsc
+	}
+	sbsc.filter = filter
+	if sbsc.queue == nil {
+		sbsc.queue = &onmemoryQueue{leases: map[uint64]onmemoryLease{}}
+	}
+	sbsc.queue.visibilityTimeout = visibilityTimeout // Keep current leases
+	return nil
+}
+
 func (s *onmemoryStorage) RemoveSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
 	unlock, err := s.lock.Lock(ctx)
 	if err != nil {
@@ -68,6 +116,71 @@ func (s *onmemoryStorage) RemoveSubscriber(ctx context.Context, sl domain.Subscr
 	return nil
 }
 
+func (s *onmemoryStorage) RewindSubscriber(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) error {
+	unlock, err := s.lock.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ch, err := s.getChannel(sl.ChannelID)
+	if err != nil {
+		return err
+	}
+	sbsc := ch.subscribers[sl.SubscriberID]
+	if sbsc == nil {
+		return xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+	}
+
+	retained := make([]*onmemoryMessage, 0, len(ch.log))
+	for _, msg := range ch.log {
+		if !msg.evicted {
+			retained = append(retained, msg)
+		}
+	}
+	sort.Slice(retained, func(i, j int) bool { return retained[i].channelClock < retained[j].channelClock })
+
+	var cursor uint64
+	switch {
+	case target.Clock != nil:
+		cursor = uint64(*target.Clock)
+		if *target.Clock < 0 || (cursor != ch.channelClock && !containsClock(retained, cursor+1)) {
+			return xerrors.Errorf("%w: clock %d", domain.ErrRewindTargetNotFound, *target.Clock)
+		}
+	case target.MessageID != nil:
+		msg := ch.log[domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: *target.MessageID}]
+		if msg == nil || msg.evicted {
+			return xerrors.Errorf("%w: message %s", domain.ErrRewindTargetNotFound, *target.MessageID)
+		}
+		cursor = msg.channelClock - 1
+	case target.Earliest:
+		cursor = ch.channelClock
+		if len(retained) > 0 {
+			cursor = retained[0].channelClock - 1
+		}
+	default:
+		return xerrors.New("Rewind target not specified")
+	}
+
+	sbsc.channelClock = cursor
+	sbsc.lastActivity = s.systemClock.Now()
+	sbsc.messages = []*onmemoryMessage{}
+	if sbsc.queue != nil {
+		sbsc.queue.leases = map[uint64]onmemoryLease{}
+	}
+	for _, msg := range retained {
+		if msg.channelClock > cursor {
+			sbsc.addMessage(*msg)
+		}
+	}
+	return nil
+}
+
+func containsClock(msgs []*onmemoryMessage, clock uint64) bool {
+	i := sort.Search(len(msgs), func(i int) bool { return msgs[i].channelClock >= clock })
+	return i < len(msgs) && msgs[i].channelClock == clock
+}
+
 func (s *onmemoryStorage) getChannel(id domain.ChannelID) (*onmemoryChannel, error) {
 	ch := s.channels[id]
 	if ch == nil {
@@ -112,6 +225,13 @@ func (s *onmemoryStorage) findSubscriberForFetchMessages(ctx context.Context, sl
 	return sbsc, nil
 }
 
+// addMessage enqueues the message regardless of the filter, FetchMessages applies the filter of that time as well as other storages.
 func (sbsc *onmemorySubscriber) addMessage(msg onmemoryMessage) {
 	sbsc.messages = append(sbsc.messages, &msg)
 }
+
+// isLeased returns true if the message is leased to a consumer and the lease has not expired
+func (q *onmemoryQueue) isLeased(msg *onmemoryMessage, now domain.Time) bool {
+	lease, ok := q.leases[msg.channelClock]
+	return ok && lease.expireAt.After(now.Time)
+}
@@ -15,7 +15,11 @@ import (
 // AckHandleData represents decoded (raw) ReceiptHandle
 type ackHandleData struct {
 	LastMessageClock channelClock `json:"clk"`
-	Checksum         string       `json:"xs"`
+	// Following fields are set only if messages are leased by queue subscriber
+	LeaseID      string         `json:"lid,omitempty"`
+	LeasedClocks []channelClock `json:"lcs,omitempty"`
+
+	Checksum string `json:"xs"`
 }
 
 // Note this method does NOT read nor write ackHandleData.Checksum field.
@@ -28,6 +32,13 @@ func (data ackHandleData) ComputeChecksum(sl domain.SubscriberLocator) string {
 	hashBuffer.WriteString(string(sl.SubscriberID))
 	hashBuffer.WriteByte(0x00)
 	binary.Write(&hashBuffer, binary.BigEndian, data.LastMessageClock) //nolint:errcheck,gosec
+	if data.LeaseID != "" {
+		hashBuffer.WriteByte(0x00)
+		hashBuffer.WriteString(data.LeaseID)
+		for _, clock := range data.LeasedClocks {
+			binary.Write(&hashBuffer, binary.BigEndian, clock) //nolint:errcheck,gosec
+		}
+	}
 
 	base64Buffer := bytes.Buffer{}
 	binary.Write(&base64Buffer, binary.BigEndian, crc32.ChecksumIEEE(hashBuffer.Bytes())) //nolint:errcheck,gosec
@@ -39,6 +39,24 @@ func TestAckHandleChecksum(t *testing.T) {
 	}
 }
 
+func TestQueueAckHandleChecksum(t *testing.T) {
+	sl := domain.SubscriberLocator{
+		ChannelID:    "ch-1",
+		SubscriberID: "sbsc-1",
+	}
+	data := ackHandleData{LastMessageClock: 3, LeaseID: "lease-1", LeasedClocks: []channelClock{1, 3}}
+	assert.NotEqual(t, data.ComputeChecksum(sl), ackHandleData{LastMessageClock: 3}.ComputeChecksum(sl))
+	assert.NotEqual(t, data.ComputeChecksum(sl), ackHandleData{LastMessageClock: 3, LeaseID: "lease-2", LeasedClocks: []channelClock{1, 3}}.ComputeChecksum(sl))
+	assert.NotEqual(t, data.ComputeChecksum(sl), ackHandleData{LastMessageClock: 3, LeaseID: "lease-1", LeasedClocks: []channelClock{2, 3}}.ComputeChecksum(sl))
+	assert.NotEqual(t, data.ComputeChecksum(sl), ackHandleData{LastMessageClock: 3, LeaseID: "lease-1", LeasedClocks: []channelClock{3}}.ComputeChecksum(sl))
+
+	encoded := encodeAckHandle(sl, data)
+	decoded, err := decodeAckHandle(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "lease-1", decoded.LeaseID)
+	assert.Equal(t, []channelClock{1, 3}, decoded.LeasedClocks)
+}
+
 func TestUnmatchAckHandle(t *testing.T) {
 	sl := domain.SubscriberLocator{
 		ChannelID:    "ch-1",
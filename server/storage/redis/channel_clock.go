@@ -32,6 +32,40 @@ func iterateClocks(count int, fromExclusive channelClock, toInclusive channelClo
 	return result
 }
 
+// Returns previous clock of the given one, considering wrap-around
+func prevClock(clock channelClock) channelClock {
+	if clock == clockMin {
+		return clockMax
+	}
+	return clock - 1
+}
+
+// Returns the clock n steps before the given one, considering wrap-around (n must be smaller than size of the clock space)
+func clockBefore(clock channelClock, n int64) channelClock {
+	result := int64(clock) - n
+	if result < int64(clockMin) {
+		result += int64(clockMax) - int64(clockMin) + 1
+	}
+	return channelClock(result)
+}
+
+// Returns next clock of the given one, considering wrap-around
+func nextClock(clock channelClock) channelClock {
+	if clock == clockMax {
+		return clockMin
+	}
+	return clock + 1
+}
+
+// Returns count of clocks in range (from, to]
+func countClocks(fromExclusive channelClock, toInclusive channelClock) int64 {
+	if toInclusive < fromExclusive {
+		// Range is (from, clockMax] and [clockMin, to]
+		return int64(clockMax-fromExclusive) + int64(toInclusive-clockMin) + 1
+	}
+	return int64(toInclusive - fromExclusive)
+}
+
 func isClockWithin(clock channelClock, fromExclusive channelClock, toInclusive channelClock) bool {
 	if toInclusive < fromExclusive {
 		// Range is (from, clockMax] and [clockMin, to]
@@ -59,3 +59,27 @@ func TestIterateClocks(t *testing.T) {
 		iterateClocks(10, clockMax-2, clockMin+2),
 	)
 }
+
+func TestCountClocks(t *testing.T) {
+	assert.Equal(t, int64(0), countClocks(10, 10))
+	assert.Equal(t, int64(3), countClocks(10, 13))
+	assert.Equal(t, int64(3), countClocks(-2, 1))
+	assert.Equal(t, int64(1), countClocks(clockMax, clockMin))
+	assert.Equal(t, int64(4), countClocks(clockMax-2, clockMin+1))
+	assert.Equal(t, int64(len(iterateClocks(100, clockMax-2, clockMin+1))), countClocks(clockMax-2, clockMin+1))
+}
+
+func TestPrevNextClock(t *testing.T) {
+	assert.Equal(t, channelClock(9), prevClock(10))
+	assert.Equal(t, channelClock(11), nextClock(10))
+	assert.Equal(t, clockMax, prevClock(clockMin))
+	assert.Equal(t, clockMin, nextClock(clockMax))
+}
+
+func TestClockBefore(t *testing.T) {
+	assert.Equal(t, channelClock(10), clockBefore(10, 0))
+	assert.Equal(t, channelClock(7), clockBefore(10, 3))
+	assert.Equal(t, clockMax, clockBefore(clockMin, 1))
+	assert.Equal(t, prevClock(clockMax), clockBefore(clockMin+1, 3))
+	assert.Equal(t, clockMin+1, clockBefore(clockMin, int64(clockMax)-int64(clockMin)))
+}
@@ -0,0 +1,183 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+// Count of message bodies to get with one MGET on export
+const exportBatchSize = 1000
+
+func (s *redisStorage) ExportChannel(ctx context.Context, channelID domain.ChannelID) (domain.ChannelExport, error) {
+	scanned, err := scanSubscriberIDs(ctx, s.RedisCmd, keyOfChannel(channelID).SubscriberCursorPattern(), locatorOfSubscriberCursorKey)
+	if err != nil {
+		return domain.ChannelExport{ChannelID: channelID}, xerrors.Errorf("ExportChannel failed due to Redis error (subscriber SCAN error): %w", err)
+	}
+	return s.exportChannel(ctx, channelID, scanned[channelID])
+}
+
+// ExportChannels SCANs channels and subscribers only once, rather than for each channel.
+func (s *redisStorage) ExportChannels(ctx context.Context, fn func(domain.ChannelExport) error) error {
+	channelIDs, err := scanChannelIDs(ctx, s.RedisCmd, clockKeyPattern, channelIDOfClockKey)
+	if err != nil {
+		return xerrors.Errorf("ExportChannels failed due to Redis error (channel SCAN error): %w", err)
+	}
+	sbscIDs, err := scanSubscriberIDs(ctx, s.RedisCmd, subscriberCursorKeyPattern, locatorOfSubscriberCursorKey)
+	if err != nil {
+		return xerrors.Errorf("ExportChannels failed due to Redis error (subscriber SCAN error): %w", err)
+	}
+	for _, id := range channelIDs {
+		export, err := s.exportChannel(ctx, id, sbscIDs[id])
+		if err != nil {
+			return err
+		}
+		if len(export.Messages) == 0 && len(export.Subscribers) == 0 {
+			continue // Expired after SCAN
+		}
+		if err := fn(export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sbscIDs are subscribers found by SCAN, could contain expired ones.
+func (s *redisStorage) exportChannel(ctx context.Context, channelID domain.ChannelID, sbscIDs []domain.SubscriberID) (domain.ChannelExport, error) {
+	result := domain.ChannelExport{
+		ChannelID:   channelID,
+		Messages:    []domain.Message{},
+		Subscribers: []domain.SubscriberExport{},
+	}
+	keys := keyOfChannel(channelID)
+	value, err := s.RedisCmd.Get(ctx, keys.Clock())
+	if err != nil {
+		return result, xerrors.Errorf("ExportChannel failed due to Redis error (GET error): %w", err)
+	}
+	if value == nil {
+		return result, nil // Channel expired or not exists
+	}
+	chClock := parseChannelClock(*value)
+	if chClock == nil {
+		return result, xerrors.Errorf("ExportChannel found corrupted channel clock (chID: %s): %s", channelID, *value)
+	}
+
+	// Messages
+	retainedClocks, err := s.exportMessages(ctx, channelID, *chClock, &result)
+	if err != nil {
+		return result, err
+	}
+
+	// Subscribers
+	mGetKeys := make([]string, 0, 2*len(sbscIDs))
+	for _, sbscID := range sbscIDs {
+		mGetKeys = append(mGetKeys, keys.SubscriberCursor(sbscID))
+	}
+	for _, sbscID := range sbscIDs {
+		mGetKeys = append(mGetKeys, keys.QueueVisibilityTimeout(sbscID))
+	}
+	cursors, err := s.RedisCmd.MGet(ctx, mGetKeys...)
+	if err != nil {
+		return result, xerrors.Errorf("ExportChannel failed due to Redis error (cursor MGET error): %w", err)
+	}
+	for i, sbscID := range sbscIDs {
+		if cursors[i] == nil {
+			continue // Subscriber expired or removed after SCAN
+		}
+		sbscClock, filter := parseSubscriberCursor(*cursors[i])
+		if sbscClock == nil {
+			continue // Corrupted, FetchMessages also treats it as missing subscriber
+		}
+		consumed := 0
+		for _, clock := range retainedClocks {
+			if isClockWithin(clock, *sbscClock, *chClock) {
+				break
+			}
+			consumed++
+		}
+		export := domain.SubscriberExport{
+			SubscriberID: sbscID,
+			Filter:       filter,
+			Consumed:     consumed,
+		}
+		if vt := cursors[i+len(sbscIDs)]; vt != nil {
+			export.VisibilityTimeout = parseVisibilityTimeout(*vt)
+		}
+		result.Subscribers = append(result.Subscribers, export)
+	}
+	return result, nil
+}
+
+// exportMessages appends retained messages to the export from the oldest one, returns clocks of them.
+// Walks clocks from the oldest retained message with MGET (see findOldestMessageClock) instead of SCAN message keys.
+func (s *redisStorage) exportMessages(ctx context.Context, channelID domain.ChannelID, chClock channelClock, result *domain.ChannelExport) ([]channelClock, error) {
+	oldest, found, err := s.findOldestMessageClock(ctx, channelID, chClock)
+	if err != nil {
+		return nil, xerrors.Errorf("ExportChannel failed due to Redis error (msg MGET error): %w", err)
+	}
+	if !found {
+		return []channelClock{}, nil
+	}
+	keys := keyOfChannel(channelID)
+	retainedClocks := []channelClock{}
+	for from := prevClock(oldest); from != chClock; {
+		clocks := iterateClocks(exportBatchSize, from, chClock)
+		msgBodyKeys := make([]string, len(clocks))
+		for i, clock := range clocks {
+			msgBodyKeys[i] = keys.MessageBody(clock)
+		}
+		rawMsgs, err := s.RedisCmd.MGet(ctx, msgBodyKeys...)
+		if err != nil {
+			return nil, xerrors.Errorf("ExportChannel failed due to Redis error (msg MGET error): %w", err)
+		}
+		for i, raw := range rawMsgs {
+			if raw == nil {
+				continue // Expired after probing
+			}
+			msg, err := unwrapMessage(channelID, *raw)
+			if err != nil {
+				logger.Of(ctx).Error(fmt.Sprintf("Skipped corrupted message (chID: %s, clock: %d) on export", channelID, clocks[i]), err)
+				continue
+			}
+			result.Messages = append(result.Messages, *msg)
+			retainedClocks = append(retainedClocks, clocks[i])
+		}
+		from = clocks[len(clocks)-1]
+	}
+	return retainedClocks, nil
+}
+
+func (s *redisStorage) ExportRevokedJwts(ctx context.Context) ([]domain.RevokedJwt, error) {
+	keys, err := s.RedisCmd.Scan(ctx, revocationKeyPattern)
+	if err != nil {
+		return nil, xerrors.Errorf("ExportRevokedJwts failed due to Redis error (SCAN error): %w", err)
+	}
+	sort.Strings(keys)
+	result := make([]domain.RevokedJwt, 0, len(keys))
+	for i, key := range keys {
+		jti, ok := jtiOfRevocationKey(key)
+		if !ok || (i > 0 && keys[i-1] == key) {
+			continue // SCAN could return duplicated keys
+		}
+		// Do not use MGET because keys belong to different slots in Redis Cluster
+		value, err := s.RedisCmd.Get(ctx, key)
+		if err != nil {
+			return nil, xerrors.Errorf("ExportRevokedJwts failed due to Redis error (GET error): %w", err)
+		}
+		if value == nil {
+			continue // Expired after SCAN
+		}
+		exp, err := domain.ParseJwtExp(*value)
+		if err != nil {
+			logger.Of(ctx).Error(fmt.Sprintf("Skipped corrupted JWT revocation (jti: %s) on export", jti), err)
+			continue
+		}
+		result = append(result, domain.RevokedJwt{Jti: jti, Exp: exp})
+	}
+	return result, nil
+}
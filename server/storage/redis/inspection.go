@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	internal "github.com/m3dev/dsps/server/storage/redis/internal"
+)
+
+func (s *redisStorage) InspectChannels(ctx context.Context, channelID domain.ChannelID) ([]domain.ChannelInspection, error) {
+	channelIDs := []domain.ChannelID{channelID}
+	cursorPattern := keyOfChannel(channelID).SubscriberCursorPattern()
+	if channelID == "" {
+		cursorPattern = subscriberCursorKeyPattern
+		var err error
+		channelIDs, err = scanChannelIDs(ctx, s.RedisCmd, clockKeyPattern, channelIDOfClockKey)
+		if err != nil {
+			return nil, xerrors.Errorf("InspectChannels failed due to Redis error (channel SCAN error): %w", err)
+		}
+	}
+	sbscIDs, err := scanSubscriberIDs(ctx, s.RedisCmd, cursorPattern, locatorOfSubscriberCursorKey)
+	if err != nil {
+		return nil, xerrors.Errorf("InspectChannels failed due to Redis error (subscriber SCAN error): %w", err)
+	}
+
+	result := make([]domain.ChannelInspection, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		inspection, err := s.inspectChannel(ctx, id, sbscIDs[id])
+		if err != nil {
+			return nil, err
+		}
+		if inspection != nil {
+			result = append(result, *inspection)
+		}
+	}
+	return result, nil
+}
+
+// scanChannelIDs finds channels with one SCAN, returns sorted channel IDs.
+func scanChannelIDs(ctx context.Context, redisCmd internal.RedisCmd, pattern string, channelIDOf func(key string) (domain.ChannelID, bool)) ([]domain.ChannelID, error) {
+	keys, err := redisCmd.Scan(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.ChannelID, 0, len(keys))
+	found := make(map[domain.ChannelID]bool, len(keys))
+	for _, key := range keys {
+		if id, ok := channelIDOf(key); ok && !found[id] {
+			found[id] = true
+			result = append(result, id)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result, nil
+}
+
+// scanSubscriberIDs finds subscribers with one SCAN, returns sorted subscriber IDs of each channel.
+// SCAN iterates the whole keyspace regardless of the pattern, use pattern of all channels rather than calling this for each channel.
+func scanSubscriberIDs(ctx context.Context, redisCmd internal.RedisCmd, pattern string, locatorOf func(key string) (domain.SubscriberLocator, bool)) (map[domain.ChannelID][]domain.SubscriberID, error) {
+	keys, err := redisCmd.Scan(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	result := map[domain.ChannelID][]domain.SubscriberID{}
+	found := make(map[domain.SubscriberLocator]bool, len(keys))
+	for _, key := range keys {
+		sl, ok := locatorOf(key)
+		if !ok || found[sl] {
+			continue // SCAN could return duplicated keys
+		}
+		found[sl] = true
+		result[sl.ChannelID] = append(result[sl.ChannelID], sl.SubscriberID)
+	}
+	for _, ids := range result {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return result, nil
+}
+
+// Returns nil if the channel does not exist.
+// sbscIDs are subscribers found by SCAN, could contain expired ones.
+func (s *redisStorage) inspectChannel(ctx context.Context, channelID domain.ChannelID, sbscIDs []domain.SubscriberID) (*domain.ChannelInspection, error) {
+	keys := keyOfChannel(channelID)
+	mGetKeys := make([]string, 0, 1+2*len(sbscIDs))
+	mGetKeys = append(mGetKeys, keys.Clock())
+	for _, sbscID := range sbscIDs {
+		mGetKeys = append(mGetKeys, keys.SubscriberCursor(sbscID))
+	}
+	for _, sbscID := range sbscIDs {
+		mGetKeys = append(mGetKeys, keys.QueueVisibilityTimeout(sbscID))
+	}
+
+	values, err := s.RedisCmd.MGet(ctx, mGetKeys...)
+	if err != nil {
+		return nil, xerrors.Errorf("InspectChannels failed due to Redis error (MGET error): %w", err)
+	}
+	if values[0] == nil {
+		return nil, nil // Channel expired or not exists
+	}
+	chClock := parseChannelClock(*values[0])
+	if chClock == nil {
+		return nil, xerrors.Errorf("InspectChannels found corrupted channel clock (chID: %s): %s", channelID, *values[0])
+	}
+	chTTL, err := s.inspectTTL(ctx, keys.Clock())
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &domain.ChannelInspection{
+		ChannelID:   channelID,
+		Clock:       int64(*chClock),
+		TTL:         chTTL,
+		Subscribers: make([]domain.SubscriberInspection, 0, len(sbscIDs)),
+	}
+	for i, sbscID := range sbscIDs {
+		value := values[i+1]
+		if value == nil {
+			continue // Subscriber expired or removed after SCAN
+		}
+		sbscClock, filter := parseSubscriberCursor(*value)
+		if sbscClock == nil {
+			continue // Corrupted, FetchMessages also treats it as missing subscriber
+		}
+		ttl, err := s.inspectTTL(ctx, keys.SubscriberCursor(sbscID))
+		if err != nil {
+			return nil, err
+		}
+		si := domain.SubscriberInspection{
+			SubscriberID: sbscID,
+			Clock:        int64(*sbscClock),
+			Backlog:      countClocks(*sbscClock, *chClock),
+			TTL:          ttl,
+			Filter:       filter,
+		}
+		if vt := values[i+1+len(sbscIDs)]; vt != nil {
+			si.VisibilityTimeout = parseVisibilityTimeout(*vt)
+		}
+		if si.VisibilityTimeout != nil {
+			if si.Leased, err = s.inspectLeased(ctx, keys.QueueLeases(sbscID)); err != nil {
+				return nil, err
+			}
+		}
+		inspection.Subscribers = append(inspection.Subscribers, si)
+	}
+	return inspection, nil
+}
+
+// Returns count of unexpired leases of the queue subscriber.
+func (s *redisStorage) inspectLeased(ctx context.Context, key string) (int64, error) {
+	leases, err := s.RedisCmd.HGetAll(ctx, key)
+	if err != nil {
+		return 0, xerrors.Errorf("InspectChannels failed due to Redis error (lease HGETALL error): %w", err)
+	}
+	now := s.clock.Now().Time
+	var count int64
+	for _, lease := range leases {
+		if expireAt, ok := parseQueueLeaseExpiry(lease); ok && expireAt.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *redisStorage) inspectTTL(ctx context.Context, key string) (*domain.Duration, error) {
+	ttl, err := s.RedisCmd.TTL(ctx, key)
+	if err != nil {
+		return nil, xerrors.Errorf("InspectChannels failed due to Redis error (TTL error): %w", err)
+	}
+	if ttl == nil || *ttl < 0 {
+		return nil, nil // Key has no TTL or already removed
+	}
+	return &domain.Duration{Duration: ttl.Truncate(time.Second)}, nil
+}
@@ -125,6 +125,7 @@ func (d *dispatcher) Await(ctx context.Context, channel RedisChannelID) (RedisPu
 		d.awaiters[channel] = chain
 	}
 	chain[id] = result
+	d.telemetry.AddRedisDispatcherAwaiters(1)
 
 	return result, func(err error) {
 		d.reject(channel, id, err)
@@ -142,6 +143,7 @@ func (d *dispatcher) rejectAll(err error) {
 		for _, awaiter := range awaiters {
 			awaiter.Reject(err)
 		}
+		d.telemetry.AddRedisDispatcherAwaiters(-len(awaiters))
 	}
 	d.awaiters = make(map[RedisChannelID]map[awaiterID]RedisPubSubPromise)
 }
@@ -163,6 +165,23 @@ func (d *dispatcher) reject(channel RedisChannelID, id awaiterID, err error) {
 
 	awaiter.Reject(err)
 	delete(chain, id)
+	d.telemetry.AddRedisDispatcherAwaiters(-1)
+}
+
+// Resolve all awaiters to let them re-check messages (spurious wakeup).
+func (d *dispatcher) resolveAll() {
+	d.backgroundLogger.Debugf(logger.CatStorage, `RedisPubSubDispatcher.resolveAll`)
+
+	d.awaitersLock.Lock()
+	defer d.awaitersLock.Unlock()
+
+	for _, awaiters := range d.awaiters {
+		for _, awaiter := range awaiters {
+			awaiter.Resolve()
+		}
+		d.telemetry.AddRedisDispatcherAwaiters(-len(awaiters))
+	}
+	d.awaiters = make(map[RedisChannelID]map[awaiterID]RedisPubSubPromise)
 }
 
 func (d *dispatcher) resolve(channel RedisChannelID) {
@@ -174,6 +193,7 @@ func (d *dispatcher) resolve(channel RedisChannelID) {
 	for _, awaiter := range d.awaiters[channel] {
 		awaiter.Resolve()
 	}
+	d.telemetry.AddRedisDispatcherAwaiters(-len(d.awaiters[channel]))
 	delete(d.awaiters, channel)
 }
 
@@ -238,10 +258,14 @@ func (d *dispatcher) reconcileCycle() bool {
 
 	if !d.checkWorkerLiveness(ctx) {
 		if err := d.repairWorker(ctx); err != nil {
+			d.telemetry.CountRedisDispatcherReconcile(telemetry.RedisDispatcherReconcileRepairFailed)
 			logger.Of(ctx).WarnError(logger.CatStorage, `failed to (re-)establish Redis PSUBSCRIBE stream`, err)
 			return false
 		}
+		d.telemetry.CountRedisDispatcherReconcile(telemetry.RedisDispatcherReconcileRepaired)
+		return true
 	}
+	d.telemetry.CountRedisDispatcherReconcile(telemetry.RedisDispatcherReconcileHealthy)
 	return true
 }
 
@@ -260,6 +284,7 @@ func (d *dispatcher) checkWorkerLiveness(ctx context.Context) bool {
 	if err := worker.CheckAvailability(ctx); err != nil {
 		err = fmt.Errorf("Redis PSUBSCRIBE connection down (may overlooked Redis PUBLISH message lost), subscription interrupted: %w", err)
 		logger.Of(ctx).Warnf(logger.CatStorage, `%v`, err)
+		d.telemetry.CountRedisDispatcherReconcile(telemetry.RedisDispatcherReconcileConnectionDown)
 
 		// Because subscription connection down, may overlooked some messages.
 		// So that notify awaiters that subscription interrupted.
@@ -282,6 +307,11 @@ func (d *dispatcher) terminateWorker(ctx context.Context) {
 func (d *dispatcher) repairWorker(ctx context.Context) error {
 	newWorker, err := newWorker(ctx, d.psubscribe, d.pattern, func(m *redis.Message) {
 		d.resolve(RedisChannelID(m.Channel))
+	}, func() {
+		// Connection had been switched (e.g. Redis Sentinel failover), may overlooked some messages during reconnect.
+		// Wake up all awaiters so that they fetch messages from the Redis again.
+		d.backgroundLogger.Warnf(logger.CatStorage, `Redis PSUBSCRIBE connection has been re-established (may overlooked Redis PUBLISH message during reconnect)`)
+		d.resolveAll()
 	})
 	if err != nil {
 		return err
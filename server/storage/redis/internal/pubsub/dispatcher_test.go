@@ -7,9 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/m3dev/dsps/server/storage/deps"
 	storagetesting "github.com/m3dev/dsps/server/storage/deps/testing"
+	"github.com/m3dev/dsps/server/telemetry"
 )
 
 func TestDispatcherParams(t *testing.T) {
@@ -100,12 +103,70 @@ func TestDispatcherConnectionDown(t *testing.T) {
 	}
 }
 
+func TestDispatcherResubscribe(t *testing.T) {
+	ctx := context.Background()
+	pubsub := newRedisRawPubSubStub(t).EnqueueDefaultSubscribeMessage().EnqueuePingResultForever(nil)
+	dispatcher, pubsubActivated := newDispatcher(t, pubsub)
+	defer func() {
+		dispatcher.Shutdown(ctx)
+		time.Sleep(10 * time.Millisecond) // Wait until background processes exits
+	}()
+	<-pubsubActivated
+
+	{ // redis.PubSub reconnected to another node (e.g. Sentinel master switch)
+		await1, _ := dispatcher.Await(ctx, "ch-1")
+		await2, _ := dispatcher.Await(ctx, "ch-2")
+		pubsub.EnqueueMessage(&redis.Subscription{Kind: "psubscribe"})
+		<-await1.Chan() // Should be woken up to re-check messages
+		<-await2.Chan()
+		assert.NoError(t, await1.Err())
+		assert.NoError(t, await2.Err())
+	}
+
+	{ // Same connection keeps working
+		await, _ := dispatcher.Await(ctx, "ch-1")
+		pubsub.EnqueueEvent("ch-1")
+		<-await.Chan() // Should receive message
+		assert.NoError(t, await.Err())
+	}
+}
+
+func TestDispatcherMetrics(t *testing.T) {
+	ctx := context.Background()
+	result := telemetry.WithStubMetrics(t, func(tm *telemetry.Telemetry) {
+		deps := storagetesting.EmptyDeps(t)
+		deps.Telemetry = tm
+		pubsub := newRedisRawPubSubStub(t).EnqueueDefaultSubscribeMessage().EnqueuePingResultForever(nil)
+		dispatcher, pubsubActivated := newDispatcherWithDeps(t, deps, pubsub)
+		<-pubsubActivated
+
+		await1, cancel1 := dispatcher.Await(ctx, "ch-1")
+		await2, _ := dispatcher.Await(ctx, "ch-1")
+		_, _ = dispatcher.Await(ctx, "ch-2")
+		cancel1(errors.New("test error"))
+		<-await1.Chan()
+		pubsub.EnqueueEvent("ch-1")
+		<-await2.Chan()
+		time.Sleep(150 * time.Millisecond) // Wait next reconcile cycle
+
+		dispatcher.Shutdown(ctx)
+		time.Sleep(10 * time.Millisecond) // Wait until background processes exits
+	})
+	assert.Equal(t, 0.0, result.Value("dsps_redis_dispatcher_awaiters", map[string]string{})) // ch-2 awaiter rejected by shutdown
+	assert.Equal(t, 1.0, result.Value("dsps_redis_dispatcher_reconciles_total", map[string]string{"event": "repaired"}))
+	assert.LessOrEqual(t, 1.0, result.Value("dsps_redis_dispatcher_reconciles_total", map[string]string{"event": "healthy"}))
+}
+
 func newDispatcher(t *testing.T, pubsubStubs ...*redisRawPubSubStub) (RedisPubSubDispatcher, chan *redisRawPubSubStub) {
+	return newDispatcherWithDeps(t, storagetesting.EmptyDeps(t), pubsubStubs...)
+}
+
+func newDispatcherWithDeps(t *testing.T, deps deps.StorageDeps, pubsubStubs ...*redisRawPubSubStub) (RedisPubSubDispatcher, chan *redisRawPubSubStub) {
 	activeStub := int32(0)
 	stubActivated := make(chan *redisRawPubSubStub, len(pubsubStubs))
 	return NewDispatcher(
 		context.Background(),
-		storagetesting.EmptyDeps(t),
+		deps,
 		DispatcherParams{
 			ReconcileInterval:        100 * time.Millisecond,
 			ReconcileRetryInterval:   100 * time.Millisecond,
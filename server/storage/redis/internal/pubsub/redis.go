@@ -2,8 +2,6 @@ package pubsub
 
 import (
 	"context"
-
-	"github.com/go-redis/redis/v8"
 )
 
 // RedisSubscribeRawFunc represents (P)SUBSCRIBE command implementation.
@@ -16,6 +14,6 @@ type RedisChannelID string
 type RedisRawPubSub interface {
 	Receive(context.Context) (interface{}, error)
 	Ping(context.Context, ...string) error
-	ChannelSize(int) <-chan *redis.Message
+	ChannelWithSubscriptions(context.Context, int) <-chan interface{}
 	Close() error
 }
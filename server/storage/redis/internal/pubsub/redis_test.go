@@ -25,7 +25,7 @@ type redisRawPubSubStub struct {
 
 	channelInit  sync.Once
 	channelClose sync.Once
-	channel      chan *redis.Message
+	channel      chan interface{}
 }
 
 func newRedisRawPubSubStub(t *testing.T) *redisRawPubSubStub {
@@ -101,14 +101,14 @@ func (s *redisRawPubSubStub) receive() (interface{}, error) {
 	}
 }
 
-func (s *redisRawPubSubStub) ChannelSize(size int) <-chan *redis.Message {
+func (s *redisRawPubSubStub) ChannelWithSubscriptions(ctx context.Context, size int) <-chan interface{} {
 	isValidCall := false
 	s.channelInit.Do(func() {
 		isValidCall = true
-		s.channel = make(chan *redis.Message, size)
+		s.channel = make(chan interface{}, size)
 		go s.receiveWorker()
 	})
-	assert.True(s.t, isValidCall, "do not call RedisRawPubSub.ChannelSize() twice")
+	assert.True(s.t, isValidCall, "do not call RedisRawPubSub.ChannelWithSubscriptions() twice")
 	return s.channel
 }
 
@@ -125,10 +125,10 @@ func (s *redisRawPubSubStub) receiveWorker() {
 			s.t.Logf("background receive worker of RedisRawPubSub stopped because of: %v", err)
 			return
 		}
-		switch value := raw.(type) {
-		case *redis.Message:
+		switch raw.(type) {
+		case *redis.Message, *redis.Subscription:
 			select {
-			case s.channel <- value:
+			case s.channel <- raw:
 			default:
 				s.t.Logf("background receive worker of RedisRawPubSub could not pass message to channel because closed.")
 			}
@@ -19,7 +19,8 @@ type worker interface {
 }
 
 type workerImpl struct {
-	handler func(*redis.Message)
+	handler       func(*redis.Message)
+	onResubscribe func()
 
 	redisPubSub RedisRawPubSub
 
@@ -36,10 +37,12 @@ type workerImpl struct {
 // Size of channel that redis.PubSub internally creates
 const redisPubSubChannelSize = 100
 
-func newWorker(ctx context.Context, psubscribe RedisSubscribeRawFunc, pattern RedisChannelID, handler func(*redis.Message)) (newWorker worker, err error) {
+// onResubscribe is called when redis.PubSub silently reconnected (e.g. Redis Sentinel master switch) and made PSUBSCRIBE again.
+func newWorker(ctx context.Context, psubscribe RedisSubscribeRawFunc, pattern RedisChannelID, handler func(*redis.Message), onResubscribe func()) (newWorker worker, err error) {
 	w := &workerImpl{
-		handler:     handler,
-		redisPubSub: psubscribe(ctx, pattern),
+		handler:       handler,
+		onResubscribe: onResubscribe,
+		redisPubSub:   psubscribe(ctx, pattern),
 
 		shutdownRequestCh: make(chan interface{}),
 		workerMainEnded:   make(chan interface{}),
@@ -133,7 +136,7 @@ func (w *workerImpl) shutdown(ctx context.Context, block bool) {
 func (w *workerImpl) workerMain() {
 	defer close(w.workerMainEnded)
 
-	ch := w.redisPubSub.ChannelSize(redisPubSubChannelSize)
+	ch := w.redisPubSub.ChannelWithSubscriptions(context.Background(), redisPubSubChannelSize)
 	for {
 		select {
 		case <-w.shutdownRequestCh:
@@ -143,7 +146,16 @@ func (w *workerImpl) workerMain() {
 				go w.shutdown(context.Background(), true)
 				return
 			}
-			w.handler(msg)
+			switch msg := msg.(type) {
+			case *redis.Message:
+				w.handler(msg)
+			case *redis.Subscription:
+				// Initial PSUBSCRIBE response has been consumed by the constructor,
+				// so that this is a response of re-subscription after reconnect.
+				if msg.Kind == "psubscribe" {
+					w.onResubscribe()
+				}
+			}
 		}
 	}
 }
@@ -37,6 +37,33 @@ func TestWorkerHandlerCall(t *testing.T) {
 	}
 }
 
+func TestWorkerResubscribe(t *testing.T) {
+	ctx := context.Background()
+	lastReceived := make(chan *redis.Message, 1024)
+	resubscribed := make(chan interface{}, 1024)
+	worker, pubsub := newHealthyWorkerWithResubscribeHandler(t, func(m *redis.Message) {
+		lastReceived <- m
+	}, func() {
+		resubscribed <- struct{}{}
+	})
+	defer worker.Shutdown(ctx)
+
+	// redis.PubSub reconnected (e.g. Sentinel master switch) and made PSUBSCRIBE again
+	pubsub.EnqueueMessage(&redis.Subscription{Kind: "psubscribe"})
+	select {
+	case <-resubscribed: // OK
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "resubscribe handler not called")
+	}
+
+	// Worker keeps working after resubscribe
+	msg := &redis.Message{Channel: "channel ID", Payload: "foo bar"}
+	pubsub.EnqueueMessage(msg)
+	received := <-lastReceived
+	assert.Same(t, msg, received)
+	assert.Len(t, resubscribed, 0)
+}
+
 func TestWorkerConnectionDown(t *testing.T) {
 	lastReceived := make(chan *redis.Message, 1024)
 	_, pubsub := newHealthyWorker(t, func(m *redis.Message) {
@@ -114,7 +141,7 @@ func TestWorkerInitialReceiveFailure(t *testing.T) {
 	pubsub.EnqueueMessage(err)
 	_, actualErr := newWorker(context.Background(), func(ctx context.Context, channel RedisChannelID) RedisRawPubSub {
 		return pubsub
-	}, "*", func(m *redis.Message) {})
+	}, "*", func(m *redis.Message) {}, func() {})
 	dspstesting.IsError(t, err, actualErr)
 }
 
@@ -125,7 +152,7 @@ func TestWorkerInitialReceiveInvalidMessage(t *testing.T) {
 	pubsub.EnqueueMessage(struct{}{})
 	_, actualErr := newWorker(context.Background(), func(ctx context.Context, channel RedisChannelID) RedisRawPubSub {
 		return pubsub
-	}, "*", func(m *redis.Message) {})
+	}, "*", func(m *redis.Message) {}, func() {})
 	assert.Regexp(t, `Unexpected response from Redis Pub/Sub subscription`, actualErr.Error())
 }
 
@@ -140,20 +167,27 @@ func TestWorkerInitialPingFailure(t *testing.T) {
 	pubsub.EnqueuePingResult(1, err)
 	_, actualErr := newWorker(context.Background(), func(ctx context.Context, channel RedisChannelID) RedisRawPubSub {
 		return pubsub
-	}, "*", func(m *redis.Message) {})
+	}, "*", func(m *redis.Message) {}, func() {})
 	dspstesting.IsError(t, err, actualErr)
 }
 
 func newHealthyWorker(t *testing.T, handler func(*redis.Message)) (worker worker, pubsub *redisRawPubSubStub) {
+	return newHealthyWorkerWithResubscribeHandler(t, handler, nil)
+}
+
+func newHealthyWorkerWithResubscribeHandler(t *testing.T, handler func(*redis.Message), onResubscribe func()) (worker worker, pubsub *redisRawPubSubStub) {
 	pubsub = newRedisRawPubSubStub(t).EnqueueDefaultSubscribeMessage()
 	pubsub.EnqueuePingResult(1, nil) // constructor calls PING once.
 
 	if handler == nil {
 		handler = func(m *redis.Message) {}
 	}
+	if onResubscribe == nil {
+		onResubscribe = func() {}
+	}
 	worker, err := newWorker(context.Background(), func(ctx context.Context, channel RedisChannelID) RedisRawPubSub {
 		return pubsub
-	}, "*", handler)
+	}, "*", handler, onResubscribe)
 	assert.NoError(t, err)
 
 	// Register shutdown hook to make sure called
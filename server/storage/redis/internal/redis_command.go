@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -12,6 +13,9 @@ import (
 
 //go:generate mockgen -source=${GOFILE} -package=mock -destination=./mock/${GOFILE}
 
+// COUNT hint of SCAN command
+const scanCount = 1000
+
 // RedisCmd wraps Redis command system
 type RedisCmd interface {
 	Ping(ctx context.Context) error
@@ -27,6 +31,18 @@ type RedisCmd interface {
 	Set(ctx context.Context, key string, value interface{}) error
 	SetEX(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Del(ctx context.Context, key string) error
+	// Returns all keys matched with the pattern, scans all master nodes in case of Redis Cluster.
+	// Note that returned keys could contain duplicates because SCAN command does not guarantee uniqueness.
+	Scan(ctx context.Context, match string) ([]string, error)
+
+	HMGet(ctx context.Context, key string, fields ...string) ([]*string, error)
+	// Returns empty map if the key does not exist
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+
+	// Returns entries of the stream in the range [start, end], count 0 means no limit
+	XRange(ctx context.Context, key string, start string, end string, count int64) ([]redis.XMessage, error)
+	// Blocks until the stream has an entry newer than afterID or timeout, returns true if such entry found
+	XReadBlock(ctx context.Context, key string, afterID string, timeout time.Duration) (bool, error)
 
 	LoadScript(ctx context.Context, script *redis.Script) error
 	RunScript(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error)
@@ -109,6 +125,82 @@ func (impl *redisCmdImpl) Del(ctx context.Context, key string) error {
 	return impl.raw.Del(ctx, key).Err()
 }
 
+func (impl *redisCmdImpl) Scan(ctx context.Context, match string) ([]string, error) {
+	if cluster, ok := impl.raw.(*redis.ClusterClient); ok {
+		var lock sync.Mutex
+		result := []string{}
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			keys, err := scanAll(ctx, client, match)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			result = append(result, keys...)
+			return nil
+		})
+		return result, err
+	}
+	return scanAll(ctx, impl.raw, match)
+}
+
+func scanAll(ctx context.Context, raw redis.Cmdable, match string) ([]string, error) {
+	result := []string{}
+	iter := raw.Scan(ctx, 0, match, scanCount).Iterator()
+	for iter.Next(ctx) {
+		result = append(result, iter.Val())
+	}
+	return result, iter.Err()
+}
+
+func (impl *redisCmdImpl) HMGet(ctx context.Context, key string, fields ...string) ([]*string, error) {
+	if len(fields) == 0 { // Redis does not allow 0-length HMGET
+		return []*string{}, nil
+	}
+
+	raws, err := impl.raw.HMGet(ctx, key, fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*string, len(raws))
+	for i, raw := range raws {
+		if raw != nil {
+			str := raw.(string)
+			result[i] = &str
+		}
+	}
+	return result, nil
+}
+
+func (impl *redisCmdImpl) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return impl.raw.HGetAll(ctx, key).Result()
+}
+
+func (impl *redisCmdImpl) XRange(ctx context.Context, key string, start string, end string, count int64) ([]redis.XMessage, error) {
+	if count > 0 {
+		return impl.raw.XRangeN(ctx, key, start, end, count).Result()
+	}
+	return impl.raw.XRange(ctx, key, start, end).Result()
+}
+
+func (impl *redisCmdImpl) XReadBlock(ctx context.Context, key string, afterID string, timeout time.Duration) (bool, error) {
+	if timeout < time.Millisecond {
+		timeout = time.Millisecond // BLOCK 0 means infinite
+	}
+	streams, err := impl.raw.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{key, afterID},
+		Count:   1,
+		Block:   timeout,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil // Timed out
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(streams) > 0 && len(streams[0].Messages) > 0, nil
+}
+
 func (impl *redisCmdImpl) LoadScript(ctx context.Context, script *redis.Script) error {
 	return script.Load(ctx, impl.raw).Err()
 }
@@ -2,13 +2,16 @@ package internal
 
 import (
 	"context"
+	"crypto/tls"
 
 	"github.com/go-redis/redis/extra/redisotel"
 	"github.com/go-redis/redis/v8"
+	"golang.org/x/xerrors"
 
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/logger"
 	"github.com/m3dev/dsps/server/storage/redis/internal/pubsub"
+	"github.com/m3dev/dsps/server/tlsconfig"
 )
 
 // RedisConnection represents Redis connection system
@@ -18,17 +21,26 @@ type RedisConnection struct {
 
 	IsSingleNode bool
 	IsCluster    bool
+	IsSentinel   bool
 
 	MaxConnections int
 }
 
 // NewRedisConnection establish connection pool to Redis server.
 func NewRedisConnection(ctx context.Context, config *config.RedisStorageConfig) (RedisConnection, error) {
+	tlsConfig, err := newRedisTLSConfig(config.TLS)
+	if err != nil {
+		return RedisConnection{}, xerrors.Errorf("Failed to setup TLS of Redis connection: %w", err)
+	}
+
+	// Because PSUBSCRIBE connection is also made by the client, TLS setting applies to it as well.
 	var conn RedisConnection
 	if config.SingleNode != nil {
-		conn = createClientSingleNode(ctx, config)
+		conn = createClientSingleNode(ctx, config, tlsConfig)
+	} else if config.Sentinel != nil {
+		conn = createClientSentinel(ctx, config, tlsConfig)
 	} else {
-		conn = createClientCluster(ctx, config)
+		conn = createClientCluster(ctx, config, tlsConfig)
 	}
 	if err := conn.RedisCmd.Ping(ctx); err != nil {
 		if err := conn.Close(); err != nil {
@@ -39,7 +51,7 @@ func NewRedisConnection(ctx context.Context, config *config.RedisStorageConfig)
 	return conn, nil
 }
 
-func createClientSingleNode(ctx context.Context, config *config.RedisStorageConfig) RedisConnection {
+func createClientSingleNode(ctx context.Context, config *config.RedisStorageConfig, tlsConfig *tls.Config) RedisConnection {
 	c := redis.NewClient(&redis.Options{
 		Addr: *config.SingleNode,
 
@@ -47,6 +59,8 @@ func createClientSingleNode(ctx context.Context, config *config.RedisStorageConf
 		Username: config.Username,
 		Password: config.Password,
 
+		TLSConfig: tlsConfig,
+
 		DialTimeout:  config.Timeout.Connect.Duration,
 		ReadTimeout:  config.Timeout.Read.Duration,
 		WriteTimeout: config.Timeout.Write.Duration,
@@ -72,13 +86,54 @@ func createClientSingleNode(ctx context.Context, config *config.RedisStorageConf
 	}
 }
 
-func createClientCluster(ctx context.Context, config *config.RedisStorageConfig) RedisConnection {
+func createClientSentinel(ctx context.Context, config *config.RedisStorageConfig, tlsConfig *tls.Config) RedisConnection {
+	c := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:       config.Sentinel.MasterName,
+		SentinelAddrs:    config.Sentinel.Addrs,
+		SentinelPassword: config.Sentinel.Password,
+
+		DB:       config.DBNumber,
+		Username: config.Username,
+		Password: config.Password,
+
+		TLSConfig: tlsConfig,
+
+		DialTimeout:  config.Timeout.Connect.Duration,
+		ReadTimeout:  config.Timeout.Read.Duration,
+		WriteTimeout: config.Timeout.Write.Duration,
+
+		MaxRetries:      *config.Retry.Count,
+		MinRetryBackoff: config.Retry.Interval.Duration - config.Retry.IntervalJitter.Duration,
+		MaxRetryBackoff: config.Retry.Interval.Duration + config.Retry.IntervalJitter.Duration,
+
+		MinIdleConns: *config.Connection.Min,
+		PoolSize:     *config.Connection.Max,
+		IdleTimeout:  config.Connection.MaxIdleTime.Duration,
+	})
+	c.AddHook(redisotel.TracingHook{})
+	return RedisConnection{
+		// On master switch, go-redis closes connections to the old master including PSUBSCRIBE one.
+		// redis.PubSub then reconnects to the new master and re-issues PSUBSCRIBE by itself (see pubsub.worker).
+		RedisCmd: NewRedisCmd(c, func(ctx context.Context, channel pubsub.RedisChannelID) pubsub.RedisRawPubSub {
+			return c.PSubscribe(ctx, string(channel))
+		}),
+		Close: func() error {
+			return c.Close()
+		},
+		IsSentinel:     true,
+		MaxConnections: *config.Connection.Max,
+	}
+}
+
+func createClientCluster(ctx context.Context, config *config.RedisStorageConfig, tlsConfig *tls.Config) RedisConnection {
 	c := redis.NewClusterClient(&redis.ClusterOptions{
 		Addrs: *config.Cluster,
 
 		Username: config.Username,
 		Password: config.Password,
 
+		TLSConfig: tlsConfig,
+
 		DialTimeout:  config.Timeout.Connect.Duration,
 		ReadTimeout:  config.Timeout.Read.Duration,
 		WriteTimeout: config.Timeout.Write.Duration,
@@ -107,3 +162,18 @@ func createClientCluster(ctx context.Context, config *config.RedisStorageConfig)
 		MaxConnections: *config.Connection.Max,
 	}
 }
+
+// newRedisTLSConfig returns nil if TLS is disabled.
+func newRedisTLSConfig(config config.RedisTLSConfig) (*tls.Config, error) {
+	if !config.Enable {
+		return nil, nil
+	}
+
+	tlsConfig, err := tlsconfig.NewClientConfig(config.CAFile, config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ServerName = config.ServerName
+	tlsConfig.InsecureSkipVerify = config.InsecureSkipVerify //nolint:gosec // Explicitly enabled by configuration (e.g. for tests)
+	return tlsConfig, nil
+}
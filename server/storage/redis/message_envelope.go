@@ -9,14 +9,18 @@ import (
 )
 
 type messageEnvelope struct {
-	ID      domain.MessageID `json:"id"`
-	Content json.RawMessage  `json:"content"`
+	ID      domain.MessageID  `json:"id"`
+	Content json.RawMessage   `json:"content"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+	Trace   string            `json:"trace,omitempty"`
 }
 
 func wrapMessage(msg domain.Message) (string, error) {
 	data, err := json.Marshal(messageEnvelope{
 		ID:      msg.MessageID,
 		Content: msg.Content,
+		Attrs:   msg.Attributes,
+		Trace:   msg.TraceParent,
 	})
 	if err != nil {
 		return "", xerrors.Errorf(`%w: %v`, domain.ErrMalformedMessageJSON, err)
@@ -34,6 +38,8 @@ func unwrapMessage(ch domain.ChannelID, raw string) (*domain.Message, error) {
 			ChannelID: ch,
 			MessageID: envelope.ID,
 		},
-		Content: envelope.Content,
+		Content:     envelope.Content,
+		Attributes:  envelope.Attrs,
+		TraceParent: envelope.Trace,
 	}, nil
 }
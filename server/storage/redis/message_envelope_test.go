@@ -1,9 +1,12 @@
 package redis
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
 )
 
 func TestCorruptedMessageEnvelope(t *testing.T) {
@@ -11,3 +14,42 @@ func TestCorruptedMessageEnvelope(t *testing.T) {
 	_, err := unwrapMessage("ch-1", raw)
 	assert.Contains(t, err.Error(), "Failed to parse message envelope JSON")
 }
+
+func TestMessageEnvelopeAttributes(t *testing.T) {
+	wrapped, err := wrapMessage(domain.Message{
+		MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-1"},
+		Content:        json.RawMessage(`{"hi":"hello"}`),
+		Attributes:     domain.MessageAttributes{"event-type": "created"},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"msg-1","content":{"hi":"hello"},"attrs":{"event-type":"created"}}`, wrapped)
+
+	msg, err := unwrapMessage("ch-1", wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MessageAttributes{"event-type": "created"}, msg.Attributes)
+
+	// Envelope written by older version
+	msg, err = unwrapMessage("ch-1", `{"id":"msg-1","content":{}}`)
+	assert.NoError(t, err)
+	assert.Nil(t, msg.Attributes)
+}
+
+func TestMessageEnvelopeTraceParent(t *testing.T) {
+	traceParent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	wrapped, err := wrapMessage(domain.Message{
+		MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-1"},
+		Content:        json.RawMessage(`{}`),
+		TraceParent:    traceParent,
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"msg-1","content":{},"trace":"`+traceParent+`"}`, wrapped)
+
+	msg, err := unwrapMessage("ch-1", wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, traceParent, msg.TraceParent)
+
+	// Envelope written by older version
+	msg, err = unwrapMessage("ch-1", `{"id":"msg-1","content":{}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "", msg.TraceParent)
+}
@@ -33,7 +33,11 @@ func (s *redisStorage) PublishMessages(ctx context.Context, msgs []domain.Messag
 		if err != nil {
 			return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
 		}
-		if err := runPublishMessageScript(ctx, s.RedisCmd, ttl, msg); err != nil {
+		ch, err := s.channelProvider.Get(msg.ChannelID)
+		if err != nil {
+			return xerrors.Errorf("Unable to get backlog limit of channel: %w", err)
+		}
+		if err := runPublishMessageScript(ctx, s.RedisCmd, ttl, ch.BacklogLimit(), msg); err != nil {
 			return err
 		}
 		sentMsgs++
@@ -53,7 +57,8 @@ func (s *redisStorage) FetchMessages(ctx context.Context, sl domain.SubscriberLo
 		await, awaitCancel = s.pubsubDispatcher.Await(ctx, s.redisPubSubKeyOf(sl.ChannelID))
 	}
 
-	if messages, moreMessages, ackHandle, err = s.fetchMessagesNow(ctx, sl, max); err != nil || len(messages) > 0 {
+	var wakeAt time.Time
+	if messages, moreMessages, ackHandle, wakeAt, err = s.fetchMessagesNow(ctx, sl, max); err != nil || len(messages) > 0 {
 		return
 	}
 
@@ -64,18 +69,27 @@ func (s *redisStorage) FetchMessages(ctx context.Context, sl domain.SubscriberLo
 		if await != nil {
 			c = await.Chan()
 		}
+		// Leased messages of queue subscriber become visible without notification
+		var leaseExpiry <-chan time.Time
+		if !wakeAt.IsZero() {
+			leaseExpiry = time.After(wakeAt.Sub(s.clock.Now().Time))
+		}
 		select {
 		case <-timeout.C:
 			return
 		case <-ctx.Done():
 			err = ctx.Err()
 			return
+		case <-leaseExpiry:
+			if messages, moreMessages, ackHandle, wakeAt, err = s.fetchMessagesNow(ctx, sl, max); err != nil || len(messages) > 0 {
+				return
+			}
 		case <-c:
 			if await != nil && await.Err() != nil {
 				err = await.Err()
 				return
 			}
-			if messages, moreMessages, ackHandle, err = s.fetchMessagesNow(ctx, sl, max); err != nil || len(messages) > 0 {
+			if messages, moreMessages, ackHandle, wakeAt, err = s.fetchMessagesNow(ctx, sl, max); err != nil || len(messages) > 0 {
 				return
 			}
 			// Await again because no messages found (spurious wakeup)
@@ -84,9 +98,21 @@ func (s *redisStorage) FetchMessages(ctx context.Context, sl domain.SubscriberLo
 	}
 }
 
-func (s *redisStorage) fetchMessagesNow(ctx context.Context, sl domain.SubscriberLocator, max int) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, err error) {
+// wakeAt is non-zero only for queue subscriber, see fetchQueueMessagesNow.
+func (s *redisStorage) fetchMessagesNow(ctx context.Context, sl domain.SubscriberLocator, max int) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, wakeAt time.Time, err error) {
+	for {
+		var skipped bool
+		if messages, moreMessages, ackHandle, wakeAt, skipped, err = s.fetchMessageBatch(ctx, sl, max); err != nil || !skipped {
+			return
+		}
+		// All messages of the batch are skipped (filtered out or missing), fetch following ones
+	}
+}
+
+// skipped is true if all messages of the batch are skipped and following messages exist.
+func (s *redisStorage) fetchMessageBatch(ctx context.Context, sl domain.SubscriberLocator, max int) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, wakeAt time.Time, skipped bool, err error) {
 	keys := keyOfChannel(sl.ChannelID)
-	clocks, err := s.RedisCmd.MGet(ctx, keys.Clock(), keys.SubscriberCursor(sl.SubscriberID))
+	clocks, err := s.RedisCmd.MGet(ctx, keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID))
 	if err != nil {
 		err = xerrors.Errorf("FetchMessages failed due to Redis error (cursor MGET error): %w", err)
 		return
@@ -97,7 +123,7 @@ func (s *redisStorage) fetchMessagesNow(ctx context.Context, sl domain.Subscribe
 		return
 	}
 	chClock := parseChannelClock(*clocks[0])
-	sbscClock := parseChannelClock(*clocks[1])
+	sbscClock, filter := parseSubscriberCursor(*clocks[1])
 	if chClock == nil || sbscClock == nil {
 		err = domain.ErrSubscriptionNotFound
 		return
@@ -105,6 +131,11 @@ func (s *redisStorage) fetchMessagesNow(ctx context.Context, sl domain.Subscribe
 	if err := s.extendSubscriberTTL(ctx, sl); err != nil { // We could use GETEX (>= Redis 6.2.0) rather than issue MGET + EXPIRE in the future.
 		logger.Of(ctx).WarnError(logger.CatStorage, `Failed to extend TTL of channel clock entry and/or subscription clock entry of Redis`, err)
 	}
+	if clocks[2] != nil {
+		if vt := parseVisibilityTimeout(*clocks[2]); vt != nil {
+			return s.fetchQueueMessagesNow(ctx, sl, max, *chClock, *sbscClock, filter, *vt)
+		}
+	}
 
 	msgClocks := iterateClocks(max, *sbscClock, *chClock)
 	msgKeys := make([]string, len(msgClocks)) // Must same length with msgClocks
@@ -136,11 +167,45 @@ func (s *redisStorage) fetchMessagesNow(ctx context.Context, sl domain.Subscribe
 			}
 			continue // may caused by message TTL expiration
 		}
+		if !filter.Match(*msg) {
+			continue
+		}
 		messages = append(messages, *msg)
 		if lastMessageClock == nil || *lastMessageClock < msgClocks[i] {
 			lastMessageClock = &msgClocks[i]
 		}
 	}
+	if len(messages) == 0 && len(msgClocks) > 0 {
+		// All messages are filtered out or missing (evicted by backlog limit or expired), skip them to not scan them again.
+		skipTo := msgClocks[len(msgClocks)-1]
+		if moreMessages && rawMsgs[len(rawMsgs)-1] == nil {
+			// Retained messages are contiguous up to the channel clock, thus jump to the oldest one rather than scan missing clocks max by max.
+			oldest, found, err := s.findOldestMessageClock(ctx, sl.ChannelID, *chClock)
+			if err != nil {
+				return nil, false, domain.AckHandle{}, time.Time{}, false, xerrors.Errorf("FetchMessages failed due to Redis error (msg MGET error): %w", err)
+			}
+			if !found {
+				skipTo = *chClock
+				moreMessages = false
+			} else if isClockWithin(oldest, skipTo, *chClock) {
+				skipTo = prevClock(oldest)
+			}
+		}
+		var ttl channelTTLSec
+		if ttl, err = s.channelRedisTTLSec(sl.ChannelID); err != nil {
+			err = xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+			return
+		}
+		if _, err = runAckScript(ctx, s.RedisCmd, sl.ChannelID, ttl, sl.SubscriberID, skipTo); err != nil {
+			return
+		}
+		skipped = moreMessages
+		return
+	}
+	if filter != nil && len(msgClocks) > 0 {
+		// Acknowledging messages also skips filtered messages.
+		lastMessageClock = &msgClocks[len(msgClocks)-1]
+	}
 	if lastMessageClock != nil {
 		ackHandle = encodeAckHandle(sl, ackHandleData{
 			LastMessageClock: *lastMessageClock,
@@ -158,6 +223,9 @@ func (s *redisStorage) AcknowledgeMessages(ctx context.Context, handle domain.Ac
 	if err != nil {
 		return err
 	}
+	if h.LeaseID != "" {
+		return s.acknowledgeQueueMessages(ctx, handle, h)
+	}
 	_, err = runAckScript(ctx, s.RedisCmd, handle.ChannelID, ttl, handle.SubscriberID, h.LastMessageClock)
 	return err
 }
@@ -182,7 +250,7 @@ func (s *redisStorage) IsOldMessages(ctx context.Context, sl domain.SubscriberLo
 		return nil, xerrors.Errorf("%w (%v)", domain.ErrSubscriptionNotFound, err)
 	}
 	chCursor := parseChannelClock(*clocks[0])
-	sbscCursor := parseChannelClock(*clocks[1])
+	sbscCursor, _ := parseSubscriberCursor(*clocks[1])
 	if chCursor == nil || sbscCursor == nil {
 		return nil, xerrors.Errorf("%w (%v)", domain.ErrSubscriptionNotFound, err)
 	}
@@ -27,7 +27,7 @@ func TestFetchMessagesFirstPollingClockGetError(t *testing.T) {
 
 	// (1st fetchMessagesNow) MGET clock cursor
 	errToReturn := errors.New(`Mocked Redis error`)
-	redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(nil, errToReturn)
+	redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(nil, errToReturn)
 
 	_, _, _, err := s.FetchMessages(context.Background(), sl, 100, domain.Duration{Duration: 30 * time.Second})
 	dspstesting.IsError(t, errToReturn, err)
@@ -45,7 +45,7 @@ func TestFetchMessagesFirstPollingClockGetInvalidValues(t *testing.T) {
 	s, redisCmd, _ := newMockedRedisStorageAndPubSubDispatcher(ctrl)
 
 	// (1st fetchMessagesNow) MGET clock cursor
-	redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(strPList(t, "INVALID", "INVALID"), nil)
+	redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(append(strPList(t, "INVALID", "INVALID"), nil), nil)
 
 	_, _, _, err := s.FetchMessages(context.Background(), sl, 100, domain.Duration{Duration: 30 * time.Second})
 	dspstesting.IsError(t, domain.ErrSubscriptionNotFound, err)
@@ -62,7 +62,7 @@ func TestFetchMessagesFirstPollingGetMsgBodyError(t *testing.T) {
 	s, redisCmd, _ := newMockedRedisStorageAndPubSubDispatcher(ctrl)
 
 	// (1st fetchMessagesNow) MGET clock cursor
-	clocksMget := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(strPList(t, "12", "10"), nil)
+	clocksMget := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(append(strPList(t, "12", "10"), nil), nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.Clock(), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.SubscriberCursor(sl.SubscriberID), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	// (1st fetchMessagesNow) MGET msg1body msg2body
@@ -85,7 +85,7 @@ func TestFetchMessagesFirstPollingCorruptedMsgBody(t *testing.T) {
 	s, redisCmd, _ := newMockedRedisStorageAndPubSubDispatcher(ctrl)
 
 	// (1st fetchMessagesNow) MGET clock cursor
-	clocksMget := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(strPList(t, "13", "10"), nil)
+	clocksMget := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(append(strPList(t, "13", "10"), nil), nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.Clock(), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.SubscriberCursor(sl.SubscriberID), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	// (1st fetchMessagesNow) MGET msg1body msg2body
@@ -112,7 +112,7 @@ func TestFetchMessagesSecondPollingError(t *testing.T) {
 	s, redisCmd, dispatcher := newMockedRedisStorageAndPubSubDispatcher(ctrl)
 
 	// (1st fetchMessagesNow) MGET clock cursor
-	clocksMget1 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(strPList(t, "10", "10"), nil)
+	clocksMget1 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(append(strPList(t, "10", "10"), nil), nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.Clock(), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.SubscriberCursor(sl.SubscriberID), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	// (1st fetchMessagesNow) MGET (no messages)
@@ -122,7 +122,7 @@ func TestFetchMessagesSecondPollingError(t *testing.T) {
 
 	// (2nd fetchMessagesNow) MGET clock cursor
 	errorToReturn := errors.New("Mocked redis error")
-	redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(nil, errorToReturn).After(bodyMget1)
+	redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(nil, errorToReturn).After(bodyMget1)
 
 	_, _, _, err := s.FetchMessages(context.Background(), sl, 100, domain.Duration{Duration: 3 * time.Second})
 	dspstesting.IsError(t, errorToReturn, err)
@@ -140,7 +140,7 @@ func TestFetchMessagesSpuriousWakeup(t *testing.T) {
 	s, redisCmd, dispatcher := newMockedRedisStorageAndPubSubDispatcher(ctrl)
 
 	// (1st fetchMessagesNow) MGET clock cursor
-	clocksMget1 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(strPList(t, "10", "10"), nil)
+	clocksMget1 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(append(strPList(t, "10", "10"), nil), nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.Clock(), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.SubscriberCursor(sl.SubscriberID), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil)
 	// (1st fetchMessagesNow) MGET (no messages)
@@ -149,7 +149,7 @@ func TestFetchMessagesSpuriousWakeup(t *testing.T) {
 	}).After(clocksMget1)
 
 	// (2nd fetchMessagesNow) MGET clock cursor
-	clocksMget2 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(strPList(t, "10", "10"), nil).After(bodyMget1)
+	clocksMget2 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(append(strPList(t, "10", "10"), nil), nil).After(bodyMget1)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.Clock(), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil).After(bodyMget1)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.SubscriberCursor(sl.SubscriberID), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(nil).After(bodyMget1)
 	// (2nd fetchMessagesNow) MGET (no messages)
@@ -170,7 +170,7 @@ func TestFetchMessagesTTLExtensionError(t *testing.T) {
 	s, redisCmd, dispatcher := newMockedRedisStorageAndPubSubDispatcher(ctrl)
 
 	// (1st fetchMessagesNow) MGET clock cursor
-	clocksMget1 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID)).Return(strPList(t, "10", "10"), nil)
+	clocksMget1 := redisCmd.EXPECT().MGet(gomock.Any(), keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)).Return(append(strPList(t, "10", "10"), nil), nil)
 	errToReturn := errors.New("Mocked redis error of EXPIRE command")
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.Clock(), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(errToReturn)
 	redisCmd.EXPECT().Expire(gomock.Any(), keys.SubscriberCursor(sl.SubscriberID), storagetesting.StubChannelExpire.Duration+ttlMargin).Return(errToReturn)
@@ -30,6 +30,8 @@ var publishMessageScript = redis.NewScript(`
 	local content = ARGV[2]             -- (string) content
 	local clockMin = tonumber(ARGV[3])  -- (number) clockMin
 	local clockMax = tonumber(ARGV[4])  -- (number) clockMax
+	local maxBacklog = tonumber(ARGV[5])  -- (number) max count of retained messages, 0 means unlimited
+	local evict = (ARGV[6] == "evict")    -- (string) "reject" or "evict"
 
 	-- Increment chanel clock
 	local nextClock = tonumber(redis.call("incr", clockKey))
@@ -47,11 +49,39 @@ var publishMessageScript = redis.NewScript(`
 		redis.call("expire", clockKey, ttlSec)
 		return false
 	end
+
+	-- Enforce backlog limit
+	-- Because clocks of messages are contiguous and older message expires earlier,
+	-- backlog is full if the message maxBacklog steps before still exists.
+	if maxBacklog > 0 then
+		local oldestClock = nextClock - maxBacklog
+		if oldestClock < clockMin then
+			oldestClock = oldestClock - clockMin + clockMax + 1  -- Wrap around
+		end
+		if redis.call("exists", msgBodyKeyPrefix .. string.format("%d", oldestClock)) == 1 then
+			if not evict then
+				-- Rollback
+				redis.call("del", msgDedupKey)
+				redis.call("set", clockKey, string.format("%d", oldClock))
+				redis.call("expire", clockKey, ttlSec)
+				return "backlog-full"
+			end
+			-- Evict it and also older ones (exist if the limit has been reduced)
+			repeat
+				redis.call("del", msgBodyKeyPrefix .. string.format("%d", oldestClock))
+				oldestClock = oldestClock - 1
+				if oldestClock < clockMin then
+					oldestClock = clockMax
+				end
+			until redis.call("exists", msgBodyKeyPrefix .. string.format("%d", oldestClock)) == 0
+		end
+	end
+
 	redis.call("set", msgBodyKeyPrefix .. string.format("%d", nextClock), content, "EX", ttlSec)
 	return redis.status_reply("OK")
 `)
 
-func runPublishMessageScript(ctx context.Context, redisCmd internal.RedisCmd, ttl channelTTLSec, msg domain.Message) error {
+func runPublishMessageScript(ctx context.Context, redisCmd internal.RedisCmd, ttl channelTTLSec, backlog domain.BacklogLimit, msg domain.Message) error {
 	wrapped, err := wrapMessage(msg)
 	if err != nil {
 		return xerrors.Errorf("Unable to encode message \"%s\": %w", msg.MessageID, err)
@@ -61,7 +91,7 @@ func runPublishMessageScript(ctx context.Context, redisCmd internal.RedisCmd, tt
 	result, err := redisCmd.RunScript(
 		ctx, publishMessageScript,
 		[]string{keys.Clock(), keys.MessageBodyPrefix(), keys.MessageDedup(msg.MessageID)},
-		ttl, wrapped, clockMin, clockMax,
+		ttl, wrapped, clockMin, clockMax, backlog.Max, string(backlog.Overflow),
 	)
 	logger.Of(ctx).Debugf(logger.CatStorage, "runPublishMessageScript(ttl = %d, msg = %v) resulted in %v (%v)", ttl, msg, result, err)
 	if err != nil {
@@ -71,7 +101,11 @@ func runPublishMessageScript(ctx context.Context, redisCmd internal.RedisCmd, tt
 			return xerrors.Errorf("Failed to execute publishMessageScript: %w", err)
 		}
 	} else {
-		if result != "OK" {
+		switch result {
+		case "OK":
+		case "backlog-full":
+			return xerrors.Errorf("channel retains %d messages: %w", backlog.Max, domain.ErrBacklogFull)
+		default:
 			return xerrors.Errorf("Unexpected result from publishMessageScript: %T(%v)", result, result)
 		}
 	}
@@ -81,6 +115,7 @@ func runPublishMessageScript(ctx context.Context, redisCmd internal.RedisCmd, tt
 var ackScript = redis.NewScript(`
 	local channelClockKey = KEYS[1]  -- Clock of the channel (c.{channel}.clock)
 	local sbscClockKey = KEYS[2]     -- Clock of the subscriber (c.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]            -- Visibility timeout of the queue subscriber (c.{{channel}}.q.{subscriber})
 	local ttlSec = tonumber(ARGV[1])            -- (number) ttl [sec]
 	local acknowledgedClock = tonumber(ARGV[2]) -- (number) Clock of the latest acknowledged message
 
@@ -88,7 +123,19 @@ var ackScript = redis.NewScript(`
 	local sbscClock = redis.call("get", sbscClockKey)
 	if channelClock == false then return "channel-not-found" end
 	if sbscClock == false then return "subscription-not-found" end
+	if redis.call("exists", vtKey) == 1 then
+		-- Subscriber has been turned into queue subscriber, must not skip messages leased to other consumers
+		return "stale"
+	end
 	channelClock = tonumber(channelClock)
+
+	-- Subscriber cursor could have filter suffix ("{clock}:{filter JSON}"), must keep it
+	local sbscFilter = ""
+	local sep = string.find(sbscClock, ":", 1, true)
+	if sep then
+		sbscFilter = string.sub(sbscClock, sep)
+		sbscClock = string.sub(sbscClock, 1, sep - 1)
+	end
 	sbscClock = tonumber(sbscClock)
 
 	if channelClock < sbscClock then
@@ -102,7 +149,7 @@ var ackScript = redis.NewScript(`
 			return "stale"
 		end
 	end
-	redis.call("set", sbscClockKey, string.format("%d", acknowledgedClock), "EX", ttlSec)
+	redis.call("set", sbscClockKey, string.format("%d", acknowledgedClock) .. sbscFilter, "EX", ttlSec)
 	redis.call("expire", channelClockKey, ttlSec)  -- Also extend channel expiry
 	return redis.status_reply("OK")
 `)
@@ -114,6 +161,7 @@ func runAckScript(ctx context.Context, redisCmd internal.RedisCmd, channelID dom
 		[]string{
 			keys.Clock(),
 			keys.SubscriberCursor(sbscID),
+			keys.QueueVisibilityTimeout(sbscID),
 		},
 		ttl, int64(acknowledgedClock),
 	)
@@ -58,12 +58,12 @@ func TestPublishMessageScript(t *testing.T) {
 			}
 
 			// 1st publish
-			assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, msg))
+			assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, domain.BacklogLimit{}, msg))
 			assertValueAndTTL(t, redisCmd, keys.Clock(), fmt.Sprintf("%d", clockAfter), time.Duration(ttl)*time.Second)
 
 			// 2nd publish (duplicate)
 			if testcase.duplicateMessage {
-				assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, msg))
+				assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, domain.BacklogLimit{}, msg))
 				// Should not advance clock
 				assertValueAndTTL(t, redisCmd, keys.Clock(), fmt.Sprintf("%d", clockAfter), time.Duration(ttl)*time.Second)
 			}
@@ -76,6 +76,78 @@ func TestPublishMessageScript(t *testing.T) {
 	})
 }
 
+func TestPublishMessageScriptBacklogLimit(t *testing.T) {
+	ctx := context.Background()
+	ttl := channelTTLSec(3)
+	newMessage := func(channelID domain.ChannelID, id string) domain.Message {
+		return domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: channelID, MessageID: domain.MessageID(id)},
+			Content:        json.RawMessage(`{}`),
+		}
+	}
+	assertExists := func(redisCmd RedisCmd, key string, expected bool) {
+		value, err := redisCmd.Get(ctx, key)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value != nil, key)
+	}
+
+	// Reject
+	WithRedisClient(t, func(redisCmd RedisCmd) {
+		channelID := randomChannelID(t)
+		keys := keyOfChannel(channelID)
+		limit := domain.BacklogLimit{Max: 2, Overflow: domain.BacklogOverflowReject}
+		assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, limit, newMessage(channelID, "msg1")))
+		assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, limit, newMessage(channelID, "msg2")))
+		for i := 0; i < 2; i++ {
+			dspstesting.IsError(t, domain.ErrBacklogFull, runPublishMessageScript(ctx, redisCmd, ttl, limit, newMessage(channelID, "msg3")))
+			assertValueAndTTL(t, redisCmd, keys.Clock(), "2", time.Duration(ttl)*time.Second)
+			assertExists(redisCmd, keys.MessageDedup("msg3"), false)
+			assertExists(redisCmd, keys.MessageBody(3), false)
+		}
+
+		// Room made by expiration
+		assert.NoError(t, redisCmd.Del(ctx, keys.MessageBody(1)))
+		assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, limit, newMessage(channelID, "msg3")))
+		assertExists(redisCmd, keys.MessageBody(3), true)
+	})
+
+	// Evict
+	WithRedisClient(t, func(redisCmd RedisCmd) {
+		channelID := randomChannelID(t)
+		keys := keyOfChannel(channelID)
+		limit := domain.BacklogLimit{Max: 2, Overflow: domain.BacklogOverflowEvict}
+		for _, id := range []string{"msg1", "msg2", "msg3"} {
+			assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, limit, newMessage(channelID, id)))
+		}
+		assertValueAndTTL(t, redisCmd, keys.Clock(), "3", time.Duration(ttl)*time.Second)
+		assertExists(redisCmd, keys.MessageBody(1), false)
+		assertExists(redisCmd, keys.MessageBody(2), true)
+		assertExists(redisCmd, keys.MessageBody(3), true)
+		assertExists(redisCmd, keys.MessageDedup("msg1"), true) // Keep for deduplication
+
+		// Reduced limit evicts all older messages
+		limit.Max = 1
+		assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, limit, newMessage(channelID, "msg4")))
+		assertExists(redisCmd, keys.MessageBody(2), false)
+		assertExists(redisCmd, keys.MessageBody(3), false)
+		assertExists(redisCmd, keys.MessageBody(4), true)
+	})
+
+	// Evict with clock wrap-around
+	WithRedisClient(t, func(redisCmd RedisCmd) {
+		channelID := randomChannelID(t)
+		keys := keyOfChannel(channelID)
+		limit := domain.BacklogLimit{Max: 2, Overflow: domain.BacklogOverflowEvict}
+		assert.NoError(t, redisCmd.Set(ctx, keys.Clock(), clockMax-1))
+		for _, id := range []string{"msg1", "msg2", "msg3"} {
+			assert.NoError(t, runPublishMessageScript(ctx, redisCmd, ttl, limit, newMessage(channelID, id)))
+		}
+		assertExists(redisCmd, keys.MessageBody(clockMax), false)
+		assertExists(redisCmd, keys.MessageBody(clockMin), true)
+		assertExists(redisCmd, keys.MessageBody(clockMin+1), true)
+	})
+}
+
 func TestPublishMessageScriptAbormalResults(t *testing.T) {
 	ctx := context.Background()
 	channelID := randomChannelID(t)
@@ -99,14 +171,14 @@ func TestPublishMessageScriptAbormalResults(t *testing.T) {
 		assert.Equal(
 			t,
 			`Failed to execute publishMessageScript: ERR Error compiling script (new function): user_script:1: '=' expected near 'tax'`,
-			runPublishMessageScript(ctx, redisCmd, ttl, msg).Error(),
+			runPublishMessageScript(ctx, redisCmd, ttl, domain.BacklogLimit{}, msg).Error(),
 		)
 
 		publishMessageScript = redis.NewScript(`return "What??"`)
 		assert.Equal(
 			t,
 			`Unexpected result from publishMessageScript: string(What??)`,
-			runPublishMessageScript(ctx, redisCmd, ttl, msg).Error(),
+			runPublishMessageScript(ctx, redisCmd, ttl, domain.BacklogLimit{}, msg).Error(),
 		)
 	})
 }
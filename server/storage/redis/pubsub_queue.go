@@ -0,0 +1,159 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+func (s *redisStorage) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	if visibilityTimeout.Milliseconds() <= 0 { // Lua script treats 0 as normal subscriber
+		return xerrors.Errorf("Visibility timeout must be positive: %s", visibilityTimeout)
+	}
+	ttl, err := s.channelRedisTTLSec(sl.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	return runCreateSubscriberScript(ctx, s.RedisCmd, sl.ChannelID, ttl, sl.SubscriberID, filter, &visibilityTimeout)
+}
+
+// fetchQueueMessagesNow leases visible messages of the queue subscriber to the caller.
+// wakeAt is the earliest expiry of other leases (zero if none), leased messages become visible again at that time.
+// skipped is true if all candidates are skipped (filtered out or missing) and following messages exist.
+func (s *redisStorage) fetchQueueMessagesNow(ctx context.Context, sl domain.SubscriberLocator, max int, chClock channelClock, sbscClock channelClock, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, wakeAt time.Time, skipped bool, err error) {
+	keys := keyOfChannel(sl.ChannelID)
+	ttl, err := s.channelRedisTTLSec(sl.ChannelID)
+	if err != nil {
+		err = xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+		return
+	}
+	leases, err := s.RedisCmd.HGetAll(ctx, keys.QueueLeases(sl.SubscriberID))
+	if err != nil {
+		err = xerrors.Errorf("FetchMessages failed due to Redis error (lease HGETALL error): %w", err)
+		return
+	}
+
+	now := s.clock.Now().Time
+	for _, lease := range leases {
+		if expireAt, ok := parseQueueLeaseExpiry(lease); ok && expireAt.After(now) && (wakeAt.IsZero() || expireAt.Before(wakeAt)) {
+			wakeAt = expireAt
+		}
+	}
+
+	// Pick visible messages, loop count is bounded by count of leases + max
+	candidates := make([]channelClock, 0, max)
+	for clock := sbscClock; clock != chClock; {
+		clock = nextClock(clock)
+		if !isQueueLeaseVisible(leases[strconv.FormatInt(int64(clock), 10)], now) {
+			continue
+		}
+		if len(candidates) == max {
+			moreMessages = true
+			break
+		}
+		candidates = append(candidates, clock)
+	}
+	msgKeys := make([]string, len(candidates)) // Must same length with candidates
+	for i, clock := range candidates {
+		msgKeys[i] = keys.MessageBody(clock)
+	}
+	rawMsgs, err := s.RedisCmd.MGet(ctx, msgKeys...)
+	if err != nil {
+		err = xerrors.Errorf("FetchMessages failed due to Redis error (msg MGET error): %w", err)
+		return
+	}
+
+	leaseClocks := make([]channelClock, 0, len(candidates))
+	skipClocks := make([]channelClock, 0)
+	found := make(map[channelClock]domain.Message, len(candidates))
+	for i, rawPtr := range rawMsgs {
+		var raw string
+		if rawPtr != nil {
+			raw = *rawPtr
+		}
+		msg, err := unwrapMessage(sl.ChannelID, raw)
+		if err != nil || msg == nil {
+			if err != nil {
+				logger.Of(ctx).Error(fmt.Sprintf("Skipped corrupted message (chID: %s, clock: %d) fetched from Redis", sl.ChannelID, candidates[i]), err)
+			}
+			skipClocks = append(skipClocks, candidates[i])
+			continue // may caused by message TTL expiration
+		}
+		if !filter.Match(*msg) {
+			skipClocks = append(skipClocks, candidates[i])
+			continue
+		}
+		leaseClocks = append(leaseClocks, candidates[i])
+		found[candidates[i]] = *msg
+	}
+
+	// Retained messages are contiguous up to the channel clock, thus if the last candidate is missing all candidates are missing.
+	// Jump to the oldest retained message rather than scan missing clocks max by max.
+	var skipUntil *channelClock
+	if moreMessages && len(candidates) > 0 && rawMsgs[len(rawMsgs)-1] == nil {
+		oldest, retained, err := s.findOldestMessageClock(ctx, sl.ChannelID, chClock)
+		if err != nil {
+			return nil, false, domain.AckHandle{}, time.Time{}, false, xerrors.Errorf("FetchMessages failed due to Redis error (msg MGET error): %w", err)
+		}
+		jumpTo := chClock
+		if retained {
+			jumpTo = prevClock(oldest)
+		}
+		if isClockWithin(jumpTo, candidates[len(candidates)-1], chClock) {
+			skipUntil = &jumpTo
+		}
+		if !retained {
+			moreMessages = false
+		}
+	}
+
+	leaseID, err := newLeaseID()
+	if err != nil {
+		return
+	}
+	// Run the script even if no candidates to move cursor over acknowledged messages and extend TTL.
+	leased, err := runQueueLeaseScript(ctx, s.RedisCmd, sl, ttl, leaseID, now, visibilityTimeout, skipUntil, leaseClocks, skipClocks)
+	if err != nil {
+		return
+	}
+
+	messages = make([]domain.Message, 0, len(leased))
+	for _, clock := range leased {
+		messages = append(messages, found[clock])
+	}
+	if len(leased) > 0 {
+		ackHandle = encodeAckHandle(sl, ackHandleData{
+			LastMessageClock: leased[len(leased)-1],
+			LeaseID:          leaseID,
+			LeasedClocks:     leased,
+		})
+		return
+	}
+	// Skipped messages have been acknowledged by the script, thus they are not scanned again.
+	skipped = len(skipClocks) > 0 && moreMessages
+	return
+}
+
+func (s *redisStorage) acknowledgeQueueMessages(ctx context.Context, handle domain.AckHandle, h ackHandleData) error {
+	ttl, err := s.channelRedisTTLSec(handle.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	return runQueueAckScript(ctx, s.RedisCmd, handle.SubscriberLocator, ttl, h.LeaseID, h.LeasedClocks)
+}
+
+func newLeaseID() (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", xerrors.Errorf("Failed to generate lease ID: %w", err)
+	}
+	return strings.ReplaceAll(id.String(), "-", ""), nil
+}
@@ -0,0 +1,220 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+	internal "github.com/m3dev/dsps/server/storage/redis/internal"
+)
+
+func (s *redisStorage) loadPubSubQueueScripts(ctx context.Context) error {
+	if err := s.RedisCmd.LoadScript(ctx, queueLeaseScript); err != nil {
+		return xerrors.Errorf("Failed to load queueLeaseScript: %w", err)
+	}
+	if err := s.RedisCmd.LoadScript(ctx, queueAckScript); err != nil {
+		return xerrors.Errorf("Failed to load queueAckScript: %w", err)
+	}
+	return nil
+}
+
+// Common part of queue scripts, KEYS and ARGV must start with following items.
+const queueScriptPrologue = `
+	local channelClockKey = KEYS[1]  -- Clock of the channel (c.{channel}.clock)
+	local sbscClockKey = KEYS[2]     -- Clock of the subscriber (c.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]            -- Visibility timeout of the queue subscriber (c.{{channel}}.q.{subscriber})
+	local leasesKey = KEYS[4]        -- Leases of the queue subscriber (c.{{channel}}.l.{subscriber})
+	local ttlSec = tonumber(ARGV[1])    -- (number) ttl [sec]
+	local clockMin = tonumber(ARGV[2])  -- (number) clockMin
+	local clockMax = tonumber(ARGV[3])  -- (number) clockMax
+	local leaseID = ARGV[4]             -- (string) ID of the lease
+
+	local channelClock = redis.call("get", channelClockKey)
+	local sbscClock = redis.call("get", sbscClockKey)
+	if channelClock == false then return "channel-not-found" end
+	if sbscClock == false then return "subscription-not-found" end
+	if redis.call("exists", vtKey) == 0 then return "not-queue" end
+	channelClock = tonumber(channelClock)
+
+	-- Subscriber cursor could have filter suffix ("{clock}:{filter JSON}"), must keep it
+	local sbscFilter = ""
+	local sep = string.find(sbscClock, ":", 1, true)
+	if sep then
+		sbscFilter = string.sub(sbscClock, sep)
+		sbscClock = string.sub(sbscClock, 1, sep - 1)
+	end
+	sbscClock = tonumber(sbscClock)
+
+	-- Returns true if the clock is in range (from, to] considering wrap-around
+	local function isWithin(clock, from, to)
+		if to < from then
+			return (from < clock) or (clock <= to)
+		end
+		return (from < clock) and (clock <= to)
+	end
+	-- Returns true if the clock is in range (sbscClock, channelClock]
+	local function isPending(clock)
+		return isWithin(clock, sbscClock, channelClock)
+	end
+
+	-- Moves cursor forward over acknowledged messages and saves it, also extends TTL of the subscriber.
+	-- Messages until skipUntil (optional) are missing (expired or evicted), the cursor jumps over them rather than walks each clock.
+	local function advanceCursor(skipUntil)
+		local clock = sbscClock
+		if skipUntil ~= nil and isPending(skipUntil) then
+			for _, field in ipairs(redis.call("hkeys", leasesKey)) do
+				if isWithin(tonumber(field), sbscClock, skipUntil) then
+					redis.call("hdel", leasesKey, field)
+				end
+			end
+			clock = skipUntil
+		end
+		-- Walk count is bounded by count of leases, because missing messages are acknowledged or skipped by fetch operation
+		while clock ~= channelClock do
+			local following = clock + 1
+			if following > clockMax then following = clockMin end
+			local field = string.format("%d", following)
+			if redis.call("hget", leasesKey, field) ~= "acked" then
+				break  -- Not acknowledged yet
+			end
+			redis.call("hdel", leasesKey, field)
+			clock = following
+		end
+		redis.call("set", sbscClockKey, string.format("%d", clock) .. sbscFilter, "EX", ttlSec)
+		redis.call("expire", channelClockKey, ttlSec)
+		redis.call("expire", vtKey, ttlSec)
+		redis.call("expire", leasesKey, ttlSec)
+	end
+`
+
+// @returns array of leased clocks if succeeded
+var queueLeaseScript = redis.NewScript(queueScriptPrologue + `
+	local nowMs = tonumber(ARGV[5])       -- (number) current time [unix ms]
+	local vtMs = tonumber(ARGV[6])        -- (number) visibility timeout [ms]
+	local leaseCount = tonumber(ARGV[7])  -- (number) count of clocks to lease
+	local skipUntil = tonumber(ARGV[8])   -- (number) messages until this clock are missing, or empty string
+	-- ARGV[9 .. 9 + leaseCount - 1] : clocks to lease
+	-- ARGV[9 + leaseCount ..]       : clocks to acknowledge without delivery (filtered out or expired messages)
+
+	local leased = {}
+	for i = 9, #ARGV do
+		local clock = tonumber(ARGV[i])
+		if isPending(clock) then
+			local field = string.format("%d", clock)
+			if i < 9 + leaseCount then
+				-- Lease only if still visible, other consumer could lease it concurrently
+				local lease = redis.call("hget", leasesKey, field)
+				local visible = (lease == false)
+				if lease ~= false and lease ~= "acked" then
+					local expireAt = tonumber(string.match(lease, ":(%d+)$"))
+					visible = (expireAt == nil) or (expireAt <= nowMs)
+				end
+				if visible then
+					redis.call("hset", leasesKey, field, leaseID .. ":" .. string.format("%d", nowMs + vtMs))
+					table.insert(leased, clock)
+				end
+			else
+				redis.call("hset", leasesKey, field, "acked")
+			end
+		end
+	end
+	advanceCursor(skipUntil)
+	return leased
+`)
+
+// skipUntil is optional, messages until the clock (inclusive) must be missing.
+func runQueueLeaseScript(ctx context.Context, redisCmd internal.RedisCmd, sl domain.SubscriberLocator, ttl channelTTLSec, leaseID string, now time.Time, visibilityTimeout domain.Duration, skipUntil *channelClock, leaseClocks []channelClock, skipClocks []channelClock) ([]channelClock, error) {
+	keys := keyOfChannel(sl.ChannelID)
+	var skipUntilArg interface{} = ""
+	if skipUntil != nil {
+		skipUntilArg = int64(*skipUntil)
+	}
+	args := make([]interface{}, 0, 8+len(leaseClocks)+len(skipClocks))
+	args = append(args, ttl, clockMin, clockMax, leaseID, now.UnixNano()/int64(time.Millisecond), visibilityTimeout.Milliseconds(), len(leaseClocks), skipUntilArg)
+	for _, clock := range leaseClocks {
+		args = append(args, int64(clock))
+	}
+	for _, clock := range skipClocks {
+		args = append(args, int64(clock))
+	}
+	result, err := redisCmd.RunScript(
+		ctx, queueLeaseScript,
+		[]string{keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID), keys.QueueLeases(sl.SubscriberID)},
+		args...,
+	)
+	logger.Of(ctx).Debugf(logger.CatStorage, `runQueueLeaseScript(channelID = %s, ttl = %d, sbscID = %s, leaseID = %s, skipUntil = %v, leaseClocks = %v, skipClocks = %v) resulted in %v (%v)`, sl.ChannelID, ttl, sl.SubscriberID, leaseID, skipUntilArg, leaseClocks, skipClocks, result, err)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to execute queueLeaseScript: %w", err)
+	}
+	switch result := result.(type) {
+	case []interface{}:
+		leased := make([]channelClock, 0, len(result))
+		for _, clock := range result {
+			i, ok := clock.(int64)
+			if !ok {
+				return nil, xerrors.Errorf("Unexpected result from queueLeaseScript: %T(%v)", clock, clock)
+			}
+			leased = append(leased, channelClock(i))
+		}
+		return leased, nil
+	case string:
+		switch result {
+		case "channel-not-found", "subscription-not-found":
+			return nil, xerrors.Errorf("%s (%w)", result, domain.ErrSubscriptionNotFound)
+		case "not-queue":
+			return []channelClock{}, nil // Turned into normal subscriber concurrently
+		}
+	}
+	return nil, xerrors.Errorf("Unexpected result from queueLeaseScript: %T(%v)", result, result)
+}
+
+// @returns "OK" (Redis status reply) if succeeded
+var queueAckScript = redis.NewScript(queueScriptPrologue + `
+	-- ARGV[5 ..] : clocks leased by the lease
+
+	for i = 5, #ARGV do
+		local clock = tonumber(ARGV[i])
+		local field = string.format("%d", clock)
+		-- Lease could be expired, acknowledge it unless other consumer leased the message again
+		if isPending(clock) then
+			local lease = redis.call("hget", leasesKey, field)
+			if lease ~= false and string.sub(lease, 1, #leaseID + 1) == leaseID .. ":" then
+				redis.call("hset", leasesKey, field, "acked")
+			end
+		end
+	end
+	advanceCursor()
+	return redis.status_reply("OK")
+`)
+
+func runQueueAckScript(ctx context.Context, redisCmd internal.RedisCmd, sl domain.SubscriberLocator, ttl channelTTLSec, leaseID string, clocks []channelClock) error {
+	keys := keyOfChannel(sl.ChannelID)
+	args := make([]interface{}, 0, 4+len(clocks))
+	args = append(args, ttl, clockMin, clockMax, leaseID)
+	for _, clock := range clocks {
+		args = append(args, int64(clock))
+	}
+	result, err := redisCmd.RunScript(
+		ctx, queueAckScript,
+		[]string{keys.Clock(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID), keys.QueueLeases(sl.SubscriberID)},
+		args...,
+	)
+	logger.Of(ctx).Debugf(logger.CatStorage, `runQueueAckScript(channelID = %s, ttl = %d, sbscID = %s, leaseID = %s, clocks = %v) resulted in %v (%v)`, sl.ChannelID, ttl, sl.SubscriberID, leaseID, clocks, result, err)
+	if err != nil {
+		return xerrors.Errorf("Failed to execute queueAckScript: %w", err)
+	}
+	switch result {
+	case "OK":
+		return nil
+	case "channel-not-found", "subscription-not-found":
+		return xerrors.Errorf("%s (%w)", result, domain.ErrSubscriptionNotFound)
+	case "not-queue":
+		return nil // Turned into normal subscriber, the lease is stale
+	default:
+		return xerrors.Errorf("Unexpected result from queueAckScript: %T(%v)", result, result)
+	}
+}
@@ -9,18 +9,20 @@ import (
 	"github.com/m3dev/dsps/server/domain"
 )
 
-func (s *redisStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
+func (s *redisStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter) error {
 	ttl, err := s.channelRedisTTLSec(sl.ChannelID)
 	if err != nil {
 		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
 	}
-	return runCreateSubscriberScript(ctx, s.RedisCmd, sl.ChannelID, ttl, sl.SubscriberID)
+	return runCreateSubscriberScript(ctx, s.RedisCmd, sl.ChannelID, ttl, sl.SubscriberID, filter, nil)
 }
 
 func (s *redisStorage) RemoveSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
 	keys := keyOfChannel(sl.ChannelID)
-	if err := s.RedisCmd.Del(ctx, keys.SubscriberCursor(sl.SubscriberID)); err != nil {
-		return xerrors.Errorf("Failed to delete subscriber: %w", err)
+	for _, key := range []string{keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID), keys.QueueLeases(sl.SubscriberID)} {
+		if err := s.RedisCmd.Del(ctx, key); err != nil {
+			return xerrors.Errorf("Failed to delete subscriber: %w", err)
+		}
 	}
 	return nil
 }
@@ -39,3 +41,77 @@ func (s *redisStorage) extendSubscriberTTL(ctx context.Context, sl domain.Subscr
 	g.Go(func() error { return s.RedisCmd.Expire(ctx, keys.SubscriberCursor(sl.SubscriberID), ttl.asDuration()) })
 	return g.Wait()
 }
+
+func (s *redisStorage) RewindSubscriber(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) error {
+	ttl, err := s.channelRedisTTLSec(sl.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	rewindTo, err := s.resolveRewindTarget(ctx, sl, target)
+	if err != nil {
+		return err
+	}
+	return runRewindSubscriberScript(ctx, s.RedisCmd, sl.ChannelID, ttl, sl.SubscriberID, rewindTo)
+}
+
+// Returns the new subscriber clock, messages after the clock (exclusive) will be redelivered.
+func (s *redisStorage) resolveRewindTarget(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) (channelClock, error) {
+	keys := keyOfChannel(sl.ChannelID)
+	switch {
+	case target.Clock != nil:
+		clock := channelClock(*target.Clock)
+		if clock < clockMin || clockMax < clock {
+			return 0, xerrors.Errorf("%w: clock %d is out of range", domain.ErrRewindTargetNotFound, clock)
+		}
+		values, err := s.RedisCmd.MGet(ctx, keys.Clock(), keys.MessageBody(nextClock(clock)))
+		if err != nil {
+			return 0, xerrors.Errorf("RewindSubscriber failed due to Redis error (MGET error): %w", err)
+		}
+		if values[0] == nil {
+			return 0, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+		}
+		if chClock := parseChannelClock(*values[0]); (chClock == nil || *chClock != clock) && values[1] == nil {
+			return 0, xerrors.Errorf("%w: clock %d", domain.ErrRewindTargetNotFound, clock)
+		}
+		return clock, nil
+
+	case target.MessageID != nil:
+		value, err := s.RedisCmd.Get(ctx, keys.MessageDedup(*target.MessageID))
+		if err != nil {
+			return 0, xerrors.Errorf("RewindSubscriber failed due to Redis error (GET error): %w", err)
+		}
+		var msgClock *channelClock
+		if value != nil {
+			msgClock = parseChannelClock(*value)
+		}
+		if msgClock == nil {
+			return 0, xerrors.Errorf("%w: message %s", domain.ErrRewindTargetNotFound, *target.MessageID)
+		}
+		return prevClock(*msgClock), nil
+
+	case target.Earliest:
+		value, err := s.RedisCmd.Get(ctx, keys.Clock())
+		if err != nil {
+			return 0, xerrors.Errorf("RewindSubscriber failed due to Redis error (GET error): %w", err)
+		}
+		var chClock *channelClock
+		if value != nil {
+			chClock = parseChannelClock(*value)
+		}
+		if chClock == nil {
+			return 0, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+		}
+		oldest, found, err := s.findOldestMessageClock(ctx, sl.ChannelID, *chClock)
+		if err != nil {
+			return 0, xerrors.Errorf("RewindSubscriber failed due to Redis error (msg MGET error): %w", err)
+		}
+		rewindTo := *chClock
+		if found {
+			rewindTo = prevClock(oldest)
+		}
+		return rewindTo, nil
+
+	default:
+		return 0, xerrors.New("Rewind target not specified")
+	}
+}
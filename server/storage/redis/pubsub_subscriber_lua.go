@@ -16,15 +16,22 @@ func (s *redisStorage) loadPubSubSubscriberScripts(ctx context.Context) error {
 	if err := s.RedisCmd.LoadScript(ctx, createSubscriberScript); err != nil {
 		return xerrors.Errorf("Failed to load createSubscriberScript: %w", err)
 	}
+	if err := s.RedisCmd.LoadScript(ctx, rewindSubscriberScript); err != nil {
+		return xerrors.Errorf("Failed to load rewindSubscriberScript: %w", err)
+	}
 	return nil
 }
 
 // @returns "OK" (Redis status reply) if succeeded
-// @returns false (Nil bulk reply) if already exists
+// @returns false (Nil bulk reply) if already exists, filter of the subscriber is replaced in this case
 var createSubscriberScript = redis.NewScript(`
 	local clockKey = KEYS[1]	      -- Clock (c.{{channel}}.clock)
 	local subscriberKey = KEYS[2]     -- XXXX (c.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]             -- Visibility timeout of the queue subscriber (c.{{channel}}.q.{subscriber})
+	local leasesKey = KEYS[4]         -- Leases of the queue subscriber (c.{{channel}}.l.{subscriber})
 	local ttlSec = tonumber(ARGV[1])  -- (number) ttl [sec]
+	local filter = ARGV[2]            -- (string) ":{filter JSON}" or empty string if no filter
+	local vtMs = tonumber(ARGV[3])    -- (number) visibility timeout [ms], 0 if not a queue subscriber
 
 	local chClock = tonumber(redis.call("get", clockKey))
 	if chClock == nil then
@@ -34,16 +41,41 @@ var createSubscriberScript = redis.NewScript(`
 		redis.call("expire", clockKey, ttlSec)  -- Extend channel life
 	end
 
-	-- Create subscriber if not exists
-	return redis.call("set", subscriberKey, string.format("%d", chClock), "EX", ttlSec, "NX")
+	if vtMs > 0 then
+		redis.call("set", vtKey, vtMs, "EX", ttlSec)
+		if redis.call("exists", leasesKey) == 1 then
+			redis.call("expire", leasesKey, ttlSec)  -- Keep current leases
+		end
+	else
+		redis.call("del", vtKey, leasesKey)  -- Turn into normal subscriber, leased messages will be redelivered
+	end
+
+	local sbscCursor = redis.call("get", subscriberKey)
+	if sbscCursor ~= false then
+		-- Already exists, keep clock of the subscriber and replace filter
+		local sep = string.find(sbscCursor, ":", 1, true)
+		if sep then
+			sbscCursor = string.sub(sbscCursor, 1, sep - 1)
+		end
+		redis.call("set", subscriberKey, sbscCursor .. filter, "EX", ttlSec)
+		return false
+	end
+
+	-- Create subscriber
+	return redis.call("set", subscriberKey, string.format("%d", chClock) .. filter, "EX", ttlSec)
 `)
 
-func runCreateSubscriberScript(ctx context.Context, redisCmd internal.RedisCmd, channelID domain.ChannelID, ttl channelTTLSec, sbscID domain.SubscriberID) error {
+// visibilityTimeout is nil unless creating queue subscriber.
+func runCreateSubscriberScript(ctx context.Context, redisCmd internal.RedisCmd, channelID domain.ChannelID, ttl channelTTLSec, sbscID domain.SubscriberID, filter *domain.SubscriberFilter, visibilityTimeout *domain.Duration) error {
+	var vtMs int64
+	if visibilityTimeout != nil {
+		vtMs = visibilityTimeout.Milliseconds()
+	}
 	keys := keyOfChannel(channelID)
 	result, err := redisCmd.RunScript(
 		ctx, createSubscriberScript,
-		[]string{keys.Clock(), keys.SubscriberCursor(sbscID)},
-		ttl,
+		[]string{keys.Clock(), keys.SubscriberCursor(sbscID), keys.QueueVisibilityTimeout(sbscID), keys.QueueLeases(sbscID)},
+		ttl, formatSubscriberCursorFilter(filter), vtMs,
 	)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
@@ -56,3 +88,48 @@ func runCreateSubscriberScript(ctx context.Context, redisCmd internal.RedisCmd,
 	}
 	return nil
 }
+
+var rewindSubscriberScript = redis.NewScript(`
+	local channelClockKey = KEYS[1]  -- Clock of the channel (c.{channel}.clock)
+	local sbscClockKey = KEYS[2]     -- Clock of the subscriber (c.{{channel}}.r.{subscriber})
+	local leasesKey = KEYS[3]        -- Leases of the queue subscriber (c.{{channel}}.l.{subscriber})
+	local ttlSec = tonumber(ARGV[1])  -- (number) ttl [sec]
+	local rewindTo = tonumber(ARGV[2])  -- (number) New clock of the subscriber
+
+	local channelClock = redis.call("get", channelClockKey)
+	local sbscClock = redis.call("get", sbscClockKey)
+	if channelClock == false then return "channel-not-found" end
+	if sbscClock == false then return "subscription-not-found" end
+
+	-- Subscriber cursor could have filter suffix ("{clock}:{filter JSON}"), must keep it
+	local sbscFilter = ""
+	local sep = string.find(sbscClock, ":", 1, true)
+	if sep then
+		sbscFilter = string.sub(sbscClock, sep)
+	end
+	redis.call("set", sbscClockKey, string.format("%d", rewindTo) .. sbscFilter, "EX", ttlSec)
+	redis.call("expire", channelClockKey, ttlSec)  -- Also extend channel expiry
+	redis.call("del", leasesKey)  -- Redeliver all messages after the cursor to queue subscriber
+	return redis.status_reply("OK")
+`)
+
+func runRewindSubscriberScript(ctx context.Context, redisCmd internal.RedisCmd, channelID domain.ChannelID, ttl channelTTLSec, sbscID domain.SubscriberID, rewindTo channelClock) error {
+	keys := keyOfChannel(channelID)
+	result, err := redisCmd.RunScript(
+		ctx, rewindSubscriberScript,
+		[]string{keys.Clock(), keys.SubscriberCursor(sbscID), keys.QueueLeases(sbscID)},
+		ttl, int64(rewindTo),
+	)
+	logger.Of(ctx).Debugf(logger.CatStorage, `runRewindSubscriberScript(channelID = %s, ttl = %d, sbscID = %s, rewindTo = %d) resulted in %v (%v)`, channelID, ttl, sbscID, rewindTo, result, err)
+	if err != nil {
+		return xerrors.Errorf("Failed to execute rewindSubscriberScript: %w", err)
+	}
+	switch result {
+	case "OK":
+		return nil
+	case "channel-not-found", "subscription-not-found":
+		return xerrors.Errorf("%s (%w)", result, domain.ErrSubscriptionNotFound)
+	default:
+		return xerrors.Errorf("Unexpected result from rewindSubscriberScript: %T(%v)", result, result)
+	}
+}
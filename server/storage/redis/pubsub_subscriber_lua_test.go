@@ -23,7 +23,7 @@ func TestSubscriberScript(t *testing.T) {
 		ttl := channelTTLSec(3)
 		sbscID := domain.SubscriberID("sbsc1")
 
-		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID))
+		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID, nil, nil))
 
 		assertValueAndTTL(t, redisCmd, keys.Clock(), "0", time.Duration(ttl)*time.Second)
 		assertValueAndTTL(t, redisCmd, keys.SubscriberCursor(sbscID), "0", time.Duration(ttl)*time.Second)
@@ -39,7 +39,7 @@ func TestSubscriberScript(t *testing.T) {
 		clock := channelClock(-1024)
 		assert.NoError(t, redisCmd.Set(ctx, keys.Clock(), clock))
 
-		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID))
+		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID, nil, nil))
 
 		assertValueAndTTL(t, redisCmd, keys.Clock(), "-1024", time.Duration(ttl)*time.Second)
 		assertValueAndTTL(t, redisCmd, keys.SubscriberCursor(sbscID), "-1024", time.Duration(ttl)*time.Second)
@@ -55,13 +55,38 @@ func TestSubscriberScript(t *testing.T) {
 		clock := channelClock(clockMin)
 		assert.NoError(t, redisCmd.Set(ctx, keys.Clock(), clock))
 
-		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID))
+		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID, nil, nil))
 
 		assertValueAndTTL(t, redisCmd, keys.Clock(), fmt.Sprintf("%d", clockMin), time.Duration(ttl)*time.Second)
 		assertValueAndTTL(t, redisCmd, keys.SubscriberCursor(sbscID), fmt.Sprintf("%d", clockMin), time.Duration(ttl)*time.Second)
 	})
 }
 
+func TestSubscriberScriptWithFilter(t *testing.T) {
+	ctx := context.Background()
+	WithRedisClient(t, func(redisCmd RedisCmd) {
+		channelID := randomChannelID(t)
+		keys := keyOfChannel(channelID)
+		ttl := channelTTLSec(3)
+		sbscID := domain.SubscriberID("sbsc1")
+		filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+		assert.NoError(t, err)
+
+		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID, filter, nil))
+		assertValueAndTTL(t, redisCmd, keys.SubscriberCursor(sbscID), `0:{"attributes":{"event-type":"created"}}`, time.Duration(ttl)*time.Second)
+
+		// Ack keeps the filter
+		assert.NoError(t, redisCmd.Set(ctx, keys.Clock(), channelClock(3)))
+		_, err = runAckScript(ctx, redisCmd, channelID, ttl, sbscID, 2)
+		assert.NoError(t, err)
+		assertValueAndTTL(t, redisCmd, keys.SubscriberCursor(sbscID), `2:{"attributes":{"event-type":"created"}}`, time.Duration(ttl)*time.Second)
+
+		// Re-creation replaces the filter but keeps the clock
+		assert.NoError(t, runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID, nil, nil))
+		assertValueAndTTL(t, redisCmd, keys.SubscriberCursor(sbscID), `2`, time.Duration(ttl)*time.Second)
+	})
+}
+
 func TestSubscriberScriptAbormalResults(t *testing.T) {
 	ctx := context.Background()
 
@@ -78,7 +103,7 @@ func TestSubscriberScriptAbormalResults(t *testing.T) {
 		assert.Equal(
 			t,
 			`Failed to execute createSubscriberScript: ERR Error compiling script (new function): user_script:1: '=' expected near 'tax'`,
-			runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID).Error(),
+			runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID, nil, nil).Error(),
 		)
 	})
 
@@ -91,7 +116,7 @@ func TestSubscriberScriptAbormalResults(t *testing.T) {
 		assert.Equal(
 			t,
 			`Unexpected result from createSubscriberScript: string(What??)`,
-			runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID).Error(),
+			runCreateSubscriberScript(ctx, redisCmd, channelID, ttl, sbscID, nil, nil).Error(),
 		)
 	})
 }
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (s *redisStorage) loadRateLimitScripts(ctx context.Context) error {
+	if err := s.RedisCmd.LoadScript(ctx, incrementRateLimitCounterScript); err != nil {
+		return xerrors.Errorf("Failed to load incrementRateLimitCounterScript: %w", err)
+	}
+	return nil
+}
+
+// @returns {count, remaining TTL in milliseconds}
+var incrementRateLimitCounterScript = redis.NewScript(`
+	local counterKey = KEYS[1]            -- Counter (rl.{key})
+	local windowMs = tonumber(ARGV[1])    -- (number) length of the window [ms]
+
+	local count = redis.call("incr", counterKey)
+	local ttlMs = redis.call("pttl", counterKey)
+	if count == 1 or ttlMs < 0 then
+		-- First hit in the window, or lost expiry for some reason
+		redis.call("pexpire", counterKey, windowMs)
+		ttlMs = windowMs
+	end
+	return {count, ttlMs}
+`)
+
+func (s *redisStorage) IncrementRateLimitCounter(ctx context.Context, key string, window domain.Duration) (int64, domain.Duration, error) {
+	windowMs := window.Milliseconds()
+	if windowMs < 1 {
+		windowMs = 1
+	}
+	result, err := s.RedisCmd.RunScript(ctx, incrementRateLimitCounterScript, []string{keyOfRateLimit(key).Counter()}, windowMs)
+	if err != nil {
+		return 0, domain.Duration{}, xerrors.Errorf("Failed to execute incrementRateLimitCounterScript: %w", err)
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, domain.Duration{}, xerrors.Errorf("Unexpected result from incrementRateLimitCounterScript: %T(%v)", result, result)
+	}
+	count, ok1 := values[0].(int64)
+	ttlMs, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, domain.Duration{}, xerrors.Errorf("Unexpected result from incrementRateLimitCounterScript: %T(%v)", result, result)
+	}
+	return count, domain.Duration{Duration: time.Duration(ttlMs) * time.Millisecond}, nil
+}
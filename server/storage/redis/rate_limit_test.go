@@ -0,0 +1,47 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+func TestRateLimitRedisErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	errToReturn := errors.New("Mocked redis error")
+
+	s, redisCmd := newMockedRedisStorage(ctrl)
+	redisCmd.EXPECT().RunScript(gomock.Any(), incrementRateLimitCounterScript, []string{keyOfRateLimit("rule1.ip.127.0.0.1").Counter()}, int64(1000)).Return(nil, errToReturn)
+
+	_, _, err := s.IncrementRateLimitCounter(context.Background(), "rule1.ip.127.0.0.1", domain.Duration{Duration: time.Second})
+	dspstesting.IsError(t, errToReturn, err)
+}
+
+func TestRateLimitUnexpectedScriptResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s, redisCmd := newMockedRedisStorage(ctrl)
+	redisCmd.EXPECT().RunScript(gomock.Any(), incrementRateLimitCounterScript, gomock.Any(), gomock.Any()).Return("OK", nil)
+	redisCmd.EXPECT().RunScript(gomock.Any(), incrementRateLimitCounterScript, gomock.Any(), gomock.Any()).Return([]interface{}{int64(1), "1000"}, nil)
+	redisCmd.EXPECT().RunScript(gomock.Any(), incrementRateLimitCounterScript, gomock.Any(), gomock.Any()).Return([]interface{}{int64(3), int64(250)}, nil)
+
+	_, _, err := s.IncrementRateLimitCounter(context.Background(), "key", domain.Duration{Duration: time.Second})
+	assert.Regexp(t, `Unexpected result from incrementRateLimitCounterScript`, err.Error())
+	_, _, err = s.IncrementRateLimitCounter(context.Background(), "key", domain.Duration{Duration: time.Second})
+	assert.Regexp(t, `Unexpected result from incrementRateLimitCounterScript`, err.Error())
+
+	count, resetIn, err := s.IncrementRateLimitCounter(context.Background(), "key", domain.Duration{Duration: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.Equal(t, 250*time.Millisecond, resetIn.Duration)
+}
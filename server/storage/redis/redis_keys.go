@@ -2,6 +2,7 @@ package redis
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/m3dev/dsps/server/domain"
 )
@@ -20,11 +21,70 @@ func (rk channelKeys) Clock() string {
 	return fmt.Sprintf("c.{%s}.clock", rk.channelID)
 }
 
+// SCAN pattern of Clock() of all channels
+const clockKeyPattern = "c.{*}.clock"
+
+// Inverse function of Clock(), returns false if given key is not a clock key
+func channelIDOfClockKey(key string) (domain.ChannelID, bool) {
+	if !strings.HasPrefix(key, "c.{") || !strings.HasSuffix(key, "}.clock") {
+		return "", false
+	}
+	id, err := domain.ParseChannelID(key[len("c.{") : len(key)-len("}.clock")])
+	if err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// SCAN pattern of SubscriberCursor() of all subscribers in the channel
+func (rk channelKeys) SubscriberCursorPattern() string {
+	return fmt.Sprintf("c.{%s}.r.*", rk.channelID)
+}
+
+// SCAN pattern of SubscriberCursor() of all channels
+const subscriberCursorKeyPattern = "c.{*}.r.*"
+
+// Inverse function of SubscriberCursor() of any channel, returns false if given key is not a subscriber cursor key
+func locatorOfSubscriberCursorKey(key string) (domain.SubscriberLocator, bool) {
+	return parseSubscriberKey(key, "c.{", "}.r.")
+}
+
+// Parses "{prefix}{channel}{infix}{subscriber}" key
+func parseSubscriberKey(key string, prefix string, infix string) (domain.SubscriberLocator, bool) {
+	if !strings.HasPrefix(key, prefix) {
+		return domain.SubscriberLocator{}, false
+	}
+	sep := strings.Index(key, infix)
+	if sep < len(prefix) {
+		return domain.SubscriberLocator{}, false
+	}
+	channelID, err := domain.ParseChannelID(key[len(prefix):sep])
+	if err != nil {
+		return domain.SubscriberLocator{}, false
+	}
+	// Do not use ParseSubscriberID, storage also has internal subscribers (e.g. "_webhook-..." of outgoing-webhook) that API does not accept
+	subscriberID := key[sep+len(infix):]
+	if subscriberID == "" || strings.ContainsAny(subscriberID, ".{}") {
+		return domain.SubscriberLocator{}, false
+	}
+	return domain.SubscriberLocator{ChannelID: channelID, SubscriberID: domain.SubscriberID(subscriberID)}, true
+}
+
 // type of value is channelClock
 func (rk channelKeys) SubscriberCursor(rcv domain.SubscriberID) string {
 	return fmt.Sprintf("c.{%s}.r.%s", rk.channelID, rcv)
 }
 
+// type of value is visibility timeout in milliseconds, exists only if the subscriber is a queue subscriber
+func (rk channelKeys) QueueVisibilityTimeout(rcv domain.SubscriberID) string {
+	return fmt.Sprintf("c.{%s}.q.%s", rk.channelID, rcv)
+}
+
+// type of value is hash (field: channelClock, value: queueLease)
+func (rk channelKeys) QueueLeases(rcv domain.SubscriberID) string {
+	return fmt.Sprintf("c.{%s}.l.%s", rk.channelID, rcv)
+}
+
 // type of value is JSON
 func (rk channelKeys) MessageBodyPrefix() string {
 	return fmt.Sprintf("c.{%s}.m.", rk.channelID)
@@ -41,6 +101,69 @@ func (rk channelKeys) MessageDedup(id domain.MessageID) string {
 	return fmt.Sprintf("c.{%s}.mid.%s", rk.channelID, id)
 }
 
+type streamKeys struct {
+	// All keys must be prefixed with {channel-id} due to partioning.
+	channelID domain.ChannelID
+}
+
+// Keys of the Redis Streams based engine, see ../doc/storage/redis-internal-structure.md
+func keyOfStream(channelID domain.ChannelID) streamKeys {
+	return streamKeys{channelID: channelID}
+}
+
+// type of value is Redis Stream, each entry has message envelope JSON in "m" field
+func (sk streamKeys) Stream() string {
+	return fmt.Sprintf("s.{%s}.stream", sk.channelID)
+}
+
+// SCAN pattern of Stream() of all channels
+const streamKeyPattern = "s.{*}.stream"
+
+// Inverse function of Stream(), returns false if given key is not a stream key
+func channelIDOfStreamKey(key string) (domain.ChannelID, bool) {
+	if !strings.HasPrefix(key, "s.{") || !strings.HasSuffix(key, "}.stream") {
+		return "", false
+	}
+	id, err := domain.ParseChannelID(key[len("s.{") : len(key)-len("}.stream")])
+	if err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// type of value is Hash of message ID to streamID
+func (sk streamKeys) MessageIDs() string {
+	return fmt.Sprintf("s.{%s}.mid", sk.channelID)
+}
+
+// type of value is Sorted Set of message ID, score is published time in milliseconds
+func (sk streamKeys) MessageExpiry() string {
+	return fmt.Sprintf("s.{%s}.mexp", sk.channelID)
+}
+
+// type of value is streamID
+func (sk streamKeys) SubscriberCursor(rcv domain.SubscriberID) string {
+	return fmt.Sprintf("s.{%s}.r.%s", sk.channelID, rcv)
+}
+
+// type of value is visibility timeout in milliseconds, exists only if the subscriber is a queue subscriber (has a consumer group of the same name)
+func (sk streamKeys) QueueVisibilityTimeout(rcv domain.SubscriberID) string {
+	return fmt.Sprintf("s.{%s}.q.%s", sk.channelID, rcv)
+}
+
+// SCAN pattern of SubscriberCursor() of all subscribers in the channel
+func (sk streamKeys) SubscriberCursorPattern() string {
+	return fmt.Sprintf("s.{%s}.r.*", sk.channelID)
+}
+
+// SCAN pattern of SubscriberCursor() of all streams
+const streamSubscriberCursorKeyPattern = "s.{*}.r.*"
+
+// Inverse function of SubscriberCursor() of any stream, returns false if given key is not a subscriber cursor key
+func locatorOfStreamSubscriberCursorKey(key string) (domain.SubscriberLocator, bool) {
+	return parseSubscriberKey(key, "s.{", "}.r.")
+}
+
 type jtiKeys struct {
 	jti domain.JwtJti
 }
@@ -52,3 +175,27 @@ func keyOfJti(jti domain.JwtJti) jtiKeys {
 func (jti jtiKeys) Revocation() string {
 	return fmt.Sprintf("jwt.{%s}.revoke", jti.jti)
 }
+
+// SCAN pattern of Revocation() of all JTIs
+const revocationKeyPattern = "jwt.{*}.revoke"
+
+// Inverse function of Revocation(), returns false if given key is not a revocation key
+func jtiOfRevocationKey(key string) (domain.JwtJti, bool) {
+	if !strings.HasPrefix(key, "jwt.{") || !strings.HasSuffix(key, "}.revoke") || len(key) == len("jwt.{}.revoke") {
+		return "", false
+	}
+	return domain.JwtJti(key[len("jwt.{") : len(key)-len("}.revoke")]), true
+}
+
+type rateLimitKeys struct {
+	key string
+}
+
+func keyOfRateLimit(key string) rateLimitKeys {
+	return rateLimitKeys{key: key}
+}
+
+// type of value is integer (INCR counter)
+func (rk rateLimitKeys) Counter() string {
+	return fmt.Sprintf("rl.{%s}", rk.key)
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
 )
 
 func TestChannelKeys(t *testing.T) {
@@ -13,6 +15,8 @@ func TestChannelKeys(t *testing.T) {
 	// All redis keys must contain {channel-id} string to control partitioning, otherwise Lua script / transaction fails due to cross partition operation.
 	assert.Contains(t, keys.Clock(), "{my-channel}")
 	assert.Contains(t, keys.SubscriberCursor("sbsc-1"), "{my-channel}")
+	assert.Contains(t, keys.QueueVisibilityTimeout("sbsc-1"), "{my-channel}")
+	assert.Contains(t, keys.QueueLeases("sbsc-1"), "{my-channel}")
 	assert.Contains(t, keys.MessageBodyPrefix(), "{my-channel}")
 	assert.Contains(t, keys.MessageBody(1234), "{my-channel}")
 	assert.Contains(t, keys.MessageDedup("msg-1"), "{my-channel}")
@@ -25,6 +29,10 @@ func TestChannelKeys(t *testing.T) {
 	assert.NotEqual(t, keys.Clock(), keys2.Clock())
 	assert.NotEqual(t, keys.SubscriberCursor("sbsc-1"), keys.SubscriberCursor("sbsc-X"))
 	assert.NotEqual(t, keys.SubscriberCursor("sbsc-1"), keys2.SubscriberCursor("sbsc-1"))
+	assert.NotEqual(t, keys.QueueVisibilityTimeout("sbsc-1"), keys.QueueVisibilityTimeout("sbsc-X"))
+	assert.NotEqual(t, keys.QueueVisibilityTimeout("sbsc-1"), keys2.QueueVisibilityTimeout("sbsc-1"))
+	assert.NotEqual(t, keys.QueueLeases("sbsc-1"), keys.QueueLeases("sbsc-X"))
+	assert.NotEqual(t, keys.QueueLeases("sbsc-1"), keys2.QueueLeases("sbsc-1"))
 	assert.NotEqual(t, keys.MessageBodyPrefix(), keys2.MessageBodyPrefix())
 	assert.NotEqual(t, keys.MessageBody(1234), keys.MessageBody(1234+1))
 	assert.NotEqual(t, keys.MessageBody(1234), keys2.MessageBody(1234))
@@ -32,6 +40,31 @@ func TestChannelKeys(t *testing.T) {
 	assert.NotEqual(t, keys.MessageDedup("msg-1"), keys2.MessageDedup("msg-1"))
 }
 
+func TestStreamKeys(t *testing.T) {
+	keys := keyOfStream("my-channel")
+
+	// All redis keys must contain {channel-id} string to control partitioning, otherwise Lua script / transaction fails due to cross partition operation.
+	assert.Contains(t, keys.Stream(), "{my-channel}")
+	assert.Contains(t, keys.MessageIDs(), "{my-channel}")
+	assert.Contains(t, keys.MessageExpiry(), "{my-channel}")
+	assert.Contains(t, keys.SubscriberCursor("sbsc-1"), "{my-channel}")
+	assert.Contains(t, keys.QueueVisibilityTimeout("sbsc-1"), "{my-channel}")
+
+	// Must not conflict with keys of the other engine
+	assert.NotEqual(t, keyOfChannel("my-channel").SubscriberCursor("sbsc-1"), keys.SubscriberCursor("sbsc-1"))
+	assert.NotEqual(t, keyOfChannel("my-channel").QueueVisibilityTimeout("sbsc-1"), keys.QueueVisibilityTimeout("sbsc-1"))
+
+	// Check uniqueness
+	keys2 := keyOfStream("my-channel-X")
+	assert.NotEqual(t, keys.Stream(), keys2.Stream())
+	assert.NotEqual(t, keys.MessageIDs(), keys2.MessageIDs())
+	assert.NotEqual(t, keys.MessageExpiry(), keys2.MessageExpiry())
+	assert.NotEqual(t, keys.SubscriberCursor("sbsc-1"), keys.SubscriberCursor("sbsc-X"))
+	assert.NotEqual(t, keys.SubscriberCursor("sbsc-1"), keys2.SubscriberCursor("sbsc-1"))
+	assert.NotEqual(t, keys.QueueVisibilityTimeout("sbsc-1"), keys.QueueVisibilityTimeout("sbsc-X"))
+	assert.NotEqual(t, keys.QueueVisibilityTimeout("sbsc-1"), keys2.QueueVisibilityTimeout("sbsc-1"))
+}
+
 func TestJtiKeys(t *testing.T) {
 	keys := keyOfJti("my-jwt")
 
@@ -39,3 +72,79 @@ func TestJtiKeys(t *testing.T) {
 	keys2 := keyOfJti("my-jwt-X")
 	assert.NotEqual(t, keys.Revocation(), keys2.Revocation())
 }
+
+func TestRateLimitKeys(t *testing.T) {
+	keys := keyOfRateLimit("rule1.ip.127.0.0.1")
+
+	// Check uniqueness
+	keys2 := keyOfRateLimit("rule1.ip.127.0.0.2")
+	assert.NotEqual(t, keys.Counter(), keys2.Counter())
+}
+
+func TestInverseChannelKeys(t *testing.T) {
+	keys := keyOfChannel("my-channel")
+
+	id, ok := channelIDOfClockKey(keys.Clock())
+	assert.True(t, ok)
+	assert.Equal(t, "my-channel", string(id))
+	_, ok = channelIDOfClockKey(keys.SubscriberCursor("sbsc-1"))
+	assert.False(t, ok)
+	_, ok = channelIDOfClockKey("c.{INVALID ID}.clock")
+	assert.False(t, ok)
+
+	sl, ok := locatorOfSubscriberCursorKey(keys.SubscriberCursor("sbsc-1"))
+	assert.True(t, ok)
+	assert.Equal(t, domain.SubscriberLocator{ChannelID: "my-channel", SubscriberID: "sbsc-1"}, sl)
+	_, ok = locatorOfSubscriberCursorKey(keys.Clock())
+	assert.False(t, ok)
+	_, ok = locatorOfSubscriberCursorKey(keys.QueueVisibilityTimeout("sbsc-1"))
+	assert.False(t, ok)
+	_, ok = locatorOfSubscriberCursorKey(keys.QueueLeases("sbsc-1"))
+	assert.False(t, ok)
+	_, ok = locatorOfSubscriberCursorKey(keys.MessageBody(1))
+	assert.False(t, ok)
+	_, ok = locatorOfSubscriberCursorKey("c.{INVALID ID}.r.sbsc-1")
+	assert.False(t, ok)
+	_, ok = locatorOfSubscriberCursorKey("c.{my-channel}.r.")
+	assert.False(t, ok)
+	sl, ok = locatorOfSubscriberCursorKey(keys.SubscriberCursor("_webhook-0123456789abcdef"))
+	assert.True(t, ok, "internal subscribers must be found")
+	assert.Equal(t, domain.SubscriberLocator{ChannelID: "my-channel", SubscriberID: "_webhook-0123456789abcdef"}, sl)
+	_, ok = locatorOfSubscriberCursorKey(keyOfStream("my-channel").SubscriberCursor("sbsc-1"))
+	assert.False(t, ok)
+}
+
+func TestInverseStreamKeys(t *testing.T) {
+	keys := keyOfStream("my-channel")
+
+	id, ok := channelIDOfStreamKey(keys.Stream())
+	assert.True(t, ok)
+	assert.Equal(t, "my-channel", string(id))
+	_, ok = channelIDOfStreamKey(keys.MessageIDs())
+	assert.False(t, ok)
+	_, ok = channelIDOfStreamKey("s.{INVALID ID}.stream")
+	assert.False(t, ok)
+
+	sl, ok := locatorOfStreamSubscriberCursorKey(keys.SubscriberCursor("sbsc-1"))
+	assert.True(t, ok)
+	assert.Equal(t, domain.SubscriberLocator{ChannelID: "my-channel", SubscriberID: "sbsc-1"}, sl)
+	_, ok = locatorOfStreamSubscriberCursorKey(keys.MessageIDs())
+	assert.False(t, ok)
+	_, ok = locatorOfStreamSubscriberCursorKey(keys.QueueVisibilityTimeout("sbsc-1"))
+	assert.False(t, ok)
+	sl, ok = locatorOfStreamSubscriberCursorKey(keys.SubscriberCursor("_webhook-0123456789abcdef"))
+	assert.True(t, ok, "internal subscribers must be found")
+	assert.Equal(t, domain.SubscriberLocator{ChannelID: "my-channel", SubscriberID: "_webhook-0123456789abcdef"}, sl)
+	_, ok = locatorOfStreamSubscriberCursorKey(keyOfChannel("my-channel").SubscriberCursor("sbsc-1"))
+	assert.False(t, ok)
+}
+
+func TestInverseJtiKeys(t *testing.T) {
+	jti, ok := jtiOfRevocationKey(keyOfJti("my-jti").Revocation())
+	assert.True(t, ok)
+	assert.Equal(t, "my-jti", string(jti))
+	_, ok = jtiOfRevocationKey(keyOfChannel("my-channel").Clock())
+	assert.False(t, ok)
+	_, ok = jtiOfRevocationKey("jwt.{}.revoke")
+	assert.False(t, ok)
+}
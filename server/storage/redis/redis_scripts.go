@@ -13,5 +13,10 @@ func (s *redisStorage) loadScripts(ctx context.Context) error {
 	g, ctx := errgroup.WithContext(ctx)
 	g.Go(func() error { return s.loadPubSubMessagingScripts(ctx) })
 	g.Go(func() error { return s.loadPubSubSubscriberScripts(ctx) })
+	g.Go(func() error { return s.loadPubSubQueueScripts(ctx) })
+	g.Go(func() error { return s.loadRateLimitScripts(ctx) })
+	if s.streams != nil {
+		g.Go(func() error { return s.loadStreamsScripts(ctx) })
+	}
 	return g.Wait()
 }
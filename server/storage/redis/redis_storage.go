@@ -38,7 +38,11 @@ func NewRedisStorage(ctx context.Context, config *config.RedisStorageConfig, sys
 			logger.Of(ctx).Error(fmt.Sprintf(`error in background routine "%s"`, name), err)
 		}),
 	}
-	s.pubsubDispatcher = pubsub.NewDispatcher(ctx, deps, pubsub.DispatcherParams{}, conn.RedisCmd.PSubscribeFunc(), s.redisPubSubKeyPattern())
+	if config.IsStreamsEngine() {
+		s.streams = &redisStreamsStorage{base: s}
+	} else {
+		s.pubsubDispatcher = pubsub.NewDispatcher(ctx, deps, pubsub.DispatcherParams{}, conn.RedisCmd.PSubscribeFunc(), s.redisPubSubKeyPattern())
+	}
 	if err := s.loadScripts(ctx); err != nil {
 		return nil, err
 	}
@@ -60,13 +64,17 @@ type redisStorage struct {
 
 	internal.RedisConnection
 	daemonSystem     *sync.DaemonSystem
-	pubsubDispatcher pubsub.RedisPubSubDispatcher
+	pubsubDispatcher pubsub.RedisPubSubDispatcher // Only for "keys" engine
+	streams          *redisStreamsStorage         // Only for "streams" engine
 }
 
 func (s *redisStorage) AsPubSubStorage() domain.PubSubStorage {
 	if !s.pubsubEnabled {
 		return nil
 	}
+	if s.streams != nil {
+		return s.streams
+	}
 	return s
 }
 func (s *redisStorage) AsJwtStorage() domain.JwtStorage {
@@ -75,11 +83,17 @@ func (s *redisStorage) AsJwtStorage() domain.JwtStorage {
 	}
 	return s
 }
+func (s *redisStorage) AsRateLimitStorage() domain.RateLimitStorage {
+	return s
+}
 
 func (s *redisStorage) String() string {
 	if s.RedisConnection.IsSingleNode {
 		return "redis-singlenode"
 	}
+	if s.RedisConnection.IsSentinel {
+		return "redis-sentinel"
+	}
 	return "redis-cluster"
 }
 
@@ -88,7 +102,9 @@ func (s *redisStorage) Shutdown(ctx context.Context) error {
 		logger.Of(ctx).WarnError(logger.CatStorage, `Failed to stop background routines`, err)
 	}
 
-	s.pubsubDispatcher.Shutdown(ctx)
+	if s.pubsubDispatcher != nil {
+		s.pubsubDispatcher.Shutdown(ctx)
+	}
 
 	logger.Of(ctx).Debugf(logger.CatStorage, "Closing Redis storage connections...")
 	return s.RedisConnection.Close()
@@ -2,7 +2,13 @@ package redis
 
 import (
 	"context"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/go-redis/redis/v8"
@@ -45,3 +51,49 @@ func TestInitialLoadScriptFailure(t *testing.T) {
 	)
 	assert.Regexp(t, `Error compiling script \(new function\)`, err.Error())
 }
+
+func TestTLSConfigFailure(t *testing.T) {
+	cfg, err := config.ParseConfig(context.Background(), config.Overrides{}, `storages: { myRedis: { redis: { singleNode: "127.0.0.1:9999", tls: { enable: true, caFile: "/no/such/ca.pem" } } } }`)
+	assert.NoError(t, err)
+
+	_, err = NewRedisStorage(
+		context.Background(),
+		cfg.Storages["myRedis"].Redis,
+		domain.RealSystemClock,
+		storagetesting.StubChannelProvider,
+		EmptyDeps(t),
+	)
+	assert.Regexp(t, `Failed to setup TLS of Redis connection: failed to read CA file`, err.Error())
+}
+
+func TestTLSHandshake(t *testing.T) {
+	// Not a Redis server, but enough to confirm that TLS handshake is made with given settings.
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	connect := func(tls string) error {
+		cfg, err := config.ParseConfig(context.Background(), config.Overrides{}, fmt.Sprintf(`storages: { myRedis: { redis: { singleNode: "%s", tls: %s, timeout: { read: 100ms }, retry: { count: 0 }, connection: { max: 10, min: 0 } } } }`, addr, tls))
+		assert.NoError(t, err)
+		_, err = NewRedisStorage(
+			context.Background(),
+			cfg.Storages["myRedis"].Redis,
+			domain.RealSystemClock,
+			storagetesting.StubChannelProvider,
+			EmptyDeps(t),
+		)
+		return err
+	}
+
+	err := connect(`{ enable: true }`)
+	assert.Regexp(t, `x509: certificate`, err.Error()) // Untrusted server certificate
+
+	err = connect(fmt.Sprintf(`{ enable: true, caFile: "%s" }`, caFile))
+	assert.NotRegexp(t, `x509|tls`, err.Error()) // Handshake succeeded but server does not speak Redis protocol
+
+	err = connect(`{ enable: true, insecureSkipVerify: true }`)
+	assert.NotRegexp(t, `x509|tls`, err.Error())
+}
@@ -29,6 +29,22 @@ var storageCtor func(t *testing.T) StorageCtor = func(t *testing.T) StorageCtor
 	}
 }
 
+var streamsStorageCtor func(t *testing.T) StorageCtor = func(t *testing.T) StorageCtor {
+	return func(ctx context.Context, systemClock domain.SystemClock, channelProvider domain.ChannelProvider) (domain.Storage, error) {
+		cfg, err := config.ParseConfig(context.Background(), config.Overrides{}, fmt.Sprintf(`storages: { myRedis: { redis: { singleNode: "%s", engine: "streams", timeout: { connect: 500ms }, connection: { max: 10 } } } }`, GetRedisAddr(nil)))
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisStorage(
+			context.Background(),
+			cfg.Storages["myRedis"].Redis,
+			systemClock,
+			channelProvider,
+			EmptyDeps(t),
+		)
+	}
+}
+
 var storageMultiplexCtor func(t *testing.T) StorageCtor = func(t *testing.T) StorageCtor {
 	return func(ctx context.Context, systemClock domain.SystemClock, channelProvider domain.ChannelProvider) (domain.Storage, error) {
 		redis1, err := storageCtor(t)(ctx, systemClock, channelProvider)
@@ -60,6 +76,14 @@ func TestPubSubMultiplex(t *testing.T) {
 	PubSubTest(t, storageMultiplexCtor(t))
 }
 
+func TestCoreFunctionStreams(t *testing.T) {
+	CoreFunctionTest(t, streamsStorageCtor(t))
+}
+
+func TestPubSubStreams(t *testing.T) {
+	PubSubTest(t, streamsStorageCtor(t))
+}
+
 func TestJwt(t *testing.T) {
 	JwtTest(t, storageCtor(t))
 }
@@ -69,3 +93,13 @@ func TestJwtMultiplex(t *testing.T) {
 	// It behaves as single storage because operations are idempotent.
 	JwtTest(t, storageMultiplexCtor(t))
 }
+
+func TestRateLimit(t *testing.T) {
+	RateLimitTest(t, storageCtor(t))
+}
+
+func TestRateLimitMultiplex(t *testing.T) {
+	// Test with two duplicate storages.
+	// Counters must be stored in only one of them, otherwise it counts twice.
+	RateLimitTest(t, storageMultiplexCtor(t))
+}
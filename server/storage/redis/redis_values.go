@@ -1,6 +1,12 @@
 package redis
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/m3dev/dsps/server/domain"
+)
 
 func parseChannelClock(value string) *channelClock {
 	i := parseRedisInt64(value)
@@ -19,3 +25,65 @@ func parseRedisInt64(value string) *int64 {
 	}
 	return &result
 }
+
+// Value of the subscriber cursor is "{clock}" or "{clock}:{filter JSON}".
+func parseSubscriberCursor(value string) (*channelClock, *domain.SubscriberFilter) {
+	var filter *domain.SubscriberFilter
+	if sep := strings.IndexByte(value, ':'); sep >= 0 {
+		var err error
+		if filter, err = domain.ParseSubscriberFilter([]byte(value[sep+1:])); err != nil {
+			return nil, nil
+		}
+		value = value[:sep]
+	}
+	return parseChannelClock(value), filter
+}
+
+func formatSubscriberCursorFilter(filter *domain.SubscriberFilter) string {
+	if filter == nil {
+		return ""
+	}
+	return ":" + filter.String()
+}
+
+// Value of the queue visibility timeout is milliseconds.
+func parseVisibilityTimeout(value string) *domain.Duration {
+	ms := parseRedisInt64(value)
+	if ms == nil || *ms <= 0 {
+		return nil
+	}
+	return &domain.Duration{Duration: time.Duration(*ms) * time.Millisecond}
+}
+
+// Value of the queue lease is queueLeaseAcked or "{lease ID}:{expiry in unix milliseconds}".
+const queueLeaseAcked = "acked"
+
+func formatQueueLease(leaseID string, expireAt time.Time) string {
+	return leaseID + ":" + strconv.FormatInt(expireAt.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// parseQueueLeaseExpiry returns expiry of the lease, returns false if the value is not a lease (acknowledged or corrupted).
+func parseQueueLeaseExpiry(value string) (time.Time, bool) {
+	sep := strings.LastIndexByte(value, ':')
+	if sep < 0 {
+		return time.Time{}, false
+	}
+	expireAt := parseRedisInt64(value[sep+1:])
+	if expireAt == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, *expireAt*int64(time.Millisecond)), true
+}
+
+// isQueueLeaseVisible returns true if the message is not acknowledged and not leased to a consumer (or lease expired).
+// Empty string means the message has not been leased yet.
+func isQueueLeaseVisible(value string, now time.Time) bool {
+	if value == "" {
+		return true
+	}
+	if value == queueLeaseAcked {
+		return false
+	}
+	expireAt, ok := parseQueueLeaseExpiry(value)
+	return !ok || !expireAt.After(now) // Lua script also treats corrupted lease as expired one
+}
@@ -2,8 +2,11 @@ package redis
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
 )
 
 func TestParseRedisInt64(t *testing.T) {
@@ -14,3 +17,51 @@ func TestParseRedisInt64(t *testing.T) {
 	assert.Equal(t, int64(-9223372036854775808), *parseRedisInt64("-9223372036854775808"))
 	assert.Equal(t, int64(9223372036854775807), *parseRedisInt64("9223372036854775807"))
 }
+
+func TestParseSubscriberCursor(t *testing.T) {
+	clock, filter := parseSubscriberCursor("123")
+	assert.Equal(t, channelClock(123), *clock)
+	assert.Nil(t, filter)
+
+	clock, filter = parseSubscriberCursor(`-123:{"attributes":{"event-type":"created"}}`)
+	assert.Equal(t, channelClock(-123), *clock)
+	assert.Equal(t, map[string]string{"event-type": "created"}, filter.Attributes)
+
+	clock, _ = parseSubscriberCursor(`123:INVALID`)
+	assert.Nil(t, clock)
+	clock, _ = parseSubscriberCursor(`INVALID`)
+	assert.Nil(t, clock)
+}
+
+func TestFormatSubscriberCursorFilter(t *testing.T) {
+	assert.Equal(t, "", formatSubscriberCursorFilter(nil))
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `:{"attributes":{"event-type":"created"}}`, formatSubscriberCursorFilter(filter))
+}
+
+func TestParseVisibilityTimeout(t *testing.T) {
+	assert.Equal(t, 1500*time.Millisecond, parseVisibilityTimeout("1500").Duration)
+	assert.Nil(t, parseVisibilityTimeout("0"))
+	assert.Nil(t, parseVisibilityTimeout("-1"))
+	assert.Nil(t, parseVisibilityTimeout("INVALID"))
+}
+
+func TestQueueLease(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	lease := formatQueueLease("lease-1", now.Add(time.Second))
+	assert.Equal(t, "lease-1:1600000001000", lease)
+	assert.False(t, isQueueLeaseVisible(lease, now))
+	assert.True(t, isQueueLeaseVisible(lease, now.Add(time.Second)))
+
+	assert.True(t, isQueueLeaseVisible("", now))
+	assert.False(t, isQueueLeaseVisible(queueLeaseAcked, now))
+	assert.True(t, isQueueLeaseVisible("INVALID", now))
+	assert.True(t, isQueueLeaseVisible("lease-1:INVALID", now))
+
+	expireAt, ok := parseQueueLeaseExpiry(lease)
+	assert.True(t, ok)
+	assert.True(t, now.Add(time.Second).Equal(expireAt))
+	_, ok = parseQueueLeaseExpiry(queueLeaseAcked)
+	assert.False(t, ok)
+}
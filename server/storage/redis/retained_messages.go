@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// Max count of keys in a MGET to probe message bodies
+const retainedMessagesProbeSize = 32
+
+// Max distance from the channel clock that a message can be retained (clock space wraps around beyond it)
+const retainedMessagesMaxAge = int64(clockMax) - int64(clockMin)
+
+// findOldestMessageClock returns clock of the oldest retained message of the channel, returns false if no message retained.
+//
+// Clocks of retained messages are contiguous up to the channel clock, because all messages of a channel have the same TTL
+// and backlog limit evicts the oldest one (see publishMessageScript).
+// Thus this method finds the boundary by probing message bodies backward from the channel clock with MGET, rather than SCAN the whole keyspace.
+func (s *redisStorage) findOldestMessageClock(ctx context.Context, channelID domain.ChannelID, chClock channelClock) (channelClock, bool, error) {
+	keys := keyOfChannel(channelID)
+	exists := func(ages []int64) ([]bool, error) {
+		msgKeys := make([]string, len(ages))
+		for i, age := range ages {
+			msgKeys[i] = keys.MessageBody(clockBefore(chClock, age))
+		}
+		values, err := s.RedisCmd.MGet(ctx, msgKeys...)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]bool, len(ages))
+		for i, value := range values {
+			result[i] = value != nil
+		}
+		return result, nil
+	}
+
+	// Probe ages (distance from the channel clock) 0, 1, 2, 4, 8, ... at once to narrow the range roughly
+	ages := []int64{0}
+	for age := int64(1); age <= retainedMessagesMaxAge; age *= 2 {
+		ages = append(ages, age)
+	}
+	found, err := exists(ages)
+	if err != nil {
+		return 0, false, err
+	}
+	// Invariant: message of age lo exists (-1 means no message), message of age hi does not exist.
+	lo, hi := narrowAgeRange(-1, retainedMessagesMaxAge+1, ages, found)
+
+	// Then probe ages evenly distributed in the range until the boundary found
+	for hi-lo > 1 {
+		ages = ages[:0]
+		for i := int64(1); i <= retainedMessagesProbeSize; i++ {
+			age := lo + (hi-lo)*i/(retainedMessagesProbeSize+1)
+			if age > lo && age < hi && (len(ages) == 0 || ages[len(ages)-1] != age) {
+				ages = append(ages, age)
+			}
+		}
+		found, err := exists(ages)
+		if err != nil {
+			return 0, false, err
+		}
+		lo, hi = narrowAgeRange(lo, hi, ages, found)
+	}
+	if lo < 0 {
+		return 0, false, nil
+	}
+	return clockBefore(chClock, lo), true, nil
+}
+
+// narrowAgeRange returns new (lo, hi) by probe results, ages must be sorted.
+func narrowAgeRange(lo int64, hi int64, ages []int64, found []bool) (int64, int64) {
+	for i, age := range ages {
+		if !found[i] {
+			return lo, age
+		}
+		lo = age
+	}
+	return lo, hi
+}
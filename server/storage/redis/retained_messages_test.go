@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	dspstesting "github.com/m3dev/dsps/server/testing"
+)
+
+func TestFindOldestMessageClock(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		chClock channelClock
+		count   int64 // Count of retained messages
+	}{
+		{"no message", 100, 0},
+		{"only latest", 100, 1},
+		{"power of two", 1000, 64},
+		{"between probes", 1000, 777},
+		{"large backlog", 10000000, 1234567},
+		{"wrap-around", clockMin + 5, 1000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ch := randomChannelID(t)
+			s, redisCmd, _ := newMockedRedisStorageAndPubSubDispatcher(ctrl)
+			oldest := clockBefore(tc.chClock, tc.count-1)
+			calls := 0
+			redisCmd.EXPECT().MGet(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, keys ...string) ([]*string, error) {
+				calls++
+				assert.LessOrEqual(t, len(keys), 64)
+				result := make([]*string, len(keys))
+				for i, key := range keys {
+					// Emulate contiguous messages from oldest to chClock
+					clock := parseChannelClock(key[len(keyOfChannel(ch).MessageBodyPrefix()):])
+					if assert.NotNil(t, clock) && tc.count > 0 && isClockWithin(*clock, prevClock(oldest), tc.chClock) {
+						body := "body"
+						result[i] = &body
+					}
+				}
+				return result, nil
+			}).AnyTimes()
+
+			clock, found, err := s.findOldestMessageClock(context.Background(), ch, tc.chClock)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.count > 0, found)
+			if tc.count > 0 {
+				assert.Equal(t, oldest, clock)
+			}
+			assert.LessOrEqual(t, calls, 12)
+		})
+	}
+}
+
+func TestFindOldestMessageClockError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s, redisCmd, _ := newMockedRedisStorageAndPubSubDispatcher(ctrl)
+	errToReturn := errors.New("Mocked Redis error")
+	redisCmd.EXPECT().MGet(gomock.Any(), gomock.Any()).Return(nil, errToReturn)
+
+	_, _, err := s.findOldestMessageClock(context.Background(), randomChannelID(t), 100)
+	dspstesting.IsError(t, errToReturn, err)
+}
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// streamID is an ID of a Redis Stream entry ("{milliseconds}-{sequence}")
+type streamID struct {
+	ms  int64
+	seq int64
+}
+
+// streamsPublishScript never assigns sequence number greater than or equal to this,
+// so that a streamID can be packed into int64 clock (see clock()) for domain.ChannelInspection or rewinding.
+const streamSeqPerMs = 1000
+
+func parseStreamID(value string) *streamID {
+	sep := strings.IndexByte(value, '-')
+	if sep < 0 {
+		return nil
+	}
+	ms, err := strconv.ParseInt(value[:sep], 10, 64)
+	if err != nil || ms < 0 {
+		return nil
+	}
+	seq, err := strconv.ParseInt(value[sep+1:], 10, 64)
+	if err != nil || seq < 0 {
+		return nil
+	}
+	return &streamID{ms: ms, seq: seq}
+}
+
+func (id streamID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+// Less returns true if this ID is older than the given one
+func (id streamID) Less(other streamID) bool {
+	return id.ms < other.ms || (id.ms == other.ms && id.seq < other.seq)
+}
+
+// next returns the smallest ID greater than this, used as inclusive start of XRANGE
+func (id streamID) next() streamID {
+	return streamID{ms: id.ms, seq: id.seq + 1}
+}
+
+// clock packs the ID into int64, the result is less than 2^53 so that it can be represented in JSON number safely
+func (id streamID) clock() int64 {
+	return id.ms*streamSeqPerMs + id.seq
+}
+
+// Inverse function of clock(), returns nil for negative clock
+func streamIDOfClock(clock int64) *streamID {
+	if clock < 0 {
+		return nil
+	}
+	return &streamID{ms: clock / streamSeqPerMs, seq: clock % streamSeqPerMs}
+}
+
+// streamIDOfTime returns the smallest ID of the given time
+func streamIDOfTime(t time.Time) streamID {
+	return streamID{ms: t.UnixNano() / 1e6}
+}
+
+// Value of the subscriber cursor is "{streamID}" or "{streamID}:{filter JSON}".
+func parseStreamSubscriberCursor(value string) (*streamID, *domain.SubscriberFilter) {
+	var filter *domain.SubscriberFilter
+	if sep := strings.IndexByte(value, ':'); sep >= 0 {
+		var err error
+		if filter, err = domain.ParseSubscriberFilter([]byte(value[sep+1:])); err != nil {
+			return nil, nil
+		}
+		value = value[:sep]
+	}
+	return parseStreamID(value), filter
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStreamID(t *testing.T) {
+	assert.Equal(t, &streamID{ms: 1609459200000, seq: 12}, parseStreamID("1609459200000-12"))
+	assert.Equal(t, &streamID{}, parseStreamID("0-0"))
+	assert.Equal(t, "1609459200000-12", parseStreamID("1609459200000-12").String())
+
+	assert.Nil(t, parseStreamID(""))
+	assert.Nil(t, parseStreamID("1609459200000"))
+	assert.Nil(t, parseStreamID("1609459200000-"))
+	assert.Nil(t, parseStreamID("-1-0"))
+	assert.Nil(t, parseStreamID("a-b"))
+}
+
+func TestStreamIDOrder(t *testing.T) {
+	id := streamID{ms: 1000, seq: 5}
+	assert.True(t, id.Less(streamID{ms: 1000, seq: 6}))
+	assert.True(t, id.Less(streamID{ms: 1001, seq: 0}))
+	assert.False(t, id.Less(id))
+	assert.False(t, id.Less(streamID{ms: 999, seq: 999}))
+
+	assert.Equal(t, streamID{ms: 1000, seq: 6}, id.next())
+	assert.True(t, id.Less(id.next()))
+}
+
+func TestStreamIDClock(t *testing.T) {
+	id := streamID{ms: 1609459200000, seq: 999}
+	assert.Equal(t, int64(1609459200000999), id.clock())
+	assert.Equal(t, &id, streamIDOfClock(id.clock()))
+	assert.Less(t, id.clock(), int64(1)<<53)
+
+	// Clock order must be same as ID order
+	assert.Less(t, id.clock(), streamID{ms: 1609459200001}.clock())
+	assert.Equal(t, &streamID{ms: 1609459200000, seq: 998}, streamIDOfClock(id.clock()-1))
+	assert.Equal(t, &streamID{ms: 1609459199999, seq: 999}, streamIDOfClock(streamID{ms: 1609459200000}.clock()-1))
+
+	assert.Equal(t, &streamID{}, streamIDOfClock(0))
+	assert.Nil(t, streamIDOfClock(-1))
+}
+
+func TestStreamIDOfTime(t *testing.T) {
+	tm := time.Date(2021, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	assert.Equal(t, streamID{ms: 1609459200123}, streamIDOfTime(tm))
+}
+
+func TestParseStreamSubscriberCursor(t *testing.T) {
+	id, filter := parseStreamSubscriberCursor("1609459200000-12")
+	assert.Equal(t, &streamID{ms: 1609459200000, seq: 12}, id)
+	assert.Nil(t, filter)
+
+	id, filter = parseStreamSubscriberCursor(`1609459200000-12:{"attributes":{"event-type":"created"}}`)
+	assert.Equal(t, &streamID{ms: 1609459200000, seq: 12}, id)
+	if assert.NotNil(t, filter) {
+		assert.Equal(t, `{"attributes":{"event-type":"created"}}`, filter.String())
+	}
+
+	id, filter = parseStreamSubscriberCursor(`1609459200000-12:{invalid`)
+	assert.Nil(t, id)
+	assert.Nil(t, filter)
+}
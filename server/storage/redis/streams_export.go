@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+func (rs *redisStreamsStorage) ExportChannel(ctx context.Context, channelID domain.ChannelID) (domain.ChannelExport, error) {
+	scanned, err := scanSubscriberIDs(ctx, rs.base.RedisCmd, keyOfStream(channelID).SubscriberCursorPattern(), locatorOfStreamSubscriberCursorKey)
+	if err != nil {
+		return domain.ChannelExport{ChannelID: channelID}, xerrors.Errorf("ExportChannel failed due to Redis error (subscriber SCAN error): %w", err)
+	}
+	return rs.exportChannel(ctx, channelID, scanned[channelID])
+}
+
+// ExportChannels SCANs channels and subscribers only once, rather than for each channel.
+func (rs *redisStreamsStorage) ExportChannels(ctx context.Context, fn func(domain.ChannelExport) error) error {
+	channelIDs, err := scanChannelIDs(ctx, rs.base.RedisCmd, streamKeyPattern, channelIDOfStreamKey)
+	if err != nil {
+		return xerrors.Errorf("ExportChannels failed due to Redis error (channel SCAN error): %w", err)
+	}
+	sbscIDs, err := scanSubscriberIDs(ctx, rs.base.RedisCmd, streamSubscriberCursorKeyPattern, locatorOfStreamSubscriberCursorKey)
+	if err != nil {
+		return xerrors.Errorf("ExportChannels failed due to Redis error (subscriber SCAN error): %w", err)
+	}
+	for _, id := range channelIDs {
+		export, err := rs.exportChannel(ctx, id, sbscIDs[id])
+		if err != nil {
+			return err
+		}
+		if len(export.Messages) == 0 && len(export.Subscribers) == 0 {
+			continue // Expired after SCAN
+		}
+		if err := fn(export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sbscIDs are subscribers found by SCAN, could contain expired ones.
+func (rs *redisStreamsStorage) exportChannel(ctx context.Context, channelID domain.ChannelID, sbscIDs []domain.SubscriberID) (domain.ChannelExport, error) {
+	result := domain.ChannelExport{
+		ChannelID:   channelID,
+		Messages:    []domain.Message{},
+		Subscribers: []domain.SubscriberExport{},
+	}
+	lastID, err := runStreamsLastIDScript(ctx, rs.base.RedisCmd, channelID)
+	if err != nil {
+		return result, xerrors.Errorf("ExportChannel failed: %w", err)
+	}
+	if lastID == nil {
+		return result, nil // Channel expired or not exists
+	}
+
+	// Messages
+	entryIDs, entries, err := rs.retainedEntries(ctx, channelID)
+	if err != nil {
+		return result, xerrors.Errorf("ExportChannel failed: %w", err)
+	}
+	exportedIDs := make([]streamID, 0, len(entries))
+	for i, entry := range entries {
+		raw, _ := entry.Values["m"].(string)
+		msg, err := unwrapMessage(channelID, raw)
+		if err != nil || msg == nil {
+			if err != nil {
+				logger.Of(ctx).Error(fmt.Sprintf("Skipped corrupted message (chID: %s, stream ID: %s) on export", channelID, entry.ID), err)
+			}
+			continue
+		}
+		result.Messages = append(result.Messages, *msg)
+		exportedIDs = append(exportedIDs, entryIDs[i])
+	}
+
+	// Subscribers
+	cursors, vts, err := rs.subscriberCursors(ctx, channelID, sbscIDs)
+	if err != nil {
+		return result, xerrors.Errorf("ExportChannel failed: %w", err)
+	}
+	for i, sbscID := range sbscIDs {
+		if cursors[i] == nil {
+			continue // Subscriber expired or removed after SCAN
+		}
+		cursor, filter := parseStreamSubscriberCursor(*cursors[i])
+		if cursor == nil {
+			continue // Corrupted, FetchMessages also treats it as missing subscriber
+		}
+		export := domain.SubscriberExport{
+			SubscriberID: sbscID,
+			Filter:       filter,
+			Consumed:     countStreamIDsUntil(exportedIDs, *cursor),
+		}
+		if vts[i] != nil {
+			export.VisibilityTimeout = parseVisibilityTimeout(*vts[i])
+		}
+		result.Subscribers = append(result.Subscribers, export)
+	}
+	return result, nil
+}
@@ -0,0 +1,147 @@
+package redis
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (rs *redisStreamsStorage) InspectChannels(ctx context.Context, channelID domain.ChannelID) ([]domain.ChannelInspection, error) {
+	channelIDs := []domain.ChannelID{channelID}
+	cursorPattern := keyOfStream(channelID).SubscriberCursorPattern()
+	if channelID == "" {
+		cursorPattern = streamSubscriberCursorKeyPattern
+		var err error
+		channelIDs, err = scanChannelIDs(ctx, rs.base.RedisCmd, streamKeyPattern, channelIDOfStreamKey)
+		if err != nil {
+			return nil, xerrors.Errorf("InspectChannels failed due to Redis error (channel SCAN error): %w", err)
+		}
+	}
+	sbscIDs, err := scanSubscriberIDs(ctx, rs.base.RedisCmd, cursorPattern, locatorOfStreamSubscriberCursorKey)
+	if err != nil {
+		return nil, xerrors.Errorf("InspectChannels failed due to Redis error (subscriber SCAN error): %w", err)
+	}
+
+	result := make([]domain.ChannelInspection, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		inspection, err := rs.inspectChannel(ctx, id, sbscIDs[id])
+		if err != nil {
+			return nil, err
+		}
+		if inspection != nil {
+			result = append(result, *inspection)
+		}
+	}
+	return result, nil
+}
+
+// Returns nil if the channel does not exist.
+// sbscIDs are subscribers found by SCAN, could contain expired ones.
+func (rs *redisStreamsStorage) inspectChannel(ctx context.Context, channelID domain.ChannelID, sbscIDs []domain.SubscriberID) (*domain.ChannelInspection, error) {
+	keys := keyOfStream(channelID)
+	lastID, err := runStreamsLastIDScript(ctx, rs.base.RedisCmd, channelID)
+	if err != nil {
+		return nil, xerrors.Errorf("InspectChannels failed: %w", err)
+	}
+	if lastID == nil {
+		return nil, nil // Channel expired or not exists
+	}
+	entryIDs, _, err := rs.retainedEntries(ctx, channelID)
+	if err != nil {
+		return nil, xerrors.Errorf("InspectChannels failed: %w", err)
+	}
+	cursors, vts, err := rs.subscriberCursors(ctx, channelID, sbscIDs)
+	if err != nil {
+		return nil, xerrors.Errorf("InspectChannels failed: %w", err)
+	}
+	chTTL, err := rs.base.inspectTTL(ctx, keys.Stream())
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &domain.ChannelInspection{
+		ChannelID:   channelID,
+		Clock:       lastID.clock(),
+		TTL:         chTTL,
+		Subscribers: make([]domain.SubscriberInspection, 0, len(sbscIDs)),
+	}
+	for i, sbscID := range sbscIDs {
+		if cursors[i] == nil {
+			continue // Subscriber expired or removed after SCAN
+		}
+		cursor, filter := parseStreamSubscriberCursor(*cursors[i])
+		if cursor == nil {
+			continue // Corrupted, FetchMessages also treats it as missing subscriber
+		}
+		ttl, err := rs.base.inspectTTL(ctx, keys.SubscriberCursor(sbscID))
+		if err != nil {
+			return nil, err
+		}
+		si := domain.SubscriberInspection{
+			SubscriberID: sbscID,
+			Clock:        cursor.clock(),
+			Backlog:      int64(len(entryIDs) - countStreamIDsUntil(entryIDs, *cursor)),
+			TTL:          ttl,
+			Filter:       filter,
+		}
+		if vts[i] != nil {
+			si.VisibilityTimeout = parseVisibilityTimeout(*vts[i])
+		}
+		if si.VisibilityTimeout != nil {
+			sl := domain.SubscriberLocator{ChannelID: channelID, SubscriberID: sbscID}
+			if si.Leased, err = runStreamsQueueLeasedScript(ctx, rs.base.RedisCmd, sl, *si.VisibilityTimeout); err != nil {
+				return nil, xerrors.Errorf("InspectChannels failed: %w", err)
+			}
+		}
+		inspection.Subscribers = append(inspection.Subscribers, si)
+	}
+	return inspection, nil
+}
+
+// retainedEntries returns unexpired entries of the stream in ascending order, entries with corrupted ID are excluded.
+func (rs *redisStreamsStorage) retainedEntries(ctx context.Context, channelID domain.ChannelID) ([]streamID, []redis.XMessage, error) {
+	ttl, err := rs.base.channelRedisTTLSec(channelID)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	entries, err := rs.base.RedisCmd.XRange(ctx, keyOfStream(channelID).Stream(), rs.retentionStart(ttl).String(), "+", 0)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("Redis error (XRANGE error): %w", err)
+	}
+	ids := make([]streamID, 0, len(entries))
+	validEntries := make([]redis.XMessage, 0, len(entries))
+	for _, entry := range entries {
+		if id := parseStreamID(entry.ID); id != nil {
+			ids = append(ids, *id)
+			validEntries = append(validEntries, entry)
+		}
+	}
+	return ids, validEntries, nil
+}
+
+// subscriberCursors returns cursor values and visibility timeouts of the subscribers.
+// Cursor is nil if the subscriber vanished, visibility timeout is nil if the subscriber is not a queue subscriber.
+func (rs *redisStreamsStorage) subscriberCursors(ctx context.Context, channelID domain.ChannelID, sbscIDs []domain.SubscriberID) ([]*string, []*string, error) {
+	keys := keyOfStream(channelID)
+	mGetKeys := make([]string, 0, len(sbscIDs)*2)
+	for _, sbscID := range sbscIDs {
+		mGetKeys = append(mGetKeys, keys.SubscriberCursor(sbscID))
+	}
+	for _, sbscID := range sbscIDs {
+		mGetKeys = append(mGetKeys, keys.QueueVisibilityTimeout(sbscID))
+	}
+	values, err := rs.base.RedisCmd.MGet(ctx, mGetKeys...)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("Redis error (cursor MGET error): %w", err)
+	}
+	return values[:len(sbscIDs)], values[len(sbscIDs):], nil
+}
+
+// countStreamIDsUntil returns count of IDs less than or equal to the cursor, ids must be sorted.
+func countStreamIDsUntil(ids []streamID, cursor streamID) int {
+	return sort.Search(len(ids), func(i int) bool { return cursor.Less(ids[i]) })
+}
@@ -0,0 +1,347 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+	internal "github.com/m3dev/dsps/server/storage/redis/internal"
+)
+
+func (s *redisStorage) loadStreamsScripts(ctx context.Context) error {
+	for name, script := range map[string]*redis.Script{
+		"streamsPublishScript":          streamsPublishScript,
+		"streamsCreateSubscriberScript": streamsCreateSubscriberScript,
+		"streamsMoveCursorScript":       streamsMoveCursorScript,
+		"streamsLastIDScript":           streamsLastIDScript,
+		"streamsRemoveSubscriberScript": streamsRemoveSubscriberScript,
+		"streamsQueueLeaseScript":       streamsQueueLeaseScript,
+		"streamsQueueAckScript":         streamsQueueAckScript,
+		"streamsQueueLeasedScript":      streamsQueueLeasedScript,
+	} {
+		if err := s.RedisCmd.LoadScript(ctx, script); err != nil {
+			return xerrors.Errorf("Failed to load %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Requires Redis >= 6.2.0 because of XTRIM MINID.
+// @returns "OK" (Redis status reply) if succeeded, including the case that all messages are duplicated
+// @returns "backlog-full" if the channel reached to the backlog limit, preceding messages have been published in this case
+var streamsPublishScript = redis.NewScript(`
+	local streamKey = KEYS[1]     -- Stream (s.{{channel}}.stream)
+	local msgIDsKey = KEYS[2]     -- Hash of message ID to stream ID (s.{{channel}}.mid)
+	local msgExpiryKey = KEYS[3]  -- Sorted set of message ID scored by published time (s.{{channel}}.mexp)
+	local ttlMs = tonumber(ARGV[1])       -- (number) ttl [ms]
+	local maxBacklog = tonumber(ARGV[2])  -- (number) max count of retained messages, 0 means unlimited
+	local evict = (ARGV[3] == "evict")    -- (string) "reject" or "evict"
+	-- ARGV[4..] are pairs of message ID and message envelope JSON
+
+	local now = redis.call("time")
+	local nowMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+	local minMs = string.format("%d", nowMs - ttlMs)
+
+	-- Discard expired messages and their deduplication entries
+	redis.call("xtrim", streamKey, "MINID", minMs)
+	while true do
+		local expired = redis.call("zrangebyscore", msgExpiryKey, "-inf", "(" .. minMs, "LIMIT", 0, 1000)
+		if #expired == 0 then break end
+		redis.call("hdel", msgIDsKey, unpack(expired))
+		redis.call("zrem", msgExpiryKey, unpack(expired))
+	end
+
+	-- Stream IDs are assigned by this script rather than "*" to keep sequence numbers less than 1000 (see stream_id.go)
+	local lastMs, lastSeq = 0, 0
+	if redis.call("exists", streamKey) == 1 then
+		local info = redis.call("xinfo", "stream", streamKey)
+		for i = 1, #info, 2 do
+			if info[i] == "last-generated-id" then
+				local sep = string.find(info[i + 1], "-", 1, true)
+				lastMs = tonumber(string.sub(info[i + 1], 1, sep - 1))
+				lastSeq = tonumber(string.sub(info[i + 1], sep + 1))
+			end
+		end
+	end
+
+	local result = redis.status_reply("OK")
+	for i = 4, #ARGV, 2 do
+		local msgID = ARGV[i]
+		if redis.call("hexists", msgIDsKey, msgID) == 0 then
+			-- Enforce backlog limit
+			local retained = redis.call("xlen", streamKey)
+			if maxBacklog > 0 and retained >= maxBacklog then
+				if not evict then
+					result = "backlog-full"
+					break
+				end
+				-- Evict the oldest one and also older ones (exist if the limit has been reduced).
+				-- Deduplication entries are kept until expiration not to accept evicted messages again.
+				for _, entry in ipairs(redis.call("xrange", streamKey, "-", "+", "COUNT", retained - maxBacklog + 1)) do
+					redis.call("xdel", streamKey, entry[1])
+				end
+			end
+
+			if nowMs > lastMs then
+				lastMs, lastSeq = nowMs, 0
+			elseif lastSeq < 999 then
+				lastSeq = lastSeq + 1
+			else
+				lastMs, lastSeq = lastMs + 1, 0
+			end
+			local id = string.format("%d-%d", lastMs, lastSeq)
+			redis.call("xadd", streamKey, id, "m", ARGV[i + 1])
+			redis.call("hset", msgIDsKey, msgID, id)
+			redis.call("zadd", msgExpiryKey, nowMs, msgID)
+		end
+	end
+	redis.call("pexpire", streamKey, ttlMs)
+	redis.call("pexpire", msgIDsKey, ttlMs)
+	redis.call("pexpire", msgExpiryKey, ttlMs)
+	return result
+`)
+
+func runStreamsPublishScript(ctx context.Context, redisCmd internal.RedisCmd, channelID domain.ChannelID, ttl channelTTLSec, backlog domain.BacklogLimit, msgs []domain.Message) error {
+	args := make([]interface{}, 0, 3+2*len(msgs))
+	args = append(args, ttl.asDuration().Milliseconds(), backlog.Max, string(backlog.Overflow))
+	for _, msg := range msgs {
+		wrapped, err := wrapMessage(msg)
+		if err != nil {
+			return xerrors.Errorf("Unable to encode message \"%s\": %w", msg.MessageID, err)
+		}
+		args = append(args, string(msg.MessageID), wrapped)
+	}
+
+	keys := keyOfStream(channelID)
+	result, err := redisCmd.RunScript(
+		ctx, streamsPublishScript,
+		[]string{keys.Stream(), keys.MessageIDs(), keys.MessageExpiry()},
+		args...,
+	)
+	logger.Of(ctx).Debugf(logger.CatStorage, "runStreamsPublishScript(channelID = %s, ttl = %d, msgs = %d) resulted in %v (%v)", channelID, ttl, len(msgs), result, err)
+	if err != nil {
+		return xerrors.Errorf("Failed to execute streamsPublishScript: %w", err)
+	}
+	switch result {
+	case "OK":
+		return nil
+	case "backlog-full":
+		return xerrors.Errorf("channel retains %d messages: %w", backlog.Max, domain.ErrBacklogFull)
+	default:
+		return xerrors.Errorf("Unexpected result from streamsPublishScript: %T(%v)", result, result)
+	}
+}
+
+// Queue subscriber has a consumer group named same as the subscriber ID, see streamsQueueLeaseScript.
+// @returns "OK" (Redis status reply) if succeeded
+// @returns false (Nil bulk reply) if already exists, filter and visibility timeout of the subscriber are replaced in this case
+var streamsCreateSubscriberScript = redis.NewScript(`
+	local streamKey = KEYS[1]      -- Stream (s.{{channel}}.stream)
+	local subscriberKey = KEYS[2]  -- Cursor of the subscriber (s.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]          -- Visibility timeout of the queue subscriber (s.{{channel}}.q.{subscriber})
+	local ttlMs = tonumber(ARGV[1])  -- (number) ttl [ms]
+	local filter = ARGV[2]           -- (string) ":{filter JSON}" or empty string if no filter
+	local group = ARGV[3]            -- (string) Subscriber ID, name of the consumer group of the queue subscriber
+	local vtMs = tonumber(ARGV[4])   -- (number) Visibility timeout [ms], 0 means normal subscriber
+
+	if redis.call("exists", streamKey) == 0 then
+		-- Create empty channel, XADD to the new stream always generates sequence number 0
+		redis.call("xadd", streamKey, "MAXLEN", "0", "*", "m", "")
+	end
+	redis.call("pexpire", streamKey, ttlMs)  -- Extend channel life
+
+	local exists = true
+	local sbscCursor = redis.call("get", subscriberKey)
+	if sbscCursor ~= false then
+		-- Already exists, keep cursor of the subscriber and replace filter
+		local sep = string.find(sbscCursor, ":", 1, true)
+		if sep then
+			sbscCursor = string.sub(sbscCursor, 1, sep - 1)
+		end
+	else
+		-- Create subscriber, it receives messages published after now
+		exists = false
+		local info = redis.call("xinfo", "stream", streamKey)
+		for i = 1, #info, 2 do
+			if info[i] == "last-generated-id" then sbscCursor = info[i + 1] end
+		end
+		if sbscCursor == false then return redis.error_reply("last-generated-id not found in XINFO STREAM") end
+	end
+
+	if vtMs > 0 then
+		-- Keep the existing consumer group not to lose leases (BUSYGROUP error)
+		redis.call("set", vtKey, vtMs, "PX", ttlMs)
+		redis.pcall("xgroup", "create", streamKey, group, sbscCursor)
+	elseif redis.call("del", vtKey) == 1 then
+		-- Turned into normal subscriber, cursor is before leased messages thus they are redelivered
+		redis.pcall("xgroup", "destroy", streamKey, group)
+	end
+	redis.call("set", subscriberKey, sbscCursor .. filter, "PX", ttlMs)
+	if exists then return false end
+	return redis.status_reply("OK")
+`)
+
+func runStreamsCreateSubscriberScript(ctx context.Context, redisCmd internal.RedisCmd, channelID domain.ChannelID, ttl channelTTLSec, sbscID domain.SubscriberID, filter *domain.SubscriberFilter, visibilityTimeout *domain.Duration) error {
+	var vtMs int64
+	if visibilityTimeout != nil {
+		vtMs = visibilityTimeout.Milliseconds()
+	}
+	keys := keyOfStream(channelID)
+	result, err := redisCmd.RunScript(
+		ctx, streamsCreateSubscriberScript,
+		[]string{keys.Stream(), keys.SubscriberCursor(sbscID), keys.QueueVisibilityTimeout(sbscID)},
+		ttl.asDuration().Milliseconds(), formatSubscriberCursorFilter(filter), string(sbscID), vtMs,
+	)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			logger.Of(ctx).Debugf(logger.CatStorage, "Subscriber already exists: %s on %s", sbscID, channelID)
+		} else {
+			return xerrors.Errorf("Failed to execute streamsCreateSubscriberScript: %w", err)
+		}
+	} else if result != "OK" {
+		return xerrors.Errorf("Unexpected result from streamsCreateSubscriberScript: %T(%v)", result, result)
+	}
+	return nil
+}
+
+// Used for both of acknowledgement (forward only) and rewinding.
+// @returns "OK" (Redis status reply) if succeeded
+// @returns "stale" if forwardOnly and the cursor is already at or after the given ID, or the subscriber is a queue subscriber
+var streamsMoveCursorScript = redis.NewScript(`
+	local streamKey = KEYS[1]      -- Stream (s.{{channel}}.stream)
+	local subscriberKey = KEYS[2]  -- Cursor of the subscriber (s.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]          -- Visibility timeout of the queue subscriber (s.{{channel}}.q.{subscriber})
+	local ttlMs = tonumber(ARGV[1])     -- (number) ttl [ms]
+	local moveTo = ARGV[2]              -- (string) New stream ID of the cursor
+	local forwardOnly = (ARGV[3] == "1")  -- (string) "1" to deny moving the cursor backward
+	local group = ARGV[4]               -- (string) Subscriber ID, name of the consumer group of the queue subscriber
+
+	if redis.call("exists", streamKey) == 0 then return "channel-not-found" end
+	local sbscCursor = redis.call("get", subscriberKey)
+	if sbscCursor == false then return "subscription-not-found" end
+
+	if redis.call("exists", vtKey) == 1 then
+		-- Queue subscriber ignores acknowledgement without lease (turned into queue subscriber after fetch)
+		if forwardOnly then return "stale" end
+		-- Rewinding discards all leases, messages after the new cursor are delivered again
+		redis.pcall("xgroup", "destroy", streamKey, group)
+		redis.call("xgroup", "create", streamKey, group, moveTo)
+	end
+
+	-- Subscriber cursor could have filter suffix ("{stream ID}:{filter JSON}"), must keep it
+	local sbscFilter = ""
+	local sep = string.find(sbscCursor, ":", 1, true)
+	if sep then
+		sbscFilter = string.sub(sbscCursor, sep)
+		sbscCursor = string.sub(sbscCursor, 1, sep - 1)
+	end
+
+	if forwardOnly then
+		local function parse(id)
+			local sep = string.find(id, "-", 1, true)
+			return tonumber(string.sub(id, 1, sep - 1)), tonumber(string.sub(id, sep + 1))
+		end
+		local curMs, curSeq = parse(sbscCursor)
+		local toMs, toSeq = parse(moveTo)
+		if toMs < curMs or (toMs == curMs and toSeq <= curSeq) then
+			return "stale"
+		end
+	end
+	redis.call("set", subscriberKey, moveTo .. sbscFilter, "PX", ttlMs)
+	redis.call("pexpire", streamKey, ttlMs)  -- Also extend channel expiry
+	return redis.status_reply("OK")
+`)
+
+func runStreamsMoveCursorScript(ctx context.Context, redisCmd internal.RedisCmd, sl domain.SubscriberLocator, ttl channelTTLSec, moveTo streamID, forwardOnly bool) (string, error) {
+	keys := keyOfStream(sl.ChannelID)
+	forwardOnlyArg := "0"
+	if forwardOnly {
+		forwardOnlyArg = "1"
+	}
+	result, err := redisCmd.RunScript(
+		ctx, streamsMoveCursorScript,
+		[]string{keys.Stream(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)},
+		ttl.asDuration().Milliseconds(), moveTo.String(), forwardOnlyArg, string(sl.SubscriberID),
+	)
+	logger.Of(ctx).Debugf(logger.CatStorage, `runStreamsMoveCursorScript(channelID = %s, ttl = %d, sbscID = %s, moveTo = %s, forwardOnly = %v) resulted in %v (%v)`, sl.ChannelID, ttl, sl.SubscriberID, moveTo, forwardOnly, result, err)
+	if err != nil {
+		return "", xerrors.Errorf("Failed to execute streamsMoveCursorScript: %w", err)
+	}
+	if strResult, ok := result.(string); ok {
+		switch strResult {
+		case "OK":
+			return strResult, nil
+		case "channel-not-found", "subscription-not-found":
+			return strResult, xerrors.Errorf("%s (%w)", strResult, domain.ErrSubscriptionNotFound)
+		case "stale":
+			return strResult, nil // Could occur due to client retry
+		default:
+			return strResult, xerrors.Errorf("Unexpected result from streamsMoveCursorScript: string(%s)", strResult)
+		}
+	}
+	return "", xerrors.Errorf("Unexpected result from streamsMoveCursorScript: %T(%v)", result, result)
+}
+
+// XINFO STREAM is parsed in Lua because its reply format varies between Redis versions.
+// @returns the last generated ID of the stream, or false (Nil bulk reply) if the stream does not exist
+var streamsLastIDScript = redis.NewScript(`
+	local streamKey = KEYS[1]  -- Stream (s.{{channel}}.stream)
+
+	if redis.call("exists", streamKey) == 0 then return false end
+	local info = redis.call("xinfo", "stream", streamKey)
+	for i = 1, #info, 2 do
+		if info[i] == "last-generated-id" then return info[i + 1] end
+	end
+	return redis.error_reply("last-generated-id not found in XINFO STREAM")
+`)
+
+// Returns nil if the channel does not exist.
+func runStreamsLastIDScript(ctx context.Context, redisCmd internal.RedisCmd, channelID domain.ChannelID) (*streamID, error) {
+	result, err := redisCmd.RunScript(ctx, streamsLastIDScript, []string{keyOfStream(channelID).Stream()})
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to execute streamsLastIDScript: %w", err)
+	}
+	if str, ok := result.(string); ok {
+		if id := parseStreamID(str); id != nil {
+			return id, nil
+		}
+	}
+	return nil, xerrors.Errorf("Unexpected result from streamsLastIDScript: %T(%v)", result, result)
+}
+
+// @returns "OK" (Redis status reply)
+var streamsRemoveSubscriberScript = redis.NewScript(`
+	local streamKey = KEYS[1]      -- Stream (s.{{channel}}.stream)
+	local subscriberKey = KEYS[2]  -- Cursor of the subscriber (s.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]          -- Visibility timeout of the queue subscriber (s.{{channel}}.q.{subscriber})
+	local group = ARGV[1]          -- (string) Subscriber ID, name of the consumer group of the queue subscriber
+
+	redis.call("del", subscriberKey, vtKey)
+	if redis.call("exists", streamKey) == 1 then
+		redis.call("xgroup", "destroy", streamKey, group)  -- Returns 0 if the group does not exist
+	end
+	return redis.status_reply("OK")
+`)
+
+func runStreamsRemoveSubscriberScript(ctx context.Context, redisCmd internal.RedisCmd, sl domain.SubscriberLocator) error {
+	keys := keyOfStream(sl.ChannelID)
+	result, err := redisCmd.RunScript(
+		ctx, streamsRemoveSubscriberScript,
+		[]string{keys.Stream(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)},
+		string(sl.SubscriberID),
+	)
+	if err != nil {
+		return xerrors.Errorf("Failed to execute streamsRemoveSubscriberScript: %w", err)
+	}
+	if result != "OK" {
+		return xerrors.Errorf("Unexpected result from streamsRemoveSubscriberScript: %T(%v)", result, result)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+// NewQueueSubscriber creates a consumer group of the stream for the subscriber, each fetch operation leases messages as a new consumer of the group.
+func (rs *redisStreamsStorage) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	if visibilityTimeout.Milliseconds() <= 0 { // Lua script treats 0 as normal subscriber
+		return xerrors.Errorf("Visibility timeout must be positive: %s", visibilityTimeout)
+	}
+	ttl, err := rs.base.channelRedisTTLSec(sl.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	return runStreamsCreateSubscriberScript(ctx, rs.base.RedisCmd, sl.ChannelID, ttl, sl.SubscriberID, filter, &visibilityTimeout)
+}
+
+// fetchQueueMessagesNow leases visible messages of the queue subscriber to the caller.
+// wakeAt is the earliest expiry of leases (zero if none), leased messages become visible again at that time.
+// isQueue is false if the subscriber turned into normal subscriber concurrently.
+func (rs *redisStreamsStorage) fetchQueueMessagesNow(ctx context.Context, sl domain.SubscriberLocator, max int, ttl channelTTLSec, filter *domain.SubscriberFilter) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, awaitAfter streamID, wakeAt time.Time, isQueue bool, err error) {
+	retention := rs.retentionStart(ttl)
+	for {
+		var leaseID string
+		if leaseID, err = newLeaseID(); err != nil {
+			return
+		}
+		var lease *streamsLeaseResult
+		if lease, err = runStreamsQueueLeaseScript(ctx, rs.base.RedisCmd, sl, ttl, leaseID, max); err != nil || lease == nil {
+			return
+		}
+		isQueue = true
+		moreMessages = lease.more
+		awaitAfter = lease.lastID
+		if lease.wakeIn >= 0 {
+			wakeAt = rs.base.clock.Now().Add(lease.wakeIn)
+		}
+
+		leasedClocks := make([]channelClock, 0, len(lease.entries))
+		skipIDs := make([]streamID, 0)
+		messages = make([]domain.Message, 0, len(lease.entries))
+		for _, entry := range lease.entries {
+			if entry.id.Less(retention) {
+				skipIDs = append(skipIDs, entry.id) // Expired but not trimmed yet
+				continue
+			}
+			msg, err := unwrapMessage(sl.ChannelID, entry.raw)
+			if err != nil || msg == nil {
+				if err != nil {
+					logger.Of(ctx).Error(fmt.Sprintf("Skipped corrupted message (chID: %s, stream ID: %s) fetched from Redis", sl.ChannelID, entry.id), err)
+				}
+				skipIDs = append(skipIDs, entry.id)
+				continue
+			}
+			if !filter.Match(*msg) {
+				skipIDs = append(skipIDs, entry.id)
+				continue
+			}
+			messages = append(messages, *msg)
+			leasedClocks = append(leasedClocks, channelClock(entry.id.clock()))
+		}
+		if len(skipIDs) > 0 {
+			// Acknowledge skipped messages not to lease them again.
+			if err = runStreamsQueueAckScript(ctx, rs.base.RedisCmd, sl, ttl, leaseID, skipIDs); err != nil {
+				return
+			}
+		}
+		if len(messages) > 0 {
+			ackHandle = encodeAckHandle(sl, ackHandleData{
+				LastMessageClock: leasedClocks[len(leasedClocks)-1],
+				LeaseID:          leaseID,
+				LeasedClocks:     leasedClocks,
+			})
+			return
+		}
+		if len(skipIDs) == 0 || !moreMessages {
+			return
+		}
+		// All messages are skipped, lease following messages
+	}
+}
+
+func (rs *redisStreamsStorage) acknowledgeQueueMessages(ctx context.Context, handle domain.AckHandle, h ackHandleData) error {
+	ttl, err := rs.base.channelRedisTTLSec(handle.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	ids := make([]streamID, 0, len(h.LeasedClocks))
+	for _, clock := range h.LeasedClocks {
+		id := streamIDOfClock(int64(clock))
+		if id == nil {
+			return xerrors.Errorf("Invalid Redis AckHandle (%s), negative clock (%w)", handle.Handle, domain.ErrMalformedAckHandle)
+		}
+		ids = append(ids, *id)
+	}
+	return runStreamsQueueAckScript(ctx, rs.base.RedisCmd, handle.SubscriberLocator, ttl, h.LeaseID, ids)
+}
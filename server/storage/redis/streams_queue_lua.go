@@ -0,0 +1,303 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+	internal "github.com/m3dev/dsps/server/storage/redis/internal"
+)
+
+// Leases messages of the queue subscriber to a new consumer (named with the lease ID) of the consumer group:
+// messages of expired leases first (XPENDING IDLE + XCLAIM), then messages never delivered to the group (XREADGROUP).
+// Requires Redis >= 6.2.0 because of XPENDING IDLE and exclusive XRANGE.
+// @returns array of [earliest lease expiry in ms from now (-1 if none), 1 if more messages, last generated ID of the stream, ...pairs of stream ID and message envelope JSON]
+// @returns "channel-not-found", "subscription-not-found" or "not-queue-subscriber"
+var streamsQueueLeaseScript = redis.NewScript(`
+	local streamKey = KEYS[1]      -- Stream (s.{{channel}}.stream)
+	local subscriberKey = KEYS[2]  -- Cursor of the subscriber (s.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]          -- Visibility timeout of the queue subscriber (s.{{channel}}.q.{subscriber})
+	local ttlMs = tonumber(ARGV[1])  -- (number) ttl [ms]
+	local group = ARGV[2]            -- (string) Subscriber ID, name of the consumer group
+	local consumer = ARGV[3]         -- (string) Lease ID, name of the consumer that holds the lease
+	local max = tonumber(ARGV[4])    -- (number) Max count of messages to lease
+
+	if redis.call("exists", streamKey) == 0 then return "channel-not-found" end
+	local sbscCursor = redis.call("get", subscriberKey)
+	if sbscCursor == false then return "subscription-not-found" end
+	local vtMs = redis.call("get", vtKey)
+	if vtMs == false then return "not-queue-subscriber" end
+	local sep = string.find(sbscCursor, ":", 1, true)
+	if sep then
+		sbscCursor = string.sub(sbscCursor, 1, sep - 1)
+	end
+
+	-- Consumer group vanishes if the stream has expired and created again (BUSYGROUP error if exists)
+	redis.pcall("xgroup", "create", streamKey, group, sbscCursor)
+
+	local result = {}
+	local more = false
+
+	-- Messages of expired leases
+	local expired = redis.call("xpending", streamKey, group, "IDLE", vtMs, "-", "+", max + 1)
+	if #expired > max then more = true end
+	local claimArgs = { "xclaim", streamKey, group, consumer, vtMs }
+	local owners = {}
+	for i = 1, math.min(#expired, max) do
+		table.insert(claimArgs, expired[i][1])
+		owners[expired[i][2]] = true
+	end
+	if #claimArgs > 5 then
+		table.insert(claimArgs, "JUSTID")
+		redis.call(unpack(claimArgs))
+		for i = 6, #claimArgs - 1 do
+			local id = claimArgs[i]
+			local entries = redis.call("xrange", streamKey, id, id)
+			if #entries > 0 then
+				table.insert(result, id)
+				table.insert(result, entries[1][2][2])
+			else
+				redis.call("xack", streamKey, group, id)  -- Evicted message
+			end
+		end
+		-- Remove consumers of expired leases that no longer have messages
+		for owner in pairs(owners) do
+			if owner ~= consumer and #redis.call("xpending", streamKey, group, "-", "+", 1, owner) == 0 then
+				redis.call("xgroup", "delconsumer", streamKey, group, owner)
+			end
+		end
+	end
+
+	-- Messages never delivered to the group
+	local remaining = max - #result / 2
+	if not more and remaining > 0 then
+		local read = redis.call("xreadgroup", "GROUP", group, consumer, "COUNT", remaining, "STREAMS", streamKey, ">")
+		if read then
+			local entries = read[1][2]
+			for _, entry in ipairs(entries) do
+				table.insert(result, entry[1])
+				table.insert(result, entry[2][2])
+			end
+			if #entries == remaining then
+				more = #redis.call("xrange", streamKey, "(" .. entries[#entries][1], "+", "COUNT", 1) > 0
+			end
+		end
+	end
+	if #result == 0 then
+		redis.call("xgroup", "delconsumer", streamKey, group, consumer)
+	end
+
+	-- Earliest expiry of leases, leased messages become visible again without new entries
+	local wakeMs = -1
+	for _, p in ipairs(redis.call("xpending", streamKey, group, "-", "+", 1000)) do
+		local ms = tonumber(vtMs) - p[3]
+		if ms > 0 and (wakeMs < 0 or ms < wakeMs) then wakeMs = ms end
+	end
+
+	local lastID = false
+	local info = redis.call("xinfo", "stream", streamKey)
+	for i = 1, #info, 2 do
+		if info[i] == "last-generated-id" then lastID = info[i + 1] end
+	end
+	if lastID == false then return redis.error_reply("last-generated-id not found in XINFO STREAM") end
+
+	redis.call("pexpire", streamKey, ttlMs)
+	redis.call("pexpire", subscriberKey, ttlMs)
+	redis.call("pexpire", vtKey, ttlMs)
+	if more then
+		return { wakeMs, 1, lastID, unpack(result) }
+	end
+	return { wakeMs, 0, lastID, unpack(result) }
+`)
+
+type streamsLeasedEntry struct {
+	id  streamID
+	raw string
+}
+
+type streamsLeaseResult struct {
+	entries []streamsLeasedEntry
+	more    bool
+	lastID  streamID
+	// Duration until the earliest lease expiry, negative if no leases
+	wakeIn time.Duration
+}
+
+// Returns nil if the subscriber is no longer a queue subscriber.
+func runStreamsQueueLeaseScript(ctx context.Context, redisCmd internal.RedisCmd, sl domain.SubscriberLocator, ttl channelTTLSec, leaseID string, max int) (*streamsLeaseResult, error) {
+	keys := keyOfStream(sl.ChannelID)
+	result, err := redisCmd.RunScript(
+		ctx, streamsQueueLeaseScript,
+		[]string{keys.Stream(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)},
+		ttl.asDuration().Milliseconds(), string(sl.SubscriberID), leaseID, max,
+	)
+	logger.Of(ctx).Debugf(logger.CatStorage, `runStreamsQueueLeaseScript(channelID = %s, ttl = %d, sbscID = %s, leaseID = %s, max = %d) resulted in %v (%v)`, sl.ChannelID, ttl, sl.SubscriberID, leaseID, max, result, err)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to execute streamsQueueLeaseScript: %w", err)
+	}
+	switch result {
+	case "channel-not-found", "subscription-not-found":
+		return nil, xerrors.Errorf("%s (%w)", result, domain.ErrSubscriptionNotFound)
+	case "not-queue-subscriber":
+		return nil, nil
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) < 3 || len(values)%2 != 1 {
+		return nil, xerrors.Errorf("Unexpected result from streamsQueueLeaseScript: %T(%v)", result, result)
+	}
+	wakeMs, ok1 := values[0].(int64)
+	more, ok2 := values[1].(int64)
+	lastIDStr, ok3 := values[2].(string)
+	var lastID *streamID
+	if ok3 {
+		lastID = parseStreamID(lastIDStr)
+	}
+	if !ok1 || !ok2 || lastID == nil {
+		return nil, xerrors.Errorf("Unexpected result from streamsQueueLeaseScript: %T(%v)", result, result)
+	}
+	lease := &streamsLeaseResult{
+		entries: make([]streamsLeasedEntry, 0, (len(values)-3)/2),
+		more:    more == 1,
+		lastID:  *lastID,
+		wakeIn:  time.Duration(wakeMs) * time.Millisecond,
+	}
+	for i := 3; i < len(values); i += 2 {
+		idStr, _ := values[i].(string)
+		id := parseStreamID(idStr)
+		if id == nil {
+			return nil, xerrors.Errorf("Unexpected stream ID from streamsQueueLeaseScript: %T(%v)", values[i], values[i])
+		}
+		raw, _ := values[i+1].(string)
+		lease.entries = append(lease.entries, streamsLeasedEntry{id: *id, raw: raw})
+	}
+	return lease, nil
+}
+
+// Acknowledges messages of the lease unless other consumer leased them again after expiry,
+// then advances the cursor to just before the oldest pending message (or the last delivered one if nothing pending).
+// @returns "OK" (Redis status reply) if succeeded
+// @returns "stale" if the subscriber is no longer a queue subscriber
+var streamsQueueAckScript = redis.NewScript(fmt.Sprintf(`
+	local streamKey = KEYS[1]      -- Stream (s.{{channel}}.stream)
+	local subscriberKey = KEYS[2]  -- Cursor of the subscriber (s.{{channel}}.r.{subscriber})
+	local vtKey = KEYS[3]          -- Visibility timeout of the queue subscriber (s.{{channel}}.q.{subscriber})
+	local ttlMs = tonumber(ARGV[1])  -- (number) ttl [ms]
+	local group = ARGV[2]            -- (string) Subscriber ID, name of the consumer group
+	local consumer = ARGV[3]         -- (string) Lease ID, name of the consumer that holds the lease
+	-- ARGV[4..] are stream IDs of the leased messages
+
+	if redis.call("exists", streamKey) == 0 then return "channel-not-found" end
+	local sbscCursor = redis.call("get", subscriberKey)
+	if sbscCursor == false then return "subscription-not-found" end
+	if redis.call("exists", vtKey) == 0 then return "stale" end
+
+	local lastDelivered = false
+	for _, g in ipairs(redis.call("xinfo", "groups", streamKey)) do
+		local name, id
+		for i = 1, #g, 2 do
+			if g[i] == "name" then name = g[i + 1] end
+			if g[i] == "last-delivered-id" then id = g[i + 1] end
+		end
+		if name == group then lastDelivered = id end
+	end
+	if lastDelivered == false then return "stale" end  -- Stream has expired and created again
+
+	for i = 4, #ARGV do
+		local p = redis.call("xpending", streamKey, group, ARGV[i], ARGV[i], 1)
+		if #p > 0 and p[1][2] == consumer then
+			redis.call("xack", streamKey, group, ARGV[i])
+		end
+	end
+	if #redis.call("xpending", streamKey, group, "-", "+", 1, consumer) == 0 then
+		redis.call("xgroup", "delconsumer", streamKey, group, consumer)
+	end
+
+	local function parse(id)
+		local sep = string.find(id, "-", 1, true)
+		return tonumber(string.sub(id, 1, sep - 1)), tonumber(string.sub(id, sep + 1))
+	end
+	local ackedUntil = lastDelivered
+	local summary = redis.call("xpending", streamKey, group)
+	if summary[1] > 0 then
+		-- Just before the oldest pending message, sequence number is less than %d (see stream_id.go)
+		local ms, seq = parse(summary[2])
+		if seq > 0 then
+			ackedUntil = string.format("%%d-%%d", ms, seq - 1)
+		else
+			ackedUntil = string.format("%%d-%%d", ms - 1, %d)
+		end
+	end
+
+	local sbscFilter = ""
+	local sep = string.find(sbscCursor, ":", 1, true)
+	if sep then
+		sbscFilter = string.sub(sbscCursor, sep)
+		sbscCursor = string.sub(sbscCursor, 1, sep - 1)
+	end
+	local curMs, curSeq = parse(sbscCursor)
+	local toMs, toSeq = parse(ackedUntil)
+	if curMs < toMs or (curMs == toMs and curSeq < toSeq) then
+		redis.call("set", subscriberKey, ackedUntil .. sbscFilter, "PX", ttlMs)
+	else
+		redis.call("pexpire", subscriberKey, ttlMs)
+	end
+	redis.call("pexpire", vtKey, ttlMs)
+	redis.call("pexpire", streamKey, ttlMs)
+	return redis.status_reply("OK")
+`, streamSeqPerMs, streamSeqPerMs-1))
+
+func runStreamsQueueAckScript(ctx context.Context, redisCmd internal.RedisCmd, sl domain.SubscriberLocator, ttl channelTTLSec, leaseID string, ids []streamID) error {
+	keys := keyOfStream(sl.ChannelID)
+	args := make([]interface{}, 0, 3+len(ids))
+	args = append(args, ttl.asDuration().Milliseconds(), string(sl.SubscriberID), leaseID)
+	for _, id := range ids {
+		args = append(args, id.String())
+	}
+	result, err := redisCmd.RunScript(
+		ctx, streamsQueueAckScript,
+		[]string{keys.Stream(), keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID)},
+		args...,
+	)
+	logger.Of(ctx).Debugf(logger.CatStorage, `runStreamsQueueAckScript(channelID = %s, ttl = %d, sbscID = %s, leaseID = %s, ids = %v) resulted in %v (%v)`, sl.ChannelID, ttl, sl.SubscriberID, leaseID, ids, result, err)
+	if err != nil {
+		return xerrors.Errorf("Failed to execute streamsQueueAckScript: %w", err)
+	}
+	switch result {
+	case "OK", "stale":
+		return nil
+	case "channel-not-found", "subscription-not-found":
+		return xerrors.Errorf("%s (%w)", result, domain.ErrSubscriptionNotFound)
+	default:
+		return xerrors.Errorf("Unexpected result from streamsQueueAckScript: %T(%v)", result, result)
+	}
+}
+
+// @returns count of unexpired leases of the queue subscriber (messages pending in the consumer group and idle less than visibility timeout)
+var streamsQueueLeasedScript = redis.NewScript(`
+	local streamKey = KEYS[1]  -- Stream (s.{{channel}}.stream)
+	local group = ARGV[1]      -- (string) Subscriber ID, name of the consumer group
+	local vtMs = ARGV[2]       -- (string) Visibility timeout [ms]
+
+	if redis.call("exists", streamKey) == 0 then return 0 end
+	local summary = redis.pcall("xpending", streamKey, group)
+	if summary.err or summary[1] == 0 then return 0 end  -- NOGROUP error if the group does not exist
+	return summary[1] - #redis.call("xpending", streamKey, group, "IDLE", vtMs, "-", "+", summary[1])
+`)
+
+func runStreamsQueueLeasedScript(ctx context.Context, redisCmd internal.RedisCmd, sl domain.SubscriberLocator, visibilityTimeout domain.Duration) (int64, error) {
+	result, err := redisCmd.RunScript(ctx, streamsQueueLeasedScript, []string{keyOfStream(sl.ChannelID).Stream()}, string(sl.SubscriberID), visibilityTimeout.Milliseconds())
+	if err != nil {
+		return 0, xerrors.Errorf("Failed to execute streamsQueueLeasedScript: %w", err)
+	}
+	count, ok := result.(int64)
+	if !ok {
+		return 0, xerrors.Errorf("Unexpected result from streamsQueueLeasedScript: %T(%v)", result, result)
+	}
+	return count, nil
+}
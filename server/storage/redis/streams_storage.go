@@ -0,0 +1,283 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+)
+
+// Upper limit of XREAD BLOCK timeout.
+// Long polling issues XREAD repeatedly to release the connection soon after the request has been cancelled.
+const streamsBlockMax = 5 * time.Second
+
+// redisStreamsStorage is PubSubStorage of "streams" engine, stores messages of each channel in a Redis Stream.
+// See ../doc/storage/redis-internal-structure.md for data structure.
+type redisStreamsStorage struct {
+	// Not embedded to not inherit PubSubStorage methods of "keys" engine
+	base *redisStorage
+}
+
+var _ domain.PubSubStorage = &redisStreamsStorage{}
+
+func (rs *redisStreamsStorage) PublishMessages(ctx context.Context, msgs []domain.Message) error {
+	if !domain.BelongsToSameChannel(msgs) {
+		return xerrors.New("Messages belongs to various channels")
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	channelID := msgs[0].ChannelID
+	ttl, err := rs.base.channelRedisTTLSec(channelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	ch, err := rs.base.channelProvider.Get(channelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to get backlog limit of channel: %w", err)
+	}
+	return runStreamsPublishScript(ctx, rs.base.RedisCmd, channelID, ttl, ch.BacklogLimit(), msgs)
+}
+
+func (rs *redisStreamsStorage) FetchMessages(ctx context.Context, sl domain.SubscriberLocator, max int, waituntil domain.Duration) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, err error) {
+	var awaitAfter streamID
+	var wakeAt time.Time
+	if messages, moreMessages, ackHandle, awaitAfter, wakeAt, err = rs.fetchMessagesNow(ctx, sl, max); err != nil || len(messages) > 0 {
+		return
+	}
+
+	deadline := time.Now().Add(waituntil.Duration)
+	for {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return
+		}
+		// Leased messages of queue subscriber become visible without new entries
+		leaseExpiring := false
+		if !wakeAt.IsZero() {
+			if untilWake := wakeAt.Sub(rs.base.clock.Now().Time); untilWake < timeout {
+				timeout = untilWake
+				leaseExpiring = true
+			}
+		}
+		var found bool
+		if found, err = rs.awaitNewEntry(ctx, sl.ChannelID, awaitAfter, timeout); err != nil {
+			return
+		}
+		if !found && !leaseExpiring {
+			continue
+		}
+		if messages, moreMessages, ackHandle, awaitAfter, wakeAt, err = rs.fetchMessagesNow(ctx, sl, max); err != nil || len(messages) > 0 {
+			return
+		}
+		// Await again because no messages found (expired or filtered out)
+	}
+}
+
+// awaitNewEntry blocks until the stream has an entry newer than the given ID.
+// Because XREAD BLOCK ignores context cancellation, run it in background and return as soon as ctx is done.
+func (rs *redisStreamsStorage) awaitNewEntry(ctx context.Context, channelID domain.ChannelID, after streamID, timeout time.Duration) (bool, error) {
+	if timeout > streamsBlockMax {
+		timeout = streamsBlockMax
+	}
+	type xreadResult struct {
+		found bool
+		err   error
+	}
+	result := make(chan xreadResult, 1)
+	go func() {
+		found, err := rs.base.RedisCmd.XReadBlock(ctx, keyOfStream(channelID).Stream(), after.String(), timeout)
+		result <- xreadResult{found: found, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case r := <-result:
+		if r.err != nil {
+			return false, xerrors.Errorf("FetchMessages failed due to Redis error (XREAD error): %w", r.err)
+		}
+		return r.found, nil
+	}
+}
+
+// Returns ID to await new entries after it in addition to messages.
+// wakeAt is non-zero only for queue subscriber, see fetchQueueMessagesNow.
+func (rs *redisStreamsStorage) fetchMessagesNow(ctx context.Context, sl domain.SubscriberLocator, max int) (messages []domain.Message, moreMessages bool, ackHandle domain.AckHandle, awaitAfter streamID, wakeAt time.Time, err error) {
+	keys := keyOfStream(sl.ChannelID)
+	ttl, err := rs.base.channelRedisTTLSec(sl.ChannelID)
+	if err != nil {
+		err = xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+		return
+	}
+	values, err := rs.base.RedisCmd.MGet(ctx, keys.SubscriberCursor(sl.SubscriberID), keys.QueueVisibilityTimeout(sl.SubscriberID))
+	if err != nil {
+		err = xerrors.Errorf("FetchMessages failed due to Redis error (cursor MGET error): %w", err)
+		return
+	}
+	if values[0] == nil {
+		err = domain.ErrSubscriptionNotFound
+		return
+	}
+	cursor, filter := parseStreamSubscriberCursor(*values[0])
+	if cursor == nil {
+		err = domain.ErrSubscriptionNotFound
+		return
+	}
+	if values[1] != nil && parseVisibilityTimeout(*values[1]) != nil {
+		var isQueue bool
+		if messages, moreMessages, ackHandle, awaitAfter, wakeAt, isQueue, err = rs.fetchQueueMessagesNow(ctx, sl, max, ttl, filter); err != nil || isQueue {
+			return
+		}
+		// Turned into normal subscriber concurrently
+	}
+	lastID, err := runStreamsLastIDScript(ctx, rs.base.RedisCmd, sl.ChannelID)
+	if err != nil {
+		return
+	}
+	if lastID == nil {
+		err = domain.ErrSubscriptionNotFound
+		return
+	}
+	if err := rs.extendSubscriberTTL(ctx, sl, ttl); err != nil {
+		logger.Of(ctx).WarnError(logger.CatStorage, `Failed to extend TTL of stream and/or subscription cursor entry of Redis`, err)
+	}
+
+	// Skip expired entries that have not been trimmed yet
+	start := cursor.next()
+	awaitAfter = *cursor
+	if retention := rs.retentionStart(ttl); start.Less(retention) {
+		start = retention
+		awaitAfter = *streamIDOfClock(retention.clock() - 1)
+	}
+	entries, err := rs.base.RedisCmd.XRange(ctx, keys.Stream(), start.String(), "+", int64(max)+1)
+	if err != nil {
+		err = xerrors.Errorf("FetchMessages failed due to Redis error (XRANGE error): %w", err)
+		return
+	}
+	if len(entries) > max {
+		moreMessages = true
+		entries = entries[:max]
+	}
+
+	// Acknowledging messages also skips filtered or corrupted messages.
+	var lastScannedID *streamID
+	messages = make([]domain.Message, 0, max)
+	for _, entry := range entries {
+		id := parseStreamID(entry.ID)
+		if id == nil {
+			continue
+		}
+		lastScannedID = id
+		raw, _ := entry.Values["m"].(string)
+		msg, err := unwrapMessage(sl.ChannelID, raw)
+		if err != nil || msg == nil {
+			if err != nil {
+				logger.Of(ctx).Error(fmt.Sprintf("Skipped corrupted message (chID: %s, stream ID: %s) fetched from Redis", sl.ChannelID, entry.ID), err)
+			}
+			continue
+		}
+		if !filter.Match(*msg) {
+			continue
+		}
+		messages = append(messages, *msg)
+	}
+	if lastScannedID == nil {
+		return
+	}
+	if len(messages) == 0 {
+		// All messages are filtered out, skip them to not scan them again.
+		if _, err = runStreamsMoveCursorScript(ctx, rs.base.RedisCmd, sl, ttl, *lastScannedID, true); err != nil {
+			return
+		}
+		if moreMessages {
+			messages, moreMessages, ackHandle, awaitAfter, _, err = rs.fetchMessagesNow(ctx, sl, max)
+			return
+		}
+		awaitAfter = *lastScannedID
+		return
+	}
+	ackHandle = encodeAckHandle(sl, ackHandleData{
+		LastMessageClock: channelClock(lastScannedID.clock()),
+	})
+	return
+}
+
+// retentionStart returns the oldest ID of unexpired entries, same as XTRIM MINID in streamsPublishScript.
+func (rs *redisStreamsStorage) retentionStart(ttl channelTTLSec) streamID {
+	return streamIDOfTime(rs.base.clock.Now().Add(-ttl.asDuration()))
+}
+
+// extendSubscriberTTL extends TTL of stream and subscriber cursor.
+// If no new messages comes in to the channel, fetchMessages operation should extend TTLs otherwise stream or subscriber could be vanished due to TTL outage.
+func (rs *redisStreamsStorage) extendSubscriberTTL(ctx context.Context, sl domain.SubscriberLocator, ttl channelTTLSec) error {
+	keys := keyOfStream(sl.ChannelID)
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error { return rs.base.RedisCmd.Expire(ctx, keys.Stream(), ttl.asDuration()) })
+	g.Go(func() error {
+		return rs.base.RedisCmd.Expire(ctx, keys.SubscriberCursor(sl.SubscriberID), ttl.asDuration())
+	})
+	return g.Wait()
+}
+
+func (rs *redisStreamsStorage) AcknowledgeMessages(ctx context.Context, handle domain.AckHandle) error {
+	ttl, err := rs.base.channelRedisTTLSec(handle.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	h, err := decodeAckHandle(handle)
+	if err != nil {
+		return err
+	}
+	if h.LeaseID != "" {
+		return rs.acknowledgeQueueMessages(ctx, handle, h)
+	}
+	id := streamIDOfClock(int64(h.LastMessageClock))
+	if id == nil {
+		return xerrors.Errorf("Invalid Redis AckHandle (%s), negative clock (%w)", handle.Handle, domain.ErrMalformedAckHandle)
+	}
+	_, err = runStreamsMoveCursorScript(ctx, rs.base.RedisCmd, handle.SubscriberLocator, ttl, *id, true)
+	return err
+}
+
+func (rs *redisStreamsStorage) IsOldMessages(ctx context.Context, sl domain.SubscriberLocator, msgs []domain.MessageLocator) (map[domain.MessageLocator]bool, error) {
+	keys := keyOfStream(sl.ChannelID)
+	value, err := rs.base.RedisCmd.Get(ctx, keys.SubscriberCursor(sl.SubscriberID))
+	if err != nil {
+		return nil, xerrors.Errorf("IsOldMessages failed due to Redis error (GET error): %w", err)
+	}
+	if value == nil {
+		return nil, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+	}
+	cursor, _ := parseStreamSubscriberCursor(*value)
+	if cursor == nil {
+		return nil, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+	}
+
+	fields := make([]string, len(msgs))
+	for i, msg := range msgs {
+		fields[i] = string(msg.MessageID)
+	}
+	ids, err := rs.base.RedisCmd.HMGet(ctx, keys.MessageIDs(), fields...)
+	if err != nil {
+		return nil, xerrors.Errorf("IsOldMessages failed due to Redis error (HMGET error): %w", err)
+	}
+	result := make(map[domain.MessageLocator]bool, len(msgs))
+	for i, value := range ids {
+		var id *streamID
+		if value != nil {
+			id = parseStreamID(*value)
+		}
+		if id == nil {
+			result[msgs[i]] = false // Message not found (unsent or expired), return false because unsure.
+			continue
+		}
+		result[msgs[i]] = !cursor.Less(*id)
+	}
+	return result, nil
+}
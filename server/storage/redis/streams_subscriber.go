@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (rs *redisStreamsStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter) error {
+	ttl, err := rs.base.channelRedisTTLSec(sl.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	return runStreamsCreateSubscriberScript(ctx, rs.base.RedisCmd, sl.ChannelID, ttl, sl.SubscriberID, filter, nil)
+}
+
+func (rs *redisStreamsStorage) RemoveSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
+	if err := runStreamsRemoveSubscriberScript(ctx, rs.base.RedisCmd, sl); err != nil {
+		return xerrors.Errorf("Failed to delete subscriber: %w", err)
+	}
+	return nil
+}
+
+func (rs *redisStreamsStorage) RewindSubscriber(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) error {
+	ttl, err := rs.base.channelRedisTTLSec(sl.ChannelID)
+	if err != nil {
+		return xerrors.Errorf("Unable to calcurate TTL of channel: %w", err)
+	}
+	rewindTo, err := rs.resolveRewindTarget(ctx, sl, ttl, target)
+	if err != nil {
+		return err
+	}
+	_, err = runStreamsMoveCursorScript(ctx, rs.base.RedisCmd, sl, ttl, rewindTo, false)
+	return err
+}
+
+// Returns the new subscriber cursor, messages after the cursor (exclusive) will be redelivered.
+func (rs *redisStreamsStorage) resolveRewindTarget(ctx context.Context, sl domain.SubscriberLocator, ttl channelTTLSec, target domain.SubscriberRewindTarget) (streamID, error) {
+	switch {
+	case target.Clock != nil:
+		id := streamIDOfClock(*target.Clock)
+		if id == nil {
+			return streamID{}, xerrors.Errorf("%w: clock %d is out of range", domain.ErrRewindTargetNotFound, *target.Clock)
+		}
+		lastID, oldestID, err := rs.retainedRange(ctx, sl.ChannelID, ttl)
+		if err != nil {
+			return streamID{}, xerrors.Errorf("RewindSubscriber failed: %w", err)
+		}
+		if lastID == nil {
+			return streamID{}, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+		}
+		// Valid range is [(just before the oldest message), (channel clock)]
+		if *id != *lastID && (oldestID == nil || lastID.Less(*id) || id.clock() < oldestID.clock()-1) {
+			return streamID{}, xerrors.Errorf("%w: clock %d", domain.ErrRewindTargetNotFound, *target.Clock)
+		}
+		return *id, nil
+
+	case target.MessageID != nil:
+		values, err := rs.base.RedisCmd.HMGet(ctx, keyOfStream(sl.ChannelID).MessageIDs(), string(*target.MessageID))
+		if err != nil {
+			return streamID{}, xerrors.Errorf("RewindSubscriber failed due to Redis error (HMGET error): %w", err)
+		}
+		var msgID *streamID
+		if values[0] != nil {
+			msgID = parseStreamID(*values[0])
+		}
+		if msgID == nil {
+			return streamID{}, xerrors.Errorf("%w: message %s", domain.ErrRewindTargetNotFound, *target.MessageID)
+		}
+		return *streamIDOfClock(msgID.clock() - 1), nil
+
+	case target.Earliest:
+		lastID, oldestID, err := rs.retainedRange(ctx, sl.ChannelID, ttl)
+		if err != nil {
+			return streamID{}, xerrors.Errorf("RewindSubscriber failed: %w", err)
+		}
+		if lastID == nil {
+			return streamID{}, xerrors.Errorf("%w", domain.ErrSubscriptionNotFound)
+		}
+		if oldestID == nil {
+			return *lastID, nil // No messages retained
+		}
+		return *streamIDOfClock(oldestID.clock() - 1), nil
+
+	default:
+		return streamID{}, xerrors.New("Rewind target not specified")
+	}
+}
+
+// retainedRange returns the last generated ID of the stream and ID of the oldest unexpired entry.
+// lastID is nil if the channel does not exist, oldestID is nil if no messages retained.
+func (rs *redisStreamsStorage) retainedRange(ctx context.Context, channelID domain.ChannelID, ttl channelTTLSec) (lastID *streamID, oldestID *streamID, err error) {
+	lastID, err = runStreamsLastIDScript(ctx, rs.base.RedisCmd, channelID)
+	if err != nil || lastID == nil {
+		return
+	}
+	entries, err := rs.base.RedisCmd.XRange(ctx, keyOfStream(channelID).Stream(), rs.retentionStart(ttl).String(), "+", 1)
+	if err != nil {
+		err = xerrors.Errorf("Redis error (XRANGE error): %w", err)
+		return
+	}
+	if len(entries) > 0 {
+		oldestID = parseStreamID(entries[0].ID)
+	}
+	return
+}
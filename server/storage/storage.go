@@ -9,7 +9,9 @@ import (
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/domain"
 	"github.com/m3dev/dsps/server/logger"
+	"github.com/m3dev/dsps/server/storage/bolt"
 	"github.com/m3dev/dsps/server/storage/deps"
+	"github.com/m3dev/dsps/server/storage/metrics"
 	"github.com/m3dev/dsps/server/storage/multiplex"
 	"github.com/m3dev/dsps/server/storage/onmemory"
 	"github.com/m3dev/dsps/server/storage/redis"
@@ -17,7 +19,7 @@ import (
 )
 
 // NewStorage initialize Storage instance as per given config
-func NewStorage(ctx context.Context, config *config.StoragesConfig, systemClock domain.SystemClock, channelProvider domain.ChannelProvider, deps deps.StorageDeps) (domain.Storage, error) {
+func NewStorage(ctx context.Context, config *config.StoragesConfig, multiplexerConfig *config.StorageMultiplexerConfig, systemClock domain.SystemClock, channelProvider domain.ChannelProvider, deps deps.StorageDeps) (domain.Storage, error) {
 	children := map[domain.StorageID]domain.Storage{}
 	for id, subConfig := range *config {
 		storage, err := newSubStorage(ctx, id, subConfig, systemClock, channelProvider, deps)
@@ -27,11 +29,31 @@ func NewStorage(ctx context.Context, config *config.StoragesConfig, systemClock
 		children[id] = tracing.NewTracingStorage(storage, id, deps)
 	}
 
-	storage, err := multiplex.NewStorageMultiplexer(children)
+	var storage domain.Storage
+	var err error
+	if multiplexerConfig.IsSharding() {
+		logger.Of(ctx).Debugf(logger.CatStorage, "Storage multiplexer routes each channel to one of %d storages (sharding mode)", len(children))
+		storage, err = multiplex.NewShardingStorageMultiplexer(children, multiplexerConfig.Shards, multiplexerConfig.PreviousShards)
+	} else {
+		storage, err = multiplex.NewStorageMultiplexer(children)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("Failed to initialize storage multiplexer: %w", err)
 	}
-	return tracing.NewTracingStorage(storage, "#root", deps), nil
+	return tracing.NewTracingStorage(metrics.NewMetricsStorage(storage, systemClock, channelProvider, deps), "#root", deps), nil
+}
+
+// NewSingleStorage initialize only one of configured storages without multiplexer, for maintenance tasks such as migration
+func NewSingleStorage(ctx context.Context, id domain.StorageID, config *config.StoragesConfig, systemClock domain.SystemClock, channelProvider domain.ChannelProvider, deps deps.StorageDeps) (domain.Storage, error) {
+	subConfig := (*config)[id]
+	if subConfig == nil {
+		return nil, fmt.Errorf("Storage \"%s\" is not configured", id)
+	}
+	storage, err := newSubStorage(ctx, id, subConfig, systemClock, channelProvider, deps)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize storage \"%s\": %w", id, err)
+	}
+	return tracing.NewTracingStorage(storage, id, deps), nil
 }
 
 func newSubStorage(ctx context.Context, id domain.StorageID, config *config.StorageConfig, systemClock domain.SystemClock, channelProvider domain.ChannelProvider, deps deps.StorageDeps) (domain.Storage, error) {
@@ -43,5 +65,9 @@ func newSubStorage(ctx context.Context, id domain.StorageID, config *config.Stor
 		logger.Of(ctx).Debugf(logger.CatStorage, "Starting Redis storage \"%s\"", id)
 		return redis.NewRedisStorage(ctx, config.Redis, systemClock, channelProvider, deps)
 	}
+	if config.Bolt != nil {
+		logger.Of(ctx).Debugf(logger.CatStorage, "Starting bolt storage \"%s\" (%s)", id, config.Bolt.Path)
+		return bolt.NewBoltStorage(ctx, config.Bolt, systemClock, channelProvider, deps)
+	}
 	return nil, xerrors.New("Empty storage configuration given")
 }
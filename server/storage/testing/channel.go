@@ -3,6 +3,7 @@ package testing
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/m3dev/dsps/server/domain"
 	dspstesting "github.com/m3dev/dsps/server/testing"
@@ -14,20 +15,36 @@ var DisabledChannelID domain.ChannelID = "disabled-channel"
 // StubChannelExpire is expire (TTL) of any channels pro
 var StubChannelExpire = dspstesting.MakeDuration("5m")
 
+// Channels of StubChannelProvider with these prefixes have BacklogLimit of StubChannelMaxBacklog messages.
+const (
+	BacklogRejectChannelPrefix = "backlog-reject-"
+	BacklogEvictChannelPrefix  = "backlog-evict-"
+	StubChannelMaxBacklog      = 3
+)
+
 // StubChannelProvider is simple stub implementation of ChannelProvider
 var StubChannelProvider domain.ChannelProvider = dspstesting.ChannelProviderFunc(func(id domain.ChannelID) (domain.Channel, error) {
 	if id == DisabledChannelID {
 		return nil, domain.ErrInvalidChannel
 	}
+	backlogLimit := domain.BacklogLimit{}
+	switch {
+	case strings.HasPrefix(string(id), BacklogRejectChannelPrefix):
+		backlogLimit = domain.BacklogLimit{Max: StubChannelMaxBacklog, Overflow: domain.BacklogOverflowReject}
+	case strings.HasPrefix(string(id), BacklogEvictChannelPrefix):
+		backlogLimit = domain.BacklogLimit{Max: StubChannelMaxBacklog, Overflow: domain.BacklogOverflowEvict}
+	}
 	return &stubChannel{
-		id:     id,
-		expire: StubChannelExpire,
+		id:           id,
+		expire:       StubChannelExpire,
+		backlogLimit: backlogLimit,
 	}, nil
 })
 
 type stubChannel struct {
-	id     domain.ChannelID
-	expire domain.Duration
+	id           domain.ChannelID
+	expire       domain.Duration
+	backlogLimit domain.BacklogLimit
 }
 
 func (c *stubChannel) String() string {
@@ -42,6 +59,26 @@ func (c *stubChannel) ValidateJwt(ctx context.Context, jwt string) error {
 	return nil
 }
 
-func (c *stubChannel) SendOutgoingWebhook(ctx context.Context, msg domain.Message) error {
+func (c *stubChannel) HasJwtValidation() bool {
+	return false
+}
+
+func (c *stubChannel) OutgoingWebhooks() []domain.OutgoingWebhook {
+	return []domain.OutgoingWebhook{}
+}
+
+func (c *stubChannel) MaxMessageBytes() int {
+	return 0
+}
+
+func (c *stubChannel) BacklogLimit() domain.BacklogLimit {
+	return c.backlogLimit
+}
+
+func (c *stubChannel) RateLimits(op domain.RateLimitOperation) []domain.RateLimitRule {
 	return nil
 }
+
+func (c *stubChannel) RegexLabel() string {
+	return ".+"
+}
@@ -21,8 +21,9 @@ func CoreFunctionTest(t *testing.T, storageCtor StorageCtor) {
 	assert.NotEmpty(t, storage.String())
 	assert.GreaterOrEqual(t, storage.GetFileDescriptorPressure(), 0)
 
-	storage.AsPubSubStorage() // Should not crash
-	storage.AsJwtStorage()    // Should not crash
+	storage.AsPubSubStorage()    // Should not crash
+	storage.AsJwtStorage()       // Should not crash
+	storage.AsRateLimitStorage() // Should not crash
 
 	if _, err := storage.Liveness(ctx); !assert.NoError(t, err) {
 		return
@@ -16,6 +16,7 @@ import (
 func JwtTest(t *testing.T, storageCtor StorageCtor) {
 	storageSubTest(t, storageCtor, "JWTScenario", _jwtScenarioTest)
 	storageSubTest(t, storageCtor, "JWTPastExp", _jwtPastExpTest)
+	storageSubTest(t, storageCtor, "JWTExport", _jwtExportTest)
 }
 
 func _jwtScenarioTest(t *testing.T, storageCtor StorageCtor) {
@@ -59,6 +60,34 @@ func _jwtPastExpTest(t *testing.T, storageCtor StorageCtor) {
 	assert.False(t, result)
 }
 
+func _jwtExportTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsJwtStorage()
+	assert.NotNil(t, storage)
+
+	jti := _randomJti()
+	exp := domain.JwtExp(time.Now().Add(24 * time.Hour).Truncate(time.Second))
+	pastJti := _randomJti()
+	assert.NoError(t, storage.RevokeJwt(ctx, exp, jti))
+	assert.NoError(t, storage.RevokeJwt(ctx, domain.JwtExp(time.Now().Add(-10*time.Minute)), pastJti))
+
+	revoked, err := storage.ExportRevokedJwts(ctx)
+	assert.NoError(t, err)
+	found := map[domain.JwtJti]domain.JwtExp{}
+	for _, r := range revoked {
+		found[r.Jti] = r.Exp
+	}
+	if assert.Contains(t, found, jti) {
+		assert.Equal(t, exp.Int64(), found[jti].Int64())
+	}
+	assert.NotContains(t, found, pastJti)
+}
+
 func _randomJti() domain.JwtJti {
 	uuid, err := uuid.NewRandom()
 	if err != nil {
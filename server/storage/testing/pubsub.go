@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
@@ -26,6 +27,18 @@ func PubSubTest(t *testing.T, storageCtor StorageCtor) {
 	storageSubTest(t, storageCtor, "pubSubInvalidChannel", _pubSubInvalidChannelTest)
 	storageSubTest(t, storageCtor, "pubsubInvalidSubscriber", _pubsubInvalidSubscriber)
 	storageSubTest(t, storageCtor, "pubSubInvalidMessage", _pubSubInvalidMessageTest)
+	storageSubTest(t, storageCtor, "messageAttributes", _messageAttributesTest)
+	storageSubTest(t, storageCtor, "messageTraceParent", _messageTraceParentTest)
+	storageSubTest(t, storageCtor, "subscriberFilter", _subscriberFilterTest)
+	storageSubTest(t, storageCtor, "inspectChannels", _inspectChannelsTest)
+	storageSubTest(t, storageCtor, "rewindSubscriber", _rewindSubscriberTest)
+	storageSubTest(t, storageCtor, "backlogLimitReject", _backlogLimitRejectTest)
+	storageSubTest(t, storageCtor, "backlogLimitEvict", _backlogLimitEvictTest)
+	storageSubTest(t, storageCtor, "backlogLimitEvictBeyondMax", _backlogLimitEvictBeyondMaxTest)
+	storageSubTest(t, storageCtor, "exportChannel", _exportChannelTest)
+	storageSubTest(t, storageCtor, "exportChannels", _exportChannelsTest)
+	storageSubTest(t, storageCtor, "internalSubscriber", _internalSubscriberTest)
+	storageSubTest(t, storageCtor, "queueSubscriber", _queueSubscriberTest)
 }
 
 func _pubSubScenarioTest(t *testing.T, storageCtor StorageCtor) {
@@ -45,10 +58,10 @@ func _pubSubScenarioTest(t *testing.T, storageCtor StorageCtor) {
 	}
 
 	// Create subscriber
-	if !assert.NoError(t, storage.NewSubscriber(ctx, sl)) {
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) {
 		return
 	}
-	if !assert.NoError(t, storage.NewSubscriber(ctx, sl)) { // Must be idempotent
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) { // Must be idempotent
 		return
 	}
 	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
@@ -171,7 +184,7 @@ func _manyQueuesMessagesTest(t *testing.T, storageCtor StorageCtor) {
 	}
 
 	// Create subscriber
-	if !assert.NoError(t, storage.NewSubscriber(ctx, sl)) {
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) {
 		return
 	}
 	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
@@ -232,7 +245,7 @@ func _longPollingTest(t *testing.T, storageCtor StorageCtor) {
 	}
 
 	// Create subscriber
-	if !assert.NoError(t, storage.NewSubscriber(ctx, sl)) {
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) {
 		return
 	}
 	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
@@ -291,7 +304,7 @@ func _longPollingCancelTest(t *testing.T, storageCtor StorageCtor) {
 	}
 
 	// Create subscriber
-	if !assert.NoError(t, storage.NewSubscriber(ctx, sl)) {
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) {
 		return
 	}
 	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
@@ -340,7 +353,7 @@ func _messageAgeDetectionTest(t *testing.T, storageCtor StorageCtor) {
 		ChannelID:    ch,
 		SubscriberID: "sbsc1",
 	}
-	if !assert.NoError(t, storage.NewSubscriber(ctx, sl)) {
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) {
 		return
 	}
 	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
@@ -466,7 +479,7 @@ func _pubSubInvalidChannelTest(t *testing.T, storageCtor StorageCtor) {
 		ChannelID:    validCh,
 		SubscriberID: "sbsc1",
 	}
-	assert.NoError(t, storage.NewSubscriber(ctx, validSL))
+	assert.NoError(t, storage.NewSubscriber(ctx, validSL, nil))
 	assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{
 		{
 			MessageLocator: domain.MessageLocator{
@@ -485,7 +498,7 @@ func _pubSubInvalidChannelTest(t *testing.T, storageCtor StorageCtor) {
 		SubscriberID: "sbsc2",
 	}
 
-	dspstesting.IsError(t, domain.ErrInvalidChannel, storage.NewSubscriber(ctx, sl))
+	dspstesting.IsError(t, domain.ErrInvalidChannel, storage.NewSubscriber(ctx, sl, nil))
 	dspstesting.IsError(t, domain.ErrInvalidChannel, storage.PublishMessages(ctx, []domain.Message{
 		{
 			MessageLocator: domain.MessageLocator{
@@ -533,7 +546,7 @@ func _pubsubInvalidSubscriber(t *testing.T, storageCtor StorageCtor) {
 		ChannelID:    ch,
 		SubscriberID: "sbsc1",
 	}
-	assert.NoError(t, storage.NewSubscriber(ctx, validSL))
+	assert.NoError(t, storage.NewSubscriber(ctx, validSL, nil))
 	assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{
 		{
 			MessageLocator: domain.MessageLocator{
@@ -595,3 +608,655 @@ func _pubSubInvalidMessageTest(t *testing.T, storageCtor StorageCtor) {
 		},
 	}))
 }
+
+func _messageAttributesTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	sl := domain.SubscriberLocator{
+		ChannelID:    randomChannelID(),
+		SubscriberID: "sbsc1",
+	}
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) {
+		return
+	}
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+
+	messages := []domain.Message{
+		{
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-with-attrs"},
+			Content:        json.RawMessage(`{"hi":"hello"}`),
+			Attributes:     domain.MessageAttributes{"event-type": "created", "empty": ""},
+		},
+		{
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-without-attrs"},
+			Content:        json.RawMessage(`{"hi":"hello"}`),
+		},
+	}
+	if !assert.NoError(t, storage.PublishMessages(ctx, messages)) {
+		return
+	}
+	if received, _, _, err := storage.FetchMessages(ctx, sl, 10, dspstesting.MakeDuration("0ms")); assert.NoError(t, err) && assert.Len(t, received, 2) {
+		assert.Equal(t, domain.MessageAttributes{"event-type": "created", "empty": ""}, received[0].Attributes)
+		assert.Empty(t, received[1].Attributes)
+	}
+}
+
+func _subscriberFilterTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	sl := domain.SubscriberLocator{
+		ChannelID:    randomChannelID(),
+		SubscriberID: "sbsc1",
+	}
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"},"content":{"user.id":1}}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, filter)) {
+		return
+	}
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+
+	publish := func(id string, eventType string, userID int) {
+		assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{{
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: domain.MessageID(id)},
+			Content:        json.RawMessage(fmt.Sprintf(`{"user":{"id":%d}}`, userID)),
+			Attributes:     domain.MessageAttributes{"event-type": eventType},
+		}}))
+	}
+	publish("msg-1", "deleted", 1)
+	publish("msg-2", "created", 2)
+	publish("msg-3", "created", 1)
+	publish("msg-4", "deleted", 1)
+	publish("msg-5", "deleted", 2)
+	publish("msg-6", "deleted", 2)
+	publish("msg-7", "created", 1)
+
+	// Messages not matched with the filter must be skipped even if fetched size is limited.
+	receivedIDs := []domain.MessageID{}
+	for i := 0; i < 7; i++ {
+		received, _, ackHandle, err := storage.FetchMessages(ctx, sl, 2, dspstesting.MakeDuration("0ms"))
+		if !assert.NoError(t, err) || len(received) == 0 {
+			break
+		}
+		for _, msg := range received {
+			receivedIDs = append(receivedIDs, msg.MessageID)
+		}
+		assert.NoError(t, storage.AcknowledgeMessages(ctx, ackHandle))
+	}
+	assert.Equal(t, []domain.MessageID{"msg-3", "msg-7"}, receivedIDs)
+
+	publish("msg-8", "deleted", 1)
+	received, _, _, err := storage.FetchMessages(ctx, sl, 2, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Len(t, received, 0)
+
+	// Re-creating subscriber replaces the filter
+	assert.NoError(t, storage.NewSubscriber(ctx, sl, nil))
+	publish("msg-9", "deleted", 1)
+	received, _, _, err = storage.FetchMessages(ctx, sl, 2, dspstesting.MakeDuration("0ms"))
+	if assert.NoError(t, err) && assert.Len(t, received, 1) {
+		assert.Equal(t, domain.MessageID("msg-9"), received[0].MessageID)
+	}
+
+	// Filter applies on fetch, thus the replaced filter also applies to messages published before
+	publish("msg-10", "created", 1)
+	assert.NoError(t, storage.NewSubscriber(ctx, sl, filter))
+	received, _, _, err = storage.FetchMessages(ctx, sl, 2, dspstesting.MakeDuration("0ms"))
+	if assert.NoError(t, err) && assert.Len(t, received, 1) {
+		assert.Equal(t, domain.MessageID("msg-10"), received[0].MessageID)
+	}
+}
+
+func _inspectChannelsTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	ch := randomChannelID()
+	sl1 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc1"}
+	sl2 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc2"}
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+	assert.NoError(t, storage.NewSubscriber(ctx, sl1, nil))
+	assert.NoError(t, storage.NewSubscriber(ctx, sl2, filter))
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl1)) }()
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl2)) }()
+
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: domain.MessageID(fmt.Sprintf("msg-%d", i))},
+			Content:        json.RawMessage(`{}`),
+			Attributes:     domain.MessageAttributes{"event-type": "created"},
+		}}))
+	}
+	if received, _, ackHandle, err := storage.FetchMessages(ctx, sl1, 1, dspstesting.MakeDuration("0ms")); assert.NoError(t, err) && assert.Len(t, received, 1) {
+		assert.NoError(t, storage.AcknowledgeMessages(ctx, ackHandle))
+	}
+
+	inspections, err := storage.InspectChannels(ctx, ch)
+	if !assert.NoError(t, err) || !assert.Len(t, inspections, 1) {
+		return
+	}
+	inspection := inspections[0]
+	assert.Equal(t, ch, inspection.ChannelID)
+	if assert.Len(t, inspection.Subscribers, 2) {
+		sbsc1, sbsc2 := inspection.Subscribers[0], inspection.Subscribers[1]
+		assert.Equal(t, sl1.SubscriberID, sbsc1.SubscriberID)
+		assert.Equal(t, int64(2), sbsc1.Backlog)
+		assert.Nil(t, sbsc1.Filter)
+		assert.Equal(t, sl2.SubscriberID, sbsc2.SubscriberID)
+		assert.Equal(t, int64(3), sbsc2.Backlog)
+		assert.Equal(t, filter.String(), sbsc2.Filter.String())
+		for _, sbsc := range inspection.Subscribers {
+			if assert.NotNil(t, sbsc.TTL) {
+				assert.Greater(t, int64(sbsc.TTL.Duration), int64(0))
+			}
+		}
+	}
+
+	// List all channels
+	inspections, err = storage.InspectChannels(ctx, "")
+	assert.NoError(t, err)
+	found := false
+	for _, inspection := range inspections {
+		found = found || inspection.ChannelID == ch
+	}
+	assert.True(t, found)
+
+	// Unknown channel
+	inspections, err = storage.InspectChannels(ctx, randomChannelID())
+	assert.NoError(t, err)
+	assert.Len(t, inspections, 0)
+}
+
+func _rewindSubscriberTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	ch := randomChannelID()
+	sl1 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc1"}
+	sl2 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc2"}
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+	assert.NoError(t, storage.NewSubscriber(ctx, sl1, nil))
+	assert.NoError(t, storage.NewSubscriber(ctx, sl2, filter))
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl1)) }()
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl2)) }()
+
+	for i, eventType := range []string{"created", "created", "deleted", "created"} {
+		assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: domain.MessageID(fmt.Sprintf("msg-%d", i+1))},
+			Content:        json.RawMessage(`{}`),
+			Attributes:     domain.MessageAttributes{"event-type": eventType},
+		}}))
+	}
+	consumeAll := func(sl domain.SubscriberLocator) []domain.MessageID {
+		receivedIDs := []domain.MessageID{}
+		for i := 0; i < 4; i++ {
+			received, _, ackHandle, err := storage.FetchMessages(ctx, sl, 4, dspstesting.MakeDuration("0ms"))
+			if !assert.NoError(t, err) || len(received) == 0 {
+				break
+			}
+			for _, msg := range received {
+				receivedIDs = append(receivedIDs, msg.MessageID)
+			}
+			assert.NoError(t, storage.AcknowledgeMessages(ctx, ackHandle))
+		}
+		return receivedIDs
+	}
+	assert.Equal(t, []domain.MessageID{"msg-1", "msg-2", "msg-3", "msg-4"}, consumeAll(sl1))
+	assert.Equal(t, []domain.MessageID{"msg-1", "msg-2", "msg-4"}, consumeAll(sl2))
+
+	// Rewind to message ID
+	msgID := domain.MessageID("msg-2")
+	assert.NoError(t, storage.RewindSubscriber(ctx, sl1, domain.SubscriberRewindTarget{MessageID: &msgID}))
+	assert.Equal(t, []domain.MessageID{"msg-2", "msg-3", "msg-4"}, consumeAll(sl1))
+
+	// Rewind to earliest, filter still applies
+	assert.NoError(t, storage.RewindSubscriber(ctx, sl2, domain.SubscriberRewindTarget{Earliest: true}))
+	assert.Equal(t, []domain.MessageID{"msg-1", "msg-2", "msg-4"}, consumeAll(sl2))
+
+	// Rewind to clock
+	inspections, err := storage.InspectChannels(ctx, ch)
+	if assert.NoError(t, err) && assert.Len(t, inspections, 1) {
+		clock := inspections[0].Clock - 1
+		assert.NoError(t, storage.RewindSubscriber(ctx, sl1, domain.SubscriberRewindTarget{Clock: &clock}))
+		assert.Equal(t, []domain.MessageID{"msg-4"}, consumeAll(sl1))
+
+		clock = inspections[0].Clock
+		assert.NoError(t, storage.RewindSubscriber(ctx, sl1, domain.SubscriberRewindTarget{Clock: &clock}))
+		assert.Equal(t, []domain.MessageID{}, consumeAll(sl1))
+	}
+
+	// Unknown targets
+	msgID = domain.MessageID("msg-unknown")
+	err = storage.RewindSubscriber(ctx, sl1, domain.SubscriberRewindTarget{MessageID: &msgID})
+	assert.True(t, errors.Is(err, domain.ErrRewindTargetNotFound))
+	clock := int64(-100)
+	err = storage.RewindSubscriber(ctx, sl1, domain.SubscriberRewindTarget{Clock: &clock})
+	assert.True(t, errors.Is(err, domain.ErrRewindTargetNotFound))
+
+	// Unknown subscriber
+	err = storage.RewindSubscriber(ctx, domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc-unknown"}, domain.SubscriberRewindTarget{Earliest: true})
+	assert.True(t, errors.Is(err, domain.ErrSubscriptionNotFound))
+}
+
+func _messageTraceParentTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	sl := domain.SubscriberLocator{
+		ChannelID:    randomChannelID(),
+		SubscriberID: "sbsc1",
+	}
+	if !assert.NoError(t, storage.NewSubscriber(ctx, sl, nil)) {
+		return
+	}
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+
+	traceParent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	messages := []domain.Message{
+		{
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-traced"},
+			Content:        json.RawMessage(`{}`),
+			TraceParent:    traceParent,
+		},
+		{
+			MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-not-traced"},
+			Content:        json.RawMessage(`{}`),
+		},
+	}
+	if !assert.NoError(t, storage.PublishMessages(ctx, messages)) {
+		return
+	}
+	if received, _, _, err := storage.FetchMessages(ctx, sl, 10, dspstesting.MakeDuration("0ms")); assert.NoError(t, err) && assert.Len(t, received, 2) {
+		assert.Equal(t, traceParent, received[0].TraceParent)
+		assert.Equal(t, "", received[1].TraceParent)
+	}
+}
+
+func _backlogLimitRejectTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	ch := domain.ChannelID(BacklogRejectChannelPrefix + string(randomChannelID()))
+	sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc1"}
+	assert.NoError(t, storage.NewSubscriber(ctx, sl, nil))
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+
+	messages := make([]domain.Message, StubChannelMaxBacklog+1)
+	for i := range messages {
+		messages[i] = domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: domain.MessageID(fmt.Sprintf("msg-%d", i+1))},
+			Content:        json.RawMessage(`{}`),
+		}
+	}
+	assert.NoError(t, storage.PublishMessages(ctx, messages[:StubChannelMaxBacklog]))
+	dspstesting.IsError(t, domain.ErrBacklogFull, storage.PublishMessages(ctx, messages[StubChannelMaxBacklog:]))
+	assert.NoError(t, storage.PublishMessages(ctx, messages[0:1])) // Duplicated message is not rejected
+
+	// Acknowledgement does not make room, messages are retained until expire
+	received, _, ackHandle, err := storage.FetchMessages(ctx, sl, 10, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, messages[:StubChannelMaxBacklog], received)
+	assert.NoError(t, storage.AcknowledgeMessages(ctx, ackHandle))
+	dspstesting.IsError(t, domain.ErrBacklogFull, storage.PublishMessages(ctx, messages[StubChannelMaxBacklog:]))
+}
+
+func _backlogLimitEvictTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	ch := domain.ChannelID(BacklogEvictChannelPrefix + string(randomChannelID()))
+	sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc1"}
+	assert.NoError(t, storage.NewSubscriber(ctx, sl, nil))
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+
+	messages := make([]domain.Message, StubChannelMaxBacklog+2)
+	for i := range messages {
+		messages[i] = domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: domain.MessageID(fmt.Sprintf("msg-%d", i+1))},
+			Content:        json.RawMessage(`{}`),
+		}
+	}
+	assert.NoError(t, storage.PublishMessages(ctx, messages))
+	assert.NoError(t, storage.PublishMessages(ctx, messages[0:1])) // Evicted message is still deduplicated
+
+	// Oldest messages are discarded
+	received, _, _, err := storage.FetchMessages(ctx, sl, 10, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, messages[2:], received)
+
+	assert.NoError(t, storage.RewindSubscriber(ctx, sl, domain.SubscriberRewindTarget{Earliest: true}))
+	received, _, _, err = storage.FetchMessages(ctx, sl, 10, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, messages[2:], received)
+}
+
+func _backlogLimitEvictBeyondMaxTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	ch := domain.ChannelID(BacklogEvictChannelPrefix + string(randomChannelID()))
+	sl := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc1"}
+	assert.NoError(t, storage.NewSubscriber(ctx, sl, nil))
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+
+	// Evicts more messages than max of FetchMessages
+	messages := make([]domain.Message, StubChannelMaxBacklog+10)
+	for i := range messages {
+		messages[i] = domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: domain.MessageID(fmt.Sprintf("msg-%d", i+1))},
+			Content:        json.RawMessage(`{}`),
+		}
+	}
+	assert.NoError(t, storage.PublishMessages(ctx, messages))
+	retained := messages[len(messages)-StubChannelMaxBacklog:]
+
+	// Subscriber must not get stuck on evicted messages
+	received, moreMessages, ackHandle, err := storage.FetchMessages(ctx, sl, 2, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, retained[:2], received)
+	assert.True(t, moreMessages)
+	assert.NoError(t, storage.AcknowledgeMessages(ctx, ackHandle))
+
+	received, moreMessages, _, err = storage.FetchMessages(ctx, sl, 2, dspstesting.MakeDuration("0ms"))
+	assert.NoError(t, err)
+	assert.Equal(t, retained[2:], received)
+	assert.False(t, moreMessages)
+}
+
+func _exportChannelTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	ch := randomChannelID()
+	sl1 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc1"}
+	sl2 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc2"}
+	sl3 := domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc3"}
+	filter, err := domain.ParseSubscriberFilter([]byte(`{"attributes":{"event-type":"created"}}`))
+	assert.NoError(t, err)
+	assert.NoError(t, storage.NewSubscriber(ctx, sl1, nil))
+	assert.NoError(t, storage.NewSubscriber(ctx, sl2, filter))
+
+	messages := make([]domain.Message, 0, 3)
+	for i := 1; i <= 3; i++ {
+		messages = append(messages, domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: domain.MessageID(fmt.Sprintf("msg-%d", i))},
+			Content:        json.RawMessage(fmt.Sprintf(`{"i":%d}`, i)),
+			Attributes:     domain.MessageAttributes{"event-type": "created"},
+		})
+		assert.NoError(t, storage.PublishMessages(ctx, messages[i-1:i]))
+	}
+	consumed := 0
+	if received, _, ackHandle, err := storage.FetchMessages(ctx, sl1, 1, dspstesting.MakeDuration("0ms")); assert.NoError(t, err) && assert.NotEmpty(t, received) {
+		assert.NoError(t, storage.AcknowledgeMessages(ctx, ackHandle))
+		consumed = len(received)
+	}
+	assert.NoError(t, storage.NewSubscriber(ctx, sl3, nil)) // Created after messages
+
+	export, err := storage.ExportChannel(ctx, ch)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ch, export.ChannelID)
+	assert.Equal(t, messages, export.Messages)
+	if assert.Len(t, export.Subscribers, 3) {
+		sbsc1, sbsc2, sbsc3 := export.Subscribers[0], export.Subscribers[1], export.Subscribers[2]
+		assert.Equal(t, sl1.SubscriberID, sbsc1.SubscriberID)
+		assert.Nil(t, sbsc1.Filter)
+		assert.Equal(t, consumed, sbsc1.Consumed)
+		assert.Equal(t, messages[consumed:], sbsc1.Backlog(export))
+		assert.Equal(t, sl2.SubscriberID, sbsc2.SubscriberID)
+		assert.Equal(t, filter.String(), sbsc2.Filter.String())
+		assert.Equal(t, 0, sbsc2.Consumed)
+		assert.Equal(t, sl3.SubscriberID, sbsc3.SubscriberID)
+		assert.Equal(t, 3, sbsc3.Consumed)
+		assert.Empty(t, sbsc3.Backlog(export))
+	}
+
+	// Unknown channel
+	export, err = storage.ExportChannel(ctx, randomChannelID())
+	assert.NoError(t, err)
+	assert.Empty(t, export.Messages)
+	assert.Empty(t, export.Subscribers)
+}
+
+func _exportChannelsTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	chs := map[domain.ChannelID]bool{}
+	for i := 1; i <= 3; i++ {
+		ch := randomChannelID()
+		chs[ch] = true
+		assert.NoError(t, storage.NewSubscriber(ctx, domain.SubscriberLocator{ChannelID: ch, SubscriberID: "sbsc1"}, nil))
+		for j := 1; j <= i; j++ {
+			assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{{
+				MessageLocator: domain.MessageLocator{ChannelID: ch, MessageID: domain.MessageID(fmt.Sprintf("msg-%d", j))},
+				Content:        json.RawMessage(fmt.Sprintf(`{"j":%d}`, j)),
+			}}))
+		}
+	}
+
+	exported := []domain.ChannelID{}
+	assert.NoError(t, storage.ExportChannels(ctx, func(export domain.ChannelExport) error {
+		if !chs[export.ChannelID] {
+			return nil // Channel of other tests
+		}
+		exported = append(exported, export.ChannelID)
+		expected, err := storage.ExportChannel(ctx, export.ChannelID)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, export)
+		return nil
+	}))
+	assert.Len(t, exported, 3)
+	assert.True(t, sort.SliceIsSorted(exported, func(i, j int) bool { return exported[i] < exported[j] }))
+
+	// Stops on error
+	errToReturn := errors.New("test error")
+	calls := 0
+	err = storage.ExportChannels(ctx, func(export domain.ChannelExport) error {
+		calls++
+		return errToReturn
+	})
+	dspstesting.IsError(t, errToReturn, err)
+	assert.Equal(t, 1, calls)
+}
+
+// Internal subscribers (e.g. of outgoing-webhook) have IDs that API does not accept, storage must treat them same as others.
+func _internalSubscriberTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	sl := domain.SubscriberLocator{ChannelID: randomChannelID(), SubscriberID: "_webhook-0123456789abcdef"}
+	assert.NoError(t, storage.NewSubscriber(ctx, sl, nil))
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+	assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{{
+		MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: "msg-1"},
+		Content:        json.RawMessage(`{}`),
+	}}))
+
+	inspections, err := storage.InspectChannels(ctx, sl.ChannelID)
+	assert.NoError(t, err)
+	if assert.Len(t, inspections, 1) && assert.Len(t, inspections[0].Subscribers, 1) {
+		assert.Equal(t, sl.SubscriberID, inspections[0].Subscribers[0].SubscriberID)
+		assert.Equal(t, int64(1), inspections[0].Subscribers[0].Backlog)
+	}
+
+	export, err := storage.ExportChannel(ctx, sl.ChannelID)
+	assert.NoError(t, err)
+	if assert.Len(t, export.Subscribers, 1) {
+		assert.Equal(t, sl.SubscriberID, export.Subscribers[0].SubscriberID)
+		assert.Len(t, export.Subscribers[0].Backlog(export), 1)
+	}
+	found := false
+	assert.NoError(t, storage.ExportChannels(ctx, func(export domain.ChannelExport) error {
+		if export.ChannelID == sl.ChannelID {
+			found = len(export.Subscribers) == 1 && export.Subscribers[0].SubscriberID == sl.SubscriberID
+		}
+		return nil
+	}))
+	assert.True(t, found)
+}
+
+func _queueSubscriberTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsPubSubStorage()
+	assert.NotNil(t, storage)
+
+	sl := domain.SubscriberLocator{ChannelID: randomChannelID(), SubscriberID: "sbsc1"}
+	vt := dspstesting.MakeDuration("500ms")
+	err = storage.NewQueueSubscriber(ctx, sl, nil, vt)
+	if errors.Is(err, domain.ErrQueueSubscriberUnsupported) {
+		return // Optional feature of the storage
+	}
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, storage.RemoveSubscriber(ctx, sl)) }()
+
+	publish := func(ids ...string) {
+		for _, id := range ids {
+			assert.NoError(t, storage.PublishMessages(ctx, []domain.Message{{
+				MessageLocator: domain.MessageLocator{ChannelID: sl.ChannelID, MessageID: domain.MessageID(id)},
+				Content:        json.RawMessage(`{}`),
+			}}))
+		}
+	}
+	fetch := func(max int, waituntil string) ([]domain.MessageID, domain.AckHandle) {
+		received, _, ackHandle, err := storage.FetchMessages(ctx, sl, max, dspstesting.MakeDuration(waituntil))
+		assert.NoError(t, err)
+		ids := []domain.MessageID{}
+		for _, msg := range received {
+			ids = append(ids, msg.MessageID)
+		}
+		return ids, ackHandle
+	}
+	publish("msg-1", "msg-2", "msg-3", "msg-4")
+
+	// Consumers receive different messages
+	idsA, handleA := fetch(2, "0ms")
+	assert.Equal(t, []domain.MessageID{"msg-1", "msg-2"}, idsA)
+	idsB, handleB := fetch(2, "0ms")
+	assert.Equal(t, []domain.MessageID{"msg-3", "msg-4"}, idsB)
+	idsC, _ := fetch(2, "0ms")
+	assert.Empty(t, idsC)
+
+	// Acknowledging completes only messages of the lease
+	assert.NoError(t, storage.AcknowledgeMessages(ctx, handleB))
+	inspections, err := storage.InspectChannels(ctx, sl.ChannelID)
+	if assert.NoError(t, err) && assert.Len(t, inspections, 1) && assert.Len(t, inspections[0].Subscribers, 1) {
+		sbsc := inspections[0].Subscribers[0]
+		if assert.NotNil(t, sbsc.VisibilityTimeout) {
+			assert.Equal(t, vt, *sbsc.VisibilityTimeout)
+		}
+		assert.Equal(t, int64(2), sbsc.Leased)
+	}
+	export, err := storage.ExportChannel(ctx, sl.ChannelID)
+	if assert.NoError(t, err) && assert.Len(t, export.Subscribers, 1) && assert.NotNil(t, export.Subscribers[0].VisibilityTimeout) {
+		assert.Equal(t, vt, *export.Subscribers[0].VisibilityTimeout)
+	}
+
+	// Unacknowledged messages become visible again after the lease expired
+	time.Sleep(vt.Duration + 100*time.Millisecond)
+	idsC, handleC := fetch(4, "0ms")
+	assert.Equal(t, []domain.MessageID{"msg-1", "msg-2"}, idsC)
+	assert.NoError(t, storage.AcknowledgeMessages(ctx, handleA)) // Stale, messages have been leased again
+	idsD, _ := fetch(4, "0ms")
+	assert.Empty(t, idsD)
+	assert.NoError(t, storage.AcknowledgeMessages(ctx, handleC))
+
+	// Long polling returns messages of expired lease
+	publish("msg-5")
+	idsD, _ = fetch(4, "0ms")
+	assert.Equal(t, []domain.MessageID{"msg-5"}, idsD)
+	idsE, _ := fetch(4, "3s")
+	assert.Equal(t, []domain.MessageID{"msg-5"}, idsE)
+
+	// Turn into normal subscriber, leased messages are redelivered
+	assert.NoError(t, storage.NewSubscriber(ctx, sl, nil))
+	ids, handle := fetch(4, "0ms")
+	assert.Equal(t, []domain.MessageID{"msg-5"}, ids)
+	assert.NoError(t, storage.AcknowledgeMessages(ctx, handle))
+	ids, _ = fetch(4, "0ms")
+	assert.Empty(t, ids)
+	inspections, err = storage.InspectChannels(ctx, sl.ChannelID)
+	if assert.NoError(t, err) && assert.Len(t, inspections, 1) && assert.Len(t, inspections[0].Subscribers, 1) {
+		assert.Nil(t, inspections[0].Subscribers[0].VisibilityTimeout)
+		assert.Equal(t, int64(0), inspections[0].Subscribers[0].Leased)
+	}
+}
@@ -0,0 +1,50 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// RateLimitTest tests common Storage behaviors
+func RateLimitTest(t *testing.T, storageCtor StorageCtor) {
+	storageSubTest(t, storageCtor, "RateLimitCounter", _rateLimitCounterTest)
+}
+
+func _rateLimitCounterTest(t *testing.T, storageCtor StorageCtor) {
+	ctx := context.Background()
+	s, err := storageCtor(ctx, domain.RealSystemClock, StubChannelProvider)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, s.Shutdown(ctx)) }()
+	storage := s.AsRateLimitStorage()
+	assert.NotNil(t, storage)
+
+	key := uuid.Must(uuid.NewRandom()).String()
+	window := domain.Duration{Duration: 500 * time.Millisecond}
+
+	for i := int64(1); i <= 3; i++ {
+		count, resetIn, err := storage.IncrementRateLimitCounter(ctx, key, window)
+		assert.NoError(t, err)
+		assert.Equal(t, i, count)
+		assert.Greater(t, int64(resetIn.Duration), int64(0))
+		assert.LessOrEqual(t, int64(resetIn.Duration), int64(window.Duration))
+	}
+
+	// Other key is independent
+	count, _, err := storage.IncrementRateLimitCounter(ctx, key+"-X", window)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// New window
+	time.Sleep(window.Duration + 100*time.Millisecond)
+	count, _, err = storage.IncrementRateLimitCounter(ctx, key, window)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -19,3 +19,9 @@ func (ts *tracingStorage) IsRevokedJwt(ctx context.Context, jti domain.JwtJti) (
 	defer end()
 	return ts.jwt.IsRevokedJwt(ctx, jti)
 }
+
+func (ts *tracingStorage) ExportRevokedJwts(ctx context.Context) ([]domain.RevokedJwt, error) {
+	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "ExportRevokedJwts")
+	defer end()
+	return ts.jwt.ExportRevokedJwts(ctx)
+}
@@ -15,6 +15,8 @@ func TestJWTTrace(t *testing.T) {
 		assert.NoError(t, s.AsJwtStorage().RevokeJwt(context.Background(), domain.JwtExp(time.Now()), domain.JwtJti("jti-value")))
 		_, err := s.AsJwtStorage().IsRevokedJwt(context.Background(), "jti-value")
 		assert.NoError(t, err)
+		_, err = s.AsJwtStorage().ExportRevokedJwts(context.Background())
+		assert.NoError(t, err)
 	})
 	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage RevokeJwt", map[string]interface{}{
 		"dsps.storage.id": "test",
@@ -24,6 +26,9 @@ func TestJWTTrace(t *testing.T) {
 		"dsps.storage.id": "test",
 		"jwt.jti":         "jti-value",
 	})
+	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage ExportRevokedJwts", map[string]interface{}{
+		"dsps.storage.id": "test",
+	})
 	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage Shutdown", map[string]interface{}{
 		"dsps.storage.id": "test",
 	})
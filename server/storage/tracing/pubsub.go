@@ -6,11 +6,18 @@ import (
 	"github.com/m3dev/dsps/server/domain"
 )
 
-func (ts *tracingStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
+func (ts *tracingStorage) NewSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter) error {
 	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "NewSubscriber")
 	ts.t.SetSubscriberAttributes(ctx, sl)
 	defer end()
-	return ts.pubsub.NewSubscriber(ctx, sl)
+	return ts.pubsub.NewSubscriber(ctx, sl, filter)
+}
+
+func (ts *tracingStorage) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "NewQueueSubscriber")
+	ts.t.SetSubscriberAttributes(ctx, sl)
+	defer end()
+	return ts.pubsub.NewQueueSubscriber(ctx, sl, filter, visibilityTimeout)
 }
 
 func (ts *tracingStorage) RemoveSubscriber(ctx context.Context, sl domain.SubscriberLocator) error {
@@ -46,3 +53,27 @@ func (ts *tracingStorage) IsOldMessages(ctx context.Context, sl domain.Subscribe
 	defer end()
 	return ts.pubsub.IsOldMessages(ctx, sl, msgs)
 }
+
+func (ts *tracingStorage) InspectChannels(ctx context.Context, channelID domain.ChannelID) ([]domain.ChannelInspection, error) {
+	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "InspectChannels")
+	defer end()
+	return ts.pubsub.InspectChannels(ctx, channelID)
+}
+
+func (ts *tracingStorage) RewindSubscriber(ctx context.Context, sl domain.SubscriberLocator, target domain.SubscriberRewindTarget) error {
+	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "RewindSubscriber")
+	defer end()
+	return ts.pubsub.RewindSubscriber(ctx, sl, target)
+}
+
+func (ts *tracingStorage) ExportChannel(ctx context.Context, channelID domain.ChannelID) (domain.ChannelExport, error) {
+	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "ExportChannel")
+	defer end()
+	return ts.pubsub.ExportChannel(ctx, channelID)
+}
+
+func (ts *tracingStorage) ExportChannels(ctx context.Context, fn func(domain.ChannelExport) error) error {
+	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "ExportChannels")
+	defer end()
+	return ts.pubsub.ExportChannels(ctx, fn)
+}
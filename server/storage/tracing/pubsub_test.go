@@ -21,14 +21,17 @@ func TestPubSubTrace(t *testing.T) {
 		ctx := context.Background()
 		pubsub := s.AsPubSubStorage()
 
-		assert.NoError(t, pubsub.NewSubscriber(ctx, sl))
+		assert.NoError(t, pubsub.NewSubscriber(ctx, sl, nil))
 		assert.NoError(t, pubsub.PublishMessages(ctx, []domain.Message{{MessageLocator: msgLocator, Content: json.RawMessage("{}")}}))
 		_, _, ackHandle, err := pubsub.FetchMessages(ctx, sl, 1, domain.Duration{Duration: 100 * time.Millisecond})
 		assert.NoError(t, err)
 		assert.NoError(t, pubsub.AcknowledgeMessages(ctx, ackHandle))
 		_, err = pubsub.IsOldMessages(ctx, sl, []domain.MessageLocator{msgLocator})
 		assert.NoError(t, err)
+		assert.NoError(t, pubsub.NewQueueSubscriber(ctx, sl, nil, domain.Duration{Duration: time.Second}))
 		assert.NoError(t, pubsub.RemoveSubscriber(ctx, sl))
+		_, err = pubsub.ExportChannel(ctx, sl.ChannelID)
+		assert.NoError(t, err)
 	})
 	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage NewSubscriber", map[string]interface{}{
 		"dsps.storage.id":       "test",
@@ -57,12 +60,21 @@ func TestPubSubTrace(t *testing.T) {
 		"messaging.destination": chID,
 		"dsps.subscriber_id":    sbscID,
 	})
+	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage NewQueueSubscriber", map[string]interface{}{
+		"dsps.storage.id":       "test",
+		"messaging.system":      "dsps",
+		"messaging.destination": chID,
+		"dsps.subscriber_id":    sbscID,
+	})
 	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage RemoveSubscriber", map[string]interface{}{
 		"dsps.storage.id":       "test",
 		"messaging.system":      "dsps",
 		"messaging.destination": chID,
 		"dsps.subscriber_id":    sbscID,
 	})
+	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage ExportChannel", map[string]interface{}{
+		"dsps.storage.id": "test",
+	})
 	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage Shutdown", map[string]interface{}{
 		"dsps.storage.id": "test",
 	})
@@ -0,0 +1,13 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+func (ts *tracingStorage) IncrementRateLimitCounter(ctx context.Context, key string, window domain.Duration) (int64, domain.Duration, error) {
+	ctx, end := ts.t.StartStorageSpan(ctx, ts.id, "IncrementRateLimitCounter")
+	defer end()
+	return ts.rateLimit.IncrementRateLimitCounter(ctx, key, window)
+}
@@ -0,0 +1,42 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/m3dev/dsps/server/domain"
+	. "github.com/m3dev/dsps/server/domain/mock"
+	. "github.com/m3dev/dsps/server/storage/deps/testing"
+	. "github.com/m3dev/dsps/server/storage/tracing"
+	"github.com/m3dev/dsps/server/telemetry"
+)
+
+func TestRateLimitTrace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tr := telemetry.WithStubTracing(t, func(telemetry *telemetry.Telemetry) {
+		s := NewMockStorage(ctrl)
+		rateLimit := NewMockRateLimitStorage(ctrl)
+		s.EXPECT().AsPubSubStorage().Return(nil)
+		s.EXPECT().AsJwtStorage().Return(nil)
+		s.EXPECT().AsRateLimitStorage().Return(rateLimit)
+		rateLimit.EXPECT().IncrementRateLimitCounter(gomock.Any(), "counter-key", domain.Duration{Duration: time.Second}).Return(int64(3), domain.Duration{Duration: 500 * time.Millisecond}, nil)
+
+		deps := EmptyDeps(t)
+		deps.Telemetry = telemetry
+		st := NewTracingStorage(s, "test", deps)
+		count, resetIn, err := st.AsRateLimitStorage().IncrementRateLimitCounter(context.Background(), "counter-key", domain.Duration{Duration: time.Second})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.Equal(t, 500*time.Millisecond, resetIn.Duration)
+	})
+	tr.OT.AssertSpanBy(trace.SpanKindInternal, "DSPS storage IncrementRateLimitCounter", map[string]interface{}{
+		"dsps.storage.id": "test",
+	})
+}
@@ -15,6 +15,8 @@ type tracingStorage struct {
 	s      domain.Storage
 	pubsub domain.PubSubStorage
 	jwt    domain.JwtStorage
+
+	rateLimit domain.RateLimitStorage
 }
 
 // NewTracingStorage wraps given Storage to trace calls
@@ -26,6 +28,8 @@ func NewTracingStorage(s domain.Storage, id domain.StorageID, deps deps.StorageD
 		s:      s,
 		pubsub: s.AsPubSubStorage(),
 		jwt:    s.AsJwtStorage(),
+
+		rateLimit: s.AsRateLimitStorage(),
 	}
 }
 
@@ -43,6 +47,13 @@ func (ts *tracingStorage) AsJwtStorage() domain.JwtStorage {
 	return ts
 }
 
+func (ts *tracingStorage) AsRateLimitStorage() domain.RateLimitStorage {
+	if ts.rateLimit == nil {
+		return nil
+	}
+	return ts
+}
+
 func (ts *tracingStorage) String() string {
 	return ts.s.String()
 }
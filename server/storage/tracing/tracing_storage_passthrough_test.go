@@ -20,6 +20,7 @@ func withMockedStorage(t *testing.T, ctrl *gomock.Controller, f func(st domain.S
 		s := NewMockStorage(ctrl)
 		s.EXPECT().AsPubSubStorage().Return(nil).Times(1)
 		s.EXPECT().AsJwtStorage().Return(nil).Times(1)
+		s.EXPECT().AsRateLimitStorage().Return(nil).Times(1)
 
 		deps := EmptyDeps(t)
 		deps.Telemetry = telemetry
@@ -37,6 +38,8 @@ func TestAsMethods(t *testing.T) {
 		assert.Nil(t, st.AsJwtStorage()) // Should cache inner storage result
 		assert.Nil(t, st.AsPubSubStorage())
 		assert.Nil(t, st.AsPubSubStorage())
+		assert.Nil(t, st.AsRateLimitStorage())
+		assert.Nil(t, st.AsRateLimitStorage())
 	})
 }
 
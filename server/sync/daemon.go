@@ -77,7 +77,7 @@ func (ds *DaemonSystem) String() string {
 }
 
 // Start creates new Daemon instance.
-// "name" parameter must be unique in the DaemonSystem.
+// "name" parameter must be unique in the DaemonSystem, name of the daemon aborted by itself can be reused.
 func (ds *DaemonSystem) Start(name string, f DaemonFunc) *Daemon {
 	var d *Daemon
 	func() {
@@ -101,6 +101,15 @@ func (ds *DaemonSystem) Get(name string) *Daemon {
 	return ds.daemons[name]
 }
 
+// remove unregisters self-aborted daemon so that the name can be reused.
+func (ds *DaemonSystem) remove(d *Daemon) {
+	ds.daemonsLock.Lock()
+	defer ds.daemonsLock.Unlock()
+	if ds.daemons[d.name] == d {
+		delete(ds.daemons, d.name)
+	}
+}
+
 // Shutdown closes this system, block until all daemons end.
 func (ds *DaemonSystem) Shutdown(ctx context.Context) error {
 	ds.daemonsLock.Lock()
@@ -158,12 +167,20 @@ func (d *Daemon) String() string {
 func (d *Daemon) start() {
 	d.timer = time.NewTimer(0)
 	go func() {
-		defer close(d.shutdownCompleteCh)
-		defer d.shutdownCtxCloser()
-		for {
-			if !d.cycle() {
-				return
+		aborted := false
+		func() {
+			defer close(d.shutdownCompleteCh)
+			defer d.shutdownCtxCloser()
+			for {
+				if !d.cycle() {
+					aborted = d.shutdownCtx.Err() == nil // Self-shutdown rather than shutdown request
+					return
+				}
 			}
+		}()
+		if aborted {
+			// Must be done after close(shutdownCompleteCh) because DaemonSystem.Shutdown() holds the lock while awaiting daemons.
+			d.system.remove(d)
 		}
 	}()
 }
@@ -210,6 +227,8 @@ func (d *Daemon) cycle() bool {
 				fErr = panicAsError
 			}
 		}()
+		startAt := time.Now()
+		defer func() { d.system.telemetry.ObserveDaemonRun(d.system.name, time.Since(startAt)) }()
 		nextRun, fErr = d.f(fCtx)
 	}()
 	func() {
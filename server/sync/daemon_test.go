@@ -83,6 +83,23 @@ func TestDaemonSystem(t *testing.T) {
 	})
 }
 
+func TestDaemonSelfAbortReleasesName(t *testing.T) {
+	testWithTimeout(t, 1*time.Second, func() {
+		ds := NewDaemonSystem("test", newEmptyDaemonSystemDeps(t), ensureNoDaemonError(t))
+		defer closeDaemon(t, ds)
+
+		for i := 0; i < 2; i++ {
+			d := ds.Start("test.job", func(c context.Context) (DaemonNextRun, error) {
+				return DaemonNextRun{Abort: true}, nil
+			})
+			assert.NoError(t, d.WaitUntilShutdown(context.Background()))
+			for ds.Get("test.job") != nil {
+				time.Sleep(1 * time.Millisecond)
+			}
+		}
+	})
+}
+
 func TestDaemonTelemetry(t *testing.T) {
 	invoked := make(chan interface{})
 	tr := telemetry.WithStubTracing(t, func(telemetry *telemetry.Telemetry) {
@@ -104,6 +121,20 @@ func TestDaemonTelemetry(t *testing.T) {
 	})
 }
 
+func TestDaemonMetrics(t *testing.T) {
+	result := telemetry.WithStubMetrics(t, func(telemetry *telemetry.Telemetry) {
+		deps := newEmptyDaemonSystemDeps(t)
+		deps.Telemetry = telemetry
+		ds := NewDaemonSystem("test.system", deps, func(ctx context.Context, name string, err error) {})
+		d := ds.Start("test.job", func(c context.Context) (DaemonNextRun, error) {
+			return DaemonNextRun{Interval: time.Hour}, nil
+		})
+		assert.NoError(t, d.WaitNextCycle(context.Background(), func() {}))
+		closeDaemon(t, ds)
+	})
+	assert.LessOrEqual(t, 1.0, result.Value("dsps_daemon_run_duration_seconds", map[string]string{"system": "test.system"}))
+}
+
 func TestDaemonDepsError(t *testing.T) {
 	telemetry := telemetry.NewEmptyTelemetry(t)
 	sentry := sentry.NewEmptySentry()
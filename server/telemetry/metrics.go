@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"net/http"
+	"time"
+)
+
+// Outcomes of outgoing-webhook
+const (
+	WebhookOutcomeSuccess      = "success"
+	WebhookOutcomeFailure      = "failure"
+	WebhookOutcomeNonRetryable = "non_retryable"
+	WebhookOutcomeCanceled     = "canceled"
+)
+
+// Events of Redis PSUBSCRIBE dispatcher reconcile cycle
+const (
+	RedisDispatcherReconcileHealthy        = "healthy"
+	RedisDispatcherReconcileConnectionDown = "connection_down"
+	RedisDispatcherReconcileRepaired       = "repaired"
+	RedisDispatcherReconcileRepairFailed   = "repair_failed"
+)
+
+// MetricsHandler returns HTTP handler of the Prometheus metrics endpoint, returns nil if disabled.
+func (t *Telemetry) MetricsHandler() http.Handler {
+	if t.prometheus == nil {
+		return nil
+	}
+	return t.prometheus.Handler()
+}
+
+// CountPublishedMessages increments count of published messages, channelLabel should be low-cardinality value (see domain.Channel.RegexLabel)
+func (t *Telemetry) CountPublishedMessages(channelLabel string, count int) {
+	if t.prometheus == nil {
+		return
+	}
+	t.prometheus.PublishedMessages.WithLabelValues(channelLabel).Add(float64(count))
+}
+
+// ObserveFetch records time spent by message fetch
+func (t *Telemetry) ObserveFetch(channelLabel string, waituntil time.Duration, elapsed time.Duration) {
+	if t.prometheus == nil {
+		return
+	}
+	if waituntil > 0 {
+		t.prometheus.LongPollingWait.WithLabelValues(channelLabel).Observe(elapsed.Seconds())
+	} else {
+		t.prometheus.FetchDuration.WithLabelValues(channelLabel).Observe(elapsed.Seconds())
+	}
+}
+
+// CountAcknowledgement increments count of acknowledgements
+func (t *Telemetry) CountAcknowledgement(channelLabel string) {
+	if t.prometheus == nil {
+		return
+	}
+	t.prometheus.Acknowledgements.WithLabelValues(channelLabel).Inc()
+}
+
+// CountWebhookAttempt increments count of outgoing-webhook HTTP requests
+func (t *Telemetry) CountWebhookAttempt(success bool) {
+	if t.prometheus == nil {
+		return
+	}
+	outcome := WebhookOutcomeFailure
+	if success {
+		outcome = WebhookOutcomeSuccess
+	}
+	t.prometheus.WebhookAttempts.WithLabelValues(outcome).Inc()
+}
+
+// CountWebhookRetry increments count of outgoing-webhook retries
+func (t *Telemetry) CountWebhookRetry() {
+	if t.prometheus == nil {
+		return
+	}
+	t.prometheus.WebhookRetries.Inc()
+}
+
+// CountWebhookDelivery increments count of outgoing-webhook final outcome (WebhookOutcome*)
+func (t *Telemetry) CountWebhookDelivery(outcome string) {
+	if t.prometheus == nil {
+		return
+	}
+	t.prometheus.WebhookDeliveries.WithLabelValues(outcome).Inc()
+}
+
+// CountRedisDispatcherReconcile increments count of reconcile event (RedisDispatcherReconcile*)
+func (t *Telemetry) CountRedisDispatcherReconcile(event string) {
+	if t.prometheus == nil {
+		return
+	}
+	t.prometheus.RedisDispatcherReconciles.WithLabelValues(event).Inc()
+}
+
+// AddRedisDispatcherAwaiters changes count of awaiters by given delta
+func (t *Telemetry) AddRedisDispatcherAwaiters(delta int) {
+	if t.prometheus == nil || delta == 0 {
+		return
+	}
+	t.prometheus.RedisDispatcherAwaiters.Add(float64(delta))
+}
+
+// ObserveDaemonRun records duration of a daemon run.
+// Daemon name is not used as label because it could be high-cardinality value (e.g. outgoing-webhook daemon for each channel).
+func (t *Telemetry) ObserveDaemonRun(systemName string, elapsed time.Duration) {
+	if t.prometheus == nil {
+		return
+	}
+	t.prometheus.DaemonRunDuration.WithLabelValues(systemName).Observe(elapsed.Seconds())
+}
@@ -27,5 +27,12 @@ func newExporters(config *config.OpenTelemetryConfig) ([]exporttrace.SpanExporte
 		}
 		exporters = append(exporters, exporter)
 	}
+	if config.Exporters.OTLP.Enable {
+		exporter, err := newOTLPExporter(&config.Exporters.OTLP)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to initialize OpenTelemetry OTLP trace exporter: %w", err)
+		}
+		exporters = append(exporters, exporter)
+	}
 	return exporters, nil
 }
@@ -0,0 +1,65 @@
+package opentelemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp"
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // Register gzip compressor for OTLP/gRPC
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/tlsconfig"
+)
+
+func newOTLPExporter(config *config.OpenTelemetryExporterOTLPConfig) (exporttrace.SpanExporter, error) {
+	var tlsConfig *tls.Config
+	if !config.Insecure {
+		var err error
+		if tlsConfig, err = tlsconfig.NewClientConfig(config.TLS.CAFile, config.TLS.CertFile, config.TLS.KeyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var exporter exporttrace.SpanExporter
+	switch config.Protocol {
+	case "grpc":
+		opts := []otlp.ExporterOption{
+			otlp.WithAddress(config.Endpoint),
+			otlp.WithHeaders(config.Headers),
+		}
+		if tlsConfig != nil {
+			opts = append(opts, otlp.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		} else {
+			opts = append(opts, otlp.WithInsecure())
+		}
+		if config.Compression != "none" {
+			opts = append(opts, otlp.WithCompressor(config.Compression))
+		}
+		grpcExporter, err := otlp.NewExporter(context.Background(), opts...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = grpcExporter
+	case "http/protobuf":
+		exporter = newOTLPHTTPExporter(config, tlsConfig)
+	default:
+		return nil, fmt.Errorf(`unsupported OTLP protocol "%s"`, config.Protocol)
+	}
+	return &timeoutSpanExporter{SpanExporter: exporter, timeout: config.Timeout.Duration}, nil
+}
+
+// timeoutSpanExporter limits time to spend for each export call
+type timeoutSpanExporter struct {
+	exporttrace.SpanExporter
+	timeout time.Duration
+}
+
+func (e *timeoutSpanExporter) ExportSpans(ctx context.Context, spans []*exporttrace.SpanData) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	return e.SpanExporter.ExportSpans(ctx, spans)
+}
@@ -0,0 +1,291 @@
+package opentelemetry
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/label"
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+)
+
+// otlpReceiverStub captures OTLP export requests
+type otlpReceiverStub struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	lock     sync.Mutex
+	requests []*coltracepb.ExportTraceServiceRequest
+	headers  []map[string]string
+}
+
+func (r *otlpReceiverStub) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	headers := map[string]string{}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			headers[key] = strings.Join(values, ",")
+		}
+	}
+	r.capture(req, headers)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (r *otlpReceiverStub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	headers := map[string]string{"path": req.URL.Path}
+	for key := range req.Header {
+		headers[strings.ToLower(key)] = req.Header.Get(key)
+	}
+
+	body := req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		var err error
+		if body, err = gzip.NewReader(req.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	exportReq := &coltracepb.ExportTraceServiceRequest{}
+	if err := proto.Unmarshal(data, exportReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.capture(exportReq, headers)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *otlpReceiverStub) capture(req *coltracepb.ExportTraceServiceRequest, headers map[string]string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = append(r.requests, req)
+	r.headers = append(r.headers, headers)
+}
+
+func (r *otlpReceiverStub) assertReceived(t *testing.T) (*coltracepb.ExportTraceServiceRequest, map[string]string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !assert.Len(t, r.requests, 1) {
+		return nil, nil
+	}
+	return r.requests[0], r.headers[0]
+}
+
+func otlpExporterConfigDefault(protocol string, endpoint string) config.OpenTelemetryExporterOTLPConfig {
+	return config.OpenTelemetryExporterOTLPConfig{
+		Enable:      true,
+		Protocol:    protocol,
+		Endpoint:    endpoint,
+		URLPath:     "/v1/traces",
+		Headers:     map[string]string{"x-api-key": "secret"},
+		Insecure:    true,
+		Compression: "gzip",
+		Timeout:     &domain.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func emitTestSpan(t *testing.T, otlpConfig config.OpenTelemetryExporterOTLPConfig) {
+	tracingConfig := tracingConfigDefault()
+	tracingConfig.Attributes["global.attr1"] = "value1"
+	ot, err := NewOTFacility(&config.OpenTelemetryConfig{
+		Tracing:   tracingConfig,
+		Exporters: config.OpenTelemetryExportersConfig{OTLP: otlpConfig},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, span := ot.Tracing.Tracer.Start(context.Background(), "test span", trace.WithAttributes(
+		label.String("attr1", "value1"),
+		label.Int64("attr2", 1234),
+	))
+	span.End()
+	assert.NoError(t, ot.Shutdown(context.Background())) // Flush
+}
+
+func assertExportedTestSpan(t *testing.T, req *coltracepb.ExportTraceServiceRequest) {
+	if !assert.Len(t, req.ResourceSpans, 1) {
+		return
+	}
+	rs := req.ResourceSpans[0]
+	assert.Equal(t, "global.attr1", rs.Resource.Attributes[0].Key)
+	assert.Equal(t, "value1", rs.Resource.Attributes[0].Value.GetStringValue())
+	if !assert.Len(t, rs.InstrumentationLibrarySpans, 1) || !assert.Len(t, rs.InstrumentationLibrarySpans[0].Spans, 1) {
+		return
+	}
+	assert.Equal(t, "github.com/m3dev/dsps", rs.InstrumentationLibrarySpans[0].InstrumentationLibrary.Name)
+
+	span := rs.InstrumentationLibrarySpans[0].Spans[0]
+	assert.Equal(t, "test span", span.Name)
+	assert.Equal(t, tracepb.Span_SPAN_KIND_INTERNAL, span.Kind)
+	assert.Len(t, span.TraceId, 16)
+	assert.Len(t, span.SpanId, 8)
+	assert.Empty(t, span.ParentSpanId)
+	assert.EqualValues(t, map[string]interface{}{
+		"attr1": "value1",
+		"attr2": int64(1234),
+	}, otlpKVToMap(span.Attributes))
+}
+
+func otlpKVToMap(kvs []*commonpb.KeyValue) map[string]interface{} {
+	result := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		switch v := kv.Value.Value.(type) {
+		case *commonpb.AnyValue_StringValue:
+			result[kv.Key] = v.StringValue
+		case *commonpb.AnyValue_IntValue:
+			result[kv.Key] = v.IntValue
+		case *commonpb.AnyValue_DoubleValue:
+			result[kv.Key] = v.DoubleValue
+		case *commonpb.AnyValue_BoolValue:
+			result[kv.Key] = v.BoolValue
+		default:
+			result[kv.Key] = kv.Value.String()
+		}
+	}
+	return result
+}
+
+func TestOTLPGRPCExporter(t *testing.T) {
+	receiver := &otlpReceiverStub{}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(server, receiver)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	emitTestSpan(t, otlpExporterConfigDefault(config.OTLPProtocolGRPC, listener.Addr().String()))
+
+	req, headers := receiver.assertReceived(t)
+	if req == nil {
+		return
+	}
+	assertExportedTestSpan(t, req)
+	assert.Equal(t, "secret", headers["x-api-key"])
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	receiver := &otlpReceiverStub{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	emitTestSpan(t, otlpExporterConfigDefault(config.OTLPProtocolHTTPProtobuf, strings.TrimPrefix(server.URL, "http://")))
+
+	req, headers := receiver.assertReceived(t)
+	if req == nil {
+		return
+	}
+	assertExportedTestSpan(t, req)
+	assert.Equal(t, "/v1/traces", headers["path"])
+	assert.Equal(t, "secret", headers["x-api-key"])
+	assert.Equal(t, "application/x-protobuf", headers["content-type"])
+	assert.Equal(t, "gzip", headers["content-encoding"])
+}
+
+func TestOTLPHTTPExporterTLS(t *testing.T) {
+	receiver := &otlpReceiverStub{}
+	server := httptest.NewTLSServer(receiver)
+	defer server.Close()
+
+	caFile, err := ioutil.TempFile("", "dsps-otlp-ca-*.pem")
+	assert.NoError(t, err)
+	defer os.Remove(caFile.Name())
+	assert.NoError(t, pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	assert.NoError(t, caFile.Close())
+
+	otlpConfig := otlpExporterConfigDefault(config.OTLPProtocolHTTPProtobuf, strings.TrimPrefix(server.URL, "https://"))
+	otlpConfig.Insecure = false
+	otlpConfig.TLS.CAFile = caFile.Name()
+	otlpConfig.Compression = "none"
+	emitTestSpan(t, otlpConfig)
+
+	req, headers := receiver.assertReceived(t)
+	if req == nil {
+		return
+	}
+	assertExportedTestSpan(t, req)
+	assert.Equal(t, "", headers["content-encoding"])
+}
+
+func TestOTLPHTTPExporterErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	otlpConfig := otlpExporterConfigDefault(config.OTLPProtocolHTTPProtobuf, strings.TrimPrefix(server.URL, "http://"))
+	exporter, err := newOTLPExporter(&otlpConfig)
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, exporter.Shutdown(context.Background())) }()
+
+	err = exporter.ExportSpans(context.Background(), []*exporttrace.SpanData{{Name: "test span", Resource: resource.Empty()}})
+	assert.Regexp(t, `OTLP collector responded HTTP 503`, err.Error())
+
+	// Nothing to send
+	assert.NoError(t, exporter.ExportSpans(context.Background(), []*exporttrace.SpanData{}))
+}
+
+func TestOTLPExporterTLSConfigError(t *testing.T) {
+	otlpConfig := otlpExporterConfigDefault(config.OTLPProtocolGRPC, "localhost:4317")
+	otlpConfig.Insecure = false
+	otlpConfig.TLS.CAFile = "/not/exists/ca.pem"
+	_, err := newExporters(&config.OpenTelemetryConfig{Exporters: config.OpenTelemetryExportersConfig{OTLP: otlpConfig}})
+	assert.Regexp(t, `failed to initialize OpenTelemetry OTLP trace exporter: failed to read CA file`, err.Error())
+
+	otlpConfig.TLS.CAFile = ""
+	otlpConfig.TLS.CertFile = "/not/exists/cert.pem"
+	otlpConfig.TLS.KeyFile = "/not/exists/key.pem"
+	_, err = newOTLPExporter(&otlpConfig)
+	assert.Regexp(t, `failed to load client certificate`, err.Error())
+}
+
+func TestOTLPValueConversion(t *testing.T) {
+	assert.EqualValues(t, map[string]interface{}{
+		"bool":    true,
+		"int32":   int64(32),
+		"int64":   int64(64),
+		"uint32":  int64(32),
+		"uint64":  int64(64),
+		"float32": float64(float32(3.2)),
+		"float64": 6.4,
+		"string":  "str",
+	}, otlpKVToMap(otlpAttributes([]label.KeyValue{
+		label.Bool("bool", true),
+		label.Int32("int32", 32),
+		label.Int64("int64", 64),
+		label.Uint32("uint32", 32),
+		label.Uint64("uint64", 64),
+		label.Float32("float32", 3.2),
+		label.Float64("float64", 6.4),
+		label.String("string", "str"),
+	})))
+
+	array := otlpValue(label.Array("array", []string{"a", "b"}).Value).GetArrayValue()
+	assert.Equal(t, "a", array.Values[0].GetStringValue())
+	assert.Equal(t, "b", array.Values[1].GetStringValue())
+}
@@ -0,0 +1,103 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/m3dev/dsps/server/config"
+)
+
+// otlpHTTPExporter sends spans with OTLP/HTTP binary protobuf encoding.
+// OpenTelemetry Go SDK in use does not provide HTTP driver, only gRPC one.
+type otlpHTTPExporter struct {
+	client   *http.Client
+	url      string
+	headers  map[string]string
+	compress bool
+}
+
+func newOTLPHTTPExporter(config *config.OpenTelemetryExporterOTLPConfig, tlsConfig *tls.Config) *otlpHTTPExporter {
+	u := url.URL{Scheme: "https", Host: config.Endpoint, Path: config.URLPath}
+	if tlsConfig == nil {
+		u.Scheme = "http"
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &otlpHTTPExporter{
+		client:   &http.Client{Transport: transport},
+		url:      u.String(),
+		headers:  config.Headers,
+		compress: config.Compression == "gzip",
+	}
+}
+
+// ExportSpans implements OT SpanExporter
+func (e *otlpHTTPExporter) ExportSpans(ctx context.Context, spans []*exporttrace.SpanData) error {
+	resourceSpans := otlpResourceSpans(spans)
+	if len(resourceSpans) == 0 {
+		return nil
+	}
+	body, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: resourceSpans})
+	if err != nil {
+		return fmt.Errorf("failed to encode OTLP request: %w", err)
+	}
+	if e.compress {
+		if body, err = gzipBytes(body); err != nil {
+			return fmt.Errorf("failed to compress OTLP request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if e.compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send OTLP request: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode < 200 || 300 <= res.StatusCode {
+		return fmt.Errorf("OTLP collector responded HTTP %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Shutdown implements OT SpanExporter
+func (e *otlpHTTPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,140 @@
+package opentelemetry
+
+import (
+	"reflect"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// otlpResourceSpans converts spans into OTLP messages grouped by resource and instrumentation library.
+func otlpResourceSpans(spans []*exporttrace.SpanData) []*tracepb.ResourceSpans {
+	result := make([]*tracepb.ResourceSpans, 0, 1)
+	resourceSpans := make(map[label.Distinct]*tracepb.ResourceSpans)
+	librarySpans := make(map[label.Distinct]map[string]*tracepb.InstrumentationLibrarySpans)
+	for _, sd := range spans {
+		if sd == nil {
+			continue
+		}
+
+		resourceKey := sd.Resource.Equivalent()
+		rs, ok := resourceSpans[resourceKey]
+		if !ok {
+			rs = &tracepb.ResourceSpans{Resource: otlpResource(sd.Resource)}
+			resourceSpans[resourceKey] = rs
+			librarySpans[resourceKey] = make(map[string]*tracepb.InstrumentationLibrarySpans)
+			result = append(result, rs)
+		}
+
+		libraryKey := sd.InstrumentationLibrary.Name + "@" + sd.InstrumentationLibrary.Version
+		ils, ok := librarySpans[resourceKey][libraryKey]
+		if !ok {
+			ils = &tracepb.InstrumentationLibrarySpans{
+				InstrumentationLibrary: &commonpb.InstrumentationLibrary{
+					Name:    sd.InstrumentationLibrary.Name,
+					Version: sd.InstrumentationLibrary.Version,
+				},
+			}
+			librarySpans[resourceKey][libraryKey] = ils
+			rs.InstrumentationLibrarySpans = append(rs.InstrumentationLibrarySpans, ils)
+		}
+		ils.Spans = append(ils.Spans, otlpSpan(sd))
+	}
+	return result
+}
+
+func otlpResource(r *resource.Resource) *resourcepb.Resource {
+	if r == nil {
+		return nil
+	}
+	return &resourcepb.Resource{Attributes: otlpAttributes(r.Attributes())}
+}
+
+func otlpSpan(sd *exporttrace.SpanData) *tracepb.Span {
+	span := &tracepb.Span{
+		TraceId:                sd.SpanContext.TraceID[:],
+		SpanId:                 sd.SpanContext.SpanID[:],
+		Name:                   sd.Name,
+		Kind:                   tracepb.Span_SpanKind(sd.SpanKind), // Both use same numbering
+		StartTimeUnixNano:      uint64(sd.StartTime.UnixNano()),
+		EndTimeUnixNano:        uint64(sd.EndTime.UnixNano()),
+		Attributes:             otlpAttributes(sd.Attributes),
+		DroppedAttributesCount: uint32(sd.DroppedAttributeCount),
+		DroppedEventsCount:     uint32(sd.DroppedMessageEventCount),
+		DroppedLinksCount:      uint32(sd.DroppedLinkCount),
+		Status:                 &tracepb.Status{Code: otlpStatusCode(sd.StatusCode), Message: sd.StatusMessage},
+	}
+	if sd.ParentSpanID.IsValid() {
+		span.ParentSpanId = sd.ParentSpanID[:]
+	}
+	for _, event := range sd.MessageEvents {
+		span.Events = append(span.Events, &tracepb.Span_Event{
+			TimeUnixNano: uint64(event.Time.UnixNano()),
+			Name:         event.Name,
+			Attributes:   otlpAttributes(event.Attributes),
+		})
+	}
+	for _, link := range sd.Links {
+		span.Links = append(span.Links, &tracepb.Span_Link{
+			TraceId:    link.TraceID[:],
+			SpanId:     link.SpanID[:],
+			Attributes: otlpAttributes(link.Attributes),
+		})
+	}
+	return span
+}
+
+func otlpStatusCode(code codes.Code) tracepb.Status_StatusCode {
+	switch code {
+	case codes.Ok:
+		return tracepb.Status_STATUS_CODE_OK
+	case codes.Error:
+		return tracepb.Status_STATUS_CODE_ERROR
+	default:
+		return tracepb.Status_STATUS_CODE_UNSET
+	}
+}
+
+func otlpAttributes(kvs []label.KeyValue) []*commonpb.KeyValue {
+	if len(kvs) == 0 {
+		return nil
+	}
+	result := make([]*commonpb.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		result = append(result, &commonpb.KeyValue{Key: string(kv.Key), Value: otlpValue(kv.Value)})
+	}
+	return result
+}
+
+func otlpValue(v label.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case label.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case label.INT32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v.AsInt32())}}
+	case label.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case label.UINT32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v.AsUint32())}}
+	case label.UINT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v.AsUint64())}}
+	case label.FLOAT32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v.AsFloat32())}}
+	case label.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case label.ARRAY:
+		array := reflect.ValueOf(v.AsArray())
+		values := make([]*commonpb.AnyValue, 0, array.Len())
+		for i := 0; i < array.Len(); i++ {
+			values = append(values, otlpValue(label.Any("", array.Index(i).Interface()).Value))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
@@ -0,0 +1,164 @@
+package prometheus
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/m3dev/dsps/server/config"
+)
+
+const namespace = "dsps"
+
+// Metrics holds Prometheus collectors of the DSPS server
+type Metrics struct {
+	registry *prometheus.Registry
+
+	PublishedMessages *prometheus.CounterVec
+	FetchDuration     *prometheus.HistogramVec
+	LongPollingWait   *prometheus.HistogramVec
+	Acknowledgements  *prometheus.CounterVec
+
+	WebhookAttempts   *prometheus.CounterVec
+	WebhookDeliveries *prometheus.CounterVec
+	WebhookRetries    prometheus.Counter
+
+	RedisDispatcherReconciles *prometheus.CounterVec
+	RedisDispatcherAwaiters   prometheus.Gauge
+
+	DaemonRunDuration *prometheus.HistogramVec
+}
+
+// NewMetrics creates Prometheus collectors, returns nil if disabled
+func NewMetrics(config *config.PrometheusConfig) *Metrics {
+	if config == nil || !config.Enable {
+		return nil
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		PublishedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "published_messages_total",
+			Help:      "Count of messages published, labeled by regex of the channel configuration.",
+		}, []string{"channel"}),
+		FetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fetch_duration_seconds",
+			Help:      "Latency of message fetch without long-polling wait.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"channel"}),
+		LongPollingWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "long_polling_wait_seconds",
+			Help:      "Time spent by message fetch with long-polling wait.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+		}, []string{"channel"}),
+		Acknowledgements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "acknowledgements_total",
+			Help:      "Count of message acknowledgements.",
+		}, []string{"channel"}),
+
+		WebhookAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_attempts_total",
+			Help:      "Count of outgoing-webhook HTTP requests, including retries.",
+		}, []string{"outcome"}),
+		WebhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Count of outgoing-webhook deliveries by final outcome.",
+		}, []string{"outcome"}),
+		WebhookRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_retries_total",
+			Help:      "Count of outgoing-webhook retries.",
+		}),
+
+		RedisDispatcherReconciles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redis_dispatcher_reconciles_total",
+			Help:      "Count of Redis PSUBSCRIBE dispatcher reconcile cycles by event.",
+		}, []string{"event"}),
+		RedisDispatcherAwaiters: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "redis_dispatcher_awaiters",
+			Help:      "Count of long-polling requests awaiting Redis PUBLISH notification.",
+		}),
+
+		DaemonRunDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "daemon_run_duration_seconds",
+			Help:      "Duration of each background daemon run, labeled by name of the daemon system.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"system"}),
+	}
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+
+		m.PublishedMessages,
+		m.FetchDuration,
+		m.LongPollingWait,
+		m.Acknowledgements,
+
+		m.WebhookAttempts,
+		m.WebhookDeliveries,
+		m.WebhookRetries,
+
+		m.RedisDispatcherReconciles,
+		m.RedisDispatcherAwaiters,
+
+		m.DaemonRunDuration,
+	)
+	return m
+}
+
+// Handler returns HTTP handler to expose metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Value returns current value of the counter or gauge, or sample count of the histogram.
+// Returns false if no such metric recorded. Mainly for testing purpose.
+func (m *Metrics) Value(name string, labels map[string]string) (float64, bool) {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return 0, false
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !matchLabels(metric.GetLabel(), labels) {
+				continue
+			}
+			switch {
+			case metric.Counter != nil:
+				return metric.Counter.GetValue(), true
+			case metric.Gauge != nil:
+				return metric.Gauge.GetValue(), true
+			case metric.Histogram != nil:
+				return float64(metric.Histogram.GetSampleCount()), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func matchLabels(pairs []*dto.LabelPair, labels map[string]string) bool {
+	if len(pairs) != len(labels) {
+		return false
+	}
+	for _, pair := range pairs {
+		if value, ok := labels[pair.GetName()]; !ok || value != pair.GetValue() {
+			return false
+		}
+	}
+	return true
+}
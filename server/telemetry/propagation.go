@@ -0,0 +1,47 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	ottrace "go.opentelemetry.io/otel/trace"
+)
+
+// W3C Trace Context header name, see: https://www.w3.org/TR/trace-context/
+const traceParentHeader = "traceparent"
+
+var traceContextPropagator = propagation.TraceContext{}
+
+// TraceParent returns W3C traceparent of the current span, empty string if not traced.
+// If tracing is disabled, returns traceparent propagated from upstream as is.
+func (t *Telemetry) TraceParent(ctx context.Context) string {
+	sc := ottrace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		sc = ottrace.RemoteSpanContextFromContext(ctx)
+	}
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.TraceFlags&ottrace.FlagsSampled)
+}
+
+// WithTraceParent returns context to continue the trace of the given W3C traceparent rather than the current span.
+// Returns ctx as is if traceparent is empty or malformed.
+func (t *Telemetry) WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	header := http.Header{}
+	header.Set(traceParentHeader, traceParent)
+	sc := ottrace.RemoteSpanContextFromContext(traceContextPropagator.Extract(context.Background(), header))
+	if !sc.IsValid() {
+		return ctx
+	}
+	ctx = ottrace.ContextWithRemoteSpanContext(ctx, sc)
+	return ottrace.ContextWithSpan(ctx, nil) // Detach current span, otherwise it is used as parent.
+}
+
+func (t *Telemetry) injectTraceParent(ctx context.Context, header http.Header) {
+	if traceParent := t.TraceParent(ctx); traceParent != "" {
+		header.Set(traceParentHeader, traceParent)
+	}
+}
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ottrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/m3dev/dsps/server/domain"
+)
+
+const testTraceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+func TestTraceParentWithoutTracing(t *testing.T) {
+	telemetry := NewEmptyTelemetry(t)
+	assert.Equal(t, "", telemetry.TraceParent(context.Background()))
+
+	// Passes through propagated trace context as is
+	ctx := telemetry.WithTraceParent(context.Background(), testTraceParent)
+	assert.Equal(t, testTraceParent, telemetry.TraceParent(ctx))
+
+	// Empty or malformed traceparent
+	assert.Equal(t, "", telemetry.TraceParent(telemetry.WithTraceParent(context.Background(), "")))
+	assert.Equal(t, "", telemetry.TraceParent(telemetry.WithTraceParent(context.Background(), "00-invalid-01")))
+
+	r := httptest.NewRequest("GET", "http://vhost.example.com/outgoing/webhook", strings.NewReader("{}"))
+	_, close := telemetry.StartHTTPSpan(ctx, false, r)
+	close()
+	assert.Equal(t, testTraceParent, r.Header.Get("traceparent"))
+}
+
+func TestHTTPSpanPropagation(t *testing.T) {
+	var clientTraceParent, clientHeader string
+	result := WithStubTracing(t, func(t *Telemetry) {
+		// Server request continues upstream trace
+		r := httptest.NewRequest("PUT", "/channel/ch-1/message/msg-1", strings.NewReader("{}"))
+		r.Header.Set("traceparent", testTraceParent)
+		ctx, close := t.StartHTTPSpan(context.Background(), true, r)
+
+		// Client request propagates current span
+		r = httptest.NewRequest("GET", "http://vhost.example.com/outgoing/webhook", strings.NewReader("{}"))
+		clientCtx, clientClose := t.StartHTTPSpan(ctx, false, r)
+		clientTraceParent = t.TraceParent(clientCtx)
+		clientClose()
+		close()
+		clientHeader = r.Header.Get("traceparent")
+	})
+
+	client := result.OT.GetSpans()[0]
+	server := result.OT.GetSpans()[1]
+	assert.Equal(t, ottrace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", server.SpanContext.TraceID.String())
+	assert.Equal(t, "b7ad6b7169203331", server.ParentSpanID.String())
+	assert.True(t, server.HasRemoteParent)
+
+	assert.Equal(t, ottrace.SpanKindClient, client.SpanKind)
+	assert.Equal(t, server.SpanContext.SpanID, client.ParentSpanID)
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", client.SpanContext.TraceID, client.SpanContext.SpanID), clientTraceParent)
+	assert.Equal(t, clientTraceParent, clientHeader)
+}
+
+func TestWithTraceParent(t *testing.T) {
+	result := WithStubTracing(t, func(t *Telemetry) {
+		ctx, close := t.StartDaemonSpan(context.Background(), "test", "daemon")
+		defer close()
+
+		// Continues the given trace rather than current span
+		_, msgClose := t.StartMessageSpan(t.WithTraceParent(ctx, testTraceParent), Acknowledge, domain.Message{})
+		msgClose()
+
+		// Keeps current span if traceparent is not available
+		_, noTraceClose := t.StartMessageSpan(t.WithTraceParent(ctx, ""), Acknowledge, domain.Message{})
+		noTraceClose()
+	})
+
+	msgSpan := result.OT.GetSpans()[0]
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", msgSpan.SpanContext.TraceID.String())
+	assert.Equal(t, "b7ad6b7169203331", msgSpan.ParentSpanID.String())
+	assert.True(t, msgSpan.HasRemoteParent)
+
+	noTraceSpan := result.OT.GetSpans()[1]
+	assert.NotEqual(t, "0af7651916cd43dd8448eb211c80319c", noTraceSpan.SpanContext.TraceID.String())
+	assert.False(t, noTraceSpan.HasRemoteParent)
+	assert.True(t, noTraceSpan.ParentSpanID.IsValid())
+}
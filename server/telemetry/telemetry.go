@@ -6,11 +6,13 @@ import (
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/logger"
 	"github.com/m3dev/dsps/server/telemetry/opentelemetry"
+	"github.com/m3dev/dsps/server/telemetry/prometheus"
 )
 
 // Telemetry represents tracing/metrics system
 type Telemetry struct {
-	ot *opentelemetry.OTFacility
+	ot         *opentelemetry.OTFacility
+	prometheus *prometheus.Metrics // nil if disabled
 }
 
 // InitTelemetry initialize telemetry facility
@@ -19,6 +21,7 @@ func InitTelemetry(config *config.TelemetryConfig) (telemetry *Telemetry, err er
 	if telemetry.ot, err = opentelemetry.NewOTFacility(config.OT); err != nil {
 		return
 	}
+	telemetry.prometheus = prometheus.NewMetrics(config.Prometheus)
 	return
 }
 
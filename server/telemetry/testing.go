@@ -9,6 +9,7 @@ import (
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/telemetry/opentelemetry"
 	ottesting "github.com/m3dev/dsps/server/telemetry/opentelemetry/testing"
+	"github.com/m3dev/dsps/server/telemetry/prometheus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,3 +45,23 @@ func WithStubTracing(t *testing.T, f func(*Telemetry)) *TraceResult {
 	f(telemetry)
 	return tr
 }
+
+// MetricsResult contains stub metrics result.
+type MetricsResult struct {
+	m *prometheus.Metrics
+}
+
+// WithStubMetrics is testing utility to create Telemetry facility with Prometheus metrics.
+func WithStubMetrics(t *testing.T, f func(*Telemetry)) *MetricsResult {
+	telemetry := NewEmptyTelemetry(t)
+	telemetry.prometheus = prometheus.NewMetrics(&config.PrometheusConfig{Enable: true, Path: "/metrics"})
+	defer telemetry.Shutdown(context.Background())
+	f(telemetry)
+	return &MetricsResult{m: telemetry.prometheus}
+}
+
+// Value returns current value of the metric, or sample count of the histogram.
+func (r *MetricsResult) Value(name string, labels map[string]string) float64 {
+	value, _ := r.m.Value(name, labels)
+	return value
+}
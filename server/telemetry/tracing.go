@@ -12,7 +12,8 @@ import (
 	"github.com/m3dev/dsps/server/logger"
 )
 
-// StartHTTPSpan starts tracing span for incoming or outgoing HTTP call
+// StartHTTPSpan starts tracing span for incoming or outgoing HTTP call.
+// Continues W3C trace context of incoming request, injects it to outgoing request.
 func (t *Telemetry) StartHTTPSpan(ctx context.Context, isServer bool, r *http.Request) (context.Context, context.CancelFunc) {
 	var spanKind ottrace.SpanKind
 	var scheme string
@@ -23,13 +24,14 @@ func (t *Telemetry) StartHTTPSpan(ctx context.Context, isServer bool, r *http.Re
 		scheme = "http"                 // Currently DSPS server only support HTTP
 		requestURIlabel = "http.target" // For server request, RequestURI is domain relative URI
 		requestURI = r.RequestURI
+		ctx = traceContextPropagator.Extract(ctx, r.Header)
 	} else {
 		spanKind = ottrace.SpanKindClient // e.g. Outgoing webhook from DSPS to anywhere
 		scheme = r.URL.Scheme
 		requestURIlabel = "http.url"
 		requestURI = r.URL.String()
 	}
-	return t.startSpan(
+	ctx, end := t.startSpan(
 		// > Therefore, HTTP client spans SHOULD be using conservative, low cardinality names formed from the available parameters of an HTTP request, such as "HTTP {METHOD_NAME}".
 		// https://github.com/open-telemetry/opentelemetry-specification/blob/master/specification/trace/semantic_conventions/http.md
 		ctx, fmt.Sprintf("HTTP %s %s", r.Method, r.Host),
@@ -44,6 +46,10 @@ func (t *Telemetry) StartHTTPSpan(ctx context.Context, isServer bool, r *http.Re
 			label.Int64("http.request_content_length", r.ContentLength),
 		),
 	)
+	if !isServer {
+		t.injectTraceParent(ctx, r.Header)
+	}
+	return ctx, end
 }
 
 // SetHTTPServerAttributes adds attributes for incoming HTTP request
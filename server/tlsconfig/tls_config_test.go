@@ -0,0 +1,76 @@
+package tlsconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/m3dev/dsps/server/tlsconfig"
+)
+
+func TestNewClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCertificate(t, dir, "cert")
+
+	tlsConfig, err := NewClientConfig("", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Nil(t, tlsConfig.RootCAs)
+	assert.Empty(t, tlsConfig.Certificates)
+
+	tlsConfig, err = NewClientConfig(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "cert.pem"), filepath.Join(dir, "cert-key.pem"))
+	assert.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+
+	_, err = NewClientConfig(filepath.Join(dir, "cert-key.pem"), "", "")
+	assert.Regexp(t, `no valid certificate found in CA file`, err.Error())
+	_, err = NewClientConfig("", filepath.Join(dir, "cert.pem"), filepath.Join(dir, "cert.pem"))
+	assert.Regexp(t, `failed to load client certificate`, err.Error())
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCertificate(t, dir, "cert")
+
+	pool, err := LoadCertPool(filepath.Join(dir, "cert.pem"))
+	assert.NoError(t, err)
+	assert.NotNil(t, pool)
+
+	_, err = LoadCertPool(filepath.Join(dir, "no-such-file.pem"))
+	assert.Regexp(t, `failed to read CA file`, err.Error())
+	_, err = LoadCertPool(filepath.Join(dir, "cert-key.pem"))
+	assert.Regexp(t, `no valid certificate found in CA file`, err.Error())
+}
+
+// writeTestCertificate writes self-signed certificate "{name}.pem" and its key "{name}-key.pem"
+func writeTestCertificate(t *testing.T, dir string, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(1 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+}
@@ -0,0 +1,68 @@
+package delivery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/webhook/outgoing"
+)
+
+// DeadLetterContent is content of the message published to the dead-letter channel.
+// See server/doc/outgoing-webhook.md for spec.
+type DeadLetterContent struct {
+	Type string `json:"type"`
+
+	ChannelID domain.ChannelID `json:"channelID"`
+	MessageID domain.MessageID `json:"messageID"`
+	Content   json.RawMessage  `json:"content"`
+
+	Attributes domain.MessageAttributes `json:"attributes,omitempty"`
+
+	Webhook    string `json:"webhook"`
+	StatusCode *int   `json:"statusCode"` // nil if no response received
+	Error      string `json:"error"`
+}
+
+// NewDeadLetter makes message to publish to the dead-letter channel.
+// MessageID is derived from the original message and the webhook, thus publishing same dead-letter twice is deduplicated by the storage.
+func NewDeadLetter(channelID domain.ChannelID, webhook domain.OutgoingWebhook, msg domain.Message, sendErr error) (domain.Message, error) {
+	content := DeadLetterContent{
+		Type: "dsps.channel.outgoing-webhook.dead-letter",
+
+		ChannelID: msg.ChannelID,
+		MessageID: msg.MessageID,
+		Content:   msg.Content,
+
+		Attributes: msg.Attributes,
+
+		Webhook: webhook.String(),
+		Error:   sendErr.Error(),
+	}
+	if statusCode := outgoing.StatusCodeOf(sendErr); statusCode != 0 {
+		content.StatusCode = &statusCode
+	}
+	if !json.Valid(content.Content) {
+		// Corrupted content, keep it as JSON string to publish dead-letter anyway.
+		encoded, _ := json.Marshal(string(msg.Content))
+		content.Content = encoded
+	}
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return domain.Message{}, xerrors.Errorf("failed to encode dead-letter message: %w", err)
+	}
+
+	hash := sha256.Sum256([]byte(string(msg.ChannelID) + "\n" + string(msg.MessageID) + "\n" + webhook.String()))
+	return domain.Message{
+		MessageLocator: domain.MessageLocator{
+			ChannelID: channelID,
+			MessageID: domain.MessageID("dead-letter-" + hex.EncodeToString(hash[:16])),
+		},
+		Content:     encoded,
+		Attributes:  msg.Attributes,
+		TraceParent: msg.TraceParent,
+	}, nil
+}
@@ -0,0 +1,396 @@
+package delivery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/logger"
+	"github.com/m3dev/dsps/server/sentry"
+	dspssync "github.com/m3dev/dsps/server/sync"
+	"github.com/m3dev/dsps/server/telemetry"
+	"github.com/m3dev/dsps/server/webhook/outgoing"
+)
+
+// Max duration of each FetchMessages call (long-polling) of the delivery routine.
+var pollingTimeout = 30 * time.Second
+
+// Interval to retry delivery after failure.
+var retryInterval = 5 * time.Second
+
+// Visibility timeout of internal subscribers, must be longer enough than a webhook call including its retries.
+// Other servers do not receive the message while this server leases it.
+var leaseTimeout = 5 * time.Minute
+
+// Interval to find internal subscribers with remaining messages, see resume().
+var resumeInterval = 1 * time.Minute
+
+// Prefix of WebhookSubscriberID
+const webhookSubscriberIDPrefix = "_webhook-"
+
+// Prefix of the counter key of delivery lease, never conflicts with rate limit rule IDs (hex string).
+const deliveryLeaseKeyPrefix = "_webhook-lease"
+
+// Queue delivers outgoing-webhooks asynchronously.
+//
+// Each webhook of a channel has an internal subscriber in the PubSubStorage.
+// Background routine fetches messages from the subscriber, sends them to the webhook, then acknowledges them.
+// Thus outgoing-webhook has the same "no misfire" guarantee as polling subscribers.
+//
+// Internal subscribers are queue subscribers if the storage supports it, so that only one of servers sends each message.
+// Otherwise servers take turns to deliver with a lease (a counter in RateLimitStorage shared among servers),
+// only the lease holder fetches and sends messages of the internal subscriber.
+// If the storage supports neither, the storage is not shared among servers (e.g. onmemory, bolt) thus this server is the only sender.
+//
+// Each server also periodically resumes delivery of internal subscribers with remaining messages,
+// e.g. messages left by restarted servers or published while delivery routine was idle.
+type Queue interface {
+	// Prepare creates internal subscribers of the channel's webhooks and starts delivery routines.
+	// Must be called before publishing messages to the channel, otherwise webhooks may miss the messages.
+	Prepare(ctx context.Context, channelID domain.ChannelID) error
+
+	// StartResuming starts background routine that periodically resumes delivery of internal subscribers with remaining messages.
+	StartResuming()
+
+	// Shutdown stops delivery routines, block until in-flight deliveries end.
+	Shutdown(ctx context.Context) error
+}
+
+// Deps contains objects required by Queue
+type Deps struct {
+	Telemetry *telemetry.Telemetry
+	Sentry    sentry.Sentry
+}
+
+type queue struct {
+	pubsub          domain.PubSubStorage
+	rateLimit       domain.RateLimitStorage
+	channelProvider domain.ChannelProvider
+	clock           domain.SystemClock
+
+	daemonSystem *dspssync.DaemonSystem
+
+	lock    sync.Mutex
+	targets map[domain.SubscriberLocator]*target
+	seq     uint64
+}
+
+// A pair of channel and webhook, corresponds to an internal subscriber.
+type target struct {
+	sl domain.SubscriberLocator
+
+	// Last time Prepare() called, guarded by queue.lock
+	lastPrepared time.Time
+
+	// True if the internal subscriber is not a queue subscriber and the storage is shared among servers.
+	// Only the holder of the lease delivers messages, see acquireLease().
+	leaseRequired bool
+	// Expiry of the lease held by this server, only accessed by the delivery routine.
+	leaseUntil time.Time
+
+	// Message leased but not delivered yet, retried without fetching again until the lease expires.
+	// Only accessed by the delivery routine.
+	pending *pendingDelivery
+}
+
+type pendingDelivery struct {
+	msg       domain.Message
+	ackHandle domain.AckHandle
+	leasedAt  time.Time
+}
+
+// NewQueue creates Queue instance
+func NewQueue(storage domain.Storage, channelProvider domain.ChannelProvider, clock domain.SystemClock, deps Deps) Queue {
+	return &queue{
+		pubsub:          storage.AsPubSubStorage(),
+		rateLimit:       storage.AsRateLimitStorage(),
+		channelProvider: channelProvider,
+		clock:           clock,
+
+		daemonSystem: dspssync.NewDaemonSystem("dsps.outgoing-webhook", dspssync.DaemonSystemDeps{
+			Telemetry: deps.Telemetry,
+			Sentry:    deps.Sentry,
+		}, func(ctx context.Context, name string, err error) {
+			logger.Of(ctx).Error(fmt.Sprintf(`error in outgoing-webhook delivery routine "%s"`, name), err)
+		}),
+
+		targets: make(map[domain.SubscriberLocator]*target, 64),
+	}
+}
+
+// WebhookSubscriberID returns ID of the internal subscriber for the webhook.
+// Returned ID never conflicts with user-created subscribers because it does not satisfy ParseSubscriberID().
+func WebhookSubscriberID(webhook domain.OutgoingWebhook) domain.SubscriberID {
+	hash := sha256.Sum256([]byte(webhook.String()))
+	return domain.SubscriberID(webhookSubscriberIDPrefix + hex.EncodeToString(hash[:8]))
+}
+
+func (q *queue) Prepare(ctx context.Context, channelID domain.ChannelID) error {
+	ch, err := q.channelProvider.Get(channelID)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range ch.OutgoingWebhooks() {
+		if q.pubsub == nil {
+			return xerrors.Errorf("outgoing-webhook requires PubSub compatible storage")
+		}
+
+		sl := domain.SubscriberLocator{ChannelID: channelID, SubscriberID: WebhookSubscriberID(webhook)}
+		if q.touch(sl) {
+			continue // Delivery routine already running
+		}
+		isQueue, err := q.newSubscriber(ctx, sl)
+		if err != nil {
+			return xerrors.Errorf(`failed to create internal subscriber of outgoing-webhook (%s): %w`, webhook, err)
+		}
+		q.start(sl, isQueue)
+	}
+	return nil
+}
+
+// newSubscriber creates queue subscriber so that servers do not send the same message concurrently, returns true if created a queue subscriber.
+// Falls back to normal subscriber if the storage does not support queue subscriber.
+func (q *queue) newSubscriber(ctx context.Context, sl domain.SubscriberLocator) (bool, error) {
+	err := q.pubsub.NewQueueSubscriber(ctx, sl, nil, domain.Duration{Duration: leaseTimeout})
+	if errors.Is(err, domain.ErrQueueSubscriberUnsupported) {
+		return false, q.pubsub.NewSubscriber(ctx, sl, nil)
+	}
+	return err == nil, err
+}
+
+func (q *queue) StartResuming() {
+	if q.pubsub == nil {
+		return
+	}
+	q.daemonSystem.Start("resume", func(ctx context.Context) (dspssync.DaemonNextRun, error) {
+		return dspssync.DaemonNextRun{Interval: resumeInterval}, q.resume(ctx)
+	})
+}
+
+// resume starts delivery routines of internal subscribers that have remaining messages in the storage.
+func (q *queue) resume(ctx context.Context) error {
+	channels, err := q.pubsub.InspectChannels(ctx, "")
+	if err != nil {
+		return xerrors.Errorf("failed to find internal subscribers of outgoing-webhook: %w", err)
+	}
+	for _, ch := range channels {
+		for _, sbsc := range ch.Subscribers {
+			if !strings.HasPrefix(string(sbsc.SubscriberID), webhookSubscriberIDPrefix) || sbsc.Backlog == 0 {
+				continue
+			}
+			sl := domain.SubscriberLocator{ChannelID: ch.ChannelID, SubscriberID: sbsc.SubscriberID}
+			if _, webhook, err := q.findWebhook(sl); err != nil || webhook == nil {
+				continue // Channel or webhook has been removed from configuration
+			}
+			if !q.touch(sl) {
+				logger.Of(ctx).Debugf(logger.CatOutgoingWebhook, "Resuming outgoing-webhook delivery of %v", sl)
+				q.start(sl, sbsc.VisibilityTimeout != nil)
+			}
+		}
+	}
+	return nil
+}
+
+// Returns true if delivery routine of the target is running.
+func (q *queue) touch(sl domain.SubscriberLocator) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if t, ok := q.targets[sl]; ok {
+		t.lastPrepared = q.clock.Now().Time
+		return true
+	}
+	return false
+}
+
+func (q *queue) start(sl domain.SubscriberLocator, isQueue bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if t, ok := q.targets[sl]; ok {
+		t.lastPrepared = q.clock.Now().Time
+		return // Started by concurrent Prepare() call
+	}
+
+	t := &target{sl: sl, lastPrepared: q.clock.Now().Time, leaseRequired: !isQueue && q.rateLimit != nil}
+	q.targets[sl] = t
+	// Daemon could be still running on the same target for a moment after unregister, thus use unique name.
+	q.seq++
+	q.daemonSystem.Start(fmt.Sprintf("%s/%s#%d", sl.ChannelID, sl.SubscriberID, q.seq), func(ctx context.Context) (dspssync.DaemonNextRun, error) {
+		return q.deliver(ctx, t)
+	})
+}
+
+func (q *queue) unregister(t *target) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.targets[t.sl] == t {
+		delete(q.targets, t.sl)
+	}
+}
+
+// Unregister the target if no Prepare() call within the expire duration, returns true if unregistered.
+func (q *queue) unregisterIfIdle(t *target, expire domain.Duration) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.clock.Now().Sub(t.lastPrepared) < expire.Duration {
+		return false
+	}
+	if q.targets[t.sl] == t {
+		delete(q.targets, t.sl)
+	}
+	return true
+}
+
+func (q *queue) findWebhook(sl domain.SubscriberLocator) (domain.Channel, domain.OutgoingWebhook, error) {
+	ch, err := q.channelProvider.Get(sl.ChannelID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, webhook := range ch.OutgoingWebhooks() {
+		if WebhookSubscriberID(webhook) == sl.SubscriberID {
+			return ch, webhook, nil
+		}
+	}
+	return ch, nil, nil
+}
+
+func (q *queue) deliver(ctx context.Context, t *target) (dspssync.DaemonNextRun, error) {
+	ctx = logger.WithAttributes(ctx).WithStr("channelID", string(t.sl.ChannelID)).WithStr("subscriberID", string(t.sl.SubscriberID)).Build()
+
+	ch, webhook, err := q.findWebhook(t.sl)
+	if err != nil && !errors.Is(err, domain.ErrInvalidChannel) {
+		return dspssync.DaemonNextRun{Interval: retryInterval}, err
+	}
+	if webhook == nil {
+		// Channel or webhook has been removed from configuration.
+		logger.Of(ctx).Infof(logger.CatOutgoingWebhook, "Stopped outgoing-webhook delivery because the webhook is no longer configured")
+		q.unregister(t)
+		return dspssync.DaemonNextRun{Abort: true}, nil
+	}
+
+	if t.leaseRequired {
+		wait, err := q.acquireLease(ctx, t)
+		if err != nil {
+			return dspssync.DaemonNextRun{Interval: retryInterval}, err
+		}
+		if wait > 0 {
+			if q.unregisterIfIdle(t, ch.Expire()) {
+				logger.Of(ctx).Debugf(logger.CatOutgoingWebhook, "Stopped idle outgoing-webhook delivery")
+				return dspssync.DaemonNextRun{Abort: true}, nil
+			}
+			return dspssync.DaemonNextRun{Interval: wait}, nil
+		}
+	}
+
+	if t.pending != nil {
+		pending := t.pending
+		t.pending = nil
+		if q.clock.Now().Sub(pending.leasedAt) < leaseTimeout/2 {
+			return q.send(ctx, t, webhook, pending)
+		}
+		// Lease could expire during the retry, fetch again
+	}
+
+	msgs, _, ackHandle, err := q.pubsub.FetchMessages(ctx, t.sl, 1, domain.Duration{Duration: pollingTimeout})
+	if err != nil {
+		if errors.Is(err, domain.ErrSubscriptionNotFound) || errors.Is(err, domain.ErrInvalidChannel) {
+			// Next Prepare() call recreates the subscriber.
+			logger.Of(ctx).WarnError(logger.CatOutgoingWebhook, "Stopped outgoing-webhook delivery because internal subscriber not found", err)
+			q.unregister(t)
+			return dspssync.DaemonNextRun{Abort: true}, nil
+		}
+		return dspssync.DaemonNextRun{Interval: retryInterval}, err
+	}
+	if len(msgs) == 0 {
+		if q.unregisterIfIdle(t, ch.Expire()) {
+			logger.Of(ctx).Debugf(logger.CatOutgoingWebhook, "Stopped idle outgoing-webhook delivery")
+			return dspssync.DaemonNextRun{Abort: true}, nil
+		}
+		return dspssync.DaemonNextRun{Interval: 0}, nil
+	}
+
+	return q.send(ctx, t, webhook, &pendingDelivery{msg: msgs[0], ackHandle: ackHandle, leasedAt: q.clock.Now().Time})
+}
+
+// acquireLease returns 0 if this server holds the lease of the target long enough to fetch and send a message,
+// otherwise returns the duration to wait until the current lease expires.
+func (q *queue) acquireLease(ctx context.Context, t *target) (time.Duration, error) {
+	now := q.clock.Now().Time
+	if remaining := t.leaseUntil.Sub(now); remaining > 0 {
+		if remaining >= leaseTimeout/2 {
+			return 0, nil
+		}
+		// Lease could expire during the delivery, wait for the expiry then compete with other servers again.
+		return remaining, nil
+	}
+
+	// The first server to increment the counter in the window holds the lease until the window ends.
+	key := fmt.Sprintf("%s.%s.%s", deliveryLeaseKeyPrefix, t.sl.ChannelID, t.sl.SubscriberID)
+	count, resetIn, err := q.rateLimit.IncrementRateLimitCounter(ctx, key, domain.Duration{Duration: leaseTimeout})
+	if err != nil {
+		return 0, xerrors.Errorf("failed to acquire lease of outgoing-webhook delivery: %w", err)
+	}
+	if count != 1 {
+		return resetIn.Duration, nil // Other server holds the lease
+	}
+	t.leaseUntil = now.Add(resetIn.Duration)
+	return 0, nil
+}
+
+func (q *queue) send(ctx context.Context, t *target, webhook domain.OutgoingWebhook, d *pendingDelivery) (dspssync.DaemonNextRun, error) {
+	if err := webhook.Send(ctx, d.msg); err != nil {
+		if ctx.Err() != nil {
+			return dspssync.DaemonNextRun{Interval: retryInterval}, nil // Shutting down, resend later (by any server after the lease expired).
+		}
+		if ack, err := q.handleFailure(ctx, webhook, d.msg, err); !ack {
+			t.pending = d
+			return dspssync.DaemonNextRun{Interval: retryInterval}, err
+		}
+	}
+	if err := q.pubsub.AcknowledgeMessages(ctx, d.ackHandle); err != nil {
+		return dspssync.DaemonNextRun{Interval: retryInterval}, xerrors.Errorf("failed to acknowledge delivered outgoing-webhook messages: %w", err)
+	}
+	return dspssync.DaemonNextRun{Interval: 0}, nil
+}
+
+// Returns true if the message should be acknowledged (never resend).
+func (q *queue) handleFailure(ctx context.Context, webhook domain.OutgoingWebhook, msg domain.Message, sendErr error) (bool, error) {
+	// Do not send to dead-letter channel if the message came from the dead-letter channel itself, otherwise it loops forever.
+	if dlc := webhook.DeadLetterChannel(); dlc != nil && *dlc != msg.ChannelID {
+		if err := q.publishDeadLetter(ctx, *dlc, webhook, msg, sendErr); err != nil {
+			return false, xerrors.Errorf(`failed to publish failed outgoing-webhook (channel: %s, msgID: %s) to dead-letter channel "%s": %w`, msg.ChannelID, msg.MessageID, *dlc, err)
+		}
+		logger.Of(ctx).WarnError(logger.CatOutgoingWebhook, fmt.Sprintf(`outgoing-webhook (channel: %s, msgID: %s) failed, published to dead-letter channel "%s"`, msg.ChannelID, msg.MessageID, *dlc), sendErr)
+		return true, nil
+	}
+	if outgoing.IsNonRetryableError(sendErr) {
+		logger.Of(ctx).WarnError(logger.CatOutgoingWebhook, fmt.Sprintf(`outgoing-webhook (channel: %s, msgID: %s) failed permanently, discarded the message`, msg.ChannelID, msg.MessageID), sendErr)
+		return true, nil
+	}
+	// Do not acknowledge, resend later.
+	logger.Of(ctx).Infof(logger.CatOutgoingWebhook, "outgoing-webhook (channel: %s, msgID: %s) failed, retrying after %v: %v", msg.ChannelID, msg.MessageID, retryInterval, sendErr)
+	return false, nil
+}
+
+func (q *queue) publishDeadLetter(ctx context.Context, channelID domain.ChannelID, webhook domain.OutgoingWebhook, msg domain.Message, sendErr error) error {
+	dl, err := NewDeadLetter(channelID, webhook, msg, sendErr)
+	if err != nil {
+		return err
+	}
+	// Dead-letter channel may have its own webhooks.
+	if err := q.Prepare(ctx, channelID); err != nil {
+		return err
+	}
+	return q.pubsub.PublishMessages(ctx, []domain.Message{dl})
+}
+
+func (q *queue) Shutdown(ctx context.Context) error {
+	return q.daemonSystem.Shutdown(ctx)
+}
@@ -0,0 +1,473 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/m3dev/dsps/server/config"
+	"github.com/m3dev/dsps/server/domain"
+	"github.com/m3dev/dsps/server/domain/channel"
+	"github.com/m3dev/dsps/server/sentry"
+	. "github.com/m3dev/dsps/server/storage/deps/testing"
+	"github.com/m3dev/dsps/server/storage/onmemory"
+	"github.com/m3dev/dsps/server/storage/redis"
+	"github.com/m3dev/dsps/server/telemetry"
+)
+
+type webhookReceiver struct {
+	lock     sync.Mutex
+	received []string // messageIDs
+	status   func(messageID string) int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	var decoded struct {
+		MessageID string `json:"messageID"`
+	}
+	_ = json.Unmarshal(body, &decoded)
+
+	r.lock.Lock()
+	r.received = append(r.received, decoded.MessageID)
+	status := r.status(decoded.MessageID)
+	r.lock.Unlock()
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) Received() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.received...)
+}
+
+func withQueue(t *testing.T, expire string, receiver *webhookReceiver, f func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider)) {
+	withQueueOn(t, expire, receiver, func(ctx context.Context, cp domain.ChannelProvider) (domain.Storage, error) {
+		return onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, domain.RealSystemClock, cp, EmptyDeps(t))
+	}, f)
+}
+
+// Same as withQueue but uses given storage
+func withQueueOn(t *testing.T, expire string, receiver *webhookReceiver, storageOf func(ctx context.Context, cp domain.ChannelProvider) (domain.Storage, error), f func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider)) {
+	defer func(p, r time.Duration) {
+		pollingTimeout = p
+		retryInterval = r
+	}(pollingTimeout, retryInterval)
+	pollingTimeout = 50 * time.Millisecond
+	retryInterval = 10 * time.Millisecond
+
+	ctx := context.Background()
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	cfg, err := config.ParseConfig(ctx, config.Overrides{}, strings.ReplaceAll(`
+channels:
+	-
+		regex: 'with-webhook-.+'
+		expire: `+expire+`
+		webhooks:
+			- url: '`+server.URL+`/webhook'
+				retry: { count: 1, interval: 2ms, intervalJitter: 1ms }
+	-
+		regex: 'with-dlc-(?P<id>.+)'
+		expire: `+expire+`
+		webhooks:
+			- url: '`+server.URL+`/webhook'
+				retry: { count: 1, interval: 2ms, intervalJitter: 1ms }
+				deadLetterChannel: 'dlc-{{.channel.id}}'
+	-
+		regex: 'dlc-.+'
+	-
+		regex: 'no-webhook-.+'
+	`, "\t", "  "))
+	if !assert.NoError(t, err) {
+		return
+	}
+	tel := telemetry.NewEmptyTelemetry(t)
+	cp, err := channel.NewChannelProvider(ctx, &cfg, channel.ProviderDeps{
+		Clock:     domain.RealSystemClock,
+		Telemetry: tel,
+		Sentry:    sentry.NewEmptySentry(),
+	})
+	assert.NoError(t, err)
+	storage, err := storageOf(ctx, cp)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, storage.Shutdown(ctx)) }()
+
+	q := NewQueue(storage, cp, domain.RealSystemClock, Deps{
+		Telemetry: tel,
+		Sentry:    sentry.NewEmptySentry(),
+	}).(*queue)
+	defer func() { assert.NoError(t, q.Shutdown(ctx)) }()
+	f(q, storage.AsPubSubStorage(), cp)
+}
+
+func publish(t *testing.T, pubsub domain.PubSubStorage, channelID domain.ChannelID, messageIDs ...domain.MessageID) {
+	msgs := make([]domain.Message, len(messageIDs))
+	for i, id := range messageIDs {
+		msgs[i] = domain.Message{
+			MessageLocator: domain.MessageLocator{ChannelID: channelID, MessageID: id},
+			Content:        json.RawMessage(`{}`),
+		}
+	}
+	assert.NoError(t, pubsub.PublishMessages(context.Background(), msgs))
+}
+
+func webhookLocatorOf(t *testing.T, cp domain.ChannelProvider, channelID domain.ChannelID) domain.SubscriberLocator {
+	ch, err := cp.Get(channelID)
+	assert.NoError(t, err)
+	return domain.SubscriberLocator{ChannelID: channelID, SubscriberID: WebhookSubscriberID(ch.OutgoingWebhooks()[0])}
+}
+
+func TestDeliverySuccess(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(string) int { return 204 }}
+	withQueue(t, "5m", receiver, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1"))
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1")) // Should be idempotent
+		publish(t, pubsub, "with-webhook-1", "msg-1", "msg-2")
+
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 2 }, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-1", "msg-2"}, receiver.Received())
+
+		// Delivered messages should be acknowledged
+		assert.Eventually(t, func() bool {
+			msgs, _, _, err := pubsub.FetchMessages(ctx, webhookLocatorOf(t, cp, "with-webhook-1"), 10, domain.Duration{})
+			return err == nil && len(msgs) == 0
+		}, 3*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestDeliveryRetry(t *testing.T) {
+	ctx := context.Background()
+	attempts := 0
+	receiver := &webhookReceiver{status: func(string) int {
+		attempts++
+		if attempts <= 2 {
+			return 500
+		}
+		return 200
+	}}
+	withQueue(t, "5m", receiver, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1"))
+		publish(t, pubsub, "with-webhook-1", "msg-1", "msg-2")
+
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 4 }, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-1", "msg-1", "msg-1", "msg-2"}, receiver.Received())
+	})
+}
+
+func TestDeliveryNonRetryableFailure(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(messageID string) int {
+		if messageID == "msg-1" {
+			return 403
+		}
+		return 200
+	}}
+	withQueue(t, "5m", receiver, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1"))
+		publish(t, pubsub, "with-webhook-1", "msg-1", "msg-2")
+
+		// Should discard msg-1 rather than retrying forever
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 2 }, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-1", "msg-2"}, receiver.Received())
+	})
+}
+
+func TestDeliveryDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(messageID string) int {
+		switch messageID {
+		case "msg-1":
+			return 503 // Fails even after retries
+		case "msg-2":
+			return 403 // Non-retryable
+		default:
+			return 200
+		}
+	}}
+	withQueue(t, "5m", receiver, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		dlcSubscriber := domain.SubscriberLocator{ChannelID: "dlc-1", SubscriberID: "sbsc-1"}
+		assert.NoError(t, pubsub.NewSubscriber(ctx, dlcSubscriber, nil))
+		assert.NoError(t, q.Prepare(ctx, "with-dlc-1"))
+		publish(t, pubsub, "with-dlc-1", "msg-1", "msg-2", "msg-3")
+
+		// Failed messages should not block following messages
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 4 }, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-1", "msg-1", "msg-2", "msg-3"}, receiver.Received())
+
+		msgs, _, _, err := pubsub.FetchMessages(ctx, dlcSubscriber, 10, domain.Duration{})
+		assert.NoError(t, err)
+		if !assert.Equal(t, 2, len(msgs)) {
+			return
+		}
+		statusCodes := []int{}
+		for _, msg := range msgs {
+			var content DeadLetterContent
+			assert.NoError(t, json.Unmarshal(msg.Content, &content))
+			assert.Equal(t, "dsps.channel.outgoing-webhook.dead-letter", content.Type)
+			assert.Equal(t, domain.ChannelID("with-dlc-1"), content.ChannelID)
+			assert.Equal(t, `{}`, string(content.Content))
+			assert.Regexp(t, `^PUT http://.+/webhook$`, content.Webhook)
+			assert.Regexp(t, fmt.Sprintf(`status code %d returned`, *content.StatusCode), content.Error)
+			statusCodes = append(statusCodes, *content.StatusCode)
+		}
+		assert.ElementsMatch(t, []int{503, 403}, statusCodes)
+	})
+}
+
+func TestNewDeadLetter(t *testing.T) {
+	webhook := stubWebhook("PUT http://example.com/")
+	msg := domain.Message{
+		MessageLocator: domain.MessageLocator{ChannelID: "ch-1", MessageID: "msg-1"},
+		Content:        json.RawMessage(`{"hi":"hello"}`),
+	}
+
+	dl, err := NewDeadLetter("dlc", webhook, msg, errors.New("connection refused"))
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ChannelID("dlc"), dl.ChannelID)
+	_, err = domain.ParseMessageID(string(dl.MessageID))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "dsps.channel.outgoing-webhook.dead-letter",
+		"channelID": "ch-1",
+		"messageID": "msg-1",
+		"content": {"hi":"hello"},
+		"webhook": "PUT http://example.com/",
+		"statusCode": null,
+		"error": "connection refused"
+	}`, string(dl.Content))
+
+	// Should be deterministic to deduplicate
+	dl2, err := NewDeadLetter("dlc", webhook, msg, errors.New("other error"))
+	assert.NoError(t, err)
+	assert.Equal(t, dl.MessageID, dl2.MessageID)
+	dl3, err := NewDeadLetter("dlc", stubWebhook("PUT http://example.com/2"), msg, errors.New("other error"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, dl.MessageID, dl3.MessageID)
+
+	// Attributes are kept in both of content and the dead-letter message
+	msg.Attributes = domain.MessageAttributes{"event-type": "created"}
+	dl, err = NewDeadLetter("dlc", webhook, msg, errors.New("connection refused"))
+	assert.NoError(t, err)
+	assert.Equal(t, msg.Attributes, dl.Attributes)
+	var withAttrs DeadLetterContent
+	assert.NoError(t, json.Unmarshal(dl.Content, &withAttrs))
+	assert.Equal(t, msg.Attributes, withAttrs.Attributes)
+
+	// Corrupted content
+	msg.Content = json.RawMessage(`{{{`)
+	dl, err = NewDeadLetter("dlc", webhook, msg, errors.New("failed to make request body"))
+	assert.NoError(t, err)
+	var content DeadLetterContent
+	assert.NoError(t, json.Unmarshal(dl.Content, &content))
+	assert.Equal(t, `"{{{"`, string(content.Content))
+}
+
+func TestDeliveryPrepare(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(string) int { return 200 }}
+	withQueue(t, "5m", receiver, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		assert.True(t, errors.Is(q.Prepare(ctx, "not-configured"), domain.ErrInvalidChannel))
+
+		assert.NoError(t, q.Prepare(ctx, "no-webhook-1"))
+		assert.Equal(t, 0, len(q.targets))
+
+		// Messages published before Prepare() are not delivered, but those after that are delivered.
+		publish(t, pubsub, "with-webhook-1", "msg-before")
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1"))
+		publish(t, pubsub, "with-webhook-1", "msg-after")
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 1 }, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-after"}, receiver.Received())
+	})
+}
+
+func TestDeliveryStopsWhenIdle(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(string) int { return 200 }}
+	withQueue(t, "100ms", receiver, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1"))
+		assert.Eventually(t, func() bool {
+			q.lock.Lock()
+			defer q.lock.Unlock()
+			return len(q.targets) == 0
+		}, 3*time.Second, 10*time.Millisecond)
+
+		// Restart on next Prepare
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1"))
+		publish(t, pubsub, "with-webhook-1", "msg-1")
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 1 }, 3*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestDeliveryResume(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(string) int { return 200 }}
+	withQueue(t, "5m", receiver, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		// Messages left by other (e.g. restarted) server
+		assert.NoError(t, pubsub.NewSubscriber(ctx, webhookLocatorOf(t, cp, "with-webhook-1"), nil))
+		publish(t, pubsub, "with-webhook-1", "msg-1")
+		assert.NoError(t, pubsub.NewSubscriber(ctx, domain.SubscriberLocator{ChannelID: "with-webhook-1", SubscriberID: "_webhook-unknown"}, nil))
+		publish(t, pubsub, "with-webhook-1", "msg-2")
+
+		q.StartResuming()
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 2 }, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-1", "msg-2"}, receiver.Received())
+
+		// Webhooks no longer configured are not resumed
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		assert.Equal(t, 1, len(q.targets))
+	})
+}
+
+func TestDeliveryResumeRedis(t *testing.T) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(string) int { return 200 }}
+	withQueueOn(t, "5m", receiver, func(ctx context.Context, cp domain.ChannelProvider) (domain.Storage, error) {
+		cfg, err := config.ParseConfig(ctx, config.Overrides{}, fmt.Sprintf(`storages: { myRedis: { redis: { singleNode: "%s", timeout: { connect: 500ms }, connection: { max: 10 } } } }`, redisAddr()))
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewRedisStorage(ctx, cfg.Storages["myRedis"].Redis, domain.RealSystemClock, cp, EmptyDeps(t))
+	}, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		// Unique channel because Redis keeps data of other test runs
+		channelID := domain.ChannelID(fmt.Sprintf("with-webhook-%d", time.Now().UnixNano()))
+		sl := webhookLocatorOf(t, cp, channelID)
+		assert.NoError(t, pubsub.NewQueueSubscriber(ctx, sl, nil, domain.Duration{Duration: leaseTimeout}))
+		defer func() { assert.NoError(t, pubsub.RemoveSubscriber(ctx, sl)) }()
+		publish(t, pubsub, channelID, "msg-1")
+
+		// Internal subscriber must be found even though its ID is not acceptable as API input
+		q.StartResuming()
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= 1 }, 3*time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{"msg-1"}, receiver.Received())
+	})
+}
+
+func redisAddr() string {
+	if addr := os.Getenv("DSPS_REDIS"); addr != "" {
+		return addr
+	}
+	return "127.0.0.1:6379"
+}
+
+func TestDeliveryExclusive(t *testing.T) {
+	testDeliveryExclusive(t, func(ctx context.Context, cp domain.ChannelProvider) (domain.Storage, error) {
+		return onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, domain.RealSystemClock, cp, EmptyDeps(t))
+	})
+}
+
+func TestDeliveryExclusiveWithLease(t *testing.T) {
+	// Storage without queue subscriber support but shared among servers
+	testDeliveryExclusive(t, func(ctx context.Context, cp domain.ChannelProvider) (domain.Storage, error) {
+		s, err := onmemory.NewOnmemoryStorage(ctx, &config.OnmemoryStorageConfig{}, domain.RealSystemClock, cp, EmptyDeps(t))
+		if err != nil {
+			return nil, err
+		}
+		return &noQueueStorage{Storage: s, rateLimit: &stubRateLimitStorage{counters: map[string]*stubCounter{}}}, nil
+	})
+}
+
+func testDeliveryExclusive(t *testing.T, storageOf func(ctx context.Context, cp domain.ChannelProvider) (domain.Storage, error)) {
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: func(string) int { return 200 }}
+	var storage domain.Storage
+	withQueueOn(t, "5m", receiver, func(ctx context.Context, cp domain.ChannelProvider) (domain.Storage, error) {
+		s, err := storageOf(ctx, cp)
+		storage = s
+		return s, err
+	}, func(q *queue, pubsub domain.PubSubStorage, cp domain.ChannelProvider) {
+		// Other server shares the storage
+		q2 := NewQueue(storage, cp, domain.RealSystemClock, Deps{
+			Telemetry: telemetry.NewEmptyTelemetry(t),
+			Sentry:    sentry.NewEmptySentry(),
+		})
+		defer func() { assert.NoError(t, q2.Shutdown(ctx)) }()
+
+		assert.NoError(t, q.Prepare(ctx, "with-webhook-1"))
+		assert.NoError(t, q2.Prepare(ctx, "with-webhook-1"))
+		messageIDs := make([]domain.MessageID, 20)
+		expected := make([]string, len(messageIDs))
+		for i := range messageIDs {
+			messageIDs[i] = domain.MessageID(fmt.Sprintf("msg-%d", i))
+			expected[i] = string(messageIDs[i])
+		}
+		publish(t, pubsub, "with-webhook-1", messageIDs...)
+
+		assert.Eventually(t, func() bool { return len(receiver.Received()) >= len(messageIDs) }, 3*time.Second, 10*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		assert.ElementsMatch(t, expected, receiver.Received())
+	})
+}
+
+type noQueueStorage struct {
+	domain.Storage
+	rateLimit domain.RateLimitStorage
+}
+
+func (s *noQueueStorage) AsPubSubStorage() domain.PubSubStorage {
+	return &noQueuePubSub{PubSubStorage: s.Storage.AsPubSubStorage()}
+}
+func (s *noQueueStorage) AsRateLimitStorage() domain.RateLimitStorage { return s.rateLimit }
+
+type noQueuePubSub struct {
+	domain.PubSubStorage
+}
+
+func (s *noQueuePubSub) NewQueueSubscriber(ctx context.Context, sl domain.SubscriberLocator, filter *domain.SubscriberFilter, visibilityTimeout domain.Duration) error {
+	return domain.ErrQueueSubscriberUnsupported
+}
+
+type stubRateLimitStorage struct {
+	lock     sync.Mutex
+	counters map[string]*stubCounter
+}
+
+type stubCounter struct {
+	count   int64
+	resetAt time.Time
+}
+
+func (s *stubRateLimitStorage) IncrementRateLimitCounter(ctx context.Context, key string, window domain.Duration) (int64, domain.Duration, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.resetAt) {
+		c = &stubCounter{resetAt: now.Add(window.Duration)}
+		s.counters[key] = c
+	}
+	c.count++
+	return c.count, domain.Duration{Duration: c.resetAt.Sub(now)}, nil
+}
+
+func TestWebhookSubscriberID(t *testing.T) {
+	id := WebhookSubscriberID(stubWebhook("PUT http://example.com/"))
+	assert.Regexp(t, `^_webhook-[0-9a-f]{16}$`, string(id))
+	assert.Equal(t, id, WebhookSubscriberID(stubWebhook("PUT http://example.com/")))
+	assert.NotEqual(t, id, WebhookSubscriberID(stubWebhook("PUT http://example.com/2")))
+
+	_, err := domain.ParseSubscriberID(string(id))
+	assert.Error(t, err, "should not conflict with user-created subscribers")
+}
+
+type stubWebhook string
+
+func (s stubWebhook) Send(ctx context.Context, msg domain.Message) error { return nil }
+func (s stubWebhook) DeadLetterChannel() *domain.ChannelID               { return nil }
+func (s stubWebhook) String() string                                     { return string(s) }
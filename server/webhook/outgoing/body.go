@@ -15,6 +15,8 @@ type outgoingWebhookBody struct {
 	ChannelID string          `json:"channelID"`
 	MessageID string          `json:"messageID"`
 	Content   json.RawMessage `json:"content"`
+
+	Attributes domain.MessageAttributes `json:"attributes,omitempty"`
 }
 
 func encodeWebhookBody(ctx context.Context, msg domain.Message) (string, error) {
@@ -23,6 +25,8 @@ func encodeWebhookBody(ctx context.Context, msg domain.Message) (string, error)
 		ChannelID: string(msg.ChannelID),
 		MessageID: string(msg.MessageID),
 		Content:   msg.Content,
+
+		Attributes: msg.Attributes,
 	}
 	bytes, err := json.Marshal(body)
 	if err != nil {
@@ -20,6 +20,9 @@ import (
 type Client interface {
 	Send(ctx context.Context, msg domain.Message) error
 
+	// Returns channel to publish messages that could not be delivered, nil if not configured.
+	DeadLetterChannel() *domain.ChannelID
+
 	// Shutdown this client.
 	// This method wait until all in-flight request ends.
 	Close(ctx context.Context)
@@ -34,6 +37,8 @@ type clientImpl struct {
 	url     string
 	headers map[string]string
 
+	deadLetterChannel *domain.ChannelID
+
 	timeout time.Duration
 	retry   retry
 
@@ -68,6 +73,17 @@ func newClientImpl(tpl *clientTemplate, tplEnv domain.TemplateStringEnv) (Client
 			return nil, xerrors.Errorf(`failed to expand template of webhook header "%s", "%s": %w`, name, valueTpl, err)
 		}
 	}
+	if tpl.DeadLetterChannel != nil {
+		expanded, err := tpl.DeadLetterChannel.Execute(tplEnv)
+		if err != nil {
+			return nil, xerrors.Errorf(`failed to expand template of webhook deadLetterChannel "%s": %w`, tpl.DeadLetterChannel, err)
+		}
+		channelID, err := domain.ParseChannelID(expanded)
+		if err != nil {
+			return nil, xerrors.Errorf(`invalid webhook deadLetterChannel "%s": %w`, expanded, err)
+		}
+		c.deadLetterChannel = &channelID
+	}
 	return c, nil
 }
 
@@ -75,6 +91,10 @@ func (c *clientImpl) String() string {
 	return fmt.Sprintf("%s %s", c.method, c.url)
 }
 
+func (c *clientImpl) DeadLetterChannel() *domain.ChannelID {
+	return c.deadLetterChannel
+}
+
 func (c *clientImpl) Send(ctx context.Context, msg domain.Message) error {
 	if c.isClosed() {
 		return xerrors.Errorf("outgoing-webhook client already closed")
@@ -86,7 +106,10 @@ func (c *clientImpl) Send(ctx context.Context, msg domain.Message) error {
 		return xerrors.Errorf("failed to generate outgoing webhook body: %w", err)
 	}
 
-	return c.retry.Do(ctx, c.sentry, fmt.Sprintf("outgoing-webhook to %s", c.url), func() (*http.Request, *http.Response, error) {
+	// Continue the publisher's trace, so that traceparent header links the receiver with it.
+	ctx = c.telemetry.WithTraceParent(ctx, msg.TraceParent)
+
+	return c.retry.Do(ctx, c.telemetry, c.sentry, fmt.Sprintf("outgoing-webhook to %s", c.url), func() (*http.Request, *http.Response, error) {
 		req, err := http.NewRequestWithContext(ctx, c.method, c.url, strings.NewReader(body))
 		if err != nil {
 			return req, nil, err
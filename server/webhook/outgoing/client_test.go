@@ -136,6 +136,43 @@ func TestClientTracing(t *testing.T) {
 	})
 }
 
+func TestClientTraceContextPropagation(t *testing.T) {
+	msg := domain.Message{
+		MessageLocator: domain.MessageLocator{
+			ChannelID: "chat-room-1234",
+			MessageID: "msg-1",
+		},
+		Content:     []byte(`{"hi":"hello"}`),
+		TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+	var receivedTraceParent string
+	var handler http.HandlerFunc = func(rw http.ResponseWriter, r *http.Request) {
+		receivedTraceParent = r.Header.Get("traceparent")
+	}
+	config := `{ "url": "${BASE_URL}/you-got-message", "timeout": "3s" }`
+
+	// Tracing disabled, passes through publisher's trace context
+	newClientAndServerByConfig(t, handler, map[string]interface{}{}, config, func(client *clientImpl) {
+		assert.NoError(t, client.Send(context.Background(), msg))
+	})
+	assert.Equal(t, msg.TraceParent, receivedTraceParent)
+
+	// Tracing enabled, outgoing HTTP span continues publisher's trace
+	tr := telemetry.WithStubTracing(t, func(telemetry *telemetry.Telemetry) {
+		newClientAndServerByConfig(t, handler, map[string]interface{}{}, config, func(client *clientImpl) {
+			client.telemetry = telemetry
+			ctx, end := telemetry.StartDaemonSpan(context.Background(), "test", "daemon")
+			defer end()
+			assert.NoError(t, client.Send(ctx, msg))
+		})
+	})
+	span := tr.OT.GetSpans()[0]
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.SpanContext.TraceID.String())
+	assert.Equal(t, "b7ad6b7169203331", span.ParentSpanID.String())
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", span.SpanContext.TraceID, span.SpanContext.SpanID), receivedTraceParent)
+}
+
 func TestClientRetry(t *testing.T) {
 	msg := domain.Message{
 		MessageLocator: domain.MessageLocator{
@@ -285,3 +322,29 @@ func TestClientTemplateEvalFailures(t *testing.T) {
 	_, err = tpl.NewClient(map[string]interface{}{})
 	assert.Regexp(t, `map has no entry for key "INVALID"`, err.Error())
 }
+
+func TestClientDeadLetterChannel(t *testing.T) {
+	tplEnv := map[string]interface{}{"channel": map[string]string{"id": "1234"}}
+
+	tpl := newClientTemplateByConfig(t, `.+`, `{ "url": "http://localhost:1234/webhook" }`)
+	tpl.Close()
+	client, err := tpl.NewClient(tplEnv)
+	assert.NoError(t, err)
+	assert.Nil(t, client.DeadLetterChannel())
+
+	tpl = newClientTemplateByConfig(t, `.+`, `{ "url": "http://localhost:1234/webhook", "deadLetterChannel": "failed-{{.channel.id}}" }`)
+	tpl.Close()
+	client, err = tpl.NewClient(tplEnv)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ChannelID("failed-1234"), *client.DeadLetterChannel())
+
+	tpl = newClientTemplateByConfig(t, `.+`, `{ "url": "http://localhost:1234/webhook", "deadLetterChannel": "INVALID {{.channel.id}}" }`)
+	tpl.Close()
+	_, err = tpl.NewClient(tplEnv)
+	assert.Regexp(t, `invalid webhook deadLetterChannel "INVALID 1234"`, err.Error())
+
+	tpl = newClientTemplateByConfig(t, `.+`, `{ "url": "http://localhost:1234/webhook", "deadLetterChannel": "{{.INVALID}}" }`)
+	tpl.Close()
+	_, err = tpl.NewClient(tplEnv)
+	assert.Regexp(t, `map has no entry for key "INVALID"`, err.Error())
+}
@@ -2,6 +2,7 @@ package outgoing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,8 +18,46 @@ import (
 	"github.com/m3dev/dsps/server/config"
 	"github.com/m3dev/dsps/server/logger"
 	"github.com/m3dev/dsps/server/sentry"
+	"github.com/m3dev/dsps/server/telemetry"
 )
 
+// NonRetryableError means outgoing-webhook failed with the reason that retrying never resolves (e.g. 403 Forbidden).
+type NonRetryableError struct {
+	err error
+}
+
+func (e *NonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *NonRetryableError) Unwrap() error {
+	return e.err
+}
+
+// IsNonRetryableError returns true if given error (or wrapped one) is NonRetryableError
+func IsNonRetryableError(err error) bool {
+	var nre *NonRetryableError
+	return errors.As(err, &nre)
+}
+
+// StatusCodeError means outgoing-webhook endpoint responded with unsuccessful HTTP status code.
+type StatusCodeError struct {
+	StatusCode int
+}
+
+func (e *StatusCodeError) Error() string {
+	return fmt.Sprintf("status code %d returned", e.StatusCode)
+}
+
+// StatusCodeOf returns HTTP status code of the last failed attempt, returns 0 if no response received (e.g. connection error).
+func StatusCodeOf(err error) int {
+	var sce *StatusCodeError
+	if errors.As(err, &sce) {
+		return sce.StatusCode
+	}
+	return 0
+}
+
 type retry struct {
 	count              int
 	interval           time.Duration
@@ -40,7 +79,7 @@ func newRetry(cfg *config.OutgoingWebhookRetryConfig) retry {
 
 // Do wraps given operation with retry handling.
 // Callback function should not close response body stream, this method closes it.
-func (r *retry) Do(ctx context.Context, sentryInstance sentry.Sentry, description string, f func() (*http.Request, *http.Response, error)) error {
+func (r *retry) Do(ctx context.Context, telemetryInstance *telemetry.Telemetry, sentryInstance sentry.Sentry, description string, f func() (*http.Request, *http.Response, error)) error {
 	attempt := 0
 	for {
 		req, res, err := f()
@@ -73,9 +112,12 @@ func (r *retry) Do(ctx context.Context, sentryInstance sentry.Sentry, descriptio
 			}
 
 			if err == nil && 200 <= res.StatusCode && res.StatusCode <= 299 {
+				telemetryInstance.CountWebhookAttempt(true)
+				telemetryInstance.CountWebhookDelivery(telemetry.WebhookOutcomeSuccess)
 				return nil // Success
 			}
 		}
+		telemetryInstance.CountWebhookAttempt(false)
 		attempt++ // Failed
 
 		var shouldRetry bool
@@ -83,12 +125,23 @@ func (r *retry) Do(ctx context.Context, sentryInstance sentry.Sentry, descriptio
 		if attempt > r.count || !shouldRetry {
 			logger.Of(ctx).Warnf(logger.CatOutgoingWebhook, "outgoing webhook failed: %w", err)
 			sentry.RecordError(ctx, fmt.Errorf("outgoing webhook failed: %w", err))
+			if !shouldRetry {
+				telemetryInstance.CountWebhookDelivery(telemetry.WebhookOutcomeNonRetryable)
+				return &NonRetryableError{err: err}
+			}
+			telemetryInstance.CountWebhookDelivery(telemetry.WebhookOutcomeFailure)
 			return err
 		}
 
 		wait := r.computeRetryWait(attempt)
 		logger.Of(ctx).Infof(logger.CatOutgoingWebhook, "retrying outgoing webhook after %s: %w", wait, err)
-		time.Sleep(wait)
+		select {
+		case <-ctx.Done():
+			telemetryInstance.CountWebhookDelivery(telemetry.WebhookOutcomeCanceled)
+			return xerrors.Errorf("outgoing webhook retry canceled: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		telemetryInstance.CountWebhookRetry()
 		continue
 	}
 }
@@ -148,7 +201,7 @@ func (r *retry) postprocess(res *http.Response, err error) (shouldRetry bool, er
 	}
 
 	if result, matched := statusCodeToRetry[res.StatusCode]; matched {
-		return result, xerrors.Errorf("status code %d returned", res.StatusCode)
+		return result, &StatusCodeError{StatusCode: res.StatusCode}
 	}
-	return true, xerrors.Errorf("status code %d returned", res.StatusCode)
+	return true, &StatusCodeError{StatusCode: res.StatusCode}
 }
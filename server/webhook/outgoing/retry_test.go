@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/m3dev/dsps/server/sentry"
+	"github.com/m3dev/dsps/server/telemetry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,7 +23,7 @@ func TestRetryResponseHandling(t *testing.T) {
 	responses[1].body.ErrOnClose = errors.New("error while closing")
 	for _, res := range responses {
 		attempts := 0
-		assert.NoError(t, (&retry{}).Do(context.Background(), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+		assert.NoError(t, (&retry{}).Do(context.Background(), telemetry.NewEmptyTelemetry(t), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
 			attempts++
 			return nil, &res.Response, nil
 		}))
@@ -41,7 +42,7 @@ func TestRetrySuccessForUnexpectedHttpStatus(t *testing.T) {
 	attempts := 0
 	assert.NoError(t, (&retry{
 		count: 2,
-	}).Do(context.Background(), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+	}).Do(context.Background(), telemetry.NewEmptyTelemetry(t), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
 		attempts++
 		return nil, &resQueue[attempts-1].Response, nil
 	}))
@@ -59,20 +60,57 @@ func TestRetryFailureForUnexpectedHttpStatus(t *testing.T) {
 	attempts := 0
 	err := (&retry{
 		count: 2, // Give up after 2nd retry
-	}).Do(context.Background(), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+	}).Do(context.Background(), telemetry.NewEmptyTelemetry(t), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
 		attempts++
 		return nil, &resQueue[attempts-1].Response, nil
 	})
 	assert.Error(t, err)
 	assert.Regexp(t, `status code 404 returned`, err.Error())
+	assert.Equal(t, 404, StatusCodeOf(err))
+	assert.False(t, IsNonRetryableError(err))
 	assert.Equal(t, 3, attempts)
 }
 
+func TestRetryFailureForNonRetryableHttpStatus(t *testing.T) {
+	attempts := 0
+	err := (&retry{
+		count: 2,
+	}).Do(context.Background(), telemetry.NewEmptyTelemetry(t), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+		attempts++
+		return nil, &newMockResponse(403, []byte("Forbidden")).Response, nil
+	})
+	assert.Error(t, err)
+	assert.Regexp(t, `status code 403 returned`, err.Error())
+	assert.True(t, IsNonRetryableError(err))
+	assert.True(t, IsNonRetryableError(fmt.Errorf("wrapped: %w", err)))
+	assert.Equal(t, 403, StatusCodeOf(fmt.Errorf("wrapped: %w", err)))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryCanceledDuringWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	start := time.Now()
+	err := (&retry{
+		count:              2,
+		interval:           10 * time.Second,
+		intervalMultiplier: 1.0,
+	}).Do(ctx, telemetry.NewEmptyTelemetry(t), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+		attempts++
+		cancel()
+		return nil, &newMockResponse(500, []byte("Internal server error")).Response, nil
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, IsNonRetryableError(err))
+	assert.Equal(t, 1, attempts)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
 func TestRetrySuccessForError(t *testing.T) {
 	attempts := 0
 	assert.NoError(t, (&retry{
 		count: 2,
-	}).Do(context.Background(), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+	}).Do(context.Background(), telemetry.NewEmptyTelemetry(t), sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
 		attempts++
 		if attempts <= 2 {
 			return nil, nil, errors.New("test error")
@@ -87,14 +125,36 @@ func TestRetryFailureForError(t *testing.T) {
 	attempts := 0
 	err := (&retry{
 		count: 2,
-	}).Do(context.Background(), sentry.NewStubSentry(), "test", func() (*http.Request, *http.Response, error) {
+	}).Do(context.Background(), telemetry.NewEmptyTelemetry(t), sentry.NewStubSentry(), "test", func() (*http.Request, *http.Response, error) {
 		attempts++
 		return &http.Request{}, nil, testError
 	})
 	assert.Equal(t, testError, err)
+	assert.Equal(t, 0, StatusCodeOf(err))
 	assert.Equal(t, 3, attempts)
 }
 
+func TestRetryMetrics(t *testing.T) {
+	result := telemetry.WithStubMetrics(t, func(tm *telemetry.Telemetry) {
+		attempts := 0
+		assert.NoError(t, (&retry{count: 2}).Do(context.Background(), tm, sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return nil, &newMockResponse(500, []byte("Internal server error")).Response, nil
+			}
+			return nil, &newMockResponse(200, []byte{}).Response, nil
+		}))
+		assert.Error(t, (&retry{count: 2}).Do(context.Background(), tm, sentry.NewEmptySentry(), "test", func() (*http.Request, *http.Response, error) {
+			return nil, &newMockResponse(403, []byte("Forbidden")).Response, nil
+		}))
+	})
+	assert.Equal(t, 1.0, result.Value("dsps_webhook_attempts_total", map[string]string{"outcome": "success"}))
+	assert.Equal(t, 2.0, result.Value("dsps_webhook_attempts_total", map[string]string{"outcome": "failure"}))
+	assert.Equal(t, 1.0, result.Value("dsps_webhook_retries_total", map[string]string{}))
+	assert.Equal(t, 1.0, result.Value("dsps_webhook_deliveries_total", map[string]string{"outcome": "success"}))
+	assert.Equal(t, 1.0, result.Value("dsps_webhook_deliveries_total", map[string]string{"outcome": "non_retryable"}))
+}
+
 func TestRetryWait(t *testing.T) {
 	retryWithoutJitter := &retry{
 		interval:           3 * time.Second,
@@ -143,5 +203,6 @@ func TestPostprocess(t *testing.T) {
 		assert.Equal(t, expectedRetry, shouldRetry)
 		assert.NotNil(t, err, "postprocess() must return non-nil err")
 		assert.Equal(t, fmt.Sprintf("status code %d returned", statusCode), err.Error())
+		assert.Equal(t, statusCode, StatusCodeOf(err))
 	}
 }